@@ -183,7 +183,9 @@ type BlockChain struct {
 	notificationsLock sync.RWMutex
 	notifications     []NotificationCallback
 
-	claimTrie *claimtrie.ClaimTrie
+	claimTrie            *claimtrie.ClaimTrie
+	strictClaimScripts   bool
+	verifyClaimTrieRoots bool
 }
 
 // HaveBlock returns whether or not the chain instance has the block represented
@@ -576,8 +578,8 @@ func (b *BlockChain) connectBlock(node *blockNode, block *btcutil.Block,
 
 	// Handle LBRY Claim Scripts
 	if b.claimTrie != nil {
-		if err := b.ParseClaimScripts(block, node, view, false); err != nil {
-			return ruleError(ErrBadClaimTrie, err.Error())
+		if err := b.ParseClaimScripts(block, node, view, b.verifyClaimTrieRoots); err != nil {
+			return err
 		}
 	}
 
@@ -1722,6 +1724,16 @@ type Config struct {
 	HashCache *txscript.HashCache
 
 	ClaimTrie *claimtrie.ClaimTrie
+
+	// StrictClaimScripts rejects the blocks with claim scripts of a version after
+	// txscript.ClaimScriptVersionCurrent, which are ignored, as NOPs, otherwise,
+	// and the blocks with invalid updates prior to param.InvalidUpdateForkHeight,
+	// which are ignored, as lbrycrd does, otherwise.
+	StrictClaimScripts bool
+
+	// VerifyClaimTrieRoots rejects the blocks connected, of which the roots of the claim trie in
+	// their headers don't match the ones of the ClaimTrie, which are only logged otherwise.
+	VerifyClaimTrieRoots bool
 }
 
 // New returns a BlockChain instance using the provided configuration details.
@@ -1759,24 +1771,26 @@ func New(config *Config) (*BlockChain, error) {
 	targetTimespan := int64(params.TargetTimespan / time.Second)
 	targetTimePerBlock := int64(params.TargetTimePerBlock / time.Second)
 	b := BlockChain{
-		checkpoints:         config.Checkpoints,
-		checkpointsByHeight: checkpointsByHeight,
-		db:                  config.DB,
-		chainParams:         params,
-		timeSource:          config.TimeSource,
-		sigCache:            config.SigCache,
-		indexManager:        config.IndexManager,
-		minRetargetTimespan: targetTimespan - (targetTimespan / 8),
-		maxRetargetTimespan: targetTimespan + (targetTimespan / 2),
-		blocksPerRetarget:   int32(targetTimespan / targetTimePerBlock),
-		index:               newBlockIndex(config.DB, params),
-		hashCache:           config.HashCache,
-		bestChain:           newChainView(nil),
-		orphans:             make(map[chainhash.Hash]*orphanBlock),
-		prevOrphans:         make(map[chainhash.Hash][]*orphanBlock),
-		warningCaches:       newThresholdCaches(vbNumBits),
-		deploymentCaches:    newThresholdCaches(chaincfg.DefinedDeployments),
-		claimTrie:           config.ClaimTrie,
+		checkpoints:          config.Checkpoints,
+		checkpointsByHeight:  checkpointsByHeight,
+		db:                   config.DB,
+		chainParams:          params,
+		timeSource:           config.TimeSource,
+		sigCache:             config.SigCache,
+		indexManager:         config.IndexManager,
+		minRetargetTimespan:  targetTimespan - (targetTimespan / 8),
+		maxRetargetTimespan:  targetTimespan + (targetTimespan / 2),
+		blocksPerRetarget:    int32(targetTimespan / targetTimePerBlock),
+		index:                newBlockIndex(config.DB, params),
+		hashCache:            config.HashCache,
+		bestChain:            newChainView(nil),
+		orphans:              make(map[chainhash.Hash]*orphanBlock),
+		prevOrphans:          make(map[chainhash.Hash][]*orphanBlock),
+		warningCaches:        newThresholdCaches(vbNumBits),
+		deploymentCaches:     newThresholdCaches(chaincfg.DefinedDeployments),
+		claimTrie:            config.ClaimTrie,
+		strictClaimScripts:   config.StrictClaimScripts,
+		verifyClaimTrieRoots: config.VerifyClaimTrieRoots,
 	}
 
 	// Initialize the chain state from the passed database.  When the db
@@ -2,63 +2,267 @@ package blockchain
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
 	"fmt"
 
 	"github.com/pkg/errors"
 
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 
 	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
 	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
 )
 
-// Hack: print which block mismatches happened, but keep recording.
-var mismatchedPrinted bool
+// Only the first block mismatching is logged as an error, as the following ones are bound to mismatch too.
+var mismatchedLogged bool
 
+// ParseClaimScripts applies the claim scripts of the block to the ClaimTrie, and appends it. Unless failOnHashMiss,
+// a root not matching the one in the header of the block is only logged. Otherwise, the block is rolled back from
+// the ClaimTrie, and an error is returned.
+// The claim scripts, and the root, breaking the rules return a RuleError, of ErrBadClaimTrie, unless the handler tells
+// the rule broken. The ClaimTrie failing to append the block, such as on a storage error, returns a plain error, and
+// the ClaimTrie is left before the block.
 func (b *BlockChain) ParseClaimScripts(block *btcutil.Block, node *blockNode, view *UtxoViewpoint, failOnHashMiss bool) error {
 	ht := block.Height()
 
+	// The writes are batched across the blocks, until the chain is current.
+	if err := b.claimTrie.SetBatching(!b.isCurrent()); err != nil {
+		return err
+	}
+
+	// The changes of the block are discarded, unless all of its transactions are valid.
+	ctx := b.claimTrie.Begin(ht)
 	for _, tx := range block.Transactions() {
-		h := handler{ht, tx, view, map[string][]byte{}}
-		if err := h.handleTxIns(b.claimTrie); err != nil {
-			return err
+		h := handler{ht, tx, view, b.chainParams, map[string][]byte{}, map[string][]byte{}, b.strictClaimScripts}
+		if err := h.handleTxIns(ctx); err != nil {
+			ctx.Abort()
+			return claimTrieRuleError(err)
 		}
-		if err := h.handleTxOuts(b.claimTrie); err != nil {
-			return err
+		if err := h.handleTxOuts(ctx); err != nil {
+			ctx.Abort()
+			return claimTrieRuleError(err)
 		}
 	}
+	if err := ctx.Commit(); err != nil {
+		return ruleError(ErrBadClaimTrie, err.Error())
+	}
 
 	// Hack: let the claimtrie know the expected Hash.
 	b.claimTrie.ReportHash(ht, node.claimTrie)
 
 	err := b.claimTrie.AppendBlock()
 	if err != nil {
-		return err
+		return fmt.Errorf("append block %d to the ClaimTrie: %w", ht, err)
 	}
 	hash := b.claimTrie.MerkleHash()
 
 	if node.claimTrie != *hash {
 		if failOnHashMiss {
-			return fmt.Errorf("height: %d, ct.MerkleHash: %s != node.ClaimTrie: %s", ht, *hash, node.claimTrie)
+			if err := b.claimTrie.ResetHeight(ht - 1); err != nil {
+				return fmt.Errorf("reset height to %d: %w", ht-1, err)
+			}
+			str := fmt.Sprintf("height: %d, ct.MerkleHash: %s != node.ClaimTrie: %s", ht, *hash, node.claimTrie)
+			return ruleError(ErrBadClaimTrie, str)
 		}
-		if !mismatchedPrinted {
-			fmt.Printf("\n\nHeight: %d, ct.MerkleHash: %s != node.ClaimTrie: %s, Error: %s\n", ht, *hash, node.claimTrie, err)
-			mismatchedPrinted = true
+		if !mismatchedLogged {
+			log.Errorf("ClaimTrie root mismatch at height %d: %s != header: %s", ht, *hash, node.claimTrie)
+			mismatchedLogged = true
+		} else {
+			log.Debugf("ClaimTrie root mismatch at height %d: %s != header: %s", ht, *hash, node.claimTrie)
 		}
 	}
 	return nil
 }
 
+// claimTrieRuleError returns the error of a handler as a RuleError of ErrBadClaimTrie, unless it's a RuleError already.
+func claimTrieRuleError(err error) error {
+	if _, ok := err.(RuleError); ok {
+		return err
+	}
+	return ruleError(ErrBadClaimTrie, err.Error())
+}
+
+// ResolveClaimName resolves the name as of the block with the hash in the main chain,
+// and verifies the root of the trie at its height against the one in its header.
+// The ClaimTrie is read as of its last block committed, without waiting for the one connected.
+// It gives up, once the context is done.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ResolveClaimName(ctx context.Context, name []byte, hash *chainhash.Hash) (*claimtrie.Resolution, error) {
+	b.chainLock.RLock()
+	node := b.index.LookupNode(hash)
+	inMainChain := node != nil && b.bestChain.Contains(node)
+	b.chainLock.RUnlock()
+	if !inMainChain {
+		str := fmt.Sprintf("block %s is not in the main chain", hash)
+		return nil, errNotInMainChain(str)
+	}
+
+	// The root check rules out the block being disconnected since.
+	res, err := b.claimTrie.ResolveAtContext(ctx, name, node.height)
+	if err != nil {
+		return nil, err
+	}
+	if res.Root != node.claimTrie {
+		return nil, fmt.Errorf("claim trie root at height %d: %s != header: %s", node.height, res.Root, node.claimTrie)
+	}
+
+	return res, nil
+}
+
+// ProveClaimName resolves the name as of the block with the hash in the main chain, as ResolveClaimName does,
+// and returns the proof of its best claim against the root of the trie in the header of the block.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ProveClaimName(ctx context.Context, name []byte, hash *chainhash.Hash) (*claimtrie.Resolution, *merkletrie.Proof, error) {
+	b.chainLock.RLock()
+	node := b.index.LookupNode(hash)
+	inMainChain := node != nil && b.bestChain.Contains(node)
+	b.chainLock.RUnlock()
+	if !inMainChain {
+		str := fmt.Sprintf("block %s is not in the main chain", hash)
+		return nil, nil, errNotInMainChain(str)
+	}
+
+	res, p, err := b.claimTrie.ProveAtContext(ctx, name, node.height)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.Root != node.claimTrie {
+		return nil, nil, fmt.Errorf("claim trie root at height %d: %s != header: %s", node.height, res.Root, node.claimTrie)
+	}
+
+	return res, p, nil
+}
+
+// RehashClaimNames rebuilds the names in the ClaimTrie from the node repo, and re-hashes their paths,
+// between the blocks connected. It also reports whether the resulting root matches the one in the
+// header of the best block.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) RehashClaimNames(names [][]byte) (*chainhash.Hash, bool, error) {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	root, err := b.claimTrie.RehashNames(names)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return root, *root == b.bestChain.Tip().claimTrie, nil
+}
+
+// PreviewClaimTrieRoot returns the root the ClaimTrie would have after connecting the block, which must
+// connect to the tip of the main chain, as a block template does. Nothing is applied to the ClaimTrie.
+// Without the ClaimTrie, which is disabled for development, the root of the tip is carried over.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) PreviewClaimTrieRoot(block *btcutil.Block) (*chainhash.Hash, error) {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	tip := b.bestChain.Tip()
+	header := block.MsgBlock().Header
+	if tip.hash != header.PrevBlock {
+		str := fmt.Sprintf("previous block must be the current chain tip %v, "+
+			"instead got %v", tip.hash, header.PrevBlock)
+		return nil, ruleError(ErrPrevBlockNotBest, str)
+	}
+	if b.claimTrie == nil {
+		root := tip.claimTrie
+		return &root, nil
+	}
+
+	return b.previewClaimTrieRoot(block, tip)
+}
+
+// CheckClaimTrieRoot returns a rule error, unless the root of the ClaimTrie in the header of the block matches
+// the one after connecting it. The blocks, which don't connect to the tip of the main chain, aren't checked,
+// and neither are any without the ClaimTrie.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) CheckClaimTrieRoot(block *btcutil.Block) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	tip := b.bestChain.Tip()
+	if b.claimTrie == nil || tip.hash != block.MsgBlock().Header.PrevBlock {
+		return nil
+	}
+
+	return b.checkClaimTrieRoot(block, tip)
+}
+
+// checkClaimTrieRoot checks the root of the ClaimTrie in the header of the block connecting to the tip.
+// It must be called with the chain state lock held.
+func (b *BlockChain) checkClaimTrieRoot(block *btcutil.Block, tip *blockNode) error {
+
+	root, err := b.previewClaimTrieRoot(block, tip)
+	if err != nil {
+		return err
+	}
+	if header := block.MsgBlock().Header; header.ClaimTrie != *root {
+		str := fmt.Sprintf("block claim trie root %s does not match the expected %s", header.ClaimTrie, root)
+		return ruleError(ErrBadClaimTrie, str)
+	}
+
+	return nil
+}
+
+// previewClaimTrieRoot runs the claim scripts of the block connecting to the tip in a claimtrie.Transaction,
+// previews the root, and aborts it. It must be called with the chain state lock held, and the ClaimTrie.
+func (b *BlockChain) previewClaimTrieRoot(block *btcutil.Block, tip *blockNode) (*chainhash.Hash, error) {
+
+	view := NewUtxoViewpoint()
+	view.SetBestHash(&tip.hash)
+	err := view.fetchInputUtxos(b.db, block)
+	if err != nil {
+		return nil, err
+	}
+
+	ht := tip.height + 1
+	ctx := b.claimTrie.Begin(ht)
+	defer ctx.Abort()
+	for _, tx := range block.Transactions() {
+		h := handler{ht, tx, view, b.chainParams, map[string][]byte{}, map[string][]byte{}, b.strictClaimScripts}
+		if err := h.handleTxIns(ctx); err != nil {
+			return nil, err
+		}
+		if err := h.handleTxOuts(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return ctx.PreviewRoot()
+}
+
 type handler struct {
-	ht    int32
-	tx    *btcutil.Tx
-	view  *UtxoViewpoint
-	spent map[string][]byte
+	ht     int32
+	tx     *btcutil.Tx
+	view   *UtxoViewpoint
+	params *chaincfg.Params
+	spent  map[string][]byte
+	owners map[string][]byte // The destination scripts of the claims spent.
+	strict bool              // The claim scripts of the future versions, and the invalid updates prior to the fork, are rejected, instead of ignored.
+}
+
+// claimOwner returns the address paid by the destination script of a claim, or its hex, if it pays none, or several.
+func (h *handler) claimOwner(script []byte) string {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(script, h.params)
+	if err != nil || len(addrs) != 1 {
+		return hex.EncodeToString(script)
+	}
+	return addrs[0].EncodeAddress()
 }
 
-func (h *handler) handleTxIns(ct *claimtrie.ClaimTrie) error {
+func (h *handler) handleTxIns(ctx *claimtrie.Transaction) error {
 	if IsCoinBase(h.tx) {
 		return nil
 	}
@@ -79,18 +283,21 @@ func (h *handler) handleTxIns(ct *claimtrie.ClaimTrie) error {
 		var id node.ClaimID
 		name := cs.Name() // name of the previous one (that we're now spending)
 
+		// Names are normalized as of the trie prior to the block.
 		switch cs.Opcode() {
 		case txscript.OP_CLAIMNAME: // OP code from previous transaction
 			id = node.NewClaimID(op) // claimID of the previous item now being spent
-			h.spent[id.String()] = node.NormalizeIfNecessary(name, ct.Height())
-			err = ct.SpendClaim(name, op, id)
+			h.spent[id.String()] = node.NormalizeIfNecessary(name, ctx.Height()-1)
+			h.owners[id.String()] = txscript.StripClaimScriptPrefix(e.pkScript)
+			err = ctx.SpendClaim(name, op, id)
 		case txscript.OP_UPDATECLAIM:
 			copy(id[:], cs.ClaimID())
-			h.spent[id.String()] = node.NormalizeIfNecessary(name, ct.Height())
-			err = ct.SpendClaim(name, op, id)
+			h.spent[id.String()] = node.NormalizeIfNecessary(name, ctx.Height()-1)
+			h.owners[id.String()] = txscript.StripClaimScriptPrefix(e.pkScript)
+			err = ctx.SpendClaim(name, op, id)
 		case txscript.OP_SUPPORTCLAIM:
 			copy(id[:], cs.ClaimID())
-			err = ct.SpendSupport(name, op, id)
+			err = ctx.SpendSupport(name, op, id)
 		}
 		if err != nil {
 			return errors.Wrapf(err, "handleTxIns")
@@ -99,13 +306,20 @@ func (h *handler) handleTxIns(ct *claimtrie.ClaimTrie) error {
 	return nil
 }
 
-func (h *handler) handleTxOuts(ct *claimtrie.ClaimTrie) error {
+func (h *handler) handleTxOuts(ctx *claimtrie.Transaction) error {
 	for i, txOut := range h.tx.MsgTx().TxOut {
 		op := wire.NewOutPoint(h.tx.Hash(), uint32(i))
-		cs, err := txscript.DecodeClaimScript(txOut.PkScript)
+		cs, err := txscript.RecognizeClaimScript(txOut.PkScript, txscript.ClaimScriptVersionCurrent)
 		if err == txscript.ErrNotClaimScript {
 			continue
 		}
+		if err == txscript.ErrFutureClaimScript {
+			if h.strict {
+				str := fmt.Sprintf("claim script of a future version in output %d of tx %s", i, h.tx.Hash())
+				return ruleError(ErrFutureClaimScript, str)
+			}
+			continue // a NOP, until a soft-fork defines it.
+		}
 		if err != nil {
 			return err
 		}
@@ -118,23 +332,33 @@ func (h *handler) handleTxOuts(ct *claimtrie.ClaimTrie) error {
 		switch cs.Opcode() {
 		case txscript.OP_CLAIMNAME:
 			id = node.NewClaimID(*op)
-			err = ct.AddClaim(name, *op, id, amt, value)
+			err = ctx.AddClaim(name, *op, id, amt, value)
 		case txscript.OP_SUPPORTCLAIM:
 			copy(id[:], cs.ClaimID())
-			err = ct.AddSupport(name, value, *op, amt, id)
+			err = ctx.AddSupport(name, value, *op, amt, id)
 		case txscript.OP_UPDATECLAIM:
 			// old code wouldn't run the update if name or claimID didn't match existing data
 			// that was a safety feature, but it should have rejected the transaction instead
-			// TODO: reject transactions with invalid update commands
 			copy(id[:], cs.ClaimID())
-			normName := node.NormalizeIfNecessary(name, ct.Height())
+			normName := node.NormalizeIfNecessary(name, ctx.Height()-1)
 			if !bytes.Equal(h.spent[id.String()], normName) {
-				fmt.Printf("Invalid update operation: name or ID mismatch for %s, %s\n", normName, id.String())
+				str := fmt.Sprintf("invalid update of claim %s under name %s in tx %s", id, normName, h.tx.Hash())
+				if h.ht >= param.InvalidUpdateForkHeight || h.strict {
+					return ruleError(ErrBadClaimUpdate, str)
+				}
+				log.Warnf("Ignoring the %s at height %d, prior to the fork", str, h.ht)
 				continue
 			}
 
 			delete(h.spent, id.String())
-			err = ct.UpdateClaim(name, *op, amt, id, value)
+			err = ctx.UpdateClaim(name, *op, amt, id, value)
+			prev, next := h.owners[id.String()], txscript.StripClaimScriptPrefix(txOut.PkScript)
+			if err == nil && !bytes.Equal(prev, next) {
+				err = ctx.TransferOwnership(*op, h.claimOwner(prev), h.claimOwner(next))
+			}
+		}
+		if err == nil {
+			err = ctx.SetOwner(*op, txscript.StripClaimScriptPrefix(txOut.PkScript))
 		}
 		if err != nil {
 			return errors.Wrapf(err, "handleTxOuts")
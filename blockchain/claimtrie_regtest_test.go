@@ -0,0 +1,333 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/database"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+// regtestHarness drives a regtest chain, backed by a ClaimTrie, mining the blocks
+// in process, with the coinbases paying to OP_TRUE so that they spend without signatures.
+type regtestHarness struct {
+	r      *require.Assertions
+	chain  *BlockChain
+	ct     *claimtrie.ClaimTrie
+	blocks []*btcutil.Block // By height, from 1.
+	nonce  int64            // Of the coinbases, telling apart the blocks of the forks.
+	start  time.Time
+}
+
+// newRegtestHarness returns a harness, of which the ClaimTrie is configured by the tunes, if any.
+func newRegtestHarness(t *testing.T, nonce int64, tunes ...func(cfg *config.Config)) *regtestHarness {
+
+	r := require.New(t)
+
+	dir := t.TempDir()
+	db, err := database.Create("ffldb", filepath.Join(dir, "blocks"), chaincfg.RegressionNetParams.Net)
+	r.NoError(err)
+	t.Cleanup(func() { db.Close() })
+
+	cfg := config.DefaultConfig
+	cfg.DataDir = filepath.Join(dir, "claimtrie")
+	for _, tune := range tunes {
+		tune(&cfg)
+	}
+	ct, err := claimtrie.New(cfg)
+	r.NoError(err)
+	t.Cleanup(func() { ct.Close() })
+
+	params := chaincfg.RegressionNetParams
+	chain, err := New(&Config{
+		DB:          db,
+		ChainParams: &params,
+		TimeSource:  NewMedianTime(),
+		SigCache:    txscript.NewSigCache(1000),
+		ClaimTrie:   ct,
+	})
+	r.NoError(err)
+
+	// The blocks are a minute apart, ending well before now, for the chain to be current.
+	return &regtestHarness{r: r, chain: chain, ct: ct, nonce: nonce, start: time.Now().Add(-6 * time.Hour)}
+}
+
+// coinbase returns the output of the coinbase of the block at the height.
+func (h *regtestHarness) coinbase(height int32) wire.OutPoint {
+	return wire.OutPoint{Hash: *h.blocks[height-1].Transactions()[0].Hash(), Index: 0}
+}
+
+// mine mines a block with the transactions on the tip, and connects it.
+func (h *regtestHarness) mine(txs ...*wire.MsgTx) *btcutil.Block {
+
+	b := h.template(txs...)
+	solve(h.r, &b.MsgBlock().Header)
+	h.process(b, true)
+	h.r.Equal(b.MsgBlock().Header.ClaimTrie, *h.ct.MerkleHash())
+	h.blocks = append(h.blocks, b)
+
+	return b
+}
+
+// template returns a block with the transactions on the tip, with the root previewed, but not solved.
+func (h *regtestHarness) template(txs ...*wire.MsgTx) *btcutil.Block {
+
+	tip := h.chain.BestSnapshot()
+	height := tip.Height + 1
+
+	sigScript, err := txscript.NewScriptBuilder().AddInt64(int64(height)).AddInt64(h.nonce).Script()
+	h.r.NoError(err)
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), sigScript, nil))
+	coinbase.AddTxOut(wire.NewTxOut(CalcBlockSubsidy(height, h.chain.chainParams), []byte{txscript.OP_TRUE}))
+
+	block := wire.MsgBlock{Transactions: append([]*wire.MsgTx{coinbase}, txs...)}
+	utxs := btcutil.NewBlock(&block).Transactions()
+	merkles := BuildMerkleTreeStore(utxs, false)
+
+	timestamp := h.start.Add(time.Duration(height) * time.Minute).Truncate(time.Second)
+	bits, err := h.chain.CalcNextRequiredDifficulty(timestamp)
+	h.r.NoError(err)
+	block.Header = wire.BlockHeader{
+		Version:    1,
+		PrevBlock:  tip.Hash,
+		MerkleRoot: *merkles[len(merkles)-1],
+		Timestamp:  timestamp,
+		Bits:       bits,
+	}
+
+	root, err := h.chain.PreviewClaimTrieRoot(btcutil.NewBlock(&block))
+	h.r.NoError(err)
+	block.Header.ClaimTrie = *root
+
+	return btcutil.NewBlock(&block)
+}
+
+// process connects a block, mined by another harness, and checks its root, if it
+// extends the main chain.
+func (h *regtestHarness) process(block *btcutil.Block, mainChain bool) {
+
+	isMainChain, isOrphan, err := h.chain.ProcessBlock(block, BFNone)
+	h.r.NoError(err)
+	h.r.False(isOrphan)
+	h.r.Equal(mainChain, isMainChain)
+	if mainChain {
+		h.r.Equal(block.MsgBlock().Header.ClaimTrie, *h.ct.MerkleHash())
+		h.r.Equal(h.chain.BestSnapshot().Height, h.ct.Height())
+	}
+}
+
+// resolve resolves the name as of the block, as the RPCs do.
+func (h *regtestHarness) resolve(name string, block *btcutil.Block) *claimtrie.Resolution {
+	res, err := h.chain.ResolveClaimName(context.Background(), []byte(name), block.Hash())
+	h.r.NoError(err)
+	return res
+}
+
+// prove proves the best claim of the name as of the block, and verifies the proof against its header.
+func (h *regtestHarness) prove(name string, block *btcutil.Block) *claimtrie.Resolution {
+	res, p, err := h.chain.ProveClaimName(context.Background(), []byte(name), block.Hash())
+	h.r.NoError(err)
+	root := block.MsgBlock().Header.ClaimTrie
+	h.r.True(p.Verify(&root, res.Name))
+	return res
+}
+
+func solve(r *require.Assertions, header *wire.BlockHeader) {
+	target := CompactToBig(header.Bits)
+	for nonce := uint32(0); ; nonce++ {
+		header.Nonce = nonce
+		hash := header.BlockPoWHash()
+		if HashToBig(&hash).Cmp(target) <= 0 {
+			return
+		}
+		r.NotEqual(^uint32(0), nonce, "no nonce solves the block")
+	}
+}
+
+// script returns the claim script built.
+func (h *regtestHarness) script(script []byte, err error) []byte {
+	h.r.NoError(err)
+	return script
+}
+
+// claimTx spends the output into a claim script, of which the amount is the output, less a fee.
+func claimTx(prev wire.OutPoint, amount int64, script []byte) *wire.MsgTx {
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(wire.NewTxIn(&prev, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(amount, script))
+	return tx
+}
+
+func outPointOf(tx *wire.MsgTx) wire.OutPoint {
+	return wire.OutPoint{Hash: tx.TxHash(), Index: 0}
+}
+
+// TestClaimTrieRegtest mines claims, updates and supports on a regtest chain, and
+// checks the roots, resolutions and proofs of the blocks, across a reorg.
+func TestClaimTrieRegtest(t *testing.T) {
+
+	param.SetNetwork(wire.TestNet)
+	defer param.SetNetwork(wire.TestNet)
+
+	h := newRegtestHarness(t, 0)
+	r := h.r
+
+	maturity := int32(h.chain.chainParams.CoinbaseMaturity)
+	for i := int32(0); i <= maturity; i++ {
+		h.mine()
+	}
+	shared := len(h.blocks)
+
+	claim := claimTx(h.coinbase(1), 100, h.script(txscript.ClaimNameScript("test", "one")))
+	b1 := h.mine(claim)
+	id := node.NewClaimID(outPointOf(claim))
+
+	res := h.prove("test", b1)
+	r.Equal(id, res.Node.BestClaim.ClaimID)
+	r.Equal(b1.Height(), res.Node.TakenOverAt)
+
+	// The update keeps the ID, and the support adds to its effective amount.
+	update := claimTx(outPointOf(claim), 90, h.script(txscript.UpdateClaimScript("test", id[:], "two")))
+	support := claimTx(h.coinbase(2), 50, h.script(txscript.SupportClaimScript("test", id[:], nil)))
+	b2 := h.mine(update, support)
+
+	res = h.prove("test", b2)
+	r.Equal(id, res.Node.BestClaim.ClaimID)
+	r.Equal(outPointOf(update), res.Node.BestClaim.OutPoint)
+	r.Equal(int64(140), res.Node.EffectiveAmount(res.Node.BestClaim))
+	r.Equal([]byte("two"), res.Node.BestClaim.Value)
+
+	// The earlier block still resolves as of its own root.
+	res = h.resolve("test", b1)
+	r.Equal(outPointOf(claim), res.Node.BestClaim.OutPoint)
+
+	// A longer fork, of another harness sharing the blocks up to the claims, takes the name over.
+	fork := newRegtestHarness(t, 1)
+	fork.start = h.start
+	for _, b := range h.blocks[:shared] {
+		fork.process(b, true)
+		fork.blocks = append(fork.blocks, b)
+	}
+	other := claimTx(fork.coinbase(1), 200, fork.script(txscript.ClaimNameScript("test", "three")))
+	f1 := fork.mine(other)
+	f2 := fork.mine()
+	f3 := fork.mine()
+	otherID := node.NewClaimID(outPointOf(other))
+
+	h.process(f1, false)
+	h.process(f2, false)
+	h.process(f3, true)
+	r.Equal(*f3.Hash(), h.chain.BestSnapshot().Hash)
+
+	res = h.prove("test", f3)
+	r.Equal(otherID, res.Node.BestClaim.ClaimID)
+	r.Equal(f1.Height(), res.Node.TakenOverAt)
+	r.Len(res.Node.Claims, 1)
+
+	// The blocks disconnected no longer resolve, being out of the main chain.
+	_, err := h.chain.ResolveClaimName(context.Background(), []byte("test"), b2.Hash())
+	r.Error(err)
+
+	// The chain, reorganized, and the fork, built on directly, agree.
+	r.Equal(*fork.ct.MerkleHash(), *h.ct.MerkleHash())
+}
+
+// TestClaimTrieRootVerified ensures a block of which the root doesn't match the ClaimTrie
+// is rejected, and rolled back from it, once the roots are verified.
+func TestClaimTrieRootVerified(t *testing.T) {
+
+	param.SetNetwork(wire.TestNet)
+	defer param.SetNetwork(wire.TestNet)
+
+	h := newRegtestHarness(t, 0)
+	r := h.r
+	h.chain.verifyClaimTrieRoots = true
+
+	maturity := int32(h.chain.chainParams.CoinbaseMaturity)
+	for i := int32(0); i <= maturity; i++ {
+		h.mine()
+	}
+	root := *h.ct.MerkleHash()
+
+	claim := claimTx(h.coinbase(1), 100, h.script(txscript.ClaimNameScript("test", "one")))
+	bad := h.template(claim)
+	bad.MsgBlock().Header.ClaimTrie = chainhash.Hash{1}
+	solve(r, &bad.MsgBlock().Header)
+
+	_, _, err := h.chain.ProcessBlock(btcutil.NewBlock(bad.MsgBlock()), BFNone)
+	var rerr RuleError
+	r.ErrorAs(err, &rerr)
+	r.Equal(ErrBadClaimTrie, rerr.ErrorCode)
+	r.Equal(maturity+1, h.ct.Height())
+	r.Equal(root, *h.ct.MerkleHash())
+	r.Equal(maturity+1, h.chain.BestSnapshot().Height)
+
+	// The block with the root expected connects in its place.
+	good := h.mine(claim)
+	res := h.resolve("test", good)
+	r.Equal(node.NewClaimID(outPointOf(claim)), res.Node.BestClaim.ClaimID)
+}
+
+// TestClaimTrieAppendFailure ensures a block, which the ClaimTrie fails to append on a storage error after
+// reaching its height, isn't rejected as invalid, and leaves the ClaimTrie before it, so it connects later.
+func TestClaimTrieAppendFailure(t *testing.T) {
+
+	param.SetNetwork(wire.TestNet)
+	defer param.SetNetwork(wire.TestNet)
+
+	maturity := int32(chaincfg.RegressionNetParams.CoinbaseMaturity)
+	var checkpoints string
+	h := newRegtestHarness(t, 0, func(cfg *config.Config) {
+		cfg.TrieCheckpointInterval = maturity + 2 // only the block failing
+		checkpoints = filepath.Join(cfg.DataDir, cfg.TrieCheckpointPath)
+	})
+	r := h.r
+
+	for i := int32(0); i <= maturity; i++ {
+		h.mine()
+	}
+	root := *h.ct.MerkleHash()
+
+	claim := claimTx(h.coinbase(1), 100, h.script(txscript.ClaimNameScript("test", "one")))
+	block := h.template(claim)
+	solve(r, &block.MsgBlock().Header)
+
+	// The checkpoint of the trie, the last write of the block, fails on a file in the place of its dir.
+	r.NoError(os.WriteFile(checkpoints, nil, 0600))
+	_, _, err := h.chain.ProcessBlock(block, BFNone)
+	r.Error(err)
+	var rerr RuleError
+	r.False(errors.As(err, &rerr))
+	r.Equal(maturity+1, h.ct.Height())
+	r.Equal(root, *h.ct.MerkleHash())
+	r.Equal(maturity+1, h.chain.BestSnapshot().Height)
+
+	// Once the storage recovers, the next block, of another harness connecting the block, connects both.
+	r.NoError(os.Remove(checkpoints))
+	other := newRegtestHarness(t, 0)
+	other.start = h.start
+	for _, b := range append(h.blocks, block) {
+		other.process(b, true)
+		other.blocks = append(other.blocks, b)
+	}
+	next := other.mine()
+	h.process(next, true)
+	r.Equal(*next.Hash(), h.chain.BestSnapshot().Hash)
+	res := h.resolve("test", block)
+	r.Equal(node.NewClaimID(outPointOf(claim)), res.Node.BestClaim.ClaimID)
+}
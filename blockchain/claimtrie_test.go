@@ -0,0 +1,251 @@
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/event"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/claimtrie/policy"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCrossNameUpdate ensures an update under a different name than the one
+// of the spent claim is ignored prior to the fork, unless strictly, and rejected after it.
+func TestCrossNameUpdate(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	defer param.SetNetwork(wire.TestNet)
+
+	cfg := config.DefaultConfig
+	cfg.DataDir = t.TempDir()
+	ct, err := claimtrie.New(cfg)
+	r.NoError(err)
+	defer func() {
+		r.NoError(ct.Close())
+	}()
+
+	script, err := txscript.ClaimNameScript("one", "value")
+	r.NoError(err)
+	claimTx := wire.NewMsgTx(1)
+	claimTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	claimTx.AddTxOut(wire.NewTxOut(10, script))
+	claim := btcutil.NewTx(claimTx)
+
+	view := NewUtxoViewpoint()
+	view.AddTxOuts(claim, 1)
+
+	op := wire.NewOutPoint(claim.Hash(), 0)
+	id := node.NewClaimID(*op)
+	script, err = txscript.UpdateClaimScript("two", id[:], "value")
+	r.NoError(err)
+	updateTx := wire.NewMsgTx(1)
+	updateTx.AddTxIn(wire.NewTxIn(op, nil, nil))
+	updateTx.AddTxOut(wire.NewTxOut(10, script))
+
+	handle := func(ht int32, strict bool) error {
+		h := handler{ht, btcutil.NewTx(updateTx), view, &chaincfg.TestNet3Params, map[string][]byte{}, map[string][]byte{}, strict}
+		ctx := ct.Begin(ht)
+		defer ctx.Abort()
+		err := h.handleTxIns(ctx)
+		r.NoError(err)
+		return h.handleTxOuts(ctx)
+	}
+
+	param.InvalidUpdateForkHeight = 2
+	r.NoError(handle(1, false))
+
+	for _, err = range []error{handle(1, true), handle(2, false)} {
+		r.Error(err)
+		rerr, ok := err.(RuleError)
+		r.True(ok)
+		r.Equal(ErrBadClaimUpdate, rerr.ErrorCode)
+	}
+	r.Equal(ErrBadClaimUpdate, claimTrieRuleError(err).(RuleError).ErrorCode)
+}
+
+// TestFutureClaimScript ensures a script of the NOPs left after the claim opcodes, which is
+// consensus-valid, isn't taken for a future claim script, which the strict claim scripts reject.
+func TestFutureClaimScript(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	defer param.SetNetwork(wire.TestNet)
+
+	cfg := config.DefaultConfig
+	cfg.DataDir = t.TempDir()
+	ct, err := claimtrie.New(cfg)
+	r.NoError(err)
+	defer func() {
+		r.NoError(ct.Close())
+	}()
+
+	script, err := txscript.NewScriptBuilder().AddOp(txscript.OP_NOP9).AddData([]byte("one")).
+		AddData([]byte("value")).AddOp(txscript.OP_2DROP).AddOp(txscript.OP_DROP).AddOp(txscript.OP_TRUE).Script()
+	r.NoError(err)
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(10, script))
+
+	handle := func(strict bool) error {
+		h := handler{1, btcutil.NewTx(tx), NewUtxoViewpoint(), &chaincfg.TestNet3Params, map[string][]byte{},
+			map[string][]byte{}, strict}
+		ctx := ct.Begin(1)
+		defer ctx.Abort()
+		return h.handleTxOuts(ctx)
+	}
+
+	r.NoError(handle(false))
+	r.NoError(handle(true))
+}
+
+// TestOwnershipTransferred ensures an update paying the claim to another address than
+// the one spent emits an OwnershipTransferred event, and one paying the same doesn't.
+func TestOwnershipTransferred(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	defer param.SetNetwork(wire.TestNet)
+
+	cfg := config.DefaultConfig
+	cfg.DataDir = t.TempDir()
+	ct, err := claimtrie.New(cfg)
+	r.NoError(err)
+	defer func() {
+		r.NoError(ct.Close())
+	}()
+
+	var events []event.Event
+	defer ct.Subscribe(func(e event.Event) {
+		if e.Type == event.OwnershipTransferred {
+			events = append(events, e)
+		}
+	})()
+
+	// payTo replaces the OP_TRUE destination of the claim script with a payment to the address.
+	payTo := func(script []byte, b byte) ([]byte, string) {
+		addr, err := btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{b}, 20), &chaincfg.TestNet3Params)
+		r.NoError(err)
+		pkScript, err := txscript.PayToAddrScript(addr)
+		r.NoError(err)
+		return append(script[:len(script)-1:len(script)-1], pkScript...), addr.EncodeAddress()
+	}
+
+	script, err := txscript.ClaimNameScript("@one", "value")
+	r.NoError(err)
+	script, owner := payTo(script, 1)
+	claimTx := wire.NewMsgTx(1)
+	claimTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	claimTx.AddTxOut(wire.NewTxOut(10, script))
+	claim := btcutil.NewTx(claimTx)
+
+	view := NewUtxoViewpoint()
+	view.AddTxOuts(claim, 1)
+
+	op := wire.NewOutPoint(claim.Hash(), 0)
+	id := node.NewClaimID(*op)
+	r.NoError(ct.AddClaim([]byte("@one"), *op, id, 10, []byte("value")))
+	r.NoError(ct.AppendBlock())
+
+	update := func(b byte) (*btcutil.Tx, string) {
+		script, err := txscript.UpdateClaimScript("@one", id[:], "value")
+		r.NoError(err)
+		script, owner := payTo(script, b)
+		tx := wire.NewMsgTx(1)
+		tx.AddTxIn(wire.NewTxIn(op, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(10, script))
+
+		h := handler{ct.Height() + 1, btcutil.NewTx(tx), view, &chaincfg.TestNet3Params, map[string][]byte{}, map[string][]byte{}, false}
+		ctx := ct.Begin(ct.Height() + 1)
+		r.NoError(h.handleTxIns(ctx))
+		r.NoError(h.handleTxOuts(ctx))
+		r.NoError(ctx.Commit())
+		r.NoError(ct.AppendBlock())
+
+		view.AddTxOuts(h.tx, ct.Height())
+		op = wire.NewOutPoint(h.tx.Hash(), 0)
+		return h.tx, owner
+	}
+
+	update(1)
+	r.Len(events, 0)
+
+	_, newOwner := update(2)
+	r.Len(events, 1)
+	r.Equal(int32(3), events[0].Height)
+	r.Equal([]byte("@one"), events[0].Name)
+	r.Equal(id.String(), events[0].ClaimID)
+	r.Equal(op.String(), events[0].OutPoint)
+	r.Equal(owner, events[0].PrevOwner)
+	r.Equal(newOwner, events[0].Owner)
+	r.Equal(id.String(), events[0].Channel)
+}
+
+func TestValidateClaimOps(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	defer param.SetNetwork(wire.TestNet)
+
+	script, err := txscript.ClaimNameScript("one", "value")
+	r.NoError(err)
+	claimTx := wire.NewMsgTx(1)
+	claimTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	claimTx.AddTxOut(wire.NewTxOut(10, script))
+	claim := btcutil.NewTx(claimTx)
+
+	view := NewUtxoViewpoint()
+	view.AddTxOuts(claim, 1)
+
+	op := wire.NewOutPoint(claim.Hash(), 0)
+	id := node.NewClaimID(*op)
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(wire.NewTxIn(op, nil, nil))
+	for _, name := range []string{"two", "one"} {
+		script, err = txscript.UpdateClaimScript(name, id[:], "value")
+		r.NoError(err)
+		tx.AddTxOut(wire.NewTxOut(10, script))
+	}
+	script, err = txscript.SupportClaimScript("one", id[:], nil)
+	r.NoError(err)
+	tx.AddTxOut(wire.NewTxOut(1, script))
+
+	param.InvalidUpdateForkHeight = 2
+	ops, err := ValidateClaimOps(btcutil.NewTx(tx), view, 2, policy.DefaultPolicy)
+	r.NoError(err)
+	r.Len(ops, 4)
+
+	r.True(ops[0].Spend)
+	r.Equal(byte(txscript.OP_CLAIMNAME), ops[0].Opcode)
+	r.Equal(id, ops[0].ClaimID)
+	r.NoError(ops[0].Err)
+
+	// The update under the other name doesn't spend the claim, but the following one does.
+	var rerr RuleError
+	r.ErrorAs(ops[1].Err, &rerr)
+	r.Equal(ErrBadClaimUpdate, rerr.ErrorCode)
+	r.Equal([]byte("two"), ops[1].Name)
+	r.NoError(ops[2].Err)
+	r.Equal(id, ops[2].ClaimID)
+
+	r.ErrorIs(ops[3].Err, policy.ErrDustSupport)
+
+	missing := wire.NewMsgTx(1)
+	missing.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{2}, 0), nil, nil))
+	_, err = ValidateClaimOps(btcutil.NewTx(missing), view, 2, policy.DefaultPolicy)
+	r.Error(err)
+}
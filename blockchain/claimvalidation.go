@@ -0,0 +1,102 @@
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/claimtrie/policy"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// ClaimOp is a claim operation of a transaction, and why it wouldn't be accepted, if so.
+type ClaimOp struct {
+	Spend   bool // Spent by an input, instead of added by an output.
+	Index   int  // Of the input, or the output.
+	Opcode  byte
+	Name    []byte
+	ClaimID node.ClaimID
+	Amount  int64
+	Err     error
+}
+
+// ValidateClaimOps returns the claim operations of the transaction, checked against the
+// consensus rules of a block at the height, and the relay policy, without applying them.
+// The view must hold the outputs spent by the transaction.
+func ValidateClaimOps(tx *btcutil.Tx, view *UtxoViewpoint, height int32, p policy.Policy) ([]ClaimOp, error) {
+
+	var ops []ClaimOp
+
+	// Names are normalized as of the trie prior to the block, as in handleTxIns.
+	spent := map[node.ClaimID][]byte{}
+	if !IsCoinBase(tx) {
+		for i, txIn := range tx.MsgTx().TxIn {
+			e := view.LookupEntry(txIn.PreviousOutPoint)
+			if e == nil || e.IsSpent() {
+				return nil, fmt.Errorf("missing input in view for %s", txIn.PreviousOutPoint)
+			}
+			cs, err := txscript.DecodeClaimScript(e.pkScript)
+			if err == txscript.ErrNotClaimScript {
+				continue
+			}
+			op := ClaimOp{Spend: true, Index: i, Amount: e.Amount(), Err: err}
+			if err == nil {
+				op.Opcode, op.Name = cs.Opcode(), cs.Name()
+				switch cs.Opcode() {
+				case txscript.OP_CLAIMNAME:
+					op.ClaimID = node.NewClaimID(txIn.PreviousOutPoint)
+					spent[op.ClaimID] = node.NormalizeIfNecessary(cs.Name(), height-1)
+				case txscript.OP_UPDATECLAIM:
+					copy(op.ClaimID[:], cs.ClaimID())
+					spent[op.ClaimID] = node.NormalizeIfNecessary(cs.Name(), height-1)
+				case txscript.OP_SUPPORTCLAIM:
+					copy(op.ClaimID[:], cs.ClaimID())
+				}
+			}
+			ops = append(ops, op)
+		}
+	}
+
+	for i, txOut := range tx.MsgTx().TxOut {
+		cs, err := txscript.DecodeClaimScript(txOut.PkScript)
+		if err == txscript.ErrNotClaimScript {
+			continue
+		}
+		op := ClaimOp{Index: i, Amount: txOut.Value, Err: err}
+		if err != nil {
+			ops = append(ops, op)
+			continue
+		}
+		op.Opcode, op.Name = cs.Opcode(), cs.Name()
+		switch cs.Opcode() {
+		case txscript.OP_CLAIMNAME:
+			op.ClaimID = node.NewClaimID(*wire.NewOutPoint(tx.Hash(), uint32(i)))
+		case txscript.OP_UPDATECLAIM, txscript.OP_SUPPORTCLAIM:
+			copy(op.ClaimID[:], cs.ClaimID())
+		}
+
+		op.Err = p.CheckScript(txOut.PkScript, txOut.Value)
+		if op.Err == nil && height >= param.MaxClaimValueSizeForkHeight && len(cs.Value()) > param.MaxClaimValueSize {
+			op.Err = fmt.Errorf("claim value is too large: %d > %d bytes", len(cs.Value()), param.MaxClaimValueSize)
+		}
+		if op.Err == nil && cs.Opcode() == txscript.OP_UPDATECLAIM {
+			normName := node.NormalizeIfNecessary(cs.Name(), height-1)
+			if !bytes.Equal(spent[op.ClaimID], normName) {
+				str := fmt.Sprintf("update of claim %s under name %s doesn't spend it", op.ClaimID, normName)
+				if height >= param.InvalidUpdateForkHeight {
+					op.Err = ruleError(ErrBadClaimUpdate, str)
+				} else {
+					op.Err = fmt.Errorf("ignored %s", str)
+				}
+			} else {
+				delete(spent, op.ClaimID)
+			}
+		}
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
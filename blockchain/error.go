@@ -224,6 +224,14 @@ const (
 	// ErrBadClaimTrie indicates the calculated ClaimTrie root does not match
 	// the expected value.
 	ErrBadClaimTrie
+
+	// ErrBadClaimUpdate indicates a transaction updates a claim, which is
+	// either not spent by the same transaction, or is under a different name.
+	ErrBadClaimUpdate
+
+	// ErrFutureClaimScript indicates a transaction output has a claim script of
+	// a version after the one known, which the strict nodes reject.
+	ErrFutureClaimScript
 )
 
 // Map of ErrorCode values back to their constant names for pretty printing.
@@ -272,6 +280,8 @@ var errorCodeStrings = map[ErrorCode]string{
 	ErrInvalidAncestorBlock:      "ErrInvalidAncestorBlock",
 	ErrPrevBlockNotBest:          "ErrPrevBlockNotBest",
 	ErrBadClaimTrie:              "ErrBadClaimTrie",
+	ErrBadClaimUpdate:            "ErrBadClaimUpdate",
+	ErrFutureClaimScript:         "ErrFutureClaimScript",
 }
 
 // String returns the ErrorCode as a human-readable name.
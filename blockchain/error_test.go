@@ -58,6 +58,9 @@ func TestErrorCodeStringer(t *testing.T) {
 		{ErrPreviousBlockUnknown, "ErrPreviousBlockUnknown"},
 		{ErrInvalidAncestorBlock, "ErrInvalidAncestorBlock"},
 		{ErrPrevBlockNotBest, "ErrPrevBlockNotBest"},
+		{ErrBadClaimTrie, "ErrBadClaimTrie"},
+		{ErrBadClaimUpdate, "ErrBadClaimUpdate"},
+		{ErrFutureClaimScript, "ErrFutureClaimScript"},
 		{0xffff, "Unknown ErrorCode (65535)"},
 	}
 
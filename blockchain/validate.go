@@ -1277,6 +1277,8 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, vi
 // CheckConnectBlockTemplate fully validates that connecting the passed block to
 // the main chain does not violate any consensus rules, aside from the proof of
 // work requirement. The block must connect to the current tip of the main chain.
+// The root of the claim trie in its header is checked against the expected one,
+// as previewed by PreviewClaimTrieRoot.
 //
 // This function is safe for concurrent access.
 func (b *BlockChain) CheckConnectBlockTemplate(block *btcutil.Block) error {
@@ -1311,5 +1313,10 @@ func (b *BlockChain) CheckConnectBlockTemplate(block *btcutil.Block) error {
 	view := NewUtxoViewpoint()
 	view.SetBestHash(&tip.hash)
 	newNode := newBlockNode(&header, tip)
-	return b.checkConnectBlock(newNode, block, view, nil)
+	err = b.checkConnectBlock(newNode, block, view, nil)
+	if err != nil || b.claimTrie == nil {
+		return err
+	}
+
+	return b.checkClaimTrieRoot(block, tip)
 }
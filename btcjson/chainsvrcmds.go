@@ -48,6 +48,15 @@ func NewAddNodeCmd(addr string, subCmd AddNodeSubCmd) *AddNodeCmd {
 	}
 }
 
+// BackupClaimTrieCmd defines the backupclaimtrie JSON-RPC command.
+type BackupClaimTrieCmd struct{}
+
+// NewBackupClaimTrieCmd returns a new instance which can be used to issue a
+// backupclaimtrie JSON-RPC command.
+func NewBackupClaimTrieCmd() *BackupClaimTrieCmd {
+	return &BackupClaimTrieCmd{}
+}
+
 // TransactionInput represents the inputs to a transaction.  Specifically a
 // transaction hash and output number pair.
 type TransactionInput struct {
@@ -149,6 +158,22 @@ type FundRawTransactionOpts struct {
 	EstimateMode           *EstimateSmartFeeMode `json:"estimate_mode,omitempty"`
 }
 
+// ExportClaimsCmd defines the exportclaims JSON-RPC command.
+type ExportClaimsCmd struct {
+	Order *string `jsonrpcdefault:"\"claimid\""`
+}
+
+// NewExportClaimsCmd returns a new instance which can be used to issue an
+// exportclaims JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewExportClaimsCmd(order *string) *ExportClaimsCmd {
+	return &ExportClaimsCmd{
+		Order: order,
+	}
+}
+
 // FundRawTransactionCmd defines the fundrawtransaction JSON-RPC command
 type FundRawTransactionCmd struct {
 	HexTx     string
@@ -166,6 +191,22 @@ func NewFundRawTransactionCmd(serializedTx []byte, opts FundRawTransactionOpts,
 	}
 }
 
+// GetActiveForksCmd defines the getactiveforks JSON-RPC command.
+type GetActiveForksCmd struct {
+	Height *int32
+}
+
+// NewGetActiveForksCmd returns a new instance which can be used to issue a
+// getactiveforks JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetActiveForksCmd(height *int32) *GetActiveForksCmd {
+	return &GetActiveForksCmd{
+		Height: height,
+	}
+}
+
 // GetAddedNodeInfoCmd defines the getaddednodeinfo JSON-RPC command.
 type GetAddedNodeInfoCmd struct {
 	DNS  bool
@@ -269,6 +310,19 @@ func NewGetBlockHashCmd(index int64) *GetBlockHashCmd {
 	}
 }
 
+// GetBlockClaimRootCmd defines the getblockclaimroot JSON-RPC command.
+type GetBlockClaimRootCmd struct {
+	Hash string
+}
+
+// NewGetBlockClaimRootCmd returns a new instance which can be used to issue a
+// getblockclaimroot JSON-RPC command.
+func NewGetBlockClaimRootCmd(hash string) *GetBlockClaimRootCmd {
+	return &GetBlockClaimRootCmd{
+		Hash: hash,
+	}
+}
+
 // GetBlockHeaderCmd defines the getblockheader JSON-RPC command.
 type GetBlockHeaderCmd struct {
 	Hash    string
@@ -665,6 +719,22 @@ func NewGetRawTransactionCmd(txHash string, verbose *int) *GetRawTransactionCmd
 	}
 }
 
+// GetTopNamesCmd defines the gettopnames JSON-RPC command.
+type GetTopNamesCmd struct {
+	Count *int `jsonrpcdefault:"10"`
+}
+
+// NewGetTopNamesCmd returns a new instance which can be used to issue a
+// gettopnames JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetTopNamesCmd(count *int) *GetTopNamesCmd {
+	return &GetTopNamesCmd{
+		Count: count,
+	}
+}
+
 // GetTxOutCmd defines the gettxout JSON-RPC command.
 type GetTxOutCmd struct {
 	Txid           string
@@ -792,6 +862,64 @@ func NewReconsiderBlockCmd(blockHash string) *ReconsiderBlockCmd {
 	}
 }
 
+// GetNameProofCmd defines the getnameproof JSON-RPC command.
+type GetNameProofCmd struct {
+	Name      string
+	BlockHash *string
+}
+
+// NewGetNameProofCmd returns a new instance which can be used to issue a
+// getnameproof JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetNameProofCmd(name string, blockHash *string) *GetNameProofCmd {
+	return &GetNameProofCmd{
+		Name:      name,
+		BlockHash: blockHash,
+	}
+}
+
+// ResolveCmd defines the resolve JSON-RPC command.
+type ResolveCmd struct {
+	Name      string
+	BlockHash *string
+}
+
+// NewResolveCmd returns a new instance which can be used to issue a resolve
+// JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewResolveCmd(name string, blockHash *string) *ResolveCmd {
+	return &ResolveCmd{
+		Name:      name,
+		BlockHash: blockHash,
+	}
+}
+
+// SearchNamesCmd defines the searchnames JSON-RPC command.
+type SearchNamesCmd struct {
+	Query     string
+	Substring *bool `jsonrpcdefault:"false"`
+	After     *string
+	Count     *int `jsonrpcdefault:"10"`
+}
+
+// NewSearchNamesCmd returns a new instance which can be used to issue a
+// searchnames JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSearchNamesCmd(query string, substring *bool, after *string, count *int) *SearchNamesCmd {
+	return &SearchNamesCmd{
+		Query:     query,
+		Substring: substring,
+		After:     after,
+		Count:     count,
+	}
+}
+
 // SearchRawTransactionsCmd defines the searchrawtransactions JSON-RPC command.
 type SearchRawTransactionsCmd struct {
 	Address     string
@@ -981,6 +1109,19 @@ func NewUptimeCmd() *UptimeCmd {
 	return &UptimeCmd{}
 }
 
+// ValidateClaimTxCmd defines the validateclaimtx JSON-RPC command.
+type ValidateClaimTxCmd struct {
+	HexTx string
+}
+
+// NewValidateClaimTxCmd returns a new instance which can be used to issue a
+// validateclaimtx JSON-RPC command.
+func NewValidateClaimTxCmd(hexTx string) *ValidateClaimTxCmd {
+	return &ValidateClaimTxCmd{
+		HexTx: hexTx,
+	}
+}
+
 // ValidateAddressCmd defines the validateaddress JSON-RPC command.
 type ValidateAddressCmd struct {
 	Address string
@@ -1047,11 +1188,14 @@ func init() {
 	flags := UsageFlag(0)
 
 	MustRegisterCmd("addnode", (*AddNodeCmd)(nil), flags)
+	MustRegisterCmd("backupclaimtrie", (*BackupClaimTrieCmd)(nil), flags)
 	MustRegisterCmd("createrawtransaction", (*CreateRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("decoderawtransaction", (*DecodeRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("decodescript", (*DecodeScriptCmd)(nil), flags)
 	MustRegisterCmd("deriveaddresses", (*DeriveAddressesCmd)(nil), flags)
+	MustRegisterCmd("exportclaims", (*ExportClaimsCmd)(nil), flags)
 	MustRegisterCmd("fundrawtransaction", (*FundRawTransactionCmd)(nil), flags)
+	MustRegisterCmd("getactiveforks", (*GetActiveForksCmd)(nil), flags)
 	MustRegisterCmd("getaddednodeinfo", (*GetAddedNodeInfoCmd)(nil), flags)
 	MustRegisterCmd("getbestblockhash", (*GetBestBlockHashCmd)(nil), flags)
 	MustRegisterCmd("getblock", (*GetBlockCmd)(nil), flags)
@@ -1059,6 +1203,7 @@ func init() {
 	MustRegisterCmd("getblockcount", (*GetBlockCountCmd)(nil), flags)
 	MustRegisterCmd("getblockfilter", (*GetBlockFilterCmd)(nil), flags)
 	MustRegisterCmd("getblockhash", (*GetBlockHashCmd)(nil), flags)
+	MustRegisterCmd("getblockclaimroot", (*GetBlockClaimRootCmd)(nil), flags)
 	MustRegisterCmd("getblockheader", (*GetBlockHeaderCmd)(nil), flags)
 	MustRegisterCmd("getblockstats", (*GetBlockStatsCmd)(nil), flags)
 	MustRegisterCmd("getblocktemplate", (*GetBlockTemplateCmd)(nil), flags)
@@ -1075,6 +1220,7 @@ func init() {
 	MustRegisterCmd("getmempoolentry", (*GetMempoolEntryCmd)(nil), flags)
 	MustRegisterCmd("getmempoolinfo", (*GetMempoolInfoCmd)(nil), flags)
 	MustRegisterCmd("getmininginfo", (*GetMiningInfoCmd)(nil), flags)
+	MustRegisterCmd("getnameproof", (*GetNameProofCmd)(nil), flags)
 	MustRegisterCmd("getnetworkinfo", (*GetNetworkInfoCmd)(nil), flags)
 	MustRegisterCmd("getnettotals", (*GetNetTotalsCmd)(nil), flags)
 	MustRegisterCmd("getnetworkhashps", (*GetNetworkHashPSCmd)(nil), flags)
@@ -1082,6 +1228,7 @@ func init() {
 	MustRegisterCmd("getpeerinfo", (*GetPeerInfoCmd)(nil), flags)
 	MustRegisterCmd("getrawmempool", (*GetRawMempoolCmd)(nil), flags)
 	MustRegisterCmd("getrawtransaction", (*GetRawTransactionCmd)(nil), flags)
+	MustRegisterCmd("gettopnames", (*GetTopNamesCmd)(nil), flags)
 	MustRegisterCmd("gettxout", (*GetTxOutCmd)(nil), flags)
 	MustRegisterCmd("gettxoutproof", (*GetTxOutProofCmd)(nil), flags)
 	MustRegisterCmd("gettxoutsetinfo", (*GetTxOutSetInfoCmd)(nil), flags)
@@ -1091,6 +1238,8 @@ func init() {
 	MustRegisterCmd("ping", (*PingCmd)(nil), flags)
 	MustRegisterCmd("preciousblock", (*PreciousBlockCmd)(nil), flags)
 	MustRegisterCmd("reconsiderblock", (*ReconsiderBlockCmd)(nil), flags)
+	MustRegisterCmd("resolve", (*ResolveCmd)(nil), flags)
+	MustRegisterCmd("searchnames", (*SearchNamesCmd)(nil), flags)
 	MustRegisterCmd("searchrawtransactions", (*SearchRawTransactionsCmd)(nil), flags)
 	MustRegisterCmd("sendrawtransaction", (*SendRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("setgenerate", (*SetGenerateCmd)(nil), flags)
@@ -1099,6 +1248,7 @@ func init() {
 	MustRegisterCmd("submitblock", (*SubmitBlockCmd)(nil), flags)
 	MustRegisterCmd("uptime", (*UptimeCmd)(nil), flags)
 	MustRegisterCmd("validateaddress", (*ValidateAddressCmd)(nil), flags)
+	MustRegisterCmd("validateclaimtx", (*ValidateClaimTxCmd)(nil), flags)
 	MustRegisterCmd("verifychain", (*VerifyChainCmd)(nil), flags)
 	MustRegisterCmd("verifymessage", (*VerifyMessageCmd)(nil), flags)
 	MustRegisterCmd("verifytxoutproof", (*VerifyTxOutProofCmd)(nil), flags)
@@ -96,6 +96,43 @@ func TestChainSvrCmds(t *testing.T) {
 				LockTime: btcjson.Int64(12312333333),
 			},
 		},
+		{
+			name: "backupclaimtrie",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("backupclaimtrie")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewBackupClaimTrieCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"backupclaimtrie","params":[],"id":1}`,
+			unmarshalled: &btcjson.BackupClaimTrieCmd{},
+		},
+		{
+			name: "exportclaims",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("exportclaims")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewExportClaimsCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"exportclaims","params":[],"id":1}`,
+			unmarshalled: &btcjson.ExportClaimsCmd{
+				Order: btcjson.String("claimid"),
+			},
+		},
+		{
+			name: "exportclaims optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("exportclaims", "name")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewExportClaimsCmd(btcjson.String("name"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"exportclaims","params":["name"],"id":1}`,
+			unmarshalled: &btcjson.ExportClaimsCmd{
+				Order: btcjson.String("name"),
+			},
+		},
 		{
 			name: "fundrawtransaction - empty opts",
 			newCmd: func() (i interface{}, e error) {
@@ -265,6 +302,32 @@ func TestChainSvrCmds(t *testing.T) {
 				Range:      &btcjson.DescriptorRange{Value: []int{0, 2}},
 			},
 		},
+		{
+			name: "getactiveforks",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getactiveforks")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetActiveForksCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getactiveforks","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetActiveForksCmd{
+				Height: nil,
+			},
+		},
+		{
+			name: "getactiveforks optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getactiveforks", 123)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetActiveForksCmd(btcjson.Int32(123))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getactiveforks","params":[123],"id":1}`,
+			unmarshalled: &btcjson.GetActiveForksCmd{
+				Height: btcjson.Int32(123),
+			},
+		},
 		{
 			name: "getaddednodeinfo",
 			newCmd: func() (interface{}, error) {
@@ -412,6 +475,19 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getblockhash","params":[123],"id":1}`,
 			unmarshalled: &btcjson.GetBlockHashCmd{Index: 123},
 		},
+		{
+			name: "getblockclaimroot",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblockclaimroot", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetBlockClaimRootCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockclaimroot","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.GetBlockClaimRootCmd{
+				Hash: "123",
+			},
+		},
 		{
 			name: "getblockheader",
 			newCmd: func() (interface{}, error) {
@@ -889,6 +965,32 @@ func TestChainSvrCmds(t *testing.T) {
 				Verbose: btcjson.Int(1),
 			},
 		},
+		{
+			name: "gettopnames",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("gettopnames")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetTopNamesCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"gettopnames","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetTopNamesCmd{
+				Count: btcjson.Int(10),
+			},
+		},
+		{
+			name: "gettopnames optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("gettopnames", 100)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetTopNamesCmd(btcjson.Int(100))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"gettopnames","params":[100],"id":1}`,
+			unmarshalled: &btcjson.GetTopNamesCmd{
+				Count: btcjson.Int(100),
+			},
+		},
 		{
 			name: "gettxout",
 			newCmd: func() (interface{}, error) {
@@ -1062,6 +1164,80 @@ func TestChainSvrCmds(t *testing.T) {
 				BlockHash: "123",
 			},
 		},
+		{
+			name: "getnameproof",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getnameproof", "test", btcjson.String("123"))
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetNameProofCmd("test", btcjson.String("123"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getnameproof","params":["test","123"],"id":1}`,
+			unmarshalled: &btcjson.GetNameProofCmd{
+				Name:      "test",
+				BlockHash: btcjson.String("123"),
+			},
+		},
+		{
+			name: "resolve",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("resolve", "test")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewResolveCmd("test", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"resolve","params":["test"],"id":1}`,
+			unmarshalled: &btcjson.ResolveCmd{
+				Name:      "test",
+				BlockHash: nil,
+			},
+		},
+		{
+			name: "resolve optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("resolve", "test", btcjson.String("123"))
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewResolveCmd("test", btcjson.String("123"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"resolve","params":["test","123"],"id":1}`,
+			unmarshalled: &btcjson.ResolveCmd{
+				Name:      "test",
+				BlockHash: btcjson.String("123"),
+			},
+		},
+		{
+			name: "searchnames",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("searchnames", "cat")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSearchNamesCmd("cat", nil, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"searchnames","params":["cat"],"id":1}`,
+			unmarshalled: &btcjson.SearchNamesCmd{
+				Query:     "cat",
+				Substring: btcjson.Bool(false),
+				After:     nil,
+				Count:     btcjson.Int(10),
+			},
+		},
+		{
+			name: "searchnames optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("searchnames", "cat", true, "bobcat", 20)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSearchNamesCmd("cat", btcjson.Bool(true), btcjson.String("bobcat"), btcjson.Int(20))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"searchnames","params":["cat",true,"bobcat",20],"id":1}`,
+			unmarshalled: &btcjson.SearchNamesCmd{
+				Query:     "cat",
+				Substring: btcjson.Bool(true),
+				After:     btcjson.String("bobcat"),
+				Count:     btcjson.Int(20),
+			},
+		},
 		{
 			name: "searchrawtransactions",
 			newCmd: func() (interface{}, error) {
@@ -1379,6 +1555,19 @@ func TestChainSvrCmds(t *testing.T) {
 				Address: "1Address",
 			},
 		},
+		{
+			name: "validateclaimtx",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("validateclaimtx", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewValidateClaimTxCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"validateclaimtx","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.ValidateClaimTxCmd{
+				HexTx: "123",
+			},
+		},
 		{
 			name: "verifychain",
 			newCmd: func() (interface{}, error) {
@@ -34,6 +34,89 @@ type GetBlockHeaderVerboseResult struct {
 	NextHash      string  `json:"nextblockhash,omitempty"`
 }
 
+// GetActiveForksResult models a fork in the data from the getactiveforks
+// command.
+type GetActiveForksResult struct {
+	Name        string `json:"name"`
+	Height      int32  `json:"height"`
+	Description string `json:"description"`
+}
+
+// BackupClaimTrieResult models the data from the backupclaimtrie command.
+type BackupClaimTrieResult struct {
+	Path   string   `json:"path"`
+	Height int32    `json:"height"`
+	Root   string   `json:"root"`
+	Repos  []string `json:"repos"`
+}
+
+// ExportClaimsResult models the data from the exportclaims command.
+type ExportClaimsResult struct {
+	Path   string `json:"path"`
+	Order  string `json:"order"`
+	Height int32  `json:"height"`
+	Root   string `json:"root"`
+	Rows   int    `json:"rows"`
+}
+
+// GetBlockClaimRootResult models the data from the getblockclaimroot command.
+type GetBlockClaimRootResult struct {
+	Hash      string `json:"hash"`
+	Height    int32  `json:"height"`
+	ClaimTrie string `json:"claimtrie"`
+}
+
+// ResolvedClaim models a claim of a name in the resolve command.
+type ResolvedClaim struct {
+	ClaimID         string `json:"claimid"`
+	OutPoint        string `json:"outpoint"`
+	Amount          int64  `json:"amount"`
+	EffectiveAmount int64  `json:"effectiveamount"`
+	AcceptedAt      int32  `json:"acceptedat"`
+	ActiveAt        int32  `json:"activeat"`
+	Value           string `json:"value"`
+	Channel         string `json:"channel,omitempty"`
+
+	Confirmations      int32  `json:"confirmations"`
+	Status             string `json:"status"`
+	BlocksUntilActive  int32  `json:"blocksuntilactive"`
+	ExpiresAt          int32  `json:"expiresat"`
+	BlocksUntilExpired int32  `json:"blocksuntilexpired"`
+}
+
+// ResolveResult models the data from the resolve command.
+type ResolveResult struct {
+	Name        string          `json:"name"`
+	Hash        string          `json:"hash"`
+	Height      int32           `json:"height"`
+	ClaimTrie   string          `json:"claimtrie"`
+	BestClaim   *ResolvedClaim  `json:"bestclaim,omitempty"`
+	TakenOverAt int32           `json:"takenoverat"`
+	Claims      []ResolvedClaim `json:"claims"`
+	TotalClaims int             `json:"totalclaims"`
+	Truncated   bool            `json:"truncated"` // Claims holds the first of TotalClaims only.
+}
+
+// NameProofChild models a child of a node on the path of the getnameproof command.
+type NameProofChild struct {
+	Character byte   `json:"character"`
+	NodeHash  string `json:"nodeHash,omitempty"` // Unset for the child on the path.
+}
+
+// NameProofNode models a node on the path of the getnameproof command.
+type NameProofNode struct {
+	Children  []NameProofChild `json:"children,omitempty"`
+	ValueHash string           `json:"valueHash,omitempty"`
+}
+
+// GetNameProofResult models the data from the getnameproof command, in the layout of lbrycrd's.
+type GetNameProofResult struct {
+	Nodes              []NameProofNode `json:"nodes"`
+	TxHash             string          `json:"txhash"`
+	NOut               uint32          `json:"nOut"`
+	LastTakeoverHeight int32           `json:"last takeover height"`
+}
+
 // GetBlockStatsResult models the data from the getblockstats command.
 type GetBlockStatsResult struct {
 	AverageFee         int64   `json:"avgfee"`
@@ -267,6 +350,7 @@ type GetBlockTemplateResult struct {
 	CurTime       int64                      `json:"curtime"`
 	Height        int64                      `json:"height"`
 	PreviousHash  string                     `json:"previousblockhash"`
+	ClaimTrie     string                     `json:"claimtrie"`
 	SigOpLimit    int64                      `json:"sigoplimit,omitempty"`
 	SizeLimit     int64                      `json:"sizelimit,omitempty"`
 	WeightLimit   int64                      `json:"weightlimit,omitempty"`
@@ -433,6 +517,14 @@ type ScriptPubKeyResult struct {
 	Addresses []string `json:"addresses,omitempty"`
 }
 
+// GetTopNamesResult models a name in the data from the gettopnames command.
+type GetTopNamesResult struct {
+	Name            string `json:"name"`
+	ClaimID         string `json:"claimid"`
+	EffectiveAmount int64  `json:"effectiveamount"`
+	TakenOverAt     int32  `json:"takenoverat"`
+}
+
 // GetTxOutResult models the data from the gettxout command.
 type GetTxOutResult struct {
 	BestBlock     string             `json:"bestblock"`
@@ -752,6 +844,26 @@ type TxRawDecodeResult struct {
 	Vout     []Vout `json:"vout"`
 }
 
+// ValidateClaimTxResult models the data from the validateclaimtx command.
+type ValidateClaimTxResult struct {
+	Txid       string          `json:"txid"`
+	Accepted   bool            `json:"accepted"`
+	Operations []ClaimOpResult `json:"operations"`
+}
+
+// ClaimOpResult models a claim operation of a transaction in the
+// validateclaimtx command.
+type ClaimOpResult struct {
+	Op       string `json:"op"`
+	Spend    bool   `json:"spend"`
+	Index    int    `json:"index"`
+	Name     string `json:"name"`
+	ClaimID  string `json:"claimid"`
+	Amount   int64  `json:"amount"`
+	Accepted bool   `json:"accepted"`
+	Reason   string `json:"reason,omitempty"`
+}
+
 // ValidateAddressChainResult models the data returned by the chain server
 // validateaddress command.
 //
@@ -221,6 +221,42 @@ func NewRescanBlocksCmd(blockHashes []string) *RescanBlocksCmd {
 	return &RescanBlocksCmd{BlockHashes: blockHashes}
 }
 
+// NotifyClaimNamesCmd defines the notifyclaimnames JSON-RPC command.
+type NotifyClaimNamesCmd struct {
+	Names    []string
+	Channels *[]string
+}
+
+// NewNotifyClaimNamesCmd returns a new instance which can be used to issue a
+// notifyclaimnames JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewNotifyClaimNamesCmd(names []string, channels *[]string) *NotifyClaimNamesCmd {
+	return &NotifyClaimNamesCmd{
+		Names:    names,
+		Channels: channels,
+	}
+}
+
+// StopNotifyClaimNamesCmd defines the stopnotifyclaimnames JSON-RPC command.
+type StopNotifyClaimNamesCmd struct {
+	Names    []string
+	Channels *[]string
+}
+
+// NewStopNotifyClaimNamesCmd returns a new instance which can be used to issue
+// a stopnotifyclaimnames JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewStopNotifyClaimNamesCmd(names []string, channels *[]string) *StopNotifyClaimNamesCmd {
+	return &StopNotifyClaimNamesCmd{
+		Names:    names,
+		Channels: channels,
+	}
+}
+
 func init() {
 	// The commands in this file are only usable by websockets.
 	flags := UFWebsocketOnly
@@ -228,11 +264,13 @@ func init() {
 	MustRegisterCmd("authenticate", (*AuthenticateCmd)(nil), flags)
 	MustRegisterCmd("loadtxfilter", (*LoadTxFilterCmd)(nil), flags)
 	MustRegisterCmd("notifyblocks", (*NotifyBlocksCmd)(nil), flags)
+	MustRegisterCmd("notifyclaimnames", (*NotifyClaimNamesCmd)(nil), flags)
 	MustRegisterCmd("notifynewtransactions", (*NotifyNewTransactionsCmd)(nil), flags)
 	MustRegisterCmd("notifyreceived", (*NotifyReceivedCmd)(nil), flags)
 	MustRegisterCmd("notifyspent", (*NotifySpentCmd)(nil), flags)
 	MustRegisterCmd("session", (*SessionCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyblocks", (*StopNotifyBlocksCmd)(nil), flags)
+	MustRegisterCmd("stopnotifyclaimnames", (*StopNotifyClaimNamesCmd)(nil), flags)
 	MustRegisterCmd("stopnotifynewtransactions", (*StopNotifyNewTransactionsCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyspent", (*StopNotifySpentCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyreceived", (*StopNotifyReceivedCmd)(nil), flags)
@@ -126,6 +126,47 @@ func TestChainSvrWsCmds(t *testing.T) {
 				Addresses: []string{"1Address"},
 			},
 		},
+		{
+			name: "notifyclaimnames",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifyclaimnames", []string{"test"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyClaimNamesCmd([]string{"test"}, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"notifyclaimnames","params":[["test"]],"id":1}`,
+			unmarshalled: &btcjson.NotifyClaimNamesCmd{
+				Names: []string{"test"},
+			},
+		},
+		{
+			name: "notifyclaimnames channels",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifyclaimnames", []string{}, []string{"123"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyClaimNamesCmd([]string{}, &[]string{"123"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"notifyclaimnames","params":[[],["123"]],"id":1}`,
+			unmarshalled: &btcjson.NotifyClaimNamesCmd{
+				Names:    []string{},
+				Channels: &[]string{"123"},
+			},
+		},
+		{
+			name: "stopnotifyclaimnames",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifyclaimnames", []string{"test"}, []string{"123"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyClaimNamesCmd([]string{"test"}, &[]string{"123"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"stopnotifyclaimnames","params":[["test"],["123"]],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyClaimNamesCmd{
+				Names:    []string{"test"},
+				Channels: &[]string{"123"},
+			},
+		},
 		{
 			name: "notifyspent",
 			newCmd: func() (interface{}, error) {
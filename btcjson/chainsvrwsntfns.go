@@ -75,6 +75,12 @@ const (
 	// from the chain server that inform a client that a transaction that
 	// matches the loaded filter was accepted by the mempool.
 	RelevantTxAcceptedNtfnMethod = "relevanttxaccepted"
+
+	// ClaimNameChangedNtfnMethod is the method used for notifications from
+	// the chain server that the best claim of a registered name, or of a
+	// name of a registered channel, its effective amount, or its value has
+	// changed in a newly-attached block.
+	ClaimNameChangedNtfnMethod = "claimnamechanged"
 )
 
 // BlockConnectedNtfn defines the blockconnected JSON-RPC notification.
@@ -285,6 +291,33 @@ func NewRelevantTxAcceptedNtfn(txHex string) *RelevantTxAcceptedNtfn {
 	return &RelevantTxAcceptedNtfn{Transaction: txHex}
 }
 
+// ClaimNameWinner describes the best claim of a name.
+type ClaimNameWinner struct {
+	ClaimID         string `json:"claimid"`
+	OutPoint        string `json:"outpoint"`
+	Amount          int64  `json:"amount"`
+	EffectiveAmount int64  `json:"effectiveamount"`
+	Value           string `json:"value"`
+	Channel         string `json:"channel,omitempty"`
+}
+
+// ClaimNameChangedNtfn defines the claimnamechanged JSON-RPC notification.
+type ClaimNameChangedNtfn struct {
+	Height int32
+	Name   string
+	Winner *ClaimNameWinner // nil if the name has no claims.
+}
+
+// NewClaimNameChangedNtfn returns a new instance which can be used to issue a
+// claimnamechanged JSON-RPC notification.
+func NewClaimNameChangedNtfn(height int32, name string, winner *ClaimNameWinner) *ClaimNameChangedNtfn {
+	return &ClaimNameChangedNtfn{
+		Height: height,
+		Name:   name,
+		Winner: winner,
+	}
+}
+
 func init() {
 	// The commands in this file are only usable by websockets and are
 	// notifications.
@@ -301,4 +334,5 @@ func init() {
 	MustRegisterCmd(TxAcceptedNtfnMethod, (*TxAcceptedNtfn)(nil), flags)
 	MustRegisterCmd(TxAcceptedVerboseNtfnMethod, (*TxAcceptedVerboseNtfn)(nil), flags)
 	MustRegisterCmd(RelevantTxAcceptedNtfnMethod, (*RelevantTxAcceptedNtfn)(nil), flags)
+	MustRegisterCmd(ClaimNameChangedNtfnMethod, (*ClaimNameChangedNtfn)(nil), flags)
 }
@@ -225,6 +225,22 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				Transaction: "001122",
 			},
 		},
+		{
+			name: "claimnamechanged",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("claimnamechanged", 100000, "test", `{"claimid":"123","outpoint":"456:1","amount":10,"effectiveamount":15,"value":"00"}`)
+			},
+			staticNtfn: func() interface{} {
+				winner := &btcjson.ClaimNameWinner{ClaimID: "123", OutPoint: "456:1", Amount: 10, EffectiveAmount: 15, Value: "00"}
+				return btcjson.NewClaimNameChangedNtfn(100000, "test", winner)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"claimnamechanged","params":[100000,"test",{"claimid":"123","outpoint":"456:1","amount":10,"effectiveamount":15,"value":"00"}],"id":null}`,
+			unmarshalled: &btcjson.ClaimNameChangedNtfn{
+				Height: 100000,
+				Name:   "test",
+				Winner: &btcjson.ClaimNameWinner{ClaimID: "123", OutPoint: "456:1", Amount: 10, EffectiveAmount: 15, Value: "00"},
+			},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -0,0 +1,66 @@
+// NOTE: This file is intended to house the RPC commands that are supported by
+// the claimtrie server of the claimtrie tool (claimtrie serve).
+
+package btcjson
+
+// ResolveNameCmd defines the resolvename JSON-RPC command.
+type ResolveNameCmd struct {
+	Name string
+}
+
+// NewResolveNameCmd returns a new instance which can be used to issue a
+// resolvename JSON-RPC command.
+func NewResolveNameCmd(name string) *ResolveNameCmd {
+	return &ResolveNameCmd{
+		Name: name,
+	}
+}
+
+// GetClaimsForNameCmd defines the getclaimsforname JSON-RPC command.
+type GetClaimsForNameCmd struct {
+	Name string
+}
+
+// NewGetClaimsForNameCmd returns a new instance which can be used to issue a
+// getclaimsforname JSON-RPC command.
+func NewGetClaimsForNameCmd(name string) *GetClaimsForNameCmd {
+	return &GetClaimsForNameCmd{
+		Name: name,
+	}
+}
+
+// GetValueForNameCmd defines the getvalueforname JSON-RPC command.
+type GetValueForNameCmd struct {
+	Name string
+}
+
+// NewGetValueForNameCmd returns a new instance which can be used to issue a
+// getvalueforname JSON-RPC command.
+func NewGetValueForNameCmd(name string) *GetValueForNameCmd {
+	return &GetValueForNameCmd{
+		Name: name,
+	}
+}
+
+// GetClaimByIDCmd defines the getclaimbyid JSON-RPC command.
+type GetClaimByIDCmd struct {
+	ClaimID string
+}
+
+// NewGetClaimByIDCmd returns a new instance which can be used to issue a
+// getclaimbyid JSON-RPC command.
+func NewGetClaimByIDCmd(claimID string) *GetClaimByIDCmd {
+	return &GetClaimByIDCmd{
+		ClaimID: claimID,
+	}
+}
+
+func init() {
+	// No special flags for commands in this file.
+	flags := UsageFlag(0)
+
+	MustRegisterCmd("getclaimbyid", (*GetClaimByIDCmd)(nil), flags)
+	MustRegisterCmd("getclaimsforname", (*GetClaimsForNameCmd)(nil), flags)
+	MustRegisterCmd("getvalueforname", (*GetValueForNameCmd)(nil), flags)
+	MustRegisterCmd("resolvename", (*ResolveNameCmd)(nil), flags)
+}
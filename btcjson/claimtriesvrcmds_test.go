@@ -0,0 +1,145 @@
+package btcjson_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+)
+
+// TestClaimTrieSvrCmds tests all of the claimtrie server commands marshal and
+// unmarshal into valid results.
+func TestClaimTrieSvrCmds(t *testing.T) {
+	t.Parallel()
+
+	testID := int(1)
+	tests := []struct {
+		name         string
+		newCmd       func() (interface{}, error)
+		staticCmd    func() interface{}
+		marshalled   string
+		unmarshalled interface{}
+	}{
+		{
+			name: "getclaimbyid",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getclaimbyid", "beef")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetClaimByIDCmd("beef")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getclaimbyid","params":["beef"],"id":1}`,
+			unmarshalled: &btcjson.GetClaimByIDCmd{
+				ClaimID: "beef",
+			},
+		},
+		{
+			name: "getclaimsforname",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getclaimsforname", "test")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetClaimsForNameCmd("test")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getclaimsforname","params":["test"],"id":1}`,
+			unmarshalled: &btcjson.GetClaimsForNameCmd{
+				Name: "test",
+			},
+		},
+		{
+			name: "getvalueforname",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getvalueforname", "test")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetValueForNameCmd("test")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getvalueforname","params":["test"],"id":1}`,
+			unmarshalled: &btcjson.GetValueForNameCmd{
+				Name: "test",
+			},
+		},
+		{
+			name: "resolvename",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("resolvename", "test")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewResolveNameCmd("test")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"resolvename","params":["test"],"id":1}`,
+			unmarshalled: &btcjson.ResolveNameCmd{
+				Name: "test",
+			},
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Marshal the command as created by the new static command
+		// creation function.
+		marshalled, err := btcjson.MarshalCmd(btcjson.RpcVersion1, testID, test.staticCmd())
+		if err != nil {
+			t.Errorf("MarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !bytes.Equal(marshalled, []byte(test.marshalled)) {
+			t.Errorf("Test #%d (%s) unexpected marshalled data - "+
+				"got %s, want %s", i, test.name, marshalled,
+				test.marshalled)
+			continue
+		}
+
+		// Ensure the command is created without error via the generic
+		// new command creation function.
+		cmd, err := test.newCmd()
+		if err != nil {
+			t.Errorf("Test #%d (%s) unexpected NewCmd error: %v ",
+				i, test.name, err)
+		}
+
+		// Marshal the command as created by the generic new command
+		// creation function.
+		marshalled, err = btcjson.MarshalCmd(btcjson.RpcVersion1, testID, cmd)
+		if err != nil {
+			t.Errorf("MarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !bytes.Equal(marshalled, []byte(test.marshalled)) {
+			t.Errorf("Test #%d (%s) unexpected marshalled data - "+
+				"got %s, want %s", i, test.name, marshalled,
+				test.marshalled)
+			continue
+		}
+
+		var request btcjson.Request
+		if err := json.Unmarshal(marshalled, &request); err != nil {
+			t.Errorf("Test #%d (%s) unexpected error while "+
+				"unmarshalling JSON-RPC request: %v", i,
+				test.name, err)
+			continue
+		}
+
+		cmd, err = btcjson.UnmarshalCmd(&request)
+		if err != nil {
+			t.Errorf("UnmarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(cmd, test.unmarshalled) {
+			t.Errorf("Test #%d (%s) unexpected unmarshalled command "+
+				"- got %s, want %s", i, test.name,
+				fmt.Sprintf("(%T) %+[1]v", cmd),
+				fmt.Sprintf("(%T) %+[1]v\n", test.unmarshalled))
+			continue
+		}
+	}
+}
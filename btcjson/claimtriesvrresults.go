@@ -0,0 +1,42 @@
+package btcjson
+
+// NameSupport models a support of a name in the getclaimsforname command.
+type NameSupport struct {
+	ClaimID    string `json:"claimid"`
+	OutPoint   string `json:"outpoint"`
+	Amount     int64  `json:"amount"`
+	AcceptedAt int32  `json:"acceptedat"`
+	ActiveAt   int32  `json:"activeat"`
+	Status     string `json:"status"`
+}
+
+// GetClaimsForNameResult models the data from the getclaimsforname command.
+type GetClaimsForNameResult struct {
+	Name        string          `json:"name"`
+	Height      int32           `json:"height"`
+	ClaimTrie   string          `json:"claimtrie"`
+	TakenOverAt int32           `json:"takenoverat"`
+	Claims      []ResolvedClaim `json:"claims"`
+	Supports    []NameSupport   `json:"supports"`
+}
+
+// GetValueForNameResult models the data from the getvalueforname command.
+type GetValueForNameResult struct {
+	Name            string `json:"name"`
+	Height          int32  `json:"height"`
+	ClaimTrie       string `json:"claimtrie"`
+	ClaimID         string `json:"claimid"`
+	OutPoint        string `json:"outpoint"`
+	Amount          int64  `json:"amount"`
+	EffectiveAmount int64  `json:"effectiveamount"`
+	TakenOverAt     int32  `json:"takenoverat"`
+	Value           string `json:"value"`
+}
+
+// GetClaimByIDResult models the data from the getclaimbyid command.
+type GetClaimByIDResult struct {
+	Name      string        `json:"name"`
+	Height    int32         `json:"height"`
+	ClaimTrie string        `json:"claimtrie"`
+	Claim     ResolvedClaim `json:"claim"`
+}
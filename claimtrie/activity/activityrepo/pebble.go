@@ -0,0 +1,351 @@
+package activityrepo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/btcsuite/btcd/claimtrie/activity"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Key formats:
+//
+//	'a' + len(2B) + name + height(4B): the heights the name was active at.
+//	'n' + name: the first seen(4B) and last active(4B) heights of the name.
+//	'f' + height(4B) + name: the names by the height first seen at.
+//	'l' + height(4B) + name: the names by the height last active at.
+//	'c' + len(2B) + name + height(4B): whether the name became claimed(1B), or unclaimed, at the height.
+const (
+	activityPrefix  = 'a'
+	namePrefix      = 'n'
+	firstSeenPrefix = 'f'
+	lastPrefix      = 'l'
+	claimedPrefix   = 'c'
+)
+
+type Pebble struct {
+	db *pebble.DB
+}
+
+func NewPebble(path string) (*Pebble, error) {
+
+	db, err := pebble.Open(path, &pebble.Options{Cache: pebble.NewCache(16 << 20)})
+	if err != nil {
+		return nil, fmt.Errorf("pebble open %s, %w", path, err)
+	}
+
+	repo := &Pebble{db: db}
+
+	return repo, nil
+}
+
+func activityKey(name []byte, height int32) []byte {
+	return nameHeightKey(activityPrefix, name, height)
+}
+
+func claimedKey(name []byte, height int32) []byte {
+	return nameHeightKey(claimedPrefix, name, height)
+}
+
+func nameHeightKey(prefix byte, name []byte, height int32) []byte {
+	key := make([]byte, 3+len(name)+4)
+	key[0] = prefix
+	binary.BigEndian.PutUint16(key[1:], uint16(len(name)))
+	copy(key[3:], name)
+	binary.BigEndian.PutUint32(key[3+len(name):], uint32(height))
+	return key
+}
+
+func heightKey(prefix byte, height int32, name []byte) []byte {
+	key := make([]byte, 5, 5+len(name))
+	key[0] = prefix
+	binary.BigEndian.PutUint32(key[1:], uint32(height))
+	return append(key, name...)
+}
+
+func nameKey(name []byte) []byte {
+	return append([]byte{namePrefix}, name...)
+}
+
+// heights returns the first seen and last active heights of the name, if any.
+func heights(r pebble.Reader, name []byte) (int32, int32, bool, error) {
+
+	b, closer, err := r.Get(nameKey(name))
+	if err == pebble.ErrNotFound {
+		return 0, 0, false, nil
+	}
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("pebble get: %w", err)
+	}
+	defer closer.Close()
+
+	first := int32(binary.BigEndian.Uint32(b))
+	last := int32(binary.BigEndian.Uint32(b[4:]))
+	return first, last, true, nil
+}
+
+// setHeights replaces the heights of the name, and their index entries, in the batch.
+func setHeights(batch *pebble.Batch, name []byte, first, last int32) error {
+
+	oldFirst, oldLast, ok, err := heights(batch, name)
+	if err != nil {
+		return err
+	}
+	if ok {
+		err = batch.Delete(heightKey(firstSeenPrefix, oldFirst, name), pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble delete: %w", err)
+		}
+		err = batch.Delete(heightKey(lastPrefix, oldLast, name), pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble delete: %w", err)
+		}
+	}
+	if first < 0 {
+		return batch.Delete(nameKey(name), pebble.NoSync)
+	}
+
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint32(value, uint32(first))
+	binary.BigEndian.PutUint32(value[4:], uint32(last))
+	err = batch.Set(nameKey(name), value, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble set: %w", err)
+	}
+	err = batch.Set(heightKey(firstSeenPrefix, first, name), nil, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble set: %w", err)
+	}
+	return batch.Set(heightKey(lastPrefix, last, name), nil, pebble.NoSync)
+}
+
+func (repo *Pebble) SetActiveAt(names [][]byte, height int32) error {
+
+	batch := repo.db.NewIndexedBatch()
+	defer batch.Close()
+
+	for _, name := range names {
+		first, _, ok, err := heights(batch, name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			first = height
+		}
+		err = setHeights(batch, name, first, height)
+		if err != nil {
+			return err
+		}
+		err = batch.Set(activityKey(name, height), nil, pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble set: %w", err)
+		}
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+func (repo *Pebble) Rewind(names [][]byte, height int32) error {
+
+	batch := repo.db.NewIndexedBatch()
+	defer batch.Close()
+
+	for _, name := range names {
+		lower := activityKey(name, 0)
+		upper := activityKey(name, -1) // sorts after all the heights
+		err := batch.DeleteRange(activityKey(name, height+1), upper, pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble delete range: %w", err)
+		}
+		err = batch.DeleteRange(claimedKey(name, height+1), claimedKey(name, -1), pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble delete range: %w", err)
+		}
+
+		first, last := int32(-1), int32(-1)
+		iter := batch.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+		if iter.First() {
+			first = int32(binary.BigEndian.Uint32(iter.Key()[len(iter.Key())-4:]))
+		}
+		if iter.Last() {
+			last = int32(binary.BigEndian.Uint32(iter.Key()[len(iter.Key())-4:]))
+		}
+		err = iter.Close()
+		if err != nil {
+			return fmt.Errorf("pebble iter: %w", err)
+		}
+
+		err = setHeights(batch, name, first, last)
+		if err != nil {
+			return err
+		}
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+// lastTransition returns whether the name was claimed as of its last transition, if any.
+func lastTransition(r pebble.Reader, name []byte) (bool, bool, error) {
+
+	iter := r.NewIter(&pebble.IterOptions{LowerBound: claimedKey(name, 0), UpperBound: claimedKey(name, -1)})
+	found, claimed := iter.Last(), false
+	if found {
+		claimed = iter.Value()[0] == 1
+	}
+	err := iter.Close()
+	if err != nil {
+		return false, false, fmt.Errorf("pebble iter: %w", err)
+	}
+
+	return claimed, found, nil
+}
+
+func (repo *Pebble) SetClaimedAt(names [][]byte, claimed []bool, height int32) error {
+
+	batch := repo.db.NewIndexedBatch()
+	defer batch.Close()
+
+	for i, name := range names {
+		last, ok, err := lastTransition(batch, name)
+		if err != nil {
+			return err
+		}
+		if (ok && last == claimed[i]) || (!ok && !claimed[i]) {
+			continue
+		}
+		value := []byte{0}
+		if claimed[i] {
+			value[0] = 1
+		}
+		err = batch.Set(claimedKey(name, height), value, pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble set: %w", err)
+		}
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+func (repo *Pebble) Transitions(name []byte) ([]activity.Transition, error) {
+
+	iter := repo.db.NewIter(&pebble.IterOptions{LowerBound: claimedKey(name, 0), UpperBound: claimedKey(name, -1)})
+
+	var transitions []activity.Transition
+	for iter.First(); iter.Valid(); iter.Next() {
+		transitions = append(transitions, activity.Transition{
+			Height:  int32(binary.BigEndian.Uint32(iter.Key()[len(iter.Key())-4:])),
+			Claimed: iter.Value()[0] == 1,
+		})
+	}
+
+	err := iter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("pebble iter: %w", err)
+	}
+
+	return transitions, nil
+}
+
+func (repo *Pebble) Counts() (activity.Counts, error) {
+
+	iter := repo.db.NewIter(&pebble.IterOptions{LowerBound: []byte{claimedPrefix}, UpperBound: []byte{claimedPrefix + 1}})
+
+	// The transitions of each name are in order by height, and start with it becoming claimed.
+	var counts activity.Counts
+	var name []byte
+	started, claimed := false, false
+	for iter.First(); iter.Valid(); iter.Next() {
+		key := iter.Key()
+		current := key[3 : len(key)-4]
+		first := !started || !bytes.Equal(current, name)
+		if first {
+			if started && !claimed {
+				counts.Unclaimed++
+			}
+			name = append(name[:0], current...)
+			started = true
+		}
+		claimed = iter.Value()[0] == 1
+		if !claimed {
+			counts.Abandonments++
+		} else if !first {
+			counts.Reclaims++
+		}
+	}
+	if started && !claimed {
+		counts.Unclaimed++
+	}
+
+	err := iter.Close()
+	if err != nil {
+		return activity.Counts{}, fmt.Errorf("pebble iter: %w", err)
+	}
+
+	return counts, nil
+}
+
+// namesBetween returns the names of the index within the heights, exclusive of to.
+func (repo *Pebble) namesBetween(prefix byte, from, to int32) ([][]byte, error) {
+
+	iter := repo.db.NewIter(&pebble.IterOptions{
+		LowerBound: heightKey(prefix, from, nil),
+		UpperBound: heightKey(prefix, to, nil),
+	})
+
+	var names [][]byte
+	for iter.First(); iter.Valid(); iter.Next() {
+		name := make([]byte, len(iter.Key())-5)
+		copy(name, iter.Key()[5:]) // iter.Key() reuses its buffer
+		names = append(names, name)
+	}
+
+	err := iter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("pebble iter: %w", err)
+	}
+
+	return names, nil
+}
+
+func (repo *Pebble) NamesCreatedBetween(from, to int32) ([][]byte, error) {
+	if to < from {
+		return nil, nil
+	}
+	if to == math.MaxInt32 {
+		to-- // heights don't get there
+	}
+	return repo.namesBetween(firstSeenPrefix, from, to+1)
+}
+
+func (repo *Pebble) InactiveSince(height int32) ([][]byte, error) {
+	return repo.namesBetween(lastPrefix, 0, height)
+}
+
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Pebble) Backup(dir string) error {
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Pebble) Close() error {
+
+	err := repo.db.Flush()
+	if err != nil {
+		return fmt.Errorf("pebble flush: %w", err)
+	}
+
+	err = repo.db.Close()
+	if err != nil {
+		return fmt.Errorf("pebble close: %w", err)
+	}
+
+	return nil
+}
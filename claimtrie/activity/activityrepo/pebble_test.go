@@ -0,0 +1,87 @@
+package activityrepo
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/claimtrie/activity"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivity(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	a, ab, b := []byte("a"), []byte("ab"), []byte("b")
+	r.NoError(repo.SetActiveAt([][]byte{a, ab}, 1))
+	r.NoError(repo.SetActiveAt([][]byte{b}, 3))
+	r.NoError(repo.SetActiveAt([][]byte{a, a}, 5))
+
+	names, err := repo.NamesCreatedBetween(1, 2)
+	r.NoError(err)
+	r.Equal([][]byte{a, ab}, names)
+	names, err = repo.NamesCreatedBetween(2, 3)
+	r.NoError(err)
+	r.Equal([][]byte{b}, names)
+
+	names, err = repo.InactiveSince(5)
+	r.NoError(err)
+	r.Equal([][]byte{ab, b}, names)
+	names, err = repo.InactiveSince(3)
+	r.NoError(err)
+	r.Equal([][]byte{ab}, names)
+
+	// The previous activity is restored on rewind.
+	r.NoError(repo.Rewind([][]byte{a, b}, 2))
+	names, err = repo.InactiveSince(2)
+	r.NoError(err)
+	r.Equal([][]byte{a, ab}, names)
+	names, err = repo.NamesCreatedBetween(0, 10)
+	r.NoError(err)
+	r.Equal([][]byte{a, ab}, names)
+}
+
+func TestTransitions(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	// The names never claimed aren't unclaimed, and the ones claimed already aren't claimed again.
+	a, b, c := []byte("a"), []byte("b"), []byte("c")
+	r.NoError(repo.SetClaimedAt([][]byte{a, b, c}, []bool{true, true, false}, 1))
+	r.NoError(repo.SetClaimedAt([][]byte{a, b}, []bool{true, false}, 2))
+	r.NoError(repo.SetClaimedAt([][]byte{a, b}, []bool{false, true}, 3))
+
+	transitions, err := repo.Transitions(a)
+	r.NoError(err)
+	r.Equal([]activity.Transition{{Height: 1, Claimed: true}, {Height: 3}}, transitions)
+	transitions, err = repo.Transitions(c)
+	r.NoError(err)
+	r.Empty(transitions)
+
+	counts, err := repo.Counts()
+	r.NoError(err)
+	r.Equal(activity.Counts{Abandonments: 2, Reclaims: 1, Unclaimed: 1}, counts)
+
+	// The transitions after the height are dropped on rewind.
+	r.NoError(repo.Rewind([][]byte{a, b}, 2))
+	transitions, err = repo.Transitions(b)
+	r.NoError(err)
+	r.Equal([]activity.Transition{{Height: 1, Claimed: true}, {Height: 2}}, transitions)
+	counts, err = repo.Counts()
+	r.NoError(err)
+	r.Equal(activity.Counts{Abandonments: 1, Unclaimed: 1}, counts)
+}
@@ -0,0 +1,38 @@
+package activity
+
+// Repo defines APIs for the index of names by the heights they were first seen,
+// and last active at, to access persistence layer.
+type Repo interface {
+	// SetActiveAt records the activity of the names at height.
+	SetActiveAt(names [][]byte, height int32) error
+	// Rewind drops the activity of the names after height.
+	Rewind(names [][]byte, height int32) error
+
+	// NamesCreatedBetween returns the names first seen within the heights, inclusive, in order by height, then name.
+	NamesCreatedBetween(from, to int32) ([][]byte, error)
+	// InactiveSince returns the names without any activity at or after height, in order by height, then name.
+	InactiveSince(height int32) ([][]byte, error)
+
+	// SetClaimedAt records the names becoming claimed, or unclaimed, at height, as per claimed, unless they
+	// already are. The names never claimed aren't recorded as unclaimed.
+	SetClaimedAt(names [][]byte, claimed []bool, height int32) error
+	// Transitions returns the heights the name became claimed, or unclaimed, at, in order.
+	Transitions(name []byte) ([]Transition, error)
+	// Counts returns the aggregate counts of the transitions of all the names.
+	Counts() (Counts, error)
+
+	Close() error
+}
+
+// Transition is a name becoming claimed, once it has a claim, or unclaimed, once all of its claims are spent, or expired.
+type Transition struct {
+	Height  int32
+	Claimed bool
+}
+
+// Counts are the aggregate counts of the transitions of the names.
+type Counts struct {
+	Abandonments int64 // The names becoming unclaimed.
+	Reclaims     int64 // The names becoming claimed again, after being unclaimed.
+	Unclaimed    int64 // The names unclaimed as of the last transition, after being claimed.
+}
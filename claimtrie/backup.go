@@ -0,0 +1,220 @@
+package claimtrie
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/param"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// backupFile is written to a backup last, with its height and root, so a backup without it is incomplete.
+const backupFile = "claimtrie_backup"
+
+// backupRepo is a repo, which writes a consistent copy of itself, as of its last write, to a dir.
+type backupRepo interface {
+	Backup(dir string) error
+}
+
+// sharedBackup backs up the repo shared by the block, node and trie repos at once.
+type sharedBackup struct {
+	db *pebble.DB
+}
+
+func (b sharedBackup) Backup(dir string) error {
+	err := b.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+	return nil
+}
+
+// BackupHeader is the summary of a backup of the ClaimTrie.
+type BackupHeader struct {
+	Height int32
+	Root   chainhash.Hash
+	Repos  []string // the paths of the repos backed up, relative to the data dir.
+}
+
+// Backup writes a consistent copy of the repos of the ClaimTrie, as of the last block appended, to the dir, which
+// must not exist. It's the same as the repos left by a crash after the block, so the ones batched across the blocks
+// are committed first. The ClaimTrie keeps running meanwhile, but the changes to it wait for the copy, which hard
+// links the files of the repos, where it can. RestoreBackup copies them back to a data dir.
+func (ct *ClaimTrie) Backup(dir string) (*BackupHeader, error) {
+
+	defer ct.holdChanges()()
+
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("backup dir %s exists already", dir)
+	}
+
+	if ct.batching && ct.txn != nil {
+		err := ct.commitBatch()
+		if err != nil {
+			return nil, err
+		}
+		ct.txn = ct.txns.Begin()
+	}
+
+	h := &BackupHeader{Height: ct.height, Root: *merkletrie.EmptyTrieHash}
+	if ct.root != nil {
+		h.Root = *ct.root
+	}
+	for path := range ct.backups {
+		h.Repos = append(h.Repos, path)
+	}
+	sort.Strings(h.Repos)
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("make backup dir: %w", err)
+	}
+	for _, path := range h.Repos {
+		err = ct.backups[path].Backup(filepath.Join(dir, path))
+		if err != nil {
+			return nil, fmt.Errorf("backup %s: %w", path, err)
+		}
+	}
+
+	summary := fmt.Sprintf("height=%d root=%s repos=%s\n", h.Height, h.Root, strings.Join(h.Repos, ","))
+	err = os.WriteFile(filepath.Join(dir, backupFile), []byte(summary), 0644)
+	if err != nil {
+		return nil, fmt.Errorf("write backup summary: %w", err)
+	}
+
+	return h, nil
+}
+
+// ReadBackupHeader reads the summary of the backup in the dir, which fails, if the backup is incomplete.
+func ReadBackupHeader(dir string) (*BackupHeader, error) {
+
+	b, err := os.ReadFile(filepath.Join(dir, backupFile))
+	if err != nil {
+		return nil, fmt.Errorf("read backup summary: %w", err)
+	}
+
+	h := &BackupHeader{}
+	var root, repos string
+	_, err = fmt.Sscanf(string(b), "height=%d root=%s repos=%s\n", &h.Height, &root, &repos)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup summary: %q: %w", strings.TrimSpace(string(b)), err)
+	}
+	hash, err := chainhash.NewHashFromStr(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup root: %w", err)
+	}
+	h.Root = *hash
+	h.Repos = strings.Split(repos, ",")
+	for _, path := range h.Repos {
+		if path == "" || filepath.IsAbs(path) || strings.HasPrefix(filepath.Clean(path), "..") {
+			return nil, fmt.Errorf("invalid backup repo path: %q", path)
+		}
+	}
+
+	return h, nil
+}
+
+// RestoreBackup copies the repos of the backup in the dir, written by Backup, to cfg.DataDir, which mustn't hold
+// any of them, and opens the ClaimTrie on them, which must be at the height of the backup, and of which the trie
+// must resolve from its root, with the value hashes of the nodes.
+func RestoreBackup(cfg config.Config, dir string) (*BackupHeader, error) {
+
+	h, err := ReadBackupHeader(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range h.Repos {
+		if _, err := os.Stat(filepath.Join(cfg.DataDir, path)); !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("repo %s exists already in the data dir", path)
+		}
+	}
+	for _, path := range h.Repos {
+		err = copyBackupTree(filepath.Join(dir, path), filepath.Join(cfg.DataDir, path))
+		if err != nil {
+			return nil, fmt.Errorf("restore %s: %w", path, err)
+		}
+	}
+
+	ct, err := New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("create claimtrie: %w", err)
+	}
+	err = ct.verifyBackup(h)
+	cerr := ct.Close()
+	if err != nil {
+		return nil, err
+	}
+	if cerr != nil {
+		return nil, fmt.Errorf("close claimtrie: %w", cerr)
+	}
+
+	return h, nil
+}
+
+// verifyBackup checks the ClaimTrie restored is at the height, and root, of the backup, and its trie resolves.
+func (ct *ClaimTrie) verifyBackup(h *BackupHeader) error {
+
+	if ct.height != h.Height {
+		return fmt.Errorf("restored at height %d, instead of %d", ct.height, h.Height)
+	}
+	root := merkletrie.EmptyTrieHash
+	if ct.root != nil {
+		root = ct.root
+	}
+	if *root != h.Root {
+		return fmt.Errorf("restored at root %s, instead of %s", root, h.Root)
+	}
+
+	res := ct.merkleTrie.At(root).Check(ct.height >= param.AllClaimsInMerkleForkHeight, func([]byte) {})
+	if len(res.Missing) > 0 || len(res.Corrupt) > 0 || len(res.Mismatched) > 0 {
+		return fmt.Errorf("the trie at root %s has %d missing, %d corrupt, and %d mismatched nodes",
+			root, len(res.Missing), len(res.Corrupt), len(res.Mismatched))
+	}
+
+	return nil
+}
+
+func copyBackupTree(src, dst string) error {
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, in)
+		if serr := out.Sync(); err == nil {
+			err = serr
+		}
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	})
+}
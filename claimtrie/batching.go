@@ -0,0 +1,75 @@
+package claimtrie
+
+import (
+	"fmt"
+	"time"
+)
+
+// txnJournalFile is the journal of the txn committing the batches, in the data dir.
+const txnJournalFile = "claimtrie_txn_journal"
+
+// SetBatching batches the writes of the block, trie and node snapshot repos across the blocks, while it's
+// enabled, as they're written much faster at once during the initial block download. They're committed every
+// BatchBlocks blocks, or once over BatchBytes, and when it's disabled. It's a no-op, unless BatchBlocks is set.
+// The batches are committed in a txn, so after a crash, the ClaimTrie resumes at the last block committed.
+func (ct *ClaimTrie) SetBatching(enabled bool) error {
+
+	if ct.batchBlocks <= 0 || enabled == ct.batching {
+		return nil
+	}
+
+	ct.batching = enabled
+	if enabled {
+		log.Infof("Batching the ClaimTrie writes every %d blocks from %d", ct.batchBlocks, ct.height)
+		ct.txn = ct.txns.Begin()
+		return nil
+	}
+
+	log.Infof("Stopped batching the ClaimTrie writes at %d", ct.height)
+	return ct.commitBatch()
+}
+
+// batchBlock counts the block appended while batching, and commits the batch, if it's due.
+func (ct *ClaimTrie) batchBlock() error {
+
+	if !ct.batching {
+		return nil
+	}
+
+	ct.batched++
+	if ct.batched < ct.batchBlocks && (ct.batchBytes <= 0 || ct.batchSize() < ct.batchBytes) {
+		return nil
+	}
+
+	err := ct.commitBatch()
+	if err != nil {
+		return err
+	}
+	ct.txn = ct.txns.Begin()
+
+	return nil
+}
+
+func (ct *ClaimTrie) batchSize() int64 {
+	if ct.txn == nil {
+		return 0
+	}
+	return ct.txn.Size()
+}
+
+// commitBatch commits the txn of the writes batched by the repos.
+func (ct *ClaimTrie) commitBatch() error {
+
+	start := time.Now()
+	size := ct.batchSize()
+	err := ct.txn.Commit()
+	if err != nil {
+		return fmt.Errorf("commit batch: %w", err)
+	}
+	log.Debugf("Committed the ClaimTrie writes of %d blocks up to %d, %d bytes, in %s",
+		ct.batched, ct.height, size, time.Since(start))
+	ct.batched = 0
+	ct.txn = nil
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package blockrepo
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// ErrClosed is returned by the Memory repo once it's closed.
+var ErrClosed = errors.New("repo closed")
+
+// Memory keeps the roots of the blocks in memory, for the tests and regtest runs.
+type Memory struct {
+	mu     sync.RWMutex
+	hashes map[int32]chainhash.Hash
+	closed bool
+}
+
+func NewMemory() *Memory {
+	return &Memory{hashes: map[int32]chainhash.Hash{}}
+}
+
+func (repo *Memory) Load() (int32, error) {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if repo.closed {
+		return 0, ErrClosed
+	}
+	var last int32
+	for height := range repo.hashes {
+		if height > last {
+			last = height
+		}
+	}
+
+	return last, nil
+}
+
+func (repo *Memory) Get(height int32) (*chainhash.Hash, error) {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if repo.closed {
+		return nil, ErrClosed
+	}
+	hash, ok := repo.hashes[height]
+	if !ok {
+		return nil, pebble.ErrNotFound
+	}
+
+	return &hash, nil
+}
+
+func (repo *Memory) Set(height int32, hash *chainhash.Hash) error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.closed {
+		return ErrClosed
+	}
+	repo.hashes[height] = *hash
+
+	return nil
+}
+
+// HeightForRoot returns the highest height stored with the root, as the Pebble repo does.
+func (repo *Memory) HeightForRoot(hash *chainhash.Hash) (int32, error) {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if repo.closed {
+		return 0, ErrClosed
+	}
+	found := int32(-1)
+	for height, h := range repo.hashes {
+		if h == *hash && height > found {
+			found = height
+		}
+	}
+	if found < 0 {
+		return 0, ErrRootNotFound
+	}
+
+	return found, nil
+}
+
+// Clone returns a copy of the repo, which the writes of either don't affect the other.
+func (repo *Memory) Clone() *Memory {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	hashes := make(map[int32]chainhash.Hash, len(repo.hashes))
+	for height, hash := range repo.hashes {
+		hashes[height] = hash
+	}
+
+	return &Memory{hashes: hashes, closed: repo.closed}
+}
+
+func (repo *Memory) Close() error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.closed {
+		return ErrClosed
+	}
+	repo.closed = true
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package blockrepo
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/cockroachdb/pebble"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+
+	r := require.New(t)
+
+	repo := NewMemory()
+	last, err := repo.Load()
+	r.NoError(err)
+	r.Equal(int32(0), last)
+
+	h1 := chainhash.HashH([]byte{1})
+	h2 := chainhash.HashH([]byte{2})
+	r.NoError(repo.Set(1, &h1))
+	r.NoError(repo.Set(2, &h2))
+	r.NoError(repo.Set(3, &h2))
+
+	last, err = repo.Load()
+	r.NoError(err)
+	r.Equal(int32(3), last)
+	hash, err := repo.Get(1)
+	r.NoError(err)
+	r.Equal(h1, *hash)
+	_, err = repo.Get(4)
+	r.ErrorIs(err, pebble.ErrNotFound)
+	height, err := repo.HeightForRoot(&h2)
+	r.NoError(err)
+	r.Equal(int32(3), height)
+
+	clone := repo.Clone()
+	r.NoError(clone.Set(4, &h1))
+	last, err = repo.Load()
+	r.NoError(err)
+	r.Equal(int32(3), last)
+	height, err = clone.HeightForRoot(&h1)
+	r.NoError(err)
+	r.Equal(int32(4), height)
+
+	r.NoError(repo.Close())
+	_, err = repo.Get(1)
+	r.ErrorIs(err, ErrClosed)
+	r.ErrorIs(repo.Close(), ErrClosed)
+	_, err = clone.Get(1)
+	r.NoError(err)
+}
@@ -2,15 +2,31 @@ package blockrepo
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 
 	"github.com/cockroachdb/pebble"
 )
 
+// ErrRootNotFound is returned when no stored height has the root.
+var ErrRootNotFound = errors.New("root not found")
+
+// rootKeyMarker leads the keys of the reverse index from the roots to the heights.
+// It sorts after the keys of all the non-negative heights.
+const rootKeyMarker = 0xff
+
 type Pebble struct {
-	db *pebble.DB
+	db     *pebble.DB
+	prefix []byte
+	shared bool
+
+	// The writes batched since BeginBatch, if it's set, which is read through.
+	// mu guards it from the concurrent reads.
+	mu    sync.RWMutex
+	batch *pebble.Batch
 }
 
 func NewPebble(path string) (*Pebble, error) {
@@ -25,9 +41,47 @@ func NewPebble(path string) (*Pebble, error) {
 	return repo, nil
 }
 
+// NewPebbleShared returns a repo storing its keys under the prefix in db, which is shared with other repos.
+// Closing the repo doesn't close db.
+func NewPebbleShared(db *pebble.DB, prefix []byte) *Pebble {
+	return &Pebble{db: db, prefix: prefix, shared: true}
+}
+
+func (repo *Pebble) key(height int32) []byte {
+	key := make([]byte, len(repo.prefix)+4)
+	copy(key, repo.prefix)
+	binary.BigEndian.PutUint32(key[len(repo.prefix):], uint32(height))
+	return key
+}
+
+func (repo *Pebble) rootKey(hash *chainhash.Hash) []byte {
+	key := make([]byte, 0, len(repo.prefix)+1+chainhash.HashSize)
+	key = append(key, repo.prefix...)
+	key = append(key, rootKeyMarker)
+	return append(key, hash[:]...)
+}
+
+// reader returns the batch, if the writes are batched, or the DB. It must be called with mu held.
+func (repo *Pebble) reader() pebble.Reader {
+	if repo.batch != nil {
+		return repo.batch
+	}
+	return repo.db
+}
+
+// heights returns an iterator over the heights, excluding the reverse index. It must be called with mu held.
+func (repo *Pebble) heights() *pebble.Iterator {
+	lower := repo.prefix
+	upper := append(append([]byte(nil), repo.prefix...), 0x80)
+	return repo.reader().NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+}
+
 func (repo *Pebble) Load() (int32, error) {
 
-	iter := repo.db.NewIter(nil)
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	iter := repo.heights()
 	if !iter.Last() {
 		if err := iter.Close(); err != nil {
 			return 0, fmt.Errorf("close iter: %w", err)
@@ -35,7 +89,7 @@ func (repo *Pebble) Load() (int32, error) {
 		return 0, nil
 	}
 
-	height := int32(binary.BigEndian.Uint32(iter.Key()))
+	height := int32(binary.BigEndian.Uint32(iter.Key()[len(repo.prefix):]))
 	if err := iter.Close(); err != nil {
 		return height, fmt.Errorf("close iter: %w", err)
 	}
@@ -45,10 +99,10 @@ func (repo *Pebble) Load() (int32, error) {
 
 func (repo *Pebble) Get(height int32) (*chainhash.Hash, error) {
 
-	key := make([]byte, 4)
-	binary.BigEndian.PutUint32(key, uint32(height))
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
 
-	b, closer, err := repo.db.Get(key)
+	b, closer, err := repo.reader().Get(repo.key(height))
 	if err != nil {
 		return nil, err
 	}
@@ -61,18 +115,214 @@ func (repo *Pebble) Get(height int32) (*chainhash.Hash, error) {
 
 func (repo *Pebble) Set(height int32, hash *chainhash.Hash) error {
 
-	key := make([]byte, 4)
-	binary.BigEndian.PutUint32(key, uint32(height))
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	batch := repo.batch
+	if batch == nil {
+		batch = repo.db.NewBatch()
+		defer batch.Close()
+	}
+
+	err := batch.Set(repo.key(height), hash[:], pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble set: %w", err)
+	}
+
+	err = batch.Set(repo.rootKey(hash), repo.key(height)[len(repo.prefix):], pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble set root: %w", err)
+	}
+	if batch == repo.batch {
+		return nil
+	}
 
-	return repo.db.Set(key, hash[:], pebble.NoSync)
+	return batch.Commit(pebble.NoSync)
+}
+
+// BeginBatch batches the writes until CommitBatch, which writes them at once.
+func (repo *Pebble) BeginBatch() {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.batch == nil {
+		repo.batch = repo.db.NewIndexedBatch()
+	}
+}
+
+// CommitBatch writes, and syncs, the writes batched since BeginBatch, if any.
+func (repo *Pebble) CommitBatch() error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.batch == nil {
+		return nil
+	}
+
+	err := repo.batch.Commit(pebble.Sync)
+	repo.batch.Close()
+	repo.batch = nil
+	if err != nil {
+		return fmt.Errorf("pebble commit: %w", err)
+	}
+
+	return nil
+}
+
+// BatchSize returns the size, in bytes, of the writes batched since BeginBatch.
+func (repo *Pebble) BatchSize() int {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if repo.batch == nil {
+		return 0
+	}
+	return len(repo.batch.Repr())
+}
+
+// BatchRepr returns a copy of the writes batched since BeginBatch, for ApplyBatch, or nil if there are none.
+func (repo *Pebble) BatchRepr() []byte {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if repo.batch == nil || repo.batch.Empty() {
+		return nil
+	}
+	return append([]byte(nil), repo.batch.Repr()...)
+}
+
+// ApplyBatch writes, and syncs, the writes returned by BatchRepr, which were batched before a crash.
+func (repo *Pebble) ApplyBatch(repr []byte) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	err := batch.SetRepr(append([]byte(nil), repr...))
+	if err != nil {
+		return fmt.Errorf("pebble batch repr: %w", err)
+	}
+	err = batch.Commit(pebble.Sync)
+	if err != nil {
+		return fmt.Errorf("pebble commit: %w", err)
+	}
+
+	return nil
+}
+
+// HeightForRoot returns the last height set with the root.
+// Roots of heights set before the reverse index existed are only found after ReindexRoots.
+func (repo *Pebble) HeightForRoot(hash *chainhash.Hash) (int32, error) {
+
+	height, err := repo.rootHeight(hash)
+	if err != nil {
+		return 0, err
+	}
+
+	// The height may have been set again with another root since, after a reorg.
+	stored, err := repo.Get(height)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return 0, ErrRootNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get %d: %w", height, err)
+	}
+	if !stored.IsEqual(hash) {
+		return 0, ErrRootNotFound
+	}
+
+	return height, nil
+}
+
+// rootHeight returns the height the root was last indexed at.
+func (repo *Pebble) rootHeight(hash *chainhash.Hash) (int32, error) {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	b, closer, err := repo.reader().Get(repo.rootKey(hash))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return 0, ErrRootNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("pebble get: %w", err)
+	}
+	defer closer.Close()
+
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+// ReindexRoots rebuilds the reverse index from the roots to the heights.
+// It returns the number of the heights indexed.
+func (repo *Pebble) ReindexRoots() (int, error) {
+
+	err := repo.CommitBatch()
+	if err != nil {
+		return 0, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	iter := repo.heights()
+	defer iter.Close()
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	indexed := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+		hash, err := chainhash.NewHash(iter.Value())
+		if err != nil {
+			return indexed, fmt.Errorf("stored hash: %w", err)
+		}
+		err = batch.Set(repo.rootKey(hash), iter.Key()[len(repo.prefix):], pebble.NoSync)
+		if err != nil {
+			return indexed, fmt.Errorf("pebble set root: %w", err)
+		}
+		indexed++
+	}
+
+	err = batch.Commit(pebble.NoSync)
+	if err != nil {
+		return indexed, fmt.Errorf("pebble commit: %w", err)
+	}
+
+	return indexed, nil
+}
+
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Pebble) Backup(dir string) error {
+
+	if repo.shared {
+		return fmt.Errorf("backup of a shared repo is not supported")
+	}
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
 }
 
 func (repo *Pebble) Close() error {
 
-	err := repo.db.Flush()
+	err := repo.CommitBatch()
+	if err != nil {
+		return err
+	}
+
+	err = repo.db.Flush()
 	if err != nil {
 		return fmt.Errorf("pebble fludh: %w", err)
 	}
+	if repo.shared {
+		return nil
+	}
 
 	err = repo.db.Close()
 	if err != nil {
@@ -0,0 +1,106 @@
+package blockrepo
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/cockroachdb/pebble"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeightForRoot(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	h1 := chainhash.HashH([]byte{1})
+	h2 := chainhash.HashH([]byte{2})
+	h3 := chainhash.HashH([]byte{3})
+	r.NoError(repo.Set(1, &h1))
+	r.NoError(repo.Set(2, &h2))
+	r.NoError(repo.Set(3, &h2)) // reused without any activity
+	r.NoError(repo.Set(4, &h3))
+
+	last, err := repo.Load()
+	r.NoError(err)
+	r.Equal(int32(4), last)
+
+	height, err := repo.HeightForRoot(&h1)
+	r.NoError(err)
+	r.Equal(int32(1), height)
+	height, err = repo.HeightForRoot(&h2)
+	r.NoError(err)
+	r.Equal(int32(3), height)
+
+	// Reorged away.
+	r.NoError(repo.Set(4, &h1))
+	_, err = repo.HeightForRoot(&h3)
+	r.ErrorIs(err, ErrRootNotFound)
+	height, err = repo.HeightForRoot(&h1)
+	r.NoError(err)
+	r.Equal(int32(4), height)
+
+	// Heights stored before the index existed.
+	h5 := chainhash.HashH([]byte{5})
+	r.NoError(repo.db.Set(repo.key(5), h5[:], pebble.NoSync))
+	_, err = repo.HeightForRoot(&h5)
+	r.ErrorIs(err, ErrRootNotFound)
+	indexed, err := repo.ReindexRoots()
+	r.NoError(err)
+	r.Equal(5, indexed)
+	height, err = repo.HeightForRoot(&h5)
+	r.NoError(err)
+	r.Equal(int32(5), height)
+}
+
+func TestBatch(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	h1 := chainhash.HashH([]byte{1})
+	h2 := chainhash.HashH([]byte{2})
+	repo.BeginBatch()
+	r.NoError(repo.Set(1, &h1))
+	r.NoError(repo.CommitBatch())
+	r.Zero(repo.BatchSize())
+
+	repo.BeginBatch()
+	r.NoError(repo.Set(2, &h2))
+	r.NotZero(repo.BatchSize())
+
+	// The batch is read through, but not written until committed.
+	last, err := repo.Load()
+	r.NoError(err)
+	r.Equal(int32(2), last)
+	hash, err := repo.Get(2)
+	r.NoError(err)
+	r.Equal(h2, *hash)
+	height, err := repo.HeightForRoot(&h2)
+	r.NoError(err)
+	r.Equal(int32(2), height)
+
+	_, closer, err := repo.db.Get(repo.key(2))
+	r.ErrorIs(err, pebble.ErrNotFound)
+	_, closer, err = repo.db.Get(repo.key(1))
+	r.NoError(err)
+	r.NoError(closer.Close())
+
+	r.NoError(repo.CommitBatch())
+	_, closer, err = repo.db.Get(repo.key(2))
+	r.NoError(err)
+	r.NoError(closer.Close())
+}
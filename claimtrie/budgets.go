@@ -0,0 +1,67 @@
+package claimtrie
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie/event"
+)
+
+// The stages of processing a block, which the budgets are set for.
+const (
+	StageBlock = "block" // all of AppendBlock.
+	StageNodes = "nodes" // applying the changes, and the activations, to the nodes.
+	StageTrie  = "trie"  // updating the trie with the names, and scheduling their next updates.
+	StageHash  = "hash"  // computing the Merkle Hash.
+)
+
+var stages = map[string]bool{StageBlock: true, StageNodes: true, StageTrie: true, StageHash: true}
+
+func checkBudgets(budgets map[string]time.Duration) error {
+
+	names := make([]string, 0, len(budgets))
+	for stage := range budgets {
+		names = append(names, stage)
+	}
+	sort.Strings(names) // so the error is the same each run.
+
+	for _, stage := range names {
+		budget := budgets[stage]
+		if !stages[stage] {
+			return fmt.Errorf("unknown stage: %q", stage)
+		}
+		if budget <= 0 {
+			return fmt.Errorf("invalid budget of %s: %s", stage, budget)
+		}
+	}
+	return nil
+}
+
+// checkBudget counts an alert of the stage, which started at start, and
+// publishes a BudgetExceeded event, if it took longer than its budget.
+// The time it took is recorded, if the metrics are.
+func (ct *ClaimTrie) checkBudget(stage string, start time.Time) {
+
+	elapsed := time.Since(start)
+	if ct.metrics != nil {
+		ct.metrics.stages.With(stage).Observe(elapsed.Seconds())
+	}
+	budget, ok := ct.budgets[stage]
+	if !ok || elapsed <= budget {
+		return
+	}
+
+	ct.statsMu.Lock()
+	ct.alerts[stage]++
+	ct.statsMu.Unlock()
+
+	log.Warnf("Budget exceeded: %s of block %d took %s, budget: %s", stage, ct.height, elapsed, budget)
+	ct.events.Publish(event.Event{
+		Type:    event.BudgetExceeded,
+		Height:  ct.height,
+		Stage:   stage,
+		Elapsed: elapsed,
+		Budget:  budget,
+	})
+}
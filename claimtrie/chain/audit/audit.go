@@ -0,0 +1,138 @@
+// Package audit flags the anomalies in the change history of a chain repo, or of a feed of it, such as the
+// dumps of an upstream one, before they're replayed: the supports added twice, the ones outliving their claims,
+// and the claims spent again by later blocks.
+package audit
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+)
+
+// The kinds of the findings.
+const (
+	DuplicateSupport     = "duplicate_support"      // a support added at the outpoint of one not spent yet.
+	SupportOutlivesClaim = "support_outlives_claim" // a support not spent yet, once its claim was spent, and not updated.
+	DoubleSpend          = "double_spend"           // a claim spent by a block, after it was spent by an earlier one.
+)
+
+// Finding is an anomaly of a change, at its Height and Seq.
+type Finding struct {
+	Kind     string `json:"kind"`
+	Height   int32  `json:"height"`
+	Seq      int32  `json:"seq"`
+	Name     string `json:"name"`
+	OutPoint string `json:"outpoint"`
+	ClaimID  string `json:"claim_id"`
+
+	// The height of the earlier change it conflicts with: the support added, or the claim spent, before.
+	PrevHeight int32 `json:"prev_height,omitempty"`
+}
+
+type support struct {
+	name    []byte
+	claimID change.ClaimID
+	height  int32
+}
+
+// Detector flags the anomalies of the changes of the blocks fed to it in order, from the first one.
+// The expirations aren't accounted for, as they aren't in the changes.
+type Detector struct {
+	supports map[change.OutPoint]support
+	backing  map[change.ClaimID]map[change.OutPoint]bool // the supports not spent yet of each claim.
+	claims   map[change.ClaimID]int                      // the outpoints not spent yet of each claim.
+	spent    map[change.OutPoint]int32                   // the heights the claims were spent at.
+
+	findings []Finding
+}
+
+func NewDetector() *Detector {
+	return &Detector{
+		supports: map[change.OutPoint]support{},
+		backing:  map[change.ClaimID]map[change.OutPoint]bool{},
+		claims:   map[change.ClaimID]int{},
+		spent:    map[change.OutPoint]int32{},
+	}
+}
+
+// Block feeds the changes of the block at the height to the detector, and returns the findings of it.
+func (d *Detector) Block(height int32, changes []change.Change) []Finding {
+
+	start := len(d.findings)
+	var ended []change.Change
+	for _, chg := range changes {
+		switch chg.Type {
+		case change.AddClaim, change.UpdateClaim:
+			d.claims[chg.ClaimID]++
+			delete(d.spent, chg.OutPoint) // a claim re-added at the outpoint, as at the normalization fork
+		case change.SpendClaim:
+			if prev, ok := d.spent[chg.OutPoint]; ok {
+				if prev != height {
+					d.flag(DoubleSpend, height, chg, prev)
+				}
+				continue
+			}
+			d.spent[chg.OutPoint] = height
+			if d.claims[chg.ClaimID] > 0 {
+				d.claims[chg.ClaimID]--
+			}
+			if d.claims[chg.ClaimID] == 0 {
+				ended = append(ended, chg) // unless it's updated later in the block
+			}
+		case change.AddSupport:
+			if prev, ok := d.supports[chg.OutPoint]; ok {
+				d.flag(DuplicateSupport, height, chg, prev.height)
+				d.unback(chg.OutPoint, prev.claimID)
+			}
+			d.supports[chg.OutPoint] = support{name: chg.Name, claimID: chg.ClaimID, height: height}
+			if d.backing[chg.ClaimID] == nil {
+				d.backing[chg.ClaimID] = map[change.OutPoint]bool{}
+			}
+			d.backing[chg.ClaimID][chg.OutPoint] = true
+		case change.SpendSupport:
+			if s, ok := d.supports[chg.OutPoint]; ok {
+				delete(d.supports, chg.OutPoint)
+				d.unback(chg.OutPoint, s.claimID)
+			}
+		}
+	}
+
+	for _, chg := range ended {
+		if d.claims[chg.ClaimID] > 0 {
+			continue
+		}
+		delete(d.claims, chg.ClaimID)
+		ops := make([]change.OutPoint, 0, len(d.backing[chg.ClaimID]))
+		for op := range d.backing[chg.ClaimID] {
+			ops = append(ops, op)
+		}
+		sort.Slice(ops, func(i, j int) bool { return bytes.Compare(ops[i][:], ops[j][:]) < 0 })
+		for _, op := range ops {
+			// The support is flagged at the spend of its claim, once.
+			s := d.supports[op]
+			d.findings = append(d.findings, Finding{Kind: SupportOutlivesClaim, Height: height, Seq: chg.Seq,
+				Name: string(s.name), OutPoint: op.String(), ClaimID: chg.ClaimID.String(), PrevHeight: s.height})
+		}
+		delete(d.backing, chg.ClaimID)
+	}
+
+	return d.findings[start:]
+}
+
+func (d *Detector) flag(kind string, height int32, chg change.Change, prev int32) {
+	d.findings = append(d.findings, Finding{Kind: kind, Height: height, Seq: chg.Seq, Name: string(chg.Name),
+		OutPoint: chg.OutPoint.String(), ClaimID: chg.ClaimID.String(), PrevHeight: prev})
+}
+
+func (d *Detector) unback(op change.OutPoint, id change.ClaimID) {
+	delete(d.backing[id], op)
+	if len(d.backing[id]) == 0 {
+		delete(d.backing, id)
+	}
+}
+
+// Findings returns the findings of all the blocks fed so far.
+func (d *Detector) Findings() []Finding {
+	return d.findings
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetector(t *testing.T) {
+
+	r := require.New(t)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	op := func(i uint32) change.OutPoint { return change.NewOutPoint(wire.OutPoint{Hash: hash, Index: i}) }
+	chg := func(typ change.ChangeType, seq int32, o change.OutPoint, id byte) change.Change {
+		return change.Change{Type: typ, Seq: seq, Name: []byte("a"), OutPoint: o, ClaimID: change.ClaimID{id}}
+	}
+
+	d := NewDetector()
+	r.Empty(d.Block(1, []change.Change{
+		chg(change.AddClaim, 0, op(1), 1),
+		chg(change.AddSupport, 1, op(2), 1),
+		chg(change.AddSupport, 2, op(3), 1),
+		chg(change.AddClaim, 3, op(4), 2),
+	}))
+
+	findings := d.Block(2, []change.Change{
+		chg(change.AddSupport, 0, op(2), 1),
+		chg(change.SpendSupport, 1, op(3), 1),
+	})
+	r.Equal([]Finding{{Kind: DuplicateSupport, Height: 2, Seq: 0, Name: "a", OutPoint: op(2).String(),
+		ClaimID: change.ClaimID{1}.String(), PrevHeight: 1}}, findings)
+
+	// The claims updated within the block don't end.
+	r.Empty(d.Block(3, []change.Change{
+		chg(change.SpendClaim, 0, op(1), 1),
+		chg(change.UpdateClaim, 1, op(5), 1),
+	}))
+
+	findings = d.Block(4, []change.Change{
+		chg(change.SpendClaim, 0, op(5), 1),
+		chg(change.SpendClaim, 1, op(1), 1),
+		chg(change.SpendClaim, 2, op(4), 2),
+	})
+	r.Len(findings, 2)
+	r.Equal(DoubleSpend, findings[0].Kind)
+	r.Equal(op(1).String(), findings[0].OutPoint)
+	r.Equal(int32(3), findings[0].PrevHeight)
+	r.Equal(SupportOutlivesClaim, findings[1].Kind)
+	r.Equal(op(2).String(), findings[1].OutPoint)
+	r.Equal(int32(0), findings[1].Seq)
+
+	r.Empty(d.Block(5, []change.Change{chg(change.SpendSupport, 0, op(2), 1)}))
+	r.Len(d.Findings(), 3)
+}
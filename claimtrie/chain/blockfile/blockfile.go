@@ -0,0 +1,262 @@
+// Package blockfile reads the blocks straight from the block files of a node, the blk*.dat of lbrycrd,
+// or the *.fdb of btcd, so that the chain repo can be filled without running one.
+package blockfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ErrNoBlockFiles is returned by Open, if there are no block files in the directory.
+var ErrNoBlockFiles = errors.New("no block files")
+
+// The records of the block files are the network magic, and the length of the block, both little-endian,
+// followed by the block, and its CRC-32 in the btcd ones.
+var formats = []struct {
+	pattern string
+	trailer int64
+}{
+	{"blk*.dat", 0},
+	{"*.fdb", 4},
+}
+
+const recordHeaderSize = 8
+
+// location is where a block is in the block files.
+type location struct {
+	file   int
+	offset int64 // of the block, past the record header
+	size   uint32
+}
+
+type entry struct {
+	prev chainhash.Hash
+	bits uint32
+	loc  location
+
+	// The height, and the work of the chain up to the block, once it's known to connect to the genesis block.
+	height int32
+	work   *big.Int
+}
+
+// The height of the blocks not connecting to the genesis block, which is -1 until it's known.
+const disconnected = -2
+
+// Index locates the blocks in the block files of a directory by their hashes.
+type Index struct {
+	files   []string
+	trailer int64
+	net     wire.BitcoinNet
+	genesis chainhash.Hash
+	blocks  map[chainhash.Hash]*entry
+
+	// The block file last read.
+	open    *os.File
+	openNum int
+}
+
+// Open indexes the headers of the blocks in the block files in dir, which are of the network of the params.
+// A file ends at its first record of zeroes, which lbrycrd preallocates, or at a truncated record.
+func Open(dir string, params *chaincfg.Params) (*Index, error) {
+
+	idx := &Index{
+		net:     params.Net,
+		genesis: *params.GenesisHash,
+		blocks:  map[chainhash.Hash]*entry{},
+		openNum: -1,
+	}
+	for _, f := range formats {
+		files, err := filepath.Glob(filepath.Join(dir, f.pattern))
+		if err != nil {
+			return nil, fmt.Errorf("list block files: %w", err)
+		}
+		if len(files) > 0 {
+			sort.Strings(files)
+			idx.files = files
+			idx.trailer = f.trailer
+			break
+		}
+	}
+	if len(idx.files) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoBlockFiles, dir)
+	}
+
+	for i := range idx.files {
+		err := idx.scan(i)
+		if err != nil {
+			return nil, fmt.Errorf("scan %s: %w", idx.files[i], err)
+		}
+	}
+
+	return idx, nil
+}
+
+// scan indexes the headers of the blocks in the file.
+func (idx *Index) scan(file int) error {
+
+	f, err := os.Open(idx.files[file])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, 1<<20)
+	var offset int64
+	for {
+		var rec [recordHeaderSize]byte
+		_, err = io.ReadFull(r, rec[:])
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		magic := binary.LittleEndian.Uint32(rec[:4])
+		if magic == 0 {
+			return nil
+		}
+		if magic != uint32(idx.net) {
+			return fmt.Errorf("block at %d is of the network %s, instead of %s", offset, wire.BitcoinNet(magic), idx.net)
+		}
+		size := binary.LittleEndian.Uint32(rec[4:])
+		if size < wire.MaxBlockHeaderPayload {
+			return fmt.Errorf("block at %d of %d bytes", offset, size)
+		}
+
+		var h wire.BlockHeader
+		err = h.Deserialize(r)
+		if err != nil {
+			return nil // truncated
+		}
+		_, err = r.Discard(int(int64(size) - wire.MaxBlockHeaderPayload + idx.trailer))
+		if err != nil {
+			return nil // truncated
+		}
+
+		hash := h.BlockHash()
+		if _, ok := idx.blocks[hash]; !ok {
+			loc := location{file: file, offset: offset + recordHeaderSize, size: size}
+			idx.blocks[hash] = &entry{prev: h.PrevBlock, bits: h.Bits, loc: loc, height: -1}
+		}
+		offset += recordHeaderSize + int64(size) + idx.trailer
+	}
+}
+
+// MainChain returns the hashes of the blocks of the chain with the most work, by their heights.
+// The blocks not connecting to the genesis block, such as the ones missing their parents, are left out.
+func (idx *Index) MainChain() ([]chainhash.Hash, error) {
+
+	genesis, ok := idx.blocks[idx.genesis]
+	if !ok {
+		return nil, fmt.Errorf("genesis block %s not found", idx.genesis)
+	}
+	genesis.height = 0
+	genesis.work = big.NewInt(0)
+
+	var tip *entry
+	var tipHash chainhash.Hash
+	var path []*entry
+	for hash, e := range idx.blocks {
+
+		// Walk back to the first block of which the height is known, then resolve the ones walked.
+		path = path[:0]
+		for e != nil && e.work == nil && e.height != disconnected {
+			path = append(path, e)
+			e = idx.blocks[e.prev]
+		}
+		if e == nil || e.work == nil {
+			for _, p := range path {
+				p.height = disconnected
+			}
+			continue
+		}
+		for i := len(path) - 1; i >= 0; i-- {
+			p := path[i]
+			p.height = e.height + 1
+			p.work = new(big.Int).Add(e.work, blockchain.CalcWork(p.bits))
+			e = p
+		}
+
+		e = idx.blocks[hash]
+		if e.height >= 0 && (tip == nil || e.work.Cmp(tip.work) > 0) {
+			tip, tipHash = e, hash
+		}
+	}
+
+	hashes := make([]chainhash.Hash, tip.height+1)
+	for hash, e := tipHash, tip; ; hash, e = e.prev, idx.blocks[e.prev] {
+		hashes[e.height] = hash
+		if e.height == 0 {
+			break
+		}
+	}
+
+	return hashes, nil
+}
+
+// Block reads the block with the hash.
+func (idx *Index) Block(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+
+	e, ok := idx.blocks[*hash]
+	if !ok {
+		return nil, fmt.Errorf("block %s not found", hash)
+	}
+	b, err := idx.read(e.loc)
+	if err != nil {
+		return nil, err
+	}
+
+	var block wire.MsgBlock
+	err = block.Deserialize(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("deserialize block %s: %w", hash, err)
+	}
+
+	return &block, nil
+}
+
+// read reads the block at the location, keeping its file open for the next one.
+func (idx *Index) read(loc location) ([]byte, error) {
+
+	if idx.openNum != loc.file {
+		idx.Close() // nolint : errchk
+		f, err := os.Open(idx.files[loc.file])
+		if err != nil {
+			return nil, err
+		}
+		idx.open, idx.openNum = f, loc.file
+	}
+
+	b := make([]byte, loc.size)
+	_, err := idx.open.ReadAt(b, loc.offset)
+	if err != nil {
+		return nil, fmt.Errorf("read block at %d of %s: %w", loc.offset, idx.files[loc.file], err)
+	}
+
+	return b, nil
+}
+
+// Close closes the block file last read.
+func (idx *Index) Close() error {
+
+	if idx.open == nil {
+		return nil
+	}
+	err := idx.open.Close()
+	idx.open, idx.openNum = nil, -1
+
+	return err
+}
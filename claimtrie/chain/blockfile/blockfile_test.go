@@ -0,0 +1,178 @@
+package blockfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/chain/chainrepo"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+var params = &chaincfg.RegressionNetParams
+
+func newBlock(prev *wire.MsgBlock, nonce uint32, txs ...*wire.MsgTx) *wire.MsgBlock {
+
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript:  []byte{byte(nonce), 0},
+	})
+	coinbase.AddTxOut(wire.NewTxOut(1, []byte{txscript.OP_TRUE}))
+
+	b := wire.NewMsgBlock(&wire.BlockHeader{
+		PrevBlock: prev.BlockHash(),
+		Timestamp: prev.Header.Timestamp.Add(time.Minute),
+		Bits:      params.PowLimitBits,
+		Nonce:     nonce,
+	})
+	b.AddTransaction(coinbase)
+	for _, tx := range txs {
+		b.AddTransaction(tx)
+	}
+
+	return b
+}
+
+func newTx(spends []wire.OutPoint, scripts ...[]byte) *wire.MsgTx {
+
+	tx := wire.NewMsgTx(1)
+	for i := range spends {
+		tx.AddTxIn(wire.NewTxIn(&spends[i], nil, nil))
+	}
+	for _, script := range scripts {
+		tx.AddTxOut(wire.NewTxOut(10, script))
+	}
+
+	return tx
+}
+
+// writeBlocks writes the records of the blocks into the file, with the trailer, followed by some zeroes.
+func writeBlocks(r *require.Assertions, path string, trailer int, blocks ...*wire.MsgBlock) {
+
+	var buf bytes.Buffer
+	for _, b := range blocks {
+		var raw bytes.Buffer
+		r.NoError(b.Serialize(&raw))
+		r.NoError(binary.Write(&buf, binary.LittleEndian, uint32(params.Net)))
+		r.NoError(binary.Write(&buf, binary.LittleEndian, uint32(raw.Len())))
+		buf.Write(raw.Bytes())
+		buf.Write(make([]byte, trailer))
+	}
+	buf.Write(make([]byte, 64))
+
+	r.NoError(os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func TestIngest(t *testing.T) {
+
+	r := require.New(t)
+
+	claimScript, err := txscript.ClaimNameScript("Test", "v1")
+	r.NoError(err)
+	tx1 := newTx([]wire.OutPoint{{Index: 7}}, claimScript)
+	claimOp := wire.OutPoint{Hash: tx1.TxHash(), Index: 0}
+	id := node.NewClaimID(claimOp)
+
+	supportScript, err := txscript.SupportClaimScript("test", id[:], nil)
+	r.NoError(err)
+	updateScript, err := txscript.UpdateClaimScript("test", id[:], "v2")
+	r.NoError(err)
+	tx2 := newTx([]wire.OutPoint{claimOp}, updateScript, supportScript)
+	updateOp := wire.OutPoint{Hash: tx2.TxHash(), Index: 0}
+	supportOp := wire.OutPoint{Hash: tx2.TxHash(), Index: 1}
+
+	// An update of a claim, which isn't spent by the same transaction, is left out.
+	tx3 := newTx([]wire.OutPoint{supportOp}, updateScript)
+
+	genesis := params.GenesisBlock
+	block1 := newBlock(genesis, 1, tx1)
+	block2 := newBlock(block1, 2, tx2, tx3)
+	stale := newBlock(genesis, 3)
+	orphan := newBlock(newBlock(block2, 4), 5)
+
+	dir := t.TempDir()
+	writeBlocks(r, filepath.Join(dir, "blk00000.dat"), 0, block2, stale, orphan)
+	writeBlocks(r, filepath.Join(dir, "blk00001.dat"), 0, genesis, block1)
+
+	idx, err := Open(dir, params)
+	r.NoError(err)
+	defer idx.Close()
+
+	hashes, err := idx.MainChain()
+	r.NoError(err)
+	r.Equal([]chainhash.Hash{genesis.BlockHash(), block1.BlockHash(), block2.BlockHash()}, hashes)
+
+	repo, err := chainrepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	defer repo.Close()
+
+	var ingested []int32
+	height, err := Ingest(idx, repo, 100, func(height int32) { ingested = append(ingested, height) })
+	r.NoError(err)
+	r.Equal(int32(2), height)
+	r.Equal([]int32{1, 2}, ingested)
+
+	changes, err := repo.Load(1)
+	r.NoError(err)
+	r.Equal([]change.Change{{
+		Type: change.AddClaim, Height: 1, Name: []byte("Test"), ClaimID: id,
+		OutPoint: change.NewOutPoint(claimOp), Amount: 10, Value: []byte("v1"),
+	}}, changes)
+
+	changes, err = repo.Load(2)
+	r.NoError(err)
+	r.Equal([]change.Change{{
+		Type: change.SpendClaim, Height: 2, Seq: 0, Name: []byte("Test"), ClaimID: id,
+		OutPoint: change.NewOutPoint(claimOp),
+	}, {
+		Type: change.UpdateClaim, Height: 2, Seq: 1, Name: []byte("test"), ClaimID: id,
+		OutPoint: change.NewOutPoint(updateOp), Amount: 10, Value: []byte("v2"),
+	}, {
+		Type: change.AddSupport, Height: 2, Seq: 2, Name: []byte("test"), ClaimID: id,
+		OutPoint: change.NewOutPoint(supportOp), Amount: 10,
+	}, {
+		Type: change.SpendSupport, Height: 2, Seq: 3, Name: []byte("test"), ClaimID: id,
+		OutPoint: change.NewOutPoint(supportOp),
+	}}, changes)
+}
+
+func TestOpenFdb(t *testing.T) {
+
+	r := require.New(t)
+
+	genesis := params.GenesisBlock
+	block1 := newBlock(genesis, 1)
+
+	dir := t.TempDir()
+	writeBlocks(r, filepath.Join(dir, "000000000.fdb"), 4, genesis, block1)
+
+	idx, err := Open(dir, params)
+	r.NoError(err)
+	defer idx.Close()
+
+	hashes, err := idx.MainChain()
+	r.NoError(err)
+	r.Equal([]chainhash.Hash{genesis.BlockHash(), block1.BlockHash()}, hashes)
+
+	hash := block1.BlockHash()
+	b, err := idx.Block(&hash)
+	r.NoError(err)
+	r.Equal(hash, b.BlockHash())
+
+	_, err = Open(t.TempDir(), params)
+	r.ErrorIs(err, ErrNoBlockFiles)
+
+	_, err = Open(dir, &chaincfg.MainNetParams)
+	r.Error(err)
+}
@@ -0,0 +1,127 @@
+package blockfile
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/claimtrie/chain"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Extractor extracts the changes of the claims from the blocks, in the order of the chain,
+// as the ClaimTrie records them while the blocks are connected.
+type Extractor struct {
+	// The scripts of the claims and the supports unspent, for telling the ones spent by the inputs.
+	scripts map[wire.OutPoint][]byte
+}
+
+func NewExtractor() *Extractor {
+	return &Extractor{scripts: map[wire.OutPoint][]byte{}}
+}
+
+// Changes returns the changes of the claims of the block at the height, which must follow the last one.
+// The updates not matching a claim spent by the same transaction are left out, as they were before the
+// invalid update fork, beyond which they don't make it into the chain.
+func (x *Extractor) Changes(height int32, block *wire.MsgBlock) []change.Change {
+
+	var changes []change.Change
+	add := func(chg change.Change) {
+		chg.Height = height
+		chg.Seq = int32(len(changes))
+		changes = append(changes, chg)
+	}
+
+	for _, tx := range block.Transactions {
+		spent := map[change.ClaimID][]byte{} // The names of the claims spent by the transaction.
+
+		if !blockchain.IsCoinBaseTx(tx) {
+			for _, in := range tx.TxIn {
+				op := in.PreviousOutPoint
+				script, ok := x.scripts[op]
+				if !ok {
+					continue
+				}
+				delete(x.scripts, op)
+				cs, _ := txscript.DecodeClaimScript(script) // decoded when it was added
+
+				chg := change.Change{Name: cs.Name(), OutPoint: change.NewOutPoint(op)}
+				switch cs.Opcode() {
+				case txscript.OP_CLAIMNAME:
+					chg.Type, chg.ClaimID = change.SpendClaim, node.NewClaimID(op)
+					spent[chg.ClaimID] = node.NormalizeIfNecessary(chg.Name, height-1)
+				case txscript.OP_UPDATECLAIM:
+					chg.Type = change.SpendClaim
+					copy(chg.ClaimID[:], cs.ClaimID())
+					spent[chg.ClaimID] = node.NormalizeIfNecessary(chg.Name, height-1)
+				case txscript.OP_SUPPORTCLAIM:
+					chg.Type = change.SpendSupport
+					copy(chg.ClaimID[:], cs.ClaimID())
+				}
+				add(chg)
+			}
+		}
+
+		hash := tx.TxHash()
+		for i, out := range tx.TxOut {
+			cs, err := txscript.DecodeClaimScript(out.PkScript)
+			if err != nil {
+				continue
+			}
+			op := *wire.NewOutPoint(&hash, uint32(i))
+			x.scripts[op] = out.PkScript
+
+			chg := change.Change{Name: cs.Name(), OutPoint: change.NewOutPoint(op), Amount: out.Value, Value: cs.Value()}
+			switch cs.Opcode() {
+			case txscript.OP_CLAIMNAME:
+				chg.Type, chg.ClaimID = change.AddClaim, node.NewClaimID(op)
+			case txscript.OP_SUPPORTCLAIM:
+				chg.Type = change.AddSupport
+				copy(chg.ClaimID[:], cs.ClaimID())
+			case txscript.OP_UPDATECLAIM:
+				chg.Type = change.UpdateClaim
+				copy(chg.ClaimID[:], cs.ClaimID())
+				if !bytes.Equal(spent[chg.ClaimID], node.NormalizeIfNecessary(chg.Name, height-1)) {
+					continue
+				}
+				delete(spent, chg.ClaimID)
+			}
+			add(chg)
+		}
+	}
+
+	return changes
+}
+
+// Ingest saves the changes of the claims of the blocks of the main chain, from height 1 up to toHeight,
+// to the repo, and returns the height of the last block ingested. fn, if it's set, is called after each block.
+func Ingest(idx *Index, repo chain.Repo, toHeight int32, fn func(height int32)) (int32, error) {
+
+	hashes, err := idx.MainChain()
+	if err != nil {
+		return 0, fmt.Errorf("main chain: %w", err)
+	}
+	if int(toHeight) >= len(hashes) {
+		toHeight = int32(len(hashes) - 1)
+	}
+
+	x := NewExtractor()
+	for height := int32(1); height <= toHeight; height++ {
+		block, err := idx.Block(&hashes[height])
+		if err != nil {
+			return height - 1, err
+		}
+		err = repo.Save(height, x.Changes(height, block))
+		if err != nil {
+			return height - 1, fmt.Errorf("save changes of block %d: %w", height, err)
+		}
+		if fn != nil {
+			fn(height)
+		}
+	}
+
+	return toHeight, nil
+}
@@ -0,0 +1,213 @@
+package chainrepo
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+)
+
+// CopyTable is the schema of the Postgres table, which the changes are moved in and out of with:
+//
+//	COPY changes FROM STDIN
+//	COPY (SELECT * FROM changes ORDER BY height, seq) TO STDOUT
+const CopyTable = `CREATE TABLE changes (
+	height         integer  NOT NULL,
+	seq            integer  NOT NULL,
+	type           smallint NOT NULL,
+	name           bytea    NOT NULL,
+	claim_id       text     NOT NULL,
+	outpoint       text     NOT NULL,
+	amount         bigint   NOT NULL,
+	value          bytea    NOT NULL,
+	active_height  integer  NOT NULL,
+	visible_height integer  NOT NULL,
+	PRIMARY KEY (height, seq)
+)`
+
+const copyColumns = 10
+
+// ErrCopyUnordered is returned when the rows of a block aren't contiguous in a COPY dump.
+var ErrCopyUnordered = errors.New("rows not ordered by height")
+
+// ExportCopy writes the changes of blocks from fromHeight up to, but not including, toHeight
+// in the text format of Postgres COPY, one row per change. It returns the number of rows written.
+func (repo *Pebble) ExportCopy(w io.Writer, fromHeight, toHeight int32) (int, error) {
+
+	bw := bufio.NewWriterSize(w, 1<<20)
+	rows := 0
+	err := repo.IterateBlocks(fromHeight, toHeight, func(height int32, changes []change.Change) error {
+		for _, chg := range changes {
+			_, err := bw.WriteString(copyRow(height, chg))
+			if err != nil {
+				return fmt.Errorf("write row: %w", err)
+			}
+			rows++
+		}
+		return nil
+	})
+	if err != nil {
+		return rows, err
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return rows, fmt.Errorf("write rows: %w", err)
+	}
+
+	return rows, nil
+}
+
+// ImportCopy saves the changes read from a dump in the text format of Postgres COPY,
+// which is ordered by height. It returns the number of rows read.
+func (repo *Pebble) ImportCopy(r io.Reader) (int, error) {
+
+	return ReadCopy(r, func(height int32, changes []change.Change) error {
+		err := repo.Save(height, changes)
+		if err != nil {
+			return fmt.Errorf("save changes at %d: %w", height, err)
+		}
+		return nil
+	})
+}
+
+// ReadCopy calls fn with the changes of each block, in order by height, read from a dump in the
+// text format of Postgres COPY, without any repo. The changes are only valid until fn returns.
+// It returns the number of rows read.
+func ReadCopy(r io.Reader, fn func(height int32, changes []change.Change) error) (int, error) {
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1<<20), 64<<20)
+
+	var changes []change.Change
+	height := int32(-1)
+	saved := map[int32]bool{}
+	flush := func() error {
+		if len(changes) == 0 {
+			return nil
+		}
+		saved[height] = true
+		err := fn(height, changes)
+		if err != nil {
+			return err
+		}
+		changes = changes[:0]
+		return nil
+	}
+
+	rows := 0
+	for scanner.Scan() {
+
+		line := scanner.Text()
+		if line == `\.` {
+			break // end of data marker
+		}
+
+		h, chg, err := parseCopyRow(line)
+		if err != nil {
+			return rows, fmt.Errorf("row %d: %w", rows+1, err)
+		}
+		if h != height {
+			err = flush()
+			if err != nil {
+				return rows, err
+			}
+			if saved[h] {
+				return rows, fmt.Errorf("row %d, height %d: %w", rows+1, h, ErrCopyUnordered)
+			}
+			height = h
+		}
+		changes = append(changes, chg)
+		rows++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return rows, fmt.Errorf("read rows: %w", err)
+	}
+
+	return rows, flush()
+}
+
+func copyRow(height int32, chg change.Change) string {
+
+	f := []string{
+		strconv.FormatInt(int64(height), 10),
+		strconv.FormatInt(int64(chg.Seq), 10),
+		strconv.Itoa(int(chg.Type)),
+		copyBytea(chg.Name),
+		chg.ClaimID.String(),
+		chg.OutPoint.String(),
+		strconv.FormatInt(chg.Amount, 10),
+		copyBytea(chg.Value),
+		strconv.FormatInt(int64(chg.ActiveHeight), 10),
+		strconv.FormatInt(int64(chg.VisibleHeight), 10),
+	}
+
+	return strings.Join(f, "\t") + "\n"
+}
+
+func parseCopyRow(line string) (int32, change.Change, error) {
+
+	var chg change.Change
+
+	f := strings.Split(line, "\t")
+	if len(f) != copyColumns {
+		return 0, chg, fmt.Errorf("expected %d columns, got %d", copyColumns, len(f))
+	}
+
+	ints := make([]int64, 0, 6)
+	for _, i := range []int{0, 1, 2, 6, 8, 9} {
+		v, err := strconv.ParseInt(f[i], 10, 64)
+		if err != nil {
+			return 0, chg, fmt.Errorf("column %d: %w", i+1, err)
+		}
+		ints = append(ints, v)
+	}
+
+	name, err := parseBytea(f[3])
+	if err != nil {
+		return 0, chg, fmt.Errorf("name: %w", err)
+	}
+	value, err := parseBytea(f[7])
+	if err != nil {
+		return 0, chg, fmt.Errorf("value: %w", err)
+	}
+	claimID, err := change.NewIDFromString(f[4])
+	if err != nil {
+		return 0, chg, fmt.Errorf("claim id: %w", err)
+	}
+	op, err := change.NewOutPointFromString(f[5])
+	if err != nil {
+		return 0, chg, fmt.Errorf("outpoint: %w", err)
+	}
+
+	chg = change.New(change.ChangeType(ints[2])).SetHeight(int32(ints[0])).SetSeq(int32(ints[1])).
+		SetName(name).SetClaimID(claimID).SetOutPoint(op).SetAmount(ints[3]).SetValue(value)
+	chg.ActiveHeight = int32(ints[4])
+	chg.VisibleHeight = int32(ints[5])
+
+	return chg.Height, chg, nil
+}
+
+// copyBytea encodes b in the hex format of bytea, with the backslash escaped for COPY.
+func copyBytea(b []byte) string {
+	return `\\x` + hex.EncodeToString(b)
+}
+
+func parseBytea(s string) ([]byte, error) {
+
+	if !strings.HasPrefix(s, `\\x`) {
+		return nil, fmt.Errorf("not a hex bytea: %.16s", s)
+	}
+	b, err := hex.DecodeString(s[3:])
+	if err != nil || len(b) > 0 {
+		return b, err
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,121 @@
+package chainrepo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+
+	"github.com/cockroachdb/pebble"
+)
+
+var (
+	// ErrDigestMismatch is returned when the changes of a block don't match their recorded digest.
+	ErrDigestMismatch = errors.New("digest mismatch")
+
+	// ErrDigestMissing is returned when the changes of a block were recorded without a digest.
+	ErrDigestMissing = errors.New("digest missing")
+)
+
+// NewPebbleWithDigests returns a repo, which chains a digest over the changes of
+// each block and the digest of the previously recorded block, and verifies it on load.
+// Tampering with, or partial corruption of, the recorded changes is detected that way.
+func NewPebbleWithDigests(path string) (*Pebble, error) {
+
+	repo, err := NewPebble(path)
+	if err != nil {
+		return nil, err
+	}
+	repo.digests = true
+
+	return repo, nil
+}
+
+// digestKey is the height followed by a 'd', which keeps the digest next to the changes of the block.
+func digestKey(height int32) []byte {
+	key := make([]byte, 5)
+	binary.BigEndian.PutUint32(key, uint32(height))
+	key[4] = 'd'
+	return key
+}
+
+func digest(prev *chainhash.Hash, height int32, value []byte) chainhash.Hash {
+	b := make([]byte, 0, chainhash.HashSize+4+len(value))
+	b = append(b, prev[:]...)
+	b = append(b, digestKey(height)[:4]...)
+	b = append(b, value...)
+	return chainhash.HashH(b)
+}
+
+// previousDigest returns the digest of the last block recorded below height,
+// or a zero hash if there is none.
+func (repo *Pebble) previousDigest(height int32) (*chainhash.Hash, error) {
+
+	iter := repo.db.NewIter(nil)
+	defer iter.Close()
+
+	for valid := iter.SeekLT(digestKey(height)[:4]); valid; valid = iter.Prev() {
+		if len(iter.Key()) != 5 {
+			continue
+		}
+		return chainhash.NewHash(iter.Value())
+	}
+
+	return &chainhash.Hash{}, iter.Error()
+}
+
+func (repo *Pebble) verifyDigest(height int32, value []byte) error {
+
+	recorded, closer, err := repo.db.Get(digestKey(height))
+	if err == pebble.ErrNotFound {
+		return fmt.Errorf("height %d: %w", height, ErrDigestMissing)
+	}
+	if err != nil {
+		return fmt.Errorf("pebble get digest: %w", err)
+	}
+	defer closer.Close()
+
+	prev, err := repo.previousDigest(height)
+	if err != nil {
+		return fmt.Errorf("previous digest: %w", err)
+	}
+
+	d := digest(prev, height, value)
+	if !bytes.Equal(d[:], recorded) {
+		return fmt.Errorf("height %d: %w", height, ErrDigestMismatch)
+	}
+
+	return nil
+}
+
+// VerifyDigests verifies the digests of all the recorded blocks,
+// and returns the height of the last one verified.
+func (repo *Pebble) VerifyDigests() (int32, error) {
+
+	iter := repo.db.NewIter(nil)
+	defer iter.Close()
+
+	var prev chainhash.Hash
+	var last int32
+	for iter.First(); iter.Valid(); iter.Next() {
+
+		if len(iter.Key()) != 4 {
+			continue
+		}
+		height := int32(binary.BigEndian.Uint32(iter.Key()))
+		value := append([]byte(nil), iter.Value()...)
+
+		if !iter.Next() || !bytes.Equal(iter.Key(), digestKey(height)) {
+			return last, fmt.Errorf("height %d: %w", height, ErrDigestMissing)
+		}
+		d := digest(&prev, height, value)
+		if !bytes.Equal(d[:], iter.Value()) {
+			return last, fmt.Errorf("height %d: %w", height, ErrDigestMismatch)
+		}
+		prev, last = d, height
+	}
+
+	return last, iter.Error()
+}
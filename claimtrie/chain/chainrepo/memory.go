@@ -0,0 +1,123 @@
+package chainrepo
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// ErrClosed is returned by the Memory repo once it's closed.
+var ErrClosed = errors.New("repo closed")
+
+// Memory keeps the changes of the blocks in memory, for the tests and regtest runs.
+type Memory struct {
+	mu      sync.RWMutex
+	changes map[int32][]change.Change
+	closed  bool
+}
+
+func NewMemory() *Memory {
+	return &Memory{changes: map[int32][]change.Change{}}
+}
+
+func (repo *Memory) Save(height int32, changes []change.Change) error {
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.closed {
+		return ErrClosed
+	}
+	repo.changes[height] = append([]change.Change(nil), changes...)
+
+	return nil
+}
+
+func (repo *Memory) Load(height int32) ([]change.Change, error) {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if repo.closed {
+		return nil, ErrClosed
+	}
+	stored, ok := repo.changes[height]
+	if !ok {
+		return nil, pebble.ErrNotFound
+	}
+
+	// In the order of their Seq, as the Pebble repo loads them.
+	changes := append([]change.Change(nil), stored...)
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Seq < changes[j].Seq
+	})
+
+	return changes, nil
+}
+
+// IterateBlocks calls f with the changes of each block from fromHeight up to, but not including,
+// toHeight, in the order of their heights, as the Pebble repo does.
+func (repo *Memory) IterateBlocks(fromHeight, toHeight int32, f func(height int32, changes []change.Change) error) error {
+
+	repo.mu.RLock()
+	var heights []int32
+	for height := range repo.changes {
+		if height >= fromHeight && height < toHeight {
+			heights = append(heights, height)
+		}
+	}
+	closed := repo.closed
+	repo.mu.RUnlock()
+
+	if closed {
+		return ErrClosed
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	for _, height := range heights {
+		changes, err := repo.Load(height)
+		if err != nil {
+			return err
+		}
+		if err = f(height, changes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Clone returns a copy of the repo, which the writes of either don't affect the other.
+func (repo *Memory) Clone() *Memory {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	// The stored slices are never written to in place, so they're shared.
+	changes := make(map[int32][]change.Change, len(repo.changes))
+	for height, chgs := range repo.changes {
+		changes[height] = chgs
+	}
+
+	return &Memory{changes: changes, closed: repo.closed}
+}
+
+func (repo *Memory) Close() error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.closed {
+		return ErrClosed
+	}
+	repo.closed = true
+
+	return nil
+}
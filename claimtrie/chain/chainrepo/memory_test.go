@@ -0,0 +1,51 @@
+package chainrepo
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+
+	r := require.New(t)
+
+	repo := NewMemory()
+	chg := change.New(change.AddClaim).SetHeight(5)
+	saved := []change.Change{
+		chg.SetSeq(1).SetName([]byte("b")),
+		chg.SetSeq(0).SetName([]byte("a")),
+	}
+	r.NoError(repo.Save(5, saved))
+	r.NoError(repo.Save(6, nil))
+	r.NoError(repo.Save(7, saved[:1]))
+
+	changes, err := repo.Load(5)
+	r.NoError(err)
+	r.Equal([]change.Change{saved[1], saved[0]}, changes)
+	_, err = repo.Load(6)
+	r.ErrorIs(err, pebble.ErrNotFound)
+
+	var heights []int32
+	r.NoError(repo.IterateBlocks(0, 8, func(height int32, changes []change.Change) error {
+		heights = append(heights, height)
+		return nil
+	}))
+	r.Equal([]int32{5, 7}, heights)
+
+	clone := repo.Clone()
+	r.NoError(clone.Save(6, saved))
+	_, err = repo.Load(6)
+	r.ErrorIs(err, pebble.ErrNotFound)
+
+	r.NoError(repo.Close())
+	_, err = repo.Load(5)
+	r.ErrorIs(err, ErrClosed)
+	r.ErrorIs(repo.Close(), ErrClosed)
+	changes, err = clone.Load(6)
+	r.NoError(err)
+	r.Len(changes, 2)
+}
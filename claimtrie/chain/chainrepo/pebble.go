@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"sort"
 
 	"github.com/btcsuite/btcd/claimtrie/change"
 	"github.com/vmihailenco/msgpack/v5"
@@ -13,6 +14,9 @@ import (
 
 type Pebble struct {
 	db *pebble.DB
+
+	// Chain a digest to the changes of each block, and verify it on load.
+	digests bool
 }
 
 func NewPebble(path string) (*Pebble, error) {
@@ -39,14 +43,31 @@ func (repo *Pebble) Save(height int32, changes []change.Change) error {
 		return fmt.Errorf("pebble prepare key: %w", err)
 	}
 
-	value, err := msgpack.Marshal(changes)
+	value := encodeChanges(height, changes)
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	err = batch.Set(key.Bytes(), value, pebble.NoSync)
 	if err != nil {
-		return fmt.Errorf("pebble msgpack marshal: %w", err)
+		return fmt.Errorf("pebble set: %w", err)
 	}
 
-	err = repo.db.Set(key.Bytes(), value, pebble.NoSync)
+	if repo.digests {
+		prev, err := repo.previousDigest(height)
+		if err != nil {
+			return fmt.Errorf("previous digest: %w", err)
+		}
+		d := digest(prev, height, value)
+		err = batch.Set(digestKey(height), d[:], pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble set digest: %w", err)
+		}
+	}
+
+	err = batch.Commit(pebble.NoSync)
 	if err != nil {
-		return fmt.Errorf("pebble set: %w", err)
+		return fmt.Errorf("pebble commit: %w", err)
 	}
 
 	return nil
@@ -66,15 +87,163 @@ func (repo *Pebble) Load(height int32) ([]change.Change, error) {
 	}
 	defer closer.Close()
 
+	if repo.digests {
+		err = repo.verifyDigest(height, b)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	changes, err := decodeChanges(b)
+	if err != nil {
+		return nil, err
+	}
+
+	// The order of changes within a block is part of consensus; don't rely on
+	// the order they happened to be stored in.
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Seq < changes[j].Seq
+	})
+
+	return changes, nil
+}
+
+// IterateBlocks calls f with the changes of each block from fromHeight up to, but not including,
+// toHeight, in the order of their heights, until f returns an error, which is returned.
+func (repo *Pebble) IterateBlocks(fromHeight, toHeight int32, f func(height int32, changes []change.Change) error) error {
+
+	lower := make([]byte, 4)
+	binary.BigEndian.PutUint32(lower, uint32(fromHeight))
+	upper := make([]byte, 4)
+	binary.BigEndian.PutUint32(upper, uint32(toHeight))
+
+	iter := repo.db.NewIter(nil)
+	defer iter.Close()
+
+	for iter.SeekGE(lower); iter.Valid() && bytes.Compare(iter.Key(), upper) < 0; iter.Next() {
+
+		if len(iter.Key()) != 4 {
+			continue // not the changes of a block
+		}
+
+		height := int32(binary.BigEndian.Uint32(iter.Key()))
+		if repo.digests {
+			err := repo.verifyDigest(height, iter.Value())
+			if err != nil {
+				return err
+			}
+		}
+
+		changes, err := decodeChanges(iter.Value())
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(changes, func(i, j int) bool {
+			return changes[i].Seq < changes[j].Seq
+		})
+
+		err = f(height, changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BackfillSeq assigns sequence numbers, in their stored order, to the changes of
+// blocks recorded before sequence numbers were introduced.
+// It returns the number of blocks migrated.
+func (repo *Pebble) BackfillSeq() (int, error) {
+
+	iter := repo.db.NewIter(nil)
+	defer iter.Close()
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	migrated := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+
+		if len(iter.Key()) != 4 {
+			continue // not the changes of a block
+		}
+
+		changes, err := decodeChanges(iter.Value())
+		if err != nil {
+			return migrated, err
+		}
+
+		needed := false
+		for i := range changes {
+			if changes[i].Seq != 0 {
+				needed = false
+				break
+			}
+			needed = needed || i > 0
+		}
+		if !needed {
+			continue
+		}
+
+		for i := range changes {
+			changes[i].Seq = int32(i)
+		}
+		height := int32(binary.BigEndian.Uint32(iter.Key()))
+		err = batch.Set(iter.Key(), encodeChanges(height, changes), pebble.NoSync)
+		if err != nil {
+			return migrated, fmt.Errorf("pebble set: %w", err)
+		}
+		migrated++
+	}
+
+	err := batch.Commit(pebble.NoSync)
+	if err != nil {
+		return migrated, fmt.Errorf("pebble commit: %w", err)
+	}
+
+	return migrated, nil
+}
+
+// protoMarker precedes the changes stored as a Block message of change.proto. It's a reserved
+// code of msgpack, which the changes stored by earlier versions, as a msgpack array, never start with.
+const protoMarker = 0xc1
+
+func encodeChanges(height int32, changes []change.Change) []byte {
+	return append([]byte{protoMarker}, change.MarshalBlock(height, changes)...)
+}
+
+// decodeChanges returns the changes stored, either as protobuf, or as msgpack by earlier versions.
+func decodeChanges(b []byte) ([]change.Change, error) {
+
+	if len(b) > 0 && b[0] == protoMarker {
+		_, changes, err := change.UnmarshalBlock(b[1:])
+		if err != nil {
+			return nil, fmt.Errorf("protobuf unmarshal: %w", err)
+		}
+		return changes, nil
+	}
+
 	var changes []change.Change
-	err = msgpack.Unmarshal(b, &changes)
+	err := msgpack.Unmarshal(b, &changes)
 	if err != nil {
-		return nil, fmt.Errorf("pebble msgpack marshal: %w", err)
+		return nil, fmt.Errorf("pebble msgpack unmarshal: %w", err)
 	}
 
 	return changes, nil
 }
 
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Pebble) Backup(dir string) error {
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
+}
+
 func (repo *Pebble) Close() error {
 
 	err := repo.db.Flush()
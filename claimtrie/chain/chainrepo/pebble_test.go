@@ -0,0 +1,209 @@
+package chainrepo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestLoadOrderedBySeq(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	chg := change.New(change.AddClaim).SetHeight(5)
+	saved := []change.Change{
+		chg.SetSeq(2).SetName([]byte("c")),
+		chg.SetSeq(0).SetName([]byte("a")),
+		chg.SetSeq(1).SetName([]byte("b")),
+	}
+	r.NoError(repo.Save(5, saved))
+
+	changes, err := repo.Load(5)
+	r.NoError(err)
+	r.Equal([]change.Change{saved[1], saved[2], saved[0]}, changes)
+}
+
+func TestBackfillSeq(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	chg := change.New(change.AddClaim)
+	legacy := []change.Change{
+		chg.SetHeight(1).SetName([]byte("b")),
+		chg.SetHeight(1).SetName([]byte("a")),
+	}
+	r.NoError(repo.Save(1, legacy))
+	r.NoError(repo.Save(2, []change.Change{chg.SetHeight(2)}))
+	r.NoError(repo.Save(3, []change.Change{chg.SetHeight(3).SetSeq(0), chg.SetHeight(3).SetSeq(1)}))
+
+	migrated, err := repo.BackfillSeq()
+	r.NoError(err)
+	r.Equal(1, migrated)
+
+	changes, err := repo.Load(1)
+	r.NoError(err)
+	r.Equal([]change.Change{legacy[0].SetSeq(0), legacy[1].SetSeq(1)}, changes)
+
+	migrated, err = repo.BackfillSeq()
+	r.NoError(err)
+	r.Equal(0, migrated)
+}
+
+func TestDigests(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebbleWithDigests(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	chg := change.New(change.AddClaim)
+	for _, height := range []int32{1, 2, 5} {
+		r.NoError(repo.Save(height, []change.Change{chg.SetHeight(height).SetName([]byte("a"))}))
+	}
+
+	changes, err := repo.Load(5)
+	r.NoError(err)
+	r.Len(changes, 1)
+
+	last, err := repo.VerifyDigests()
+	r.NoError(err)
+	r.Equal(int32(5), last)
+
+	// Tamper with the changes of a block in the middle of the chain.
+	repo.digests = false
+	r.NoError(repo.Save(2, []change.Change{chg.SetHeight(2).SetName([]byte("b"))}))
+	repo.digests = true
+
+	_, err = repo.Load(2)
+	r.ErrorIs(err, ErrDigestMismatch)
+	last, err = repo.VerifyDigests()
+	r.ErrorIs(err, ErrDigestMismatch)
+	r.Equal(int32(1), last)
+
+	// Blocks recorded without digests.
+	repo.digests = false
+	r.NoError(repo.Save(6, []change.Change{chg.SetHeight(6)}))
+	repo.digests = true
+	_, err = repo.Load(6)
+	r.ErrorIs(err, ErrDigestMissing)
+}
+
+func TestCopyRoundTrip(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	op, err := change.NewOutPointFromString("0100000000000000000000000000000000000000000000000000000000000000:3")
+	r.NoError(err)
+	chg := change.New(change.AddClaim).SetOutPoint(op).SetAmount(7).SetValue([]byte("va\tl\\ue\n"))
+	saved := map[int32][]change.Change{
+		1: {chg.SetHeight(1).SetName([]byte("a")), chg.SetHeight(1).SetSeq(1).SetName([]byte("b\tc"))},
+		3: {chg.SetHeight(3).SetName([]byte("d"))},
+		9: {chg.SetHeight(9).SetName([]byte("e"))},
+	}
+	saved[3][0].ActiveHeight = 5
+	for height, changes := range saved {
+		r.NoError(repo.Save(height, changes))
+	}
+
+	var dump bytes.Buffer
+	rows, err := repo.ExportCopy(&dump, 1, 9)
+	r.NoError(err)
+	r.Equal(3, rows)
+
+	imported, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := imported.Close()
+		r.NoError(err)
+	}()
+
+	rows, err = imported.ImportCopy(bytes.NewReader(append(dump.Bytes(), "\\.\n"...)))
+	r.NoError(err)
+	r.Equal(3, rows)
+	for _, height := range []int32{1, 3} {
+		changes, err := imported.Load(height)
+		r.NoError(err)
+		r.Equal(saved[height], changes)
+	}
+	_, err = imported.Load(9)
+	r.Error(err)
+
+	lines := strings.SplitAfter(dump.String(), "\n")
+	_, err = imported.ImportCopy(strings.NewReader(lines[0] + lines[2] + lines[1]))
+	r.ErrorIs(err, ErrCopyUnordered)
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	// The changes stored as msgpack by earlier versions are still loaded.
+	chg := change.New(change.AddClaim).SetAmount(7).SetValue([]byte("value"))
+	legacy := []change.Change{chg.SetHeight(1).SetName([]byte("a"))}
+	value, err := msgpack.Marshal(legacy)
+	r.NoError(err)
+	r.NoError(repo.db.Set([]byte{0, 0, 0, 1}, value, pebble.Sync))
+
+	saved := []change.Change{chg.SetHeight(2).SetName([]byte("b")), chg.SetHeight(2).SetSeq(1).SetName([]byte("c"))}
+	r.NoError(repo.Save(2, saved))
+
+	var export bytes.Buffer
+	rows, err := repo.ExportProto(&export, 0, 3)
+	r.NoError(err)
+	r.Equal(3, rows)
+
+	imported, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := imported.Close()
+		r.NoError(err)
+	}()
+
+	rows, err = imported.ImportProto(&export)
+	r.NoError(err)
+	r.Equal(3, rows)
+	changes, err := imported.Load(1)
+	r.NoError(err)
+	r.Equal(legacy, changes)
+	changes, err = imported.Load(2)
+	r.NoError(err)
+	r.Equal(saved, changes)
+}
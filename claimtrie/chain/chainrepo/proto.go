@@ -0,0 +1,70 @@
+package chainrepo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+)
+
+// ExportProto writes the changes of blocks from fromHeight up to, but not including, toHeight
+// as Block messages of change.proto, each prefixed with its length. It returns the number of changes written.
+func (repo *Pebble) ExportProto(w io.Writer, fromHeight, toHeight int32) (int, error) {
+
+	bw := bufio.NewWriterSize(w, 1<<20)
+	rows := 0
+	err := repo.IterateBlocks(fromHeight, toHeight, func(height int32, changes []change.Change) error {
+		err := change.WriteDelimitedBlock(bw, height, changes)
+		if err != nil {
+			return fmt.Errorf("write block: %w", err)
+		}
+		rows += len(changes)
+		return nil
+	})
+	if err != nil {
+		return rows, err
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return rows, fmt.Errorf("write blocks: %w", err)
+	}
+
+	return rows, nil
+}
+
+// ImportProto saves the changes read from an export of ExportProto. It returns the number of changes read.
+func (repo *Pebble) ImportProto(r io.Reader) (int, error) {
+
+	return ReadProto(r, func(height int32, changes []change.Change) error {
+		err := repo.Save(height, changes)
+		if err != nil {
+			return fmt.Errorf("save changes at %d: %w", height, err)
+		}
+		return nil
+	})
+}
+
+// ReadProto calls fn with the changes of each block read from an export of ExportProto, without any repo.
+// It returns the number of changes read.
+func ReadProto(r io.Reader, fn func(height int32, changes []change.Change) error) (int, error) {
+
+	br := bufio.NewReaderSize(r, 1<<20)
+	rows := 0
+	for {
+		height, changes, err := change.ReadDelimitedBlock(br)
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, fmt.Errorf("block after %d changes: %w", rows, err)
+		}
+		err = fn(height, changes)
+		if err != nil {
+			return rows, err
+		}
+		rows += len(changes)
+	}
+}
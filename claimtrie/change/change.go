@@ -13,10 +13,11 @@ const (
 type Change struct {
 	Type   ChangeType
 	Height int32
+	Seq    int32 // order of the change within the block, from 1 in the ClaimTrie; 0 in the legacy changes
 
 	Name     []byte
-	ClaimID  string
-	OutPoint string
+	ClaimID  ClaimID
+	OutPoint OutPoint
 	Amount   int64
 	Value    []byte
 
@@ -33,17 +34,22 @@ func (c Change) SetHeight(height int32) Change {
 	return c
 }
 
+func (c Change) SetSeq(seq int32) Change {
+	c.Seq = seq
+	return c
+}
+
 func (c Change) SetName(name []byte) Change {
 	c.Name = name
 	return c
 }
 
-func (c Change) SetClaimID(claimID string) Change {
+func (c Change) SetClaimID(claimID ClaimID) Change {
 	c.ClaimID = claimID
 	return c
 }
 
-func (c Change) SetOutPoint(op string) Change {
+func (c Change) SetOutPoint(op OutPoint) Change {
 	c.OutPoint = op
 	return c
 }
@@ -57,3 +63,19 @@ func (c Change) SetValue(value []byte) Change {
 	c.Value = value
 	return c
 }
+
+// Key identifies a change of a name, so that the ones applied again, such as
+// when a block is re-fed after a partial failure, can be told apart. Only the
+// changes with a Seq are keyed: the legacy changes, of which it's 0, may be
+// legitimately applied twice at a height, and are applied as they were.
+type Key struct {
+	Height   int32
+	Seq      int32
+	OutPoint OutPoint
+	Type     ChangeType
+}
+
+// Key returns the dedup key of the change, and false if it has no Seq to be deduped by.
+func (c Change) Key() (Key, bool) {
+	return Key{Height: c.Height, Seq: c.Seq, OutPoint: c.OutPoint, Type: c.Type}, c.Seq != 0
+}
@@ -0,0 +1,56 @@
+package change
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error is an error in handling a change, with the context of the change.
+type Error struct {
+	Name     []byte
+	Height   int32
+	ClaimID  ClaimID
+	OutPoint OutPoint
+	Err      error
+}
+
+// Wrap returns err with the name, height, claim ID and outpoint of the change,
+// or err itself, if it's nil or already carries the context of a change.
+func Wrap(err error, chg Change) error {
+
+	var e *Error
+	if err == nil || errors.As(err, &e) {
+		return err
+	}
+
+	return &Error{
+		Name:     chg.Name,
+		Height:   chg.Height,
+		ClaimID:  chg.ClaimID,
+		OutPoint: chg.OutPoint,
+		Err:      err,
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s, %s", e.Err, strings.Join(e.Fields(), ", "))
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Fields returns the context of the change as "key: value" pairs, omitting the unset ones.
+func (e *Error) Fields() []string {
+
+	fields := []string{fmt.Sprintf("name: %q", e.Name), fmt.Sprintf("height: %d", e.Height)}
+	if e.ClaimID != (ClaimID{}) {
+		fields = append(fields, fmt.Sprintf("claimID: %s", e.ClaimID))
+	}
+	if e.OutPoint != (OutPoint{}) {
+		fields = append(fields, fmt.Sprintf("outpoint: %s", e.OutPoint))
+	}
+
+	return fields
+}
@@ -0,0 +1,32 @@
+package change
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrap(t *testing.T) {
+
+	r := require.New(t)
+
+	r.NoError(Wrap(nil, New(AddClaim)))
+
+	errTest := errors.New("test")
+	op := *wire.NewOutPoint(&chainhash.Hash{1, 2, 3}, 7)
+	chg := New(AddClaim).SetName([]byte("test")).SetHeight(5).SetOutPoint(NewOutPoint(op))
+	err := Wrap(fmt.Errorf("apply: %w", errTest), chg)
+	r.ErrorIs(err, errTest)
+	r.Equal(`apply: test, name: "test", height: 5, outpoint: `+op.String(), err.Error())
+
+	// The innermost context is kept.
+	err = Wrap(fmt.Errorf("outer: %w", err), chg.SetName([]byte("other")))
+	var e *Error
+	r.ErrorAs(err, &e)
+	r.Equal([]byte("test"), e.Name)
+}
@@ -0,0 +1,163 @@
+package change
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// ClaimID represents a Claim's ClaimID.
+type ClaimID [20]byte
+
+// NewIDFromString returns a Claim ID from its hex string, which is exactly 40 characters.
+func NewIDFromString(s string) (ClaimID, error) {
+
+	var id ClaimID
+	if len(s) != 2*len(id) {
+		return ClaimID{}, fmt.Errorf("invalid claim ID length: %d, expected %d", len(s), 2*len(id))
+	}
+	if _, err := hex.Decode(id[:], []byte(s)); err != nil {
+		return ClaimID{}, fmt.Errorf("invalid claim ID: %w", err)
+	}
+	for i, j := 0, len(id)-1; i < j; i, j = i+1, j-1 {
+		id[i], id[j] = id[j], id[i]
+	}
+
+	return id, nil
+}
+
+func (id ClaimID) String() string {
+
+	for i, j := 0, len(id)-1; i < j; i, j = i+1, j-1 {
+		id[i], id[j] = id[j], id[i]
+	}
+
+	return hex.EncodeToString(id[:])
+}
+
+func (id ClaimID) EncodeMsgpack(enc *msgpack.Encoder) error {
+	return enc.EncodeBytes(id[:])
+}
+
+// DecodeMsgpack also accepts the hex strings stored by earlier versions.
+func (id *ClaimID) DecodeMsgpack(dec *msgpack.Decoder) error {
+
+	s, b, err := decodeStringOrBytes(dec)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		if len(s) > 0 {
+			*id, err = NewIDFromString(s)
+		}
+		return err
+	}
+
+	return copyFixed(id[:], b)
+}
+
+// OutPoint is the binary, comparable form of a wire.OutPoint:
+// the transaction hash followed by the big-endian output index.
+type OutPoint [chainhash.HashSize + 4]byte
+
+// NewOutPoint returns the binary form of op.
+func NewOutPoint(op wire.OutPoint) OutPoint {
+
+	var o OutPoint
+	copy(o[:chainhash.HashSize], op.Hash[:])
+	binary.BigEndian.PutUint32(o[chainhash.HashSize:], op.Index)
+
+	return o
+}
+
+// NewOutPointFromString returns an OutPoint from a "<txid>:<index>" string.
+func NewOutPointFromString(s string) (OutPoint, error) {
+
+	f := strings.Split(s, ":")
+	if len(f) != 2 {
+		return OutPoint{}, fmt.Errorf("invalid outpoint: %s", s)
+	}
+	hash, err := chainhash.NewHashFromStr(f[0])
+	if err != nil {
+		return OutPoint{}, fmt.Errorf("invalid outpoint hash: %w", err)
+	}
+	idx, err := strconv.ParseUint(f[1], 10, 32)
+	if err != nil {
+		return OutPoint{}, fmt.Errorf("invalid outpoint index: %w", err)
+	}
+
+	return NewOutPoint(*wire.NewOutPoint(hash, uint32(idx))), nil
+}
+
+// Wire returns the OutPoint as a wire.OutPoint.
+func (o OutPoint) Wire() wire.OutPoint {
+
+	var op wire.OutPoint
+	copy(op.Hash[:], o[:chainhash.HashSize])
+	op.Index = binary.BigEndian.Uint32(o[chainhash.HashSize:])
+
+	return op
+}
+
+func (o OutPoint) String() string {
+	op := o.Wire()
+	return op.String()
+}
+
+func (o OutPoint) EncodeMsgpack(enc *msgpack.Encoder) error {
+	return enc.EncodeBytes(o[:])
+}
+
+// DecodeMsgpack also accepts the "<txid>:<index>" strings stored by earlier versions.
+func (o *OutPoint) DecodeMsgpack(dec *msgpack.Decoder) error {
+
+	s, b, err := decodeStringOrBytes(dec)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		if len(s) > 0 {
+			*o, err = NewOutPointFromString(s)
+		}
+		return err
+	}
+
+	return copyFixed(o[:], b)
+}
+
+func decodeStringOrBytes(dec *msgpack.Decoder) (string, []byte, error) {
+
+	code, err := dec.PeekCode()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if msgpcode.IsString(code) {
+		s, err := dec.DecodeString()
+		return s, nil, err
+	}
+
+	b, err := dec.DecodeBytes()
+	if b == nil && err == nil {
+		b = []byte{}
+	}
+	return "", b, err
+}
+
+func copyFixed(dst, src []byte) error {
+
+	if len(src) != 0 && len(src) != len(dst) {
+		return fmt.Errorf("invalid length: %d, expected %d", len(src), len(dst))
+	}
+	copy(dst, src)
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package change
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestMsgpackBinaryRoundTrip(t *testing.T) {
+
+	r := require.New(t)
+
+	op := *wire.NewOutPoint(&chainhash.Hash{1, 2, 3}, 7)
+	chg := New(AddClaim).SetName([]byte("test")).SetOutPoint(NewOutPoint(op)).SetClaimID(ClaimID{4, 5, 6})
+
+	b, err := msgpack.Marshal(chg)
+	r.NoError(err)
+
+	var decoded Change
+	r.NoError(msgpack.Unmarshal(b, &decoded))
+	r.Equal(chg, decoded)
+	r.Equal(op, decoded.OutPoint.Wire())
+	r.Equal(op.String(), decoded.OutPoint.String())
+}
+
+func TestMsgpackLegacyStrings(t *testing.T) {
+
+	r := require.New(t)
+
+	type legacyChange struct {
+		Type     ChangeType
+		Height   int32
+		Name     []byte
+		ClaimID  string
+		OutPoint string
+	}
+
+	op := *wire.NewOutPoint(&chainhash.Hash{1, 2, 3}, 7)
+	id := ClaimID{4, 5, 6}
+	b, err := msgpack.Marshal(legacyChange{
+		Type:     SpendClaim,
+		Height:   5,
+		Name:     []byte("test"),
+		ClaimID:  id.String(),
+		OutPoint: op.String(),
+	})
+	r.NoError(err)
+
+	var decoded Change
+	r.NoError(msgpack.Unmarshal(b, &decoded))
+	r.Equal(New(SpendClaim).SetHeight(5).SetName([]byte("test")).SetOutPoint(NewOutPoint(op)).SetClaimID(id), decoded)
+}
+
+func TestNewIDFromString(t *testing.T) {
+
+	r := require.New(t)
+
+	id := ClaimID{4, 5, 6}
+	parsed, err := NewIDFromString(id.String())
+	r.NoError(err)
+	r.Equal(id, parsed)
+
+	_, err = NewIDFromString(id.String() + "00") // would overflow the ID
+	r.Error(err)
+	_, err = NewIDFromString(id.String()[2:]) // would be zero-padded into another ID
+	r.Error(err)
+	_, err = NewIDFromString("zz" + id.String()[2:])
+	r.Error(err)
+	_, err = NewIDFromString("")
+	r.Error(err)
+
+	// A legacy string of an invalid ID fails to decode, instead of panicking.
+	b, err := msgpack.Marshal(struct{ ClaimID string }{id.String() + id.String()})
+	r.NoError(err)
+	var decoded struct{ ClaimID ClaimID }
+	r.Error(msgpack.Unmarshal(b, &decoded))
+}
@@ -0,0 +1,233 @@
+package change
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The wire types, and the field numbers, of the messages in change.proto.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+
+	blockHeight  = 1
+	blockChanges = 2
+
+	changeType          = 1
+	changeHeight        = 2
+	changeSeq           = 3
+	changeName          = 4
+	changeClaimID       = 5
+	changeOutPoint      = 6
+	changeAmount        = 7
+	changeValue         = 8
+	changeActiveHeight  = 9
+	changeVisibleHeight = 10
+)
+
+// The longest Block message read, well over any block.
+const maxProtoBlock = 256 << 20
+
+// ErrInvalidProto is returned when a message doesn't conform to change.proto.
+var ErrInvalidProto = errors.New("invalid protobuf message")
+
+// MarshalBlock returns the changes of the block at the height as a Block message of change.proto.
+func MarshalBlock(height int32, changes []Change) []byte {
+
+	var b []byte
+	b = appendVarintField(b, blockHeight, uint64(height))
+	var msg []byte
+	for _, chg := range changes {
+		msg = chg.appendProto(msg[:0])
+		b = appendBytesField(b, blockChanges, msg, true)
+	}
+
+	return b
+}
+
+// UnmarshalBlock returns the height, and the changes, of a Block message of change.proto.
+// The changes don't share any memory with b.
+func UnmarshalBlock(b []byte) (int32, []Change, error) {
+
+	var height int32
+	var changes []Change
+	err := parseProto(b, func(field int, v uint64, msg []byte) error {
+		switch field {
+		case blockHeight:
+			height = int32(v)
+		case blockChanges:
+			var chg Change
+			err := chg.parseProto(msg)
+			if err != nil {
+				return fmt.Errorf("change %d: %w", len(changes), err)
+			}
+			changes = append(changes, chg)
+		}
+		return nil
+	})
+
+	return height, changes, err
+}
+
+// WriteDelimitedBlock writes the Block message of the changes, prefixed with its length as a varint.
+func WriteDelimitedBlock(w io.Writer, height int32, changes []Change) error {
+
+	msg := MarshalBlock(height, changes)
+	_, err := w.Write(appendVarint(nil, uint64(len(msg))))
+	if err == nil {
+		_, err = w.Write(msg)
+	}
+
+	return err
+}
+
+// ReadDelimitedBlock reads a Block message written by WriteDelimitedBlock.
+// It returns io.EOF, if there are no more.
+func ReadDelimitedBlock(r *bufio.Reader) (int32, []Change, error) {
+
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if size > maxProtoBlock {
+		return 0, nil, fmt.Errorf("%w: block of %d bytes", ErrInvalidProto, size)
+	}
+
+	msg := make([]byte, size)
+	_, err = io.ReadFull(r, msg)
+	if err != nil {
+		return 0, nil, fmt.Errorf("read block: %w", io.ErrUnexpectedEOF)
+	}
+
+	return UnmarshalBlock(msg)
+}
+
+// appendProto appends the change as a Change message, leaving out the fields with the default values.
+func (c Change) appendProto(b []byte) []byte {
+
+	b = appendVarintField(b, changeType, uint64(c.Type))
+	b = appendVarintField(b, changeHeight, uint64(c.Height))
+	b = appendVarintField(b, changeSeq, uint64(c.Seq))
+	b = appendBytesField(b, changeName, c.Name, false)
+	if c.ClaimID != (ClaimID{}) {
+		b = appendBytesField(b, changeClaimID, c.ClaimID[:], false)
+	}
+	if c.OutPoint != (OutPoint{}) {
+		b = appendBytesField(b, changeOutPoint, c.OutPoint[:], false)
+	}
+	b = appendVarintField(b, changeAmount, uint64(c.Amount))
+	b = appendBytesField(b, changeValue, c.Value, false)
+	b = appendVarintField(b, changeActiveHeight, uint64(c.ActiveHeight))
+	b = appendVarintField(b, changeVisibleHeight, uint64(c.VisibleHeight))
+
+	return b
+}
+
+func (c *Change) parseProto(b []byte) error {
+
+	return parseProto(b, func(field int, v uint64, msg []byte) error {
+		switch field {
+		case changeType:
+			c.Type = ChangeType(int32(v))
+		case changeHeight:
+			c.Height = int32(v)
+		case changeSeq:
+			c.Seq = int32(v)
+		case changeName:
+			c.Name = append([]byte(nil), msg...)
+		case changeClaimID:
+			return copyFixed(c.ClaimID[:], msg)
+		case changeOutPoint:
+			return copyFixed(c.OutPoint[:], msg)
+		case changeAmount:
+			c.Amount = int64(v)
+		case changeValue:
+			c.Value = append([]byte(nil), msg...)
+		case changeActiveHeight:
+			c.ActiveHeight = int32(v)
+		case changeVisibleHeight:
+			c.VisibleHeight = int32(v)
+		}
+		return nil
+	})
+}
+
+// parseProto calls fn with the number of each field of the message, and its value, either
+// a varint, or the bytes of a length-delimited one. The other fields are skipped.
+func parseProto(b []byte, fn func(field int, v uint64, msg []byte) error) error {
+
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return fmt.Errorf("%w: tag", ErrInvalidProto)
+		}
+		b = b[n:]
+
+		field := int(tag >> 3)
+		var v uint64
+		var msg []byte
+		switch tag & 7 {
+		case protoVarint:
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return fmt.Errorf("%w: varint of field %d", ErrInvalidProto, field)
+			}
+			b = b[n:]
+		case protoBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return fmt.Errorf("%w: length of field %d", ErrInvalidProto, field)
+			}
+			msg = b[n : n+int(size)]
+			b = b[n+int(size):]
+		case protoFixed64, protoFixed32:
+			size := 8
+			if tag&7 == protoFixed32 {
+				size = 4
+			}
+			if len(b) < size {
+				return fmt.Errorf("%w: field %d", ErrInvalidProto, field)
+			}
+			b = b[size:]
+			continue
+		default:
+			return fmt.Errorf("%w: wire type %d of field %d", ErrInvalidProto, tag&7, field)
+		}
+
+		err := fn(field, v, msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// appendVarintField appends the field, unless it's 0. The negative ints are sign extended to 64 bits, as in protobuf.
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendVarint(b, uint64(field)<<3|protoVarint)
+	return appendVarint(b, v)
+}
+
+// appendBytesField appends the field, unless it's empty and not a message, which is always.
+func appendBytesField(b []byte, field int, v []byte, message bool) []byte {
+	if len(v) == 0 && !message {
+		return b
+	}
+	b = appendVarint(b, uint64(field)<<3|protoBytes)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
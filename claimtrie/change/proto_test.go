@@ -0,0 +1,72 @@
+package change
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+
+	r := require.New(t)
+
+	op := NewOutPoint(*wire.NewOutPoint(&chainhash.Hash{1, 2, 3}, 7))
+	chg := New(UpdateClaim).SetHeight(9).SetName([]byte("test")).SetOutPoint(op).SetClaimID(ClaimID{4, 5, 6})
+	changes := []Change{
+		chg.SetSeq(0).SetAmount(1 << 40).SetValue([]byte{0, 1, 2}),
+		New(AddClaim).SetHeight(9).SetSeq(1), // all the defaults
+		chg.SetSeq(2).SetAmount(-5),
+	}
+	changes[2].ActiveHeight = -1
+	changes[2].VisibleHeight = 10
+
+	b := MarshalBlock(9, changes)
+	height, decoded, err := UnmarshalBlock(b)
+	r.NoError(err)
+	r.Equal(int32(9), height)
+	r.Equal(changes, decoded)
+
+	// The fields of later versions are skipped.
+	unknown := appendVarintField(nil, 15, 3)
+	unknown = appendBytesField(unknown, 16, []byte("x"), false)
+	unknown = append(unknown, 12<<3|protoFixed32, 1, 2, 3, 4)
+	_, decoded, err = UnmarshalBlock(append(unknown, b...))
+	r.NoError(err)
+	r.Equal(changes, decoded)
+
+	_, _, err = UnmarshalBlock(b[:len(b)-1])
+	r.ErrorIs(err, ErrInvalidProto)
+}
+
+func TestDelimitedBlocks(t *testing.T) {
+
+	r := require.New(t)
+
+	var buf bytes.Buffer
+	r.NoError(WriteDelimitedBlock(&buf, 1, []Change{New(AddClaim).SetHeight(1).SetName([]byte("a"))}))
+	r.NoError(WriteDelimitedBlock(&buf, 3, []Change{New(SpendClaim).SetHeight(3).SetName([]byte("a"))}))
+
+	br := bufio.NewReader(bytes.NewReader(buf.Bytes()))
+	height, changes, err := ReadDelimitedBlock(br)
+	r.NoError(err)
+	r.Equal(int32(1), height)
+	r.Equal(AddClaim, changes[0].Type)
+	height, changes, err = ReadDelimitedBlock(br)
+	r.NoError(err)
+	r.Equal(int32(3), height)
+	r.Equal(SpendClaim, changes[0].Type)
+	_, _, err = ReadDelimitedBlock(br)
+	r.Equal(io.EOF, err)
+
+	br = bufio.NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	_, _, err = ReadDelimitedBlock(br)
+	r.NoError(err)
+	_, _, err = ReadDelimitedBlock(br)
+	r.ErrorIs(err, io.ErrUnexpectedEOF)
+}
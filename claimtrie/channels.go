@@ -0,0 +1,188 @@
+package claimtrie
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// ChannelStats are the aggregates of the active claims signed by a channel.
+type ChannelStats struct {
+	Claims      int
+	TotalStaked int64 // effective amounts of the claims, including their supports.
+	UpdatedAt   int32 // the last height at which the aggregates changed, or were rebuilt on startup.
+}
+
+type channelShare struct {
+	claims int
+	staked int64
+}
+
+type channelIndex struct {
+	mu     sync.RWMutex
+	stats  map[node.ClaimID]*ChannelStats
+	shares map[string]map[node.ClaimID]channelShare // by name
+}
+
+// ChannelStats returns the aggregates of the claims signed by the channel.
+// It returns false if no active claim is signed by it, or the aggregates aren't maintained.
+func (ct *ClaimTrie) ChannelStats(channelID node.ClaimID) (ChannelStats, bool) {
+
+	if ct.channels == nil {
+		return ChannelStats{}, false
+	}
+
+	ct.channels.mu.RLock()
+	defer ct.channels.mu.RUnlock()
+
+	s, ok := ct.channels.stats[channelID]
+	if !ok {
+		return ChannelStats{}, false
+	}
+	return *s, true
+}
+
+func newChannelIndex() *channelIndex {
+	return &channelIndex{
+		stats:  map[node.ClaimID]*ChannelStats{},
+		shares: map[string]map[node.ClaimID]channelShare{},
+	}
+}
+
+// rebuildChannelIndex aggregates the claims of all the names from scratch.
+func (ct *ClaimTrie) rebuildChannelIndex() error {
+
+	ct.channels.mu.Lock()
+	ct.channels.stats = map[node.ClaimID]*ChannelStats{}
+	ct.channels.shares = map[string]map[node.ClaimID]channelShare{}
+	ct.channels.mu.Unlock()
+
+	var names [][]byte
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		names = append(names, append([]byte(nil), name...))
+		return true
+	})
+
+	return ct.updateChannelIndex(names)
+}
+
+// updateChannelIndex replaces the shares of the names in the aggregates of their channels.
+func (ct *ClaimTrie) updateChannelIndex(names [][]byte) error {
+
+	ct.channels.mu.Lock()
+	defer ct.channels.mu.Unlock()
+
+	// The names are keyed as the node manager resolves them, so that the nodes
+	// stored under the names, which normalize to the same one, are counted once.
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = node.NormalizeIfNecessary(name, ct.height)
+		if seen[string(name)] {
+			continue
+		}
+		seen[string(name)] = true
+
+		n, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return fmt.Errorf("node: %w", err)
+		}
+
+		shares, err := ct.channelShares(n)
+		if err != nil {
+			return err
+		}
+		previous := ct.channels.shares[string(name)]
+		for id, p := range previous {
+			if s, ok := shares[id]; !ok || s != p {
+				ct.channels.add(id, -p.claims, -p.staked, ct.height)
+			}
+		}
+		for id, s := range shares {
+			if p, ok := previous[id]; !ok || s != p {
+				ct.channels.add(id, s.claims, s.staked, ct.height)
+			}
+		}
+
+		if len(shares) == 0 {
+			delete(ct.channels.shares, string(name))
+		} else {
+			ct.channels.shares[string(name)] = shares
+		}
+	}
+
+	return nil
+}
+
+// rewindChannelIndex updates the aggregates of the names after resetting from a later height.
+func (ct *ClaimTrie) rewindChannelIndex(names [][]byte, from int32) error {
+
+	var err error
+	if ct.height < param.NormalizedNameForkHeight && from >= param.NormalizedNameForkHeight {
+		err = ct.rebuildChannelIndex() // the names were normalized in between
+	} else {
+		err = ct.updateChannelIndex(names)
+	}
+	if err != nil {
+		return err
+	}
+
+	ct.channels.mu.Lock()
+	defer ct.channels.mu.Unlock()
+
+	for _, s := range ct.channels.stats {
+		if s.UpdatedAt > ct.height {
+			s.UpdatedAt = ct.height
+		}
+	}
+
+	return nil
+}
+
+func (ci *channelIndex) add(id node.ClaimID, claims int, staked int64, height int32) {
+
+	s, ok := ci.stats[id]
+	if !ok {
+		s = &ChannelStats{}
+		ci.stats[id] = s
+	}
+	s.Claims += claims
+	s.TotalStaked += staked
+	s.UpdatedAt = height
+
+	if s.Claims == 0 {
+		delete(ci.stats, id)
+	}
+}
+
+func (ct *ClaimTrie) channelShares(n *node.Node) (map[node.ClaimID]channelShare, error) {
+
+	if n == nil {
+		return nil, nil
+	}
+
+	var shares map[node.ClaimID]channelShare
+	for _, c := range n.Claims {
+		if c.Status != node.Activated {
+			continue
+		}
+		value, err := ct.ClaimValue(c)
+		if err != nil {
+			return nil, err
+		}
+		id, ok := node.SigningChannel(value)
+		if !ok {
+			continue
+		}
+		if shares == nil {
+			shares = map[node.ClaimID]channelShare{}
+		}
+		s := shares[id]
+		s.claims++
+		s.staked += c.EffectiveAmount(n.Supports)
+		shares[id] = s
+	}
+
+	return shares, nil
+}
@@ -0,0 +1,271 @@
+package claimtrie
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// The orders of the claims in an export.
+const (
+	ExportByClaimID = "claimid" // by Claim ID, then outpoint.
+	ExportByName    = "name"    // by name, then Claim ID, then outpoint.
+)
+
+// ExportCopyTable is the schema of the Postgres table, which the rows of an export, past its header,
+// are copied into with:
+//
+//	tail -n +259 claims.tsv | psql -c 'COPY claims FROM STDIN'
+const ExportCopyTable = `CREATE TABLE claims (
+	claim_id text   NOT NULL,
+	name     bytea  NOT NULL,
+	outpoint text   NOT NULL,
+	amount   bigint NOT NULL,
+	status   text   NOT NULL
+)`
+
+const (
+	exportMagic     = "claimtrie-claims"
+	exportVersion   = 1
+	exportBuckets   = 256
+	exportIndexLine = len("00 0000000000000000\n")
+)
+
+// ExportIndex is the header of an export of the claims, which indexes its rows, sorted by their keys, the
+// Claim IDs or the names, by the first byte of the keys, so that the ones of a key can be read by seeking.
+//
+// The export is in the text format of Postgres COPY, after the header:
+//
+//	claimtrie-claims v1 order=<order> height=<height> root=<root> rows=<rows> size=<bytes of the rows>
+//	<first byte of the keys, in hex> <offset of the first row with it, relative to Start, in 16 hex digits> * 256
+//	<blank line>
+//	<claim ID> <name, as \x-prefixed hex> <outpoint> <amount> <status> * rows
+type ExportIndex struct {
+	Order  string
+	Height int32
+	Root   chainhash.Hash
+	Rows   int
+
+	Start   int64                    // The offset of the first row.
+	Offsets [exportBuckets + 1]int64 // The offsets of the buckets, relative to Start, and of the end of the rows.
+}
+
+// Bucket returns the range of the offsets, relative to Start, of the rows with the keys starting with b.
+func (x *ExportIndex) Bucket(b byte) (int64, int64) {
+	return x.Offsets[b], x.Offsets[int(b)+1]
+}
+
+// ExportedClaim is a row of an export of the claims.
+type ExportedClaim struct {
+	ClaimID  node.ClaimID
+	Name     []byte
+	OutPoint wire.OutPoint
+	Amount   int64
+	Status   node.Status
+}
+
+// ExportClaims writes the claims of all the names as of the block, other than the spent ones, to w, sorted
+// in the order, ExportByClaimID or ExportByName, after an ExportIndex of them. It returns the number of claims.
+//
+// The history is read locked for each name, rather than across the export, which fails with ErrStaleSnapshot,
+// if the block is reset in between.
+func (s *Snapshot) ExportClaims(ctx context.Context, w io.Writer, order string) (int, error) {
+
+	var less func(a, b *ExportedClaim) bool
+	var key func(c *ExportedClaim) byte
+	switch order {
+	case ExportByClaimID:
+		less = func(a, b *ExportedClaim) bool {
+			if a.ClaimID != b.ClaimID {
+				return bytes.Compare(a.ClaimID[:], b.ClaimID[:]) < 0
+			}
+			return node.OutPointLess(a.OutPoint, b.OutPoint)
+		}
+		key = func(c *ExportedClaim) byte { return c.ClaimID[0] }
+	case ExportByName:
+		less = func(a, b *ExportedClaim) bool {
+			if cmp := bytes.Compare(a.Name, b.Name); cmp != 0 {
+				return cmp < 0
+			}
+			if a.ClaimID != b.ClaimID {
+				return bytes.Compare(a.ClaimID[:], b.ClaimID[:]) < 0
+			}
+			return node.OutPointLess(a.OutPoint, b.OutPoint)
+		}
+		key = func(c *ExportedClaim) byte {
+			if len(c.Name) == 0 {
+				return 0
+			}
+			return c.Name[0]
+		}
+	default:
+		return 0, fmt.Errorf("unknown export order: %q", order)
+	}
+
+	claims, err := s.exportedClaims(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(claims, func(i, j int) bool { return less(&claims[i], &claims[j]) })
+
+	x := ExportIndex{Order: order, Height: s.height, Root: s.root, Rows: len(claims)}
+	var offset int64
+	next := 0
+	for i := range claims {
+		for k := int(key(&claims[i])); next <= k; next++ {
+			x.Offsets[next] = offset
+		}
+		offset += int64(len(exportRow(&claims[i])))
+	}
+	for ; next <= exportBuckets; next++ {
+		x.Offsets[next] = offset
+	}
+
+	bw := bufio.NewWriterSize(w, 1<<20)
+	_, err = bw.WriteString(x.header())
+	if err != nil {
+		return 0, fmt.Errorf("write header: %w", err)
+	}
+	for i := range claims {
+		_, err = bw.WriteString(exportRow(&claims[i]))
+		if err != nil {
+			return 0, fmt.Errorf("write row: %w", err)
+		}
+	}
+	err = bw.Flush()
+	if err != nil {
+		return 0, fmt.Errorf("write rows: %w", err)
+	}
+
+	return len(claims), nil
+}
+
+// exportedClaims returns the claims of all the names of the trie at the root of the block.
+func (s *Snapshot) exportedClaims(ctx context.Context) ([]ExportedClaim, error) {
+
+	var names [][]byte
+	s.ct.merkleTrie.At(&s.root).IterateNames(nil, func(name []byte) bool {
+		names = append(names, append([]byte(nil), name...))
+		return true
+	})
+
+	var claims []ExportedClaim
+	for _, name := range names {
+		n, err := s.nodeAt(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			continue
+		}
+		for _, c := range n.Claims {
+			if c.Status == node.Deactivated {
+				continue
+			}
+			claims = append(claims, ExportedClaim{ClaimID: c.ClaimID, Name: name, OutPoint: c.OutPoint,
+				Amount: c.Amount, Status: c.Status})
+		}
+	}
+
+	return claims, nil
+}
+
+// nodeAt returns the node of the name as of the block, read locking the history only for it.
+func (s *Snapshot) nodeAt(ctx context.Context, name []byte) (*node.Node, error) {
+
+	unlock, err := s.ct.readHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	err = s.check()
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := s.ct.nodeManager.NodeAt(s.height, name)
+	if err != nil {
+		return nil, fmt.Errorf("node %q at %d: %w", name, s.height, err)
+	}
+
+	return n, nil
+}
+
+func (x *ExportIndex) header() string {
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s v%d order=%s height=%d root=%s rows=%d size=%d\n",
+		exportMagic, exportVersion, x.Order, x.Height, x.Root, x.Rows, x.Offsets[exportBuckets])
+	for b := 0; b < exportBuckets; b++ {
+		fmt.Fprintf(&sb, "%02x %016x\n", b, x.Offsets[b])
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+func exportRow(c *ExportedClaim) string {
+	return fmt.Sprintf("%s\t\\\\x%s\t%s\t%d\t%s\n", c.ClaimID, hex.EncodeToString(c.Name), c.OutPoint, c.Amount, c.Status)
+}
+
+// ReadExportIndex reads the header of an export of the claims written by ExportClaims.
+func ReadExportIndex(r io.Reader) (*ExportIndex, error) {
+
+	br := bufio.NewReader(r)
+	summary, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read summary: %w", err)
+	}
+
+	x := &ExportIndex{}
+	var version int
+	var root string
+	_, err = fmt.Sscanf(summary, exportMagic+" v%d order=%s height=%d root=%s rows=%d size=%d\n",
+		&version, &x.Order, &x.Height, &root, &x.Rows, &x.Offsets[exportBuckets])
+	if err != nil {
+		return nil, fmt.Errorf("invalid summary: %q: %w", strings.TrimSpace(summary), err)
+	}
+	if version != exportVersion {
+		return nil, fmt.Errorf("unsupported export version: %d", version)
+	}
+	hash, err := chainhash.NewHashFromStr(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root: %w", err)
+	}
+	x.Root = *hash
+
+	line := make([]byte, exportIndexLine)
+	for b := 0; b < exportBuckets; b++ {
+		_, err = io.ReadFull(br, line)
+		if err != nil {
+			return nil, fmt.Errorf("read index: %w", err)
+		}
+		if string(line[:2]) != fmt.Sprintf("%02x", b) || line[2] != ' ' || line[len(line)-1] != '\n' {
+			return nil, fmt.Errorf("invalid index line: %q", line)
+		}
+		offset, err := strconv.ParseInt(string(line[3:len(line)-1]), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index offset: %w", err)
+		}
+		x.Offsets[b] = offset
+	}
+	blank, err := br.ReadByte()
+	if err != nil || blank != '\n' {
+		return nil, fmt.Errorf("missing the end of the header")
+	}
+
+	x.Start = int64(len(summary) + exportBuckets*exportIndexLine + 1)
+
+	return x, nil
+}
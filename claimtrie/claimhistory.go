@@ -0,0 +1,84 @@
+package claimtrie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/claimhistory"
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// ErrClaimHistoryNotRecorded is returned by the queries of the history of the best claims, unless it's recorded.
+var ErrClaimHistoryNotRecorded = errors.New("claim history isn't recorded")
+
+// ClaimHistory returns the takeovers of the name, and the heights it was left without a best claim at, oldest first.
+// The name is looked up as is, so the history of the one normalized starts at the normalization fork.
+func (ct *ClaimTrie) ClaimHistory(name []byte) ([]claimhistory.Entry, error) {
+
+	if ct.claimHistory == nil {
+		return nil, ErrClaimHistoryNotRecorded
+	}
+
+	return ct.claimHistory.History(name)
+}
+
+// ClaimHistoryAt returns the takeover of the name, of which the claim was its best one as of the height,
+// or nil, if it had none. It's recorded as the blocks are appended, so it's nil before it's enabled too.
+func (ct *ClaimTrie) ClaimHistoryAt(name []byte, height int32) (*claimhistory.Entry, error) {
+
+	if ct.claimHistory == nil {
+		return nil, ErrClaimHistoryNotRecorded
+	}
+
+	e, ok, err := ct.claimHistory.At(node.NormalizeIfNecessary(name, height), height)
+	if err != nil {
+		return nil, fmt.Errorf("claim history repo at: %w", err)
+	}
+	if !ok || e.Vacated {
+		return nil, nil
+	}
+
+	return &e, nil
+}
+
+// recordClaimHistory records the takeovers of the names updated by the block, and the ones left without
+// a best claim by it.
+func (ct *ClaimTrie) recordClaimHistory(names [][]byte) error {
+
+	var recorded [][]byte
+	var entries []claimhistory.Entry
+	for _, name := range names {
+		n, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return fmt.Errorf("node %q: %w", name, err)
+		}
+
+		if n != nil && n.BestClaim != nil {
+			if n.TakenOverAt != ct.height {
+				continue
+			}
+			recorded = append(recorded, name)
+			entries = append(entries, claimhistory.Entry{
+				Height:          ct.height,
+				ClaimID:         n.BestClaim.ClaimID,
+				EffectiveAmount: n.EffectiveAmount(n.BestClaim),
+			})
+			continue
+		}
+
+		prev, ok, err := ct.claimHistory.At(name, ct.height-1)
+		if err != nil {
+			return fmt.Errorf("claim history repo at: %w", err)
+		}
+		if ok && !prev.Vacated {
+			recorded = append(recorded, name)
+			entries = append(entries, claimhistory.Entry{Height: ct.height, Vacated: true})
+		}
+	}
+
+	if len(recorded) == 0 {
+		return nil
+	}
+
+	return ct.claimHistory.Set(recorded, entries)
+}
@@ -0,0 +1,164 @@
+package claimhistoryrepo
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/claimhistory"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Key format:
+//
+//	len(2B) + name + height(4B): the claimID(20B) + effective amount(8B) of the takeover of the name at the height,
+//	or none, once it's vacated.
+type Pebble struct {
+	db *pebble.DB
+}
+
+func NewPebble(path string) (*Pebble, error) {
+
+	db, err := pebble.Open(path, &pebble.Options{Cache: pebble.NewCache(16 << 20)})
+	if err != nil {
+		return nil, fmt.Errorf("pebble open %s, %w", path, err)
+	}
+
+	repo := &Pebble{db: db}
+
+	return repo, nil
+}
+
+func key(name []byte, height int32) []byte {
+	k := make([]byte, 2+len(name)+4)
+	binary.BigEndian.PutUint16(k, uint16(len(name)))
+	copy(k[2:], name)
+	binary.BigEndian.PutUint32(k[2+len(name):], uint32(height))
+	return k
+}
+
+const valueSize = len(change.ClaimID{}) + 8
+
+func marshal(e claimhistory.Entry) []byte {
+	if e.Vacated {
+		return nil
+	}
+	value := make([]byte, valueSize)
+	copy(value, e.ClaimID[:])
+	binary.BigEndian.PutUint64(value[len(e.ClaimID):], uint64(e.EffectiveAmount))
+	return value
+}
+
+func unmarshal(k, value []byte) (claimhistory.Entry, error) {
+
+	e := claimhistory.Entry{Height: int32(binary.BigEndian.Uint32(k[len(k)-4:]))}
+	switch len(value) {
+	case 0:
+		e.Vacated = true
+	case valueSize:
+		copy(e.ClaimID[:], value)
+		e.EffectiveAmount = int64(binary.BigEndian.Uint64(value[len(e.ClaimID):]))
+	default:
+		return e, fmt.Errorf("invalid entry of %d bytes at %d", len(value), e.Height)
+	}
+
+	return e, nil
+}
+
+func (repo *Pebble) Set(names [][]byte, entries []claimhistory.Entry) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	for i, name := range names {
+		err := batch.Set(key(name, entries[i].Height), marshal(entries[i]), pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble set: %w", err)
+		}
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+func (repo *Pebble) Rewind(names [][]byte, height int32) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	for _, name := range names {
+		err := batch.DeleteRange(key(name, height+1), key(name, -1), pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble delete range: %w", err)
+		}
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+func (repo *Pebble) At(name []byte, height int32) (claimhistory.Entry, bool, error) {
+
+	iter := repo.db.NewIter(&pebble.IterOptions{LowerBound: key(name, 0), UpperBound: key(name, height+1)})
+	found := iter.Last()
+	var e claimhistory.Entry
+	var err error
+	if found {
+		e, err = unmarshal(iter.Key(), iter.Value())
+	}
+	if cerr := iter.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("pebble iter: %w", cerr)
+	}
+	if err != nil {
+		return e, false, fmt.Errorf("entry of %q at %d: %w", name, height, err)
+	}
+
+	return e, found, nil
+}
+
+func (repo *Pebble) History(name []byte) ([]claimhistory.Entry, error) {
+
+	iter := repo.db.NewIter(&pebble.IterOptions{LowerBound: key(name, 0), UpperBound: key(name, -1)})
+
+	var entries []claimhistory.Entry
+	for iter.First(); iter.Valid(); iter.Next() {
+		e, err := unmarshal(iter.Key(), iter.Value())
+		if err != nil {
+			iter.Close()
+			return nil, fmt.Errorf("entry of %q: %w", name, err)
+		}
+		entries = append(entries, e)
+	}
+
+	err := iter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("pebble iter: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Pebble) Backup(dir string) error {
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Pebble) Close() error {
+
+	err := repo.db.Flush()
+	if err != nil {
+		return fmt.Errorf("pebble flush: %w", err)
+	}
+
+	err = repo.db.Close()
+	if err != nil {
+		return fmt.Errorf("pebble close: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package claimhistoryrepo
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/claimhistory"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimHistory(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	a, ab := []byte("a"), []byte("ab")
+	first := claimhistory.Entry{Height: 3, ClaimID: change.ClaimID{1}, EffectiveAmount: 10}
+	second := claimhistory.Entry{Height: 7, ClaimID: change.ClaimID{2}, EffectiveAmount: 1 << 40}
+	vacated := claimhistory.Entry{Height: 9, Vacated: true}
+	r.NoError(repo.Set([][]byte{a, ab}, []claimhistory.Entry{first, first}))
+	r.NoError(repo.Set([][]byte{a}, []claimhistory.Entry{second}))
+	r.NoError(repo.Set([][]byte{a}, []claimhistory.Entry{vacated}))
+
+	entries, err := repo.History(a)
+	r.NoError(err)
+	r.Equal([]claimhistory.Entry{first, second, vacated}, entries)
+
+	// The names sharing the prefix are kept apart.
+	entries, err = repo.History(ab)
+	r.NoError(err)
+	r.Equal([]claimhistory.Entry{first}, entries)
+
+	for height, exp := range map[int32]claimhistory.Entry{3: first, 6: first, 7: second, 8: second, 100: vacated} {
+		e, ok, err := repo.At(a, height)
+		r.NoError(err)
+		r.True(ok)
+		r.Equal(exp, e, "at %d", height)
+	}
+	_, ok, err := repo.At(a, 2)
+	r.NoError(err)
+	r.False(ok)
+
+	r.NoError(repo.Rewind([][]byte{a}, 7))
+	entries, err = repo.History(a)
+	r.NoError(err)
+	r.Equal([]claimhistory.Entry{first, second}, entries)
+}
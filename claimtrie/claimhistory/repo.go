@@ -0,0 +1,28 @@
+package claimhistory
+
+import "github.com/btcsuite/btcd/claimtrie/change"
+
+// Entry is a takeover of a name at the height: its best claim from then on, until the next one, and its
+// effective amount at the takeover. Vacated is set, instead, once the name is left without a best claim.
+type Entry struct {
+	Height          int32
+	ClaimID         change.ClaimID
+	EffectiveAmount int64
+	Vacated         bool
+}
+
+// Repo defines APIs for the history of the best claims of the names, by the heights they took over at,
+// to access persistence layer.
+type Repo interface {
+	// Set records the entries of the names, at their heights.
+	Set(names [][]byte, entries []Entry) error
+	// Rewind drops the entries of the names after height.
+	Rewind(names [][]byte, height int32) error
+
+	// At returns the last entry of the name at, or before, the height, and whether there's any.
+	At(name []byte, height int32) (Entry, bool, error)
+	// History returns the entries of the name, oldest first.
+	History(name []byte) ([]Entry, error)
+
+	Close() error
+}
@@ -0,0 +1,306 @@
+package claimidrepo
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/claimid"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Key formats:
+//
+//	'i' + claim ID(20B), in the order displayed, + outpoint(36B): accepted at(4B) + name, the entry.
+//	'n' + name length(2B) + name + claim ID(20B) + outpoint(36B): the entries of the name.
+//	'h': the height(4B) last updated to.
+const (
+	entryPrefix = 'i'
+	namePrefix  = 'n'
+	heightKey   = 'h'
+)
+
+const idSize = len(change.ClaimID{})
+
+type Pebble struct {
+	db *pebble.DB
+}
+
+func NewPebble(path string) (*Pebble, error) {
+
+	db, err := pebble.Open(path, &pebble.Options{Cache: pebble.NewCache(16 << 20)})
+	if err != nil {
+		return nil, fmt.Errorf("pebble open %s, %w", path, err)
+	}
+
+	repo := &Pebble{db: db}
+
+	return repo, nil
+}
+
+// displayed returns the Claim ID in the order of its hex encoding, which is reversed, so that
+// the keys of the IDs with a hex prefix are ranged over.
+func displayed(id change.ClaimID) []byte {
+	b := make([]byte, idSize)
+	for i := range id {
+		b[idSize-1-i] = id[i]
+	}
+	return b
+}
+
+func idFromDisplayed(b []byte) change.ClaimID {
+	var id change.ClaimID
+	for i := range id {
+		id[i] = b[idSize-1-i]
+	}
+	return id
+}
+
+func entryKey(id change.ClaimID, op wire.OutPoint) []byte {
+	key := append([]byte{entryPrefix}, displayed(id)...)
+	o := change.NewOutPoint(op)
+	return append(key, o[:]...)
+}
+
+func nameBounds(name []byte) []byte {
+	key := make([]byte, 3, 3+len(name)+idSize+len(change.OutPoint{}))
+	key[0] = namePrefix
+	binary.BigEndian.PutUint16(key[1:], uint16(len(name)))
+	return append(key, name...)
+}
+
+func nameKey(e claimid.Entry) []byte {
+	key := append(nameBounds(e.Name), displayed(e.ClaimID)...)
+	o := change.NewOutPoint(e.OutPoint)
+	return append(key, o[:]...)
+}
+
+// prefixEnd returns the first key after the ones with the prefix.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// prefixBounds returns the bounds of the keys of the entries, of which the hex encoded Claim IDs start with
+// the prefix, or false, if it's no hex prefix of one.
+func prefixBounds(prefix string) ([]byte, []byte, bool) {
+
+	if len(prefix) > 2*idSize {
+		return nil, nil, false
+	}
+	whole, err := hex.DecodeString(prefix[:len(prefix)/2*2])
+	if err != nil {
+		return nil, nil, false
+	}
+	lower := append([]byte{entryPrefix}, whole...)
+	if len(prefix)%2 == 0 {
+		return lower, prefixEnd(lower), true
+	}
+
+	// The odd nibble bounds the next byte.
+	nibble, err := hex.DecodeString(prefix[len(prefix)-1:] + "0")
+	if err != nil {
+		return nil, nil, false
+	}
+	upper := append(append([]byte(nil), lower...), nibble[0]+0x10)
+	lower = append(lower, nibble[0])
+	if nibble[0] == 0xf0 {
+		upper = prefixEnd(lower[:len(lower)-1])
+	}
+
+	return lower, upper, true
+}
+
+func encodeEntry(e claimid.Entry) []byte {
+	value := make([]byte, 4, 4+len(e.Name))
+	binary.BigEndian.PutUint32(value, uint32(e.AcceptedAt))
+	return append(value, e.Name...)
+}
+
+func decodeEntry(key, value []byte) (claimid.Entry, error) {
+	if len(key) != 1+idSize+len(change.OutPoint{}) || len(value) < 4 {
+		return claimid.Entry{}, fmt.Errorf("invalid entry: %d, %d bytes", len(key), len(value))
+	}
+	var o change.OutPoint
+	copy(o[:], key[1+idSize:])
+	return claimid.Entry{
+		ClaimID:    idFromDisplayed(key[1 : 1+idSize]),
+		Name:       append([]byte(nil), value[4:]...),
+		OutPoint:   o.Wire(),
+		AcceptedAt: int32(binary.BigEndian.Uint32(value)),
+	}, nil
+}
+
+func (repo *Pebble) entries(lower, upper []byte, max int) ([]claimid.Entry, error) {
+
+	iter := repo.db.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+	var entries []claimid.Entry
+	ids := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+		e, err := decodeEntry(iter.Key(), iter.Value())
+		if err != nil {
+			iter.Close()
+			return nil, err
+		}
+		if len(entries) == 0 || entries[len(entries)-1].ClaimID != e.ClaimID {
+			if ids++; max > 0 && ids > max {
+				break
+			}
+		}
+		entries = append(entries, e)
+	}
+	err := iter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("pebble iter: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (repo *Pebble) Claims(id change.ClaimID) ([]claimid.Entry, error) {
+	lower := append([]byte{entryPrefix}, displayed(id)...)
+	return repo.entries(lower, prefixEnd(lower), 0)
+}
+
+func (repo *Pebble) ClaimsByPrefix(prefix string, max int) ([]claimid.Entry, error) {
+
+	lower, upper, ok := prefixBounds(prefix)
+	if !ok {
+		return nil, nil
+	}
+
+	return repo.entries(lower, upper, max)
+}
+
+func (repo *Pebble) Update(height int32, names [][]byte, entries []claimid.Entry) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	// The entries of the names are dropped before the ones given are set, which replace any of them.
+	for _, name := range names {
+		lower := nameBounds(name)
+		iter := repo.db.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: prefixEnd(lower)})
+		for iter.First(); iter.Valid(); iter.Next() {
+			key := append([]byte{entryPrefix}, iter.Key()[len(lower):]...)
+			err := batch.Delete(key, pebble.NoSync)
+			if err != nil {
+				iter.Close()
+				return fmt.Errorf("pebble delete: %w", err)
+			}
+		}
+		err := iter.Close()
+		if err != nil {
+			return fmt.Errorf("pebble iter: %w", err)
+		}
+		err = batch.DeleteRange(lower, prefixEnd(lower), pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble delete range: %w", err)
+		}
+	}
+
+	err := setEntries(batch, entries)
+	if err != nil {
+		return err
+	}
+	err = setHeight(batch, height)
+	if err != nil {
+		return err
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+func setEntries(batch *pebble.Batch, entries []claimid.Entry) error {
+	for _, e := range entries {
+		err := batch.Set(entryKey(e.ClaimID, e.OutPoint), encodeEntry(e), pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble set: %w", err)
+		}
+		err = batch.Set(nameKey(e), nil, pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble set: %w", err)
+		}
+	}
+	return nil
+}
+
+func setHeight(batch *pebble.Batch, height int32) error {
+	value := make([]byte, 4)
+	binary.BigEndian.PutUint32(value, uint32(height))
+	err := batch.Set([]byte{heightKey}, value, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble set: %w", err)
+	}
+	return nil
+}
+
+func (repo *Pebble) Height() (int32, error) {
+
+	value, closer, err := repo.db.Get([]byte{heightKey})
+	if err == pebble.ErrNotFound {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("pebble get: %w", err)
+	}
+	defer closer.Close()
+
+	return int32(binary.BigEndian.Uint32(value)), nil
+}
+
+func (repo *Pebble) Reset(height int32, entries []claimid.Entry) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	err := batch.DeleteRange([]byte{0}, []byte{0xff}, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble delete range: %w", err)
+	}
+	err = setEntries(batch, entries)
+	if err != nil {
+		return err
+	}
+	err = setHeight(batch, height)
+	if err != nil {
+		return err
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Pebble) Backup(dir string) error {
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Pebble) Close() error {
+
+	err := repo.db.Flush()
+	if err != nil {
+		return fmt.Errorf("pebble flush: %w", err)
+	}
+
+	err = repo.db.Close()
+	if err != nil {
+		return fmt.Errorf("pebble close: %w", err)
+	}
+
+	return nil
+}
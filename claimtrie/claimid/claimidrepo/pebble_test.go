@@ -0,0 +1,80 @@
+package claimidrepo
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/claimid"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimIDs(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	height, err := repo.Height()
+	r.NoError(err)
+	r.Equal(int32(-1), height)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	op := func(i uint32) wire.OutPoint { return wire.OutPoint{Hash: hash, Index: i} }
+	id := func(s string) change.ClaimID {
+		id, err := change.NewIDFromString(s)
+		r.NoError(err)
+		return id
+	}
+	a := claimid.Entry{ClaimID: id("ab12000000000000000000000000000000000000"), Name: []byte("a"), OutPoint: op(1), AcceptedAt: 1}
+	b := claimid.Entry{ClaimID: id("ab34000000000000000000000000000000000000"), Name: []byte("b"), OutPoint: op(2), AcceptedAt: 1}
+	c := claimid.Entry{ClaimID: id("ac00000000000000000000000000000000000000"), Name: []byte("b"), OutPoint: op(3), AcceptedAt: 2}
+	shared := claimid.Entry{ClaimID: a.ClaimID, Name: []byte("c"), OutPoint: op(0), AcceptedAt: 2}
+
+	r.NoError(repo.Reset(1, []claimid.Entry{a, b}))
+	r.NoError(repo.Update(2, [][]byte{[]byte("b"), []byte("c")}, []claimid.Entry{c, shared}))
+
+	height, err = repo.Height()
+	r.NoError(err)
+	r.Equal(int32(2), height)
+
+	// The entries of a name are replaced, and the ones sharing an ID are in order by outpoint.
+	entries, err := repo.Claims(b.ClaimID)
+	r.NoError(err)
+	r.Empty(entries)
+	entries, err = repo.Claims(a.ClaimID)
+	r.NoError(err)
+	r.Equal([]claimid.Entry{shared, a}, entries)
+
+	for _, test := range []struct {
+		prefix string
+		max    int
+		claims []claimid.Entry
+	}{
+		{"", 0, []claimid.Entry{shared, a, c}},
+		{"a", 0, []claimid.Entry{shared, a, c}},
+		{"ab", 0, []claimid.Entry{shared, a}},
+		{"ab1", 0, []claimid.Entry{shared, a}},
+		{"ab3", 0, nil},
+		{"ac", 0, []claimid.Entry{c}},
+		{"", 1, []claimid.Entry{shared, a}},
+		{"f", 0, nil},
+		{"zz", 0, nil},
+	} {
+		entries, err = repo.ClaimsByPrefix(test.prefix, test.max)
+		r.NoError(err)
+		r.Equal(test.claims, entries, test.prefix)
+	}
+
+	r.NoError(repo.Update(3, [][]byte{[]byte("a"), []byte("c")}, nil))
+	entries, err = repo.ClaimsByPrefix("", 0)
+	r.NoError(err)
+	r.Equal([]claimid.Entry{c}, entries)
+}
@@ -0,0 +1,33 @@
+package claimid
+
+import (
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Entry is a claim, by its Claim ID.
+type Entry struct {
+	ClaimID    change.ClaimID
+	Name       []byte
+	OutPoint   wire.OutPoint
+	AcceptedAt int32
+}
+
+// Repo defines APIs for the index of the claims by their Claim IDs to access persistence layer.
+// The entries of a name are replaced as a whole, as of its claims, which are recomputed on the rewinds.
+type Repo interface {
+	// Claims returns the entries with the Claim ID, in order by outpoint.
+	Claims(id change.ClaimID) ([]Entry, error)
+	// ClaimsByPrefix returns the entries, of which the hex encoded Claim IDs start with the prefix, in order
+	// by them, as they're displayed, and by outpoint. It stops after the entries of max Claim IDs, if it's positive.
+	ClaimsByPrefix(prefix string, max int) ([]Entry, error)
+	// Update replaces the entries of the names with the ones given, at the height.
+	Update(height int32, names [][]byte, entries []Entry) error
+
+	// Height returns the height last updated to, or -1 if there's none.
+	Height() (int32, error)
+	// Reset replaces all the entries with the ones at the height.
+	Reset(height int32, entries []Entry) error
+
+	Close() error
+}
@@ -0,0 +1,236 @@
+package claimtrie
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/claimtrie/claimid"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	// ErrClaimIDNotIndexed is returned by the lookups by the Claim IDs, if the index isn't maintained.
+	ErrClaimIDNotIndexed = errors.New("claim IDs aren't indexed")
+
+	// ErrClaimIDCollision is returned by the lookups, under config.CollisionsStrict, when claims share a Claim ID.
+	ErrClaimIDCollision = errors.New("claim ID collision")
+
+	// ErrAmbiguousClaimID is returned, under config.CollisionsStrict, when a prefix matches several Claim IDs.
+	ErrAmbiguousClaimID = errors.New("ambiguous claim ID prefix")
+)
+
+// IndexedClaim is a claim found by its Claim ID.
+type IndexedClaim struct {
+	ClaimID    node.ClaimID
+	Name       []byte
+	OutPoint   wire.OutPoint
+	AcceptedAt int32
+}
+
+type claimIDIndex struct {
+	policy string
+	repo   claimid.Repo
+}
+
+func newClaimIDIndex(policy string, repo claimid.Repo) (*claimIDIndex, error) {
+
+	switch policy {
+	case "":
+		policy = config.CollisionsStrict
+	case config.CollisionsStrict, config.CollisionsFirst, config.CollisionsBoth:
+	default:
+		return nil, fmt.Errorf("unknown claim ID collision policy: %q", policy)
+	}
+
+	return &claimIDIndex{policy: policy, repo: repo}, nil
+}
+
+func indexedClaims(entries []claimid.Entry) []IndexedClaim {
+	claims := make([]IndexedClaim, 0, len(entries))
+	for _, e := range entries {
+		claims = append(claims, IndexedClaim(e))
+	}
+	return claims
+}
+
+// ClaimByID returns the claims with the Claim ID, as the collision policy resolves them:
+// the only one, or ErrClaimIDCollision, under CollisionsStrict, the one accepted first,
+// under CollisionsFirst, and all of them, in order by outpoint, under CollisionsBoth.
+// It returns no claim, if none has the ID.
+func (ct *ClaimTrie) ClaimByID(id node.ClaimID) ([]IndexedClaim, error) {
+
+	if ct.claimIDs == nil {
+		return nil, ErrClaimIDNotIndexed
+	}
+
+	entries, err := ct.claimIDs.repo.Claims(id)
+	if err != nil {
+		return nil, fmt.Errorf("claims of %s: %w", id, err)
+	}
+
+	return ct.claimIDs.resolve(indexedClaims(entries))
+}
+
+// ClaimsByIDPrefix returns the claims, of which the hex encoded Claim IDs start with the prefix,
+// as the collision policy resolves them: the claims of the only matching ID, or ErrAmbiguousClaimID,
+// under CollisionsStrict, the one accepted first, under CollisionsFirst, and all of them, in order
+// by Claim ID and outpoint, under CollisionsBoth.
+func (ct *ClaimTrie) ClaimsByIDPrefix(prefix string) ([]IndexedClaim, error) {
+
+	if ct.claimIDs == nil {
+		return nil, ErrClaimIDNotIndexed
+	}
+
+	// Strictly, a second ID is enough to tell the prefix is ambiguous.
+	max := 0
+	if ct.claimIDs.policy == config.CollisionsStrict {
+		max = 2
+	}
+	entries, err := ct.claimIDs.repo.ClaimsByPrefix(strings.ToLower(prefix), max)
+	if err != nil {
+		return nil, fmt.Errorf("claims by prefix %s: %w", prefix, err)
+	}
+	if max > 0 && len(entries) > 1 && entries[0].ClaimID != entries[len(entries)-1].ClaimID {
+		return nil, fmt.Errorf("%w: %s matches several claim IDs", ErrAmbiguousClaimID, prefix)
+	}
+
+	return ct.claimIDs.resolve(indexedClaims(entries))
+}
+
+// resolve applies the collision policy to the claims sorted by outpoint.
+func (ci *claimIDIndex) resolve(claims []IndexedClaim) ([]IndexedClaim, error) {
+
+	if len(claims) <= 1 {
+		return claims, nil
+	}
+
+	switch ci.policy {
+	case config.CollisionsFirst:
+		first := claims[0]
+		for _, c := range claims[1:] {
+			if c.AcceptedAt < first.AcceptedAt {
+				first = c
+			}
+		}
+		return []IndexedClaim{first}, nil
+	case config.CollisionsBoth:
+		return claims, nil
+	default:
+		if claims[0].ClaimID == claims[1].ClaimID {
+			return nil, fmt.Errorf("%w: %s is claimed by %d outpoints", ErrClaimIDCollision, claims[0].ClaimID, len(claims))
+		}
+		return nil, fmt.Errorf("%w: %d claims", ErrClaimIDCollision, len(claims))
+	}
+}
+
+// claimIDEntries returns the entries of the claims of the node under the name.
+func claimIDEntries(name []byte, n *node.Node) []claimid.Entry {
+
+	if n == nil {
+		return nil
+	}
+	var entries []claimid.Entry
+	for _, c := range n.Claims {
+		if c.Status == node.Deactivated {
+			continue
+		}
+		entries = append(entries, claimid.Entry{ClaimID: c.ClaimID, Name: name, OutPoint: c.OutPoint, AcceptedAt: c.AcceptedAt})
+	}
+
+	return entries
+}
+
+// rebuildClaimIDIndex indexes the claims of all the names from scratch. The claims sharing an ID
+// are only logged: the collision policy applies to the lookups, and never fails the block.
+func (ct *ClaimTrie) rebuildClaimIDIndex() error {
+
+	var entries []claimid.Entry
+	var err error
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		n, e := ct.nodeManager.Node(name)
+		if e != nil {
+			err = fmt.Errorf("node: %w", e)
+			return false
+		}
+		entries = append(entries, claimIDEntries(append([]byte(nil), name...), n)...)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	err = ct.claimIDs.repo.Reset(ct.height, entries)
+	if err != nil {
+		return fmt.Errorf("reset claim ID repo: %w", err)
+	}
+
+	claims := make(map[node.ClaimID]int, len(entries))
+	for _, e := range entries {
+		claims[e.ClaimID]++
+	}
+	for _, e := range entries {
+		if claims[e.ClaimID] > 1 {
+			logCollision(e, claims[e.ClaimID])
+		}
+	}
+
+	return nil
+}
+
+// updateClaimIDIndex replaces the claims of the names in the index. As in rebuildClaimIDIndex,
+// the claims sharing an ID are only logged.
+func (ct *ClaimTrie) updateClaimIDIndex(names [][]byte) error {
+
+	var entries []claimid.Entry
+	var normalized [][]byte
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = node.NormalizeIfNecessary(name, ct.height)
+		if seen[string(name)] {
+			continue
+		}
+		seen[string(name)] = true
+		normalized = append(normalized, name)
+
+		n, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return fmt.Errorf("node: %w", err)
+		}
+		entries = append(entries, claimIDEntries(append([]byte(nil), name...), n)...)
+	}
+
+	err := ct.claimIDs.repo.Update(ct.height, normalized, entries)
+	if err != nil {
+		return fmt.Errorf("update claim ID repo: %w", err)
+	}
+
+	for _, e := range entries {
+		claims, err := ct.claimIDs.repo.Claims(e.ClaimID)
+		if err != nil {
+			return fmt.Errorf("claims of %s: %w", e.ClaimID, err)
+		}
+		if len(claims) > 1 {
+			logCollision(e, len(claims))
+		}
+	}
+
+	return nil
+}
+
+// logCollision logs the claim sharing its ID with others.
+func logCollision(e claimid.Entry, shared int) {
+	log.Warnf("Claim ID %s of %s under %s is shared by %d claims", e.ClaimID, e.OutPoint, e.Name, shared)
+}
+
+// rewindClaimIDIndex updates the claims of the names after resetting from a later height.
+func (ct *ClaimTrie) rewindClaimIDIndex(names [][]byte, from int32) error {
+
+	if ct.height < param.NormalizedNameForkHeight && from >= param.NormalizedNameForkHeight {
+		return ct.rebuildClaimIDIndex() // the names were normalized in between
+	}
+	return ct.updateClaimIDIndex(names)
+}
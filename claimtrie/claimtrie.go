@@ -2,27 +2,55 @@ package claimtrie
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/btcsuite/btcd/claimtrie/activity"
+	"github.com/btcsuite/btcd/claimtrie/activity/activityrepo"
 	"github.com/btcsuite/btcd/claimtrie/block"
 	"github.com/btcsuite/btcd/claimtrie/block/blockrepo"
 	"github.com/btcsuite/btcd/claimtrie/chain"
 	"github.com/btcsuite/btcd/claimtrie/chain/chainrepo"
 	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/claimhistory"
+	"github.com/btcsuite/btcd/claimtrie/claimhistory/claimhistoryrepo"
+	"github.com/btcsuite/btcd/claimtrie/claimid/claimidrepo"
 	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/deltasync"
+	"github.com/btcsuite/btcd/claimtrie/encryptedrepo"
+	"github.com/btcsuite/btcd/claimtrie/event"
 	"github.com/btcsuite/btcd/claimtrie/merkletrie"
 	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
 	"github.com/btcsuite/btcd/claimtrie/node"
 	"github.com/btcsuite/btcd/claimtrie/node/noderepo"
+	"github.com/btcsuite/btcd/claimtrie/outpoint"
+	"github.com/btcsuite/btcd/claimtrie/outpoint/outpointrepo"
 	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/claimtrie/progress"
+	"github.com/btcsuite/btcd/claimtrie/standby"
+	"github.com/btcsuite/btcd/claimtrie/takeover"
+	"github.com/btcsuite/btcd/claimtrie/takeover/takeoverrepo"
 	"github.com/btcsuite/btcd/claimtrie/temporal"
 	"github.com/btcsuite/btcd/claimtrie/temporal/temporalrepo"
+	"github.com/btcsuite/btcd/claimtrie/txn"
+	"github.com/btcsuite/btcd/claimtrie/upstream"
+	"github.com/btcsuite/btcd/claimtrie/valuehash"
+	"github.com/btcsuite/btcd/claimtrie/valuehash/valuehashrepo"
+	"github.com/btcsuite/btcd/claimtrie/webhook"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
+
+	"github.com/cockroachdb/pebble"
 )
 
 // ClaimTrie implements a Merkle Trie supporting linear history of commits.
@@ -48,13 +76,179 @@ type ClaimTrie struct {
 	// Prefix tree (trie) that manages merkle hash of each node.
 	merkleTrie *merkletrie.MerkleTrie
 
+	// Leaf hashes of the trie, refreshed as the names are updated, if enabled.
+	values *valueCache
+
+	// Publisher of the names dirtied by each block, and their leaf hashes, if enabled.
+	deltas *deltasync.Publisher
+
+	standbys *standby.Publisher
+
 	// Current block height, which is increased by one when AppendBlock() is called.
 	height int32
 
+	// Merkle Hash of the current block, reused by the next one if nothing changes.
+	root *chainhash.Hash
+
 	// Write buffer for batching changes written to repo.
 	// flushed before block is appended.
 	changes []change.Change
 
+	// Dispatcher of the events to the subscribers.
+	events *event.Bus
+
+	// The sinks of the changes applied by each block.
+	sinks changeSinks
+
+	// Destinations of the claims updated by the changes not appended yet, which differ from the ones spent.
+	transfers map[change.OutPoint]ownerChange
+
+	// Repository for the names, of which supports are about to expire at each block height.
+	// Only used if SupportExpiring events are enabled.
+	supportExpiringRepo   temporal.Repo
+	supportExpiringNotice int32
+
+	// Writes a checkpoint of the trie repo for read-only replicas, if enabled.
+	trieCheckpoint func(height int32, root *chainhash.Hash) error
+
+	// Names of which the events and states are retained.
+	watcher *watcher
+
+	// Aggregates of the claims signed by each channel, if enabled.
+	channels *channelIndex
+
+	// Index of the claims by their Claim IDs, if enabled.
+	claimIDs *claimIDIndex
+
+	// Names ranked by the effective amounts of their best claims, if enabled.
+	topNames *topNames
+
+	// Index of the names by their tokens, for searching them, if enabled.
+	search *nameSearch
+
+	// Storage of the values of the claims and supports, which may be apart from the node repo.
+	claimValues *claimValueStore
+
+	// Index of the claims and supports by their outpoints, if enabled, and the ones created by the changes
+	// not appended yet.
+	outPoints        outpoint.Repo
+	pendingOutPoints map[change.OutPoint]outpoint.Entry
+	pendingOwners    map[change.OutPoint][]byte // The destination scripts of the outpoints created by the next block.
+
+	// Index of the names by the heights they were first seen, and last active at, if enabled.
+	activityRepo activity.Repo
+
+	// The contenders of the takeovers are logged, and attached to the events, if it's set,
+	// and saved to the repo, if any.
+	takeoverDiagnostics bool
+	takeoverRepo        takeover.Repo
+
+	// Index of the leaf value hashes of the names by the heights, if enabled.
+	valueHashes valuehash.Repo
+
+	// The history of the best claims of the names, if it's recorded.
+	claimHistory claimhistory.Repo
+
+	// Blocks taking longer than this to append are logged, if it's set.
+	slowBlockThreshold time.Duration
+
+	// Budgets of the stages of processing a block, and the counts of the ones exceeded.
+	budgets map[string]time.Duration
+	alerts  map[string]int64
+
+	// The metrics of the blocks, and the repos, if they're recorded.
+	metrics *blockMetrics
+
+	// Bytes the caches of the trie and the nodes are kept within, if it's set.
+	memoryBudget int64
+
+	// The repos writing in batches across the blocks while batching, which are committed in a txn every
+	// batchBlocks blocks, or once over batchBytes, if it's set, and the blocks batched since.
+	txns        *txn.Coordinator
+	txn         *txn.Txn
+	batchBlocks int32
+	batchBytes  int64
+	batching    bool
+	batched     int32
+
+	// Workers computing the Merkle Hash of all the claims.
+	hashWorkers int
+
+	// Claims added with the TXO of existing ones.
+	conflicts *node.ConflictTracker
+
+	// Compacts the trie and node repos in the background, if enabled.
+	compaction *compactionScheduler
+
+	// Warms up the names due in the next blocks in the background, once idle, if enabled.
+	warmer *idleWarmer
+
+	// The trie and node repos, which are flushed by Flush.
+	flushers map[string]flusher
+
+	// The repos by their paths in the data dir, which are copied by Backup. The changes to the ClaimTrie are
+	// held from it by changing.
+	backups  map[string]backupRepo
+	changing sync.Mutex
+
+	// Updated along with the merkleTrie, and their roots compared after each block, if it's set.
+	ramTrie *merkletrie.RamTrie
+
+	// Serializes the external coordinators and the block processing per name.
+	nameLocks *nameLocks
+
+	// The height, read by the coordinators, which is updated once the names of a block are unlocked.
+	tip int32
+
+	// The *Snapshot of the last block committed, which the queries read while a block is appended.
+	// The history is read locked by them, and locked by the resets and prunes rewriting it.
+	committed       atomic.Value
+	history         historyLock
+	historyReadWait time.Duration
+
+	// The ClaimTrie can't be reset further back than this, if it's set.
+	maxReorgDepth int32
+
+	// The changes past the retention are pruned every pruneInterval blocks, if there's a pruner.
+	// The ClaimTrie can't be reset before prunedAt, which is assumed to be the latest possible after a restart.
+	retention       string
+	retentionBlocks int32
+	pruneInterval   int32
+	pruner          pruner
+	prunedAt        int32
+	importer        stateImporter // of the base snapshots, while the ClaimTrie is bootstrapped by ImportState.
+
+	// The names empty for expiredBlocks blocks are compacted into tombstones every pruneInterval blocks, if
+	// there's an expiredCompactor. The ClaimTrie can't be reset before compactedAt, which is assumed the latest possible too.
+	expiredCompactor expiredCompactor
+	expiredBlocks    int32
+	compactedAt      int32
+
+	// The trie repo is pruned by PruneTrie of the nodes of the roots before the last trieRetentionBlocks blocks, if it's
+	// set, and compacted with trieCompacter, if it's set. The ClaimTrie can't be reset before trieRetainedFrom, which
+	// is assumed the latest possible too. It's written atomically, as the proofs read it.
+	trieRetentionBlocks int32
+	trieRetainedFrom    int32
+	trieCompacter       compacter
+
+	// Verifies the roots against the upstream checkpoints, if enabled, and counts the divergences atomically.
+	upstream            *upstream.Verifier
+	upstreamDivergences int64
+
+	// Set to 1 to check the updated nodes after each block; accessed atomically.
+	consistencyCheck int32
+	inconsistencies  int64
+
+	statsMu       sync.Mutex
+	stats         Stats
+	lastBlockAt   time.Time
+	lastBlockTook time.Duration
+
+	// The latest progress of each long-running operation, which is also reported to onProgress, if it's set.
+	progressMu sync.Mutex
+	progress   map[string]progress.Progress
+	onProgress progress.Func
+
 	// Registrered cleanup functions which are invoked in the Close() in reverse order.
 	cleanups []func() error
 }
@@ -62,60 +256,254 @@ type ClaimTrie struct {
 func New(cfg config.Config) (*ClaimTrie, error) {
 
 	var cleanups []func() error
+	backups := map[string]backupRepo{}
+
+	if err := setParams(cfg); err != nil {
+		return nil, fmt.Errorf("params: %w", err)
+	}
+	if err := checkBudgets(cfg.Budgets); err != nil {
+		return nil, fmt.Errorf("budgets: %w", err)
+	}
+	if err := checkRetention(cfg); err != nil {
+		return nil, fmt.Errorf("retention: %w", err)
+	}
+	retain := cfg.ChangeRetention != config.RetainAll && cfg.ChangeRetention != ""
 
-	blockRepo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.BlockRepoPebble.Path))
+	if cfg.BatchBlocks > 0 && cfg.TrieCheckpointInterval > 0 {
+		return nil, fmt.Errorf("trie checkpoints can't be written while batching across the blocks")
+	}
+	if cfg.ChannelStats && cfg.ValueStorage == config.ValuesHashesOnly {
+		return nil, fmt.Errorf("channel stats can't be maintained without the values of the claims")
+	}
+	repoCipher, err := newRepoCipher(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("new block repo: %w", err)
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+
+	var sharedDB *pebble.DB
+	if cfg.SharedRepoPebble.Path != "" {
+		if cfg.TrieCheckpointInterval > 0 {
+			return nil, fmt.Errorf("trie checkpoints require a separate trie repo")
+		}
+		prefixes := []string{cfg.BlockRepoPebble.Prefix, cfg.NodeRepoPebble.Prefix, cfg.MerkleTrieRepoPebble.Prefix}
+		for i, a := range prefixes {
+			for j, b := range prefixes {
+				if a == "" || (i != j && strings.HasPrefix(a, b)) {
+					return nil, fmt.Errorf("shared repo prefixes must be non-empty and distinct: %q", prefixes)
+				}
+			}
+		}
+		db, err := pebble.Open(filepath.Join(cfg.DataDir, cfg.SharedRepoPebble.Path),
+			&pebble.Options{Cache: pebble.NewCache(cacheSize(cfg.SharedRepoPebble.CacheSize, 512<<20)), BytesPerSync: 32 << 20})
+		if err != nil {
+			return nil, fmt.Errorf("new shared repo: %w", err)
+		}
+		cleanups = append(cleanups, db.Close) // the repos flush it in their cleanups
+		sharedDB = db
+		backups[cfg.SharedRepoPebble.Path] = sharedBackup{db}
+	}
+
+	var blockRepo *blockrepo.Pebble
+	if sharedDB != nil {
+		blockRepo = blockrepo.NewPebbleShared(sharedDB, []byte(cfg.BlockRepoPebble.Prefix))
+	} else {
+		blockRepo, err = blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.BlockRepoPebble.Path))
+		if err != nil {
+			return nil, fmt.Errorf("new block repo: %w", err)
+		}
+		backups[cfg.BlockRepoPebble.Path] = blockRepo
 	}
 	cleanups = append(cleanups, blockRepo.Close)
 
-	temporalRepo, err := temporalrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.TemporalRepoPebble.Path))
+	temporalRepo, err := temporalrepo.NewPebbleWithCache(filepath.Join(cfg.DataDir, cfg.TemporalRepoPebble.Path),
+		cacheSize(cfg.TemporalRepoPebble.CacheSize, temporalrepo.DefaultCacheSize))
 	if err != nil {
 		return nil, fmt.Errorf("new temporal repo: %w", err)
 	}
 	cleanups = append(cleanups, temporalRepo.Close)
+	backups[cfg.TemporalRepoPebble.Path] = temporalRepo
 
 	// Initialize repository for changes to nodes.
 	// The cleanup is delegated to the Node Manager.
-	nodeRepo, err := noderepo.NewPebble(filepath.Join(cfg.DataDir, cfg.NodeRepoPebble.Path))
-	if err != nil {
-		return nil, fmt.Errorf("new node repo: %w", err)
+	var nodeRepo *noderepo.Pebble
+	if sharedDB != nil {
+		nodeRepo = noderepo.NewPebbleShared(sharedDB, []byte(cfg.NodeRepoPebble.Prefix))
+	} else {
+		nodeRepo, err = noderepo.NewPebbleWithCache(filepath.Join(cfg.DataDir, cfg.NodeRepoPebble.Path),
+			cacheSize(cfg.NodeRepoPebble.CacheSize, noderepo.DefaultCacheSize))
+		if err != nil {
+			return nil, fmt.Errorf("new node repo: %w", err)
+		}
+		backups[cfg.NodeRepoPebble.Path] = nodeRepo
+	}
+	var changeRepo node.Repo = nodeRepo
+	if repoCipher != nil {
+		changeRepo = encryptedrepo.Node(nodeRepo, repoCipher)
+	}
+	var metered *blockMetrics
+	if cfg.Metrics {
+		metered = newBlockMetrics()
+		changeRepo = metered.timeNodeRepo(changeRepo)
 	}
 
-	baseManager, err := node.NewBaseManager(nodeRepo)
+	// The repos are committed in order, the block repo last, and the node repo, which isn't batched, flushed first.
+	txns := txn.New(filepath.Join(cfg.DataDir, txnJournalFile))
+	txns.RegisterFlusher("node", nodeRepo)
+
+	conflicts := node.NewConflictTracker(cfg.StrictConflicts)
+	conflicts.SetStrictSpends(cfg.StrictSpends)
+	var baseManager node.Manager
+	switch cfg.NodeManager {
+	case config.NodeManagerReplay, "":
+		baseManager, err = node.NewBaseManagerWithTracker(changeRepo, conflicts)
+	case config.NodeManagerSnapshot:
+		var snapshotRepo *noderepo.Snapshots
+		snapshotRepo, err = noderepo.NewSnapshots(filepath.Join(cfg.DataDir, cfg.NodeSnapshotRepoPebble.Path))
+		if err != nil {
+			return nil, fmt.Errorf("new node snapshot repo: %w", err)
+		}
+		txns.Register("node snapshot", snapshotRepo)
+		backups[cfg.NodeSnapshotRepoPebble.Path] = snapshotRepo
+		var snapshots node.SnapshotRepo = snapshotRepo
+		if repoCipher != nil {
+			snapshots = encryptedrepo.Snapshots(snapshotRepo, repoCipher)
+		}
+		if !retain && cfg.ExpiredCompactionBlocks == 0 {
+			baseManager, err = node.NewSnapshotManager(changeRepo, snapshots, cfg.NodeSnapshotThreshold, conflicts)
+			break
+		}
+		var baseRepo *noderepo.Snapshots
+		baseRepo, err = noderepo.NewSnapshots(filepath.Join(cfg.DataDir, cfg.NodeBaseRepoPebble.Path))
+		if err != nil {
+			return nil, fmt.Errorf("new node base repo: %w", err)
+		}
+		backups[cfg.NodeBaseRepoPebble.Path] = baseRepo
+		var bases node.SnapshotRepo = baseRepo
+		if repoCipher != nil {
+			bases = encryptedrepo.Snapshots(baseRepo, repoCipher)
+		}
+		baseManager, err = node.NewSnapshotManagerWithBases(changeRepo, snapshots, bases, cfg.NodeSnapshotThreshold, conflicts)
+	default:
+		err = fmt.Errorf("unknown strategy: %q", cfg.NodeManager)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("new node manager: %w", err)
 	}
+	if cfg.HistoryCacheSize > 0 {
+		baseManager.(historyCacher).SetHistoryCacheSize(cfg.HistoryCacheSize)
+	}
+	if cfg.NodeVersionDepth > 0 {
+		baseManager.(versioner).SetVersionDepth(cfg.NodeVersionDepth)
+	}
 	nodeManager := node.NewNormalizingManager(baseManager)
+	if cfg.SlowNameThreshold > 0 {
+		nodeManager = node.NewSlowNameManager(nodeManager, cfg.SlowNameThreshold)
+	}
 	cleanups = append(cleanups, nodeManager.Close)
 
 	// Initialize repository for MerkleTrie.
 	// The cleanup is delegated to MerkleTrie.
-	trieRepo, err := merkletrierepo.NewPebble(filepath.Join(cfg.DataDir, cfg.MerkleTrieRepoPebble.Path))
-	if err != nil {
-		return nil, fmt.Errorf("new trie repo: %w", err)
+	var trieRepo merkletrie.Repo
+	var triePebble *merkletrierepo.Pebble // unless the trie is backed by a remote store
+	switch {
+	case cfg.MerkleTrieRemote != "":
+		trieRepo, err = merkletrierepo.NewRemote(cfg.MerkleTrieRemote, merkletrierepo.DefaultRemoteOptions)
+		if err != nil {
+			return nil, fmt.Errorf("new remote trie repo: %w", err)
+		}
+	case sharedDB != nil:
+		triePebble = merkletrierepo.NewPebbleShared(sharedDB, []byte(cfg.MerkleTrieRepoPebble.Prefix))
+		trieRepo = triePebble
+	default:
+		triePebble, err = merkletrierepo.NewPebbleWithCache(filepath.Join(cfg.DataDir, cfg.MerkleTrieRepoPebble.Path),
+			cacheSize(cfg.MerkleTrieRepoPebble.CacheSize, merkletrierepo.DefaultCacheSize))
+		if err != nil {
+			return nil, fmt.Errorf("new trie repo: %w", err)
+		}
+		trieRepo = triePebble
+		backups[cfg.MerkleTrieRepoPebble.Path] = triePebble
 	}
+	if triePebble != nil {
+		txns.Register("trie", triePebble)
+	}
+	txns.Register("block", blockRepo)
 
-	trie := merkletrie.New(nodeManager, trieRepo)
+	var values *valueCache
+	var store merkletrie.ValueStore = nodeManager
+	if cfg.ValueCacheSize > 0 {
+		values = newValueCache(nodeManager, cfg.ValueCacheSize)
+		store = values
+	}
+	trie := merkletrie.New(store, trieRepo)
+	if cfg.TriePrefetch > 0 {
+		trie.SetPrefetch(cfg.TriePrefetch)
+	}
+	if cfg.TrieNodeCache > 0 {
+		trie.SetNodeCache(cfg.TrieNodeCache)
+	}
+	if cfg.TrieAsyncWrites > 0 {
+		trie.SetAsyncWrites(cfg.TrieAsyncWrites)
+	}
+	if metered != nil {
+		trie.SetRepoTimer(metered.timeTrieRepo)
+	}
 	cleanups = append(cleanups, trie.Close)
 
+	var trieCheckpoint func(height int32, root *chainhash.Hash) error
+	if interval := cfg.TrieCheckpointInterval; interval > 0 {
+		if triePebble == nil {
+			return nil, fmt.Errorf("checkpoints of a remote trie repo aren't supported")
+		}
+		dir := filepath.Join(cfg.DataDir, cfg.TrieCheckpointPath)
+		deltas := cfg.TrieCheckpointDeltas
+		trieCheckpoint = func(height int32, root *chainhash.Hash) error {
+			if height%interval != 0 {
+				return nil
+			}
+			if deltas > 0 {
+				base, err := merkletrierepo.LastCheckpoint(dir)
+				if err != nil {
+					return err
+				}
+				if base > 0 && base < height && (height-base)/interval <= deltas {
+					return triePebble.DeltaCheckpoint(dir, base, height, root)
+				}
+			}
+			return triePebble.Checkpoint(dir, height, root, 2)
+		}
+	}
+
+	// Write the batches of the last txn again, if the repos were left committing them.
+	recovered, err := txns.Recover()
+	if err != nil {
+		return nil, fmt.Errorf("recover txn: %w", err)
+	}
+	if recovered {
+		log.Warnf("Recovered the ClaimTrie writes of an interrupted commit")
+	}
+
 	// Restore the last height.
 	previousHeight, err := blockRepo.Load()
 	if err != nil {
 		return nil, fmt.Errorf("load blocks: %w", err)
 	}
 
+	var root *chainhash.Hash
 	if previousHeight > 0 {
 		hash, err := blockRepo.Get(previousHeight)
 		if err != nil {
 			return nil, fmt.Errorf("get hash: %w", err)
 		}
 		trie.SetRoot(hash)
+		root = hash
 
 		_, err = nodeManager.IncrementHeightTo(previousHeight)
 		if err != nil {
 			return nil, fmt.Errorf("node manager init: %w", err)
 		}
+	} else if hash, err := blockRepo.Get(0); err == nil { // the genesis claims were applied
+		trie.SetRoot(hash)
+		root = hash
 	}
 
 	ct := &ClaimTrie{
@@ -124,129 +512,482 @@ func New(cfg config.Config) (*ClaimTrie, error) {
 
 		nodeManager: nodeManager,
 		merkleTrie:  trie,
+		values:      values,
 
 		height: previousHeight,
+		root:   root,
+		tip:    previousHeight,
+
+		events:             event.NewBus(),
+		trieCheckpoint:     trieCheckpoint,
+		slowBlockThreshold: cfg.SlowBlockThreshold,
+		memoryBudget:       cfg.MemoryBudget,
+		txns:               txns,
+		backups:            backups,
+		batchBlocks:        cfg.BatchBlocks,
+		batchBytes:         cfg.BatchBytes,
+		hashWorkers:        runtime.NumCPU(),
+		budgets:            cfg.Budgets,
+		alerts:             map[string]int64{},
+		metrics:            metered,
+		conflicts:          conflicts,
+		maxReorgDepth:      cfg.MaxReorgDepth,
+		historyReadWait:    cfg.HistoryReadWait,
+		watcher:            &watcher{names: map[string]*WatchedName{}},
+		nameLocks:          newNameLocks(),
+		progress:           map[string]progress.Progress{},
+	}
+	trie.SetProgress(ct.reportProgress)
+	if triePebble != nil {
+		triePebble.SetProgress(ct.reportProgress)
+	}
+
+	if cfg.HashWorkers > 0 {
+		ct.hashWorkers = cfg.HashWorkers
+	}
+
+	if retain {
+		ct.retention = cfg.ChangeRetention
+		ct.retentionBlocks = cfg.ChangeRetentionBlocks
+		ct.pruneInterval = cfg.PruneInterval
+		ct.pruner = baseManager.(pruner)
+		ct.prunedAt = ct.retainedFrom(previousHeight)
+		ct.importer, _ = baseManager.(stateImporter)
+	}
+	if cfg.ExpiredCompactionBlocks > 0 {
+		ct.pruneInterval = cfg.PruneInterval
+		ct.expiredCompactor = baseManager.(expiredCompactor)
+		ct.expiredBlocks = cfg.ExpiredCompactionBlocks
+		ct.compactedAt = previousHeight - cfg.ExpiredCompactionBlocks
+	}
+
+	if cfg.TrieRetentionBlocks > 0 {
+		ct.trieRetentionBlocks = cfg.TrieRetentionBlocks
+		ct.trieRetainedFrom = previousHeight - cfg.TrieRetentionBlocks + 1
+		if triePebble != nil {
+			ct.trieCompacter = triePebble
+		}
+	}
+
+	if cfg.CrossValidateTrie {
+		ct.ramTrie = newRamTrie(nodeManager)
+	}
+
+	if cfg.DeltaSyncBlocks > 0 {
+		ct.deltas = deltasync.NewPublisher(cfg.DeltaSyncBlocks)
+		if cfg.DeltaSyncListen != "" {
+			l, err := net.Listen("tcp", cfg.DeltaSyncListen)
+			if err != nil {
+				return nil, fmt.Errorf("listen for delta followers: %w", err)
+			}
+			go ct.deltas.Serve(l) // nolint : errchk
+			cleanups = append(cleanups, l.Close)
+		}
+	}
+
+	if cfg.StandbyBlocks > 0 {
+		ct.standbys = standby.NewPublisher(cfg.StandbyBlocks)
+		if cfg.StandbyListen != "" {
+			l, err := net.Listen("tcp", cfg.StandbyListen)
+			if err != nil {
+				return nil, fmt.Errorf("listen for standbys: %w", err)
+			}
+			go ct.standbys.Serve(l) // nolint : errchk
+			cleanups = append(cleanups, l.Close)
+		}
+	}
+
+	if cfg.Metrics {
+		metered.registerStats(ct)
+		if cfg.MetricsListen != "" {
+			l, err := net.Listen("tcp", cfg.MetricsListen)
+			if err != nil {
+				return nil, fmt.Errorf("listen for metrics: %w", err)
+			}
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", ct.MetricsHandler())
+			srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+			go srv.Serve(l) // nolint : errchk
+			cleanups = append(cleanups, srv.Close)
+		}
+	}
+
+	if cfg.UpstreamURL != "" {
+		source, err := upstream.NewSource(cfg.UpstreamURL, cfg.UpstreamKey)
+		if err != nil {
+			return nil, fmt.Errorf("new upstream source: %w", err)
+		}
+		ct.upstream = upstream.NewVerifier(source, cfg.UpstreamInterval)
+		cleanups = append(cleanups, ct.upstream.Close)
+	}
+
+	if cfg.SupportExpiringNotice > 0 {
+		supportExpiringRepo, err := temporalrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.SupportExpiringRepoPebble.Path))
+		if err != nil {
+			return nil, fmt.Errorf("new support expiring repo: %w", err)
+		}
+		cleanups = append(cleanups, supportExpiringRepo.Close)
+		backups[cfg.SupportExpiringRepoPebble.Path] = supportExpiringRepo
+		ct.supportExpiringRepo = supportExpiringRepo
+		ct.supportExpiringNotice = cfg.SupportExpiringNotice
+	}
+
+	if len(cfg.Webhooks) > 0 {
+		hooks, err := newHooks(cfg.Webhooks)
+		if err != nil {
+			return nil, fmt.Errorf("new webhooks: %w", err)
+		}
+		dispatcher := webhook.New(hooks, webhook.DefaultOptions)
+		ct.events.Subscribe(dispatcher.Handle)
+		cleanups = append(cleanups, dispatcher.Close)
+	}
+
+	if cfg.NameActivity {
+		activityRepo, err := activityrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.NameActivityRepoPebble.Path))
+		if err != nil {
+			return nil, fmt.Errorf("new name activity repo: %w", err)
+		}
+		cleanups = append(cleanups, activityRepo.Close)
+		backups[cfg.NameActivityRepoPebble.Path] = activityRepo
+		ct.activityRepo = activityRepo
+	}
+
+	if cfg.TakeoverDiagnostics {
+		ct.takeoverDiagnostics = true
+		if cfg.TakeoverRecord {
+			takeoverRepo, err := takeoverrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.TakeoverRepoPebble.Path))
+			if err != nil {
+				return nil, fmt.Errorf("new takeover repo: %w", err)
+			}
+			cleanups = append(cleanups, takeoverRepo.Close)
+			backups[cfg.TakeoverRepoPebble.Path] = takeoverRepo
+			ct.takeoverRepo = takeoverRepo
+		}
+	}
+
+	if cfg.ClaimHistory {
+		claimHistory, err := claimhistoryrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ClaimHistoryRepoPebble.Path))
+		if err != nil {
+			return nil, fmt.Errorf("new claim history repo: %w", err)
+		}
+		cleanups = append(cleanups, claimHistory.Close)
+		backups[cfg.ClaimHistoryRepoPebble.Path] = claimHistory
+		ct.claimHistory = claimHistory
+	}
+
+	if cfg.ValueHashIndex {
+		valueHashes, err := valuehashrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ValueHashRepoPebble.Path))
+		if err != nil {
+			return nil, fmt.Errorf("new value hash repo: %w", err)
+		}
+		cleanups = append(cleanups, valueHashes.Close)
+		backups[cfg.ValueHashRepoPebble.Path] = valueHashes
+		ct.valueHashes = valueHashes
 	}
 
 	if cfg.Record {
-		chainRepo, err := chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
+		newChainRepo := chainrepo.NewPebble
+		if cfg.ChainRepoDigests {
+			newChainRepo = chainrepo.NewPebbleWithDigests
+		}
+		chainRepo, err := newChainRepo(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
 		if err != nil {
 			return nil, fmt.Errorf("new change change repo: %w", err)
 		}
 		cleanups = append(cleanups, chainRepo.Close)
+		backups[cfg.ChainRepoPebble.Path] = chainRepo
 		ct.chainRepo = chainRepo
+		if repoCipher != nil {
+			ct.chainRepo = encryptedrepo.Chain(chainRepo, repoCipher)
+		}
 
 		reportedBlockRepo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ReportedBlockRepoPebble.Path))
 		if err != nil {
 			return nil, fmt.Errorf("new reported block repo: %w", err)
 		}
 		cleanups = append(cleanups, reportedBlockRepo.Close)
+		backups[cfg.ReportedBlockRepoPebble.Path] = reportedBlockRepo
 		ct.reportedBlockRepo = reportedBlockRepo
 	}
+
+	claimValues, err := newClaimValueStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if claimValues.repo != nil {
+		cleanups = append(cleanups, claimValues.repo.Close)
+		if b, ok := claimValues.repo.(backupRepo); ok {
+			backups[cfg.ValueRepoPebble.Path] = b
+		}
+		if repoCipher != nil {
+			claimValues.repo = encryptedrepo.ClaimValue(claimValues.repo, repoCipher)
+		}
+	}
+	ct.claimValues = claimValues
+
+	if cfg.GenesisClaims != "" && previousHeight == 0 && root == nil {
+		err = ct.applyGenesisClaims(cfg.GenesisClaims, changeRepo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.OutPointIndex {
+		outPoints, err := outpointrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.OutPointRepoPebble.Path))
+		if err != nil {
+			return nil, fmt.Errorf("new outpoint repo: %w", err)
+		}
+		cleanups = append(cleanups, outPoints.Close)
+		backups[cfg.OutPointRepoPebble.Path] = outPoints
+		ct.outPoints = outPoints
+		ct.pendingOutPoints = map[change.OutPoint]outpoint.Entry{}
+
+		indexed, err := outPoints.Height()
+		if err != nil {
+			return nil, fmt.Errorf("outpoint repo height: %w", err)
+		}
+		if indexed != ct.height || indexed <= 0 {
+			err = ct.rebuildOutPoints() // enabled, or left behind, since the last run
+			if err != nil {
+				return nil, fmt.Errorf("build outpoint index: %w", err)
+			}
+		}
+	}
+
+	if cfg.ChannelStats {
+		ct.channels = newChannelIndex()
+		err := ct.rebuildChannelIndex()
+		if err != nil {
+			return nil, fmt.Errorf("build channel index: %w", err)
+		}
+	}
+
+	if cfg.ClaimIDIndex {
+		claimIDRepo, err := claimidrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ClaimIDRepoPebble.Path))
+		if err != nil {
+			return nil, fmt.Errorf("new claim ID repo: %w", err)
+		}
+		cleanups = append(cleanups, claimIDRepo.Close)
+		backups[cfg.ClaimIDRepoPebble.Path] = claimIDRepo
+		ct.claimIDs, err = newClaimIDIndex(cfg.ClaimIDCollisions, claimIDRepo)
+		if err != nil {
+			return nil, fmt.Errorf("new claim ID index: %w", err)
+		}
+
+		indexed, err := claimIDRepo.Height()
+		if err != nil {
+			return nil, fmt.Errorf("claim ID repo height: %w", err)
+		}
+		if indexed != ct.height || indexed <= 0 {
+			err = ct.rebuildClaimIDIndex() // enabled, or left behind, since the last run
+			if err != nil {
+				return nil, fmt.Errorf("build claim ID index: %w", err)
+			}
+		}
+	}
+
+	if cfg.TopNames {
+		ct.topNames = newTopNames()
+		err := ct.rebuildTopNames()
+		if err != nil {
+			return nil, fmt.Errorf("build top names: %w", err)
+		}
+	}
+
+	if cfg.NameSearch {
+		ct.search = newNameSearch()
+		err := ct.rebuildNameSearch()
+		if err != nil {
+			return nil, fmt.Errorf("build name search index: %w", err)
+		}
+	}
+
+	ct.flushers = map[string]flusher{"node": nodeRepo}
+	if f, ok := trieRepo.(flusher); ok {
+		ct.flushers["trie"] = f
+	}
+	ct.updateStats()
+
+	if cfg.CompactionThreshold > 0 {
+		repos := map[string]compacter{"node": nodeRepo}
+		if triePebble != nil {
+			repos["trie"] = triePebble
+		}
+		ct.compaction = newCompactionScheduler(repos, cfg.CompactionThreshold, cfg.CompactionWindows)
+		ct.compaction.onProgress = ct.reportProgress
+		cleanups = append(cleanups, ct.compaction.wait) // before closing the repos
+	}
 	ct.cleanups = cleanups
+	logForks(ct.height)
+
+	ct.commit()
+
+	if cfg.IdleWarmup > 0 {
+		snapshots, _ := baseManager.(snapshotSaver)
+		ct.warmer = newIdleWarmer(ct, cfg.IdleWarmup, snapshots)
+		ct.cleanups = append(ct.cleanups, ct.warmer.stop) // before closing the repos
+	}
+	ct.cleanups = append(ct.cleanups, func() error { return ct.SetBatching(false) })
 
 	return ct, nil
 }
 
 // AddClaim adds a Claim to the ClaimTrie.
 func (ct *ClaimTrie) AddClaim(name []byte, op wire.OutPoint, id node.ClaimID, amt int64, val []byte) error {
+	return ct.forwardNodeChange(addClaimChange(name, op, id, amt, val))
+}
+
+// UpdateClaim updates a Claim in the ClaimTrie.
+func (ct *ClaimTrie) UpdateClaim(name []byte, op wire.OutPoint, amt int64, id node.ClaimID, val []byte) error {
+	return ct.forwardNodeChange(updateClaimChange(name, op, amt, id, val))
+}
+
+// SpendClaim spends a Claim in the ClaimTrie.
+func (ct *ClaimTrie) SpendClaim(name []byte, op wire.OutPoint, id node.ClaimID) error {
+	return ct.forwardNodeChange(spendClaimChange(name, op, id))
+}
+
+// AddSupport adds a Support to the ClaimTrie.
+func (ct *ClaimTrie) AddSupport(name []byte, value []byte, op wire.OutPoint, amt int64, id node.ClaimID) error {
+	return ct.forwardNodeChange(addSupportChange(name, value, op, amt, id))
+}
+
+// SpendSupport spends a Support in the ClaimTrie.
+func (ct *ClaimTrie) SpendSupport(name []byte, op wire.OutPoint, id node.ClaimID) error {
+	return ct.forwardNodeChange(spendSupportChange(name, op, id))
+}
 
-	chg := change.Change{
+func addClaimChange(name []byte, op wire.OutPoint, id node.ClaimID, amt int64, val []byte) change.Change {
+	return change.Change{
 		Type:     change.AddClaim,
 		Name:     name,
-		OutPoint: op.String(),
+		OutPoint: change.NewOutPoint(op),
 		Amount:   amt,
-		ClaimID:  id.String(),
+		ClaimID:  id,
 		Value:    val,
 	}
-
-	return ct.forwardNodeChange(chg)
 }
 
-// UpdateClaim updates a Claim in the ClaimTrie.
-func (ct *ClaimTrie) UpdateClaim(name []byte, op wire.OutPoint, amt int64, id node.ClaimID, val []byte) error {
-
-	chg := change.Change{
+func updateClaimChange(name []byte, op wire.OutPoint, amt int64, id node.ClaimID, val []byte) change.Change {
+	return change.Change{
 		Type:     change.UpdateClaim,
 		Name:     name,
-		OutPoint: op.String(),
+		OutPoint: change.NewOutPoint(op),
 		Amount:   amt,
-		ClaimID:  id.String(),
+		ClaimID:  id,
 		Value:    val,
 	}
-
-	return ct.forwardNodeChange(chg)
 }
 
-// SpendClaim spends a Claim in the ClaimTrie.
-func (ct *ClaimTrie) SpendClaim(name []byte, op wire.OutPoint, id node.ClaimID) error {
-
-	chg := change.Change{
+func spendClaimChange(name []byte, op wire.OutPoint, id node.ClaimID) change.Change {
+	return change.Change{
 		Type:     change.SpendClaim,
 		Name:     name,
-		OutPoint: op.String(),
-		ClaimID:  id.String(),
+		OutPoint: change.NewOutPoint(op),
+		ClaimID:  id,
 	}
-
-	return ct.forwardNodeChange(chg)
 }
 
-// AddSupport adds a Support to the ClaimTrie.
-func (ct *ClaimTrie) AddSupport(name []byte, value []byte, op wire.OutPoint, amt int64, id node.ClaimID) error {
-
-	chg := change.Change{
+func addSupportChange(name []byte, value []byte, op wire.OutPoint, amt int64, id node.ClaimID) change.Change {
+	return change.Change{
 		Type:     change.AddSupport,
 		Name:     name,
-		OutPoint: op.String(),
+		OutPoint: change.NewOutPoint(op),
 		Amount:   amt,
-		ClaimID:  id.String(),
+		ClaimID:  id,
 		Value:    value,
 	}
-
-	return ct.forwardNodeChange(chg)
 }
 
-// SpendSupport spends a Support in the ClaimTrie.
-func (ct *ClaimTrie) SpendSupport(name []byte, op wire.OutPoint, id node.ClaimID) error {
-
-	chg := change.Change{
+func spendSupportChange(name []byte, op wire.OutPoint, id node.ClaimID) change.Change {
+	return change.Change{
 		Type:     change.SpendSupport,
 		Name:     name,
-		OutPoint: op.String(),
-		ClaimID:  id.String(),
+		OutPoint: change.NewOutPoint(op),
+		ClaimID:  id,
 	}
-
-	return ct.forwardNodeChange(chg)
 }
 
-// AppendBlock increases block by one.
+// AppendBlock increases block by one. If it fails, the block is rolled back, along with its changes,
+// instead of leaving the ClaimTrie at its height with its state partially written, so the block can be
+// fed, and appended, again.
 func (ct *ClaimTrie) AppendBlock() error {
 
+	defer ct.holdChanges()()
+
+	height := ct.height
+	names := make([][]byte, 0, len(ct.changes))
+	for i := range ct.changes {
+		names = append(names, ct.changes[i].Name)
+	}
+
+	err := ct.appendBlock()
+	if err != nil && ct.height > height {
+		if rerr := ct.abortBlock(height, names); rerr != nil {
+			return fmt.Errorf("%w; roll back to %d: %s", err, height, rerr)
+		}
+	}
+
+	return err
+}
+
+// appendBlock is AppendBlock, without the roll back. It must be called with the changes held.
+func (ct *ClaimTrie) appendBlock() error {
+
+	start := time.Now()
 	ct.height++
+	changes := len(ct.changes)
 
 	if len(ct.changes) > 0 && ct.chainRepo != nil {
 		err := ct.chainRepo.Save(ct.height, ct.changes)
 		if err != nil {
 			return fmt.Errorf("chain change repo save: %w", err)
 		}
-		ct.changes = ct.changes[:0]
+	}
+	blockChanges := ct.changes
+	ct.changes = ct.changes[:0]
+	transfers := ct.transfers
+	ct.transfers = nil
+
+	expirations, err := ct.temporalRepo.NodesAt(ct.height)
+	if err != nil {
+		return fmt.Errorf("temporal repo nodes at: %w", err)
 	}
 
+	// The names updated by the block are held from the coordinators until it's appended.
+	locked := make([][]byte, 0, len(blockChanges)+len(expirations))
+	for i := range blockChanges {
+		locked = append(locked, blockChanges[i].Name)
+	}
+	unlock := ct.nameLocks.lockAll(append(locked, expirations...), ct.height)
+	defer func() {
+		atomic.StoreInt32(&ct.tip, ct.height)
+		unlock()
+	}()
+
+	ct.merkleTrie.Prefetch() // the paths of the last block, while the nodes are updated
+
+	stageStart := time.Now()
 	names, err := ct.nodeManager.IncrementHeightTo(ct.height)
 	if err != nil {
 		return fmt.Errorf("node mgr increment: %w", err)
 	}
+	ct.checkBudget(StageNodes, stageStart)
 
-	expirations, err := ct.temporalRepo.NodesAt(ct.height)
-	if err != nil {
-		return fmt.Errorf("temporal repo nodes at: %w", err)
+	if ct.outPoints != nil {
+		err = ct.updateOutPoints(blockChanges)
+		if err != nil {
+			return fmt.Errorf("update outpoint index: %w", err)
+		}
 	}
 
+	stageStart = time.Now()
 	names = removeDuplicates(names) // comes out sorted
 
 	updateNames := make([][]byte, 0, len(names)+len(expirations))
 	updateHeights := make([]int32, 0, len(names)+len(expirations))
 	updateNames = append(updateNames, names...)
+	changedNames := updateNames[:len(names)]
 	for range names { // log to the db that we updated a name at this height for rollback purposes
 		updateHeights = append(updateHeights, ct.height)
 	}
@@ -256,6 +997,9 @@ func (ct *ClaimTrie) AppendBlock() error {
 	for _, name := range names {
 
 		ct.merkleTrie.Update(name, true)
+		if ct.values != nil {
+			ct.values.refresh(name, ct.height)
+		}
 
 		newName, nextUpdate := ct.nodeManager.NextUpdateHeightOfNode(name)
 		if nextUpdate <= 0 {
@@ -268,35 +1012,251 @@ func (ct *ClaimTrie) AppendBlock() error {
 	if err != nil {
 		return fmt.Errorf("temporal repo set at: %w", err)
 	}
+	ct.checkBudget(StageTrie, stageStart)
 
-	hitFork := ct.updateTrieForHashForkIfNecessary()
-
-	h := ct.MerkleHash()
-	ct.blockRepo.Set(ct.height, h)
-
-	if hitFork {
-		ct.merkleTrie.SetRoot(h) // for clearing the memory entirely
-		runtime.GC()
+	if ct.maxReorgDepth > 0 && ct.height > ct.maxReorgDepth {
+		err = ct.temporalRepo.DropNodesBefore(ct.height - ct.maxReorgDepth + 1)
+		if err != nil {
+			return fmt.Errorf("temporal repo drop before: %w", err)
+		}
+		if ct.outPoints != nil {
+			err = ct.outPoints.DropBefore(ct.height - ct.maxReorgDepth + 1)
+			if err != nil {
+				return fmt.Errorf("outpoint repo drop before: %w", err)
+			}
+		}
 	}
 
-	return nil
+	if ct.activityRepo != nil {
+		err = ct.activityRepo.SetActiveAt(changedNames, ct.height)
+		if err != nil {
+			return fmt.Errorf("name activity repo set: %w", err)
+		}
+		err = ct.noticeClaimed(names)
+		if err != nil {
+			return fmt.Errorf("notice claimed names: %w", err)
+		}
+	}
+
+	if ct.valueHashes != nil {
+		err = ct.recordValueHashes(names)
+		if err != nil {
+			return fmt.Errorf("record value hashes: %w", err)
+		}
+	}
+
+	if ct.claimHistory != nil {
+		err = ct.recordClaimHistory(names)
+		if err != nil {
+			return fmt.Errorf("record claim history: %w", err)
+		}
+	}
+
+	if ct.supportExpiringRepo != nil {
+		err = ct.noticeSupportExpirations(changedNames)
+		if err != nil {
+			return fmt.Errorf("notice support expirations: %w", err)
+		}
+	}
+
+	if ct.events.HasSubscribers() {
+		err = ct.publishBlockEvents(blockChanges, transfers, names)
+		if err != nil {
+			return fmt.Errorf("publish block events: %w", err)
+		}
+	}
+
+	if ct.sinks.active() {
+		err = ct.publishChanges(blockChanges)
+		if err != nil {
+			return fmt.Errorf("publish changes: %w", err)
+		}
+	}
+
+	if ct.takeoverDiagnostics {
+		err = ct.diagnoseTakeovers(names)
+		if err != nil {
+			return fmt.Errorf("diagnose takeovers: %w", err)
+		}
+	}
+
+	err = ct.snapshotWatched(names)
+	if err != nil {
+		return fmt.Errorf("snapshot watched names: %w", err)
+	}
+
+	if atomic.LoadInt32(&ct.consistencyCheck) == 1 {
+		err = ct.checkConsistency(names)
+		if err != nil {
+			return fmt.Errorf("check consistency: %w", err)
+		}
+	}
+
+	if ct.channels != nil {
+		if ct.height == param.NormalizedNameForkHeight {
+			err = ct.rebuildChannelIndex() // the names are normalized from now on
+		} else {
+			err = ct.updateChannelIndex(names)
+		}
+		if err != nil {
+			return fmt.Errorf("update channel index: %w", err)
+		}
+	}
+
+	if ct.claimIDs != nil {
+		if ct.height == param.NormalizedNameForkHeight {
+			err = ct.rebuildClaimIDIndex() // the names are normalized from now on
+		} else {
+			err = ct.updateClaimIDIndex(names)
+		}
+		if err != nil {
+			return fmt.Errorf("update claim ID index: %w", err)
+		}
+	}
+
+	if ct.topNames != nil {
+		if ct.height == param.NormalizedNameForkHeight {
+			err = ct.rebuildTopNames() // the names are normalized from now on
+		} else {
+			err = ct.updateTopNames(names)
+		}
+		if err != nil {
+			return fmt.Errorf("update top names: %w", err)
+		}
+	}
+
+	if ct.search != nil {
+		if ct.height == param.NormalizedNameForkHeight {
+			err = ct.rebuildNameSearch() // the names are normalized from now on
+		} else {
+			err = ct.updateNameSearch(names)
+		}
+		if err != nil {
+			return fmt.Errorf("update name search index: %w", err)
+		}
+	}
+
+	hitFork := ct.updateTrieForHashForkIfNecessary()
+
+	// Without any name dirtied, activated or expired, the trie is untouched.
+	stageStart = time.Now()
+	parent := ct.root
+	h := ct.root
+	if hitFork {
+		ct.rebuildCorruptNodes()
+		h = ct.merkleTrie.ParallelMerkleHashAllClaims(ct.hashWorkers)
+	} else if len(names) > 0 || h == nil {
+		h = ct.MerkleHash()
+	}
+	ct.checkBudget(StageHash, stageStart)
+	err = ct.merkleTrie.Commit() // before the root, so the repos never hold a root without its nodes
+	if err != nil {
+		return fmt.Errorf("merkle trie commit: %w", err)
+	}
+	if ct.ramTrie != nil {
+		err = ct.crossValidate(names, h)
+		if err != nil {
+			return err
+		}
+	}
+	ct.root = h
+	err = ct.blockRepo.Set(ct.height, h)
+	if err != nil {
+		return fmt.Errorf("block repo set: %w", err)
+	}
+	err = ct.batchBlock()
+	if err != nil {
+		return fmt.Errorf("batch block: %w", err)
+	}
+	ct.commit()
+	if ct.deltas != nil {
+		ct.publishDelta(parent, names, hitFork)
+	}
+	if ct.standbys != nil {
+		ct.publishStandbyBlock(parent, blockChanges)
+	}
+	if ct.upstream != nil {
+		ct.verifyUpstream(h)
+	}
+
+	if hitFork {
+		ct.merkleTrie.SetRoot(h) // for clearing the memory entirely
+		runtime.GC()
+	} else if ct.memoryBudget > 0 {
+		ct.enforceMemoryBudget(h)
+	}
+
+	if ct.trieCheckpoint != nil {
+		err = ct.trieCheckpoint(ct.height, h)
+		if err != nil {
+			return fmt.Errorf("trie checkpoint: %w", err)
+		}
+	}
+
+	err = ct.pruneIfDue()
+	if err != nil {
+		return err
+	}
+	err = ct.compactExpiredIfDue()
+	if err != nil {
+		return err
+	}
+
+	if ct.compaction != nil {
+		ct.compaction.add(changes)
+	}
+	if ct.metrics != nil {
+		ct.metrics.recordBlock(blockChanges, len(names))
+	}
+	ct.updateStats()
+
+	if elapsed := time.Since(start); ct.slowBlockThreshold > 0 && elapsed >= ct.slowBlockThreshold {
+		log.Warnf("Slow block: %d took %s, changes: %d, names updated: %d, scheduled: %d",
+			ct.height, elapsed, changes, len(names), len(expirations))
+	}
+	ct.checkBudget(StageBlock, start)
+	ct.recordBlockTime(start)
+
+	return nil
 }
 
 func (ct *ClaimTrie) updateTrieForHashForkIfNecessary() bool {
 	if ct.height != param.AllClaimsInMerkleForkHeight {
 		return false
 	}
-	fmt.Printf("Marking all trie nodes as dirty for the hash fork...")
+	log.Info("Marking all trie nodes as dirty for the hash fork...")
 	// invalidate all names because we have to recompute the hash on everything
 	// requires its own 8GB of RAM in current trie impl.
+	if ct.values != nil {
+		ct.values.reset()
+	}
 	ct.nodeManager.IterateNames(func(name []byte) bool {
 		ct.merkleTrie.Update(name, false)
 		return true
 	})
-	fmt.Printf(" Done. Now recomputing all hashes...\n")
+	log.Info("Marked all trie nodes as dirty. Now recomputing all hashes...")
 	return true
 }
 
+// rebuildCorruptNodes rebuilds the subtries of the trie nodes, which failed their checksums
+// on being resolved, from the names in the node manager, instead of the stored nodes.
+func (ct *ClaimTrie) rebuildCorruptNodes() {
+	for _, key := range ct.merkleTrie.Corrupted() {
+		rebuilt := 0
+		ct.nodeManager.IterateNames(func(name []byte) bool {
+			if bytes.HasPrefix(name, key) {
+				ct.merkleTrie.Update(name, false)
+				if ct.values != nil {
+					ct.values.invalidate([][]byte{name})
+				}
+				rebuilt++
+			}
+			return true
+		})
+		log.Warnf("Rebuilt the corrupt trie node at %q of block %d from %d names", key, ct.height, rebuilt)
+	}
+}
+
 func removeDuplicates(names [][]byte) [][]byte { // this might be too expensive; we'll have to profile it
 	sort.Slice(names, func(i, j int) bool { // put names in order so we can skip duplicates
 		return bytes.Compare(names[i], names[j]) < 0
@@ -321,9 +1281,38 @@ func (ct *ClaimTrie) ReportHash(height int32, hash chainhash.Hash) error {
 	return nil
 }
 
+// ErrReorgTooDeep is returned when the ClaimTrie is reset further back than the MaxReorgDepth.
+var ErrReorgTooDeep = errors.New("reorg is too deep")
+
 // ResetHeight resets the ClaimTrie to a previous known height..
 func (ct *ClaimTrie) ResetHeight(height int32) error {
 
+	defer ct.holdChanges()()
+
+	ct.history.Lock()
+	defer ct.history.Unlock()
+
+	if height >= ct.height {
+		return fmt.Errorf("invalid height: %d, at %d", height, ct.height)
+	}
+	if ct.maxReorgDepth > 0 && ct.height-height > ct.maxReorgDepth {
+		return fmt.Errorf("%w: from %d to %d, over the max of %d blocks; "+
+			"restore a checkpoint at, or before, height %d, or rebuild the ClaimTrie",
+			ErrReorgTooDeep, ct.height, height, ct.maxReorgDepth, height)
+	}
+	if height < ct.prunedAt {
+		return fmt.Errorf("%w: from %d to %d, past the changes pruned up to %d; rebuild the ClaimTrie",
+			ErrReorgTooDeep, ct.height, height, ct.prunedAt)
+	}
+	if height < ct.compactedAt {
+		return fmt.Errorf("%w: from %d to %d, past the expired names compacted up to %d; rebuild the ClaimTrie",
+			ErrReorgTooDeep, ct.height, height, ct.compactedAt)
+	}
+	if height < ct.trieRetainedFrom {
+		return fmt.Errorf("%w: from %d to %d, past the trie nodes pruned before %d; rebuild the ClaimTrie",
+			ErrReorgTooDeep, ct.height, height, ct.trieRetainedFrom)
+	}
+
 	names := make([][]byte, 0)
 	for h := height + 1; h <= ct.height; h++ {
 		results, err := ct.temporalRepo.NodesAt(h)
@@ -332,28 +1321,379 @@ func (ct *ClaimTrie) ResetHeight(height int32) error {
 		}
 		names = append(names, results...)
 	}
-	err := ct.nodeManager.DecrementHeightTo(names, height)
+
+	return ct.rewind(height, names)
+}
+
+// abortBlock rolls the block failing to append back to the height, as ResetHeight does, with the names
+// of its changes, which are dropped, whether they're pending, or appended to the node repo already.
+// It must be called with the changes held.
+func (ct *ClaimTrie) abortBlock(height int32, names [][]byte) error {
+
+	ct.history.Lock()
+	defer ct.history.Unlock()
+
+	log.Warnf("Rolling back the block %d failing to append", ct.height)
+
+	scheduled, err := ct.temporalRepo.NodesAt(ct.height)
 	if err != nil {
-		return err
+		log.Warnf("Rolling back without the names scheduled at %d: %s", ct.height, err)
 	}
+	names = append(names, scheduled...)
 
+	ct.changes = ct.changes[:0]
+	ct.transfers = nil
+	for key := range ct.pendingOutPoints {
+		delete(ct.pendingOutPoints, key)
+	}
+	ct.pendingOwners = nil
+	ct.nodeManager.DiscardChanges(0)
+
+	return ct.rewind(height, names)
+}
+
+// rewind rewinds the node manager, the trie, and the indexes, to the height, with the names updated after it.
+// It must be called with the changes, and the history, held.
+func (ct *ClaimTrie) rewind(height int32, names [][]byte) error {
+
+	unlock := ct.nameLocks.lockAll(names, ct.height+1)
+	defer func() {
+		atomic.StoreInt32(&ct.tip, ct.height)
+		unlock()
+	}()
+
+	if ct.nodeManager.Height() > height { // unless an aborted block failed before its changes were appended
+		err := ct.nodeManager.DecrementHeightTo(names, height)
+		if err != nil {
+			return err
+		}
+	}
+
+	from := ct.height
 	ct.height = height
 	hash, err := ct.blockRepo.Get(height)
 	if err != nil {
 		return err
 	}
 	ct.merkleTrie.SetRoot(hash)
-	return nil
+	ct.root = hash
+	ct.commit()
+	if ct.values != nil {
+		ct.values.invalidate(names)
+	}
+	if ct.deltas != nil {
+		ct.deltas.Rewind(height)
+	}
+	if ct.standbys != nil {
+		ct.standbys.Rewind(height)
+	}
+	if ct.ramTrie != nil {
+		for _, name := range names {
+			ct.ramTrie.Update(name, false)
+		}
+	}
+
+	if ct.activityRepo != nil {
+		err = ct.activityRepo.Rewind(names, height)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ct.takeoverRepo != nil {
+		err = ct.takeoverRepo.Rewind(names, height)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ct.valueHashes != nil {
+		err = ct.valueHashes.Rewind(names, height)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ct.claimHistory != nil {
+		err = ct.claimHistory.Rewind(names, height)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ct.outPoints != nil {
+		err = ct.outPoints.Rewind(height)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ct.channels != nil {
+		err = ct.rewindChannelIndex(names, from)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ct.claimIDs != nil {
+		err = ct.rewindClaimIDIndex(names, from)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ct.topNames != nil {
+		err = ct.rewindTopNames(names, from)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ct.search != nil {
+		err = ct.rewindNameSearch(names, from)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ct.compaction != nil {
+		ct.compaction.add(len(names))
+	}
+	if ct.sinks.active() {
+		ct.sinks.publish(sinkItem{height: height, reset: true})
+	}
+	ct.updateStats()
+	return ct.refreshWatched()
+}
+
+// DisconnectBlock undoes the last block appended, as ResetHeight to the height before it does.
+// The changes of the block, kept by the node repo, are its undo data.
+func (ct *ClaimTrie) DisconnectBlock() error {
+
+	if ct.height <= 0 {
+		return fmt.Errorf("disconnect block: no block appended")
+	}
+
+	return ct.ResetHeight(ct.height - 1)
+}
+
+// Repair rebuilds the node states of the names updated at the current height
+// from the node repo, bypassing the cached ones, and re-hashes their paths.
+// The resulting Merkle Hash replaces the one calculated for the current height.
+func (ct *ClaimTrie) Repair() (*chainhash.Hash, error) {
+
+	defer ct.holdChanges()()
+
+	names, err := ct.temporalRepo.NodesAt(ct.height)
+	if err != nil {
+		return nil, fmt.Errorf("temporal repo nodes at: %w", err)
+	}
+
+	return ct.rehash(names)
+}
+
+// RehashNames rebuilds the node states of the names from the node repo, bypassing the cached ones,
+// and re-hashes their paths, as Repair does for the names updated at the current height, such as
+// for the ones the consistency check flagged. The ClaimTrie must not be appended to meanwhile.
+func (ct *ClaimTrie) RehashNames(names [][]byte) (*chainhash.Hash, error) {
+
+	defer ct.holdChanges()()
+
+	normalized := make([][]byte, 0, len(names))
+	for _, name := range names {
+		normalized = append(normalized, node.NormalizeIfNecessary(name, ct.height))
+	}
+	h, err := ct.rehash(normalized)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Rehashed %d names at %d, root: %s", len(names), ct.height, h)
+
+	return h, nil
+}
+
+func (ct *ClaimTrie) rehash(names [][]byte) (*chainhash.Hash, error) {
+
+	ct.nodeManager.Invalidate(names)
+	if ct.values != nil {
+		ct.values.invalidate(names)
+	}
+	for _, name := range names {
+		ct.merkleTrie.Update(name, true)
+		if ct.ramTrie != nil {
+			ct.ramTrie.Update(name, false)
+		}
+	}
+
+	h := ct.MerkleHash()
+	err := ct.merkleTrie.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("merkle trie commit: %w", err)
+	}
+	err = ct.blockRepo.Set(ct.height, h)
+	if err != nil {
+		return nil, fmt.Errorf("block repo set: %w", err)
+	}
+	ct.root = h
+	ct.commit()
+
+	return h, nil
 }
 
 // MerkleHash returns the Merkle Hash of the claimTrie.
 func (ct *ClaimTrie) MerkleHash() *chainhash.Hash {
+	ct.rebuildCorruptNodes()
 	if ct.height >= param.AllClaimsInMerkleForkHeight {
 		return ct.merkleTrie.MerkleHashAllClaims()
 	}
 	return ct.merkleTrie.MerkleHash()
 }
 
+// NameProof returns the proof of the best claim of the name against the Merkle Hash.
+// It fails with ErrNameNotFound, or ErrNoBestClaim, without one.
+// Proofs are only supported before the all-claims fork.
+func (ct *ClaimTrie) NameProof(name []byte) (*merkletrie.Proof, error) {
+
+	if ct.height >= param.AllClaimsInMerkleForkHeight {
+		return nil, fmt.Errorf("name proofs are unsupported after the all-claims fork")
+	}
+
+	n, err := ct.nodeManager.Node(name)
+	if err != nil {
+		return nil, fmt.Errorf("node: %w", err)
+	}
+	if err = checkBestClaim(name, n); err != nil {
+		return nil, err
+	}
+
+	ct.MerkleHash() // the trie is hashed for the proof
+	name = node.NormalizeIfNecessary(name, ct.height)
+
+	return ct.merkleTrie.Prove(name, n.BestClaim.OutPoint, n.TakenOverAt)
+}
+
+// AbsenceProof returns the proof of the name not having a best claim against the Merkle Hash, which
+// proof.Proof.VerifyAbsence verifies. It fails, if the name has a best claim.
+// Proofs are only supported before the all-claims fork.
+func (ct *ClaimTrie) AbsenceProof(name []byte) (*merkletrie.Proof, error) {
+
+	if ct.height >= param.AllClaimsInMerkleForkHeight {
+		return nil, fmt.Errorf("name proofs are unsupported after the all-claims fork")
+	}
+
+	n, err := ct.nodeManager.Node(name)
+	if err != nil {
+		return nil, fmt.Errorf("node: %w", err)
+	}
+	if checkBestClaim(name, n) == nil {
+		return nil, fmt.Errorf("best claim of %q: %s", name, n.BestClaim.ClaimID)
+	}
+
+	ct.MerkleHash() // the trie is hashed for the proof
+	name = node.NormalizeIfNecessary(name, ct.height)
+
+	return ct.merkleTrie.ProveAbsence(name)
+}
+
+// ProveMany returns the proof of the best claims of the names against the Merkle Hash,
+// sharing the nodes common to their paths.
+// Proofs are only supported before the all-claims fork.
+func (ct *ClaimTrie) ProveMany(names [][]byte) (*merkletrie.MultiProof, error) {
+
+	if ct.height >= param.AllClaimsInMerkleForkHeight {
+		return nil, fmt.Errorf("name proofs are unsupported after the all-claims fork")
+	}
+
+	values := make([]merkletrie.ProofValue, 0, len(names))
+	for _, name := range names {
+		n, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return nil, fmt.Errorf("node: %w", err)
+		}
+		if err = checkBestClaim(name, n); err != nil {
+			return nil, err
+		}
+		values = append(values, merkletrie.ProofValue{
+			Name:           node.NormalizeIfNecessary(name, ct.height),
+			OutPoint:       n.BestClaim.OutPoint,
+			TakeoverHeight: n.TakenOverAt,
+		})
+	}
+
+	ct.MerkleHash() // the trie is hashed for the proof
+
+	return ct.merkleTrie.ProveMany(values)
+}
+
+// NameProofs returns the proofs of the best claim of the name against the roots of the
+// last k blocks, newest first, for clients which may be behind or on another branch.
+// The heights at which the name has no best claim are skipped, as are those after the
+// all-claims fork.
+func (ct *ClaimTrie) NameProofs(name []byte, k int) (*merkletrie.ProofBundle, error) {
+
+	ct.MerkleHash() // the trie is hashed for the proof at the current height
+
+	bundle := &merkletrie.ProofBundle{Name: node.NormalizeIfNecessary(name, ct.height)}
+	for h := ct.height; h > 0 && h > ct.height-int32(k); h-- {
+		if h >= param.AllClaimsInMerkleForkHeight {
+			continue
+		}
+
+		normName := node.NormalizeIfNecessary(name, h)
+		if !bytes.Equal(normName, bundle.Name) {
+			break // the proofs of a bundle share the name
+		}
+		n, err := ct.nodeManager.NodeAt(h, normName)
+		if err != nil {
+			return nil, fmt.Errorf("node at %d: %w", h, err)
+		}
+		if n == nil || n.BestClaim == nil {
+			continue
+		}
+
+		root, err := ct.blockRepo.Get(h)
+		if err != nil {
+			return nil, fmt.Errorf("root at %d: %w", h, err)
+		}
+		trie := ct.merkleTrie
+		if h != ct.height {
+			trie = trie.At(root)
+		}
+		p, err := trie.Prove(normName, n.BestClaim.OutPoint, n.TakenOverAt)
+		if err != nil {
+			return nil, fmt.Errorf("prove at %d: %w", h, err)
+		}
+		bundle.Proofs = append(bundle.Proofs, merkletrie.BundledProof{Height: h, Root: *root, Proof: p})
+	}
+
+	if len(bundle.Proofs) == 0 {
+		return nil, fmt.Errorf("%w in the last %d blocks: %q", ErrNoBestClaim, k, name)
+	}
+
+	return bundle, nil
+}
+
+// NamesCreatedBetween returns the names first seen within the heights, inclusive, in order by height, then name.
+func (ct *ClaimTrie) NamesCreatedBetween(from, to int32) ([][]byte, error) {
+	if ct.activityRepo == nil {
+		return nil, fmt.Errorf("name activity isn't indexed")
+	}
+	return ct.activityRepo.NamesCreatedBetween(from, to)
+}
+
+// InactiveSince returns the names without any claim or support changes at or after height.
+func (ct *ClaimTrie) InactiveSince(height int32) ([][]byte, error) {
+	if ct.activityRepo == nil {
+		return nil, fmt.Errorf("name activity isn't indexed")
+	}
+	return ct.activityRepo.InactiveSince(height)
+}
+
+// Conflicts returns the claims added with the TXO of existing ones, so far.
+func (ct *ClaimTrie) Conflicts() []node.Conflict {
+	return ct.conflicts.Conflicts()
+}
+
 // Height returns the current block height.
 func (ct *ClaimTrie) Height() int32 {
 	return ct.height
@@ -363,6 +1703,8 @@ func (ct *ClaimTrie) Height() int32 {
 // Any calls to the ClaimTrie after Close() being called results undefined behaviour.
 func (ct *ClaimTrie) Close() error {
 
+	ct.sinks.removeAll()
+
 	for i := len(ct.cleanups) - 1; i >= 0; i-- {
 		cleanup := ct.cleanups[i]
 		err := cleanup()
@@ -374,16 +1716,82 @@ func (ct *ClaimTrie) Close() error {
 	return nil
 }
 
+// ErrValueTooLarge is returned when the value of a claim, or a support, is larger than
+// param.MaxClaimValueSize from param.MaxClaimValueSizeForkHeight on.
+var ErrValueTooLarge = errors.New("claim value is too large")
+
+// cacheSize returns the size of the block cache of the repo, or the default, if it's not set.
+func cacheSize(size, def int64) int64 {
+	if size > 0 {
+		return size
+	}
+	return def
+}
+
+func checkValueSize(chg change.Change, height int32) error {
+	if height < param.MaxClaimValueSizeForkHeight || len(chg.Value) <= param.MaxClaimValueSize {
+		return nil
+	}
+	return change.Wrap(fmt.Errorf("%w: %d bytes", ErrValueTooLarge, len(chg.Value)), chg.SetHeight(height))
+}
+
 func (ct *ClaimTrie) forwardNodeChange(chg change.Change) error {
 
+	defer ct.holdChanges()()
+
+	return ct.appendNodeChange(chg)
+}
+
+// forwardNodeChanges appends all of the changes, or none of them: the ones appended before one failing are discarded.
+func (ct *ClaimTrie) forwardNodeChanges(changes []change.Change) error {
+
+	defer ct.holdChanges()()
+
+	forwarded, pending := len(ct.changes), ct.nodeManager.PendingChanges()
+	for _, chg := range changes {
+		err := ct.appendNodeChange(chg)
+		if err == nil {
+			continue
+		}
+		for _, appended := range ct.changes[forwarded:] {
+			if _, ok := createdEntry(appended); ok && ct.pendingOutPoints != nil {
+				delete(ct.pendingOutPoints, appended.OutPoint)
+			}
+		}
+		ct.changes = ct.changes[:forwarded]
+		ct.nodeManager.DiscardChanges(pending)
+		return err
+	}
+
+	return nil
+}
+
+// appendNodeChange appends the change to the node manager, and the ones of the next block. It must be called
+// with the changes held.
+func (ct *ClaimTrie) appendNodeChange(chg change.Change) error {
+
 	chg.Height = ct.Height() + 1
+	err := checkValueSize(chg, chg.Height)
+	if err != nil {
+		return err
+	}
+	chg.Seq = int32(len(ct.changes)) + 1 // 0 is left to the legacy changes, which the node manager doesn't dedup
 
-	err := ct.nodeManager.AppendChange(chg)
+	stored, err := ct.claimValues.externalize(chg)
 	if err != nil {
-		return fmt.Errorf("node manager handle change: %w", err)
+		return change.Wrap(err, chg)
+	}
+	err = ct.nodeManager.AppendChange(stored)
+	if err != nil {
+		return change.Wrap(fmt.Errorf("node manager handle change: %w", err), chg)
 	}
 
 	ct.changes = append(ct.changes, chg)
+	if ct.pendingOutPoints != nil {
+		if e, ok := createdEntry(chg); ok {
+			ct.pendingOutPoints[chg.OutPoint] = e
+		}
+	}
 
 	return nil
 }
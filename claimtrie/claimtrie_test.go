@@ -1,13 +1,38 @@
 package claimtrie
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/btcsuite/btcd/claimtrie/chain/chainrepo"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/claimhistory"
 	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/encryptedrepo"
+	"github.com/btcsuite/btcd/claimtrie/event"
+	"github.com/btcsuite/btcd/claimtrie/faultyrepo"
 	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
 	"github.com/btcsuite/btcd/claimtrie/node"
 	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/claimtrie/upstream"
+	"github.com/btcsuite/btcd/claimtrie/valuehash"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 
@@ -72,6 +97,56 @@ func TestFixedHashes(t *testing.T) {
 	r.Equal(expected[:], ct.MerkleHash()[:])
 }
 
+// TestDuplicateOutPointHashes pins the roots of the claims added with the TXO of an existing claim of the name,
+// which are kept along with it, as the replay always did.
+func TestDuplicateOutPointHashes(t *testing.T) {
+
+	r := require.New(t)
+
+	tx1 := buildTx(*merkletrie.EmptyTrieHash)
+	tx2 := buildTx(tx1.TxHash())
+	o1 := tx1.TxIn[0].PreviousOutPoint
+	o2 := tx2.TxIn[0].PreviousOutPoint
+
+	tests := []struct {
+		name    string
+		id      node.ClaimID // of the claim re-added with o1 at height 2, outbidding the existing one
+		best    node.ClaimID
+		expects [2]string // the roots at heights 1 and 2
+	}{
+		{"another ID", node.NewClaimID(o2), node.NewClaimID(o2), [2]string{
+			"8946450a35c6a33f506e6ca53cf4032fdd7f517fb33667c3de2ab4d6f98dee41",
+			"66476b37bad456834d28b01cb2c5ad321338239e88a46878430ad0327b6134d5"}},
+		{"the same ID", node.NewClaimID(o1), node.NewClaimID(o1), [2]string{
+			"8946450a35c6a33f506e6ca53cf4032fdd7f517fb33667c3de2ab4d6f98dee41",
+			"8946450a35c6a33f506e6ca53cf4032fdd7f517fb33667c3de2ab4d6f98dee41"}},
+	}
+	for _, test := range tests {
+		setup(t)
+		ct, err := New(cfg)
+		r.NoError(err)
+
+		r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), 50, nil))
+		r.NoError(ct.AppendBlock())
+		expected, err := chainhash.NewHashFromStr(test.expects[0])
+		r.NoError(err)
+		r.Equal(expected[:], ct.MerkleHash()[:], test.name)
+
+		r.NoError(ct.AddClaim(b("test"), o1, test.id, 100, nil))
+		r.NoError(ct.AppendBlock())
+		expected, err = chainhash.NewHashFromStr(test.expects[1])
+		r.NoError(err)
+		r.Equal(expected[:], ct.MerkleHash()[:], test.name)
+
+		n, err := ct.Node(b("test"))
+		r.NoError(err)
+		r.Len(n.Claims, 2, test.name)
+		r.Equal(test.best, n.BestClaim.ClaimID, test.name)
+		r.Len(ct.Conflicts(), 1, test.name)
+		r.NoError(ct.Close())
+	}
+}
+
 func TestNormalizationFork(t *testing.T) {
 
 	r := require.New(t)
@@ -231,3 +306,3862 @@ func verifyBestIndex(t *testing.T, ct *ClaimTrie, name string, idx uint32, claim
 		r.Equal(idx, n.BestClaim.OutPoint.Index)
 	}
 }
+
+func TestRepair(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	err = ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil)
+	r.NoError(err)
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	err = ct.AddClaim([]byte("tester"), o2, node.NewClaimID(o2), 5, nil)
+	r.NoError(err)
+
+	err = ct.AppendBlock()
+	r.NoError(err)
+	expected := *ct.MerkleHash()
+
+	// Corrupt the materialized node, and let the repair rebuild it from the repo.
+	n, err := ct.nodeManager.Node([]byte("test"))
+	r.NoError(err)
+	n.TakenOverAt = 0
+	ct.merkleTrie.Update([]byte("test"), true)
+	r.NotEqual(expected[:], ct.MerkleHash()[:])
+
+	repaired, err := ct.Repair()
+	r.NoError(err)
+	r.Equal(expected[:], repaired[:])
+}
+
+func TestRehashNames(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	r.NoError(ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil))
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim([]byte("tester"), o2, node.NewClaimID(o2), 5, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AppendBlock())
+	expected := *ct.MerkleHash()
+
+	// The name wasn't updated at the current height, so only rehashing it rebuilds it.
+	n, err := ct.nodeManager.Node([]byte("test"))
+	r.NoError(err)
+	n.TakenOverAt = 0
+	ct.merkleTrie.Update([]byte("test"), true)
+	r.NotEqual(expected[:], ct.MerkleHash()[:])
+
+	repaired, err := ct.Repair()
+	r.NoError(err)
+	r.NotEqual(expected[:], repaired[:])
+
+	rehashed, err := ct.RehashNames([][]byte{[]byte("test"), []byte("missing")})
+	r.NoError(err)
+	r.Equal(expected[:], rehashed[:])
+	stored, err := ct.blockRepo.Get(ct.Height())
+	r.NoError(err)
+	r.Equal(expected[:], stored[:])
+	r.Equal(expected, ct.Snapshot().Root())
+}
+
+func TestSupportExpiring(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	param.OriginalClaimExpirationTime = 20
+	cfg.SupportExpiringNotice = 5
+	defer func() { cfg.SupportExpiringNotice = 0 }()
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	var events []event.Event
+	unsubscribe := ct.Subscribe(func(e event.Event) {
+		if e.Type == event.SupportExpiring {
+			events = append(events, e)
+		}
+	})
+	defer unsubscribe()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	id := node.NewClaimID(o1)
+	err = ct.AddClaim([]byte("test"), o1, id, 10, nil)
+	r.NoError(err)
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	err = ct.AddSupport([]byte("test"), nil, o2, 7, id)
+	r.NoError(err)
+
+	for i := 0; i < 15; i++ {
+		err = ct.AppendBlock()
+		r.NoError(err)
+	}
+	r.Empty(events)
+
+	err = ct.AppendBlock()
+	r.NoError(err)
+	r.Len(events, 1)
+	r.Equal(event.SupportExpiring, events[0].Type)
+	r.Equal(int32(16), events[0].Height)
+	r.Equal(int32(21), events[0].ExpireAt)
+	r.Equal(o2.String(), events[0].OutPoint)
+	r.Equal(int64(7), events[0].Amount)
+
+	for i := 0; i < 10; i++ {
+		err = ct.AppendBlock()
+		r.NoError(err)
+	}
+	r.Len(events, 1)
+}
+
+func TestRootReusedWithoutActivity(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	param.OriginalClaimExpirationTime = 10
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	err = ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil)
+	r.NoError(err)
+
+	err = ct.AppendBlock()
+	r.NoError(err)
+	expected := *ct.MerkleHash()
+	r.NotEqual(merkletrie.EmptyTrieHash[:], expected[:])
+
+	for ct.Height() < 10 {
+		err = ct.AppendBlock()
+		r.NoError(err)
+		h, err := ct.blockRepo.Get(ct.Height())
+		r.NoError(err)
+		r.Equal(expected[:], h[:])
+	}
+
+	// The expiration is scheduled in the temporal repo, and must not be skipped.
+	err = ct.AppendBlock()
+	r.NoError(err)
+	h, err := ct.blockRepo.Get(ct.Height())
+	r.NoError(err)
+	r.Equal(merkletrie.EmptyTrieHash[:], h[:])
+}
+
+func TestBlockEvents(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	var events []event.Event
+	unsubscribe := ct.Subscribe(func(e event.Event) {
+		events = append(events, e)
+	})
+	defer unsubscribe()
+
+	channel := node.ClaimID{1, 2, 3}
+	value := append(append([]byte{1}, channel[:]...), make([]byte, 64)...)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	id1 := node.NewClaimID(o1)
+	err = ct.AddClaim([]byte("test"), o1, id1, 10, value)
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+
+	r.Len(events, 3)
+	r.Equal(event.ClaimAdded, events[0].Type)
+	r.Equal(id1.String(), events[0].ClaimID)
+	r.Equal(channel.String(), events[0].Channel)
+	r.Equal(event.Takeover, events[1].Type)
+	r.Equal(id1.String(), events[1].ClaimID)
+	r.Equal(int32(1), events[1].Height)
+	r.Equal(event.NameChanged, events[2].Type)
+	r.Equal(id1.String(), events[2].ClaimID)
+	r.Equal(int64(10), events[2].EffectiveAmount)
+	r.Equal(channel.String(), events[2].Channel)
+
+	events = nil
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	err = ct.AddClaim([]byte("test"), o2, node.NewClaimID(o2), 5, nil)
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+
+	r.Len(events, 2)
+	r.Equal(event.ClaimAdded, events[0].Type)
+	r.Empty(events[0].Channel)
+	r.Equal(event.NameChanged, events[1].Type)
+	r.Equal(id1.String(), events[1].ClaimID) // still the best claim.
+
+	events = nil
+	err = ct.SpendClaim([]byte("test"), o1, id1)
+	r.NoError(err)
+	err = ct.SpendClaim([]byte("test"), o2, node.NewClaimID(o2))
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+
+	r.Len(events, 1)
+	r.Equal(event.NameChanged, events[0].Type)
+	r.Empty(events[0].ClaimID)
+}
+
+func TestWatch(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	r.NoError(ct.Watch([][]byte{[]byte("test")}))
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	id := node.NewClaimID(o1)
+	err = ct.AddClaim([]byte("test"), o1, id, 10, nil)
+	r.NoError(err)
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	err = ct.AddClaim([]byte("other"), o2, node.NewClaimID(o2), 10, nil)
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+
+	// Adding a change clears the node cache; the watched state survives it.
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	err = ct.AddSupport([]byte("test"), nil, o3, 5, id)
+	r.NoError(err)
+
+	states := ct.WatchedState()
+	r.Len(states, 1)
+	r.Equal([]byte("test"), states[0].Name)
+	r.Equal(int32(1), states[0].UpdatedAt)
+	r.NotNil(states[0].Node)
+	r.Equal(id, states[0].Node.BestClaim.ClaimID)
+	r.Empty(states[0].Node.Supports)
+	r.Len(states[0].Events, 3) // ClaimAdded, Takeover and NameChanged
+
+	err = ct.AppendBlock()
+	r.NoError(err)
+	states = ct.WatchedState()
+	r.Equal(int32(2), states[0].UpdatedAt)
+	r.Len(states[0].Node.Supports, 1)
+
+	ct.Unwatch([][]byte{[]byte("test")})
+	r.Empty(ct.WatchedState())
+}
+
+func TestSharedRepo(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg := cfg
+	cfg.SharedRepoPebble.Path = "shared_pebble_db"
+
+	ct, err := New(cfg)
+	r.NoError(err)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	err = ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil)
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+	expected := *ct.MerkleHash()
+	r.NoError(ct.Close())
+
+	ct, err = New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+	r.Equal(int32(2), ct.Height())
+	r.Equal(expected[:], ct.MerkleHash()[:])
+
+	n, err := ct.Node([]byte("test"))
+	r.NoError(err)
+	r.NotNil(n)
+	r.Equal(int64(10), n.BestClaim.Amount)
+
+	var names []string
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		names = append(names, string(name))
+		return true
+	})
+	r.Equal([]string{"test"}, names)
+
+	cfg.NodeRepoPebble.Prefix = cfg.BlockRepoPebble.Prefix
+	_, err = New(cfg)
+	r.Error(err)
+}
+
+func TestRepoFailures(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	err = ct.AppendBlock()
+	r.NoError(err)
+
+	injected := errors.New("disk full")
+	in := faultyrepo.NewInjector()
+	ct.blockRepo = faultyrepo.Block(ct.blockRepo, in)
+	ct.temporalRepo = faultyrepo.Temporal(ct.temporalRepo, in)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	addClaims := func() {
+		err := ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil)
+		r.NoError(err)
+		err = ct.AddClaim([]byte("tester"), o2, node.NewClaimID(o2), 10, nil)
+		r.NoError(err)
+	}
+
+	// Only the first of the names is scheduled before the batch fails. The block is rolled back,
+	// instead of being left at its height.
+	addClaims()
+	in.PartialAt(in.Ops()+2, 1, injected)
+	err = ct.AppendBlock()
+	r.ErrorIs(err, injected)
+	r.Equal(int32(1), ct.Height())
+	names, err := ct.temporalRepo.NodesAt(2)
+	r.NoError(err)
+	r.Equal([][]byte{[]byte("test")}, names)
+
+	// NodesAt, SetNodesAt, then the block hash Set, after the changes are appended to the node repo.
+	addClaims()
+	in.FailAt(in.Ops()+3, injected)
+	err = ct.AppendBlock()
+	r.ErrorIs(err, injected)
+	r.Equal(int32(1), ct.Height())
+	r.Equal(int32(1), ct.nodeManager.Height())
+
+	// The block fed again is appended, without the changes of the failed attempts.
+	addClaims()
+	err = ct.AppendBlock()
+	r.NoError(err)
+	r.Equal(int32(2), ct.Height())
+	for _, name := range []string{"test", "tester"} {
+		res, err := ct.ResolveAt([]byte(name), 2)
+		r.NoError(err)
+		r.Len(res.Node.Claims, 1)
+	}
+
+	setup(t)
+	expected, err := New(cfg)
+	r.NoError(err)
+	defer expected.Close()
+	r.NoError(expected.AppendBlock())
+	r.NoError(expected.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(expected.AddClaim([]byte("tester"), o2, node.NewClaimID(o2), 10, nil))
+	r.NoError(expected.AppendBlock())
+	r.Equal(expected.MerkleHash(), ct.MerkleHash())
+}
+
+func TestChannelStats(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.ChannelStats = true
+	defer func() { cfg.ChannelStats = false }()
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	channel := node.ClaimID{7}
+	signed := append(append([]byte{1}, channel[:]...), make([]byte, 64)...)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	o4 := wire.OutPoint{Hash: hash, Index: 4}
+	err = ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, signed)
+	r.NoError(err)
+	err = ct.AddClaim([]byte("tester"), o2, node.NewClaimID(o2), 20, signed)
+	r.NoError(err)
+	err = ct.AddClaim([]byte("other"), o3, node.NewClaimID(o3), 30, nil)
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+
+	stats, ok := ct.ChannelStats(channel)
+	r.True(ok)
+	r.Equal(ChannelStats{Claims: 2, TotalStaked: 30, UpdatedAt: 1}, stats)
+
+	err = ct.AddSupport([]byte("test"), nil, o4, 5, node.NewClaimID(o1))
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+
+	stats, ok = ct.ChannelStats(channel)
+	r.True(ok)
+	r.Equal(ChannelStats{Claims: 2, TotalStaked: 35, UpdatedAt: 2}, stats)
+
+	err = ct.SpendClaim([]byte("tester"), o2, node.NewClaimID(o2))
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+
+	stats, ok = ct.ChannelStats(channel)
+	r.True(ok)
+	r.Equal(ChannelStats{Claims: 1, TotalStaked: 15, UpdatedAt: 4}, stats)
+
+	err = ct.ResetHeight(1)
+	r.NoError(err)
+	stats, ok = ct.ChannelStats(channel)
+	r.True(ok)
+	r.Equal(ChannelStats{Claims: 2, TotalStaked: 30, UpdatedAt: 1}, stats)
+
+	// The aggregates are rebuilt on startup.
+	err = ct.AddSupport([]byte("test"), nil, o4, 5, node.NewClaimID(o1))
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+	r.NoError(ct.Close())
+	ct, err = New(cfg)
+	r.NoError(err)
+	stats, ok = ct.ChannelStats(channel)
+	r.True(ok)
+	r.Equal(int64(35), stats.TotalStaked)
+
+	_, ok = ct.ChannelStats(node.ClaimID{8})
+	r.False(ok)
+}
+
+func TestClaimIDIndex(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.ClaimIDIndex = true
+	defer func() { cfg.ClaimIDIndex, cfg.ClaimIDCollisions = false, "" }()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	id1, id2 := node.NewClaimID(o1), node.NewClaimID(o2)
+
+	newTrie := func(policy string) *ClaimTrie {
+		setup(t)
+		cfg.ClaimIDCollisions = policy
+		ct, err := New(cfg)
+		r.NoError(err)
+		t.Cleanup(func() { r.NoError(ct.Close()) })
+
+		err = ct.AddClaim([]byte("test"), o1, id1, 10, nil)
+		r.NoError(err)
+		err = ct.AddClaim([]byte("other"), o2, id2, 20, nil)
+		r.NoError(err)
+		err = ct.AppendBlock()
+		r.NoError(err)
+		return ct
+	}
+
+	ct := newTrie("")
+	claims, err := ct.ClaimByID(id1)
+	r.NoError(err)
+	r.Equal([]IndexedClaim{{ClaimID: id1, Name: []byte("test"), OutPoint: o1, AcceptedAt: 1}}, claims)
+	claims, err = ct.ClaimsByIDPrefix(id2.String()[:8])
+	r.NoError(err)
+	r.Len(claims, 1)
+	r.Equal(o2, claims[0].OutPoint)
+	claims, err = ct.ClaimByID(node.ClaimID{9})
+	r.NoError(err)
+	r.Empty(claims)
+
+	// A pathological feed claims the ID of another claim. The block is indexed regardless; strictly,
+	// the lookups of the shared ID, and of the prefixes matching several IDs, fail.
+	err = ct.AddClaim([]byte("tester"), o3, id1, 30, nil)
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+	r.Equal(int32(2), ct.Height())
+	_, err = ct.ClaimByID(id1)
+	r.ErrorIs(err, ErrClaimIDCollision)
+	claims, err = ct.ClaimByID(id2)
+	r.NoError(err)
+	r.Len(claims, 1)
+	_, err = ct.ClaimsByIDPrefix("")
+	r.ErrorIs(err, ErrAmbiguousClaimID)
+
+	ct = newTrie(config.CollisionsFirst)
+	err = ct.AddClaim([]byte("tester"), o3, id1, 30, nil)
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+	claims, err = ct.ClaimByID(id1)
+	r.NoError(err)
+	r.Len(claims, 1)
+	r.Equal(o1, claims[0].OutPoint)
+	claims, err = ct.ClaimsByIDPrefix("")
+	r.NoError(err)
+	r.Len(claims, 1)
+
+	ct = newTrie(config.CollisionsBoth)
+	err = ct.AddClaim([]byte("tester"), o3, id1, 30, nil)
+	r.NoError(err)
+	err = ct.AppendBlock()
+	r.NoError(err)
+	claims, err = ct.ClaimByID(id1)
+	r.NoError(err)
+	r.Len(claims, 2)
+	r.Equal(o1, claims[0].OutPoint)
+	r.Equal(o3, claims[1].OutPoint)
+	r.Equal([]byte("tester"), claims[1].Name)
+	claims, err = ct.ClaimsByIDPrefix("")
+	r.NoError(err)
+	r.Len(claims, 3)
+
+	// Resetting drops the colliding claim.
+	err = ct.ResetHeight(1)
+	r.NoError(err)
+	claims, err = ct.ClaimByID(id1)
+	r.NoError(err)
+	r.Len(claims, 1)
+
+	// The index is kept across the restarts.
+	setup(t)
+	cfg.ClaimIDCollisions = ""
+	ct, err = New(cfg)
+	r.NoError(err)
+	r.NoError(ct.AddClaim([]byte("test"), o1, id1, 10, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.Close())
+	ct, err = New(cfg)
+	r.NoError(err)
+	claims, err = ct.ClaimsByIDPrefix(id1.String()[:5])
+	r.NoError(err)
+	r.Equal([]IndexedClaim{{ClaimID: id1, Name: []byte("test"), OutPoint: o1, AcceptedAt: 1}}, claims)
+	r.NoError(ct.Close())
+
+	setup(t)
+	cfg.ClaimIDCollisions = "last"
+	_, err = New(cfg)
+	r.Error(err)
+}
+
+func TestGenesisClaims(t *testing.T) {
+
+	r := require.New(t)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	id1 := node.NewClaimID(o1)
+
+	// The genesis claims are dumped as the changes of height 0 of a chain repo.
+	dump := func(height int32, changes ...change.Change) string {
+		repo, err := chainrepo.NewPebble(t.TempDir())
+		r.NoError(err)
+		defer repo.Close()
+		r.NoError(repo.Save(height, changes))
+		path := filepath.Join(t.TempDir(), "genesis.copy")
+		f, err := os.Create(path)
+		r.NoError(err)
+		defer f.Close()
+		_, err = repo.ExportCopy(f, height, height+1)
+		r.NoError(err)
+		return path
+	}
+
+	setup(t)
+	cfg.GenesisClaims = dump(0,
+		addClaimChange([]byte("test"), o1, id1, 10, []byte("value")),
+		addSupportChange([]byte("test"), nil, o2, 5, id1),
+		addClaimChange([]byte("other"), o3, node.NewClaimID(o3), 20, nil))
+	defer func() { cfg.GenesisClaims = "" }()
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	r.Equal(int32(0), ct.Height())
+	genesis := *ct.MerkleHash()
+	r.NotEqual(*merkletrie.EmptyTrieHash, genesis)
+	r.Equal(genesis, ct.Snapshot().Root())
+
+	n, err := ct.Node([]byte("test"))
+	r.NoError(err)
+	r.Equal(id1, n.BestClaim.ClaimID)
+	r.Equal(int32(0), n.TakenOverAt)
+	r.Equal(int64(15), n.BestClaim.EffectiveAmount(n.Supports))
+
+	// They're applied only once, and the root of height 0 is restored.
+	r.NoError(ct.Close())
+	ct, err = New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+	r.Equal(genesis, *ct.MerkleHash())
+	n, err = ct.Node([]byte("test"))
+	r.NoError(err)
+	r.Len(n.Claims, 1)
+	r.Len(n.Supports, 1)
+
+	// The blocks build on the genesis claims, and resetting to 0 restores them.
+	o4 := wire.OutPoint{Hash: hash, Index: 4}
+	r.NoError(ct.AddClaim([]byte("test"), o4, node.NewClaimID(o4), 100, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AppendBlock())
+	r.NotEqual(genesis, *ct.MerkleHash())
+	r.NoError(ct.ResetHeight(0))
+	r.Equal(genesis, *ct.MerkleHash())
+	n, err = ct.Node([]byte("test"))
+	r.NoError(err)
+	r.Len(n.Claims, 1)
+
+	setup(t)
+	cfg.GenesisClaims = dump(1, addClaimChange([]byte("test"), o1, id1, 10, nil))
+	_, err = New(cfg)
+	r.Error(err)
+
+	setup(t)
+	cfg.GenesisClaims = dump(0, spendClaimChange([]byte("test"), o1, id1))
+	_, err = New(cfg)
+	r.Error(err)
+}
+
+func TestIdleWarmup(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.NodeManager = config.NodeManagerSnapshot
+	cfg.NodeSnapshotThreshold = 1000
+	cfg.IdleWarmup = 10 * time.Millisecond
+	defer func() {
+		cfg.NodeManager = config.NodeManagerReplay
+		cfg.NodeSnapshotThreshold = config.DefaultConfig.NodeSnapshotThreshold
+		cfg.IdleWarmup = 0
+	}()
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	id1 := node.NewClaimID(o1)
+	r.NoError(ct.AddClaim([]byte("test"), o1, id1, 10, nil))
+	r.NoError(ct.AppendBlock())
+	for i := 0; i < 40; i++ {
+		op := wire.OutPoint{Hash: hash, Index: uint32(100 + i)}
+		r.NoError(ct.AddSupport([]byte("test"), nil, op, 1, id1))
+		r.NoError(ct.AppendBlock())
+	}
+	for ct.height < 70 {
+		r.NoError(ct.AppendBlock())
+	}
+
+	// The competing claim is activated 2 blocks later, so the name is due then.
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim([]byte("test"), o2, node.NewClaimID(o2), 100, nil))
+	r.NoError(ct.AppendBlock())
+
+	r.Eventually(func() bool {
+		ct.warmer.mu.Lock()
+		defer ct.warmer.mu.Unlock()
+		return ct.warmer.warmedAt == ct.height
+	}, 5*time.Second, time.Millisecond)
+
+	cost, err := ct.warmer.snapshots.ReplayCost([]byte("test"), ct.height)
+	r.NoError(err)
+	r.Zero(cost)
+
+	ct.nodeManager.Invalidate([][]byte{[]byte("test")})
+	n, err := ct.nodeManager.Node([]byte("test"))
+	r.NoError(err)
+	r.Len(n.Claims, 2)
+	r.Len(n.Supports, 40)
+	r.Equal(int64(50), n.BestClaim.EffectiveAmount(n.Supports))
+
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AppendBlock())
+	n, err = ct.nodeManager.Node([]byte("test"))
+	r.NoError(err)
+	r.Equal(node.NewClaimID(o2), n.BestClaim.ClaimID)
+}
+
+func TestTopNames(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.TopNames = true
+	defer func() { cfg.TopNames = false }()
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	ranked := func(n int) []string {
+		top, err := ct.TopNames(n)
+		r.NoError(err)
+		var names []string
+		for _, tn := range top {
+			names = append(names, fmt.Sprintf("%s:%d", tn.Name, tn.Amount))
+		}
+		return names
+	}
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	o4 := wire.OutPoint{Hash: hash, Index: 4}
+	o5 := wire.OutPoint{Hash: hash, Index: 5}
+
+	r.NoError(ct.AddClaim([]byte("a"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AddClaim([]byte("b"), o2, node.NewClaimID(o2), 30, nil))
+	r.NoError(ct.AddClaim([]byte("c"), o3, node.NewClaimID(o3), 20, nil))
+	r.NoError(ct.AppendBlock())
+	r.Equal([]string{"b:30", "c:20"}, ranked(2))
+	r.Equal([]string{"b:30", "c:20", "a:10"}, ranked(10))
+
+	// Supports count, and the ties are ranked by name.
+	r.NoError(ct.AddSupport([]byte("a"), nil, o4, 25, node.NewClaimID(o1)))
+	r.NoError(ct.AddClaim([]byte("bb"), o5, node.NewClaimID(o5), 20, nil))
+	r.NoError(ct.SpendClaim([]byte("b"), o2, node.NewClaimID(o2)))
+	r.NoError(ct.AppendBlock())
+	r.Equal([]string{"a:35", "bb:20", "c:20"}, ranked(10))
+
+	top, err := ct.TopNames(1)
+	r.NoError(err)
+	r.Equal(node.NewClaimID(o1), top[0].ClaimID)
+	r.Equal(int32(1), top[0].TakenOverAt)
+
+	r.NoError(ct.ResetHeight(1))
+	r.Equal([]string{"b:30", "c:20", "a:10"}, ranked(10))
+
+	// Many names and updates are ranked as sorting all of them would.
+	type ranking struct {
+		name string
+		id   node.ClaimID
+		amt  int64
+	}
+	claims := map[string]*ranking{"a": {"a", node.NewClaimID(o1), 10}, "b": {"b", node.NewClaimID(o2), 30}, "c": {"c", node.NewClaimID(o3), 20}}
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 50; j++ {
+			name := fmt.Sprintf("name%d", (i*31+j*7)%200)
+			op := wire.OutPoint{Hash: hash, Index: uint32(100 + i*50 + j)}
+			amt := int64((i*17+j*13)%97 + 1)
+			if c, ok := claims[name]; ok {
+				r.NoError(ct.AddSupport([]byte(name), nil, op, amt, c.id))
+				c.amt += amt
+				continue
+			}
+			r.NoError(ct.AddClaim([]byte(name), op, node.NewClaimID(op), amt, nil))
+			claims[name] = &ranking{name, node.NewClaimID(op), amt}
+		}
+		r.NoError(ct.AppendBlock())
+	}
+	var sorted []*ranking
+	for _, c := range claims {
+		sorted = append(sorted, c)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].amt != sorted[j].amt {
+			return sorted[i].amt > sorted[j].amt
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	var expected []string
+	for _, c := range sorted {
+		expected = append(expected, fmt.Sprintf("%s:%d", c.name, c.amt))
+	}
+	r.Equal(expected, ranked(len(claims)+1))
+
+	cfg.TopNames = false
+	setup(t)
+	ct2, err := New(cfg)
+	r.NoError(err)
+	defer ct2.Close()
+	_, err = ct2.TopNames(10)
+	r.ErrorIs(err, ErrTopNamesNotIndexed)
+}
+
+func TestSearchNames(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.NameSearch = true
+	defer func() { cfg.NameSearch = false }()
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	search := func(query string, opts SearchOptions) []string {
+		if opts.Limit == 0 {
+			opts.Limit = 10
+		}
+		found, err := ct.SearchNames(query, opts)
+		r.NoError(err)
+		var names []string
+		for _, name := range found {
+			names = append(names, string(name))
+		}
+		return names
+	}
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	for i, name := range []string{"cat-videos", "Catalog", "funny_cats", "dog.cat", "dogs", "bobcat"} {
+		op := wire.OutPoint{Hash: hash, Index: uint32(i)}
+		r.NoError(ct.AddClaim([]byte(name), op, node.NewClaimID(op), 10, nil))
+	}
+	r.NoError(ct.AppendBlock())
+
+	r.Equal([]string{"Catalog", "cat-videos", "dog.cat", "funny_cats"}, search("cat", SearchOptions{}))
+	r.Equal([]string{"Catalog", "bobcat", "cat-videos", "dog.cat", "funny_cats"}, search("CAT", SearchOptions{Substring: true}))
+	r.Equal([]string{"dog.cat"}, search("cat dog", SearchOptions{}))
+	r.Equal([]string{"cat-videos"}, search("videos/cat", SearchOptions{}))
+	r.Empty(search("fish", SearchOptions{}))
+	r.Empty(search("--", SearchOptions{}))
+
+	// Paginated by the last name of the previous page.
+	r.Equal([]string{"Catalog", "cat-videos"}, search("cat", SearchOptions{Limit: 2}))
+	r.Equal([]string{"dog.cat", "funny_cats"}, search("cat", SearchOptions{After: []byte("cat-videos"), Limit: 2}))
+	r.Empty(search("cat", SearchOptions{After: []byte("funny_cats"), Limit: 2}))
+
+	// The names without best claims are dropped.
+	r.NoError(ct.SpendClaim([]byte("dog.cat"), wire.OutPoint{Hash: hash, Index: 3}, node.NewClaimID(wire.OutPoint{Hash: hash, Index: 3})))
+	r.NoError(ct.AppendBlock())
+	r.Equal([]string{"dogs"}, search("dog", SearchOptions{}))
+
+	r.NoError(ct.ResetHeight(1))
+	r.Equal([]string{"dog.cat", "dogs"}, search("dog", SearchOptions{}))
+
+	_, err = ct.SearchNames("cat", SearchOptions{})
+	r.Error(err)
+}
+
+func TestOutPointIndex(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.OutPointIndex = true
+	defer func() { cfg.OutPointIndex = false }()
+
+	ct, err := New(cfg)
+	r.NoError(err)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	o4 := wire.OutPoint{Hash: hash, Index: 4}
+
+	r.NoError(ct.AddClaim([]byte("a"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AddSupport([]byte("a"), nil, o2, 5, node.NewClaimID(o1)))
+	r.NoError(ct.AddClaim([]byte("b"), o3, node.NewClaimID(o3), 20, nil))
+	r.NoError(ct.AppendBlock())
+
+	// The outpoints, which aren't claims or supports, are left alone.
+	spent, err := ct.SpendOutPoint(o4)
+	r.NoError(err)
+	r.False(spent)
+
+	spent, err = ct.SpendOutPoint(o2)
+	r.NoError(err)
+	r.True(spent)
+	r.NoError(ct.AppendBlock())
+	n, err := ct.Node([]byte("a"))
+	r.NoError(err)
+	r.Len(n.Supports, 0)
+	r.Len(n.Claims, 1)
+
+	// A claim added, and spent, by the same block.
+	tx := ct.Begin(3)
+	r.NoError(tx.SpendClaim([]byte("b"), o3, node.NewClaimID(o3)))
+	r.NoError(tx.AddClaim([]byte("c"), o4, node.NewClaimID(o4), 30, nil))
+	spent, err = tx.SpendOutPoint(o4)
+	r.NoError(err)
+	r.True(spent)
+	spent, err = tx.SpendOutPoint(o1)
+	r.NoError(err)
+	r.True(spent)
+	r.NoError(tx.Commit())
+	r.NoError(ct.AppendBlock())
+	for _, name := range []string{"a", "b", "c"} {
+		n, err = ct.Node([]byte(name))
+		r.NoError(err)
+		r.True(n == nil || len(n.Claims) == 0, name)
+	}
+	spent, err = ct.SpendOutPoint(o1)
+	r.NoError(err)
+	r.False(spent)
+
+	// Resetting restores the outpoints spent since.
+	r.NoError(ct.ResetHeight(1))
+	spent, err = ct.SpendOutPoint(o2)
+	r.NoError(err)
+	r.True(spent)
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.Close())
+
+	// The index is rebuilt from the nodes, if it's enabled later.
+	cfg.OutPointIndex = false
+	ct, err = New(cfg)
+	r.NoError(err)
+	_, err = ct.SpendOutPoint(o1)
+	r.ErrorIs(err, ErrOutPointNotIndexed)
+	r.NoError(ct.AddClaim([]byte("d"), o4, node.NewClaimID(o4), 30, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.Close())
+
+	cfg.OutPointIndex = true
+	ct, err = New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+	spent, err = ct.SpendOutPoint(o4)
+	r.NoError(err)
+	r.True(spent)
+	spent, err = ct.SpendOutPoint(o3)
+	r.NoError(err)
+	r.True(spent)
+	r.NoError(ct.AppendBlock())
+	for _, name := range []string{"b", "d"} {
+		n, err = ct.Node([]byte(name))
+		r.NoError(err)
+		r.True(n == nil || len(n.Claims) == 0, name)
+	}
+}
+
+func TestValueStorage(t *testing.T) {
+
+	r := require.New(t)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+
+	build := func(storage string) *ClaimTrie {
+		setup(t)
+		cfg.ValueStorage = storage
+		cfg.ValueBlobSize = 4
+		defer func() { cfg.ValueStorage, cfg.ValueBlobSize = "", 0 }()
+
+		ct, err := New(cfg)
+		r.NoError(err)
+		r.NoError(ct.AddClaim([]byte("a"), o1, node.NewClaimID(o1), 10, []byte("large value")))
+		r.NoError(ct.AddClaim([]byte("a"), o2, node.NewClaimID(o2), 5, []byte("ab")))
+		r.NoError(ct.AppendBlock())
+		r.NoError(ct.SpendClaim([]byte("a"), o1, node.NewClaimID(o1)))
+		r.NoError(ct.UpdateClaim([]byte("a"), o3, 20, node.NewClaimID(o1), []byte("updated value")))
+		r.NoError(ct.AppendBlock())
+		return ct
+	}
+
+	inline := build(config.ValuesInline)
+	defer inline.Close()
+
+	ct := build(config.ValuesBlobs)
+	r.Equal(inline.MerkleHash(), ct.MerkleHash())
+
+	n, err := ct.Node([]byte("a"))
+	r.NoError(err)
+	r.Len(n.Claims, 2)
+	for _, c := range n.Claims {
+		value, err := ct.ClaimValue(c)
+		r.NoError(err)
+		switch c.OutPoint {
+		case o2:
+			r.Equal([]byte("ab"), c.Value) // kept inline, under the blob size
+			r.Equal([]byte("ab"), value)
+		default:
+			r.Equal(o3, c.OutPoint)
+			r.Nil(c.Value)
+			r.Equal([]byte("updated value"), value)
+		}
+	}
+	r.NoError(ct.Close())
+
+	// The blobs are fetched on demand after a restart.
+	cfg.ValueStorage = config.ValuesBlobs
+	ct, err = New(cfg)
+	cfg.ValueStorage = ""
+	r.NoError(err)
+	n, err = ct.Node([]byte("a"))
+	r.NoError(err)
+	value, err := ct.ClaimValue(n.BestClaim)
+	r.NoError(err)
+	r.Equal([]byte("updated value"), value)
+	r.NoError(ct.Close())
+
+	ct = build(config.ValuesHashesOnly)
+	defer ct.Close()
+	r.Equal(inline.MerkleHash(), ct.MerkleHash())
+	n, err = ct.Node([]byte("a"))
+	r.NoError(err)
+	_, err = ct.ClaimValue(n.BestClaim)
+	r.ErrorIs(err, ErrValueNotStored)
+
+	setup(t)
+	cfg.ValueStorage, cfg.ChannelStats = config.ValuesHashesOnly, true
+	_, err = New(cfg)
+	cfg.ValueStorage, cfg.ChannelStats = "", false
+	r.Error(err)
+}
+
+func TestRenewalCandidates(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.OutPointIndex = true
+	defer func() { cfg.OutPointIndex = false }()
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	o4 := wire.OutPoint{Hash: hash, Index: 4}
+	o5 := wire.OutPoint{Hash: hash, Index: 5}
+	owner, other := []byte{0x76, 0xa9, 1}, []byte{0x76, 0xa9, 2}
+
+	tx := ct.Begin(1)
+	r.NoError(tx.AddClaim(b("a"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(tx.SetOwner(o1, owner))
+	r.NoError(tx.AddSupport(b("a"), nil, o4, 5, node.NewClaimID(o1)))
+	r.NoError(tx.SetOwner(o4, owner))
+	r.NoError(tx.AddClaim(b("c"), o3, node.NewClaimID(o3), 10, nil))
+	r.NoError(tx.SetOwner(o3, other))
+	r.NoError(tx.Commit())
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AddClaim(b("b"), o2, node.NewClaimID(o2), 20, nil))
+	ct.SetOwner(o2, owner)
+	r.NoError(ct.AppendBlock())
+	for ct.height < 495 {
+		r.NoError(ct.AppendBlock())
+	}
+
+	outPoints := func(window int32) []wire.OutPoint {
+		candidates, err := ct.RenewalCandidates(owner, window)
+		r.NoError(err)
+		var ops []wire.OutPoint
+		for _, c := range candidates {
+			ops = append(ops, c.OutPoint)
+		}
+		return ops
+	}
+	r.Equal([]wire.OutPoint{o1, o2}, outPoints(10))
+	r.Equal([]wire.OutPoint{o1}, outPoints(6))
+	r.Empty(outPoints(5))
+
+	// The claims updated are renewed.
+	r.NoError(ct.SpendClaim(b("a"), o1, node.NewClaimID(o1)))
+	r.NoError(ct.UpdateClaim(b("a"), o5, 10, node.NewClaimID(o1), nil))
+	ct.SetOwner(o5, owner)
+	r.NoError(ct.AppendBlock())
+	candidates, err := ct.RenewalCandidates(owner, 10)
+	r.NoError(err)
+	r.Len(candidates, 1)
+	r.Equal(o2, candidates[0].OutPoint)
+	r.Equal(b("b"), candidates[0].Name)
+	r.Equal(int32(502), candidates[0].ExpireAt)
+	r.Equal(int32(6), candidates[0].BlocksUntilExpired)
+
+	// The claims expired aren't.
+	for ct.height < 502 {
+		r.NoError(ct.AppendBlock())
+	}
+	r.Empty(outPoints(10))
+}
+
+func TestDisconnectBlock(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+	r.Error(ct.DisconnectBlock())
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	r.NoError(ct.AddClaim(b("a"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AppendBlock())
+	root := *ct.MerkleHash()
+	before, err := ct.Node(b("a"))
+	r.NoError(err)
+
+	// The claim added, the support added, and the takeover of the block are undone.
+	r.NoError(ct.AddClaim(b("a"), o2, node.NewClaimID(o2), 20, nil))
+	r.NoError(ct.AddSupport(b("a"), nil, o3, 5, node.NewClaimID(o1)))
+	r.NoError(ct.AppendBlock())
+	n, err := ct.Node(b("a"))
+	r.NoError(err)
+	r.Len(n.Claims, 2)
+	r.Equal(o2, n.BestClaim.OutPoint)
+
+	r.NoError(ct.DisconnectBlock())
+	r.Equal(int32(1), ct.height)
+	r.Equal(root, *ct.MerkleHash())
+	n, err = ct.Node(b("a"))
+	r.NoError(err)
+	r.Len(n.Claims, 1)
+	r.Empty(n.Supports)
+	r.Equal(o1, n.BestClaim.OutPoint)
+	r.Equal(before.TakenOverAt, n.TakenOverAt)
+}
+
+// recordingSink records the changes, and the resets, it receives, and blocks until release is closed.
+type recordingSink struct {
+	release chan struct{}
+	mu      sync.Mutex
+	heights []int32
+	changes []AppliedChange
+	resets  []int32
+}
+
+func (s *recordingSink) HandleChanges(height int32, changes []AppliedChange) {
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.heights = append(s.heights, height)
+	s.changes = append(s.changes, changes...)
+}
+
+func (s *recordingSink) HandleReset(height int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resets = append(s.resets, height)
+}
+
+func TestChangeSink(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	sink := &recordingSink{release: make(chan struct{})}
+	remove := ct.AddChangeSink(sink, SinkOptions{QueueSize: 1, Block: true})
+	full := &recordingSink{release: make(chan struct{})}
+	removeFull := ct.AddChangeSink(full, SinkOptions{QueueSize: 1})
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim(b("a"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AddSupport(b("a"), nil, o2, 5, node.NewClaimID(o1)))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.SpendSupport(b("a"), o2, node.NewClaimID(o1)))
+
+	// The blocking sink holds up the next blocks, until it's released, while the other drops them.
+	appended := make(chan error)
+	go func() {
+		err := ct.AppendBlock()
+		if err == nil {
+			err = ct.AppendBlock()
+		}
+		appended <- err
+	}()
+	select {
+	case <-appended:
+		r.Fail("the blocks were appended past the blocking sink")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(sink.release)
+	r.NoError(<-appended)
+	r.NoError(ct.ResetHeight(2))
+	remove()
+	close(full.release)
+	removeFull()
+
+	r.Equal([]int32{1, 2, 3}, sink.heights)
+	r.Equal([]int32{2}, sink.resets)
+	r.Len(sink.changes, 3)
+	r.Equal(change.AddClaim, sink.changes[0].Type)
+	r.Equal(node.Activated, sink.changes[0].Status)
+	r.Equal(change.AddSupport, sink.changes[1].Type)
+	r.Equal(o2, sink.changes[1].OutPoint.Wire())
+	r.Equal(node.Activated, sink.changes[1].Status)
+	r.Equal(change.SpendSupport, sink.changes[2].Type)
+	r.Equal(node.Deactivated, sink.changes[2].Status)
+
+	r.Less(len(full.heights)+len(full.resets), 4)
+	r.Greater(ct.Stats().SinkDrops, int64(0))
+
+	// The sinks removed don't receive the blocks anymore.
+	r.NoError(ct.AppendBlock())
+	r.Equal([]int32{1, 2, 3}, sink.heights)
+}
+
+func TestHistoryLock(t *testing.T) {
+
+	r := require.New(t)
+
+	var l historyLock
+	r.NoError(l.RLock(context.Background(), 0))
+
+	// The writer waits for the reader holding the lock, while the readers coming after wait for the writer.
+	locked := make(chan struct{})
+	go func() {
+		l.Lock()
+		close(locked)
+	}()
+	r.Eventually(func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.writing != nil
+	}, time.Second, time.Millisecond)
+	r.ErrorIs(l.RLock(context.Background(), 10*time.Millisecond), ErrHistoryBusy)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.ErrorIs(l.RLock(ctx, 0), context.Canceled)
+	select {
+	case <-locked:
+		r.Fail("the writer didn't wait for the reader")
+	default:
+	}
+
+	l.RUnlock()
+	<-locked
+	read := make(chan error)
+	go func() { read <- l.RLock(context.Background(), time.Minute) }()
+	l.Unlock()
+	r.NoError(<-read)
+	l.RUnlock()
+}
+
+func TestResolveDuringReset(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim(b("a"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AddClaim(b("a"), o2, node.NewClaimID(o2), 20, nil))
+	r.NoError(ct.AppendBlock())
+
+	// The queries started before the reset see the state before it, which waits for them.
+	unlock, err := ct.readHistory(context.Background())
+	r.NoError(err)
+	reset := make(chan error)
+	go func() { reset <- ct.ResetHeight(1) }()
+	r.Eventually(func() bool {
+		ct.history.mu.Lock()
+		defer ct.history.mu.Unlock()
+		return ct.history.writing != nil
+	}, time.Second, time.Millisecond)
+	r.Equal(int32(2), ct.Snapshot().Height())
+	unlock()
+	r.NoError(<-reset)
+
+	// The ones coming during the reset give up after the wait.
+	ct.historyReadWait = 10 * time.Millisecond
+	ct.history.Lock()
+	_, err = ct.ResolveAt(b("a"), 1)
+	r.ErrorIs(err, ErrHistoryBusy)
+	_, err = ct.Snapshot().Resolve(b("a"))
+	r.ErrorIs(err, ErrHistoryBusy)
+	ct.history.Unlock()
+
+	res, err := ct.ResolveAt(b("a"), 1)
+	r.NoError(err)
+	r.Len(res.Node.Claims, 1)
+	_, err = ct.ResolveAt(b("a"), 2)
+	r.ErrorIs(err, ErrNotRetained)
+}
+
+func TestMemoryBudget(t *testing.T) {
+
+	r := require.New(t)
+
+	roots := func(budget int64) []string {
+		setup(t)
+		cfg.MemoryBudget = budget
+		defer func() { cfg.MemoryBudget = 0 }()
+
+		ct, err := New(cfg)
+		r.NoError(err)
+		defer func() {
+			err = ct.Close()
+			r.NoError(err)
+		}()
+
+		var roots []string
+		for i := 0; i < 20; i++ {
+			hash := chainhash.HashH([]byte{byte(i)})
+			for j := 0; j < 10; j++ {
+				op := wire.OutPoint{Hash: hash, Index: uint32(j)}
+				name := []byte(fmt.Sprintf("name-%d", (i*7+j)%30))
+				err = ct.AddClaim(name, op, node.NewClaimID(op), int64(i+j), nil)
+				r.NoError(err)
+			}
+			err = ct.AppendBlock()
+			r.NoError(err)
+			roots = append(roots, ct.MerkleHash().String())
+			if budget > 0 {
+				r.LessOrEqual(ct.nodeManager.CacheSize()+ct.merkleTrie.CacheSize(), budget)
+			}
+		}
+		return roots
+	}
+
+	r.Equal(roots(0), roots(1))
+}
+
+func TestTransaction(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+
+	tx := ct.Begin(1)
+	r.NoError(tx.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil))
+	tx.Abort()
+	r.ErrorIs(tx.AddClaim([]byte("test"), o2, node.NewClaimID(o2), 10, nil), ErrTransactionDone)
+	r.ErrorIs(tx.Commit(), ErrTransactionDone)
+
+	n, err := ct.Node([]byte("test"))
+	r.NoError(err)
+	r.Nil(n)
+	r.Empty(ct.changes)
+
+	tx = ct.Begin(1)
+	r.NoError(tx.AddClaim([]byte("test"), o2, node.NewClaimID(o2), 10, nil))
+	r.NoError(tx.Commit())
+	r.NoError(ct.AppendBlock())
+
+	n, err = ct.Node([]byte("test"))
+	r.NoError(err)
+	r.Equal(node.NewClaimID(o2), n.BestClaim.ClaimID)
+
+	// A transaction of a block other than the next one.
+	tx = ct.Begin(1)
+	r.NoError(tx.SpendClaim([]byte("test"), o2, node.NewClaimID(o2)))
+	r.Error(tx.Commit())
+}
+
+func TestRejectedTransaction(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	strict := cfg
+	strict.StrictConflicts = true
+	strict.OutPointIndex = true
+	ct, err := New(strict)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AppendBlock())
+	root := *ct.MerkleHash()
+
+	// The claim added ahead of the duplicate outpoint is discarded along with it.
+	tx := ct.Begin(2)
+	r.NoError(tx.AddClaim([]byte("new"), o2, node.NewClaimID(o2), 10, nil))
+	r.NoError(tx.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil))
+	r.ErrorIs(tx.Commit(), node.ErrDuplicateOutPoint)
+	r.Empty(ct.changes)
+	r.Empty(ct.pendingOutPoints)
+
+	// A competing block at the same height holds none of its changes.
+	r.NoError(ct.AppendBlock())
+	r.Equal(root, *ct.MerkleHash())
+	n, err := ct.Node([]byte("new"))
+	r.NoError(err)
+	r.Nil(n)
+
+	// The changes discarded can be appended again.
+	tx = ct.Begin(3)
+	r.NoError(tx.AddClaim([]byte("new"), o2, node.NewClaimID(o2), 10, nil))
+	r.NoError(tx.Commit())
+	r.NoError(ct.AppendBlock())
+	n, err = ct.Node([]byte("new"))
+	r.NoError(err)
+	r.Equal(node.NewClaimID(o2), n.BestClaim.ClaimID)
+}
+
+func TestNameActivity(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.NameActivity = true
+	defer func() { cfg.NameActivity = false }()
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	err = ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil)
+	r.NoError(err)
+	r.NoError(ct.AppendBlock())
+	err = ct.AddClaim([]byte("tester"), o2, node.NewClaimID(o2), 10, nil)
+	r.NoError(err)
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AppendBlock())
+	err = ct.AddSupport([]byte("test"), nil, o3, 10, node.NewClaimID(o1))
+	r.NoError(err)
+	r.NoError(ct.AppendBlock())
+
+	names, err := ct.NamesCreatedBetween(2, 4)
+	r.NoError(err)
+	r.Equal([][]byte{[]byte("tester")}, names)
+	names, err = ct.InactiveSince(3)
+	r.NoError(err)
+	r.Equal([][]byte{[]byte("tester")}, names)
+
+	r.NoError(ct.ResetHeight(3))
+	names, err = ct.InactiveSince(3)
+	r.NoError(err)
+	r.Equal([][]byte{[]byte("test"), []byte("tester")}, names)
+}
+
+func TestNameLifecycles(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.NameActivity = true
+	defer func() { cfg.NameActivity = false }()
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AddClaim(b("other"), o3, node.NewClaimID(o3), 10, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.SpendClaim(b("test"), o1, node.NewClaimID(o1)))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AddClaim(b("test"), o2, node.NewClaimID(o2), 10, nil))
+	r.NoError(ct.AppendBlock())
+
+	lifecycles, err := ct.NameLifecycles(b("test"))
+	r.NoError(err)
+	r.Equal([]NameLifecycle{{ClaimedAt: 1, AbandonedAt: 2}, {ClaimedAt: 4}}, lifecycles)
+	counts, err := ct.NameLifecycleCounts()
+	r.NoError(err)
+	r.Equal(int64(1), counts.Abandonments)
+	r.Equal(int64(1), counts.Reclaims)
+	r.Zero(counts.Unclaimed)
+
+	r.NoError(ct.ResetHeight(3))
+	lifecycles, err = ct.NameLifecycles(b("test"))
+	r.NoError(err)
+	r.Equal([]NameLifecycle{{ClaimedAt: 1, AbandonedAt: 2}}, lifecycles)
+	counts, err = ct.NameLifecycleCounts()
+	r.NoError(err)
+	r.Equal(int64(1), counts.Unclaimed)
+}
+
+func TestValueHashes(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.SpendClaim(b("test"), o1, node.NewClaimID(o1)))
+	r.NoError(ct.AppendBlock())
+	_, err = ct.ValueHashAt(b("test"), 1)
+	r.ErrorIs(err, ErrValueHashesNotIndexed)
+	r.NoError(ct.Close())
+
+	// The blocks appended before are backfilled, and the ones after are recorded as they're appended.
+	cfg.ValueHashIndex = true
+	defer func() { cfg.ValueHashIndex = false }()
+	ct, err = New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+	recorded, err := ct.BackfillValueHashes(1, 3)
+	r.NoError(err)
+	r.Equal(2, recorded)
+	r.NoError(ct.AddClaim(b("test"), o2, node.NewClaimID(o2), 10, nil))
+	r.NoError(ct.AppendBlock())
+
+	h1 := node.CalculateNodeHash(o1, 1)
+	h2 := node.CalculateNodeHash(o2, 4)
+	history, err := ct.ValueHashHistory(b("test"))
+	r.NoError(err)
+	r.Equal([]valuehash.Entry{{Height: 1, Hash: h1}, {Height: 3}, {Height: 4, Hash: h2}}, history)
+	h, err := ct.ValueHashAt(b("test"), 2)
+	r.NoError(err)
+	r.Equal(h1, h)
+	h, err = ct.ValueHashAt(b("test"), 4)
+	r.NoError(err)
+	r.Equal(h2, h)
+
+	r.NoError(ct.ResetHeight(3))
+	h, err = ct.ValueHashAt(b("test"), 4)
+	r.NoError(err)
+	r.Nil(h)
+}
+
+func TestPredictTakeovers(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	err = ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil)
+	r.NoError(err)
+	for ct.Height() < 99 {
+		r.NoError(ct.AppendBlock())
+	}
+
+	err = ct.AddClaim([]byte("test"), o2, node.NewClaimID(o2), 20, nil)
+	r.NoError(err)
+	r.NoError(ct.AppendBlock())
+
+	// Delayed by (100 - 1) / 32 blocks.
+	predictions, err := ct.PredictTakeovers(2)
+	r.NoError(err)
+	r.Empty(predictions)
+	predictions, err = ct.PredictTakeovers(10)
+	r.NoError(err)
+	r.Len(predictions, 1)
+	r.Equal([]byte("test"), predictions[0].Name)
+	r.Equal(int32(103), predictions[0].Height)
+	r.Equal(node.NewClaimID(o1), predictions[0].Previous.ClaimID)
+	r.Equal(node.NewClaimID(o2), predictions[0].Winner.ClaimID)
+
+	for ct.Height() < 103 {
+		n, err := ct.Node([]byte("test"))
+		r.NoError(err)
+		r.Equal(node.NewClaimID(o1), n.BestClaim.ClaimID)
+		r.NoError(ct.AppendBlock())
+	}
+	n, err := ct.Node([]byte("test"))
+	r.NoError(err)
+	r.Equal(node.NewClaimID(o2), n.BestClaim.ClaimID)
+	r.Equal(int32(103), n.TakenOverAt)
+
+	predictions, err = ct.PredictTakeovers(10)
+	r.NoError(err)
+	r.Empty(predictions)
+}
+
+func TestDiffNode(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	err = ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil)
+	r.NoError(err)
+	r.NoError(ct.AppendBlock())
+
+	err = ct.AddClaim([]byte("test"), o2, node.NewClaimID(o2), 12, nil)
+	r.NoError(err)
+	err = ct.AddSupport([]byte("test"), nil, o3, 5, node.NewClaimID(o1))
+	r.NoError(err)
+	r.NoError(ct.AppendBlock())
+
+	err = ct.SpendClaim([]byte("test"), o1, node.NewClaimID(o1))
+	r.NoError(err)
+	r.NoError(ct.AppendBlock())
+
+	diff, err := ct.DiffNode([]byte("test"), 1, 2)
+	r.NoError(err)
+	r.Len(diff.AddedClaims, 1)
+	r.Equal(node.NewClaimID(o2), diff.AddedClaims[0].ClaimID)
+	r.Len(diff.AddedSupports, 1)
+	r.Equal(o3, diff.AddedSupports[0].OutPoint)
+	r.Empty(diff.RemovedClaims)
+	r.Nil(diff.Takeover)
+
+	diff, err = ct.DiffNode([]byte("test"), 1, 3)
+	r.NoError(err)
+	r.Len(diff.RemovedClaims, 1)
+	r.Equal(node.NewClaimID(o1), diff.RemovedClaims[0].ClaimID)
+	r.NotNil(diff.Takeover)
+	r.Equal(node.NewClaimID(o1), diff.Takeover.Previous.ClaimID)
+	r.Equal(node.NewClaimID(o2), diff.Takeover.Winner.ClaimID)
+	r.Equal(int32(3), diff.Takeover.TakenOverAt)
+
+	diff, err = ct.DiffNode([]byte("test"), 0, 1)
+	r.NoError(err)
+	r.Len(diff.AddedClaims, 1)
+	r.NotNil(diff.Takeover)
+	r.Nil(diff.Takeover.Previous)
+
+	_, err = ct.DiffNode([]byte("test"), 1, 4)
+	r.Error(err)
+}
+
+type countingCompacter struct{ compactions int32 }
+
+func (c *countingCompacter) Compact() error {
+	atomic.AddInt32(&c.compactions, 1)
+	return nil
+}
+
+func TestCompaction(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.CompactionThreshold = 2
+	cfg.CompactionWindows, _ = config.ParseTimeWindows("23:00-01:00")
+	defer func() {
+		cfg.CompactionThreshold = 0
+		cfg.CompactionWindows = nil
+	}()
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	repo := &countingCompacter{}
+	ct.compaction.repos = map[string]compacter{"test": repo}
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.Local)
+	ct.compaction.now = func() time.Time { return now }
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	for i := uint32(0); i < 3; i++ {
+		o := wire.OutPoint{Hash: hash, Index: i}
+		r.NoError(ct.AddClaim([]byte("test"), o, node.NewClaimID(o), 10, nil))
+	}
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.compaction.wait())
+	r.Equal(int32(0), atomic.LoadInt32(&repo.compactions)) // outside of the window
+
+	now = time.Date(2021, 6, 1, 0, 30, 0, 0, time.Local)
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.compaction.wait())
+	r.Equal(int32(1), atomic.LoadInt32(&repo.compactions))
+
+	o := wire.OutPoint{Hash: hash, Index: 3}
+	r.NoError(ct.AddClaim([]byte("test"), o, node.NewClaimID(o), 10, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.compaction.wait())
+	r.Equal(int32(1), atomic.LoadInt32(&repo.compactions)) // below the threshold
+
+	r.NoError(ct.ResetHeight(1))
+	r.NoError(ct.compaction.wait())
+	r.Equal(int32(2), atomic.LoadInt32(&repo.compactions))
+}
+
+func TestConsistencyCheck(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	ct.SetConsistencyCheck(true)
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	err = ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil)
+	r.NoError(err)
+	for i := 0; i < 3; i++ {
+		r.NoError(ct.AppendBlock())
+	}
+	r.NoError(ct.Flush())
+
+	stats := ct.Stats()
+	r.Equal(int32(3), stats.Height)
+	r.Equal(ct.MerkleHash().String(), stats.Root)
+	r.True(stats.ConsistencyCheck)
+	r.Zero(stats.Inconsistencies)
+	r.Positive(stats.NodeCacheBytes)
+
+	// Corrupt the cached node.
+	n, err := ct.nodeManager.Node([]byte("test"))
+	r.NoError(err)
+	n.TakenOverAt = 0
+	r.NoError(ct.checkConsistency([][]byte{[]byte("test")}))
+	r.Equal(int64(1), ct.Stats().Inconsistencies)
+
+	ct.SetConsistencyCheck(false)
+	r.False(ct.Stats().ConsistencyCheck)
+}
+
+func TestValueSizeLimit(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	param.MaxClaimValueSizeForkHeight = 3
+	param.MaxClaimValueSize = 4
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	r.NoError(ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, []byte("before the fork")))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AppendBlock())
+
+	err = ct.AddClaim([]byte("test"), o2, node.NewClaimID(o2), 10, []byte("after the fork"))
+	r.ErrorIs(err, ErrValueTooLarge)
+	var chgErr *change.Error
+	r.ErrorAs(err, &chgErr)
+	r.Equal([]byte("test"), chgErr.Name)
+	r.Equal(int32(3), chgErr.Height)
+	r.Equal(node.NewClaimID(o2), chgErr.ClaimID)
+	err = ct.AddSupport([]byte("test"), []byte("after the fork"), o3, 10, node.NewClaimID(o1))
+	r.ErrorIs(err, ErrValueTooLarge)
+
+	tx := ct.Begin(3)
+	err = tx.UpdateClaim([]byte("test"), o2, 10, node.NewClaimID(o1), []byte("after the fork"))
+	r.ErrorIs(err, ErrValueTooLarge)
+	r.ErrorAs(err, &chgErr)
+	r.Equal(int32(3), chgErr.Height)
+	r.NoError(tx.AddClaim([]byte("test"), o2, node.NewClaimID(o2), 10, []byte("fits")))
+	r.NoError(tx.Commit())
+	r.NoError(ct.AppendBlock())
+
+	n, err := ct.Node([]byte("test"))
+	r.NoError(err)
+	r.Len(n.Claims, 2)
+	r.Empty(n.Supports)
+}
+
+func TestNameProofs(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AddClaim(b("tester"), o2, node.NewClaimID(o2), 10, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AddClaim(b("other"), o3, node.NewClaimID(o3), 10, nil))
+	r.NoError(ct.AppendBlock())
+
+	bundle, err := ct.NameProofs(b("test"), 5)
+	r.NoError(err)
+	r.Len(bundle.Proofs, 3)
+	for i, p := range bundle.Proofs {
+		r.Equal(ct.height-int32(i), p.Height)
+		root, err := ct.blockRepo.Get(p.Height)
+		r.NoError(err)
+		height, ok := bundle.Verify(root)
+		r.True(ok)
+		r.Equal(p.Height, height)
+	}
+
+	bundle, err = ct.NameProofs(b("tester"), 5)
+	r.NoError(err)
+	r.Len(bundle.Proofs, 2)
+
+	_, err = ct.NameProofs(b("other"), 0)
+	r.ErrorIs(err, ErrNoBestClaim)
+}
+
+func TestProveAt(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AddClaim(b("tester"), o2, node.NewClaimID(o2), 10, nil))
+	r.NoError(ct.AppendBlock())
+
+	// The proofs at the previous heights verify against their roots.
+	for h := int32(1); h <= ct.height; h++ {
+		res, p, err := ct.ProveAtContext(context.Background(), b("test"), h)
+		r.NoError(err)
+		r.Equal(o1, p.OutPoint)
+		r.True(p.Verify(&res.Root, b("test")))
+		root, err := ct.blockRepo.Get(h)
+		r.NoError(err)
+		r.Equal(*root, res.Root)
+	}
+	res, p, err := ct.ProveAtContext(context.Background(), b("tester"), 2)
+	r.NoError(err)
+	r.True(p.Verify(&res.Root, b("tester")))
+
+	_, _, err = ct.ProveAtContext(context.Background(), b("tester"), 1)
+	r.ErrorIs(err, ErrNameNotFound)
+}
+
+func TestAbsenceProofs(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AddSupport(b("tester"), nil, o2, 10, node.NewClaimID(o1)))
+	r.NoError(ct.AppendBlock())
+
+	// A name without claims or supports isn't found, while one with only a support has no best claim.
+	_, err = ct.NameProof(b("other"))
+	r.ErrorIs(err, ErrNameNotFound)
+	_, err = ct.NameProof(b("tester"))
+	r.ErrorIs(err, ErrNoBestClaim)
+	_, err = ct.ProveMany([][]byte{b("test"), b("other")})
+	r.ErrorIs(err, ErrNameNotFound)
+
+	res, err := ct.ResolveAt(b("tester"), ct.Height())
+	r.NoError(err)
+	r.Nil(res.Node.BestClaim)
+
+	for _, name := range []string{"other", "tester", "tes"} {
+		p, err := ct.AbsenceProof(b(name))
+		r.NoError(err, name)
+		r.True(p.VerifyAbsence(ct.MerkleHash(), b(name)), name)
+	}
+	_, err = ct.AbsenceProof(b("test"))
+	r.Error(err)
+
+	// As of the snapshots, too, once the name is claimed.
+	s := ct.Snapshot()
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	r.NoError(ct.AddClaim(b("other"), o3, node.NewClaimID(o3), 10, nil))
+	r.NoError(ct.AppendBlock())
+	_, err = ct.AbsenceProof(b("other"))
+	r.Error(err)
+	p, err := s.ProveAbsence(b("other"))
+	r.NoError(err)
+	root := s.Root()
+	r.True(p.VerifyAbsence(&root, b("other")))
+	r.False(p.VerifyAbsence(ct.MerkleHash(), b("other")))
+}
+
+func TestMaxReorgDepth(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.MaxReorgDepth = 2
+	defer func() { cfg.MaxReorgDepth = 0 }()
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	for i := uint32(1); i <= 4; i++ {
+		o := wire.OutPoint{Hash: hash, Index: i}
+		r.NoError(ct.AddClaim([]byte(fmt.Sprintf("test%d", i)), o, node.NewClaimID(o), 10, nil))
+		r.NoError(ct.AppendBlock())
+	}
+
+	// The names updated at the heights only needed for deeper resets are pruned.
+	for h, pruned := range map[int32]bool{1: true, 2: true, 3: false, 4: false} {
+		names, err := ct.temporalRepo.NodesAt(h)
+		r.NoError(err)
+		r.Equal(pruned, len(names) == 0, "height %d", h)
+	}
+
+	err = ct.ResetHeight(1)
+	r.ErrorIs(err, ErrReorgTooDeep)
+	r.Equal(int32(4), ct.Height())
+
+	r.NoError(ct.ResetHeight(2))
+	n, err := ct.Node([]byte("test3"))
+	r.NoError(err)
+	r.Nil(n)
+	root, err := ct.blockRepo.Get(2)
+	r.NoError(err)
+	r.Equal(root, ct.MerkleHash())
+}
+
+func TestCrossValidateTrie(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.CrossValidateTrie = true
+	defer func() { cfg.CrossValidateTrie = false }()
+	ct, err := New(cfg)
+	r.NoError(err)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	for i := uint32(1); i <= 6; i++ {
+		o := wire.OutPoint{Hash: hash, Index: i}
+		r.NoError(ct.AddClaim([]byte(fmt.Sprintf("test%d", i%3)), o, node.NewClaimID(o), int64(i), nil))
+		if i%2 == 0 {
+			r.NoError(ct.SpendClaim([]byte(fmt.Sprintf("test%d", i%3)), o, node.NewClaimID(o)))
+		}
+		r.NoError(ct.AppendBlock())
+	}
+	r.NoError(ct.Close())
+
+	// The RamTrie is populated with the names of the nodes on reopening.
+	ct, err = New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+	o := wire.OutPoint{Hash: hash, Index: 7}
+	r.NoError(ct.AddClaim([]byte("tes"), o, node.NewClaimID(o), 7, nil))
+	r.NoError(ct.AppendBlock())
+
+	r.NoError(ct.ResetHeight(3))
+	o = wire.OutPoint{Hash: hash, Index: 8}
+	r.NoError(ct.AddClaim([]byte("test"), o, node.NewClaimID(o), 8, nil))
+	r.NoError(ct.AppendBlock())
+
+	// A RamTrie missing the names diverges.
+	ct.ramTrie = merkletrie.NewRamTrie(ct.nodeManager)
+	o = wire.OutPoint{Hash: hash, Index: 9}
+	r.NoError(ct.AddClaim([]byte("other"), o, node.NewClaimID(o), 9, nil))
+	err = ct.AppendBlock()
+	r.ErrorIs(err, ErrTrieDivergence)
+}
+
+func TestScheduledTakeoverAfterRestart(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil))
+	for ct.Height() < 99 {
+		r.NoError(ct.AppendBlock())
+	}
+	r.NoError(ct.AddClaim([]byte("test"), o2, node.NewClaimID(o2), 20, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.Close())
+
+	// The activation at 103 is read back from the temporal repo, and updates the trie on its own.
+	ct, err = New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+	root := ct.MerkleHash()
+	for ct.Height() < 102 {
+		r.NoError(ct.AppendBlock())
+		r.Equal(root, ct.MerkleHash())
+	}
+	r.NoError(ct.AppendBlock())
+	r.NotEqual(root, ct.MerkleHash())
+
+	n, err := ct.Node([]byte("test"))
+	r.NoError(err)
+	r.Equal(node.NewClaimID(o2), n.BestClaim.ClaimID)
+}
+
+func TestBatching(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ref, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ref.Close()
+		r.NoError(err)
+	}()
+
+	setup(t)
+	cfg.BatchBlocks = 10
+	defer func() { cfg.BatchBlocks = 0 }()
+	ct, err := New(cfg)
+	r.NoError(err)
+	r.NoError(ct.SetBatching(true))
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	for i := 0; i < 25; i++ {
+		op := wire.OutPoint{Hash: hash, Index: uint32(i)}
+		name := []byte(fmt.Sprintf("test%d", i%7))
+		r.NoError(ref.AddClaim(name, op, node.NewClaimID(op), int64(i+1), nil))
+		r.NoError(ct.AddClaim(name, op, node.NewClaimID(op), int64(i+1), nil))
+		r.NoError(ref.AppendBlock())
+		r.NoError(ct.AppendBlock())
+		r.Equal(ref.MerkleHash(), ct.MerkleHash())
+	}
+	r.Equal(int32(5), ct.batched)
+	r.NotZero(ct.batchSize())
+
+	// The batch is committed on close.
+	root := ct.MerkleHash()
+	r.NoError(ct.Close())
+	ct, err = New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+	r.Equal(int32(25), ct.Height())
+	r.Equal(root, ct.MerkleHash())
+
+	// It's committed when disabled, too.
+	r.NoError(ct.SetBatching(true))
+	r.NoError(ct.AppendBlock())
+	r.NotZero(ct.batchSize())
+	r.NoError(ct.SetBatching(false))
+	r.Zero(ct.batchSize())
+	r.Zero(ct.batched)
+}
+
+func TestTrieCheckpointDeltas(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.TrieCheckpointInterval = 2
+	cfg.TrieCheckpointDeltas = 2
+	defer func() { cfg.TrieCheckpointInterval, cfg.TrieCheckpointDeltas = 0, 0 }()
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	for i := uint32(1); i <= 10; i++ {
+		o := wire.OutPoint{Hash: hash, Index: i}
+		r.NoError(ct.AddClaim([]byte(fmt.Sprintf("test%d", i)), o, node.NewClaimID(o), 10, nil))
+		r.NoError(ct.AppendBlock())
+	}
+
+	dir := filepath.Join(cfg.DataDir, cfg.TrieCheckpointPath)
+	last, err := merkletrierepo.LastCheckpoint(dir)
+	r.NoError(err)
+	r.Equal(int32(8), last)
+	for _, delta := range [][2]int32{{2, 4}, {2, 6}, {8, 10}} {
+		r.FileExists(merkletrierepo.DeltaName(dir, delta[0], delta[1]))
+	}
+	r.NoFileExists(merkletrierepo.DeltaName(dir, 2, 8))
+}
+
+func TestListNames(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	all := []string{"a", "ab", "abc", "b", "ba", "c"}
+	for i, name := range all {
+		o := wire.OutPoint{Hash: hash, Index: uint32(i)}
+		r.NoError(ct.AddClaim([]byte(name), o, node.NewClaimID(o), 10, nil))
+	}
+	r.NoError(ct.AppendBlock())
+
+	names, nodes, cursor, err := ct.ListNames(nil, 4)
+	r.NoError(err)
+	r.Equal([][]byte{b("a"), b("ab"), b("abc"), b("b")}, names)
+	r.Len(nodes, 4)
+	r.NotNil(nodes[0].BestClaim)
+
+	// The names added and spent since don't affect the next pages.
+	o := wire.OutPoint{Hash: hash, Index: 100}
+	r.NoError(ct.AddClaim(b("bb"), o, node.NewClaimID(o), 10, nil))
+	o = wire.OutPoint{Hash: hash, Index: 5}
+	r.NoError(ct.SpendClaim(b("c"), o, node.NewClaimID(o)))
+	r.NoError(ct.AppendBlock())
+
+	cursor, err = ParseNamesCursor(cursor.String())
+	r.NoError(err)
+	names, nodes, next, err := ct.ListNames(cursor, 4)
+	r.NoError(err)
+	r.Equal([][]byte{b("ba"), b("c")}, names)
+	r.NotNil(nodes[1].BestClaim)
+	r.Nil(next)
+
+	names, _, _, err = ct.ListNames(nil, 10)
+	r.NoError(err)
+	r.Equal([][]byte{b("a"), b("ab"), b("abc"), b("b"), b("ba"), b("bb")}, names)
+
+	// The cursors of the blocks reorganized out of the chain are stale.
+	r.NoError(ct.ResetHeight(1))
+	r.NoError(ct.AppendBlock())
+	_, _, _, err = ct.ListNames(&NamesCursor{Height: 2, Root: *ct.MerkleHash()}, 4)
+	r.NoError(err)
+	o = wire.OutPoint{Hash: hash, Index: 101}
+	r.NoError(ct.AddClaim(b("d"), o, node.NewClaimID(o), 10, nil))
+	r.NoError(ct.AppendBlock())
+	_, _, _, err = ct.ListNames(&NamesCursor{Height: 3, Root: chainhash.Hash{1}}, 4)
+	r.ErrorIs(err, ErrStaleCursor)
+}
+
+func TestWithNameLock(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AppendBlock())
+
+	locked, release := make(chan struct{}), make(chan struct{})
+	held := make(chan error, 1)
+	go func() {
+		held <- ct.WithNameLock(b("test"), func(n *node.Node, height int32) error {
+			if n == nil || n.BestClaim.OutPoint != o1 || height != 1 {
+				return fmt.Errorf("unexpected node at %d: %v", height, n)
+			}
+			close(locked)
+			<-release
+			return nil
+		})
+	}()
+	<-locked
+
+	// The blocks not updating the name aren't held up.
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim(b("other"), o2, node.NewClaimID(o2), 10, nil))
+	r.NoError(ct.AppendBlock())
+
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	r.NoError(ct.AddSupport(b("test"), nil, o3, 10, node.NewClaimID(o1)))
+	appended := make(chan error, 1)
+	go func() { appended <- ct.AppendBlock() }()
+	select {
+	case <-appended:
+		r.Fail("the block updating the locked name was appended")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	r.NoError(<-held)
+	r.NoError(<-appended)
+
+	r.NoError(ct.WithNameLock(b("test"), func(n *node.Node, height int32) error {
+		r.Equal(int32(3), height)
+		r.Len(n.Supports, 1)
+		return nil
+	}))
+	r.NoError(ct.WithNameLock(b("missing"), func(n *node.Node, height int32) error {
+		r.Nil(n)
+		return nil
+	}))
+}
+
+func TestProfiles(t *testing.T) {
+
+	r := require.New(t)
+
+	// The profiles only tune the resources; the roots, before and after the fork of all the claims, are the same.
+	var roots []chainhash.Hash
+	for _, name := range []string{"", config.ProfileDesktop, config.ProfileServer, config.ProfileRaspberryPi} {
+		setup(t)
+		c := cfg
+		if name != "" {
+			r.NoError(c.ApplyProfile(name))
+		}
+		ct, err := New(c)
+		r.NoError(err)
+
+		hash := chainhash.HashH([]byte{4, 5, 6})
+		for i := uint32(1); i <= uint32(param.AllClaimsInMerkleForkHeight)+2; i++ {
+			if i%10 == 0 {
+				o := wire.OutPoint{Hash: hash, Index: i}
+				r.NoError(ct.AddClaim([]byte(fmt.Sprintf("test%d", i%7)), o, node.NewClaimID(o), int64(i), nil))
+			}
+			r.NoError(ct.AppendBlock())
+		}
+		roots = append(roots, *ct.MerkleHash())
+		r.NoError(ct.Close())
+	}
+	for _, root := range roots[1:] {
+		r.Equal(roots[0], root)
+	}
+
+	err := cfg.ApplyProfile("mainframe")
+	r.Error(err)
+	r.Contains(err.Error(), "raspberry-pi")
+}
+
+func TestRebuildCorruptTrieNode(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{7, 8, 9})
+	for i, name := range []string{"a", "ab", "abc", "b"} {
+		o := wire.OutPoint{Hash: hash, Index: uint32(i)}
+		r.NoError(ct.AddClaim([]byte(name), o, node.NewClaimID(o), int64(i+1), nil))
+	}
+	r.NoError(ct.AppendBlock())
+	root := *ct.MerkleHash()
+
+	// The trie is resolved from a repo, of which the node at "a" rots.
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	defer repo.Close()
+	trie := merkletrie.New(ct.nodeManager, repo)
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		trie.Update(name, false)
+		return true
+	})
+	r.Equal(root, *trie.MerkleHash())
+	r.NoError(trie.Commit())
+
+	in := faultyrepo.NewInjector().CorruptAt(2)
+	ct.merkleTrie = merkletrie.New(ct.nodeManager, faultyrepo.MerkleTrie(repo, in))
+	ct.merkleTrie.SetRoot(&root)
+
+	o := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.SpendClaim([]byte("abc"), o, node.NewClaimID(o)))
+	o = wire.OutPoint{Hash: hash, Index: 4}
+	r.NoError(ct.AddClaim([]byte("abcd"), o, node.NewClaimID(o), 5, nil))
+	r.NoError(ct.AppendBlock())
+
+	// The root matches the one of a trie rebuilt from scratch.
+	expected := merkletrie.New(ct.nodeManager, repo)
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		expected.Update(name, false)
+		return true
+	})
+	r.Equal(expected.MerkleHash().String(), ct.MerkleHash().String())
+	r.Empty(ct.merkleTrie.Corrupted())
+}
+
+func TestBudgets(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.Budgets = map[string]time.Duration{StageBlock: time.Nanosecond, StageHash: time.Hour}
+	defer func() { cfg.Budgets = nil }()
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	var exceeded []event.Event
+	unsubscribe := ct.Subscribe(func(e event.Event) {
+		if e.Type == event.BudgetExceeded {
+			exceeded = append(exceeded, e)
+		}
+	})
+	defer unsubscribe()
+
+	for i := 0; i < 2; i++ {
+		r.NoError(ct.AppendBlock())
+	}
+
+	r.Len(exceeded, 2)
+	r.Equal(StageBlock, exceeded[1].Stage)
+	r.Equal(int32(2), exceeded[1].Height)
+	r.Equal(time.Nanosecond, exceeded[1].Budget)
+	r.Greater(exceeded[1].Elapsed, exceeded[1].Budget)
+	r.Equal(map[string]int64{StageBlock: 2}, ct.Stats().Alerts)
+
+	cfg.Budgets = map[string]time.Duration{"mempool": time.Second}
+	_, err = New(cfg)
+	r.Error(err)
+}
+
+func TestTakeoverDiagnostics(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.TakeoverDiagnostics = true
+	cfg.TakeoverRecord = true
+	defer func() {
+		cfg.TakeoverDiagnostics = false
+		cfg.TakeoverRecord = false
+	}()
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	var takeovers []event.Event
+	unsubscribe := ct.Subscribe(func(e event.Event) {
+		if e.Type == event.Takeover {
+			takeovers = append(takeovers, e)
+		}
+	})
+	defer unsubscribe()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	err = ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil)
+	r.NoError(err)
+	for ct.Height() < 99 {
+		r.NoError(ct.AppendBlock())
+	}
+	err = ct.AddClaim([]byte("test"), o2, node.NewClaimID(o2), 20, nil)
+	r.NoError(err)
+	for ct.Height() < 103 {
+		r.NoError(ct.AppendBlock())
+	}
+
+	r.Len(takeovers, 2)
+	r.Len(takeovers[0].Contenders, 1)
+	r.Equal(int32(103), takeovers[1].Height)
+	r.Len(takeovers[1].Contenders, 2)
+	r.Equal(node.NewClaimID(o2).String(), takeovers[1].Contenders[0].ClaimID)
+	r.Equal(int64(20), takeovers[1].Contenders[0].EffectiveAmount)
+	r.Equal(int32(100), takeovers[1].Contenders[0].AcceptedAt)
+	r.Equal(node.NewClaimID(o1).String(), takeovers[1].Contenders[1].ClaimID)
+	r.Equal(int64(10), takeovers[1].Contenders[1].EffectiveAmount)
+
+	records, err := ct.Takeovers([]byte("test"))
+	r.NoError(err)
+	r.Len(records, 2)
+	r.Equal(int32(1), records[0].Height)
+	r.Equal(takeovers[1].Contenders, records[1].Contenders)
+
+	r.NoError(ct.ResetHeight(102))
+	records, err = ct.Takeovers([]byte("test"))
+	r.NoError(err)
+	r.Len(records, 1)
+}
+
+func TestFsck(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	report := ct.Fsck()
+	r.Equal(SeverityInfo, report.Worst())
+	r.Len(report.Findings, 1)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AddClaim(b("tester"), o2, node.NewClaimID(o2), 10, nil))
+	for ct.Height() < param.AllClaimsInMerkleForkHeight {
+		r.NoError(ct.AppendBlock())
+	}
+
+	report = ct.Fsck()
+	r.Empty(report.Findings)
+	r.Equal(int(ct.Height()), report.Blocks)
+	r.Equal(2, report.Names)
+	r.Equal(ct.MerkleHash(), report.Root)
+
+	// The nodes changed behind the back of the trie.
+	chg := change.New(change.SpendClaim).SetName(b("test")).SetOutPoint(change.NewOutPoint(o1)).SetHeight(ct.height + 1)
+	r.NoError(ct.nodeManager.AppendChange(chg))
+	chg = change.New(change.AddClaim).SetName(b("other")).SetOutPoint(change.NewOutPoint(o3)).
+		SetClaimID(node.NewClaimID(o3)).SetAmount(10).SetHeight(ct.height + 1)
+	r.NoError(ct.nodeManager.AppendChange(chg))
+	_, err = ct.nodeManager.IncrementHeightTo(ct.height + 1)
+	r.NoError(err)
+
+	report = ct.Fsck()
+	r.Equal(SeverityError, report.Worst())
+	r.Len(report.Findings, 2)
+	r.Equal(Finding{Severity: SeverityError, Repo: "trie", Key: `"test"`,
+		Problem: "the value hash differs from the one of the node", Repair: repairNode}, report.Findings[0])
+	r.Equal("node", report.Findings[1].Repo)
+	r.Equal(`"other"`, report.Findings[1].Key)
+}
+
+func TestChangeRetention(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	reference, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = reference.Close()
+		r.NoError(err)
+	}()
+
+	retained := cfg
+	retained.DataDir = t.TempDir()
+	retained.NodeManager = config.NodeManagerSnapshot
+	retained.ChangeRetention = config.RetainBlocks
+	retained.ChangeRetentionBlocks = 5
+	retained.PruneInterval = 10
+	ct, err := New(retained)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	append := func(ct *ClaimTrie, i uint32) {
+		o1 := wire.OutPoint{Hash: hash, Index: i}
+		o2 := wire.OutPoint{Hash: hash, Index: 100 + i}
+		switch i % 4 {
+		case 1:
+			r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), int64(i), nil))
+		case 2:
+			o := wire.OutPoint{Hash: hash, Index: 1}
+			r.NoError(ct.AddSupport(b("test"), nil, o2, int64(i), node.NewClaimID(o)))
+		case 3:
+			r.NoError(ct.AddClaim(b(fmt.Sprintf("test%d", i)), o1, node.NewClaimID(o1), 1, nil))
+		}
+		r.NoError(ct.AppendBlock())
+	}
+	for i := uint32(1); i <= 260; i++ {
+		append(reference, i)
+		append(ct, i)
+		r.Equal(reference.MerkleHash(), ct.MerkleHash(), "height %d", i)
+	}
+	r.Equal(int32(255), ct.prunedAt)
+
+	// The nodes are materialized from their base snapshots.
+	ct.nodeManager.(interface{ ShrinkCache(int64) int }).ShrinkCache(0)
+	n, err := ct.Node(b("test"))
+	r.NoError(err)
+	expected, err := reference.Node(b("test"))
+	r.NoError(err)
+	r.Equal(expected.BestClaim.ClaimID, n.BestClaim.ClaimID)
+	r.Equal(expected.BestClaim.EffectiveAmount(expected.Supports), n.BestClaim.EffectiveAmount(n.Supports))
+	_, err = ct.nodeManager.NodeAt(250, b("test"))
+	r.ErrorIs(err, node.ErrPruned)
+
+	err = ct.ResetHeight(254)
+	r.ErrorIs(err, ErrReorgTooDeep)
+	r.NoError(ct.ResetHeight(256))
+	r.NoError(reference.ResetHeight(256))
+	for i := uint32(257); i <= 262; i++ {
+		append(reference, i)
+		append(ct, i)
+		r.Equal(reference.MerkleHash(), ct.MerkleHash(), "height %d", i)
+	}
+
+	retained.NodeManager = config.NodeManagerReplay
+	_, err = New(retained)
+	r.Error(err)
+}
+
+func TestStateSnapshot(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	reference, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = reference.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	append := func(ct *ClaimTrie, i uint32) {
+		o1 := wire.OutPoint{Hash: hash, Index: i}
+		o2 := wire.OutPoint{Hash: hash, Index: 100 + i}
+		switch i % 5 {
+		case 1:
+			r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), int64(i), []byte("value")))
+		case 2:
+			o := wire.OutPoint{Hash: hash, Index: 1}
+			r.NoError(ct.AddSupport(b("test"), nil, o2, int64(i), node.NewClaimID(o)))
+		case 3:
+			r.NoError(ct.AddClaim(b(fmt.Sprintf("test%d", i)), o1, node.NewClaimID(o1), 1, nil))
+		case 4:
+			r.NoError(ct.AddSupport(b("unclaimed"), nil, o2, 1, node.NewClaimID(o1)))
+		case 0:
+			if i%10 == 0 {
+				o := wire.OutPoint{Hash: hash, Index: i - 2}
+				r.NoError(ct.SpendClaim(b(fmt.Sprintf("test%d", i-2)), o, node.NewClaimID(o)))
+			}
+		}
+		r.NoError(ct.AppendBlock())
+	}
+	for i := uint32(1); i <= 40; i++ {
+		append(reference, i)
+	}
+
+	var buf bytes.Buffer
+	h, err := reference.ExportState(context.Background(), &buf, 30)
+	r.NoError(err)
+	r.Equal(int32(30), h.Height)
+	r.Equal(5, h.Nodes) // test, unclaimed, test3, test13 and test23, while the other names claimed are spent
+	_, err = reference.ExportState(context.Background(), &buf, 41)
+	r.Error(err)
+
+	imported := cfg
+	imported.DataDir = t.TempDir()
+	_, err = ImportState(imported, bytes.NewReader(buf.Bytes()))
+	r.Error(err) // without the base snapshots
+	imported.NodeManager = config.NodeManagerSnapshot
+	imported.ChangeRetention = config.RetainNone
+	imported.PruneInterval = 10
+	i, err := ImportState(imported, bytes.NewReader(buf.Bytes()))
+	r.NoError(err)
+	r.Equal(h, i)
+	_, err = ImportState(imported, bytes.NewReader(buf.Bytes()))
+	r.Error(err) // into a ClaimTrie, which isn't empty
+
+	ct, err := New(imported)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+	r.Equal(int32(30), ct.Height())
+
+	r.NoError(reference.ResetHeight(30))
+	r.Equal(reference.MerkleHash(), ct.MerkleHash())
+	n, err := ct.Node(b("test"))
+	r.NoError(err)
+	expected, err := reference.Node(b("test"))
+	r.NoError(err)
+	r.Equal(expected.BestClaim.ClaimID, n.BestClaim.ClaimID)
+	r.Equal(expected.BestClaim.EffectiveAmount(expected.Supports), n.BestClaim.EffectiveAmount(n.Supports))
+	r.Equal([]byte("value"), n.BestClaim.Value)
+	n, err = ct.Node(b("unclaimed"))
+	r.NoError(err)
+	r.Len(n.Supports, 6)
+
+	for i := uint32(31); i <= 80; i++ {
+		append(reference, i)
+		append(ct, i)
+		r.Equal(reference.MerkleHash(), ct.MerkleHash(), "height %d", i)
+	}
+}
+
+func TestExpiredCompaction(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	reference, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = reference.Close()
+		r.NoError(err)
+	}()
+
+	compacted := cfg
+	compacted.DataDir = t.TempDir()
+	compacted.NodeManager = config.NodeManagerSnapshot
+	compacted.ExpiredCompactionBlocks = 50
+	compacted.PruneInterval = 100
+	ct, err := New(compacted)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	for i := int32(1); i <= 700; i++ {
+		for _, ct := range []*ClaimTrie{reference, ct} {
+			switch i {
+			case 1:
+				r.NoError(ct.AddClaim(b("expired"), o1, node.NewClaimID(o1), 10, nil))
+			case 260:
+				r.NoError(ct.AddClaim(b("spent"), o2, node.NewClaimID(o2), 10, nil))
+			case 300:
+				r.NoError(ct.SpendClaim(b("spent"), o2, node.NewClaimID(o2)))
+			case 500:
+				r.NoError(ct.AddClaim(b("kept"), o3, node.NewClaimID(o3), 10, nil))
+			}
+			r.NoError(ct.AppendBlock())
+		}
+		r.Equal(reference.MerkleHash(), ct.MerkleHash(), "height %d", i)
+	}
+	r.Equal(int32(650), ct.compactedAt)
+
+	// The names left empty are replaced with tombstones, before which they can't be resolved.
+	for name, height := range map[string]int32{"expired": 550, "spent": 350} {
+		changes, err := ct.flushers["node"].(node.Repo).LoadChanges(b(name))
+		r.NoError(err)
+		r.Empty(changes)
+		_, err = ct.ResolveAt(b(name), height-1)
+		r.ErrorIs(err, node.ErrPruned)
+		_, err = ct.ResolveAt(b(name), height)
+		r.ErrorIs(err, ErrNameNotFound)
+	}
+
+	// The other ones are still resolved from their changes.
+	res, err := ct.ResolveAt(b("kept"), 520)
+	r.NoError(err)
+	r.Equal(node.NewClaimID(o3), res.Node.BestClaim.ClaimID)
+
+	err = ct.ResetHeight(640)
+	r.ErrorIs(err, ErrReorgTooDeep)
+	r.NoError(ct.ResetHeight(660))
+	r.NoError(reference.ResetHeight(660))
+	for i := 661; i <= 720; i++ {
+		r.NoError(reference.AppendBlock())
+		r.NoError(ct.AppendBlock())
+		r.Equal(reference.MerkleHash(), ct.MerkleHash(), "height %d", i)
+	}
+
+	compacted.NodeManager = config.NodeManagerReplay
+	_, err = New(compacted)
+	r.Error(err)
+}
+
+func TestPruneTrie(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	pruned := cfg
+	pruned.TrieRetentionBlocks = 10
+	ct, err := New(pruned)
+	r.NoError(err)
+
+	repo := ct.trieCompacter.(*merkletrierepo.Pebble)
+	countKeys := func() int {
+		count := 0
+		r.NoError(repo.IterateKeys(func([]byte) bool {
+			count++
+			return true
+		}))
+		return count
+	}
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	for i := uint32(1); i <= 50; i++ {
+		o := wire.OutPoint{Hash: hash, Index: i}
+		r.NoError(ct.AddClaim(b(fmt.Sprintf("test%d", i%5)), o, node.NewClaimID(o), int64(i), nil))
+		r.NoError(ct.AppendBlock())
+	}
+	r.NoError(ct.merkleTrie.Commit())
+	before := countKeys()
+
+	res, err := ct.PruneTrie()
+	r.NoError(err)
+	r.Equal(11, res.Roots)
+	r.Equal(before-res.Kept, res.Deleted)
+	r.Equal(res.Kept, countKeys())
+	r.Equal(int32(41), ct.trieRetainedFrom)
+
+	// The roots retained are still proven, and checked, while the ones before can't be reset to.
+	for h := int32(41); h <= 50; h++ {
+		root, err := ct.blockRepo.Get(h)
+		r.NoError(err)
+		r.Empty(ct.merkleTrie.At(root).Check(false, func([]byte) {}).Missing, "height %d", h)
+	}
+	_, p, err := ct.ProveAtContext(context.Background(), b("test1"), 45)
+	r.NoError(err)
+	r.NotNil(p)
+	_, _, err = ct.ProveAtContext(context.Background(), b("test1"), 40)
+	r.ErrorIs(err, ErrNotRetained)
+
+	err = ct.ResetHeight(40)
+	r.ErrorIs(err, ErrReorgTooDeep)
+	r.NoError(ct.ResetHeight(42))
+	root, err := ct.blockRepo.Get(42)
+	r.NoError(err)
+	r.Equal(root, ct.MerkleHash())
+	for i := uint32(43); i <= 55; i++ {
+		o := wire.OutPoint{Hash: hash, Index: 100 + i}
+		r.NoError(ct.AddClaim(b(fmt.Sprintf("test%d", i%5)), o, node.NewClaimID(o), int64(i), nil))
+		r.NoError(ct.AppendBlock())
+	}
+	r.NoError(ct.Close())
+
+	// The retention is assumed the latest possible after a restart.
+	ct, err = New(pruned)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+	r.Equal(int32(46), ct.trieRetainedFrom)
+	res, err = ct.PruneTrie()
+	r.NoError(err)
+	r.Greater(res.Deleted, 0)
+	r.Equal(int32(46), ct.trieRetainedFrom)
+}
+
+func TestClaimHistory(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	recorded := cfg
+	recorded.ClaimHistory = true
+	ct, err := New(recorded)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	id1, id2 := node.NewClaimID(o1), node.NewClaimID(o2)
+	for i := int32(1); i <= 40; i++ {
+		switch i {
+		case 1:
+			r.NoError(ct.AddClaim(b("test"), o1, id1, 10, nil))
+		case 10:
+			r.NoError(ct.AddClaim(b("test"), o2, id2, 20, nil))
+		case 20:
+			r.NoError(ct.SpendClaim(b("test"), o2, id2))
+		case 30:
+			r.NoError(ct.SpendClaim(b("test"), o1, id1))
+		}
+		r.NoError(ct.AppendBlock())
+	}
+
+	entries, err := ct.ClaimHistory(b("test"))
+	r.NoError(err)
+	r.Equal([]claimhistory.Entry{
+		{Height: 1, ClaimID: id1, EffectiveAmount: 10},
+		{Height: 10, ClaimID: id2, EffectiveAmount: 20},
+		{Height: 20, ClaimID: id1, EffectiveAmount: 10},
+		{Height: 30, Vacated: true},
+	}, entries)
+
+	for height, id := range map[int32]node.ClaimID{1: id1, 9: id1, 10: id2, 19: id2, 25: id1} {
+		e, err := ct.ClaimHistoryAt(b("test"), height)
+		r.NoError(err)
+		r.Equal(id, e.ClaimID, "at %d", height)
+	}
+	for _, height := range []int32{0, 30, 40} {
+		e, err := ct.ClaimHistoryAt(b("test"), height)
+		r.NoError(err)
+		r.Nil(e, "at %d", height)
+	}
+
+	// The entries of the blocks reset are dropped.
+	r.NoError(ct.ResetHeight(25))
+	entries, err = ct.ClaimHistory(b("test"))
+	r.NoError(err)
+	r.Len(entries, 3)
+	e, err := ct.ClaimHistoryAt(b("test"), 30)
+	r.NoError(err)
+	r.Equal(id1, e.ClaimID)
+
+	entries, err = ct.ClaimHistory(b("other"))
+	r.NoError(err)
+	r.Empty(entries)
+}
+
+func TestValueCache(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	reference, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = reference.Close()
+		r.NoError(err)
+	}()
+
+	// The small cache evicts the values of the names, which are read through again.
+	var cts []*ClaimTrie
+	for _, size := range []int{100000, 3} {
+		cached := cfg
+		cached.DataDir = t.TempDir()
+		cached.ValueCacheSize = size
+		ct, err := New(cached)
+		r.NoError(err)
+		defer func() {
+			err = ct.Close()
+			r.NoError(err)
+		}()
+		cts = append(cts, ct)
+	}
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	append := func(ct *ClaimTrie, i uint32) {
+		o1 := wire.OutPoint{Hash: hash, Index: i}
+		o2 := wire.OutPoint{Hash: hash, Index: 1000 + i}
+		switch i % 4 {
+		case 1:
+			r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), int64(i), nil))
+		case 2:
+			o := wire.OutPoint{Hash: hash, Index: 1}
+			r.NoError(ct.AddSupport(b("test"), nil, o2, int64(i), node.NewClaimID(o)))
+		case 3:
+			r.NoError(ct.AddClaim(b(fmt.Sprintf("test%d", i%16)), o1, node.NewClaimID(o1), 1, nil))
+		}
+		r.NoError(ct.AppendBlock())
+	}
+	for i := uint32(1); i <= 360; i++ {
+		append(reference, i)
+		for _, ct := range cts {
+			append(ct, i)
+			r.Equal(reference.MerkleHash(), ct.MerkleHash(), "height %d", i)
+		}
+		if i == 300 {
+			// The values of the updated names are refreshed along with them.
+			r.Zero(cts[0].values.Misses())
+		}
+	}
+
+	r.NoError(reference.ResetHeight(340))
+	for _, ct := range cts {
+		r.NoError(ct.ResetHeight(340))
+		r.Equal(reference.MerkleHash(), ct.MerkleHash())
+	}
+	for i := uint32(341); i <= 360; i++ {
+		append(reference, i+2000)
+		for _, ct := range cts {
+			append(ct, i+2000)
+			r.Equal(reference.MerkleHash(), ct.MerkleHash(), "height %d", i)
+		}
+	}
+}
+
+func TestResolveAt(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim([]byte("Test"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AddClaim([]byte("Test"), o2, node.NewClaimID(o2), 20, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.SpendClaim([]byte("Test"), o2, node.NewClaimID(o2)))
+	roots := map[int32]chainhash.Hash{}
+	for ct.Height() < param.NormalizedNameForkHeight {
+		r.NoError(ct.AppendBlock())
+		roots[ct.Height()] = *ct.MerkleHash()
+	}
+
+	res, err := ct.ResolveAt([]byte("Test"), 1)
+	r.NoError(err)
+	r.Equal([]byte("Test"), res.Name)
+	r.Equal(node.NewClaimID(o1), res.Node.BestClaim.ClaimID)
+	r.Len(res.Node.Claims, 1)
+
+	res, err = ct.ResolveAt([]byte("Test"), 2)
+	r.NoError(err)
+	r.Len(res.Node.Claims, 2)
+
+	res, err = ct.ResolveAt([]byte("Test"), 3)
+	r.NoError(err)
+	r.Len(res.Node.Claims, 1)
+	r.Equal(roots[3], res.Root)
+
+	// The name is normalized as of the height.
+	res, err = ct.ResolveAt([]byte("Test"), param.NormalizedNameForkHeight)
+	r.NoError(err)
+	r.Equal([]byte("test"), res.Name)
+	r.Equal(node.NewClaimID(o1), res.Node.BestClaim.ClaimID)
+	r.Equal(roots[param.NormalizedNameForkHeight], res.Root)
+
+	_, err = ct.ResolveAt([]byte("other"), 3)
+	r.ErrorIs(err, ErrNameNotFound)
+
+	_, err = ct.ResolveAt([]byte("Test"), ct.Height()+1)
+	r.ErrorIs(err, ErrNotRetained)
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	// A claim is added to the name by each block, so that its state tells the height.
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	appendBlock := func() {
+		h := ct.Height() + 1
+		op := wire.OutPoint{Hash: hash, Index: uint32(h)}
+		r.NoError(ct.AddClaim([]byte("test"), op, node.NewClaimID(op), int64(h), nil))
+		r.NoError(ct.AppendBlock())
+	}
+	appendBlock()
+
+	done := make(chan struct{})
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			errs <- func() error {
+				for queries := 0; ; queries++ {
+					select {
+					case <-done:
+						if queries > 0 { // the readers may not be scheduled before the blocks are appended
+							return nil
+						}
+					default:
+					}
+
+					s := ct.Snapshot()
+					claims, err := s.ListClaims([]byte("test"))
+					if errors.Is(err, ErrStaleSnapshot) {
+						continue
+					}
+					if err != nil {
+						return err
+					}
+					if int32(len(claims)) != s.Height() {
+						return fmt.Errorf("%d claims at height %d", len(claims), s.Height())
+					}
+
+					p, err := s.Prove([]byte("test"))
+					if errors.Is(err, ErrStaleSnapshot) {
+						continue
+					}
+					if err != nil {
+						return err
+					}
+					root := s.Root()
+					if !p.Verify(&root, []byte("test")) {
+						return fmt.Errorf("proof at height %d doesn't verify", s.Height())
+					}
+
+					res, err := ct.ResolveAt([]byte("test"), s.Height())
+					if errors.Is(err, ErrNotRetained) {
+						continue // reset since
+					}
+					if err != nil {
+						return err
+					}
+					if int32(len(res.Node.Claims)) != s.Height() {
+						return fmt.Errorf("%d claims resolved at height %d", len(res.Node.Claims), s.Height())
+					}
+				}
+			}()
+		}()
+	}
+
+	for ct.Height() < 40 {
+		appendBlock()
+	}
+	r.NoError(ct.ResetHeight(20))
+	for ct.Height() < 60 {
+		appendBlock()
+	}
+	close(done)
+	for i := 0; i < cap(errs); i++ {
+		r.NoError(<-errs)
+	}
+
+	s := ct.Snapshot()
+	r.Equal(int32(60), s.Height())
+	r.Equal(*ct.MerkleHash(), s.Root())
+
+	// A snapshot of a block reset since is stale.
+	r.NoError(ct.ResetHeight(50))
+	_, err = s.Resolve([]byte("test"))
+	r.ErrorIs(err, ErrStaleSnapshot)
+}
+
+// TestIndexReaders ensures the lookups of the indexes run while the blocks are appended, and
+// are as of the last Snapshot, or the block after it.
+func TestIndexReaders(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.ClaimIDIndex, cfg.TopNames, cfg.NameSearch = true, true, true
+	defer func() { cfg.ClaimIDIndex, cfg.TopNames, cfg.NameSearch = false, false, false }()
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	// A claim is added to the name by each block, so that the claim of a height tells whether it's indexed.
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	id := func(h int32) node.ClaimID {
+		return node.NewClaimID(wire.OutPoint{Hash: hash, Index: uint32(h)})
+	}
+	appendBlock := func() {
+		h := ct.Height() + 1
+		op := wire.OutPoint{Hash: hash, Index: uint32(h)}
+		r.NoError(ct.AddClaim([]byte("test"), op, id(h), int64(h), nil))
+		r.NoError(ct.AppendBlock())
+	}
+	appendBlock()
+
+	done := make(chan struct{})
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			errs <- func() error {
+				for queries := 0; ; queries++ {
+					select {
+					case <-done:
+						if queries > 0 {
+							return nil
+						}
+					default:
+					}
+
+					before := ct.Snapshot().Height()
+					claims, err := ct.ClaimByID(id(before))
+					if err != nil {
+						return err
+					}
+					if len(claims) != 1 {
+						return fmt.Errorf("claim of %d not indexed", before)
+					}
+					claims, err = ct.ClaimByID(id(before + 2))
+					if err != nil {
+						return err
+					}
+					if len(claims) > 0 && ct.Snapshot().Height() < before+1 {
+						return fmt.Errorf("claim of %d indexed at %d", before+2, before)
+					}
+
+					top, err := ct.TopNames(1)
+					if err != nil {
+						return err
+					}
+					if len(top) != 1 || string(top[0].Name) != "test" || top[0].Amount < int64(before) {
+						return fmt.Errorf("top names at %d: %v", before, top)
+					}
+					names, err := ct.SearchNames("test", SearchOptions{Limit: 10})
+					if err != nil {
+						return err
+					}
+					if len(names) != 1 {
+						return fmt.Errorf("%d names found at %d", len(names), before)
+					}
+				}
+			}()
+		}()
+	}
+
+	for ct.Height() < 60 {
+		appendBlock()
+	}
+	close(done)
+	for i := 0; i < cap(errs); i++ {
+		r.NoError(<-errs)
+	}
+}
+
+func TestUpstreamVerification(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	for i := uint32(1); i <= 3; i++ {
+		op := wire.OutPoint{Hash: hash, Index: i}
+		r.NoError(ct.AddClaim([]byte("test"), op, node.NewClaimID(op), int64(i), nil))
+		r.NoError(ct.AppendBlock())
+	}
+	root2, err := ct.blockRepo.Get(2)
+	r.NoError(err)
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	r.NoError(err)
+	doc, err := upstream.Sign([]upstream.Checkpoint{
+		{Height: 2, Root: *root2},
+		{Height: 3, Root: chainhash.Hash{3}},
+		{Height: 4, Root: chainhash.Hash{4}},
+	}, key)
+	r.NoError(err)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write(doc) // nolint : errchk
+	}))
+	defer srv.Close()
+
+	source := &upstream.Source{URL: srv.URL, PubKey: key.PubKey(), Client: srv.Client()}
+	ct.upstream = upstream.NewVerifier(source, time.Hour)
+	defer ct.upstream.Close()
+	r.Eventually(func() bool { return len(ct.upstream.Unverified(5)) == 3 }, 10*time.Second, 10*time.Millisecond)
+
+	var diverged []int32
+	unsubscribe := ct.Subscribe(func(e event.Event) {
+		if e.Type == event.UpstreamDiverged {
+			diverged = append(diverged, e.Height)
+		}
+	})
+	defer unsubscribe()
+
+	// The checkpoints of the heights passed already are verified along with the next block.
+	r.NoError(ct.AppendBlock())
+	r.Equal([]int32{3, 4}, diverged)
+	r.Equal(int64(2), ct.Stats().UpstreamDivergences)
+	r.Equal(int32(4), ct.Stats().UpstreamVerified)
+	r.Empty(ct.upstream.Unverified(5))
+}
+
+func TestPreviewRoot(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	param.AllClaimsInMerkleForkHeight = 74 // after the claim added with a delay
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	o4 := wire.OutPoint{Hash: hash, Index: 4}
+	o5 := wire.OutPoint{Hash: hash, Index: 5}
+
+	// The roots previewed match the ones appended, including the blocks activating the claim added with a delay,
+	// and the one at the all-claims fork.
+	appendBlock := func(build func(tx *Transaction)) {
+		tx := ct.Begin(ct.height + 1)
+		if build != nil {
+			build(tx)
+		}
+		before, err := ct.Node(b("a"))
+		r.NoError(err)
+		preview, err := tx.PreviewRoot()
+		r.NoError(err)
+		after, err := ct.Node(b("a"))
+		r.NoError(err)
+		r.Equal(before, after)
+
+		r.NoError(tx.Commit())
+		r.NoError(ct.AppendBlock())
+		r.Equal(*ct.MerkleHash(), *preview, "height: %d", ct.height)
+	}
+
+	appendBlock(func(tx *Transaction) {
+		r.NoError(tx.AddClaim(b("a"), o1, node.NewClaimID(o1), 10, nil))
+		r.NoError(tx.AddClaim(b("b"), o2, node.NewClaimID(o2), 10, nil))
+		r.NoError(tx.AddClaim(b("c"), o5, node.NewClaimID(o5), 10, nil))
+	})
+	for i := 0; i < 70; i++ {
+		appendBlock(nil)
+	}
+	appendBlock(func(tx *Transaction) {
+		r.NoError(tx.AddClaim(b("a"), o3, node.NewClaimID(o3), 20, nil))
+		r.NoError(tx.SpendClaim(b("b"), o2, node.NewClaimID(o2)))
+	})
+	appendBlock(func(tx *Transaction) {
+		r.NoError(tx.AddSupport(b("a"), nil, o4, 15, node.NewClaimID(o1)))
+	})
+	for i := 0; i < 3; i++ {
+		appendBlock(nil)
+	}
+	n, err := ct.Node(b("a"))
+	r.NoError(err)
+	r.Equal(o1, n.BestClaim.OutPoint)
+
+	tx := ct.Begin(ct.height + 2)
+	_, err = tx.PreviewRoot()
+	r.Error(err)
+	tx.Abort()
+	_, err = tx.PreviewRoot()
+	r.ErrorIs(err, ErrTransactionDone)
+}
+
+func TestHealth(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	h := ct.Health()
+	r.True(h.LastBlockAt.IsZero())
+	r.Empty(h.RepoErr)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AppendBlock())
+
+	h = ct.Health()
+	r.Equal(int32(1), h.Height)
+	r.Equal(ct.MerkleHash().String(), h.Root)
+	r.False(h.LastBlockAt.IsZero())
+	r.Empty(h.RepoErr)
+
+	// A root of the tip differing in the block repo is reported.
+	r.NoError(ct.blockRepo.Set(1, &hash))
+	h = ct.Health()
+	r.Contains(h.RepoErr, "root of 1 in the block repo")
+}
+
+func TestExportClaims(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AddClaim(b("test"), o2, node.NewClaimID(o2), 20, nil))
+	r.NoError(ct.AddClaim(b("abc"), o3, node.NewClaimID(o3), 30, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.SpendClaim(b("test"), o2, node.NewClaimID(o2)))
+	r.NoError(ct.AppendBlock())
+
+	var buf bytes.Buffer
+	rows, err := ct.Snapshot().ExportClaims(context.Background(), &buf, ExportByName)
+	r.NoError(err)
+	r.Equal(2, rows)
+
+	x, err := ReadExportIndex(bytes.NewReader(buf.Bytes()))
+	r.NoError(err)
+	r.Equal(ExportByName, x.Order)
+	r.Equal(int32(2), x.Height)
+	r.Equal(*ct.MerkleHash(), x.Root)
+	r.Equal(2, x.Rows)
+	r.Equal(int64(buf.Len()), x.Start+x.Offsets[256])
+
+	// The rows of a key are found by seeking to its bucket.
+	from, to := x.Bucket('t')
+	row := string(buf.Bytes()[x.Start+from : x.Start+to])
+	r.Equal(fmt.Sprintf("%s\t\\\\x74657374\t%s\t10\tactivated\n", node.NewClaimID(o1), o1), row)
+	from, to = x.Bucket('b')
+	r.Equal(from, to)
+
+	// The rows start at the line the schema documents.
+	r.Equal(259, strings.Count(string(buf.Bytes()[:x.Start]), "\n")+1)
+
+	buf.Reset()
+	_, err = ct.Snapshot().ExportClaims(context.Background(), &buf, ExportByClaimID)
+	r.NoError(err)
+	x, err = ReadExportIndex(bytes.NewReader(buf.Bytes()))
+	r.NoError(err)
+	id := node.NewClaimID(o3)
+	from, to = x.Bucket(id[0])
+	r.True(strings.HasPrefix(string(buf.Bytes()[x.Start+from:x.Start+to]), id.String()))
+
+	_, err = ct.Snapshot().ExportClaims(context.Background(), &buf, "amount")
+	r.Error(err)
+}
+
+func TestBackup(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	cfg.Record = true
+	cfg.OutPointIndex = true
+	defer func() {
+		cfg.Record = false
+		cfg.OutPointIndex = false
+	}()
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	for i := uint32(1); i <= 20; i++ {
+		o := wire.OutPoint{Hash: hash, Index: i}
+		r.NoError(ct.AddClaim(b(fmt.Sprintf("test%d", i%7)), o, node.NewClaimID(o), int64(i), []byte("value")))
+		r.NoError(ct.AppendBlock())
+	}
+
+	dir := filepath.Join(t.TempDir(), "backup")
+	h, err := ct.Backup(dir)
+	r.NoError(err)
+	r.Equal(int32(20), h.Height)
+	r.Equal(*ct.MerkleHash(), h.Root)
+	r.Contains(h.Repos, cfg.ChainRepoPebble.Path)
+	r.Contains(h.Repos, cfg.OutPointRepoPebble.Path)
+	_, err = ct.Backup(dir)
+	r.Error(err) // over the last one
+
+	// The ClaimTrie keeps going after the backup, which stays as of its block.
+	o := wire.OutPoint{Hash: hash, Index: 21}
+	r.NoError(ct.AddClaim(b("test21"), o, node.NewClaimID(o), 1, nil))
+	r.NoError(ct.AppendBlock())
+
+	restored := cfg
+	restored.DataDir = t.TempDir()
+	_, err = RestoreBackup(restored, t.TempDir())
+	r.Error(err) // without a backup
+	rh, err := RestoreBackup(restored, dir)
+	r.NoError(err)
+	r.Equal(h, rh)
+	_, err = RestoreBackup(restored, dir)
+	r.Error(err) // over the repos restored
+
+	rt, err := New(restored)
+	r.NoError(err)
+	defer func() {
+		err = rt.Close()
+		r.NoError(err)
+	}()
+	r.Equal(int32(20), rt.Height())
+	r.Equal(h.Root, *rt.MerkleHash())
+	r.Empty(rt.Fsck().Findings)
+}
+
+func TestNetworkConfig(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	defer param.SetNetwork(wire.TestNet)
+	defer func() { cfg.Network, cfg.ParamOverrides = "", nil }()
+
+	cfg.ParamOverrides = []string{"expiration=50"}
+	_, err := New(cfg)
+	r.Error(err)
+
+	cfg.Network = "regtest"
+	cfg.ParamOverrides = []string{"expiration=50@100"}
+	_, err = New(cfg)
+	r.Error(err)
+
+	cfg.Network = "mainnet"
+	cfg.ParamOverrides = []string{"expiration=50", "normalized_names_height=never"}
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+	r.Equal(int32(50), param.OriginalClaimExpirationTime)
+	r.Equal(int32(math.MaxInt32), param.NormalizedNameForkHeight)
+	r.Equal(int32(658309), param.AllClaimsInMerkleForkHeight)
+}
+
+func TestMetrics(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	r.ErrorIs(ct.WriteMetrics(io.Discard), ErrMetricsDisabled)
+	r.NoError(ct.Close())
+
+	metered := cfg
+	metered.Metrics = true
+	metered.MetricsListen = "127.0.0.1:0"
+	ct, err = New(metered)
+	r.NoError(err)
+	defer func() {
+		err = ct.Close()
+		r.NoError(err)
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	id1 := node.NewClaimID(o1)
+	r.NoError(ct.AddClaim(b("test"), o1, id1, 10, nil))
+	r.NoError(ct.AddSupport(b("test"), nil, o2, 5, id1))
+	r.NoError(ct.AddClaim(b("tester"), o2, node.NewClaimID(o2), 10, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.SpendSupport(b("test"), o2, id1))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AppendBlock())
+
+	var buf bytes.Buffer
+	r.NoError(ct.WriteMetrics(&buf))
+	text := buf.String()
+	for _, sample := range []string{
+		"claimtrie_blocks_total 3\n",
+		`claimtrie_changes_total{type="add_claim"} 2` + "\n",
+		`claimtrie_changes_total{type="add_support"} 1` + "\n",
+		`claimtrie_changes_total{type="spend_support"} 1` + "\n",
+		"claimtrie_nodes_resolved_total 3\n",
+		`claimtrie_stage_seconds_count{stage="hash"} 3` + "\n",
+		`claimtrie_stage_seconds_count{stage="block"} 3` + "\n",
+		`claimtrie_repo_seconds_count{repo="node",op="write"} `,
+		`claimtrie_repo_seconds_count{repo="trie",op="write"} 2` + "\n",
+		"claimtrie_height 3\n",
+	} {
+		r.Contains(text, sample)
+	}
+
+	rec := httptest.NewRecorder()
+	ct.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	r.Equal(http.StatusOK, rec.Code)
+	r.Contains(rec.Body.String(), "claimtrie_blocks_total 3\n")
+}
+
+func TestSimulateBid(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim([]byte("test"), o1, node.NewClaimID(o1), 10, nil))
+	for ct.Height() < 99 {
+		r.NoError(ct.AppendBlock())
+	}
+
+	// Delayed by (100 - 1) / 32 blocks.
+	sim, err := ct.SimulateBid([]byte("test"), 5, nil)
+	r.NoError(err)
+	r.Equal(int32(100), sim.AcceptAt)
+	r.Equal(int32(103), sim.ActivateAt)
+	r.False(sim.Wins)
+	r.Equal(int64(5), sim.EffectiveAmount)
+	r.Equal(node.NewClaimID(o1), sim.Previous.ClaimID)
+	r.Equal(node.NewClaimID(o1), sim.Best.ClaimID)
+	r.Equal(int64(11), sim.CounterBid)
+
+	sim, err = ct.SimulateBid([]byte("test"), 20, nil)
+	r.NoError(err)
+	r.Equal(int32(103), sim.ActivateAt)
+	r.True(sim.Wins)
+	r.Equal(sim.ClaimID, sim.Best.ClaimID)
+	r.Equal(int64(21), sim.CounterBid)
+
+	// The supports of the best claim aren't delayed.
+	id := node.NewClaimID(o1)
+	sim, err = ct.SimulateBid([]byte("test"), 5, &id)
+	r.NoError(err)
+	r.True(sim.Support)
+	r.Equal(int32(100), sim.ActivateAt)
+	r.True(sim.Wins)
+	r.Equal(int64(15), sim.EffectiveAmount)
+	r.Equal(int64(16), sim.CounterBid)
+
+	id = node.NewClaimID(o2)
+	_, err = ct.SimulateBid([]byte("test"), 5, &id)
+	r.ErrorIs(err, ErrNameNotFound)
+
+	// The changes pending are accounted for, and nothing is applied.
+	r.NoError(ct.AddClaim([]byte("test"), o2, node.NewClaimID(o2), 20, nil))
+	sim, err = ct.SimulateBid([]byte("test"), 15, nil)
+	r.NoError(err)
+	r.False(sim.Wins)
+	r.Equal(node.NewClaimID(o2), sim.Best.ClaimID)
+	r.Equal(int64(20), sim.BestAmount)
+	r.Len(ct.changes, 1)
+	r.NoError(ct.AppendBlock())
+	n, err := ct.Node([]byte("test"))
+	r.NoError(err)
+	r.Len(n.Claims, 2)
+	r.Empty(n.Supports)
+}
+
+func TestEncryptedRepos(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	key := filepath.Join(t.TempDir(), "key")
+	r.NoError(os.WriteFile(key, []byte(strings.Repeat("ab", encryptedrepo.KeySize)+"\n"), 0600))
+	cfg.EncryptionKeyFile = key
+	cfg.Record = true
+	cfg.NodeManager = config.NodeManagerSnapshot
+	cfg.ValueStorage = config.ValuesBlobs
+	cfg.ValueBlobSize = 16
+	defer func() {
+		cfg.EncryptionKeyFile, cfg.EncryptionKMS = "", ""
+		cfg.Record = false
+		cfg.NodeManager = ""
+		cfg.ValueStorage, cfg.ValueBlobSize = "", 0
+	}()
+
+	ct, err := New(cfg)
+	r.NoError(err)
+
+	inline, blob := []byte("inline-secret"), []byte("the secret kept in the value repo")
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1, o2 := wire.OutPoint{Hash: hash, Index: 1}, wire.OutPoint{Hash: hash, Index: 2}
+	r.NoError(ct.AddClaim(b("test"), o1, node.NewClaimID(o1), 10, inline))
+	r.NoError(ct.AddClaim(b("test"), o2, node.NewClaimID(o2), 5, blob))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AppendBlock())
+	root := *ct.MerkleHash()
+	r.NoError(ct.Close())
+
+	// None of the values are at rest in the clear.
+	err = filepath.Walk(cfg.DataDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		r.NoError(err)
+		r.False(bytes.Contains(data, inline), path)
+		r.False(bytes.Contains(data, blob), path)
+		return nil
+	})
+	r.NoError(err)
+
+	ct, err = New(cfg)
+	r.NoError(err)
+	r.Equal(root, *ct.MerkleHash())
+	n, err := ct.Node(b("test"))
+	r.NoError(err)
+	r.Len(n.Claims, 2)
+	for _, c := range n.Claims {
+		value, err := ct.ClaimValue(c)
+		r.NoError(err)
+		if c.OutPoint == o1 {
+			r.Equal(inline, value)
+		} else {
+			r.Equal(blob, value)
+		}
+	}
+	changes, err := ct.chainRepo.Load(1)
+	r.NoError(err)
+	r.Len(changes, 2)
+	r.Equal(inline, changes[0].Value)
+	r.Equal(blob, changes[1].Value)
+	r.NoError(ct.Close())
+
+	// The values don't open with another key.
+	r.NoError(os.WriteFile(key, []byte(strings.Repeat("cd", encryptedrepo.KeySize)), 0600))
+	ct, err = New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+	_, err = ct.chainRepo.Load(1)
+	r.ErrorIs(err, encryptedrepo.ErrDecrypt)
+
+	cfg.EncryptionKMS = "test:key"
+	_, err = New(cfg)
+	r.Error(err) // with both
+	cfg.EncryptionKeyFile = ""
+	_, err = New(cfg)
+	r.Error(err) // without the source registered
+}
+
+func TestTuning(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	report, err := Tune(cfg)
+	r.NoError(err)
+	b := report.Benchmark
+	r.Positive(b.HashesPerSec)
+	r.Positive(b.RepoWrite)
+	r.Positive(b.RepoRead)
+	dirs, err := os.ReadDir(cfg.DataDir)
+	r.NoError(err)
+	r.Empty(dirs) // the scratch repo is removed
+
+	fast := &Benchmark{CPUs: 4, HashesPerSec: 1e6, RepoWrite: time.Millisecond, RepoRead: 50 * time.Microsecond,
+		DataDirBytes: 10 << 30}
+	r.Empty(Advise(cfg, fast))
+
+	slow := *fast
+	slow.HashesPerSec = 1e5
+	slow.RepoWrite = 10 * time.Millisecond
+	slow.RepoRead = time.Millisecond
+	tuned := cfg
+	tuned.HashWorkers = 1
+	tuned.MemoryBudget = 1 << 30
+	var settings []string
+	for _, rec := range Advise(tuned, &slow) {
+		settings = append(settings, rec.Setting)
+	}
+	r.Equal([]string{"HashWorkers", "NodeRepoPebble.CacheSize", "TrieNodeCache", "TriePrefetch", "TrieAsyncWrites",
+		"BatchBlocks"}, settings)
+
+	tuned.HashWorkers = 8
+	recs := Advise(tuned, fast)
+	r.Len(recs, 1)
+	r.Equal("4", recs[0].Suggested)
+}
+
+func TestDeterministicClaimOrder(t *testing.T) {
+
+	r := require.New(t)
+
+	defer func() {
+		cfg.NodeManager = config.NodeManagerReplay
+		cfg.NodeSnapshotThreshold = config.DefaultConfig.NodeSnapshotThreshold
+	}()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	name := []byte("test")
+	indexes := func(l node.ClaimList) []uint32 {
+		var idx []uint32
+		for _, c := range l {
+			idx = append(idx, c.OutPoint.Index)
+		}
+		return idx
+	}
+
+	// The nodes replayed, and the ones loaded from their snapshots, list their claims the same, in the order
+	// they were added, however many times they were hashed, or dropped from the cache.
+	for _, manager := range []string{config.NodeManagerReplay, config.NodeManagerSnapshot} {
+		setup(t)
+		cfg.NodeManager = manager
+		cfg.NodeSnapshotThreshold = 1
+
+		ct, err := New(cfg)
+		r.NoError(err)
+
+		for i := uint32(1); i <= 5; i++ {
+			op := wire.OutPoint{Hash: hash, Index: i}
+			r.NoError(ct.AddClaim(name, op, node.NewClaimID(op), int64(i), nil))
+			r.NoError(ct.AppendBlock())
+		}
+		op := wire.OutPoint{Hash: hash, Index: 2}
+		r.NoError(ct.SpendClaim(name, op, node.NewClaimID(op)))
+		for i := 0; i < 5; i++ {
+			r.NoError(ct.AppendBlock())
+		}
+		r.NotNil(ct.MerkleHash())
+
+		n, err := ct.Node(name)
+		r.NoError(err, manager)
+		r.Equal([]uint32{1, 3, 4, 5}, indexes(n.Claims), manager)
+		r.Equal([]uint32{5, 4, 3, 1}, indexes(n.SortedClaims()), manager)
+
+		ct.nodeManager.Invalidate([][]byte{name})
+		res, err := ct.ResolveAt(name, ct.height)
+		r.NoError(err, manager)
+		r.Equal([]uint32{1, 3, 4, 5}, indexes(res.Node.Claims), manager)
+
+		r.NoError(ct.Close())
+	}
+}
+
+func TestDiffRoot(t *testing.T) {
+
+	r := require.New(t)
+
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	r.NoError(ct.AddClaim([]byte("a"), o1, node.NewClaimID(o1), 10, nil))
+	r.NoError(ct.AddClaim([]byte("b"), o2, node.NewClaimID(o2), 10, nil))
+	r.NoError(ct.AppendBlock())
+	root := *ct.MerkleHash()
+
+	r.NoError(ct.SpendClaim([]byte("b"), o2, node.NewClaimID(o2)))
+	r.NoError(ct.AddClaim([]byte("c"), o3, node.NewClaimID(o3), 10, nil))
+	r.NoError(ct.AppendBlock())
+
+	diffs, err := ct.DiffRoot(&root)
+	r.NoError(err)
+	r.Len(diffs, 2)
+	r.Equal([]byte("b"), diffs[0].Name)
+	r.NotNil(diffs[0].Before)
+	r.Nil(diffs[0].After)
+	r.Equal([]byte("c"), diffs[1].Name)
+	r.Nil(diffs[1].Before)
+	r.Equal(node.CalculateNodeHash(o3, 2), diffs[1].After)
+	for _, d := range diffs {
+		r.Equal(d.After, d.Rebuilt)
+	}
+}
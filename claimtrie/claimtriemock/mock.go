@@ -0,0 +1,271 @@
+// Package claimtriemock provides a mock of the claimtrie.Interface, for testing its users without the repos.
+package claimtriemock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Call is a call of a method of the Mock, with its arguments.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// Mock is a claimtrie.Interface, of which each method calls its func, if it's set, or returns the zero values
+// otherwise. The calls are recorded in order. It's safe for concurrent access, once the funcs are set.
+type Mock struct {
+	HeightFunc           func() int32
+	MerkleHashFunc       func() *chainhash.Hash
+	NodeFunc             func(name []byte) (*node.Node, error)
+	ResolveAtFunc        func(name []byte, height int32) (*claimtrie.Resolution, error)
+	ResolveAtContextFunc func(ctx context.Context, name []byte, height int32) (*claimtrie.Resolution, error)
+	ProveAtContextFunc   func(ctx context.Context, name []byte, height int32) (*claimtrie.Resolution, *merkletrie.Proof, error)
+	NameProofFunc        func(name []byte) (*merkletrie.Proof, error)
+	ClaimByIDFunc        func(id node.ClaimID) ([]claimtrie.IndexedClaim, error)
+	ClaimValueFunc       func(c *node.Claim) ([]byte, error)
+	TopNamesFunc         func(n int) ([]claimtrie.TopName, error)
+	SearchNamesFunc      func(query string, opts claimtrie.SearchOptions) ([][]byte, error)
+	StatsFunc            func() claimtrie.Stats
+	HealthFunc           func() claimtrie.Health
+	AddClaimFunc         func(name []byte, op wire.OutPoint, id node.ClaimID, amt int64, val []byte) error
+	UpdateClaimFunc      func(name []byte, op wire.OutPoint, amt int64, id node.ClaimID, val []byte) error
+	SpendClaimFunc       func(name []byte, op wire.OutPoint, id node.ClaimID) error
+	AddSupportFunc       func(name []byte, value []byte, op wire.OutPoint, amt int64, id node.ClaimID) error
+	SpendSupportFunc     func(name []byte, op wire.OutPoint, id node.ClaimID) error
+	AppendBlockFunc      func() error
+	ResetHeightFunc      func(height int32) error
+	ReportHashFunc       func(height int32, hash chainhash.Hash) error
+	SetBatchingFunc      func(enabled bool) error
+	FlushFunc            func() error
+	CloseFunc            func() error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ claimtrie.Interface = (*Mock)(nil)
+
+// Calls returns the calls made so far, in order.
+func (m *Mock) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// CallsTo returns the calls of the method made so far, in order.
+func (m *Mock) CallsTo(method string) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var calls []Call
+	for _, c := range m.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+func (m *Mock) record(method string, args ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, Call{Method: method, Args: args})
+}
+
+func (m *Mock) Height() int32 {
+	m.record("Height")
+	if m.HeightFunc != nil {
+		return m.HeightFunc()
+	}
+	return 0
+}
+
+func (m *Mock) MerkleHash() *chainhash.Hash {
+	m.record("MerkleHash")
+	if m.MerkleHashFunc != nil {
+		return m.MerkleHashFunc()
+	}
+	return nil
+}
+
+func (m *Mock) Node(name []byte) (*node.Node, error) {
+	m.record("Node", name)
+	if m.NodeFunc != nil {
+		return m.NodeFunc(name)
+	}
+	return nil, nil
+}
+
+func (m *Mock) ResolveAt(name []byte, height int32) (*claimtrie.Resolution, error) {
+	m.record("ResolveAt", name, height)
+	if m.ResolveAtFunc != nil {
+		return m.ResolveAtFunc(name, height)
+	}
+	return nil, nil
+}
+
+func (m *Mock) ResolveAtContext(ctx context.Context, name []byte, height int32) (*claimtrie.Resolution, error) {
+	m.record("ResolveAtContext", ctx, name, height)
+	if m.ResolveAtContextFunc != nil {
+		return m.ResolveAtContextFunc(ctx, name, height)
+	}
+	return nil, nil
+}
+
+func (m *Mock) ProveAtContext(ctx context.Context, name []byte, height int32) (*claimtrie.Resolution, *merkletrie.Proof, error) {
+	m.record("ProveAtContext", ctx, name, height)
+	if m.ProveAtContextFunc != nil {
+		return m.ProveAtContextFunc(ctx, name, height)
+	}
+	return nil, nil, nil
+}
+
+func (m *Mock) NameProof(name []byte) (*merkletrie.Proof, error) {
+	m.record("NameProof", name)
+	if m.NameProofFunc != nil {
+		return m.NameProofFunc(name)
+	}
+	return nil, nil
+}
+
+func (m *Mock) ClaimByID(id node.ClaimID) ([]claimtrie.IndexedClaim, error) {
+	m.record("ClaimByID", id)
+	if m.ClaimByIDFunc != nil {
+		return m.ClaimByIDFunc(id)
+	}
+	return nil, nil
+}
+
+func (m *Mock) ClaimValue(c *node.Claim) ([]byte, error) {
+	m.record("ClaimValue", c)
+	if m.ClaimValueFunc != nil {
+		return m.ClaimValueFunc(c)
+	}
+	return nil, nil
+}
+
+func (m *Mock) TopNames(n int) ([]claimtrie.TopName, error) {
+	m.record("TopNames", n)
+	if m.TopNamesFunc != nil {
+		return m.TopNamesFunc(n)
+	}
+	return nil, nil
+}
+
+func (m *Mock) SearchNames(query string, opts claimtrie.SearchOptions) ([][]byte, error) {
+	m.record("SearchNames", query, opts)
+	if m.SearchNamesFunc != nil {
+		return m.SearchNamesFunc(query, opts)
+	}
+	return nil, nil
+}
+
+func (m *Mock) Stats() claimtrie.Stats {
+	m.record("Stats")
+	if m.StatsFunc != nil {
+		return m.StatsFunc()
+	}
+	return claimtrie.Stats{}
+}
+
+func (m *Mock) Health() claimtrie.Health {
+	m.record("Health")
+	if m.HealthFunc != nil {
+		return m.HealthFunc()
+	}
+	return claimtrie.Health{}
+}
+
+func (m *Mock) AddClaim(name []byte, op wire.OutPoint, id node.ClaimID, amt int64, val []byte) error {
+	m.record("AddClaim", name, op, id, amt, val)
+	if m.AddClaimFunc != nil {
+		return m.AddClaimFunc(name, op, id, amt, val)
+	}
+	return nil
+}
+
+func (m *Mock) UpdateClaim(name []byte, op wire.OutPoint, amt int64, id node.ClaimID, val []byte) error {
+	m.record("UpdateClaim", name, op, amt, id, val)
+	if m.UpdateClaimFunc != nil {
+		return m.UpdateClaimFunc(name, op, amt, id, val)
+	}
+	return nil
+}
+
+func (m *Mock) SpendClaim(name []byte, op wire.OutPoint, id node.ClaimID) error {
+	m.record("SpendClaim", name, op, id)
+	if m.SpendClaimFunc != nil {
+		return m.SpendClaimFunc(name, op, id)
+	}
+	return nil
+}
+
+func (m *Mock) AddSupport(name []byte, value []byte, op wire.OutPoint, amt int64, id node.ClaimID) error {
+	m.record("AddSupport", name, value, op, amt, id)
+	if m.AddSupportFunc != nil {
+		return m.AddSupportFunc(name, value, op, amt, id)
+	}
+	return nil
+}
+
+func (m *Mock) SpendSupport(name []byte, op wire.OutPoint, id node.ClaimID) error {
+	m.record("SpendSupport", name, op, id)
+	if m.SpendSupportFunc != nil {
+		return m.SpendSupportFunc(name, op, id)
+	}
+	return nil
+}
+
+func (m *Mock) AppendBlock() error {
+	m.record("AppendBlock")
+	if m.AppendBlockFunc != nil {
+		return m.AppendBlockFunc()
+	}
+	return nil
+}
+
+func (m *Mock) ResetHeight(height int32) error {
+	m.record("ResetHeight", height)
+	if m.ResetHeightFunc != nil {
+		return m.ResetHeightFunc(height)
+	}
+	return nil
+}
+
+func (m *Mock) ReportHash(height int32, hash chainhash.Hash) error {
+	m.record("ReportHash", height, hash)
+	if m.ReportHashFunc != nil {
+		return m.ReportHashFunc(height, hash)
+	}
+	return nil
+}
+
+func (m *Mock) SetBatching(enabled bool) error {
+	m.record("SetBatching", enabled)
+	if m.SetBatchingFunc != nil {
+		return m.SetBatchingFunc(enabled)
+	}
+	return nil
+}
+
+func (m *Mock) Flush() error {
+	m.record("Flush")
+	if m.FlushFunc != nil {
+		return m.FlushFunc()
+	}
+	return nil
+}
+
+func (m *Mock) Close() error {
+	m.record("Close")
+	if m.CloseFunc != nil {
+		return m.CloseFunc()
+	}
+	return nil
+}
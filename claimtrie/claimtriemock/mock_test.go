@@ -0,0 +1,46 @@
+package claimtriemock_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/claimtriemock"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMock(t *testing.T) {
+
+	r := require.New(t)
+
+	height := int32(0)
+	m := &claimtriemock.Mock{
+		HeightFunc: func() int32 { return height },
+		AppendBlockFunc: func() error {
+			height++
+			return nil
+		},
+	}
+
+	var ct claimtrie.Interface = m
+	r.NoError(ct.AddClaim([]byte("test"), wire.OutPoint{}, node.ClaimID{}, 1, nil))
+	r.NoError(ct.AppendBlock())
+	r.Equal(int32(1), ct.Height())
+
+	// The methods without funcs return the zero values.
+	n, err := ct.Node([]byte("test"))
+	r.NoError(err)
+	r.Nil(n)
+
+	m.ResetHeightFunc = func(height int32) error { return errors.New("reset") }
+	r.Error(ct.ResetHeight(0))
+
+	r.Len(m.Calls(), 5)
+	calls := m.CallsTo("AddClaim")
+	r.Len(calls, 1)
+	r.Equal([]byte("test"), calls[0].Args[0])
+	r.Equal(int64(1), calls[0].Args[3])
+}
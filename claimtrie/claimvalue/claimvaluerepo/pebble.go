@@ -0,0 +1,78 @@
+package claimvaluerepo
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Key format:
+//
+//	outpoint(36B): the value of the claim, or the support, created at the outpoint.
+type Pebble struct {
+	db *pebble.DB
+}
+
+func NewPebble(path string) (*Pebble, error) {
+
+	db, err := pebble.Open(path, &pebble.Options{Cache: pebble.NewCache(16 << 20)})
+	if err != nil {
+		return nil, fmt.Errorf("pebble open %s, %w", path, err)
+	}
+
+	repo := &Pebble{db: db}
+
+	return repo, nil
+}
+
+func key(op wire.OutPoint) []byte {
+	o := change.NewOutPoint(op)
+	return o[:]
+}
+
+func (repo *Pebble) Set(op wire.OutPoint, value []byte) error {
+	return repo.db.Set(key(op), value, pebble.NoSync)
+}
+
+func (repo *Pebble) Get(op wire.OutPoint) ([]byte, error) {
+
+	value, closer, err := repo.db.Get(key(op))
+	if err == pebble.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pebble get: %w", err)
+	}
+	defer closer.Close()
+
+	return append([]byte(nil), value...), nil
+}
+
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Pebble) Backup(dir string) error {
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Pebble) Close() error {
+
+	err := repo.db.Flush()
+	if err != nil {
+		return fmt.Errorf("pebble flush: %w", err)
+	}
+
+	err = repo.db.Close()
+	if err != nil {
+		return fmt.Errorf("pebble close: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package claimvaluerepo
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValues(t *testing.T) {
+
+	r := require.New(t)
+
+	path := t.TempDir()
+	repo, err := NewPebble(path)
+	r.NoError(err)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	op1, op2 := wire.OutPoint{Hash: hash, Index: 1}, wire.OutPoint{Hash: hash, Index: 2}
+
+	r.NoError(repo.Set(op1, []byte("value")))
+
+	value, err := repo.Get(op1)
+	r.NoError(err)
+	r.Equal([]byte("value"), value)
+
+	value, err = repo.Get(op2)
+	r.NoError(err)
+	r.Nil(value)
+
+	r.NoError(repo.Close())
+
+	repo, err = NewPebble(path)
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	value, err = repo.Get(op1)
+	r.NoError(err)
+	r.Equal([]byte("value"), value)
+}
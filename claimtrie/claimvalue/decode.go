@@ -0,0 +1,355 @@
+package claimvalue
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+)
+
+// ErrInvalidValue is returned for the values, which don't conform to the Claim message of the LBRY schema.
+var ErrInvalidValue = errors.New("invalid claim value")
+
+// ErrLegacyValue is returned for the values of the earlier versions of the LBRY schema, which aren't decoded.
+var ErrLegacyValue = errors.New("legacy claim value")
+
+// The types of the claims, by the fields of the Claim message holding them.
+const (
+	TypeStream     = "stream"
+	TypeChannel    = "channel"
+	TypeCollection = "collection"
+	TypeRepost     = "repost"
+)
+
+// Claim is the metadata of a claim, decoded from its value: a Claim message of the LBRY schema, which
+// is preceded by the version: 0 for the unsigned ones, and 1 for the ones signed by a channel, followed
+// by the Claim ID of the channel, and the signature.
+type Claim struct {
+	Type        string
+	Title       string
+	Description string
+	Thumbnail   *Source
+	Tags        []string
+
+	Stream     *Stream          // Of TypeStream.
+	Channel    *Channel         // Of TypeChannel.
+	Collection []change.ClaimID // Of TypeCollection.
+	Repost     *change.ClaimID  // Of TypeRepost.
+
+	Signature *Signature // Of the signed ones.
+}
+
+// Stream is the metadata of a claim of a file.
+type Stream struct {
+	Source      *Source
+	Author      string
+	License     string
+	LicenseURL  string
+	ReleaseTime int64
+	Fee         *Fee
+}
+
+// Channel is the metadata of a claim of a channel, which signs the claims published in it by its public key.
+type Channel struct {
+	PublicKey  []byte // DER encoded SubjectPublicKeyInfo of a secp256k1 key.
+	Email      string
+	WebsiteURL string
+	Cover      *Source
+	Featured   []change.ClaimID
+}
+
+// Source locates the content of a claim.
+type Source struct {
+	SDHash     []byte // Of the stream descriptor.
+	Name       string
+	Size       uint64
+	MediaType  string
+	URL        string
+	Hash       []byte // Of the file.
+	BTInfoHash []byte
+}
+
+// Fee is the price of a stream.
+type Fee struct {
+	Currency string
+	Address  []byte
+	Amount   uint64 // In the smallest units of the currency.
+}
+
+// Signature is the signature of a claim by a channel.
+type Signature struct {
+	Channel   change.ClaimID
+	Signature []byte // r and s, 32 bytes each.
+	Payload   []byte // The Claim message signed.
+}
+
+// The currencies of the fees, by the values of the Currency enum.
+var currencies = []string{"UNKNOWN_CURRENCY", "LBC", "BTC", "USD"}
+
+// signatureSize is the size, in bytes, of the signature following the Claim ID of the channel.
+const signatureSize = 64
+
+// Decode returns the metadata of the claim value. The slices of it share the memory of value.
+func Decode(value []byte) (*Claim, error) {
+
+	if len(value) == 0 {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidValue)
+	}
+
+	c := &Claim{}
+	payload := value[1:]
+	switch value[0] {
+	case 0:
+	case 1:
+		var id change.ClaimID
+		if len(value) < 1+len(id)+signatureSize {
+			return nil, fmt.Errorf("%w: truncated signature", ErrInvalidValue)
+		}
+		copy(id[:], value[1:])
+		sig := value[1+len(id) : 1+len(id)+signatureSize]
+		payload = value[1+len(id)+signatureSize:]
+		c.Signature = &Signature{Channel: id, Signature: sig, Payload: payload}
+	default:
+		return nil, fmt.Errorf("%w: version %d", ErrLegacyValue, value[0])
+	}
+
+	err := parseProto(payload, func(field int, v uint64, msg []byte) error {
+		var err error
+		switch field {
+		case 1:
+			c.Type = TypeStream
+			c.Stream, err = decodeStream(msg)
+		case 2:
+			c.Type = TypeChannel
+			c.Channel, err = decodeChannel(msg)
+		case 3:
+			c.Type = TypeCollection
+			c.Collection, err = decodeClaimList(msg)
+		case 4:
+			c.Type = TypeRepost
+			var id change.ClaimID
+			id, err = decodeClaimReference(msg)
+			c.Repost = &id
+		case 8:
+			c.Title = string(msg)
+		case 9:
+			c.Description = string(msg)
+		case 10:
+			c.Thumbnail, err = decodeSource(msg)
+		case 11:
+			c.Tags = append(c.Tags, string(msg))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func decodeStream(b []byte) (*Stream, error) {
+
+	s := &Stream{}
+	err := parseProto(b, func(field int, v uint64, msg []byte) error {
+		var err error
+		switch field {
+		case 1:
+			s.Source, err = decodeSource(msg)
+		case 2:
+			s.Author = string(msg)
+		case 3:
+			s.License = string(msg)
+		case 4:
+			s.LicenseURL = string(msg)
+		case 5:
+			s.ReleaseTime = int64(v)
+		case 6:
+			s.Fee, err = decodeFee(msg)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("stream: %w", err)
+	}
+
+	return s, nil
+}
+
+func decodeChannel(b []byte) (*Channel, error) {
+
+	ch := &Channel{}
+	err := parseProto(b, func(field int, v uint64, msg []byte) error {
+		var err error
+		switch field {
+		case 1:
+			ch.PublicKey = msg
+		case 2:
+			ch.Email = string(msg)
+		case 3:
+			ch.WebsiteURL = string(msg)
+		case 4:
+			ch.Cover, err = decodeSource(msg)
+		case 5:
+			ch.Featured, err = decodeClaimList(msg)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("channel: %w", err)
+	}
+
+	return ch, nil
+}
+
+func decodeSource(b []byte) (*Source, error) {
+
+	s := &Source{}
+	err := parseProto(b, func(field int, v uint64, msg []byte) error {
+		switch field {
+		case 1:
+			s.SDHash = msg
+		case 2:
+			s.Name = string(msg)
+		case 3:
+			s.Size = v
+		case 4:
+			s.MediaType = string(msg)
+		case 5:
+			s.URL = string(msg)
+		case 6:
+			s.Hash = msg
+		case 7:
+			s.BTInfoHash = msg
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+
+	return s, nil
+}
+
+func decodeFee(b []byte) (*Fee, error) {
+
+	f := &Fee{Currency: currencies[0]}
+	err := parseProto(b, func(field int, v uint64, msg []byte) error {
+		switch field {
+		case 1:
+			if v < uint64(len(currencies)) {
+				f.Currency = currencies[v]
+			} else {
+				f.Currency = fmt.Sprintf("currency(%d)", v)
+			}
+		case 2:
+			f.Address = msg
+		case 3:
+			f.Amount = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fee: %w", err)
+	}
+
+	return f, nil
+}
+
+func decodeClaimList(b []byte) ([]change.ClaimID, error) {
+
+	var ids []change.ClaimID
+	err := parseProto(b, func(field int, v uint64, msg []byte) error {
+		if field != 2 {
+			return nil
+		}
+		id, err := decodeClaimReference(msg)
+		ids = append(ids, id)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim list: %w", err)
+	}
+
+	return ids, nil
+}
+
+func decodeClaimReference(b []byte) (change.ClaimID, error) {
+
+	var id change.ClaimID
+	err := parseProto(b, func(field int, v uint64, msg []byte) error {
+		if field != 1 {
+			return nil
+		}
+		if len(msg) != len(id) {
+			return fmt.Errorf("%w: claim hash of %d bytes", ErrInvalidValue, len(msg))
+		}
+		copy(id[:], msg)
+		return nil
+	})
+	if err != nil {
+		return id, fmt.Errorf("claim reference: %w", err)
+	}
+
+	return id, nil
+}
+
+// The wire types of the protobuf fields.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// parseProto calls fn with each field of the message: with the value of the varints, and the
+// contents of the length-delimited ones. The fixed ones are skipped.
+func parseProto(b []byte, fn func(field int, v uint64, msg []byte) error) error {
+
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return fmt.Errorf("%w: tag", ErrInvalidValue)
+		}
+		b = b[n:]
+
+		field := int(tag >> 3)
+		var v uint64
+		var msg []byte
+		switch tag & 7 {
+		case protoVarint:
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return fmt.Errorf("%w: varint of field %d", ErrInvalidValue, field)
+			}
+			b = b[n:]
+		case protoBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return fmt.Errorf("%w: length of field %d", ErrInvalidValue, field)
+			}
+			msg = b[n : n+int(size)]
+			b = b[n+int(size):]
+		case protoFixed64, protoFixed32:
+			size := 8
+			if tag&7 == protoFixed32 {
+				size = 4
+			}
+			if len(b) < size {
+				return fmt.Errorf("%w: field %d", ErrInvalidValue, field)
+			}
+			b = b[size:]
+			continue
+		default:
+			return fmt.Errorf("%w: wire type %d of field %d", ErrInvalidValue, tag&7, field)
+		}
+
+		err := fn(field, v, msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
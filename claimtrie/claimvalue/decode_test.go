@@ -0,0 +1,131 @@
+package claimvalue
+
+import (
+	"encoding/asn1"
+	"encoding/binary"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/change"
+
+	"github.com/stretchr/testify/require"
+)
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendField(b []byte, field int, msg []byte) []byte {
+	b = appendUvarint(b, uint64(field<<3|protoBytes))
+	b = appendUvarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = appendUvarint(b, uint64(field<<3|protoVarint))
+	return appendUvarint(b, v)
+}
+
+func testPublicKey(r *require.Assertions, key *btcec.PublicKey) []byte {
+
+	var info subjectPublicKeyInfo
+	info.Algorithm.Algorithm = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	params, err := asn1.Marshal(asn1.ObjectIdentifier{1, 3, 132, 0, 10})
+	r.NoError(err)
+	info.Algorithm.Parameters = asn1.RawValue{FullBytes: params}
+	uncompressed := key.SerializeUncompressed()
+	info.PublicKey = asn1.BitString{Bytes: uncompressed, BitLength: 8 * len(uncompressed)}
+	der, err := asn1.Marshal(info)
+	r.NoError(err)
+
+	return der
+}
+
+func TestDecode(t *testing.T) {
+
+	r := require.New(t)
+
+	priv, err := btcec.NewPrivateKey(btcec.S256())
+	r.NoError(err)
+	publicKey := testPublicKey(r, priv.PubKey())
+
+	// A channel claim, unsigned.
+	var featured []byte
+	featured = appendField(featured, 2, appendField(nil, 1, make([]byte, 20)))
+	var ch []byte
+	ch = appendField(ch, 1, publicKey)
+	ch = appendField(ch, 3, []byte("https://example.com"))
+	ch = appendField(ch, 5, featured)
+	msg := appendField(nil, 2, ch)
+	msg = appendField(msg, 8, []byte("Channel"))
+
+	c, err := Decode(append([]byte{0}, msg...))
+	r.NoError(err)
+	r.Equal(TypeChannel, c.Type)
+	r.Equal("Channel", c.Title)
+	r.Equal(publicKey, c.Channel.PublicKey)
+	r.Equal("https://example.com", c.Channel.WebsiteURL)
+	r.Equal([]change.ClaimID{{}}, c.Channel.Featured)
+	r.Nil(c.Signature)
+	r.ErrorIs(c.Verify(&chainhash.Hash{}, publicKey), ErrUnsigned)
+
+	// A stream claim, signed by the channel.
+	var source []byte
+	source = appendField(source, 1, []byte{1, 2, 3})
+	source = appendField(source, 2, []byte("video.mp4"))
+	source = appendVarintField(source, 3, 1000)
+	source = appendField(source, 4, []byte("video/mp4"))
+	var fee []byte
+	fee = appendVarintField(fee, 1, 1)
+	fee = appendVarintField(fee, 3, 500)
+	var stream []byte
+	stream = appendField(stream, 1, source)
+	stream = appendField(stream, 2, []byte("author"))
+	stream = appendVarintField(stream, 5, 1600000000)
+	stream = appendField(stream, 6, fee)
+	msg = appendField(nil, 1, stream)
+	msg = appendField(msg, 8, []byte("Title"))
+	msg = appendField(msg, 11, []byte("one"))
+	msg = appendField(msg, 11, []byte("two"))
+	msg = appendUvarint(msg, uint64(20<<3|protoFixed32)) // unknown fields are skipped
+	msg = append(msg, 1, 2, 3, 4)
+
+	channel := change.ClaimID{1, 2, 3}
+	firstInput := chainhash.HashH([]byte("input"))
+	digest := (&Signature{Channel: channel, Payload: msg}).Digest(&firstInput)
+	sig, err := priv.Sign(digest)
+	r.NoError(err)
+	value := append([]byte{1}, channel[:]...)
+	value = append(value, sig.R.FillBytes(make([]byte, 32))...)
+	value = append(value, sig.S.FillBytes(make([]byte, 32))...)
+	value = append(value, msg...)
+
+	c, err = Decode(value)
+	r.NoError(err)
+	r.Equal(TypeStream, c.Type)
+	r.Equal("Title", c.Title)
+	r.Equal([]string{"one", "two"}, c.Tags)
+	r.Equal("author", c.Stream.Author)
+	r.Equal(int64(1600000000), c.Stream.ReleaseTime)
+	r.Equal(&Source{SDHash: []byte{1, 2, 3}, Name: "video.mp4", Size: 1000, MediaType: "video/mp4"}, c.Stream.Source)
+	r.Equal(&Fee{Currency: "LBC", Amount: 500}, c.Stream.Fee)
+	r.Equal(channel, c.Signature.Channel)
+	r.NoError(c.Verify(&firstInput, publicKey))
+
+	// The signature doesn't verify for another input, nor for another key.
+	r.ErrorIs(c.Verify(&chainhash.Hash{}, publicKey), ErrInvalidSignature)
+	other, err := btcec.NewPrivateKey(btcec.S256())
+	r.NoError(err)
+	r.ErrorIs(c.Verify(&firstInput, testPublicKey(r, other.PubKey())), ErrInvalidSignature)
+	r.Error(c.Verify(&firstInput, []byte{1, 2, 3}))
+
+	// The values of the earlier schema, and the malformed ones, aren't decoded.
+	_, err = Decode([]byte{0x08, 0x01})
+	r.ErrorIs(err, ErrLegacyValue)
+	for _, v := range [][]byte{nil, value[:50], append([]byte{0}, msg[:len(msg)-2]...)} {
+		_, err = Decode(v)
+		r.ErrorIs(err, ErrInvalidValue)
+	}
+}
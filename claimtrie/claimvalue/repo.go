@@ -0,0 +1,15 @@
+package claimvalue
+
+import "github.com/btcsuite/btcd/wire"
+
+// Repo defines APIs for the values of the claims and supports, kept apart from their changes in the node repo,
+// by the outpoints they're created at, to access persistence layer. The values of an outpoint never change,
+// so they're kept across the rewinds.
+type Repo interface {
+	// Set stores the value of the outpoint.
+	Set(op wire.OutPoint, value []byte) error
+	// Get returns the value of the outpoint, or nil if there's none.
+	Get(op wire.OutPoint) ([]byte, error)
+
+	Close() error
+}
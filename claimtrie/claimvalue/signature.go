@@ -0,0 +1,82 @@
+package claimvalue
+
+import (
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// ErrInvalidSignature is returned for the claims, of which the signatures don't verify.
+var ErrInvalidSignature = errors.New("invalid claim signature")
+
+// ErrUnsigned is returned for verifying the claims, which aren't signed.
+var ErrUnsigned = errors.New("claim isn't signed")
+
+// subjectPublicKeyInfo is the DER encoding of the public keys of the channels.
+type subjectPublicKeyInfo struct {
+	Algorithm struct {
+		Algorithm  asn1.ObjectIdentifier
+		Parameters asn1.RawValue `asn1:"optional"`
+	}
+	PublicKey asn1.BitString
+}
+
+// ParsePublicKey returns the secp256k1 key of the public key of a channel.
+func ParsePublicKey(der []byte) (*btcec.PublicKey, error) {
+
+	var info subjectPublicKeyInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("public key: %d trailing bytes", len(rest))
+	}
+
+	key, err := btcec.ParsePubKey(info.PublicKey.RightAlign(), btcec.S256())
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Digest returns the digest signed by the channel of the claim: the SHA-256 of the txid of the outpoint
+// spent by the first input of the tx of the claim, the Claim ID of the channel, and the Claim message.
+func (s *Signature) Digest(firstInput *chainhash.Hash) []byte {
+
+	h := sha256.New()
+	h.Write(firstInput[:])
+	h.Write(s.Channel[:])
+	h.Write(s.Payload)
+
+	return h.Sum(nil)
+}
+
+// Verify verifies the signature of the claim by the public key of the channel, as Channel.PublicKey holds it,
+// against the txid of the outpoint spent by the first input of the tx of the claim.
+func (c *Claim) Verify(firstInput *chainhash.Hash, publicKey []byte) error {
+
+	if c.Signature == nil {
+		return ErrUnsigned
+	}
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	sig := btcec.Signature{
+		R: new(big.Int).SetBytes(c.Signature.Signature[:32]),
+		S: new(big.Int).SetBytes(c.Signature.Signature[32:]),
+	}
+	if !sig.Verify(c.Signature.Digest(firstInput), key) {
+		return fmt.Errorf("%w: by channel %s", ErrInvalidSignature, c.Signature.Channel)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package claimtrie
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/claimvalue"
+	"github.com/btcsuite/btcd/claimtrie/claimvalue/claimvaluerepo"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// ErrValueNotStored is returned for the values of the claims, and supports, appended under config.ValuesHashesOnly.
+var ErrValueNotStored = errors.New("claim value is not stored")
+
+// claimValueStore keeps the values of the claims and supports apart from the node repo, as the storage is configured.
+type claimValueStore struct {
+	storage string
+	minSize int
+	repo    claimvalue.Repo // the blobs, under config.ValuesBlobs.
+}
+
+func newClaimValueStore(cfg config.Config) (*claimValueStore, error) {
+
+	vs := &claimValueStore{storage: cfg.ValueStorage, minSize: cfg.ValueBlobSize}
+	switch vs.storage {
+	case "":
+		vs.storage = config.ValuesInline
+	case config.ValuesInline, config.ValuesHashesOnly:
+	case config.ValuesBlobs:
+		repo, err := claimvaluerepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ValueRepoPebble.Path))
+		if err != nil {
+			return nil, fmt.Errorf("new value repo: %w", err)
+		}
+		vs.repo = repo
+	default:
+		return nil, fmt.Errorf("unknown value storage: %q", vs.storage)
+	}
+	if vs.minSize < 1 {
+		vs.minSize = 1 // the empty values are never fetched.
+	}
+
+	return vs, nil
+}
+
+// externalize returns the change as it's kept in the node repo, once its value is stored in the blobs, or dropped.
+func (vs *claimValueStore) externalize(chg change.Change) (change.Change, error) {
+
+	if vs.storage == config.ValuesInline || len(chg.Value) == 0 {
+		return chg, nil
+	}
+	switch chg.Type {
+	case change.AddClaim, change.UpdateClaim, change.AddSupport:
+	default:
+		return chg, nil
+	}
+
+	if vs.storage == config.ValuesBlobs {
+		if len(chg.Value) < vs.minSize {
+			return chg, nil
+		}
+		// The outpoints are unique, so are their values; the ones of the blocks reset are just left behind.
+		err := vs.repo.Set(chg.OutPoint.Wire(), chg.Value)
+		if err != nil {
+			return chg, fmt.Errorf("value repo set: %w", err)
+		}
+	}
+	chg.Value = nil
+
+	return chg, nil
+}
+
+// ClaimValue returns the value of the claim, or the support, fetching it from the value repo, if it's kept
+// there. It returns ErrValueNotStored for the ones appended under config.ValuesHashesOnly.
+func (ct *ClaimTrie) ClaimValue(c *node.Claim) ([]byte, error) {
+
+	if len(c.Value) > 0 || ct.claimValues.storage == config.ValuesInline {
+		return c.Value, nil
+	}
+	if ct.claimValues.storage == config.ValuesHashesOnly {
+		return nil, ErrValueNotStored
+	}
+
+	value, err := ct.claimValues.repo.Get(c.OutPoint)
+	if err != nil {
+		return nil, fmt.Errorf("value repo get %s: %w", c.OutPoint, err)
+	}
+
+	return value, nil
+}
+
+// eventValue returns the value of the claim for the events, which have none under config.ValuesHashesOnly.
+func (ct *ClaimTrie) eventValue(c *node.Claim) ([]byte, error) {
+
+	value, err := ct.ClaimValue(c)
+	if errors.Is(err, ErrValueNotStored) {
+		return nil, nil
+	}
+
+	return value, err
+}
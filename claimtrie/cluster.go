@@ -0,0 +1,29 @@
+package claimtrie
+
+import (
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// Top returns the top level of the trie at the current height, from which the root hash
+// of the names partitioned across several ClaimTries by their first bytes is combined.
+func (ct *ClaimTrie) Top() merkletrie.Top {
+	ct.rebuildCorruptNodes()
+	return ct.merkleTrie.Top(ct.height >= param.AllClaimsInMerkleForkHeight)
+}
+
+// Leaves returns the values hashed into the trie at the current height of the names
+// under the top-level child ch.
+func (ct *ClaimTrie) Leaves(ch byte) merkletrie.LeafStore {
+
+	allClaims := ct.height >= param.AllClaimsInMerkleForkHeight
+	leaves := merkletrie.LeafStore{}
+	ct.merkleTrie.At(ct.MerkleHash()).IterateChild(ch, func(name []byte) bool {
+		if hs := ct.leafHashes(name, allClaims); len(hs) > 0 {
+			leaves[string(name)] = hs
+		}
+		return true
+	})
+
+	return leaves
+}
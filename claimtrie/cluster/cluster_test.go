@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newClaimTrie(t *testing.T) *claimtrie.ClaimTrie {
+
+	cfg := config.DefaultConfig
+	cfg.DataDir = t.TempDir()
+	ct, err := claimtrie.New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { ct.Close() })
+
+	return ct
+}
+
+func serve(t *testing.T, w *Worker) string {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go w.Serve(l) // nolint : errchk
+
+	return l.Addr().String()
+}
+
+func TestCluster(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	reference := newClaimTrie(t)
+
+	var workers []*Worker
+	var addrs []string
+	for i := 0; i < 3; i++ {
+		w := NewWorker(newClaimTrie(t), Shard{Index: i, Count: 3})
+		workers = append(workers, w)
+		addrs = append(addrs, serve(t, w))
+	}
+	c := NewCoordinator(addrs, 10*time.Second)
+	defer c.Close()
+
+	// Before the normalization fork, "Ábc" and "ðx" share their first byte, but not their shards.
+	names := []string{"test", "Test", "Ábc", "ábc", "ðx", "other", "a", "b", "c"}
+	r.False(Shard{Count: 3}.Has([]byte("Ábc")))
+	r.True(Shard{Count: 3}.Has([]byte("ðx")))
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	add := func(ct *claimtrie.ClaimTrie, name string, i uint32) {
+		op := wire.OutPoint{Hash: hash, Index: i}
+		r.NoError(ct.AddClaim([]byte(name), op, node.NewClaimID(op), int64(i), nil))
+	}
+	for i := uint32(1); i <= 360; i++ {
+		if i%5 == 0 {
+			name := names[int(i/5)%len(names)]
+			add(reference, name, i)
+			for _, w := range workers {
+				if w.Shard().Has([]byte(name)) {
+					r.NoError(w.Do(func(ct *claimtrie.ClaimTrie) error {
+						add(ct, name, i)
+						return nil
+					}))
+				}
+			}
+		}
+		r.NoError(reference.AppendBlock())
+		for _, w := range workers {
+			r.NoError(w.Do(func(ct *claimtrie.ClaimTrie) error { return ct.AppendBlock() }))
+		}
+
+		height, root, err := c.Root()
+		r.NoError(err)
+		r.Equal(reference.Height(), height)
+		r.Equal(reference.MerkleHash(), root, fmt.Sprintf("height %d", i))
+	}
+
+	r.NoError(workers[1].Do(func(ct *claimtrie.ClaimTrie) error { return ct.AppendBlock() }))
+	_, _, err := c.Root()
+	r.ErrorIs(err, ErrHeightMismatch)
+}
@@ -0,0 +1,180 @@
+package cluster
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// ErrHeightMismatch is returned when the workers are at different heights.
+var ErrHeightMismatch = errors.New("workers at different heights")
+
+type workerConn struct {
+	addr string
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// Coordinator combines the root hash of all the names from the tops of the tries of the
+// workers, one per shard.
+//
+// Before the normalization fork, the names of different shards may share their first bytes,
+// as they're only sharded by their normalized forms. The subtries of these top-level children
+// are rebuilt from the leaves of the workers having them, which are few.
+type Coordinator struct {
+	workers []*workerConn
+	timeout time.Duration
+}
+
+// NewCoordinator returns a Coordinator of the workers at the addresses, each of which keeps
+// one connection. The workers are dialed, and answer each request, within the timeout, if it's set.
+func NewCoordinator(addrs []string, timeout time.Duration) *Coordinator {
+	c := &Coordinator{timeout: timeout}
+	for _, addr := range addrs {
+		c.workers = append(c.workers, &workerConn{addr: addr})
+	}
+	return c
+}
+
+// Close closes the connections to the workers.
+func (c *Coordinator) Close() error {
+	var err error
+	for _, wc := range c.workers {
+		if wc.conn != nil {
+			if e := wc.conn.Close(); e != nil && err == nil {
+				err = e
+			}
+			wc.conn = nil
+		}
+	}
+	return err
+}
+
+// Root returns the height of the workers, and the root hash of all the names at it.
+func (c *Coordinator) Root() (int32, *chainhash.Hash, error) {
+
+	if len(c.workers) == 0 {
+		return 0, nil, fmt.Errorf("no workers")
+	}
+
+	tops := make([]merkletrie.Top, len(c.workers))
+	var height int32
+	for i, wc := range c.workers {
+		b, err := c.request(wc, requestTop)
+		if err != nil {
+			return 0, nil, err
+		}
+		h, top, err := decodeTop(b)
+		if err != nil {
+			return 0, nil, fmt.Errorf("worker %s: %w", wc.addr, err)
+		}
+		if i > 0 && h != height {
+			return 0, nil, fmt.Errorf("%w: %d at %s, %d at %s", ErrHeightMismatch, height, c.workers[0].addr, h, wc.addr)
+		}
+		height = h
+		tops[i] = top
+	}
+
+	allClaims := height >= param.AllClaimsInMerkleForkHeight
+	owners := map[byte][]int{}
+	for i, top := range tops {
+		for ch := range top.Children {
+			owners[ch] = append(owners[ch], i)
+		}
+	}
+	for ch, workers := range owners {
+		if len(workers) < 2 {
+			continue
+		}
+		h, err := c.rebuild(ch, workers, height, allClaims)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, i := range workers {
+			delete(tops[i].Children, ch)
+		}
+		tops = append(tops, merkletrie.Top{Children: map[byte]*chainhash.Hash{ch: h}})
+	}
+
+	root, err := merkletrie.CombineTops(tops, allClaims)
+	if err != nil {
+		return 0, nil, fmt.Errorf("combine tops at %d: %w", height, err)
+	}
+
+	return height, root, nil
+}
+
+// rebuild returns the hash of the top-level child ch from the leaves of the workers.
+func (c *Coordinator) rebuild(ch byte, workers []int, height int32, allClaims bool) (*chainhash.Hash, error) {
+
+	leaves := merkletrie.LeafStore{}
+	for _, i := range workers {
+		wc := c.workers[i]
+		b, err := c.request(wc, requestLeaves, ch)
+		if err != nil {
+			return nil, err
+		}
+		h, ls, err := decodeLeaves(b)
+		if err != nil {
+			return nil, fmt.Errorf("worker %s: %w", wc.addr, err)
+		}
+		if h != height {
+			return nil, fmt.Errorf("%w: %d at %s, while combining %d", ErrHeightMismatch, h, wc.addr, height)
+		}
+		for name, hs := range ls {
+			leaves[name] = hs
+		}
+	}
+
+	rt := merkletrie.NewRamTrie(leaves)
+	for name := range leaves {
+		rt.Update([]byte(name), false)
+	}
+	h := rt.Top(allClaims).Children[ch]
+	if h == nil {
+		return nil, fmt.Errorf("no leaves under %q", ch)
+	}
+
+	return h, nil
+}
+
+// request sends the request to the worker, and returns the payload of its response.
+// The connection is dropped on failures, and dialed again by the next request.
+func (c *Coordinator) request(wc *workerConn, req ...byte) ([]byte, error) {
+
+	if wc.conn == nil {
+		conn, err := net.DialTimeout("tcp", wc.addr, c.timeout)
+		if err != nil {
+			return nil, fmt.Errorf("dial worker %s: %w", wc.addr, err)
+		}
+		wc.conn, wc.r, wc.w = conn, bufio.NewReader(conn), bufio.NewWriter(conn)
+	}
+	if c.timeout > 0 {
+		wc.conn.SetDeadline(time.Now().Add(c.timeout)) // nolint : errchk
+	}
+
+	_, err := wc.w.Write(req)
+	if err == nil {
+		err = wc.w.Flush()
+	}
+	var payload []byte
+	if err == nil {
+		payload, err = readResponse(wc.r)
+	}
+	if err != nil {
+		if !errors.Is(err, ErrWorker) {
+			wc.conn.Close()
+			wc.conn = nil
+		}
+		return nil, fmt.Errorf("worker %s: %w", wc.addr, err)
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,187 @@
+package cluster
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/frame"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+)
+
+// The cluster protocol is a stream of requests to a worker, each answered by a response
+// in order. The integers are big-endian.
+//
+//	request:  't'
+//	          'l' ch(1B)
+//	response: status(1B) len(4B) payload, of up to frame.MaxSize bytes
+//
+// The payload of a 't' is the top of the trie of the shard:
+//
+//	height(4B) count(2B) { ch(1B) hash(32B) } * count [ value(32B) ]
+//
+// The payload of an 'l' is the leaves under the top-level child ch:
+//
+//	height(4B) count(4B) { nameLen(4B) name hashes(4B) { hash(32B) } * hashes } * count
+//
+// The payload of a statusError is the message.
+const (
+	requestTop    = 't'
+	requestLeaves = 'l'
+
+	statusOK    = 0
+	statusError = 1
+)
+
+// ErrWorker is returned when a worker fails a request.
+var ErrWorker = errors.New("cluster worker")
+
+func encodeTop(height int32, top merkletrie.Top) []byte {
+
+	b := make([]byte, 6, 6+len(top.Children)*(1+chainhash.HashSize)+chainhash.HashSize)
+	binary.BigEndian.PutUint32(b, uint32(height))
+	binary.BigEndian.PutUint16(b[4:], uint16(len(top.Children)))
+	for ch := 0; ch < 256; ch++ {
+		if h := top.Children[byte(ch)]; h != nil {
+			b = append(b, byte(ch))
+			b = append(b, h[:]...)
+		}
+	}
+	if top.Value != nil {
+		b = append(b, top.Value[:]...)
+	}
+
+	return b
+}
+
+func decodeTop(b []byte) (int32, merkletrie.Top, error) {
+
+	top := merkletrie.Top{Children: map[byte]*chainhash.Hash{}}
+	if len(b) < 6 {
+		return 0, top, fmt.Errorf("top of %d bytes", len(b))
+	}
+	height := int32(binary.BigEndian.Uint32(b))
+	count := int(binary.BigEndian.Uint16(b[4:]))
+	b = b[6:]
+	for i := 0; i < count; i++ {
+		if len(b) < 1+chainhash.HashSize {
+			return 0, top, fmt.Errorf("truncated top")
+		}
+		var h chainhash.Hash
+		copy(h[:], b[1:])
+		top.Children[b[0]] = &h
+		b = b[1+chainhash.HashSize:]
+	}
+	switch len(b) {
+	case 0:
+	case chainhash.HashSize:
+		var h chainhash.Hash
+		copy(h[:], b)
+		top.Value = &h
+	default:
+		return 0, top, fmt.Errorf("trailing %d bytes of top", len(b))
+	}
+
+	return height, top, nil
+}
+
+func encodeLeaves(height int32, leaves merkletrie.LeafStore) []byte {
+
+	var b []byte
+	b = appendUint32(b, uint32(height))
+	b = appendUint32(b, uint32(len(leaves)))
+	for name, hs := range leaves {
+		b = appendUint32(b, uint32(len(name)))
+		b = append(b, name...)
+		b = appendUint32(b, uint32(len(hs)))
+		for _, h := range hs {
+			b = append(b, h[:]...)
+		}
+	}
+
+	return b
+}
+
+func decodeLeaves(b []byte) (int32, merkletrie.LeafStore, error) {
+
+	leaves := merkletrie.LeafStore{}
+	next := func(n int) ([]byte, error) {
+		if len(b) < n {
+			return nil, fmt.Errorf("truncated leaves")
+		}
+		v := b[:n]
+		b = b[n:]
+		return v, nil
+	}
+	nextUint32 := func() (int, error) {
+		v, err := next(4)
+		if err != nil {
+			return 0, err
+		}
+		return int(binary.BigEndian.Uint32(v)), nil
+	}
+
+	height, err := nextUint32()
+	if err != nil {
+		return 0, nil, err
+	}
+	count, err := nextUint32()
+	if err != nil {
+		return 0, nil, err
+	}
+	for i := 0; i < count; i++ {
+		n, err := nextUint32()
+		if err != nil {
+			return 0, nil, err
+		}
+		name, err := next(n)
+		if err != nil {
+			return 0, nil, err
+		}
+		n, err = nextUint32()
+		if err != nil {
+			return 0, nil, err
+		}
+		hs := make([]*chainhash.Hash, n)
+		for j := range hs {
+			v, err := next(chainhash.HashSize)
+			if err != nil {
+				return 0, nil, err
+			}
+			hs[j] = new(chainhash.Hash)
+			copy(hs[j][:], v)
+		}
+		leaves[string(name)] = hs
+	}
+	if len(b) > 0 {
+		return 0, nil, fmt.Errorf("trailing %d bytes of leaves", len(b))
+	}
+
+	return int32(height), leaves, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], v)
+	return append(b, n[:]...)
+}
+
+func writeResponse(w *bufio.Writer, status byte, payload []byte) error {
+	if err := frame.Write(w, status, payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func readResponse(r *bufio.Reader) ([]byte, error) {
+	status, payload, err := frame.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if status != statusOK {
+		return nil, fmt.Errorf("%w: %s", ErrWorker, payload)
+	}
+	return payload, nil
+}
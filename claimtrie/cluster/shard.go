@@ -0,0 +1,24 @@
+// Package cluster partitions the names across several ClaimTries, each maintaining its shard
+// of them, and combines the tops of their tries into the root hash of all the names.
+// It's experimental.
+package cluster
+
+import (
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// Shard is one of the partitions of the names by the first bytes of their normalized forms,
+// so the names merged at the normalization fork are in the same one.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// Has reports whether the name belongs to the shard. The empty name belongs to the first one.
+func (s Shard) Has(name []byte) bool {
+	norm := node.Normalize(name)
+	if len(norm) == 0 {
+		return s.Index == 0
+	}
+	return int(norm[0])%s.Count == s.Index
+}
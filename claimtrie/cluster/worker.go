@@ -0,0 +1,83 @@
+package cluster
+
+import (
+	"bufio"
+	"net"
+	"sync"
+
+	"github.com/btcsuite/btcd/claimtrie"
+)
+
+// Worker serves the top of the trie of a ClaimTrie, which maintains the names of its shard,
+// to the Coordinator.
+type Worker struct {
+	mu    sync.Mutex
+	ct    *claimtrie.ClaimTrie
+	shard Shard
+}
+
+// NewWorker returns a Worker of the ClaimTrie, which is only fed the names of the shard.
+func NewWorker(ct *claimtrie.ClaimTrie, shard Shard) *Worker {
+	return &Worker{ct: ct, shard: shard}
+}
+
+// Shard returns the shard of the names maintained by the worker.
+func (w *Worker) Shard() Shard {
+	return w.shard
+}
+
+// Do calls f with the ClaimTrie, while no requests are answered,
+// such as for appending the blocks.
+func (w *Worker) Do(f func(ct *claimtrie.ClaimTrie) error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return f(w.ct)
+}
+
+// Serve answers the requests of the cluster protocol on the connections accepted from l,
+// until l is closed.
+func (w *Worker) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go w.serveConn(conn)
+	}
+}
+
+func (w *Worker) serveConn(conn net.Conn) {
+
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	bw := bufio.NewWriter(conn)
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+
+		var payload []byte
+		switch op {
+		case requestTop:
+			w.mu.Lock()
+			payload = encodeTop(w.ct.Height(), w.ct.Top())
+			w.mu.Unlock()
+		case requestLeaves:
+			ch, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			w.mu.Lock()
+			payload = encodeLeaves(w.ct.Height(), w.ct.Leaves(ch))
+			w.mu.Unlock()
+		default:
+			writeResponse(bw, statusError, []byte("unknown request")) // nolint : errchk
+			return
+		}
+
+		if err = writeResponse(bw, statusOK, payload); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+
+	backupCmd.Flags().StringVar(&backupOut, "out", "", "dir to write the backup to, which must not exist")
+}
+
+var backupOut string
+
+var backupCmd = &cobra.Command{
+	Use:   "backup --out <dir>",
+	Short: "Write a consistent copy of the repos of the ClaimTrie, as of the last block, to the dir",
+	Long: "Write a consistent copy of the repos of the ClaimTrie, as of the last block, to the dir, laid out as in\n" +
+		"the data dir. The repos of a running node are locked by it, which backs them up with the backupclaimtrie\n" +
+		"JSON-RPC command instead, while it keeps running. The backup is restored with backup restore:\n" +
+		"  claimtrie backup --out /backups/claimtrie",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if backupOut == "" {
+			return fmt.Errorf("the dir to write the backup to is required: --out")
+		}
+
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		h, err := ct.Backup(backupOut)
+		if err != nil {
+			return fmt.Errorf("backup: %w", err)
+		}
+		fmt.Printf("Backed up %d repos at height %d, root %s, to %s\n", len(h.Repos), h.Height, h.Root, backupOut)
+
+		return nil
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <dir>",
+	Short: "Restore the repos of the ClaimTrie from the backup in the dir, and verify the root of its last block",
+	Long: "Restore the repos of the ClaimTrie from the backup in the dir, written by backup, or backupclaimtrie,\n" +
+		"to the data dir, which mustn't hold any of them, and verify the trie resolves from the root of the last\n" +
+		"block of the backup:\n" +
+		"  claimtrie backup restore /backups/claimtrie",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		h, err := claimtrie.RestoreBackup(cfg, args[0])
+		if err != nil {
+			return fmt.Errorf("restore backup: %w", err)
+		}
+		fmt.Printf("Restored %d repos at height %d, root %s\n", len(h.Repos), h.Height, h.Root)
+
+		return nil
+	},
+}
@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
 	"strconv"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/claimtrie/block/blockrepo"
 	"github.com/btcsuite/btcd/claimtrie/merkletrie"
 	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
@@ -21,6 +24,7 @@ func init() {
 	blockCmd.AddCommand(blockLastCmd)
 	blockCmd.AddCommand(blockListCmd)
 	blockCmd.AddCommand(blockNameCmd)
+	blockCmd.AddCommand(blockHeightCmd)
 }
 
 var blockCmd = &cobra.Command{
@@ -48,7 +52,7 @@ var blockLastCmd = &cobra.Command{
 			return fmt.Errorf("load changes from repo: %w", err)
 		}
 
-		fmt.Printf("blk %-7d: %s\n", last, hash.String())
+		showBlock(last, hash)
 
 		return nil
 	},
@@ -92,7 +96,7 @@ var blockListCmd = &cobra.Command{
 			if err != nil {
 				return fmt.Errorf("load changes from repo: %w", err)
 			}
-			fmt.Printf("blk %-7d: %s\n", i, hash.String())
+			showBlock(int32(i), hash)
 		}
 
 		return nil
@@ -157,3 +161,41 @@ var blockNameCmd = &cobra.Command{
 		return nil
 	},
 }
+
+var blockHeightCmd = &cobra.Command{
+	Use:   "height <root>",
+	Short: "Show the last height of block with the Merkle Hash",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		hash, err := chainhash.NewHashFromStr(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid args")
+		}
+
+		repo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.BlockRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("can't open block repo: %w", err)
+		}
+		defer repo.Close()
+
+		height, err := repo.HeightForRoot(hash)
+		if errors.Is(err, blockrepo.ErrRootNotFound) {
+			// The heights may have been stored before the index existed.
+			var indexed int
+			indexed, err = repo.ReindexRoots()
+			if err != nil {
+				return fmt.Errorf("reindex roots: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "indexed the roots of %d blocks\n", indexed)
+			height, err = repo.HeightForRoot(hash)
+		}
+		if err != nil {
+			return fmt.Errorf("height for root: %w", err)
+		}
+
+		showBlock(height, hash)
+
+		return nil
+	},
+}
@@ -1,19 +1,27 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"syscall"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/claimtrie"
 	"github.com/btcsuite/btcd/claimtrie/block"
 	"github.com/btcsuite/btcd/claimtrie/block/blockrepo"
+	"github.com/btcsuite/btcd/claimtrie/chain/audit"
+	"github.com/btcsuite/btcd/claimtrie/chain/blockfile"
 	"github.com/btcsuite/btcd/claimtrie/chain/chainrepo"
 	"github.com/btcsuite/btcd/claimtrie/change"
-	"github.com/btcsuite/btcd/claimtrie/config"
-	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
 
 	"github.com/cockroachdb/pebble"
 	"github.com/spf13/cobra"
@@ -24,8 +32,41 @@ func init() {
 
 	chainCmd.AddCommand(chainDumpCmd)
 	chainCmd.AddCommand(chainReplayCmd)
+	chainCmd.AddCommand(chainMigrateCmd)
+	chainCmd.AddCommand(chainVerifyCmd)
+	chainCmd.AddCommand(chainExportCmd)
+	chainCmd.AddCommand(chainImportCmd)
+	chainCmd.AddCommand(chainIngestCmd)
+	chainCmd.AddCommand(chainAuditCmd)
+
+	chainReplayCmd.Flags().BoolVar(&chainRepair, "repair", false, "rebuild the names of a mismatched block and verify again")
+	chainReplayCmd.Flags().Int32Var(&chainReplayFrom, "from", 2, "height to replay from")
+	chainReplayCmd.Flags().Int32Var(&chainReplayTo, "to", math.MaxInt32, "height to replay up to, and including")
+	chainReplayCmd.Flags().Int32Var(&chainCheckpoint, "checkpoint", 1000, "blocks between the checkpoints to resume from (0 to disable)")
+	chainReplayCmd.Flags().BoolVar(&chainRestart, "restart", false, "replay from --from, instead of resuming from the last checkpoint")
+	chainReplayCmd.Flags().StringVar(&chainChangesFile, "changes-from-file", "",
+		"replay the changes of a file written by chain export, without any repos, and print the root at <height>")
+	chainReplayCmd.Flags().StringArrayVar(&chainParams, "param", nil,
+		"override a param, <name>=<value>[@<height>], from the start, or the height, on, replaying into a scratch datadir "+
+			"without verifying the roots; one of "+strings.Join(param.OverridableParams(), ", "))
+	for _, c := range []*cobra.Command{chainExportCmd, chainImportCmd, chainReplayCmd, chainAuditCmd} {
+		c.Flags().BoolVar(&chainProto, "proto", false,
+			"the changes are written, or read, as length-prefixed Block messages of change.proto, instead of COPY rows")
+	}
 }
 
+var (
+	chainRepair      bool
+	chainChangesFile string
+	chainProto       bool
+	chainParams      []string
+
+	chainReplayFrom int32
+	chainReplayTo   int32
+	chainCheckpoint int32
+	chainRestart    bool
+)
+
 var chainCmd = &cobra.Command{
 	Use:   "chain",
 	Short: "chain related command",
@@ -76,55 +117,325 @@ var chainDumpCmd = &cobra.Command{
 	},
 }
 
-var chainReplayCmd = &cobra.Command{
-	Use:   "replay <height>",
-	Short: "Replay the chain up to <height>",
-	Args:  cobra.RangeArgs(0, 1),
+var chainMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Backfill sequence numbers of changes recorded before they were introduced",
+	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		fmt.Printf("not working until we pass record flag to claimtrie\n")
+		chainRepo, err := chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open chain repo: %w", err)
+		}
+		defer chainRepo.Close()
 
-		fromHeight := 2
-		toHeight := int(math.MaxInt32)
+		migrated, err := chainRepo.BackfillSeq()
+		if err != nil {
+			return fmt.Errorf("backfill sequence numbers: %w", err)
+		}
+
+		fmt.Printf("Migrated %d blocks\n", migrated)
+
+		return nil
+	},
+}
+
+var chainVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the digest chain of the recorded changes",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		chainRepo, err := chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open chain repo: %w", err)
+		}
+		defer chainRepo.Close()
+
+		last, err := chainRepo.VerifyDigests()
+		if err != nil {
+			return fmt.Errorf("verify digests after height %d: %w", last, err)
+		}
+
+		fmt.Printf("Verified up to height %d\n", last)
+
+		return nil
+	},
+}
+
+var chainExportCmd = &cobra.Command{
+	Use:   "export <fromHeight> [<toHeight>]",
+	Short: "Write changes from <fromHeight> to [<toHeight>] to stdout, in the text format of Postgres COPY",
+	Long: "Write changes from <fromHeight> to [<toHeight>] to stdout, in the text format of Postgres COPY:\n" +
+		"  claimtrie chain export 0 | psql -c 'COPY changes FROM STDIN'\n\n" + chainrepo.CopyTable + "\n\n" +
+		"Or as JSON lines, with --format jsonl, or as length-prefixed Block messages of change.proto, with --proto.",
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		fromHeight, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid args")
+		}
+
+		toHeight := math.MaxInt32
+		if len(args) == 2 {
+			toHeight, err = strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid args")
+			}
+		}
+
+		chainRepo, err := chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open chain repo: %w", err)
+		}
+		defer chainRepo.Close()
+
+		var rows int
+		if chainProto {
+			rows, err = chainRepo.ExportProto(os.Stdout, int32(fromHeight), int32(toHeight))
+		} else if outputFormat == formatJSONL {
+			err = chainRepo.IterateBlocks(int32(fromHeight), int32(toHeight), func(height int32, changes []change.Change) error {
+				for _, chg := range changes {
+					if err := jsonOut.Encode(newJSONChange(chg)); err != nil {
+						return fmt.Errorf("write change: %w", err)
+					}
+					rows++
+				}
+				return nil
+			})
+		} else {
+			rows, err = chainRepo.ExportCopy(os.Stdout, int32(fromHeight), int32(toHeight))
+		}
+		if err != nil {
+			return fmt.Errorf("export changes: %w", err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Exported %d changes\n", rows)
+
+		return nil
+	},
+}
+
+var chainImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Save changes read from stdin, in the text format of Postgres COPY",
+	Long: "Save changes read from stdin, in the text format of Postgres COPY:\n" +
+		"  psql -c 'COPY (SELECT * FROM changes ORDER BY height, seq) TO STDOUT' | claimtrie chain import",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		chainRepo, err := chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open chain repo: %w", err)
+		}
+		defer chainRepo.Close()
+
+		importChanges := chainRepo.ImportCopy
+		if chainProto {
+			importChanges = chainRepo.ImportProto
+		}
+		rows, err := importChanges(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("import changes: %w", err)
+		}
+
+		fmt.Printf("Imported %d changes\n", rows)
+
+		return nil
+	},
+}
+
+var chainAuditCmd = &cobra.Command{
+	Use:   "audit [<changes file>]",
+	Short: "Report the supports added twice, the ones outliving their claims, and the claims spent twice, in the changes",
+	Long: "Report the supports added at the outpoints of the ones not spent yet, the supports left once their claims are\n" +
+		"spent, and the claims spent again by later blocks, in the changes of the chain repo, or of the file written by\n" +
+		"chain export, such as the dump of an upstream feed, before replaying it. It fails, if there are any:\n" +
+		"  claimtrie chain audit --format jsonl changes.tsv > findings.jsonl",
+	Args: cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		d := audit.NewDetector()
+		block := func(height int32, changes []change.Change) error {
+			for _, f := range d.Block(height, changes) {
+				showAuditFinding(f)
+			}
+			return nil
+		}
 
 		var err error
 		if len(args) == 1 {
-			toHeight, err = strconv.Atoi(args[0])
+			var f *os.File
+			f, err = os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("open changes file: %w", err)
+			}
+			defer f.Close()
+			readChanges := chainrepo.ReadCopy
+			if chainProto {
+				readChanges = chainrepo.ReadProto
+			}
+			_, err = readChanges(f, block)
+		} else {
+			var chainRepo *chainrepo.Pebble
+			chainRepo, err = chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
+			if err != nil {
+				return fmt.Errorf("open chain repo: %w", err)
+			}
+			defer chainRepo.Close()
+			err = chainRepo.IterateBlocks(0, math.MaxInt32, block)
+		}
+		if err != nil {
+			return fmt.Errorf("read changes: %w", err)
+		}
+
+		if n := len(d.Findings()); n > 0 {
+			return fmt.Errorf("found %d anomalies in the changes", n)
+		}
+		fmt.Fprintln(os.Stderr, "No anomalies found")
+
+		return nil
+	},
+}
+
+func showAuditFinding(f audit.Finding) {
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(f) // nolint : errchk
+		return
+	}
+	fmt.Printf("%6d/%-4d %-22s %s, claim %s, of %q, after %d\n", f.Height, f.Seq, f.Kind, f.OutPoint, f.ClaimID, f.Name, f.PrevHeight)
+}
+
+var chainIngestCmd = &cobra.Command{
+	Use:   "ingest <blocksDir> [<toHeight>]",
+	Short: "Save the changes of the main chain read from the block files of a node, such as ~/.lbrycrd/blocks",
+	Long: "Save the changes of the main chain read from the block files of a node, the blk*.dat of lbrycrd,\n" +
+		"or the *.fdb of btcd, up to <toHeight>, without a running node. The node must not be writing them.",
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		toHeight := int(math.MaxInt32)
+		if len(args) == 2 {
+			var err error
+			toHeight, err = strconv.Atoi(args[1])
 			if err != nil {
 				return fmt.Errorf("invalid args")
 			}
 		}
 
-		err = os.RemoveAll(filepath.Join(cfg.DataDir, cfg.NodeRepoPebble.Path))
+		chainRepo, err := chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
 		if err != nil {
-			return fmt.Errorf("delete node repo: %w", err)
+			return fmt.Errorf("open chain repo: %w", err)
 		}
+		defer chainRepo.Close()
 
-		fmt.Printf("Deleted node repo\n")
+		idx, err := blockfile.Open(args[0], netParams)
+		if err != nil {
+			return fmt.Errorf("index block files: %w", err)
+		}
+		defer idx.Close()
+
+		height, err := blockfile.Ingest(idx, chainRepo, int32(toHeight), func(height int32) {
+			if height%1000 == 0 {
+				fmt.Printf("block: %d\n", height)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("ingest block files: %w", err)
+		}
+
+		fmt.Printf("Ingested the changes of the blocks up to %d\n", height)
+
+		return nil
+	},
+}
+
+var chainReplayCmd = &cobra.Command{
+	Use:   "replay [<height>]",
+	Short: "Replay the chain from --from up to --to, or <height>, resuming from the last checkpoint",
+	Long: "Replay the changes of the chain repo from --from up to --to, verifying each root against the reported one,\n" +
+		"and checkpointing the ClaimTrie every --checkpoint blocks. A replay stopped by a crash, or a mismatched root,\n" +
+		"resumes from its last checkpoint, unless --restart is set, and one interrupted, by SIGINT or SIGTERM, finishes\n" +
+		"the block being appended, and checkpoints it, before exiting. The stored state behind --from is caught up\n" +
+		"first, while the one ahead of --to, or of the last checkpoint, is reset:\n" +
+		"  claimtrie chain replay --from 2 --to 1000000 --checkpoint 10000",
+	Args: cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		fromHeight := chainReplayFrom
+		toHeight := chainReplayTo
+
+		if len(args) == 1 {
+			height, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid args")
+			}
+			toHeight = int32(height)
+		}
+		if fromHeight < 2 || fromHeight > toHeight {
+			return fmt.Errorf("invalid range: %d to %d", fromHeight, toHeight)
+		}
+
+		overrides, err := parseParamOverrides(chainParams)
+		if err != nil {
+			return err
+		}
+		if chainChangesFile != "" {
+			return replayChangesFile(chainChangesFile, toHeight, overrides)
+		}
+		if len(overrides) > 0 {
+			return replayWithOverrides(toHeight, overrides)
+		}
 
 		chainRepo, err := chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
 		if err != nil {
 			return fmt.Errorf("open change repo: %w", err)
 		}
+		defer chainRepo.Close()
 
 		reportedBlockRepo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ReportedBlockRepoPebble.Path))
 		if err != nil {
 			return fmt.Errorf("open block repo: %w", err)
 		}
+		defer reportedBlockRepo.Close()
+
+		// An interrupt is held off until the ClaimTrie is at a block, reset to, or appended.
+		ctx, stop := notifyInterrupt()
+		defer stop()
 
-		cfg := config.DefaultConfig
 		ct, err := claimtrie.New(cfg)
 		if err != nil {
 			return fmt.Errorf("create claimtrie: %w", err)
 		}
 		defer ct.Close()
+		ct.SetProgress(showProgress)
+
+		last, err := reportedBlockRepo.Load()
+		if err != nil {
+			return fmt.Errorf("load reported height: %w", err)
+		}
+		if toHeight > last {
+			toHeight = last
+		}
 
-		err = ct.ResetHeight(int32(fromHeight - 1))
+		start, err := resumeReplay(ct, reportedBlockRepo, fromHeight, toHeight)
 		if err != nil {
-			return fmt.Errorf("reset claimtrie height: %w", err)
+			return err
 		}
 
-		for height := int32(fromHeight); height < int32(toHeight); height++ {
+		rate := newBlockRate(start-1, toHeight)
+		for height := start; height <= toHeight; height++ {
+
+			if ctx.Err() != nil {
+				stop() // so that a second one kills it, while it's flushed
+				err = checkpointReplay(ct)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Interrupted, checkpointed at %d, root: %s, which the replay resumes from\n", ct.Height(), ct.MerkleHash())
+				return nil
+			}
 
 			changes, err := chainRepo.Load(height)
 			if err == pebble.ErrNotFound {
@@ -134,50 +445,312 @@ var chainReplayCmd = &cobra.Command{
 			}
 
 			for _, chg := range changes {
-				claimID, _ := node.NewIDFromString(chg.ClaimID)
+				err = applyChange(ct, chg)
+				if err != nil {
+					return fmt.Errorf("execute change %d of block %d: %w", chg.Seq, height, change.Wrap(err, chg))
+				}
+			}
+			err = appendBlock(ct, reportedBlockRepo)
+			if err != nil {
+				return err
+			}
+			rate.appended(ct.Height())
+			if chainCheckpoint > 0 && ct.Height()%chainCheckpoint == 0 {
+				err = checkpointReplay(ct)
+				if err != nil {
+					return err
+				}
+			}
+		}
 
-				switch chg.Type {
-				case change.AddClaim:
-					op := *node.NewOutPointFromString(chg.OutPoint)
-					err = ct.AddClaim(chg.Name, op, claimID, chg.Amount, chg.Value)
+		err = checkpointReplay(ct)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Replayed up to %d, root: %s\n", ct.Height(), ct.MerkleHash())
 
-				case change.UpdateClaim:
-					op := *node.NewOutPointFromString(chg.OutPoint)
-					err = ct.UpdateClaim(chg.Name, op, chg.Amount, claimID, chg.Value)
+		return nil
+	},
+}
 
-				case change.SpendClaim:
-					op := *node.NewOutPointFromString(chg.OutPoint)
-					err = ct.SpendClaim(chg.Name, op, claimID)
+// replayCheckpointFile is written to the data dir by the replay, with the last height, and root, it verified.
+const replayCheckpointFile = "claimtrie_replay"
 
-				case change.AddSupport:
-					op := *node.NewOutPointFromString(chg.OutPoint)
-					claimID, _ := node.NewIDFromString(chg.ClaimID)
-					id := claimID
-					err = ct.AddSupport(chg.Name, chg.Value, op, chg.Amount, id)
+// resumeReplay resets the ClaimTrie to the height the replay of the range resumes after, and returns the next one.
+// It's the last checkpoint, or the stored height, if there's none, unless they're ahead of toHeight, or restarting,
+// when it's the one before fromHeight, and the checkpoint is dropped. The root at the height must match the reported one.
+func resumeReplay(ct *claimtrie.ClaimTrie, reported block.Repo, fromHeight, toHeight int32) (int32, error) {
 
-				case change.SpendSupport:
-					op := *node.NewOutPointFromString(chg.OutPoint)
-					err = ct.SpendSupport(chg.Name, op, claimID)
+	height := ct.Height()
+	cp, root, err := readReplayCheckpoint()
+	if err != nil {
+		return 0, err
+	}
+	if cp > 0 && cp < height {
+		fmt.Printf("Resetting to the last checkpoint at %d, from %d\n", cp, height)
+		height = cp
+	}
+	if chainRestart || height > toHeight {
+		height = fromHeight - 1
+		err = os.RemoveAll(filepath.Join(cfg.DataDir, replayCheckpointFile))
+		if err != nil {
+			return 0, fmt.Errorf("remove replay checkpoint: %w", err)
+		}
+		cp = 0
+	}
+	if height != ct.Height() {
+		err = ct.ResetHeight(height)
+		if err != nil {
+			return 0, fmt.Errorf("reset claimtrie height: %w", err)
+		}
+	}
+	if cp > 0 && cp == height && *ct.MerkleHash() != root {
+		return 0, fmt.Errorf("root at the checkpoint %d: exp: %s, got: %s", cp, root, ct.MerkleHash())
+	}
 
-				default:
-					err = fmt.Errorf("invalid change: %v", chg)
-				}
+	if height > 1 {
+		hash, err := reported.Get(height)
+		if err != nil {
+			return 0, fmt.Errorf("load from block repo: %w", err)
+		}
+		if *ct.MerkleHash() != *hash {
+			return 0, fmt.Errorf("the stored state mismatched at height %d, rerun with --restart: exp: %s, got: %s",
+				height, hash, ct.MerkleHash())
+		}
+	}
+	if height < fromHeight-1 {
+		fmt.Printf("Catching up from %d, before %d\n", height+1, fromHeight)
+	} else if height >= fromHeight {
+		fmt.Printf("Resuming from %d\n", height+1)
+	}
 
-				if err != nil {
-					return fmt.Errorf("execute change %v: %w", chg, err)
-				}
+	return height + 1, nil
+}
+
+// checkpointReplay flushes the ClaimTrie, and records its height and root, which are verified, to resume from.
+func checkpointReplay(ct *claimtrie.ClaimTrie) error {
+
+	err := ct.Flush()
+	if err != nil {
+		return fmt.Errorf("flush claimtrie: %w", err)
+	}
+
+	path := filepath.Join(cfg.DataDir, replayCheckpointFile)
+	err = os.WriteFile(path+".tmp", []byte(fmt.Sprintf("height=%d root=%s\n", ct.Height(), ct.MerkleHash())), 0644)
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		return fmt.Errorf("write replay checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// notifyInterrupt returns a context, which is done once the process is sent SIGINT, or SIGTERM, rather than
+// killed by it, so that the replay stops after the block being appended, and the func restoring the default.
+func notifyInterrupt() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// readReplayCheckpoint returns the height and root of the last checkpoint, or 0, if there's none.
+func readReplayCheckpoint() (int32, chainhash.Hash, error) {
+
+	b, err := os.ReadFile(filepath.Join(cfg.DataDir, replayCheckpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, chainhash.Hash{}, nil
+	}
+	if err != nil {
+		return 0, chainhash.Hash{}, fmt.Errorf("read replay checkpoint: %w", err)
+	}
+
+	var height int32
+	var root string
+	_, err = fmt.Sscanf(string(b), "height=%d root=%s\n", &height, &root)
+	if err != nil {
+		return 0, chainhash.Hash{}, fmt.Errorf("invalid replay checkpoint: %q: %w", strings.TrimSpace(string(b)), err)
+	}
+	hash, err := chainhash.NewHashFromStr(root)
+	if err != nil {
+		return 0, chainhash.Hash{}, fmt.Errorf("invalid replay checkpoint root: %w", err)
+	}
+
+	return height, *hash, nil
+}
+
+// parseParamOverrides parses the param overrides, and applies the ones from the start on.
+// It returns the rest, in order by height, which are applied as the replay reaches them.
+func parseParamOverrides(args []string) ([]param.Override, error) {
+
+	var overrides []param.Override
+	for _, arg := range args {
+		o, err := param.ParseOverride(arg)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Overriding %s\n", o)
+		if o.Height <= 1 {
+			o.Apply()
+			continue
+		}
+		overrides = append(overrides, o)
+	}
+	sort.SliceStable(overrides, func(i, j int) bool { return overrides[i].Height < overrides[j].Height })
+
+	return overrides, nil
+}
+
+// applyParamOverrides applies the overrides up to the height, and returns the rest.
+func applyParamOverrides(overrides []param.Override, height int32) []param.Override {
+	for len(overrides) > 0 && overrides[0].Height <= height {
+		overrides[0].Apply()
+		overrides = overrides[1:]
+	}
+	return overrides
+}
+
+// replayWithOverrides replays the changes of the blocks up to the height, from the chain repo, into a ClaimTrie
+// in a temporary directory, with the params overridden, and prints its root. The roots aren't verified against
+// the ones reported, as they're expected to diverge.
+func replayWithOverrides(toHeight int32, overrides []param.Override) error {
+
+	chainRepo, err := chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
+	if err != nil {
+		return fmt.Errorf("open chain repo: %w", err)
+	}
+	defer chainRepo.Close()
+
+	return replayScratch(toHeight, overrides, func(f func(height int32, changes []change.Change) error) error {
+		if toHeight == math.MaxInt32 {
+			return chainRepo.IterateBlocks(1, toHeight, f)
+		}
+		return chainRepo.IterateBlocks(1, toHeight+1, f)
+	})
+}
+
+// replayChangesFile replays the changes of the blocks up to the height, read from a file in
+// the text format of Postgres COPY, into a ClaimTrie in a temporary directory, and prints its root.
+// The file is the only input, so that it's all that's shared to reproduce a mismatched root.
+func replayChangesFile(path string, toHeight int32, overrides []param.Override) error {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open changes file: %w", err)
+	}
+	defer f.Close()
+
+	readChanges := chainrepo.ReadCopy
+	if chainProto {
+		readChanges = chainrepo.ReadProto
+	}
+
+	return replayScratch(toHeight, overrides, func(fn func(height int32, changes []change.Change) error) error {
+		_, err := readChanges(f, fn)
+		return err
+	})
+}
+
+// replayScratch replays the changes of the blocks passed by iterate, in order, up to the height, into a ClaimTrie
+// in a temporary directory, and prints its root. The overrides are applied as the replay reaches their heights.
+func replayScratch(toHeight int32, overrides []param.Override,
+	iterate func(f func(height int32, changes []change.Change) error) error) error {
+
+	dir, err := os.MkdirTemp("", "claimtrie-replay")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctCfg := cfg
+	ctCfg.DataDir = dir
+	ct, err := claimtrie.New(ctCfg)
+	if err != nil {
+		return fmt.Errorf("create claimtrie: %w", err)
+	}
+	defer ct.Close()
+	ct.SetProgress(showProgress)
+
+	// The scratch dir is removed once interrupted, as there's nothing to resume.
+	ctx, stop := notifyInterrupt()
+	defer stop()
+	rate := newBlockRate(0, toHeight)
+
+	// The blocks without changes aren't exported, but they still expire and activate claims.
+	appendBlocksTo := func(height int32) error {
+		for ct.Height() < height {
+			if ctx.Err() != nil {
+				return fmt.Errorf("interrupted at %d", ct.Height())
 			}
-			err = appendBlock(ct, reportedBlockRepo)
+			overrides = applyParamOverrides(overrides, ct.Height()+1)
+			err := ct.AppendBlock()
 			if err != nil {
-				return err
+				return fmt.Errorf("append block %d: %w", ct.Height()+1, err)
 			}
-			if ct.Height()%1000 == 0 {
-				fmt.Printf("block: %d\n", ct.Height())
+			rate.appended(ct.Height())
+		}
+		return nil
+	}
+
+	errReached := errors.New("height reached")
+	err = iterate(func(height int32, changes []change.Change) error {
+		if height > toHeight {
+			return errReached
+		}
+		if height <= ct.Height() {
+			return fmt.Errorf("changes of block %d after block %d", height, ct.Height())
+		}
+		err := appendBlocksTo(height - 1)
+		if err != nil {
+			return err
+		}
+		overrides = applyParamOverrides(overrides, height)
+		for _, chg := range changes {
+			err = applyChange(ct, chg)
+			if err != nil {
+				return fmt.Errorf("execute change %d of block %d: %w", chg.Seq, height, change.Wrap(err, chg))
 			}
 		}
+		return appendBlocksTo(height)
+	})
+	if err != nil && !errors.Is(err, errReached) {
+		return fmt.Errorf("replay changes: %w", err)
+	}
 
-		return nil
-	},
+	if toHeight != math.MaxInt32 {
+		err = appendBlocksTo(toHeight)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("height: %d, root: %s\n", ct.Height(), ct.MerkleHash())
+
+	return nil
+}
+
+func applyChange(ct *claimtrie.ClaimTrie, chg change.Change) error {
+
+	claimID := chg.ClaimID
+	op := chg.OutPoint.Wire()
+
+	switch chg.Type {
+	case change.AddClaim:
+		return ct.AddClaim(chg.Name, op, claimID, chg.Amount, chg.Value)
+
+	case change.UpdateClaim:
+		return ct.UpdateClaim(chg.Name, op, chg.Amount, claimID, chg.Value)
+
+	case change.SpendClaim:
+		return ct.SpendClaim(chg.Name, op, claimID)
+
+	case change.AddSupport:
+		return ct.AddSupport(chg.Name, chg.Value, op, chg.Amount, claimID)
+
+	case change.SpendSupport:
+		return ct.SpendSupport(chg.Name, op, claimID)
+	}
+
+	return fmt.Errorf("invalid change: %v", chg)
 }
 
 func appendBlock(ct *claimtrie.ClaimTrie, blockRepo block.Repo) error {
@@ -194,9 +767,20 @@ func appendBlock(ct *claimtrie.ClaimTrie, blockRepo block.Repo) error {
 		return fmt.Errorf("load from block repo: %w", err)
 	}
 
-	if *ct.MerkleHash() != *hash {
-		return fmt.Errorf("hash mismatched at height %5d: exp: %s, got: %s", height, hash, ct.MerkleHash())
+	if *ct.MerkleHash() == *hash {
+		return nil
 	}
 
-	return nil
+	if chainRepair {
+		fmt.Printf("hash mismatched at height %5d, repairing...\n", height)
+		repaired, err := ct.Repair()
+		if err != nil {
+			return fmt.Errorf("repair: %w", err)
+		}
+		if *repaired == *hash {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("hash mismatched at height %5d: exp: %s, got: %s", height, hash, ct.MerkleHash())
 }
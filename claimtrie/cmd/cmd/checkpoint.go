@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	checkpointCmd.AddCommand(checkpointSignCmd)
+	checkpointCmd.AddCommand(checkpointPublishCmd)
+	checkpointCmd.AddCommand(checkpointRestoreCmd)
+	rootCmd.AddCommand(checkpointCmd)
+}
+
+var checkpointCmd = &cobra.Command{
+	Use:   "checkpoint",
+	Short: "Sign, publish and restore the trie checkpoints",
+}
+
+var checkpointSignCmd = &cobra.Command{
+	Use:   "sign <height> <key file>",
+	Short: "Sign the manifest of the checkpoint at the height with the hex encoded private key in the file",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		height, err := parseHeight(args[0])
+		if err != nil {
+			return err
+		}
+		b, err := os.ReadFile(args[1])
+		if err != nil {
+			return fmt.Errorf("read key: %w", err)
+		}
+		b, err = hex.DecodeString(strings.TrimSpace(string(b)))
+		if err != nil {
+			return fmt.Errorf("decode key: %w", err)
+		}
+		key, _ := btcec.PrivKeyFromBytes(btcec.S256(), b)
+
+		path, base, err := merkletrierepo.FindCheckpoint(checkpointDir(), height)
+		if err != nil {
+			return err
+		}
+		m, err := merkletrierepo.SignCheckpoint(path, height, base, key)
+		if err != nil {
+			return fmt.Errorf("sign checkpoint: %w", err)
+		}
+		fmt.Printf("Signed %d files of the checkpoint at %d, root %s, with %x\n",
+			len(m.Files), height, m.Root, key.PubKey().SerializeCompressed())
+
+		return nil
+	},
+}
+
+var checkpointPublishCmd = &cobra.Command{
+	Use:   "publish <height> <dir> <public key>",
+	Short: "Copy the checkpoint at the height, and the full one it applies to, into the dir, once verified against the key",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		height, err := parseHeight(args[0])
+		if err != nil {
+			return err
+		}
+		key, err := parsePubKey(args[2])
+		if err != nil {
+			return err
+		}
+
+		path, base, err := merkletrierepo.FindCheckpoint(checkpointDir(), height)
+		if err != nil {
+			return err
+		}
+		paths := []string{path}
+		if base != 0 {
+			paths = append(paths, merkletrierepo.CheckpointName(checkpointDir(), base))
+		}
+
+		err = os.MkdirAll(args[1], 0755)
+		if err != nil {
+			return fmt.Errorf("make publish dir: %w", err)
+		}
+		for _, p := range paths {
+			if _, err = os.Stat(filepath.Join(args[1], filepath.Base(p))); err == nil {
+				continue // the full checkpoint is published already along with another delta
+			}
+			_, err = merkletrierepo.VerifyCheckpoint(p, key)
+			if err != nil {
+				return fmt.Errorf("verify %s: %w", p, err)
+			}
+			err = merkletrierepo.CopyCheckpoint(p, args[1])
+			if err != nil {
+				return fmt.Errorf("copy %s: %w", p, err)
+			}
+			fmt.Printf("Published %s\n", filepath.Base(p))
+		}
+
+		return nil
+	},
+}
+
+var checkpointRestoreCmd = &cobra.Command{
+	Use:   "restore <dir> <height> <public key>",
+	Short: "Restore the trie repo from the checkpoint at the height in the dir, once verified against the key",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		height, err := parseHeight(args[1])
+		if err != nil {
+			return err
+		}
+		key, err := parsePubKey(args[2])
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(cfg.DataDir, cfg.MerkleTrieRepoPebble.Path)
+		m, err := merkletrierepo.RestoreCheckpoint(args[0], height, path, key)
+		if err != nil {
+			return fmt.Errorf("restore checkpoint: %w", err)
+		}
+		fmt.Printf("Restored the trie repo at %d, root %s\n", m.Height, m.Root)
+
+		return nil
+	},
+}
+
+func checkpointDir() string {
+	return filepath.Join(cfg.DataDir, cfg.TrieCheckpointPath)
+}
+
+func parseHeight(arg string) (int32, error) {
+	height, err := strconv.ParseInt(arg, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid height: %w", err)
+	}
+	return int32(height), nil
+}
+
+func parsePubKey(arg string) (*btcec.PublicKey, error) {
+	b, err := hex.DecodeString(arg)
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %w", err)
+	}
+	key, err := btcec.ParsePubKey(b, btcec.S256())
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/btcsuite/btcd/claimtrie"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(claimsCmd)
+
+	claimsCmd.AddCommand(claimsExportCmd)
+
+	claimsExportCmd.Flags().StringVar(&claimsOrder, "order", claimtrie.ExportByClaimID,
+		"order of the claims: "+claimtrie.ExportByClaimID+", or "+claimtrie.ExportByName)
+}
+
+var claimsOrder string
+
+var claimsCmd = &cobra.Command{
+	Use:     "claims",
+	Aliases: []string{"claim"},
+	Short:   "Claims related commands",
+}
+
+var claimsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the claims of all the names to stdout, sorted by claim ID, or name, after an index of them",
+	Long: "Write the unspent claims of all the names to stdout, sorted by claim ID, or name, after an index of the\n" +
+		"offsets of the rows by the first byte of their keys. The rows past the index are in the text format of Postgres COPY:\n" +
+		"  claimtrie claims export > claims.tsv\n" +
+		"  tail -n +259 claims.tsv | psql -c 'COPY claims FROM STDIN'\n\n" + claimtrie.ExportCopyTable,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		rows, err := ct.Snapshot().ExportClaims(context.Background(), os.Stdout, claimsOrder)
+		if err != nil {
+			return fmt.Errorf("export claims: %w", err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Exported %d claims\n", rows)
+
+		return nil
+	},
+}
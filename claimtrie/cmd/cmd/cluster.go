@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/block/blockrepo"
+	"github.com/btcsuite/btcd/claimtrie/chain/chainrepo"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/cluster"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(clusterCmd)
+
+	clusterCmd.AddCommand(clusterWorkerCmd)
+	clusterCmd.AddCommand(clusterRootCmd)
+
+	clusterWorkerCmd.Flags().StringVar(&clusterListen, "listen", "127.0.0.1:9250", "address to serve the coordinator on")
+	clusterWorkerCmd.Flags().DurationVar(&clusterPoll, "poll", 10*time.Second, "interval of checking the chain repo for new blocks")
+	clusterRootCmd.Flags().DurationVar(&clusterTimeout, "timeout", 30*time.Second, "timeout of each request to the workers")
+}
+
+var (
+	clusterListen  string
+	clusterPoll    time.Duration
+	clusterTimeout time.Duration
+)
+
+var clusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "Experimental: shard the names across several claimtrie processes",
+}
+
+var clusterWorkerCmd = &cobra.Command{
+	Use:   "worker <index> <count>",
+	Short: "Maintain the names of shard <index> of <count> from the chain repo, and serve the top of their trie",
+	Long: "Maintain the names of shard <index> of <count> from the chain repo, and serve the top of their trie.\n" +
+		"The ClaimTrie of the shard is kept in the shard-<index> directory of the data dir.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		index, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid args")
+		}
+		count, err := strconv.Atoi(args[1])
+		if err != nil || count < 1 || index < 0 || index >= count {
+			return fmt.Errorf("invalid args")
+		}
+		shard := cluster.Shard{Index: index, Count: count}
+
+		chainRepo, err := chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open chain repo: %w", err)
+		}
+		defer chainRepo.Close()
+
+		reportedBlockRepo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ReportedBlockRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open block repo: %w", err)
+		}
+		defer reportedBlockRepo.Close()
+
+		shardCfg := cfg
+		shardCfg.DataDir = filepath.Join(cfg.DataDir, fmt.Sprintf("shard-%d", index))
+		ct, err := claimtrie.New(shardCfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		w := cluster.NewWorker(ct, shard)
+		l, err := net.Listen("tcp", clusterListen)
+		if err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+		defer l.Close()
+		go w.Serve(l) // nolint : errchk
+
+		for {
+			tip, err := reportedBlockRepo.Load()
+			if err != nil {
+				return fmt.Errorf("load block repo: %w", err)
+			}
+			err = w.Do(func(ct *claimtrie.ClaimTrie) error {
+				for height := ct.Height() + 1; height <= tip; height++ {
+					err := appendShardBlock(ct, shard, chainRepo, height)
+					if err != nil {
+						return err
+					}
+					if height%1000 == 0 {
+						fmt.Printf("block: %d\n", height)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			time.Sleep(clusterPoll)
+		}
+	},
+}
+
+// appendShardBlock appends the block at the height with the changes of the names of the shard.
+func appendShardBlock(ct *claimtrie.ClaimTrie, shard cluster.Shard, chainRepo *chainrepo.Pebble, height int32) error {
+
+	changes, err := chainRepo.Load(height)
+	if err != nil && err != pebble.ErrNotFound {
+		return fmt.Errorf("load from change repo: %w", err)
+	}
+	for _, chg := range changes {
+		if !shard.Has(chg.Name) {
+			continue
+		}
+		err = applyChange(ct, chg)
+		if err != nil {
+			return fmt.Errorf("execute change %d of block %d: %w", chg.Seq, height, change.Wrap(err, chg))
+		}
+	}
+
+	err = ct.AppendBlock()
+	if err != nil {
+		return fmt.Errorf("append block: %w", err)
+	}
+
+	return nil
+}
+
+var clusterRootCmd = &cobra.Command{
+	Use:   "root <worker address>...",
+	Short: "Combine the root hash of all the names from the workers, one per shard, and compare it with the reported one",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		c := cluster.NewCoordinator(args, clusterTimeout)
+		defer c.Close()
+
+		height, root, err := c.Root()
+		if err != nil {
+			return fmt.Errorf("combine root: %w", err)
+		}
+		fmt.Printf("height: %d, root: %s\n", height, root)
+
+		reportedBlockRepo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ReportedBlockRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open block repo: %w", err)
+		}
+		defer reportedBlockRepo.Close()
+
+		reported, err := reportedBlockRepo.Get(height)
+		if err != nil {
+			return fmt.Errorf("load from block repo: %w", err)
+		}
+		if !reported.IsEqual(root) {
+			return fmt.Errorf("root mismatched at height %d, reported: %s", height, reported)
+		}
+
+		return nil
+	},
+}
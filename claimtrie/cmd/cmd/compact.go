@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+	"github.com/btcsuite/btcd/claimtrie/node/noderepo"
+	"github.com/btcsuite/btcd/claimtrie/progress"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(compactCmd)
+}
+
+var compactCmd = &cobra.Command{
+	Use:   "compact",
+	Short: "Compact the trie and node repos",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		nodeRepo, err := noderepo.NewPebble(filepath.Join(cfg.DataDir, cfg.NodeRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open node repo: %w", err)
+		}
+		defer nodeRepo.Close()
+
+		start := time.Now()
+		meter := progress.Start("compact node", showProgress)
+		err = nodeRepo.Compact()
+		meter.Stop()
+		if err != nil {
+			return fmt.Errorf("compact node repo: %w", err)
+		}
+		fmt.Printf("Compacted the node repo in %s\n", time.Since(start))
+
+		trieRepo, err := merkletrierepo.NewPebble(filepath.Join(cfg.DataDir, cfg.MerkleTrieRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open trie repo: %w", err)
+		}
+		defer trieRepo.Close()
+
+		start = time.Now()
+		meter = progress.Start("compact trie", showProgress)
+		err = trieRepo.Compact()
+		meter.Stop()
+		if err != nil {
+			return fmt.Errorf("compact trie repo: %w", err)
+		}
+		fmt.Printf("Compacted the trie repo in %s\n", time.Since(start))
+
+		return nil
+	},
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/chain/chainrepo"
+	"github.com/btcsuite/btcd/claimtrie/crosscheck"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(crosscheckCmd)
+
+	crosscheckCmd.Flags().StringVar(&crosscheckRPC, "rpc", "http://127.0.0.1:9245", "JSON-RPC URL of the lbrycrd node")
+	crosscheckCmd.Flags().StringVar(&crosscheckUser, "rpcuser", "", "rpcuser of the lbrycrd node")
+	crosscheckCmd.Flags().StringVar(&crosscheckPass, "rpcpass", "", "rpcpassword of the lbrycrd node")
+	crosscheckCmd.Flags().Int32Var(&crosscheckFrom, "from", 1, "height to compare from")
+	crosscheckCmd.Flags().Int32Var(&crosscheckTo, "to", 0, "height to compare up to, and including (default the last block)")
+	crosscheckCmd.Flags().StringArrayVar(&crosscheckNames, "name", nil,
+		"name to compare at every height, besides the ones changed at it")
+}
+
+var (
+	crosscheckRPC   string
+	crosscheckUser  string
+	crosscheckPass  string
+	crosscheckFrom  int32
+	crosscheckTo    int32
+	crosscheckNames []string
+)
+
+type jsonDivergence struct {
+	Height  int32  `json:"height"`
+	Name    string `json:"name,omitempty"`
+	ClaimID string `json:"claimId,omitempty"`
+	Field   string `json:"field"`
+	Local   string `json:"local"`
+	Remote  string `json:"remote"`
+}
+
+var crosscheckCmd = &cobra.Command{
+	Use:   "crosscheck --rpc <url> --from <height> --to <height>",
+	Short: "Compare the ClaimTrie against a running lbrycrd node, and report the divergences",
+	Long: "Compare the roots of the blocks in the range against the ones of a running lbrycrd node, over its JSON-RPC,\n" +
+		"along with the winners, effective amounts, and takeover heights of the names changed at each of them, and of\n" +
+		"the --name ones, as getclaimsforname, and getvalueforname, report them. Both must be synced past the range:\n" +
+		"  claimtrie crosscheck --rpcuser lbry --rpcpass lbry --from 1000000 --to 1001000 --name @lbry",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if err := validateFormat(); err != nil {
+			return err
+		}
+
+		chainRepo, err := chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open change repo: %w", err)
+		}
+		defer chainRepo.Close()
+
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		to := crosscheckTo
+		if to == 0 || to > ct.Height() {
+			to = ct.Height()
+		}
+		if crosscheckFrom < 1 || crosscheckFrom > to {
+			return fmt.Errorf("invalid range: %d to %d", crosscheckFrom, to)
+		}
+
+		names := func(height int32) ([][]byte, error) {
+			changes, err := chainRepo.Load(height)
+			if err != nil && err != pebble.ErrNotFound {
+				return nil, fmt.Errorf("load from change repo: %w", err)
+			}
+			seen := map[string]bool{}
+			var ns [][]byte
+			for _, name := range crosscheckNames {
+				seen[name] = true
+				ns = append(ns, []byte(name))
+			}
+			for _, chg := range changes {
+				if !seen[string(chg.Name)] {
+					seen[string(chg.Name)] = true
+					ns = append(ns, chg.Name)
+				}
+			}
+			return ns, nil
+		}
+
+		c := &crosscheck.Checker{Local: ct, Remote: crosscheck.NewClient(crosscheckRPC, crosscheckUser, crosscheckPass)}
+		report, err := c.Check(context.Background(), crosscheckFrom, to, names)
+		if report != nil {
+			for _, d := range report.Divergences {
+				showDivergence(d)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("crosscheck: %w", err)
+		}
+		if outputFormat == formatText {
+			fmt.Printf("Compared %d heights, from %d to %d, and %d names: %d divergences\n",
+				report.Heights, report.From, report.To, report.Names, len(report.Divergences))
+		}
+
+		if len(report.Divergences) > 0 {
+			return fmt.Errorf("found %d divergences", len(report.Divergences))
+		}
+
+		return nil
+	},
+}
+
+func showDivergence(d crosscheck.Divergence) {
+	if outputFormat == formatJSONL {
+		js := jsonDivergence{Height: d.Height, Name: string(d.Name), ClaimID: d.ClaimID, Field: d.Field, Local: d.Local, Remote: d.Remote}
+		jsonOut.Encode(js) // nolint : errchk
+		return
+	}
+	fmt.Println(d)
+}
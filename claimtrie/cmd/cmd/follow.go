@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/block/blockrepo"
+	"github.com/btcsuite/btcd/claimtrie/deltasync"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(followCmd)
+
+	followCmd.Flags().DurationVar(&followPoll, "poll", 10*time.Second, "interval of fetching the new deltas")
+	followCmd.Flags().DurationVar(&followTimeout, "timeout", 30*time.Second, "timeout of each request to the publisher")
+}
+
+var (
+	followPoll    time.Duration
+	followTimeout time.Duration
+)
+
+var followCmd = &cobra.Command{
+	Use:   "follow <publisher address>",
+	Short: "Follow the trie of a synced ClaimTrie by the deltas of its blocks, verified against the reported roots",
+	Long: "Follow the trie of a synced ClaimTrie by the deltas of its blocks, verified against the reported roots.\n" +
+		"The trie, and the leaf hashes of the names, are kept in the follower directory of the data dir.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		reportedBlockRepo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ReportedBlockRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open block repo: %w", err)
+		}
+		defer reportedBlockRepo.Close()
+
+		f, err := deltasync.NewFollower(filepath.Join(cfg.DataDir, "follower"))
+		if err != nil {
+			return fmt.Errorf("open follower: %w", err)
+		}
+		defer f.Close()
+
+		c := deltasync.NewClient(args[0], followTimeout)
+		defer c.Close()
+
+		roots := func(height int32) (*chainhash.Hash, error) {
+			return reportedBlockRepo.Get(height)
+		}
+		for {
+			applied, err := f.Sync(c, roots)
+			if err != nil {
+				return fmt.Errorf("sync at height %d: %w", f.Height(), err)
+			}
+			if applied > 0 {
+				root := f.Root()
+				fmt.Printf("height: %d, root: %s, applied: %d\n", f.Height(), root.String(), applied)
+			}
+			time.Sleep(followPoll)
+		}
+	},
+}
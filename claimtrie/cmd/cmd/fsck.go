@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(fsckCmd)
+}
+
+type jsonFinding struct {
+	Severity string `json:"severity"`
+	Repo     string `json:"repo"`
+	Key      string `json:"key,omitempty"`
+	Problem  string `json:"problem"`
+	Repair   string `json:"repair,omitempty"`
+}
+
+type jsonFsckReport struct {
+	Height   int32  `json:"height"`
+	Root     string `json:"root,omitempty"`
+	Blocks   int    `json:"blocks"`
+	Names    int    `json:"names"`
+	Findings int    `json:"findings"`
+	Worst    string `json:"worst"`
+}
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "Cross-check the repos at the last block, and report the problems found with their repairs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		report := ct.Fsck()
+		for _, f := range report.Findings {
+			showFinding(f)
+		}
+		showFsckReport(report)
+
+		if report.Worst() == claimtrie.SeverityError {
+			return fmt.Errorf("found %d problems", len(report.Findings))
+		}
+
+		return nil
+	},
+}
+
+func showFinding(f claimtrie.Finding) {
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(jsonFinding{Severity: f.Severity.String(), Repo: f.Repo, Key: f.Key, Problem: f.Problem, Repair: f.Repair}) // nolint : errchk
+		return
+	}
+	fmt.Printf("%-7s %-5s %s: %s\n", f.Severity, f.Repo, f.Key, f.Problem)
+	if f.Repair != "" {
+		fmt.Printf("        repair: %s\n", f.Repair)
+	}
+}
+
+func showFsckReport(r *claimtrie.FsckReport) {
+	js := jsonFsckReport{Height: r.Height, Blocks: r.Blocks, Names: r.Names, Findings: len(r.Findings), Worst: r.Worst().String()}
+	if r.Root != nil {
+		js.Root = r.Root.String()
+	}
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(js) // nolint : errchk
+		return
+	}
+	fmt.Printf("height: %d, root: %s, blocks: %d, names: %d, findings: %d, worst: %s\n",
+		js.Height, js.Root, js.Blocks, js.Names, js.Findings, js.Worst)
+}
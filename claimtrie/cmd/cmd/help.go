@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var helpJSON bool
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+
+	rootCmd.PersistentFlags().BoolVar(&helpJSON, "json", false,
+		"print the help, with --help, or the help command, as the JSON metadata of the command, its flags, and its subcommands")
+
+	defaultHelp := rootCmd.HelpFunc()
+	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if !helpJSON {
+			defaultHelp(cmd, args)
+			return
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(describeCommand(cmd)); err != nil {
+			cmd.PrintErrln(fmt.Errorf("write help: %w", err))
+		}
+	})
+}
+
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Write the completion script of the shell to stdout",
+	Long: "Write the completion script of the shell to stdout, such as for loading it into the current shell:\n" +
+		"  source <(claimtrie completion bash)",
+	Args:      cobra.ExactValidArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		var err error
+		switch args[0] {
+		case "bash":
+			err = rootCmd.GenBashCompletion(os.Stdout)
+		case "zsh":
+			err = rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = rootCmd.GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			err = rootCmd.GenPowerShellCompletion(os.Stdout)
+		}
+		if err != nil {
+			return fmt.Errorf("write %s completion: %w", args[0], err)
+		}
+
+		return nil
+	},
+}
+
+// commandInfo is the metadata of a command, written by the help with --json, for the scripts wrapping the commands.
+type commandInfo struct {
+	Name     string        `json:"name"`
+	Path     string        `json:"path"`
+	Use      string        `json:"use"`
+	Short    string        `json:"short,omitempty"`
+	Long     string        `json:"long,omitempty"`
+	Aliases  []string      `json:"aliases,omitempty"`
+	Runnable bool          `json:"runnable"`
+	Flags    []flagInfo    `json:"flags,omitempty"`
+	Commands []commandInfo `json:"commands,omitempty"`
+}
+
+type flagInfo struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	Type      string `json:"type"`
+	Default   string `json:"default"`
+	Usage     string `json:"usage"`
+	Inherited bool   `json:"inherited,omitempty"` // It's a global flag of a parent command.
+}
+
+// describeCommand returns the metadata of the command, and its available subcommands, recursively.
+func describeCommand(cmd *cobra.Command) commandInfo {
+
+	info := commandInfo{
+		Name:     cmd.Name(),
+		Path:     cmd.CommandPath(),
+		Use:      cmd.UseLine(),
+		Short:    cmd.Short,
+		Long:     cmd.Long,
+		Aliases:  cmd.Aliases,
+		Runnable: cmd.Runnable(),
+	}
+
+	describeFlags := func(flags *pflag.FlagSet, inherited bool) {
+		flags.VisitAll(func(f *pflag.Flag) {
+			if f.Hidden {
+				return
+			}
+			info.Flags = append(info.Flags, flagInfo{
+				Name:      f.Name,
+				Shorthand: f.Shorthand,
+				Type:      f.Value.Type(),
+				Default:   f.DefValue,
+				Usage:     f.Usage,
+				Inherited: inherited,
+			})
+		})
+	}
+	describeFlags(cmd.LocalFlags(), false)
+	describeFlags(cmd.InheritedFlags(), true)
+
+	for _, c := range cmd.Commands() {
+		if c.IsAvailableCommand() {
+			info.Commands = append(info.Commands, describeCommand(c))
+		}
+	}
+
+	return info
+}
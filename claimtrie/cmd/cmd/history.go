@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/claimhistory"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	nodeCmd.AddCommand(nodeHistoryCmd)
+}
+
+type jsonClaimHistory struct {
+	Name            string `json:"name"`
+	Height          int32  `json:"height"`
+	ClaimID         string `json:"claim_id,omitempty"`
+	EffectiveAmount int64  `json:"effective_amount,omitempty"`
+}
+
+var nodeHistoryCmd = &cobra.Command{
+	Use:   "history <name> [<height>]",
+	Short: "Show the takeovers of a name, or the one of its best claim as of a height",
+	Long: "Show the takeovers of a name, at the heights the claims took it over at, with their effective amounts, and\n" +
+		"the heights it was left without a best claim at, or the takeover of the best claim as of the height. They're\n" +
+		"recorded with clmtclaimhistory as the blocks are appended:\n" +
+		"  claimtrie node history @lbry 1000000",
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		cfg.ClaimHistory = true
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		name := []byte(args[0])
+		if len(args) == 2 {
+			height, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid args")
+			}
+			e, err := ct.ClaimHistoryAt(name, int32(height))
+			if err != nil {
+				return fmt.Errorf("claim history at: %w", err)
+			}
+			if e == nil {
+				e = &claimhistory.Entry{Height: int32(height), Vacated: true}
+			}
+			showClaimHistory(name, *e)
+			return nil
+		}
+
+		entries, err := ct.ClaimHistory(name)
+		if err != nil {
+			return fmt.Errorf("claim history: %w", err)
+		}
+		for _, e := range entries {
+			showClaimHistory(name, e)
+		}
+
+		return nil
+	},
+}
+
+func showClaimHistory(name []byte, e claimhistory.Entry) {
+	js := jsonClaimHistory{Name: string(name), Height: e.Height}
+	if !e.Vacated {
+		js.ClaimID = e.ClaimID.String()
+		js.EffectiveAmount = e.EffectiveAmount
+	}
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(js) // nolint : errchk
+		return
+	}
+	if e.Vacated {
+		fmt.Printf("%s %7d (none)\n", js.Name, js.Height)
+		return
+	}
+	fmt.Printf("%s %7d %s %d\n", js.Name, js.Height, js.ClaimID, js.EffectiveAmount)
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/claimvalue"
+	"github.com/btcsuite/btcd/claimtrie/node"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	claimsCmd.AddCommand(claimsInspectCmd)
+
+	claimsInspectCmd.Flags().StringVar(&inspectInput, "input", "",
+		"txid of the outpoint spent by the first input of the claim tx, which the channel signature is verified against")
+}
+
+var inspectInput string
+
+var claimsInspectCmd = &cobra.Command{
+	Use:   "inspect <name|claimID>",
+	Short: "Show the metadata decoded from the value of a claim, and verify its channel signature",
+	Long: "Show the metadata decoded from the value of the best claim of a name, or of the claim with the Claim ID,\n" +
+		"such as its title, and the hash of its stream, and the channel, which signed it. The signature is verified\n" +
+		"against the public key of the channel, which requires the claim IDs indexed, and the txid spent by the first\n" +
+		"input of the claim tx, as it's signed along with the claim:\n" +
+		"  claimtrie claim inspect @lbry\n" +
+		"  claimtrie claim inspect 3dbfa29a8b1ba74e7dbd4b2fa9bd6c9bddecd9b7 --input <txid>",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		var input *chainhash.Hash
+		if inspectInput != "" {
+			var err error
+			input, err = chainhash.NewHashFromStr(inspectInput)
+			if err != nil {
+				return fmt.Errorf("parse input: %w", err)
+			}
+		}
+
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		name, c, err := inspectedClaim(ct, args[0])
+		if err != nil {
+			return err
+		}
+		meta, err := decodeClaim(ct, c)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("name: %s, claim: %s, outpoint: %s, amount: %d\n", name, c.ClaimID, c.OutPoint, c.Amount)
+		showClaimMeta(meta)
+		if meta.Signature == nil {
+			return nil
+		}
+
+		fmt.Printf("channel: %s\n", meta.Signature.Channel)
+		if input == nil {
+			fmt.Printf("signature: not verified, without the txid spent by the first input of the claim tx: --input\n")
+			return nil
+		}
+		err = verifyClaim(ct, meta, input)
+		if errors.Is(err, claimvalue.ErrInvalidSignature) {
+			fmt.Printf("signature: invalid\n")
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("verify signature: %w", err)
+		}
+		fmt.Printf("signature: valid\n")
+
+		return nil
+	},
+}
+
+// inspectedClaim returns the best claim of the name, or the claim with the Claim ID, at the last height.
+func inspectedClaim(ct *claimtrie.ClaimTrie, arg string) ([]byte, *node.Claim, error) {
+
+	if len(arg) == 2*len(node.ClaimID{}) {
+		if _, err := hex.DecodeString(arg); err == nil {
+			id, _ := node.NewIDFromString(arg)
+			return claimByID(ct, id)
+		}
+	}
+
+	res, err := ct.ResolveAt([]byte(arg), ct.Height())
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolve %q: %w", arg, err)
+	}
+	if res.Node.BestClaim == nil {
+		return nil, nil, fmt.Errorf("resolve %q: %w", arg, claimtrie.ErrNoBestClaim)
+	}
+
+	return res.Name, res.Node.BestClaim, nil
+}
+
+// claimByID returns the claim with the Claim ID, the first accepted of them, if they collide, at the last height.
+func claimByID(ct *claimtrie.ClaimTrie, id node.ClaimID) ([]byte, *node.Claim, error) {
+
+	claims, err := ct.ClaimByID(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("claim %s: %w", id, err)
+	}
+	if len(claims) == 0 {
+		return nil, nil, fmt.Errorf("claim %s not found", id)
+	}
+
+	ic := claims[0]
+	res, err := ct.ResolveAt(ic.Name, ct.Height())
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolve %q: %w", ic.Name, err)
+	}
+	for _, c := range res.Node.Claims {
+		if c.ClaimID == id && c.OutPoint == ic.OutPoint {
+			return res.Name, c, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("claim %s not found in %q", id, ic.Name)
+}
+
+func decodeClaim(ct *claimtrie.ClaimTrie, c *node.Claim) (*claimvalue.Claim, error) {
+
+	value, err := ct.ClaimValue(c)
+	if err != nil {
+		return nil, fmt.Errorf("value of %s: %w", c.ClaimID, err)
+	}
+	meta, err := claimvalue.Decode(value)
+	if err != nil {
+		return nil, fmt.Errorf("decode value of %s: %w", c.ClaimID, err)
+	}
+
+	return meta, nil
+}
+
+// verifyClaim verifies the signature of the claim by the public key of its channel.
+func verifyClaim(ct *claimtrie.ClaimTrie, meta *claimvalue.Claim, input *chainhash.Hash) error {
+
+	_, c, err := claimByID(ct, meta.Signature.Channel)
+	if err != nil {
+		return err
+	}
+	channel, err := decodeClaim(ct, c)
+	if err != nil {
+		return err
+	}
+	if channel.Channel == nil {
+		return fmt.Errorf("claim %s is a %s, not a channel", c.ClaimID, channel.Type)
+	}
+
+	return meta.Verify(input, channel.Channel.PublicKey)
+}
+
+func showClaimMeta(c *claimvalue.Claim) {
+
+	fmt.Printf("type: %s\n", c.Type)
+	if c.Title != "" {
+		fmt.Printf("title: %s\n", c.Title)
+	}
+	if c.Description != "" {
+		fmt.Printf("description: %s\n", c.Description)
+	}
+	if len(c.Tags) > 0 {
+		fmt.Printf("tags: %q\n", c.Tags)
+	}
+	if c.Thumbnail != nil && c.Thumbnail.URL != "" {
+		fmt.Printf("thumbnail: %s\n", c.Thumbnail.URL)
+	}
+
+	switch {
+	case c.Stream != nil:
+		s := c.Stream
+		if s.Author != "" {
+			fmt.Printf("author: %s\n", s.Author)
+		}
+		if s.License != "" {
+			fmt.Printf("license: %s %s\n", s.License, s.LicenseURL)
+		}
+		if s.ReleaseTime > 0 {
+			fmt.Printf("release time: %s\n", time.Unix(s.ReleaseTime, 0).UTC().Format(time.RFC3339))
+		}
+		if src := s.Source; src != nil {
+			fmt.Printf("stream hash: %x\n", src.SDHash)
+			fmt.Printf("file: %s, %d bytes, %s, hash: %x\n", src.Name, src.Size, src.MediaType, src.Hash)
+		}
+		if s.Fee != nil {
+			fmt.Printf("fee: %d %s, address: %x\n", s.Fee.Amount, s.Fee.Currency, s.Fee.Address)
+		}
+	case c.Channel != nil:
+		ch := c.Channel
+		fmt.Printf("public key: %x\n", ch.PublicKey)
+		if ch.WebsiteURL != "" {
+			fmt.Printf("website: %s\n", ch.WebsiteURL)
+		}
+		if ch.Email != "" {
+			fmt.Printf("email: %s\n", ch.Email)
+		}
+		for _, id := range ch.Featured {
+			fmt.Printf("featured: %s\n", id)
+		}
+	case c.Repost != nil:
+		fmt.Printf("reposted: %s\n", c.Repost)
+	case c.Type == claimvalue.TypeCollection:
+		for _, id := range c.Collection {
+			fmt.Printf("claim: %s\n", id)
+		}
+	}
+}
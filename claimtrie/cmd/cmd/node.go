@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strconv"
 
+	"github.com/btcsuite/btcd/claimtrie"
 	"github.com/btcsuite/btcd/claimtrie/node"
 	"github.com/btcsuite/btcd/claimtrie/node/noderepo"
 
@@ -17,8 +18,13 @@ func init() {
 
 	nodeCmd.AddCommand(nodeDumpCmd)
 	nodeCmd.AddCommand(nodeReplayCmd)
+	nodeCmd.AddCommand(nodeShowCmd)
+
+	nodeShowCmd.Flags().Int32Var(&nodeShowHeight, "height", 0, "height to show the node as of (default the last block)")
 }
 
+var nodeShowHeight int32
+
 var nodeCmd = &cobra.Command{
 	Use:   "node",
 	Short: "Replay the application of changes on a node up to certain height",
@@ -98,7 +104,37 @@ var nodeReplayCmd = &cobra.Command{
 			return fmt.Errorf("get node: %w", err)
 		}
 
-		showNode(n)
+		showNode(name, int32(height), n)
+		return nil
+	},
+}
+
+var nodeShowCmd = &cobra.Command{
+	Use:   "show <node_name>",
+	Short: "Show the best claim, takeover height, and the claims and supports of a node, as of a height",
+	Long: "Show the best claim, and the takeover height, of a node, as of a height, with the amounts, outpoints,\n" +
+		"and the activation and expiration heights of its claims and supports, as the ClaimTrie resolves it:\n" +
+		"  claimtrie node show @lbry --height 1000000",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		height := nodeShowHeight
+		if height == 0 {
+			height = ct.Height()
+		}
+
+		res, err := ct.ResolveAt([]byte(args[0]), height)
+		if err != nil {
+			return fmt.Errorf("resolve %q at %d: %w", args[0], height, err)
+		}
+
+		showNodeDetail([]byte(args[0]), res)
 		return nil
 	},
 }
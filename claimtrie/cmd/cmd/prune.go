@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/progress"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.Flags().Int32Var(&pruneBlocks, "blocks", 0, "number of the last blocks, of which the trie roots are retained")
+}
+
+var pruneBlocks int32
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune --blocks <count>",
+	Short: "Delete the trie nodes unreachable from the roots of the last blocks, and compact the trie repo",
+	Long: "Delete the nodes of the trie repo, which aren't reachable from the roots of the last blocks, as referenced\n" +
+		"by the block repo, and compact it. The nodes of the roots superseded by each block are never overwritten,\n" +
+		"so the trie repo grows without them pruned. The ClaimTrie can't be reset past the roots retained after,\n" +
+		"so the node must be run with clmttrieretain set to at least as many blocks:\n" +
+		"  claimtrie prune --blocks 1000",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if pruneBlocks <= 0 {
+			return fmt.Errorf("the number of the blocks to retain the roots of is required: --blocks")
+		}
+		cfg.TrieRetentionBlocks = pruneBlocks
+
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		start := time.Now()
+		meter := progress.Start("prune trie", showProgress)
+		res, err := ct.PruneTrie()
+		meter.Stop()
+		if err != nil {
+			return fmt.Errorf("prune trie: %w", err)
+		}
+		fmt.Printf("Pruned %d trie nodes, keeping the %d nodes of %d roots, in %s\n",
+			res.Deleted, res.Kept, res.Roots, time.Since(start))
+
+		return nil
+	},
+}
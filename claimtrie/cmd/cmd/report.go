@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/btcsuite/btcd/claimtrie/block/blockrepo"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/node/noderepo"
+	"github.com/btcsuite/btcd/claimtrie/param"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.AddCommand(reportExpiringCmd)
+	reportCmd.AddCommand(reportCollisionsCmd)
+
+	reportExpiringCmd.Flags().Int32Var(&reportWindow, "window", 1000, "list the names, whose best claims expire within this many blocks")
+	reportExpiringCmd.Flags().StringVar(&reportSort, "sort", sortByAmount, "sort by: amount, supports, or expiration")
+	reportExpiringCmd.Flags().IntVar(&reportLimit, "limit", 0, "list at most this many names, if it's set")
+}
+
+// The sort orders of the expiring names.
+const (
+	sortByAmount     = "amount"     // the effective amount of the best claim, descending.
+	sortBySupports   = "supports"   // the number of the active supports of the best claim, descending.
+	sortByExpiration = "expiration" // the height the best claim expires at, ascending.
+)
+
+var (
+	reportWindow int32
+	reportSort   string
+	reportLimit  int
+)
+
+// expiringName is a name, whose best claim expires within the window.
+type expiringName struct {
+	Name            string `json:"name"`
+	ClaimID         string `json:"claim_id"`
+	ExpireAt        int32  `json:"expire_at"`
+	EffectiveAmount int64  `json:"effective_amount"`
+	Supports        int    `json:"supports"`
+}
+
+// collidingClaim is a best claim in a collision at the normalization fork.
+type collidingClaim struct {
+	Name            string `json:"name"`
+	ClaimID         string `json:"claim_id"`
+	OutPoint        string `json:"outpoint"`
+	EffectiveAmount int64  `json:"effective_amount"`
+}
+
+// collision is a set of names merging into one at the normalization fork.
+type collision struct {
+	Name   string           `json:"name"`
+	Names  []string         `json:"names"`
+	Winner *collidingClaim  `json:"winner"`
+	Losers []collidingClaim `json:"losers"`
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report related commands",
+}
+
+var reportExpiringCmd = &cobra.Command{
+	Use:   "expiring [<height>]",
+	Short: "List the names, whose best claims expire within the window after <height>, or the last block",
+	Args:  cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		less, ok := expiringOrders[reportSort]
+		if !ok {
+			return fmt.Errorf("unknown sort order: %q", reportSort)
+		}
+		if reportWindow <= 0 {
+			return fmt.Errorf("invalid window: %d", reportWindow)
+		}
+
+		var height int32
+		if len(args) == 1 {
+			h, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid args")
+			}
+			height = int32(h)
+		} else {
+			blockRepo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.BlockRepoPebble.Path))
+			if err != nil {
+				return fmt.Errorf("open block repo: %w", err)
+			}
+			height, err = blockRepo.Load()
+			blockRepo.Close()
+			if err != nil {
+				return fmt.Errorf("load previous height: %w", err)
+			}
+		}
+
+		repo, err := noderepo.NewPebble(filepath.Join(cfg.DataDir, cfg.NodeRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open node repo: %w", err)
+		}
+		defer repo.Close()
+
+		nm, err := node.NewBaseManager(repo)
+		if err != nil {
+			return fmt.Errorf("create node manager: %w", err)
+		}
+
+		expiring, err := expiringNames(nm, height, reportWindow)
+		if err != nil {
+			return err
+		}
+
+		sort.SliceStable(expiring, func(i, j int) bool { return less(expiring[i], expiring[j]) })
+		if reportLimit > 0 && len(expiring) > reportLimit {
+			expiring = expiring[:reportLimit]
+		}
+
+		for _, e := range expiring {
+			showExpiringName(e)
+		}
+
+		return nil
+	},
+}
+
+var reportCollisionsCmd = &cobra.Command{
+	Use:   "collisions",
+	Short: "List the names merging into one at the normalization fork, their winners, and the claims losing the control of their names",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		blockRepo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.BlockRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open block repo: %w", err)
+		}
+		height, err := blockRepo.Load()
+		blockRepo.Close()
+		if err != nil {
+			return fmt.Errorf("load previous height: %w", err)
+		}
+		if height < param.NormalizedNameForkHeight {
+			return fmt.Errorf("the ClaimTrie at %d hasn't reached the normalization fork at %d",
+				height, param.NormalizedNameForkHeight)
+		}
+
+		repo, err := noderepo.NewPebble(filepath.Join(cfg.DataDir, cfg.NodeRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open node repo: %w", err)
+		}
+		defer repo.Close()
+
+		nm, err := node.NewBaseManager(repo)
+		if err != nil {
+			return fmt.Errorf("create node manager: %w", err)
+		}
+
+		collisions, err := node.NormalizationCollisions(nm)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range collisions {
+			showCollision(newCollision(c))
+		}
+
+		return nil
+	},
+}
+
+func newCollision(c node.Collision) collision {
+
+	js := collision{Name: string(c.Name), Losers: []collidingClaim{}}
+	for _, name := range c.Names {
+		js.Names = append(js.Names, string(name))
+	}
+	if c.Winner != nil {
+		js.Winner = &collidingClaim{
+			Name:            string(c.Name),
+			ClaimID:         c.Winner.ClaimID.String(),
+			OutPoint:        c.Winner.OutPoint.String(),
+			EffectiveAmount: c.EffectiveAmount,
+		}
+	}
+	for _, l := range c.Losers {
+		js.Losers = append(js.Losers, collidingClaim{
+			Name:            string(l.Name),
+			ClaimID:         l.Claim.ClaimID.String(),
+			OutPoint:        l.Claim.OutPoint.String(),
+			EffectiveAmount: l.EffectiveAmount,
+		})
+	}
+
+	return js
+}
+
+var expiringOrders = map[string]func(a, b expiringName) bool{
+	sortByAmount:     func(a, b expiringName) bool { return a.EffectiveAmount > b.EffectiveAmount },
+	sortBySupports:   func(a, b expiringName) bool { return a.Supports > b.Supports },
+	sortByExpiration: func(a, b expiringName) bool { return a.ExpireAt < b.ExpireAt },
+}
+
+// expiringNames returns the names, whose best claims at the height expire within the window after it.
+func expiringNames(nm node.Manager, height int32, window int32) ([]expiringName, error) {
+
+	var err error
+	var expiring []expiringName
+	nm.IterateNames(func(name []byte) bool {
+		var n *node.Node
+		n, err = nm.NodeAt(height, name)
+		if err != nil {
+			err = fmt.Errorf("node %q at %d: %w", name, height, err)
+			return false
+		}
+		if n == nil || n.BestClaim == nil {
+			return true
+		}
+
+		c := n.BestClaim
+		if c.ExpireAt() <= height || c.ExpireAt() > height+window {
+			return true
+		}
+
+		e := expiringName{
+			Name:            string(name),
+			ClaimID:         c.ClaimID.String(),
+			ExpireAt:        c.ExpireAt(),
+			EffectiveAmount: c.EffectiveAmount(n.Supports),
+		}
+		for _, s := range n.Supports {
+			if s.ClaimID == c.ClaimID && s.Status == node.Activated {
+				e.Supports++
+			}
+		}
+		expiring = append(expiring, e)
+		return true
+	})
+
+	return expiring, err
+}
@@ -1,25 +1,166 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/change"
 	"github.com/btcsuite/btcd/claimtrie/config"
 	"github.com/btcsuite/btcd/claimtrie/param"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btclog"
+	"github.com/btcsuite/btcutil"
 
 	"github.com/spf13/cobra"
 )
 
 var cfg = config.DefaultConfig
 
+var (
+	configFile string
+	dataDir    string
+	network    string
+	logLevel   string
+	metrics    string
+)
+
+// networkParams are the params of the chains of the networks of the --network flag, one of param.NetworkNames.
+var networkParams = map[string]*chaincfg.Params{
+	"mainnet": &chaincfg.MainNetParams,
+	"testnet": &chaincfg.TestNet3Params,
+	"regtest": &chaincfg.RegressionNetParams,
+}
+
+// netParams is the chain of the network selected.
+var netParams = &chaincfg.MainNetParams
+
 func init() {
 	param.SetNetwork(wire.MainNet)
+
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", formatText,
+		"output format of the query commands: text, or jsonl")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "",
+		"JSON file of the ClaimTrie config fields to override the defaults with, such as the ones of another instance")
+	rootCmd.PersistentFlags().StringVar(&dataDir, "datadir", "",
+		"directory of the ClaimTrie repos, such as a copy of the ones of a running instance, overriding the config")
+	rootCmd.PersistentFlags().StringVar(&network, "network", "mainnet",
+		"network of the ClaimTrie, of which the params, and the default datadir, are used: "+strings.Join(param.NetworkNames(), ", "))
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "off",
+		"level of the logs of the ClaimTrie written to stderr: trace, debug, info, warn, error, critical, or off")
+	rootCmd.PersistentFlags().StringVar(&metrics, "metrics", "",
+		"address to serve the metrics of the ClaimTrie on, at /metrics in the Prometheus text format, such as during a replay")
 }
 
 var rootCmd = &cobra.Command{
 	Use:          "claimtrie",
 	Short:        "ClaimTrie Command Line Interface",
 	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		err := validateFormat()
+		if err != nil {
+			return err
+		}
+		err = setNetwork()
+		if err != nil {
+			return err
+		}
+		err = setLogLevel()
+		if err != nil {
+			return err
+		}
+		return loadConfig(cmd.Flags().Changed("network"))
+	},
+}
+
+// setNetwork sets the params of the network selected.
+func setNetwork() error {
+
+	err := param.SetNetworkByName(network)
+	if err != nil {
+		return err
+	}
+	netParams = networkParams[network]
+
+	return nil
+}
+
+// setLogLevel writes the logs of the ClaimTrie packages at, or above, the level to stderr.
+func setLogLevel() error {
+
+	level, ok := btclog.LevelFromString(logLevel)
+	if !ok {
+		return fmt.Errorf("invalid log level: %q", logLevel)
+	}
+
+	claimtrie.UseBackend(btclog.NewBackend(os.Stderr), level)
+
+	return nil
+}
+
+// loadConfig sets the config of the commands from the defaults, overridden by the config file, if any,
+// and the data directory, if any, so that each run can target its own instance. The network of the config file,
+// if any, applies, unless the one of the flag is set.
+func loadConfig(networkSet bool) error {
+
+	cfg = config.DefaultConfig
+	if configFile != "" {
+		f, err := os.Open(configFile)
+		if err != nil {
+			return fmt.Errorf("open config: %w", err)
+		}
+		defer f.Close()
+
+		dec := json.NewDecoder(f)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&cfg)
+		if err != nil {
+			return fmt.Errorf("parse config %s: %w", configFile, err)
+		}
+	}
+	// The network of the config file applies, unless the flag is set. The params are set here, and not by
+	// claimtrie.New, so the param overrides of the commands, applied in between, aren't reset.
+	if cfg.Network != "" && !networkSet {
+		network = cfg.Network
+		err := setNetwork()
+		if err != nil {
+			return fmt.Errorf("config network: %w", err)
+		}
+	}
+	for _, s := range cfg.ParamOverrides {
+		o, err := param.ParseOverride(s)
+		if err != nil {
+			return fmt.Errorf("config param overrides: %w", err)
+		}
+		o.Apply()
+	}
+	cfg.Network, cfg.ParamOverrides = "", nil
+	if network != "mainnet" && cfg.DataDir == config.DefaultConfig.DataDir {
+		cfg.DataDir = filepath.Join(btcutil.AppDataDir("chain", false), "data", netParams.Name, "claim_dbs")
+	}
+	if dataDir != "" {
+		cfg.DataDir = dataDir
+	}
+	if metrics != "" {
+		cfg.Metrics, cfg.MetricsListen = true, metrics
+	}
+
+	return nil
 }
 
 func Execute() {
-	rootCmd.Execute() // nolint : errchk
+	err := rootCmd.Execute()
+
+	// Spell out the change at fault, for digging into it with the node and chain commands.
+	var chgErr *change.Error
+	if errors.As(err, &chgErr) {
+		for _, field := range chgErr.Fields() {
+			fmt.Fprintf(os.Stderr, "  %s\n", field)
+		}
+	}
 }
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/block/blockrepo"
+	"github.com/btcsuite/btcd/claimtrie/chain/chainrepo"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/resolver"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:9246", "address to serve the JSON-RPC on")
+	serveCmd.Flags().DurationVar(&servePoll, "poll", 10*time.Second, "interval of checking the chain repo for new blocks")
+}
+
+var (
+	serveListen string
+	servePoll   time.Duration
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Sync the ClaimTrie from the chain repo, and answer the queries of the names and claims over JSON-RPC",
+	Long: "Sync the ClaimTrie from the chain repo, verified against the reported roots, and answer the resolvename,\n" +
+		"getclaimsforname, getvalueforname and getclaimbyid commands over the JSON-RPC of btcd, as of the last\n" +
+		"block synced. The claim IDs are indexed for getclaimbyid.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		chainRepo, err := chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open chain repo: %w", err)
+		}
+		defer chainRepo.Close()
+
+		reportedBlockRepo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ReportedBlockRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open block repo: %w", err)
+		}
+		defer reportedBlockRepo.Close()
+
+		serveCfg := cfg
+		serveCfg.ClaimIDIndex = true
+		ct, err := claimtrie.New(serveCfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		l, err := net.Listen("tcp", serveListen)
+		if err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+		srv := &http.Server{Handler: resolver.NewServer(ct), ReadHeaderTimeout: 10 * time.Second}
+		defer srv.Close()
+		go srv.Serve(l) // nolint : errchk
+
+		fmt.Printf("Serving on %s from height %d\n", l.Addr(), ct.Height())
+
+		for {
+			tip, err := reportedBlockRepo.Load()
+			if err != nil {
+				return fmt.Errorf("load block repo: %w", err)
+			}
+			for height := ct.Height() + 1; height <= tip; height++ {
+				changes, err := chainRepo.Load(height)
+				if err != nil && err != pebble.ErrNotFound {
+					return fmt.Errorf("load from change repo: %w", err)
+				}
+				for _, chg := range changes {
+					err = applyChange(ct, chg)
+					if err != nil {
+						return fmt.Errorf("execute change %d of block %d: %w", chg.Seq, height, change.Wrap(err, chg))
+					}
+				}
+				err = appendBlock(ct, reportedBlockRepo)
+				if err != nil {
+					return err
+				}
+				if height%1000 == 0 {
+					fmt.Printf("block: %d\n", height)
+				}
+			}
+			time.Sleep(servePoll)
+		}
+	},
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(simulateBidCmd)
+
+	simulateBidCmd.Flags().StringVar(&simulateName, "name", "", "name of the bid")
+	simulateBidCmd.Flags().Int64Var(&simulateAmount, "amount", 0, "amount of the bid, in dewies")
+	simulateBidCmd.Flags().StringVar(&simulateSupport, "support", "", "claim ID to support, instead of claiming the name")
+}
+
+var (
+	simulateName    string
+	simulateAmount  int64
+	simulateSupport string
+)
+
+type jsonBidSimulation struct {
+	Name            string `json:"name"`
+	Amount          int64  `json:"amount"`
+	ClaimID         string `json:"claim_id"`
+	Support         bool   `json:"support"`
+	AcceptAt        int32  `json:"accept_at"`
+	ActivateAt      int32  `json:"activate_at"`
+	Wins            bool   `json:"wins"`
+	EffectiveAmount int64  `json:"effective_amount"`
+	Previous        string `json:"previous,omitempty"`
+	Best            string `json:"best,omitempty"`
+	BestAmount      int64  `json:"best_amount"`
+	CounterBid      int64  `json:"counter_bid"`
+}
+
+var simulateBidCmd = &cobra.Command{
+	Use:   "simulate-bid --name <name> --amount <amount> [--support <claim ID>]",
+	Short: "Report whether a claim, or a support, of a name would win, once activated, without applying it",
+	Long: "Report whether a claim, or a support of the claim of the ID, of the amount, accepted by the next block,\n" +
+		"would make its claim the best one of the name, once it's activated, and when, along with the effective\n" +
+		"amount a claim must reach, once activated, to take the name from the best one then. It's computed\n" +
+		"against the ClaimTrie as of the last block, and the other transactions may affect the name by then:\n" +
+		"  claimtrie simulate-bid --name @channel --amount 100000000",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if simulateName == "" || simulateAmount <= 0 {
+			return fmt.Errorf("the name, and a positive amount, are required: --name, --amount")
+		}
+		var supported *node.ClaimID
+		if simulateSupport != "" {
+			id, err := node.NewIDFromString(simulateSupport)
+			if err != nil {
+				return fmt.Errorf("invalid claim ID: %w", err)
+			}
+			supported = &id
+		}
+
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		sim, err := ct.SimulateBid([]byte(simulateName), simulateAmount, supported)
+		if err != nil {
+			return fmt.Errorf("simulate bid: %w", err)
+		}
+		showBidSimulation(sim)
+
+		return nil
+	},
+}
+
+func showBidSimulation(sim *claimtrie.BidSimulation) {
+
+	js := jsonBidSimulation{Name: string(sim.Name), Amount: sim.Amount, ClaimID: sim.ClaimID.String(),
+		Support: sim.Support, AcceptAt: sim.AcceptAt, ActivateAt: sim.ActivateAt, Wins: sim.Wins,
+		EffectiveAmount: sim.EffectiveAmount, BestAmount: sim.BestAmount, CounterBid: sim.CounterBid}
+	if sim.Previous != nil {
+		js.Previous = sim.Previous.ClaimID.String()
+	}
+	if sim.Best != nil {
+		js.Best = sim.Best.ClaimID.String()
+	}
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(js) // nolint : errchk
+		return
+	}
+
+	kind := "claim"
+	if js.Support {
+		kind = "support"
+	}
+	fmt.Printf("A %s of %d for %s, accepted at %d, activates at %d\n", kind, js.Amount, js.ClaimID, js.AcceptAt, js.ActivateAt)
+	if js.Wins && js.Previous == "" {
+		fmt.Printf("  wins %q with an effective amount of %d, which has no best claim yet\n", js.Name, js.EffectiveAmount)
+	} else if js.Wins {
+		fmt.Printf("  wins %q with an effective amount of %d, over the best claim before: %s\n", js.Name, js.EffectiveAmount, js.Previous)
+	} else {
+		fmt.Printf("  loses %q with an effective amount of %d, to %s with %d\n", js.Name, js.EffectiveAmount, js.Best, js.BestAmount)
+	}
+	fmt.Printf("  a claim retakes it with an effective amount of %d, once activated\n", js.CounterBid)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/config"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+
+	snapshotCmd.AddCommand(snapshotExportCmd)
+	snapshotCmd.AddCommand(snapshotImportCmd)
+
+	snapshotExportCmd.Flags().Int32Var(&snapshotHeight, "height", 0, "height of the state to export (default the last block)")
+}
+
+var snapshotHeight int32
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "State snapshot related commands",
+}
+
+var snapshotExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the nodes of all the names as of the height to stdout, for bootstrapping a ClaimTrie from",
+	Long: "Write the nodes of all the names as of the height, with their claims, supports and takeover heights,\n" +
+		"to stdout, gzipped, for bootstrapping a ClaimTrie from, without replaying their changes:\n" +
+		"  claimtrie snapshot export --height 1000000 > state.gz",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		height := snapshotHeight
+		if height == 0 {
+			height = ct.Height()
+		}
+		h, err := ct.ExportState(context.Background(), os.Stdout, height)
+		if err != nil {
+			return fmt.Errorf("export state: %w", err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Exported %d nodes at height %d, root %s\n", h.Nodes, h.Height, h.Root)
+
+		return nil
+	},
+}
+
+var snapshotImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Bootstrap the empty ClaimTrie from a state snapshot read from stdin",
+	Long: "Bootstrap the empty ClaimTrie from a state snapshot read from stdin, once its trie hashes to the root\n" +
+		"of the snapshot. The nodes are kept as the base snapshots of the snapshot node manager, which the ClaimTrie\n" +
+		"has to be run with, along with a change retention, other than all, such as --clmtnodemanager=snapshot\n" +
+		"--clmtretention=none. It can't be reset before the height of the snapshot:\n" +
+		"  claimtrie snapshot import < state.gz",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		importCfg := cfg
+		importCfg.NodeManager = config.NodeManagerSnapshot
+		if importCfg.ChangeRetention == config.RetainAll || importCfg.ChangeRetention == "" {
+			importCfg.ChangeRetention = config.RetainNone
+		}
+
+		h, err := claimtrie.ImportState(importCfg, os.Stdin)
+		if err != nil {
+			return fmt.Errorf("import state: %w", err)
+		}
+
+		fmt.Printf("Imported %d nodes at height %d, root %s\n", h.Nodes, h.Height, h.Root)
+
+		return nil
+	},
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/standby"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(standbyCmd)
+	standbyCmd.AddCommand(standbyPromoteCmd)
+
+	standbyCmd.Flags().Int32Var(&standbyLag, "lag", 6, "number of blocks to stay behind the tip of the primary")
+	standbyCmd.Flags().DurationVar(&standbyPoll, "poll", 10*time.Second, "interval of fetching the new blocks")
+	standbyCmd.PersistentFlags().DurationVar(&standbyTimeout, "timeout", 30*time.Second, "timeout of each request to the primary")
+}
+
+var (
+	standbyLag     int32
+	standbyPoll    time.Duration
+	standbyTimeout time.Duration
+)
+
+var standbyCmd = &cobra.Command{
+	Use:   "standby <primary address>",
+	Short: "Keep the ClaimTrie the lag blocks behind a primary, by the changes of its blocks, verified against their roots",
+	Long: "Keep the ClaimTrie of the data dir the lag blocks behind a primary, which serves the changes of its blocks\n" +
+		"with clmtstandby, verified against their roots, so the blocks it reorganizes away within the lag aren't\n" +
+		"applied. Once the primary fails, stop the standby, and promote it, before serving the queries from it:\n" +
+		"  claimtrie standby 10.0.0.1:9247 --lag 6\n" +
+		"  claimtrie standby promote 10.0.0.1:9247",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		c := standby.NewClient(args[0], standbyTimeout)
+		defer c.Close()
+
+		s := claimtrie.NewStandby(ct, c, standbyLag)
+		fmt.Printf("Standing by %s from height %d\n", args[0], ct.Height())
+		for {
+			applied, err := s.Sync()
+			if err != nil {
+				return fmt.Errorf("sync at height %d: %w", ct.Height(), err)
+			}
+			if applied > 0 {
+				fmt.Printf("height: %d, root: %s, applied: %d\n", ct.Height(), ct.MerkleHash(), applied)
+			}
+			time.Sleep(standbyPoll)
+		}
+	},
+}
+
+var standbyPromoteCmd = &cobra.Command{
+	Use:   "promote <primary address>",
+	Short: "Catch the standby up to the tip of the primary, and verify its root, before it takes over the queries",
+	Long: "Catch the ClaimTrie of the data dir up to the tip of the primary, and verify it's at the height, and the\n" +
+		"root, of it, before it takes over the queries, such as with serve. The primary must still answer for its\n" +
+		"tip, which isn't known otherwise.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		c := standby.NewClient(args[0], standbyTimeout)
+		defer c.Close()
+
+		height, root, err := claimtrie.NewStandby(ct, c, 0).Promote()
+		if err != nil {
+			return fmt.Errorf("promote: %w", err)
+		}
+		fmt.Printf("Promoted at height %d, root %s\n", height, root)
+
+		return nil
+	},
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"path/filepath"
 	"strconv"
 
 	"github.com/btcsuite/btcd/claimtrie/temporal/temporalrepo"
@@ -23,7 +24,7 @@ var temporalCmd = &cobra.Command{
 
 func runListNodes(cmd *cobra.Command, args []string) error {
 
-	repo, err := temporalrepo.NewPebble(cfg.TemporalRepoPebble.Path)
+	repo, err := temporalrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.TemporalRepoPebble.Path))
 	if err != nil {
 		log.Fatalf("can't open reported block repo: %s", err)
 	}
@@ -51,11 +52,7 @@ func runListNodes(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
-		fmt.Printf("%7d: %q", height, names[0])
-		for _, name := range names[1:] {
-			fmt.Printf(", %q ", name)
-		}
-		fmt.Printf("\n")
+		showNames(int32(height), names)
 	}
 
 	return nil
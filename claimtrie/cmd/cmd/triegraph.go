@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/block/blockrepo"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	trieGraphDepth  int
+	trieGraphHeight int32
+)
+
+func init() {
+	trieGraphCmd.Flags().IntVar(&trieGraphDepth, "depth", 3, "show the vertices at most this many levels below the prefix")
+	trieGraphCmd.Flags().Int32Var(&trieGraphHeight, "height", 0, "show the trie at the block of this height, instead of the last one")
+	rootCmd.AddCommand(trieGraphCmd)
+}
+
+type jsonTrieTree struct {
+	Key      string          `json:"key"`
+	Hash     string          `json:"hash"`
+	Value    string          `json:"value,omitempty"`
+	Children []*jsonTrieTree `json:"children,omitempty"`
+	Elided   []jsonTrieChild `json:"elided,omitempty"` // The children below the depth limit.
+}
+
+var trieGraphCmd = &cobra.Command{
+	Use:   "triegraph [<prefix>]",
+	Short: "Show the stored trie under the prefix, as a Graphviz DOT graph, or a JSON tree with --format jsonl",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		var prefix []byte
+		if len(args) == 1 {
+			prefix = []byte(args[0])
+		}
+
+		root, err := rootAt(trieGraphHeight)
+		if err != nil {
+			return err
+		}
+
+		repo, err := merkletrierepo.NewPebble(filepath.Join(cfg.DataDir, cfg.MerkleTrieRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open trie repo: %w", err)
+		}
+		trie := merkletrie.New(nil, repo)
+		defer trie.Close()
+
+		var nodes []*merkletrie.RawNode
+		err = trie.At(root).Walk(prefix, trieGraphDepth, func(n *merkletrie.RawNode) error {
+			nodes = append(nodes, n)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("walk trie: %w", err)
+		}
+
+		if outputFormat == formatJSONL {
+			jsonOut.Encode(trieTree(nodes)) // nolint : errchk
+			return nil
+		}
+		showTrieGraph(nodes)
+
+		return nil
+	},
+}
+
+// rootAt returns the Merkle Hash of the block at the height, or of the last block if it's 0.
+func rootAt(height int32) (*chainhash.Hash, error) {
+
+	if height == 0 {
+		return lastRoot()
+	}
+
+	blockRepo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.BlockRepoPebble.Path))
+	if err != nil {
+		return nil, fmt.Errorf("open block repo: %w", err)
+	}
+	defer blockRepo.Close()
+
+	root, err := blockRepo.Get(height)
+	if err != nil {
+		return nil, fmt.Errorf("get root of block %d: %w", height, err)
+	}
+
+	return root, nil
+}
+
+// trieTree nests the nodes walked in pre-order under their parents.
+func trieTree(nodes []*merkletrie.RawNode) *jsonTrieTree {
+
+	trees := map[string]*jsonTrieTree{}
+	for _, n := range nodes {
+		t := &jsonTrieTree{Key: string(n.Key), Hash: n.Hash.String()}
+		if n.Value != nil {
+			t.Value = n.Value.String()
+		}
+		trees[t.Key] = t
+		if len(n.Key) > 0 {
+			if parent, ok := trees[t.Key[:len(t.Key)-1]]; ok {
+				parent.Children = append(parent.Children, t)
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		for _, c := range n.Children {
+			key := string(n.Key) + string([]byte{c.Ch})
+			if _, ok := trees[key]; !ok {
+				t := trees[string(n.Key)]
+				t.Elided = append(t.Elided, jsonTrieChild{Key: key, Hash: c.Hash.String()})
+			}
+		}
+	}
+
+	return trees[string(nodes[0].Key)]
+}
+
+// showTrieGraph prints the nodes as a DOT graph, with the children below the depth limit dashed.
+func showTrieGraph(nodes []*merkletrie.RawNode) {
+
+	walked := map[string]bool{}
+	for _, n := range nodes {
+		walked[string(n.Key)] = true
+	}
+
+	fmt.Println("digraph trie {")
+	fmt.Println("  node [shape=box, fontname=monospace];")
+	for _, n := range nodes {
+		label := fmt.Sprintf("%s\\n%s", dotEscape(strconv.Quote(string(n.Key))), n.Hash)
+		shape := ""
+		if n.Value != nil {
+			label += fmt.Sprintf("\\nvalue: %s", n.Value)
+			shape = ", peripheries=2"
+		}
+		fmt.Printf("  %s [label=\"%s\"%s];\n", dotID(n.Key), label, shape)
+
+		for _, c := range n.Children {
+			key := append(n.Key[:len(n.Key):len(n.Key)], c.Ch)
+			if !walked[string(key)] {
+				fmt.Printf("  %s [label=\"%s\\n%s\", style=dashed];\n", dotID(key), dotEscape(strconv.Quote(string(key))), c.Hash)
+			}
+			fmt.Printf("  %s -> %s [label=\"%s\"];\n", dotID(n.Key), dotID(key), dotEscape(strconv.Quote(string([]byte{c.Ch}))))
+		}
+	}
+	fmt.Println("}")
+}
+
+// dotID returns an identifier of the vertex at the key, which is valid whatever bytes the key has.
+func dotID(key []byte) string {
+	return fmt.Sprintf("\"k%x\"", key)
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
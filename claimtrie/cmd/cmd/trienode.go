@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/block/blockrepo"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(trieNodeCmd)
+}
+
+type jsonTrieChild struct {
+	Key  string `json:"key"`
+	Hash string `json:"hash"`
+}
+
+type jsonTrieNode struct {
+	Key      string          `json:"key"`
+	Hash     string          `json:"hash"`
+	Children []jsonTrieChild `json:"children"`
+	Value    string          `json:"value,omitempty"`
+	Checksum bool            `json:"checksum"`
+}
+
+var trieNodeCmd = &cobra.Command{
+	Use:   "trienode <hash> [<key>]",
+	Short: "Show the stored trie node with the hash, at the key, or searched for from the root of the last block",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		hash, err := chainhash.NewHashFromStr(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid args")
+		}
+
+		repo, err := merkletrierepo.NewPebble(filepath.Join(cfg.DataDir, cfg.MerkleTrieRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open trie repo: %w", err)
+		}
+		trie := merkletrie.New(nil, repo)
+		defer trie.Close()
+
+		var n *merkletrie.RawNode
+		if len(args) == 2 {
+			n, err = trie.NodeAt([]byte(args[1]), hash)
+		} else {
+			var root *chainhash.Hash
+			root, err = lastRoot()
+			if err != nil {
+				return err
+			}
+			n, err = trie.At(root).NodeByHash(hash)
+		}
+		if err != nil {
+			return fmt.Errorf("trie node: %w", err)
+		}
+
+		showTrieNode(n)
+
+		return nil
+	},
+}
+
+// lastRoot returns the Merkle Hash of the last block.
+func lastRoot() (*chainhash.Hash, error) {
+
+	blockRepo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.BlockRepoPebble.Path))
+	if err != nil {
+		return nil, fmt.Errorf("open block repo: %w", err)
+	}
+	defer blockRepo.Close()
+
+	height, err := blockRepo.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load block repo: %w", err)
+	}
+	root, err := blockRepo.Get(height)
+	if err != nil {
+		return nil, fmt.Errorf("get root of block %d: %w", height, err)
+	}
+
+	return root, nil
+}
+
+func showTrieNode(n *merkletrie.RawNode) {
+
+	js := jsonTrieNode{Key: string(n.Key), Hash: n.Hash.String(), Checksum: n.Checksum, Children: []jsonTrieChild{}}
+	for _, c := range n.Children {
+		js.Children = append(js.Children, jsonTrieChild{Key: string(append(n.Key[:len(n.Key):len(n.Key)], c.Ch)), Hash: c.Hash.String()})
+	}
+	if n.Value != nil {
+		js.Value = n.Value.String()
+	}
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(js) // nolint : errchk
+		return
+	}
+
+	fmt.Printf("key: %q, hash: %s, checksum: %t\n", js.Key, js.Hash, js.Checksum)
+	if js.Value != "" {
+		fmt.Printf("  value: %s\n", js.Value)
+	}
+	for _, c := range js.Children {
+		fmt.Printf("  child: %q %s\n", c.Key, c.Hash)
+	}
+}
@@ -1,13 +1,106 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
 	"github.com/btcsuite/btcd/claimtrie/change"
 	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/progress"
 )
 
+// The output formats of the query commands.
+const (
+	formatText  = "text"
+	formatJSONL = "jsonl" // one JSON object per line, for piping into jq and such.
+)
+
+var outputFormat = formatText
+
+var jsonOut = json.NewEncoder(os.Stdout)
+
+type jsonChange struct {
+	Height   int32  `json:"height"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	ClaimID  string `json:"claim_id"`
+	OutPoint string `json:"outpoint"`
+	Amount   int64  `json:"amount"`
+	Value    []byte `json:"value,omitempty"`
+}
+
+type jsonSupport struct {
+	OutPoint   string `json:"outpoint"`
+	AcceptedAt int32  `json:"accepted_at"`
+	ActiveAt   int32  `json:"active_at"`
+	Status     string `json:"status"`
+	Amount     int64  `json:"amount"`
+}
+
+type jsonClaim struct {
+	ClaimID         string        `json:"claim_id"`
+	OutPoint        string        `json:"outpoint"`
+	AcceptedAt      int32         `json:"accepted_at"`
+	ActiveAt        int32         `json:"active_at"`
+	Status          string        `json:"status"`
+	Amount          int64         `json:"amount"`
+	EffectiveAmount int64         `json:"effective_amount"`
+	Best            bool          `json:"best"`
+	Supports        []jsonSupport `json:"supports"`
+}
+
+type jsonNode struct {
+	Name        string      `json:"name"`
+	Height      int32       `json:"height"`
+	TakenOverAt int32       `json:"taken_over_at"`
+	Claims      []jsonClaim `json:"claims"`
+}
+
+type jsonDetailClaim struct {
+	ClaimID         string `json:"claim_id"`
+	OutPoint        string `json:"outpoint"`
+	Amount          int64  `json:"amount"`
+	EffectiveAmount int64  `json:"effective_amount,omitempty"` // of the claims only.
+	AcceptedAt      int32  `json:"accepted_at"`
+	ActiveAt        int32  `json:"active_at"`
+	ExpireAt        int32  `json:"expire_at"`
+	Status          string `json:"status"`
+}
+
+type jsonDetailNode struct {
+	Name        string            `json:"name"`
+	Normalized  string            `json:"normalized"`
+	Height      int32             `json:"height"`
+	Root        string            `json:"root"`
+	BestClaim   string            `json:"best_claim,omitempty"`
+	TakenOverAt int32             `json:"taken_over_at"`
+	Claims      []jsonDetailClaim `json:"claims"`
+	Supports    []jsonDetailClaim `json:"supports"`
+}
+
+type jsonBlock struct {
+	Height int32  `json:"height"`
+	Root   string `json:"root"`
+}
+
+type jsonNames struct {
+	Height int32    `json:"height"`
+	Names  []string `json:"names"`
+}
+
+func validateFormat() error {
+	if outputFormat != formatText && outputFormat != formatJSONL {
+		return fmt.Errorf("unknown format: %q", outputFormat)
+	}
+	return nil
+}
+
 var status = map[node.Status]string{
 	node.Accepted:    "Accepted",
 	node.Activated:   "Activated",
@@ -31,6 +124,10 @@ func changeName(c change.ChangeType) string {
 }
 
 func showChange(chg change.Change) {
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(newJSONChange(chg)) // nolint : errchk
+		return
+	}
 	fmt.Printf(">>> Height: %6d: %s for %04s, %d, %s\n",
 		chg.Height, changeName(chg.Type), chg.ClaimID, chg.Amount, chg.OutPoint)
 }
@@ -50,7 +147,12 @@ func showSupport(c *node.Claim) {
 		c.ClaimID, c.OutPoint, c.AcceptedAt, c.ActiveAt, status[c.Status], c.Amount)
 }
 
-func showNode(n *node.Node) {
+func showNode(name []byte, height int32, n *node.Node) {
+
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(newJSONNode(name, height, n)) // nolint : errchk
+		return
+	}
 
 	fmt.Printf("%s\n", strings.Repeat("-", 200))
 	fmt.Printf("Last Node Takeover: %d\n\n", n.TakenOverAt)
@@ -66,3 +168,213 @@ func showNode(n *node.Node) {
 	}
 	fmt.Printf("\n\n")
 }
+
+// showNodeDetail shows the node the name resolves to, with the expiration heights of its claims and supports,
+// and the supports of the claims not in the node, if any, which showNode leaves out.
+func showNodeDetail(name []byte, res *claimtrie.Resolution) {
+
+	n := res.Node
+	n.SortClaims()
+	if outputFormat == formatJSONL {
+		js := jsonDetailNode{Name: string(name), Normalized: string(res.Name), Height: res.Height,
+			Root: res.Root.String(), TakenOverAt: n.TakenOverAt, Claims: []jsonDetailClaim{}, Supports: []jsonDetailClaim{}}
+		if n.BestClaim != nil {
+			js.BestClaim = n.BestClaim.ClaimID.String()
+		}
+		for _, c := range n.Claims {
+			jc := newJSONDetailClaim(c)
+			jc.EffectiveAmount = c.EffectiveAmount(n.Supports)
+			js.Claims = append(js.Claims, jc)
+		}
+		for _, s := range n.Supports {
+			js.Supports = append(js.Supports, newJSONDetailClaim(s))
+		}
+		jsonOut.Encode(js) // nolint : errchk
+		return
+	}
+
+	fmt.Printf("Name: %q, normalized: %q, height: %d, root: %s\n", name, res.Name, res.Height, res.Root)
+	if n.BestClaim != nil {
+		fmt.Printf("Best Claim: %s, Last Node Takeover: %d\n\n", n.BestClaim.ClaimID, n.TakenOverAt)
+	} else {
+		fmt.Printf("Best Claim: none, Last Node Takeover: %d\n\n", n.TakenOverAt)
+	}
+
+	claims := map[node.ClaimID]bool{}
+	for _, c := range n.Claims {
+		claims[c.ClaimID] = true
+		showClaim(c, n)
+		fmt.Printf("     expires at: %d\n", c.ExpireAt())
+		for _, s := range n.Supports {
+			if s.ClaimID == c.ClaimID {
+				showSupportDetail(s)
+			}
+		}
+	}
+	for _, s := range n.Supports {
+		if !claims[s.ClaimID] {
+			fmt.Printf("\nSupports of the claims not in the node:\n")
+			break
+		}
+	}
+	for _, s := range n.Supports {
+		if !claims[s.ClaimID] {
+			showSupportDetail(s)
+		}
+	}
+}
+
+func showSupportDetail(s *node.Claim) {
+	fmt.Printf("    S id: %s, op: %s, %5d/%-5d, expires at: %-7d, %9s, amt: %15d\n",
+		s.ClaimID, s.OutPoint, s.AcceptedAt, s.ActiveAt, s.ExpireAt(), status[s.Status], s.Amount)
+}
+
+func newJSONDetailClaim(c *node.Claim) jsonDetailClaim {
+	return jsonDetailClaim{
+		ClaimID:    c.ClaimID.String(),
+		OutPoint:   c.OutPoint.String(),
+		Amount:     c.Amount,
+		AcceptedAt: c.AcceptedAt,
+		ActiveAt:   c.ActiveAt,
+		ExpireAt:   c.ExpireAt(),
+		Status:     status[c.Status],
+	}
+}
+
+func showBlock(height int32, hash *chainhash.Hash) {
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(jsonBlock{Height: height, Root: hash.String()}) // nolint : errchk
+		return
+	}
+	fmt.Printf("blk %-7d: %s\n", height, hash.String())
+}
+
+func showNames(height int32, names [][]byte) {
+
+	if outputFormat == formatJSONL {
+		js := jsonNames{Height: height, Names: make([]string, 0, len(names))}
+		for _, name := range names {
+			js.Names = append(js.Names, string(name))
+		}
+		jsonOut.Encode(js) // nolint : errchk
+		return
+	}
+
+	fmt.Printf("%7d: %q", height, names[0])
+	for _, name := range names[1:] {
+		fmt.Printf(", %q ", name)
+	}
+	fmt.Printf("\n")
+}
+
+// showProgress rewrites the progress line of the operation on stderr, which is ended once it's done,
+// so that it doesn't mix with the output of the commands.
+func showProgress(p progress.Progress) {
+	end := ""
+	if p.Done {
+		end = "\n"
+	}
+	fmt.Fprintf(os.Stderr, "\r%s: %d names hashed, %d nodes written, %s%s",
+		p.Op, p.Names, p.Nodes, p.Elapsed.Round(time.Second), end)
+}
+
+// blockRate reports the progress of a replay every reportBlocks blocks, with the rate of the blocks since the
+// last report, and the time left up to the last height, at it, unless it's unbounded.
+type blockRate struct {
+	to     int32
+	height int32
+	at     time.Time
+}
+
+const reportBlocks = 1000
+
+func newBlockRate(from, to int32) *blockRate {
+	return &blockRate{to: to, height: from, at: time.Now()}
+}
+
+// appended reports the progress, once the block at the height is appended, if it's due.
+func (br *blockRate) appended(height int32) {
+
+	if height%reportBlocks != 0 || height <= br.height {
+		return
+	}
+	now := time.Now()
+	rate := float64(height-br.height) / now.Sub(br.at).Seconds()
+	br.height, br.at = height, now
+
+	if br.to == math.MaxInt32 || height >= br.to {
+		fmt.Printf("block: %d, %.0f blocks/s\n", height, rate)
+		return
+	}
+	eta := time.Duration(float64(br.to-height) / rate * float64(time.Second))
+	fmt.Printf("block: %d, %.0f blocks/s, ETA: %s\n", height, rate, eta.Round(time.Second))
+}
+
+func newJSONChange(chg change.Change) jsonChange {
+	return jsonChange{
+		Height:   chg.Height,
+		Type:     changeName(chg.Type),
+		Name:     string(chg.Name),
+		ClaimID:  chg.ClaimID.String(),
+		OutPoint: chg.OutPoint.String(),
+		Amount:   chg.Amount,
+		Value:    chg.Value,
+	}
+}
+
+func newJSONNode(name []byte, height int32, n *node.Node) jsonNode {
+
+	js := jsonNode{Name: string(name), Height: height, TakenOverAt: n.TakenOverAt, Claims: []jsonClaim{}}
+	n.SortClaims()
+	for _, c := range n.Claims {
+		jc := jsonClaim{
+			ClaimID:         c.ClaimID.String(),
+			OutPoint:        c.OutPoint.String(),
+			AcceptedAt:      c.AcceptedAt,
+			ActiveAt:        c.ActiveAt,
+			Status:          status[c.Status],
+			Amount:          c.Amount,
+			EffectiveAmount: c.EffectiveAmount(n.Supports),
+			Best:            c == n.BestClaim,
+			Supports:        []jsonSupport{},
+		}
+		for _, s := range n.Supports {
+			if s.ClaimID != c.ClaimID {
+				continue
+			}
+			jc.Supports = append(jc.Supports, jsonSupport{
+				OutPoint:   s.OutPoint.String(),
+				AcceptedAt: s.AcceptedAt,
+				ActiveAt:   s.ActiveAt,
+				Status:     status[s.Status],
+				Amount:     s.Amount,
+			})
+		}
+		js.Claims = append(js.Claims, jc)
+	}
+
+	return js
+}
+
+func showExpiringName(e expiringName) {
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(e) // nolint : errchk
+		return
+	}
+	fmt.Printf("%7d: %s, %q, Effective Amount: %15d, Supports: %5d\n",
+		e.ExpireAt, e.ClaimID, e.Name, e.EffectiveAmount, e.Supports)
+}
+
+func showCollision(c collision) {
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(c) // nolint : errchk
+		return
+	}
+	fmt.Printf("%q: %q\n", c.Name, c.Names)
+	if c.Winner != nil {
+		fmt.Printf("  winner: %s, %q, Effective Amount: %15d\n", c.Winner.ClaimID, c.Winner.Name, c.Winner.EffectiveAmount)
+	}
+	for _, l := range c.Losers {
+		fmt.Printf("  loser:  %s, %q, Effective Amount: %15d\n", l.ClaimID, l.Name, l.EffectiveAmount)
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/valuehash"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(valueHashCmd)
+
+	valueHashCmd.AddCommand(valueHashBackfillCmd)
+	valueHashCmd.AddCommand(valueHashShowCmd)
+}
+
+type jsonValueHash struct {
+	Name   string `json:"name"`
+	Height int32  `json:"height"`
+	Hash   string `json:"hash,omitempty"`
+}
+
+var valueHashCmd = &cobra.Command{
+	Use:   "valuehash",
+	Short: "Value hash audit index related commands",
+}
+
+var valueHashBackfillCmd = &cobra.Command{
+	Use:   "backfill <from_height> <to_height>",
+	Short: "Index the value hashes of the names updated by the blocks appended before they were indexed",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		from, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid args")
+		}
+		to, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid args")
+		}
+
+		cfg.ValueHashIndex = true
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		recorded, err := ct.BackfillValueHashes(int32(from), int32(to))
+		if err != nil {
+			return fmt.Errorf("backfill value hashes: %w", err)
+		}
+		fmt.Printf("Indexed the value hashes of %d names\n", recorded)
+
+		return nil
+	},
+}
+
+var valueHashShowCmd = &cobra.Command{
+	Use:   "show <name> [<height>]",
+	Short: "Show the value hashes of a name at the heights they changed at, or the one as of a height",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		cfg.ValueHashIndex = true
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+
+		name := []byte(args[0])
+		if len(args) == 2 {
+			height, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid args")
+			}
+			h, err := ct.ValueHashAt(name, int32(height))
+			if err != nil {
+				return fmt.Errorf("value hash at: %w", err)
+			}
+			showValueHash(name, valuehash.Entry{Height: int32(height), Hash: h})
+			return nil
+		}
+
+		entries, err := ct.ValueHashHistory(name)
+		if err != nil {
+			return fmt.Errorf("value hash history: %w", err)
+		}
+		for _, e := range entries {
+			showValueHash(name, e)
+		}
+
+		return nil
+	},
+}
+
+func showValueHash(name []byte, e valuehash.Entry) {
+	js := jsonValueHash{Name: string(name), Height: e.Height}
+	if e.Hash != nil {
+		js.Hash = e.Hash.String()
+	}
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(js) // nolint : errchk
+		return
+	}
+	hash := js.Hash
+	if hash == "" {
+		hash = "(none)"
+	}
+	fmt.Printf("%s %7d %s\n", js.Name, js.Height, hash)
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/block/blockrepo"
+	"github.com/btcsuite/btcd/claimtrie/chain/chainrepo"
+	"github.com/btcsuite/btcd/claimtrie/change"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().Int32Var(&verifyFrom, "from", 2, "height to verify from")
+	verifyCmd.Flags().Int32Var(&verifyTo, "to", math.MaxInt32, "height to verify up to, and including")
+}
+
+var (
+	verifyFrom int32
+	verifyTo   int32
+)
+
+type jsonRootDiff struct {
+	Name    string `json:"name"`
+	Before  string `json:"before,omitempty"`
+	After   string `json:"after,omitempty"`
+	Rebuilt string `json:"rebuilt,omitempty"`
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify --from <height> --to <height>",
+	Short: "Verify the roots of the blocks in the range against the reported ones, and diff the tries at a mismatch",
+	Long: "Replay the changes of the chain repo over the range, resetting the stored state to the height before --from,\n" +
+		"or catching it up to it, and verify the root of each block against the reported one. At a mismatch, the trie\n" +
+		"is walked against the one of the block before, and the names, of which the value hashes differ, are listed\n" +
+		"with the value hashes of their nodes rebuilt from their changes; a '!' marks the ones differing from the trie:\n" +
+		"  claimtrie verify --from 1000000 --to 1010000",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if err := validateFormat(); err != nil {
+			return err
+		}
+		if verifyFrom < 2 || verifyFrom > verifyTo {
+			return fmt.Errorf("invalid range: %d to %d", verifyFrom, verifyTo)
+		}
+
+		chainRepo, err := chainrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ChainRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open change repo: %w", err)
+		}
+		defer chainRepo.Close()
+
+		reportedBlockRepo, err := blockrepo.NewPebble(filepath.Join(cfg.DataDir, cfg.ReportedBlockRepoPebble.Path))
+		if err != nil {
+			return fmt.Errorf("open block repo: %w", err)
+		}
+		defer reportedBlockRepo.Close()
+
+		ct, err := claimtrie.New(cfg)
+		if err != nil {
+			return fmt.Errorf("create claimtrie: %w", err)
+		}
+		defer ct.Close()
+		ct.SetProgress(showProgress)
+
+		last, err := reportedBlockRepo.Load()
+		if err != nil {
+			return fmt.Errorf("load reported height: %w", err)
+		}
+		toHeight := verifyTo
+		if toHeight > last {
+			toHeight = last
+		}
+
+		if ct.Height() >= verifyFrom {
+			err = ct.ResetHeight(verifyFrom - 1)
+			if err != nil {
+				return fmt.Errorf("reset claimtrie height: %w", err)
+			}
+		} else if ct.Height() < verifyFrom-1 {
+			fmt.Printf("Catching up from %d, before %d\n", ct.Height()+1, verifyFrom)
+		}
+
+		verified := 0
+		prev := *ct.MerkleHash()
+		for height := ct.Height() + 1; height <= toHeight; height++ {
+
+			changes, err := chainRepo.Load(height)
+			if err != nil && err != pebble.ErrNotFound {
+				return fmt.Errorf("load from change repo: %w", err)
+			}
+			for _, chg := range changes {
+				err = applyChange(ct, chg)
+				if err != nil {
+					return fmt.Errorf("execute change %d of block %d: %w", chg.Seq, height, change.Wrap(err, chg))
+				}
+			}
+			err = ct.AppendBlock()
+			if err != nil {
+				return fmt.Errorf("append block: %w", err)
+			}
+
+			hash, err := reportedBlockRepo.Get(height)
+			if err != nil {
+				return fmt.Errorf("load from block repo: %w", err)
+			}
+			if *ct.MerkleHash() != *hash {
+				fmt.Printf("hash mismatched at height %5d: exp: %s, got: %s\n", height, hash, ct.MerkleHash())
+				return diagnoseRoot(ct, &prev)
+			}
+			prev = *hash
+			if height >= verifyFrom {
+				verified++
+			}
+			if height%1000 == 0 {
+				fmt.Printf("block: %d\n", height)
+			}
+		}
+
+		fmt.Printf("Verified %d blocks up to %d, root: %s\n", verified, ct.Height(), ct.MerkleHash())
+
+		return nil
+	},
+}
+
+// diagnoseRoot lists the names, of which the value hashes differ between the trie at prev, the last root verified,
+// and the mismatched one.
+func diagnoseRoot(ct *claimtrie.ClaimTrie, prev *chainhash.Hash) error {
+
+	diffs, err := ct.DiffRoot(prev)
+	if err != nil {
+		return fmt.Errorf("diff root: %w", err)
+	}
+
+	rebuilt := 0
+	for _, d := range diffs {
+		if !sameHash(d.After, d.Rebuilt) {
+			rebuilt++
+		}
+		showRootDiff(d)
+	}
+
+	return fmt.Errorf("hash mismatched at height %d: %d names differ from the block before, %d of them from their changes",
+		ct.Height(), len(diffs), rebuilt)
+}
+
+func showRootDiff(d claimtrie.RootDiff) {
+
+	hex := func(h *chainhash.Hash) string {
+		if h == nil {
+			return ""
+		}
+		return h.String()
+	}
+	if outputFormat == formatJSONL {
+		jsonOut.Encode(jsonRootDiff{Name: string(d.Name), Before: hex(d.Before), After: hex(d.After), Rebuilt: hex(d.Rebuilt)}) // nolint : errchk
+		return
+	}
+
+	mark := " "
+	if !sameHash(d.After, d.Rebuilt) {
+		mark = "!"
+	}
+	fmt.Printf("%s %q\n    before:  %s\n    after:   %s\n    rebuilt: %s\n", mark, d.Name, hex(d.Before), hex(d.After), hex(d.Rebuilt))
+}
+
+func sameHash(a, b *chainhash.Hash) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
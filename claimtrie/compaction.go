@@ -0,0 +1,96 @@
+package claimtrie
+
+import (
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/progress"
+)
+
+type compacter interface {
+	Compact() error
+}
+
+// compactionScheduler compacts the repos in the background, once enough changes were made
+// since the last compaction, and the time is within a window, if there are any.
+type compactionScheduler struct {
+	repos     map[string]compacter
+	threshold int
+	windows   []config.TimeWindow
+	now       func() time.Time
+
+	onProgress progress.Func
+
+	pending int
+	running bool
+
+	mu sync.Mutex
+	wg sync.WaitGroup
+}
+
+func newCompactionScheduler(repos map[string]compacter, threshold int, windows []config.TimeWindow) *compactionScheduler {
+	return &compactionScheduler{
+		repos:     repos,
+		threshold: threshold,
+		windows:   windows,
+		now:       time.Now,
+	}
+}
+
+// add accounts for the changes, and starts a compaction if it's due.
+func (s *compactionScheduler) add(changes int) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending += changes
+	if s.running || s.pending < s.threshold || !s.inWindow(s.now()) {
+		return
+	}
+	s.pending = 0
+	s.running = true
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.compact()
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+}
+
+func (s *compactionScheduler) inWindow(t time.Time) bool {
+
+	if len(s.windows) == 0 {
+		return true
+	}
+	for _, w := range s.windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *compactionScheduler) compact() {
+	for name, repo := range s.repos {
+		start := time.Now()
+		meter := progress.Start("compact "+name, s.onProgress)
+		err := repo.Compact()
+		meter.Stop()
+		if err != nil {
+			log.Warnf("Compacting the %s repo: %s", name, err)
+			continue
+		}
+		log.Infof("Compacted the %s repo in %s", name, time.Since(start))
+	}
+}
+
+// wait waits for the running compaction, if any, to finish.
+func (s *compactionScheduler) wait() error {
+	s.wg.Wait()
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/btcsuite/btcutil"
 )
@@ -13,16 +14,19 @@ var DefaultConfig = Config{
 	DataDir: filepath.Join(btcutil.AppDataDir("chain", false), "data", "mainnet", "claim_dbs"),
 
 	BlockRepoPebble: pebbleConfig{
-		Path: "blocks_pebble_db",
+		Path:   "blocks_pebble_db",
+		Prefix: "b",
 	},
 	NodeRepoPebble: pebbleConfig{
-		Path: "node_change_pebble_db",
+		Path:   "node_change_pebble_db",
+		Prefix: "n",
 	},
 	TemporalRepoPebble: pebbleConfig{
 		Path: "temporal_pebble_db",
 	},
 	MerkleTrieRepoPebble: pebbleConfig{
-		Path: "merkletrie_pebble_db",
+		Path:   "merkletrie_pebble_db",
+		Prefix: "t",
 	},
 	ChainRepoPebble: pebbleConfig{
 		Path: "chain_pebble_db",
@@ -30,8 +34,85 @@ var DefaultConfig = Config{
 	ReportedBlockRepoPebble: pebbleConfig{
 		Path: "reported_blocks_pebble_db",
 	},
+
+	TrieCheckpointPath: "merkletrie_checkpoints",
+
+	SupportExpiringRepoPebble: pebbleConfig{
+		Path: "support_expiring_pebble_db",
+	},
+
+	NameActivityRepoPebble: pebbleConfig{
+		Path: "name_activity_pebble_db",
+	},
+
+	TakeoverRepoPebble: pebbleConfig{
+		Path: "takeover_pebble_db",
+	},
+
+	ValueHashRepoPebble: pebbleConfig{
+		Path: "value_hash_pebble_db",
+	},
+
+	ClaimHistoryRepoPebble: pebbleConfig{
+		Path: "claim_history_pebble_db",
+	},
+
+	OutPointRepoPebble: pebbleConfig{
+		Path: "outpoint_pebble_db",
+	},
+
+	ClaimIDRepoPebble: pebbleConfig{
+		Path: "claim_id_pebble_db",
+	},
+
+	ValueRepoPebble: pebbleConfig{
+		Path: "value_pebble_db",
+	},
+
+	NodeManager:           NodeManagerReplay,
+	NodeSnapshotThreshold: 100,
+	NodeSnapshotRepoPebble: pebbleConfig{
+		Path: "node_snapshot_pebble_db",
+	},
+
+	ChangeRetention: RetainAll,
+	PruneInterval:   1000,
+	NodeBaseRepoPebble: pebbleConfig{
+		Path: "node_base_pebble_db",
+	},
+
+	UpstreamInterval: time.Hour,
+
+	HistoryReadWait: 10 * time.Second,
 }
 
+// The strategies of materializing the nodes.
+const (
+	NodeManagerReplay   = "replay"   // replays all the changes of a node.
+	NodeManagerSnapshot = "snapshot" // replays the changes since the snapshot of a node.
+)
+
+// The retention policies of the change history of the nodes.
+const (
+	RetainAll    = "all"    // keeps all the changes, which allows resetting to any height.
+	RetainBlocks = "blocks" // keeps the changes of the last ChangeRetentionBlocks blocks.
+	RetainNone   = "none"   // keeps only the changes since the nodes were last pruned.
+)
+
+// The policies of the claims sharing a Claim ID, or matching the same prefix of one, in the claim ID index.
+const (
+	CollisionsStrict = "strict" // the lookups matching several claims fail; the blocks are indexed regardless.
+	CollisionsFirst  = "first"  // the lookups return the claim accepted first.
+	CollisionsBoth   = "both"   // the lookups return all the claims, told apart by their outpoints.
+)
+
+// The storage of the values of the claims and supports.
+const (
+	ValuesInline     = "inline"      // keeps the values in the changes of the node repo.
+	ValuesBlobs      = "blobs"       // keeps the values of at least ValueBlobSize bytes in the value repo, fetched on demand.
+	ValuesHashesOnly = "hashes-only" // drops the values, for resolving the names, and proving them, only.
+)
+
 // Config is the container of all configurations.
 type Config struct {
 	Record  bool
@@ -39,15 +120,239 @@ type Config struct {
 
 	DataDir string
 
+	// If Network is set, New sets the params of the network of the name, one of param.NetworkNames, instead of
+	// the ones set by param.SetNetwork, and overrides them with ParamOverrides, of the form of param.ParseOverride,
+	// without a height, such as to shorten the expirations and delays of a regtest for the integration tests.
+	Network        string
+	ParamOverrides []string
+
 	BlockRepoPebble      pebbleConfig
 	NodeRepoPebble       pebbleConfig
 	TemporalRepoPebble   pebbleConfig
 	MerkleTrieRepoPebble pebbleConfig
 
+	// If MerkleTrieRemote is set, the trie is backed by the KV store at the address,
+	// which speaks the remote KV protocol of merkletrierepo, instead of MerkleTrieRepoPebble.
+	MerkleTrieRemote string
+
+	// If SharedRepoPebble.Path is set, the block, node and trie repos share the
+	// DB, and their keys are prefixed with the Prefix of their configs instead.
+	SharedRepoPebble pebbleConfig
+
 	ChainRepoPebble         pebbleConfig
 	ReportedBlockRepoPebble pebbleConfig
+
+	// Chain a digest to the recorded changes of each block, and verify it on load.
+	ChainRepoDigests bool
+
+	// Checkpoints of the trie repo for read-only replicas are written to
+	// TrieCheckpointPath every TrieCheckpointInterval blocks, if it's set.
+	TrieCheckpointPath     string
+	TrieCheckpointInterval int32
+
+	// Up to TrieCheckpointDeltas differential checkpoints, holding only the keys missing from
+	// the last full checkpoint, are written between the full ones, if it's set.
+	TrieCheckpointDeltas int32
+
+	// PruneTrie deletes the nodes of the trie repo, which aren't reachable from the roots of the last
+	// TrieRetentionBlocks blocks, if it's set. The ClaimTrie can't be reset, nor proven at, past them after.
+	TrieRetentionBlocks int32
+
+	// SupportExpiring events are emitted the specified number of blocks
+	// before supports expire, if it's set.
+	SupportExpiringNotice     int32
+	SupportExpiringRepoPebble pebbleConfig
+
+	// Blocks and names taking longer than the thresholds to process are logged, if they're set.
+	SlowBlockThreshold time.Duration
+	SlowNameThreshold  time.Duration
+
+	// The stages of processing a block taking longer than their budgets, keyed by the stages,
+	// emit BudgetExceeded events, and are counted in the alerts of the stats.
+	Budgets map[string]time.Duration
+
+	// Events are POSTed to the webhooks, if any.
+	Webhooks []WebhookConfig
+
+	// Aggregates of the claims signed by each channel are maintained, if it's set.
+	ChannelStats bool
+
+	// The claims are indexed by their Claim IDs, if ClaimIDIndex is set. ClaimIDCollisions is the
+	// policy of the claims sharing an ID, or a prefix of one. It's CollisionsStrict, if it's empty.
+	ClaimIDIndex      bool
+	ClaimIDCollisions string
+	ClaimIDRepoPebble pebbleConfig
+
+	// Names are ranked by the effective amounts of their best claims, if it's set.
+	TopNames bool
+
+	// Names are indexed by their tokens, for searching them by prefixes and substrings, if it's set.
+	NameSearch bool
+
+	// The claims and supports are indexed by their outpoints, for spending them by those alone, if it's set.
+	OutPointIndex      bool
+	OutPointRepoPebble pebbleConfig
+
+	// ValueStorage is how the values of the claims and supports are stored. It's ValuesInline, if it's empty.
+	// It applies to the changes appended since it's set, and the values kept in the value repo are read under ValuesBlobs only.
+	ValueStorage    string
+	ValueBlobSize   int
+	ValueRepoPebble pebbleConfig
+
+	// The values of the claims and supports, in the node, node snapshot, chain and value repos, are encrypted at
+	// rest with the key in EncryptionKeyFile, or the one EncryptionKMS refers to, as "<scheme>:<ref>", by the
+	// source registered with encryptedrepo.RegisterKeySource, if either is set. It must be the same across restarts.
+	EncryptionKeyFile string
+	EncryptionKMS     string
+
+	// The caches of the trie and the nodes are kept within this many bytes, if it's set.
+	MemoryBudget int64
+
+	// Up to this many of the trie nodes committed by a block are read ahead of the next one, while its names
+	// are updated, once the trie was dropped from memory in between, such as to keep within the MemoryBudget.
+	TriePrefetch int
+
+	// The stored trie nodes resolved are cached within this many bytes, apart from the MemoryBudget, if it's set,
+	// so the paths resolved again, once the trie was dropped from memory, aren't read from the trie repo.
+	TrieNodeCache int64
+
+	// The trie nodes hashed by a block are written in the background, once they're over this many bytes, while
+	// the hashing goes on, if it's set. They're all written before the root of the block still.
+	TrieAsyncWrites int
+
+	// While the ClaimTrie is set to batch, as during the initial block download, the writes of the block, trie
+	// and node snapshot repos are batched across the blocks, and committed every BatchBlocks blocks, or once
+	// they're over BatchBytes, if it's set. It's never set to, unless BatchBlocks is set.
+	BatchBlocks int32
+	BatchBytes  int64
+
+	// Names are indexed by the heights they were first seen, and last active at, along with the ones they became
+	// claimed, or unclaimed, at, if it's set.
+	NameActivity           bool
+	NameActivityRepoPebble pebbleConfig
+
+	// The claims for the names at each takeover are logged, and attached to the Takeover events, if it's set.
+	// They're also saved to the TakeoverRepoPebble, if TakeoverRecord is set.
+	TakeoverDiagnostics bool
+	TakeoverRecord      bool
+	TakeoverRepoPebble  pebbleConfig
+
+	// The leaf value hashes of the names are indexed by the heights they changed at, for auditing the trie, if it's set.
+	ValueHashIndex      bool
+	ValueHashRepoPebble pebbleConfig
+
+	// The takeovers of the names, and the heights they're left without a best claim at, are recorded to the
+	// ClaimHistoryRepoPebble, for the queries of the best claims of the names as of the heights, if it's set.
+	ClaimHistory           bool
+	ClaimHistoryRepoPebble pebbleConfig
+
+	// Claims added with the TXO of existing ones are rejected, instead of being kept along with them, if it's set.
+	StrictConflicts bool
+
+	// The spends, and updates, of the claims and supports missing from the nodes are rejected, with
+	// node.ErrMissingClaim, or node.ErrMissingSupport, instead of being logged, and ignored, if it's set.
+	StrictSpends bool
+
+	// The trie and node repos are compacted in the background once this many changes were
+	// appended, or names were reset, since the last compaction, if it's set.
+	// Only within the windows of the local time, if there are any.
+	CompactionThreshold int
+	CompactionWindows   []TimeWindow
+
+	// NodeManager is the strategy of materializing the nodes. With NodeManagerSnapshot, a
+	// snapshot of a node is saved once more than NodeSnapshotThreshold changes were replayed.
+	NodeManager            string
+	NodeSnapshotThreshold  int
+	NodeSnapshotRepoPebble pebbleConfig
+
+	// ChangeRetention is the retention policy of the change history, which requires NodeManagerSnapshot,
+	// unless it's RetainAll. Every PruneInterval blocks, the nodes are saved as their base snapshots to
+	// NodeBaseRepoPebble, and their changes before the retained ones are dropped.
+	// The ClaimTrie can't be reset past the pruned heights after.
+	ChangeRetention       string
+	ChangeRetentionBlocks int32
+	PruneInterval         int32
+	NodeBaseRepoPebble    pebbleConfig
+
+	// The change histories of the names, of which the nodes have been left with no claims, or supports, for
+	// ExpiredCompactionBlocks blocks, are replaced with the tombstones saved to NodeBaseRepoPebble every
+	// PruneInterval blocks, if it's set, which requires NodeManagerSnapshot too. The ClaimTrie can't be reset
+	// past the tombstones after, while the other names are still resolved before them from their changes.
+	ExpiredCompactionBlocks int32
+
+	// The ClaimTrie can't be reset more than this many blocks back, if it's set.
+	// The names updated at the heights before are pruned, as they're only kept for the resets.
+	MaxReorgDepth int32
+
+	// The trie is cross-validated with a RamTrie, which is updated along with it, if it's set.
+	// A block, after which their roots differ, fails. The RamTrie holds all the names in memory.
+	CrossValidateTrie bool
+
+	// The Merkle Hash of all the claims is computed by this many workers, instead of one per CPU, if it's set.
+	HashWorkers int
+
+	// The claims and supports dumped to this file, in the COPY format of the chain repo, are added
+	// at height 0, before any block, if it's set and the ClaimTrie is empty, such as for the fixtures
+	// of the private networks. The root of the trie with them is recorded as the one of height 0.
+	GenesisClaims string
+
+	// Once no block was appended for this long, the names due to be updated by the next blocks are
+	// warmed up in the background, if it's set: the nodes replaying the most changes are snapshotted
+	// first, with NodeManagerSnapshot, and the stored trie nodes along their paths are read.
+	IdleWarmup time.Duration
+
+	// The leaf hashes of up to this many names are cached for the trie, if it's set. The ones of
+	// the names updated by a block are refreshed along with them, so hashing it loads no nodes.
+	ValueCacheSize int
+
+	// The nodes materialized at up to this many (name, height) pairs by the historical queries, such
+	// as ResolveAt, are cached, if it's set, so that the bursts of them don't replay the same changes.
+	HistoryCacheSize int
+
+	// The nodes changed in the last NodeVersionDepth blocks are kept, as of the heights of their changes, if it's
+	// set, so that the queries at the recent heights, and the blocks appended, don't rebuild them from their changes.
+	NodeVersionDepth int32
+
+	// The historical queries wait up to this long for a reset, or a prune, rewriting the history,
+	// before failing with ErrHistoryBusy, if it's set, while the ones started before it aren't held up.
+	HistoryReadWait time.Duration
+
+	// The names dirtied by each of the last DeltaSyncBlocks blocks, and their leaf hashes, are
+	// published to the followers, if it's set, and served on DeltaSyncListen, if that's set too.
+	DeltaSyncBlocks int
+	DeltaSyncListen string
+
+	// The changes of each of the last StandbyBlocks blocks, and their roots, are published to the
+	// standbys, if it's set, and served on StandbyListen, if that's set too.
+	StandbyBlocks int
+	StandbyListen string
+
+	// The roots are verified against the checkpoints published at UpstreamURL, signed by the
+	// hex encoded UpstreamKey, and refreshed every UpstreamInterval, if it's set.
+	// A divergence is logged, counted and emitted as an event.
+	UpstreamURL      string
+	UpstreamKey      string
+	UpstreamInterval time.Duration
+
+	// The metrics of appending the blocks, such as the time taken by their stages, the changes applied by type,
+	// and the latency of the node and trie repos, are recorded, if it's set, and served in the Prometheus text
+	// format at /metrics on MetricsListen, if that's set too.
+	Metrics       bool
+	MetricsListen string
+}
+
+// WebhookConfig specifies the URL, to which the events of the specified types,
+// and about the watched names or channels, are POSTed.
+// Empty lists match everything.
+type WebhookConfig struct {
+	URL      string
+	Events   []string
+	Names    []string
+	Channels []string
 }
 
 type pebbleConfig struct {
-	Path string
+	Path      string
+	Prefix    string // only used in the SharedRepoPebble.
+	CacheSize int64  // bytes of the block cache; the default of the repo, if it's not set.
 }
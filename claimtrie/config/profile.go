@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// The profiles tuning the ClaimTrie for classes of hardware.
+const (
+	ProfileDesktop     = "desktop"      // the defaults of the repos, on a few GiB of RAM.
+	ProfileServer      = "server"       // large caches, snapshots and background compactions.
+	ProfileRaspberryPi = "raspberry-pi" // small caches and few workers, within 512 MiB.
+)
+
+// Profiles preset the caches, the parallelism and the Pebble options of the ClaimTrie.
+var Profiles = map[string]func(c *Config){
+	ProfileDesktop: func(c *Config) {
+		c.setCacheSizes(128<<20, 128<<20, 512<<20)
+		c.HashWorkers = runtime.NumCPU()
+		c.MemoryBudget = 2 << 30
+		c.NodeManager = NodeManagerReplay
+	},
+	ProfileServer: func(c *Config) {
+		c.setCacheSizes(512<<20, 256<<20, 2<<30)
+		c.HashWorkers = runtime.NumCPU()
+		c.MemoryBudget = 0
+		c.NodeManager = NodeManagerSnapshot
+		c.CompactionThreshold = 1000000
+	},
+	ProfileRaspberryPi: func(c *Config) {
+		c.setCacheSizes(32<<20, 16<<20, 64<<20)
+		c.HashWorkers = 2
+		c.MemoryBudget = 512 << 20
+		c.NodeManager = NodeManagerSnapshot
+		c.CompactionThreshold = 200000
+	},
+}
+
+// ApplyProfile presets the config with the named profile. The settings
+// outside of the profile are left alone, and can be overridden after.
+func (c *Config) ApplyProfile(name string) error {
+
+	apply, ok := Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(Profiles))
+		for name := range Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown profile: %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+
+	apply(c)
+	return nil
+}
+
+func (c *Config) setCacheSizes(node, temporal, trie int64) {
+	c.NodeRepoPebble.CacheSize = node
+	c.TemporalRepoPebble.CacheSize = temporal
+	c.MerkleTrieRepoPebble.CacheSize = trie
+	c.SharedRepoPebble.CacheSize = node + trie
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeWindow is a daily period of the local time, as offsets from midnight.
+// It wraps around midnight if End is before Start.
+type TimeWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Contains reports whether t is within the window.
+func (w TimeWindow) Contains(t time.Time) bool {
+
+	y, m, d := t.Date()
+	offset := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+
+	return offset >= w.Start || offset < w.End
+}
+
+// ParseTimeWindows parses a comma separated list of windows of the form "HH:MM-HH:MM".
+func ParseTimeWindows(s string) ([]TimeWindow, error) {
+
+	var windows []TimeWindow
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		bounds := strings.Split(f, "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid time window: %s", f)
+		}
+		start, err := parseTimeOfDay(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid time window %s: %w", f, err)
+		}
+		end, err := parseTimeOfDay(bounds[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid time window %s: %w", f, err)
+		}
+		windows = append(windows, TimeWindow{Start: start, End: end})
+	}
+
+	return windows, nil
+}
+
+// ParseBudgets parses a comma separated list of budgets of the form "stage=duration", such as "block=500ms".
+func ParseBudgets(s string) (map[string]time.Duration, error) {
+
+	budgets := map[string]time.Duration{}
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid budget: %s", f)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid budget %s: %w", f, err)
+		}
+		budgets[strings.TrimSpace(kv[0])] = d
+	}
+
+	return budgets, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
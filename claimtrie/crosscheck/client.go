@@ -0,0 +1,197 @@
+package crosscheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+)
+
+// maxResponseSize bounds the responses read, as of the names with many claims.
+const maxResponseSize = 64 << 20
+
+// Client queries a running lbrycrd node over its JSON-RPC.
+type Client struct {
+	URL      string
+	User     string
+	Password string
+	HTTP     *http.Client
+
+	id int64
+}
+
+// NewClient returns the Client of the node at the URL, such as http://127.0.0.1:9245, with the credentials of the
+// rpcuser and rpcpassword of its config.
+func NewClient(url, user, password string) *Client {
+	return &Client{URL: url, User: user, Password: password, HTTP: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// RPCError is an error returned by the node, such as for a block it doesn't have.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+type request struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int64         `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+func (c *Client) call(method string, result interface{}, params ...interface{}) error {
+
+	b, err := json.Marshal(request{JSONRPC: "1.0", ID: atomic.AddInt64(&c.id, 1), Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", method, err)
+	}
+	req, err := http.NewRequest(http.MethodPost, c.URL, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(c.User, c.Password)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("post %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	// The node replies to the errors with a status code of 500, along with the error in the body.
+	var r response
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&r)
+	if err != nil {
+		return fmt.Errorf("decode %s: status %d: %w", method, resp.StatusCode, err)
+	}
+	if r.Error != nil {
+		return fmt.Errorf("%s: %w", method, r.Error)
+	}
+	err = json.Unmarshal(r.Result, result)
+	if err != nil {
+		return fmt.Errorf("decode %s result: %w", method, err)
+	}
+
+	return nil
+}
+
+// BlockHash returns the hash of the block at the height, in the main chain of the node.
+func (c *Client) BlockHash(height int32) (string, error) {
+	var hash string
+	err := c.call("getblockhash", &hash, height)
+	return hash, err
+}
+
+// Root returns the root of the ClaimTrie, as of the block, from its header.
+func (c *Client) Root(blockHash string) (*chainhash.Hash, error) {
+
+	var header struct {
+		NameClaimRoot string `json:"nameclaimroot"`
+	}
+	err := c.call("getblockheader", &header, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	root, err := chainhash.NewHashFromStr(header.NameClaimRoot)
+	if err != nil {
+		return nil, fmt.Errorf("decode nameclaimroot of %s: %w", blockHash, err)
+	}
+
+	return root, nil
+}
+
+// Claim is a claim of a name, as the node reports it.
+type Claim struct {
+	ClaimID         string
+	TxID            string
+	N               uint32
+	Amount          int64 // In dewies, as are the rest.
+	EffectiveAmount int64
+}
+
+// Claims are the claims of a name, as of a block, as the node reports them.
+type Claims struct {
+	Claims             []Claim
+	LastTakeoverHeight int32
+}
+
+type claimJSON struct {
+	ClaimID         string  `json:"claimId"`
+	TxID            string  `json:"txId"`
+	N               uint32  `json:"n"`
+	Amount          float64 `json:"amount"`
+	EffectiveAmount float64 `json:"effectiveAmount"`
+}
+
+func (c claimJSON) claim() (Claim, error) {
+
+	amt, err := btcutil.NewAmount(c.Amount)
+	if err != nil {
+		return Claim{}, fmt.Errorf("amount of %s: %w", c.ClaimID, err)
+	}
+	eff, err := btcutil.NewAmount(c.EffectiveAmount)
+	if err != nil {
+		return Claim{}, fmt.Errorf("effective amount of %s: %w", c.ClaimID, err)
+	}
+
+	return Claim{ClaimID: c.ClaimID, TxID: c.TxID, N: c.N, Amount: int64(amt), EffectiveAmount: int64(eff)}, nil
+}
+
+// ClaimsForName returns the claims of the name, as of the block, as getclaimsforname does.
+func (c *Client) ClaimsForName(name []byte, blockHash string) (*Claims, error) {
+
+	var res struct {
+		Claims             []claimJSON `json:"claims"`
+		LastTakeoverHeight int32       `json:"lastTakeoverHeight"`
+	}
+	err := c.call("getclaimsforname", &res, string(name), blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := &Claims{LastTakeoverHeight: res.LastTakeoverHeight}
+	for _, cj := range res.Claims {
+		claim, err := cj.claim()
+		if err != nil {
+			return nil, err
+		}
+		cs.Claims = append(cs.Claims, claim)
+	}
+
+	return cs, nil
+}
+
+// ValueForName returns the winning claim of the name, as of the block, as getvalueforname does, or nil, if it has
+// none.
+func (c *Client) ValueForName(name []byte, blockHash string) (*Claim, error) {
+
+	var res claimJSON
+	err := c.call("getvalueforname", &res, string(name), blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if res.ClaimID == "" {
+		return nil, nil
+	}
+	claim, err := res.claim()
+	if err != nil {
+		return nil, err
+	}
+
+	return &claim, nil
+}
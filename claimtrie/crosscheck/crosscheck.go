@@ -0,0 +1,201 @@
+// Package crosscheck compares the ClaimTrie against a running lbrycrd node, over its JSON-RPC, for a range of heights:
+// the roots of the blocks, and the winners, effective amounts and takeover heights of the names, which it reports
+// the divergences of, for gaining confidence in the ClaimTrie before relying on it for consensus.
+package crosscheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// Local is the ClaimTrie compared against the node.
+type Local interface {
+	RootAt(height int32) (*chainhash.Hash, error)
+	ResolveAtContext(ctx context.Context, name []byte, height int32) (*claimtrie.Resolution, error)
+}
+
+var _ Local = (*claimtrie.ClaimTrie)(nil)
+
+// The fields compared.
+const (
+	FieldRoot            = "root"
+	FieldWinner          = "winner"
+	FieldTakeoverHeight  = "takeover height"
+	FieldEffectiveAmount = "effective amount"
+	FieldClaim           = "claim" // Of a claim listed by only one of them.
+)
+
+// Divergence is a field, of which the values differ between the ClaimTrie and the node, at a height.
+// An empty value is a missing one, such as of a name without a winner.
+type Divergence struct {
+	Height  int32
+	Name    []byte // Empty for the root.
+	ClaimID string // Set for the effective amounts, and the claims.
+	Field   string
+	Local   string
+	Remote  string
+}
+
+func (d Divergence) String() string {
+	if len(d.Name) == 0 {
+		return fmt.Sprintf("%d: %s: local: %s, remote: %s", d.Height, d.Field, d.Local, d.Remote)
+	}
+	if d.ClaimID != "" {
+		return fmt.Sprintf("%d: %q: %s of %s: local: %s, remote: %s", d.Height, d.Name, d.Field, d.ClaimID, d.Local, d.Remote)
+	}
+	return fmt.Sprintf("%d: %q: %s: local: %s, remote: %s", d.Height, d.Name, d.Field, d.Local, d.Remote)
+}
+
+// Report is the outcome of comparing a range of heights.
+type Report struct {
+	From, To    int32
+	Heights     int
+	Names       int // Compared, at all the heights.
+	Divergences []Divergence
+}
+
+// Checker compares the ClaimTrie against the node.
+type Checker struct {
+	Local  Local
+	Remote *Client
+}
+
+// Check compares the roots of the heights in the range, inclusive, and the names returned by names for each of
+// them, such as the ones changed at it, until the context is done.
+func (c *Checker) Check(ctx context.Context, from, to int32, names func(height int32) ([][]byte, error)) (*Report, error) {
+
+	report := &Report{From: from, To: to}
+	for height := from; height <= to; height++ {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		ns, err := names(height)
+		if err != nil {
+			return report, fmt.Errorf("names at %d: %w", height, err)
+		}
+		divs, err := c.CheckHeight(ctx, height, ns)
+		if err != nil {
+			return report, err
+		}
+		report.Heights++
+		report.Names += len(ns)
+		report.Divergences = append(report.Divergences, divs...)
+	}
+
+	return report, nil
+}
+
+// CheckHeight compares the root at the height, and the names, as of it.
+func (c *Checker) CheckHeight(ctx context.Context, height int32, names [][]byte) ([]Divergence, error) {
+
+	blockHash, err := c.Remote.BlockHash(height)
+	if err != nil {
+		return nil, fmt.Errorf("remote block hash at %d: %w", height, err)
+	}
+
+	var divs []Divergence
+	local, err := c.Local.RootAt(height)
+	if err != nil {
+		return nil, fmt.Errorf("local root at %d: %w", height, err)
+	}
+	remote, err := c.Remote.Root(blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("remote root at %d: %w", height, err)
+	}
+	if *local != *remote {
+		divs = append(divs, Divergence{Height: height, Field: FieldRoot, Local: local.String(), Remote: remote.String()})
+	}
+
+	for _, name := range names {
+		nd, err := c.checkName(ctx, height, blockHash, name)
+		if err != nil {
+			return nil, err
+		}
+		divs = append(divs, nd...)
+	}
+
+	return divs, nil
+}
+
+func (c *Checker) checkName(ctx context.Context, height int32, blockHash string, name []byte) ([]Divergence, error) {
+
+	res, err := c.Local.ResolveAtContext(ctx, name, height)
+	if err != nil && !errors.Is(err, claimtrie.ErrNameNotFound) {
+		return nil, fmt.Errorf("local resolve %q at %d: %w", name, height, err)
+	}
+	claims, err := c.Remote.ClaimsForName(name, blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("remote claims of %q at %d: %w", name, height, err)
+	}
+	winner, err := c.Remote.ValueForName(name, blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("remote value of %q at %d: %w", name, height, err)
+	}
+
+	var divs []Divergence
+	add := func(field, id string, local, remote interface{}) {
+		l, r := fmt.Sprint(local), fmt.Sprint(remote)
+		if l != r {
+			divs = append(divs, Divergence{Height: height, Name: name, ClaimID: id, Field: field, Local: l, Remote: r})
+		}
+	}
+
+	n := node.New()
+	if res != nil {
+		n = res.Node
+	}
+	localWinner, remoteWinner := "", ""
+	if n.BestClaim != nil && n.BestClaim.Status == node.Activated {
+		localWinner = n.BestClaim.ClaimID.String()
+	}
+	if winner != nil {
+		remoteWinner = winner.ClaimID
+	}
+	add(FieldWinner, "", localWinner, remoteWinner)
+	if localWinner != "" || remoteWinner != "" {
+		add(FieldTakeoverHeight, "", n.TakenOverAt, claims.LastTakeoverHeight)
+	}
+
+	// The claims are matched by their IDs, and listed in order by them.
+	remoteClaims := map[string]Claim{}
+	for _, rc := range claims.Claims {
+		remoteClaims[rc.ClaimID] = rc
+	}
+	localClaims := map[string]*node.Claim{}
+	for _, lc := range n.Claims {
+		if lc.Status != node.Deactivated {
+			localClaims[lc.ClaimID.String()] = lc
+		}
+	}
+	ids := make([]string, 0, len(remoteClaims)+len(localClaims))
+	for id := range remoteClaims {
+		ids = append(ids, id)
+	}
+	for id := range localClaims {
+		if _, ok := remoteClaims[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		lc, inLocal := localClaims[id]
+		rc, inRemote := remoteClaims[id]
+		switch {
+		case !inRemote:
+			add(FieldClaim, id, lc.OutPoint, "")
+		case !inLocal:
+			add(FieldClaim, id, "", fmt.Sprintf("%s:%d", rc.TxID, rc.N))
+		default:
+			add(FieldEffectiveAmount, id, n.EffectiveAmount(lc), rc.EffectiveAmount)
+		}
+	}
+
+	return divs, nil
+}
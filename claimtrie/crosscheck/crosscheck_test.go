@@ -0,0 +1,91 @@
+package crosscheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	cfg := config.DefaultConfig
+	cfg.DataDir = t.TempDir()
+	ct, err := claimtrie.New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	id1, id2 := node.NewClaimID(o1), node.NewClaimID(o2)
+	r.NoError(ct.AddClaim([]byte("test"), o1, id1, 100000000, nil))
+	r.NoError(ct.AppendBlock())
+	r.NoError(ct.AddClaim([]byte("test"), o2, id2, 50000000, nil))
+	r.NoError(ct.AppendBlock())
+	root, err := ct.RootAt(2)
+	r.NoError(err)
+
+	// The node agrees, but for the effective amount of the second claim, and the root at 1.
+	results := map[string]interface{}{
+		"getclaimsforname": map[string]interface{}{
+			"lastTakeoverHeight": 1,
+			"claims": []map[string]interface{}{
+				{"claimId": id1.String(), "txId": hash.String(), "n": 1, "amount": 1.0, "effectiveAmount": 1.0},
+				{"claimId": id2.String(), "txId": hash.String(), "n": 2, "amount": 0.5, "effectiveAmount": 0.4},
+			},
+		},
+		"getvalueforname": map[string]interface{}{"claimId": id1.String(), "amount": 1.0, "effectiveAmount": 1.0},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, pass, _ := req.BasicAuth()
+		r.Equal("user", user)
+		r.Equal("pass", pass)
+		var body request
+		r.NoError(json.NewDecoder(req.Body).Decode(&body))
+		result := results[body.Method]
+		switch body.Method {
+		case "getblockhash":
+			result = fmt.Sprintf("block-%v", body.Params[0])
+		case "getblockheader":
+			result = map[string]interface{}{"nameclaimroot": root.String()}
+			if body.Params[0] != "block-2" {
+				result = map[string]interface{}{"nameclaimroot": chainhash.Hash{9}.String()}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "error": nil, "id": body.ID}) // nolint : errchk
+	}))
+	defer srv.Close()
+
+	c := &Checker{Local: ct, Remote: NewClient(srv.URL, "user", "pass")}
+	report, err := c.Check(context.Background(), 1, 2, func(height int32) ([][]byte, error) {
+		return [][]byte{[]byte("test")}, nil
+	})
+	r.NoError(err)
+	r.Equal(2, report.Heights)
+	r.Equal(2, report.Names)
+
+	var fields []string
+	for _, d := range report.Divergences {
+		fields = append(fields, d.String())
+	}
+	r.Equal([]string{
+		"1: root: local: " + root.String() + ", remote: " + chainhash.Hash{9}.String(),
+		"1: \"test\": claim of " + id2.String() + ": local: , remote: " + hash.String() + ":2",
+		"2: \"test\": effective amount of " + id2.String() + ": local: 50000000, remote: 40000000",
+	}, fields)
+}
@@ -0,0 +1,47 @@
+package claimtrie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// ErrTrieDivergence is returned when the roots of the MerkleTrie and the RamTrie differ.
+var ErrTrieDivergence = errors.New("trie roots diverge")
+
+// newRamTrie returns a RamTrie of all the names of the nodes.
+func newRamTrie(nm node.Manager) *merkletrie.RamTrie {
+
+	rt := merkletrie.NewRamTrie(nm)
+	nm.IterateNames(func(name []byte) bool {
+		rt.Update(name, false)
+		return true
+	})
+
+	return rt
+}
+
+// crossValidate updates the names in the RamTrie, and compares its root with the one
+// of the MerkleTrie.
+func (ct *ClaimTrie) crossValidate(names [][]byte, root *chainhash.Hash) error {
+
+	for _, name := range names {
+		ct.ramTrie.Update(name, false)
+	}
+
+	var h *chainhash.Hash
+	if ct.height >= param.AllClaimsInMerkleForkHeight {
+		h = ct.ramTrie.MerkleHashAllClaims()
+	} else {
+		h = ct.ramTrie.MerkleHash()
+	}
+	if !h.IsEqual(root) {
+		return fmt.Errorf("%w at height %d: %s, collapsed: %s", ErrTrieDivergence, ct.height, root, h)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package claimtrie
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// ErrStaleCursor is returned when the block of a cursor is no longer in the chain.
+var ErrStaleCursor = errors.New("cursor is stale")
+
+// NamesCursor is a position in the names of the trie at a block. It stays valid across
+// new blocks, as the names are iterated from the trie at its root, and their nodes at its height.
+type NamesCursor struct {
+	Height int32
+	Root   chainhash.Hash
+	Name   []byte // The last name of the previous page.
+}
+
+// String encodes the cursor as height:root:name, with the name in hex.
+func (c *NamesCursor) String() string {
+	return fmt.Sprintf("%d:%s:%s", c.Height, c.Root, hex.EncodeToString(c.Name))
+}
+
+// ParseNamesCursor decodes a cursor encoded by NamesCursor.String.
+func ParseNamesCursor(s string) (*NamesCursor, error) {
+
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid cursor: %q", s)
+	}
+	height, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor height: %w", err)
+	}
+	root, err := chainhash.NewHashFromStr(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor root: %w", err)
+	}
+	name, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor name: %w", err)
+	}
+
+	return &NamesCursor{Height: int32(height), Root: *root, Name: name}, nil
+}
+
+// ListNames returns up to limit names, in order, and their nodes, after the cursor, or from
+// the first name at the current height if it's nil. The cursor of the next page is nil after the last one.
+// It fails with ErrStaleCursor if the block of the cursor was reorganized out of the chain.
+func (ct *ClaimTrie) ListNames(cursor *NamesCursor, limit int) ([][]byte, []*node.Node, *NamesCursor, error) {
+
+	if limit <= 0 {
+		return nil, nil, nil, fmt.Errorf("invalid limit: %d", limit)
+	}
+
+	if cursor == nil {
+		cursor = &NamesCursor{Height: ct.height, Root: *ct.MerkleHash()}
+	} else if cursor.Height > ct.height {
+		return nil, nil, nil, fmt.Errorf("%w: height %d is past %d", ErrStaleCursor, cursor.Height, ct.height)
+	} else if cursor.Height > 0 {
+		root, err := ct.blockRepo.Get(cursor.Height)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("block repo get: %w", err)
+		}
+		if !root.IsEqual(&cursor.Root) {
+			return nil, nil, nil, fmt.Errorf("%w: root of height %d is %s", ErrStaleCursor, cursor.Height, root)
+		}
+	}
+
+	var names [][]byte
+	var nodes []*node.Node
+	var err error
+	ct.merkleTrie.At(&cursor.Root).IterateNames(cursor.Name, func(name []byte) bool {
+		var n *node.Node
+		n, err = ct.nodeManager.NodeAt(cursor.Height, name)
+		if err != nil {
+			err = fmt.Errorf("node %q at %d: %w", name, cursor.Height, err)
+			return false
+		}
+		names = append(names, append([]byte(nil), name...))
+		nodes = append(nodes, n)
+		return len(names) < limit
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var next *NamesCursor
+	if len(names) == limit {
+		next = &NamesCursor{Height: cursor.Height, Root: cursor.Root, Name: names[len(names)-1]}
+	}
+
+	return names, nodes, next, nil
+}
@@ -0,0 +1,48 @@
+package claimtrie
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/deltasync"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// DeltaPublisher returns the publisher of the deltas of the blocks, if enabled.
+func (ct *ClaimTrie) DeltaPublisher() *deltasync.Publisher {
+	return ct.deltas
+}
+
+// publishDelta publishes the names dirtied by the block, or all of them at the all-claims fork,
+// with their leaf hashes.
+func (ct *ClaimTrie) publishDelta(parent *chainhash.Hash, names [][]byte, hitFork bool) {
+
+	if parent == nil {
+		parent = merkletrie.EmptyTrieHash
+	}
+	if hitFork {
+		names = names[:0:0]
+		ct.nodeManager.IterateNames(func(name []byte) bool {
+			names = append(names, append([]byte(nil), name...))
+			return true
+		})
+	}
+
+	allClaims := ct.height >= param.AllClaimsInMerkleForkHeight
+	d := &deltasync.Delta{Height: ct.height, Parent: *parent, Root: *ct.root, Leaves: make(merkletrie.LeafStore, len(names))}
+	for _, name := range names {
+		d.Leaves[string(name)] = ct.leafHashes(name, allClaims)
+	}
+	ct.deltas.Publish(d)
+}
+
+// leafHashes returns the values of the name hashed into the trie: the claims hashes,
+// if allClaims is set, or the hash of the best claim.
+func (ct *ClaimTrie) leafHashes(name []byte, allClaims bool) []*chainhash.Hash {
+	if allClaims {
+		return ct.nodeManager.ClaimHashes(name)
+	}
+	if h := ct.nodeManager.Hash(name); h != nil {
+		return []*chainhash.Hash{h}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// Package deltasync lets query replicas, which trust the roots in the block headers, follow
+// a synced ClaimTrie by the names dirtied in each block, and their new leaf hashes, instead
+// of replaying the changes.
+package deltasync
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+)
+
+// Delta is the names dirtied by the block at the height, with their leaf hashes after it:
+// the hash of the best claim before the all-claims fork, and the claims hashes after.
+// The names without any leaf hashes were removed from the trie.
+// At the all-claims fork, all the names are dirtied.
+type Delta struct {
+	Height int32
+	Parent chainhash.Hash // The root of the previous block.
+	Root   chainhash.Hash
+	Leaves merkletrie.LeafStore
+}
+
+// The encoding of a Delta, of which the integers are big-endian:
+//
+//	height(4B) parent(32B) root(32B) count(4B) { nameLen(4B) name hashes(4B) { hash(32B) } * hashes } * count
+func (d *Delta) encode() []byte {
+
+	b := make([]byte, 0, 4+2*chainhash.HashSize+4)
+	b = appendUint32(b, uint32(d.Height))
+	b = append(b, d.Parent[:]...)
+	b = append(b, d.Root[:]...)
+	b = appendUint32(b, uint32(len(d.Leaves)))
+	for name, hs := range d.Leaves {
+		b = appendUint32(b, uint32(len(name)))
+		b = append(b, name...)
+		b = appendUint32(b, uint32(len(hs)))
+		b = append(b, encodeHashes(hs)...)
+	}
+
+	return b
+}
+
+func decodeDelta(b []byte) (*Delta, error) {
+
+	next := func(n int) ([]byte, error) {
+		if len(b) < n {
+			return nil, fmt.Errorf("truncated delta")
+		}
+		v := b[:n]
+		b = b[n:]
+		return v, nil
+	}
+	nextUint32 := func() (int, error) {
+		v, err := next(4)
+		if err != nil {
+			return 0, err
+		}
+		return int(binary.BigEndian.Uint32(v)), nil
+	}
+
+	d := &Delta{Leaves: merkletrie.LeafStore{}}
+	height, err := nextUint32()
+	if err != nil {
+		return nil, err
+	}
+	d.Height = int32(height)
+	for _, h := range []*chainhash.Hash{&d.Parent, &d.Root} {
+		v, err := next(chainhash.HashSize)
+		if err != nil {
+			return nil, err
+		}
+		copy(h[:], v)
+	}
+	count, err := nextUint32()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < count; i++ {
+		n, err := nextUint32()
+		if err != nil {
+			return nil, err
+		}
+		name, err := next(n)
+		if err != nil {
+			return nil, err
+		}
+		v, err := nextUint32()
+		if err != nil {
+			return nil, err
+		}
+		hs, err := next(v * chainhash.HashSize)
+		if err != nil {
+			return nil, err
+		}
+		d.Leaves[string(name)] = decodeHashes(hs)
+	}
+	if len(b) > 0 {
+		return nil, fmt.Errorf("trailing %d bytes of delta", len(b))
+	}
+
+	return d, nil
+}
+
+func encodeHashes(hs []*chainhash.Hash) []byte {
+	b := make([]byte, 0, len(hs)*chainhash.HashSize)
+	for _, h := range hs {
+		b = append(b, h[:]...)
+	}
+	return b
+}
+
+func decodeHashes(b []byte) []*chainhash.Hash {
+	hs := make([]*chainhash.Hash, len(b)/chainhash.HashSize)
+	for i := range hs {
+		hs[i] = new(chainhash.Hash)
+		copy(hs[i][:], b[i*chainhash.HashSize:])
+	}
+	return hs
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], v)
+	return append(b, n[:]...)
+}
@@ -0,0 +1,290 @@
+package deltasync
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/frame"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+	"github.com/btcsuite/btcd/claimtrie/param"
+
+	"github.com/cockroachdb/pebble"
+)
+
+var (
+	// ErrOutOfOrder is returned for a delta, which isn't of the block after the follower's.
+	ErrOutOfOrder = errors.New("delta out of order")
+
+	// ErrDiverged is returned for a delta, of which the parent isn't the follower's root,
+	// such as after a reorganization deeper than the follower.
+	ErrDiverged = errors.New("delta diverges from the follower")
+
+	// ErrRootMismatch is returned when the trie of the follower with a delta applied
+	// doesn't hash to the root of the block.
+	ErrRootMismatch = errors.New("root mismatch")
+)
+
+// Key formats of the follower repo:
+//
+//	'l' + name: the leaf hashes of the name.
+//	's': the height(4B) and root(32B) of the follower.
+//	't' + key: the nodes of the trie.
+const (
+	leafPrefix = 'l'
+	stateKey   = 's'
+	triePrefix = 't'
+)
+
+// Follower maintains the trie, and the leaf hashes of the names, by applying the deltas of
+// the blocks, verified against their roots. It only moves forward.
+type Follower struct {
+	db     *pebble.DB
+	trie   *merkletrie.MerkleTrie
+	height int32
+	root   chainhash.Hash
+
+	// The leaves of the delta being applied, which are read before the ones of the repo.
+	pending merkletrie.LeafStore
+}
+
+// NewFollower opens the follower repo at path, and restores its last block.
+func NewFollower(path string) (*Follower, error) {
+
+	db, err := pebble.Open(path, &pebble.Options{Cache: pebble.NewCache(64 << 20)})
+	if err != nil {
+		return nil, fmt.Errorf("pebble open %s, %w", path, err)
+	}
+
+	f := &Follower{db: db, root: *merkletrie.EmptyTrieHash}
+	f.trie = merkletrie.New(f, merkletrierepo.NewPebbleShared(db, []byte{triePrefix}))
+
+	state, closer, err := db.Get([]byte{stateKey})
+	if err == nil {
+		f.height = int32(binary.BigEndian.Uint32(state))
+		copy(f.root[:], state[4:])
+		closer.Close()
+		f.resetTrie()
+	} else if !errors.Is(err, pebble.ErrNotFound) {
+		db.Close()
+		return nil, fmt.Errorf("pebble get: %w", err)
+	}
+
+	return f, nil
+}
+
+// Height returns the height of the last block applied.
+func (f *Follower) Height() int32 {
+	return f.height
+}
+
+// Root returns the root of the last block applied.
+func (f *Follower) Root() chainhash.Hash {
+	return f.root
+}
+
+// Leaf returns the leaf hashes of the name, if it's in the trie.
+func (f *Follower) Leaf(name []byte) []*chainhash.Hash {
+	return f.ClaimHashes(name)
+}
+
+func (f *Follower) ClaimHashes(name []byte) []*chainhash.Hash {
+
+	if hs, ok := f.pending[string(name)]; ok {
+		return hs
+	}
+	b, closer, err := f.db.Get(leafKey(name))
+	if err != nil {
+		return nil
+	}
+	defer closer.Close()
+
+	return decodeHashes(b)
+}
+
+func (f *Follower) Hash(name []byte) *chainhash.Hash {
+	if hs := f.ClaimHashes(name); len(hs) > 0 {
+		return hs[0]
+	}
+	return nil
+}
+
+// Apply applies the delta of the next block, and verifies the trie against root,
+// which is the one of the block header. The follower is left at its last block on errors.
+func (f *Follower) Apply(d *Delta, root *chainhash.Hash) error {
+
+	if d.Height != f.height+1 {
+		return fmt.Errorf("%w: %d after %d", ErrOutOfOrder, d.Height, f.height)
+	}
+	if d.Parent != f.root {
+		return fmt.Errorf("%w at %d: parent %s, root %s", ErrDiverged, d.Height, d.Parent, f.root)
+	}
+	if d.Root != *root {
+		return fmt.Errorf("%w at %d: delta %s, block %s", ErrRootMismatch, d.Height, d.Root, root)
+	}
+
+	f.pending = d.Leaves
+	defer func() { f.pending = nil }()
+	for name := range d.Leaves {
+		f.trie.Update([]byte(name), true)
+	}
+	var h *chainhash.Hash
+	if d.Height >= param.AllClaimsInMerkleForkHeight {
+		h = f.trie.MerkleHashAllClaims()
+	} else {
+		h = f.trie.MerkleHash()
+	}
+	if !h.IsEqual(root) {
+		f.resetTrie()
+		return fmt.Errorf("%w at %d: applied %s, block %s", ErrRootMismatch, d.Height, h, root)
+	}
+	err := f.trie.Commit()
+	if err != nil {
+		return fmt.Errorf("merkle trie commit: %w", err)
+	}
+
+	batch := f.db.NewBatch()
+	defer batch.Close()
+	for name, hs := range d.Leaves {
+		if len(hs) == 0 {
+			err := batch.Delete(leafKey([]byte(name)), nil)
+			if err != nil {
+				return fmt.Errorf("batch delete: %w", err)
+			}
+			continue
+		}
+		err := batch.Set(leafKey([]byte(name)), encodeHashes(hs), nil)
+		if err != nil {
+			return fmt.Errorf("batch set: %w", err)
+		}
+	}
+	state := make([]byte, 4, 4+chainhash.HashSize)
+	binary.BigEndian.PutUint32(state, uint32(d.Height))
+	err = batch.Set([]byte{stateKey}, append(state, h[:]...), nil)
+	if err != nil {
+		return fmt.Errorf("batch set: %w", err)
+	}
+	err = batch.Commit(pebble.Sync)
+	if err != nil {
+		f.resetTrie()
+		return fmt.Errorf("batch commit: %w", err)
+	}
+
+	f.height = d.Height
+	f.root = *h
+
+	return nil
+}
+
+// Sync applies the deltas fetched from the publisher, verified against the roots of
+// the block headers, until it has none past the follower. It returns how many were applied.
+func (f *Follower) Sync(c *Client, roots func(height int32) (*chainhash.Hash, error)) (int, error) {
+
+	applied := 0
+	for {
+		d, err := c.Delta(f.height + 1)
+		if errors.Is(err, ErrNotPublished) {
+			return applied, nil
+		}
+		if err != nil {
+			return applied, err
+		}
+		root, err := roots(d.Height)
+		if err != nil {
+			return applied, fmt.Errorf("root of block %d: %w", d.Height, err)
+		}
+		err = f.Apply(d, root)
+		if err != nil {
+			return applied, err
+		}
+		applied++
+	}
+}
+
+// resetTrie drops the vertices of the trie updated since the last block.
+func (f *Follower) resetTrie() {
+	root := f.root
+	f.trie.SetRoot(&root)
+}
+
+// Close closes the follower repo.
+func (f *Follower) Close() error {
+	return f.db.Close()
+}
+
+func leafKey(name []byte) []byte {
+	return append([]byte{leafPrefix}, name...)
+}
+
+// Client fetches the deltas from a publisher over one connection.
+type Client struct {
+	addr    string
+	timeout time.Duration
+	conn    net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+}
+
+// NewClient returns a Client of the publisher at the address, which is dialed, and answers
+// each request, within the timeout, if it's set.
+func NewClient(addr string, timeout time.Duration) *Client {
+	return &Client{addr: addr, timeout: timeout}
+}
+
+// Delta fetches the delta of the block at the height. The connection is dropped on failures,
+// and dialed again by the next request.
+func (c *Client) Delta(height int32) (*Delta, error) {
+
+	if c.conn == nil {
+		conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+		if err != nil {
+			return nil, fmt.Errorf("dial publisher %s: %w", c.addr, err)
+		}
+		c.conn, c.r, c.w = conn, bufio.NewReader(conn), bufio.NewWriter(conn)
+	}
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout)) // nolint : errchk
+	}
+
+	req := [5]byte{requestDelta}
+	binary.BigEndian.PutUint32(req[1:], uint32(height))
+	_, err := c.w.Write(req[:])
+	if err == nil {
+		err = c.w.Flush()
+	}
+	var status byte
+	var payload []byte
+	if err == nil {
+		status, payload, err = frame.Read(c.r)
+	}
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return nil, fmt.Errorf("publisher %s: %w", c.addr, err)
+	}
+
+	switch status {
+	case statusOK:
+		return decodeDelta(payload)
+	case statusNotPublished:
+		return nil, fmt.Errorf("%w: %d at %s", ErrNotPublished, height, c.addr)
+	case statusPruned:
+		return nil, fmt.Errorf("%w: %d at %s", ErrPruned, height, c.addr)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPublisher, payload)
+}
+
+// Close closes the connection to the publisher.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
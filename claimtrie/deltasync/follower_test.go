@@ -0,0 +1,113 @@
+package deltasync_test
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/deltasync"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollower(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	cfg := config.DefaultConfig
+	cfg.DataDir = t.TempDir()
+	cfg.DeltaSyncBlocks = 20
+	ct, err := claimtrie.New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	defer l.Close()
+	go ct.DeltaPublisher().Serve(l) // nolint : errchk
+	c := deltasync.NewClient(l.Addr().String(), 10*time.Second)
+	defer c.Close()
+
+	dir := t.TempDir()
+	f, err := deltasync.NewFollower(dir)
+	r.NoError(err)
+
+	roots := map[int32]*chainhash.Hash{}
+	rootOf := func(height int32) (*chainhash.Hash, error) {
+		if h, ok := roots[height]; ok {
+			return h, nil
+		}
+		return nil, fmt.Errorf("no block %d", height)
+	}
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	names := []string{"test", "Test", "other", "a", "ab"}
+	appendBlock := func(i uint32) {
+		op := wire.OutPoint{Hash: hash, Index: i}
+		name := []byte(names[int(i)%len(names)])
+		switch i % 3 {
+		case 0:
+			r.NoError(ct.AddClaim(name, op, node.NewClaimID(op), int64(i), nil))
+		case 1:
+			if i > 16 { // the claims are spent a few blocks after
+				prev := wire.OutPoint{Hash: hash, Index: i - 16}
+				r.NoError(ct.SpendClaim([]byte(names[int(i-16)%len(names)]), prev, node.NewClaimID(prev)))
+			}
+		}
+		r.NoError(ct.AppendBlock())
+		roots[ct.Height()] = ct.MerkleHash()
+	}
+
+	for i := uint32(1); i <= 360; i++ {
+		appendBlock(i)
+		if i%7 == 0 || i >= 340 {
+			_, err = f.Sync(c, rootOf)
+			r.NoError(err)
+			r.Equal(ct.Height(), f.Height())
+			r.Equal(*ct.MerkleHash(), f.Root(), "height %d", i)
+		}
+	}
+	r.NotEmpty(f.Leaf([]byte("test")))
+	r.Empty(f.Leaf([]byte("Test"))) // normalized at the fork
+
+	// The roots of the headers are verified.
+	appendBlock(361)
+	d, err := c.Delta(361)
+	r.NoError(err)
+	r.ErrorIs(f.Apply(d, &chainhash.Hash{7}), deltasync.ErrRootMismatch)
+	for name := range d.Leaves {
+		d.Leaves[name] = []*chainhash.Hash{{9}}
+	}
+	r.ErrorIs(f.Apply(d, &d.Root), deltasync.ErrRootMismatch)
+	r.Equal(int32(360), f.Height())
+
+	// The follower resumes from its repo.
+	r.NoError(f.Close())
+	f, err = deltasync.NewFollower(dir)
+	r.NoError(err)
+	defer f.Close()
+	r.Equal(int32(360), f.Height())
+	_, err = f.Sync(c, rootOf)
+	r.NoError(err)
+	r.Equal(*ct.MerkleHash(), f.Root())
+
+	// A reorganization past the follower diverges from it.
+	r.NoError(ct.ResetHeight(359))
+	for i := uint32(1360); i <= 1363; i++ {
+		appendBlock(i)
+	}
+	_, err = f.Sync(c, rootOf)
+	r.ErrorIs(err, deltasync.ErrDiverged)
+
+	// The deltas before the kept ones are pruned.
+	_, err = c.Delta(300)
+	r.ErrorIs(err, deltasync.ErrPruned)
+}
@@ -0,0 +1,156 @@
+package deltasync
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/btcsuite/btcd/claimtrie/frame"
+)
+
+// The delta sync protocol is a stream of requests to a publisher, each answered by
+// a response in order. The integers are big-endian.
+//
+//	request:  'd' height(4B)
+//	response: status(1B) len(4B) payload, of up to frame.MaxSize bytes
+//
+// The payload of a statusOK is the encoded Delta, and the message of the others.
+const (
+	requestDelta = 'd'
+
+	statusOK           = 0
+	statusNotPublished = 1
+	statusPruned       = 2
+	statusError        = 3
+)
+
+var (
+	// ErrNotPublished is returned for the deltas past the last block of the publisher.
+	ErrNotPublished = errors.New("delta not published yet")
+
+	// ErrPruned is returned for the deltas older than the ones kept by the publisher.
+	// The follower has to be restored from a checkpoint of the trie instead.
+	ErrPruned = errors.New("delta pruned")
+
+	// ErrPublisher is returned when the publisher fails a request.
+	ErrPublisher = errors.New("delta publisher")
+)
+
+// Publisher keeps the deltas of the last blocks, and serves them to the followers.
+type Publisher struct {
+	mu     sync.Mutex
+	keep   int
+	deltas []*Delta // of consecutive heights.
+}
+
+// NewPublisher returns a Publisher, which keeps the deltas of the last keep blocks.
+func NewPublisher(keep int) *Publisher {
+	if keep < 1 {
+		keep = 1
+	}
+	return &Publisher{keep: keep}
+}
+
+// Publish adds the delta of the block, replacing the ones of its height, and after, if any.
+func (p *Publisher) Publish(d *Delta) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rewind(d.Height - 1)
+	if n := len(p.deltas); n > 0 && p.deltas[n-1].Height != d.Height-1 {
+		p.deltas = nil // a gap, such as after a reset past the kept ones
+	}
+	p.deltas = append(p.deltas, d)
+	if n := len(p.deltas); n > p.keep {
+		p.deltas = append(p.deltas[:0:0], p.deltas[n-p.keep:]...)
+	}
+}
+
+// Rewind drops the deltas after the height, which were reorganized away.
+func (p *Publisher) Rewind(height int32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rewind(height)
+}
+
+func (p *Publisher) rewind(height int32) {
+	for len(p.deltas) > 0 && p.deltas[len(p.deltas)-1].Height > height {
+		p.deltas = p.deltas[:len(p.deltas)-1]
+	}
+}
+
+// Delta returns the delta of the block at the height.
+func (p *Publisher) Delta(height int32) (*Delta, error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.deltas) == 0 || height > p.deltas[len(p.deltas)-1].Height {
+		return nil, fmt.Errorf("%w: %d", ErrNotPublished, height)
+	}
+	first := p.deltas[0].Height
+	if height < first {
+		return nil, fmt.Errorf("%w: %d, before %d", ErrPruned, height, first)
+	}
+
+	return p.deltas[height-first], nil
+}
+
+// Serve answers the requests of the delta sync protocol on the connections accepted from l,
+// until l is closed.
+func (p *Publisher) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go p.serveConn(conn)
+	}
+}
+
+func (p *Publisher) serveConn(conn net.Conn) {
+
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		if op != requestDelta {
+			writeResponse(w, statusError, []byte("unknown request")) // nolint : errchk
+			return
+		}
+		var n [4]byte
+		if _, err = io.ReadFull(r, n[:]); err != nil {
+			return
+		}
+
+		status, payload := byte(statusOK), []byte(nil)
+		d, err := p.Delta(int32(binary.BigEndian.Uint32(n[:])))
+		switch {
+		case errors.Is(err, ErrNotPublished):
+			status, payload = statusNotPublished, []byte(err.Error())
+		case errors.Is(err, ErrPruned):
+			status, payload = statusPruned, []byte(err.Error())
+		default:
+			payload = d.encode()
+		}
+		if err = writeResponse(w, status, payload); err != nil {
+			return
+		}
+	}
+}
+
+func writeResponse(w *bufio.Writer, status byte, payload []byte) error {
+	if err := frame.Write(w, status, payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,154 @@
+package claimtrie
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// ClaimDiff is a claim, or a support, present at both heights, of which the states differ.
+type ClaimDiff struct {
+	Before *node.Claim
+	After  *node.Claim
+}
+
+// NodeDiff is what changed about a name between two heights.
+// Claims are matched by their IDs, and supports by their TXOs.
+type NodeDiff struct {
+	Name []byte
+	From int32
+	To   int32
+
+	AddedClaims    []*node.Claim
+	RemovedClaims  []*node.Claim
+	ModifiedClaims []ClaimDiff
+
+	AddedSupports    []*node.Claim
+	RemovedSupports  []*node.Claim
+	ModifiedSupports []ClaimDiff
+
+	// Set if the best claim, or the height it took over at, differs.
+	Takeover *TakeoverDiff
+}
+
+// TakeoverDiff is a change of the best claim; the claims are nil if there's none.
+type TakeoverDiff struct {
+	Previous    *node.Claim
+	Winner      *node.Claim
+	TakenOverAt int32
+}
+
+// DiffNode returns the differences of the name between the heights, computed from its
+// recorded changes. The heights must not be above the current one.
+func (ct *ClaimTrie) DiffNode(name []byte, from, to int32) (*NodeDiff, error) {
+
+	if from > ct.height || to > ct.height {
+		return nil, fmt.Errorf("height is above the current one: %d", ct.height)
+	}
+
+	before, err := ct.nodeManager.NodeAt(from, node.NormalizeIfNecessary(name, from))
+	if err != nil {
+		return nil, fmt.Errorf("node at %d: %w", from, err)
+	}
+	after, err := ct.nodeManager.NodeAt(to, node.NormalizeIfNecessary(name, to))
+	if err != nil {
+		return nil, fmt.Errorf("node at %d: %w", to, err)
+	}
+	if before == nil {
+		before = node.New()
+	}
+	if after == nil {
+		after = node.New()
+	}
+
+	diff := &NodeDiff{Name: name, From: from, To: to}
+
+	byID := func(c *node.Claim) string { return string(c.ClaimID[:]) }
+	diff.AddedClaims, diff.RemovedClaims, diff.ModifiedClaims = diffClaims(before.Claims, after.Claims, byID)
+
+	byOut := func(c *node.Claim) string { return c.OutPoint.String() }
+	diff.AddedSupports, diff.RemovedSupports, diff.ModifiedSupports = diffClaims(before.Supports, after.Supports, byOut)
+
+	if !sameClaim(before.BestClaim, after.BestClaim) || before.TakenOverAt != after.TakenOverAt {
+		diff.Takeover = &TakeoverDiff{
+			Previous:    copyClaim(before.BestClaim),
+			Winner:      copyClaim(after.BestClaim),
+			TakenOverAt: after.TakenOverAt,
+		}
+	}
+
+	return diff, nil
+}
+
+func diffClaims(before, after node.ClaimList, key func(c *node.Claim) string) (added, removed []*node.Claim, modified []ClaimDiff) {
+
+	previous := make(map[string]*node.Claim, len(before))
+	for _, c := range before {
+		previous[key(c)] = c
+	}
+
+	for _, c := range after {
+		k := key(c)
+		p, ok := previous[k]
+		if !ok {
+			added = append(added, copyClaim(c))
+			continue
+		}
+		delete(previous, k)
+		if !equalClaims(p, c) {
+			modified = append(modified, ClaimDiff{Before: copyClaim(p), After: copyClaim(c)})
+		}
+	}
+
+	for _, c := range before {
+		if _, ok := previous[key(c)]; ok {
+			removed = append(removed, copyClaim(c))
+		}
+	}
+
+	return added, removed, modified
+}
+
+func equalClaims(a, b *node.Claim) bool {
+	return a.OutPoint == b.OutPoint && a.ClaimID == b.ClaimID && a.Amount == b.Amount &&
+		a.AcceptedAt == b.AcceptedAt && a.ActiveAt == b.ActiveAt && a.Status == b.Status &&
+		a.VisibleAt == b.VisibleAt && bytes.Equal(a.Value, b.Value)
+}
+
+// RootDiff is a name, of which the value hash differs between the tries at two roots; a hash is nil where the
+// name has none.
+type RootDiff struct {
+	Name   []byte
+	Before *chainhash.Hash // In the trie at the root diffed against.
+	After  *chainhash.Hash // In the trie at the current root.
+
+	// Rebuilt is the value hash of the node, rebuilt from its changes as of the current height, which differs from
+	// After, if the node the trie was hashed from was wrong.
+	Rebuilt *chainhash.Hash
+}
+
+// DiffRoot returns the names, of which the value hashes differ between the trie at the root, such as of the last
+// block verified, and the current one, in order, for diagnosing a mismatched root. Both tries must be in the
+// trie repo. It mustn't be called concurrently with AppendBlock.
+func (ct *ClaimTrie) DiffRoot(root *chainhash.Hash) ([]RootDiff, error) {
+
+	current := ct.MerkleHash()
+	diffs, err := merkletrie.Diff(ct.merkleTrie.At(root), ct.merkleTrie.At(current))
+	if err != nil {
+		return nil, fmt.Errorf("diff %s and %s: %w", root, current, err)
+	}
+
+	rds := make([]RootDiff, 0, len(diffs))
+	for _, d := range diffs {
+		n, err := ct.nodeManager.NodeAt(ct.height, d.Name)
+		if err != nil {
+			return nil, fmt.Errorf("node %q at %d: %w", d.Name, ct.height, err)
+		}
+		rds = append(rds, RootDiff{Name: d.Name, Before: d.A, After: d.B, Rebuilt: leafHash(n, ct.height)})
+	}
+
+	return rds, nil
+}
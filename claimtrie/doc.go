@@ -0,0 +1,27 @@
+/*
+Package claimtrie maintains the ClaimTrie of LBRY: the claims and supports of the names, as changed by the blocks,
+and the Merkle trie of their best claims, of which the root is committed to by each block header.
+
+# Concurrency
+
+A ClaimTrie is changed by a single writer: the changes, AppendBlock, ResetHeight, and the rest changing it, such as
+Repair, aren't safe for concurrent access, and neither are the methods reading the state being changed, such as
+Height, MerkleHash, Node and NameProof, which see the changes not appended yet.
+
+The readers, such as the RPCs, read the blocks committed instead, concurrently with each other, and with the writer:
+
+  - A Snapshot is the state as of the last block committed, when it was taken. Its queries never see the changes of
+    a block being appended, nor of the ones after it, so the ones issued to the same Snapshot agree with each other.
+    Once its block is reset, they fail with ErrStaleSnapshot.
+  - ResolveAt, RootAt and ProveAtContext read the state as of any committed, and retained, height, up to the one of
+    the last Snapshot, failing with ErrNotRetained past it.
+  - A reset, or a prune, rewriting the history waits for the readers holding it, while the readers coming after wait
+    for it, up to the HistoryReadWait, before failing with ErrHistoryBusy. So a reader never sees part of a rewrite.
+
+The indexes, such as ClaimByID, TopNames, SearchNames and ChannelStats, are safe for concurrent access too, but are
+updated while a block is appended, so they may see it before its Snapshot is: they're as of the last Snapshot, or
+the block after it.
+
+WithNameLock holds off the blocks updating a name, for the coordinators reading it, and acting on it, at once.
+*/
+package claimtrie
@@ -0,0 +1,135 @@
+// Package encryptedrepo wraps repos with the encryption of the values they hold at rest, with AES-GCM, for the
+// operators with compliance requirements. The keys of the repos, such as the names and the outpoints, are kept
+// in the clear, as they're iterated in order, and so are the amounts and the heights of the changes; only the
+// values of the claims and supports, and the node snapshots holding them, are sealed.
+package encryptedrepo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// KeySize is the size of the keys, for AES-256.
+const KeySize = 32
+
+// ErrDecrypt is returned for the values, which fail to open with the key, as they're sealed with another one,
+// or corrupt.
+var ErrDecrypt = errors.New("decrypt value")
+
+// Cipher seals the values with a key, and a random nonce prefixed to each of them.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func NewCipher(key []byte) (*Cipher, error) {
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key of %d bytes, instead of %d", len(key), KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("new aes cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("new gcm: %w", err)
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal returns the value encrypted, and bound to the data, such as the key it's stored by, which must be the
+// same to open it. The empty values are kept empty.
+func (c *Cipher) Seal(value, data []byte) []byte {
+
+	if len(value) == 0 {
+		return value
+	}
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(value)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("read nonce: %s", err)) // crypto/rand doesn't fail on the supported platforms.
+	}
+
+	return c.aead.Seal(nonce, nonce, value, data)
+}
+
+// Open returns the value sealed with the data.
+func (c *Cipher) Open(sealed, data []byte) ([]byte, error) {
+
+	if len(sealed) == 0 {
+		return sealed, nil
+	}
+	n := c.aead.NonceSize()
+	if len(sealed) < n+c.aead.Overhead() {
+		return nil, fmt.Errorf("%w: sealed value of %d bytes is too short", ErrDecrypt, len(sealed))
+	}
+	value, err := c.aead.Open(nil, sealed[:n], sealed[n:], data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDecrypt, err)
+	}
+
+	return value, nil
+}
+
+// KeySource returns the key referred to by ref, such as by fetching it from, or unwrapping it with, a KMS.
+type KeySource func(ref string) ([]byte, error)
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = map[string]KeySource{}
+)
+
+// RegisterKeySource registers the source of the keys referred to as "<scheme>:<ref>", as by config.EncryptionKMS.
+func RegisterKeySource(scheme string, source KeySource) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources[scheme] = source
+}
+
+// LoadKey returns the key referred to as "<scheme>:<ref>", by the source registered for the scheme.
+func LoadKey(uri string) ([]byte, error) {
+
+	i := strings.Index(uri, ":")
+	if i <= 0 {
+		return nil, fmt.Errorf("invalid key ref: %q, instead of <scheme>:<ref>", uri)
+	}
+	sourcesMu.RLock()
+	source, ok := sources[uri[:i]]
+	sourcesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no key source registered for %q", uri[:i])
+	}
+
+	key, err := source(uri[i+1:])
+	if err != nil {
+		return nil, fmt.Errorf("key source %s: %w", uri[:i], err)
+	}
+
+	return key, nil
+}
+
+// LoadKeyFile returns the key in the file, either raw, or hex encoded.
+func LoadKeyFile(path string) ([]byte, error) {
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	if len(b) == KeySize {
+		return b, nil
+	}
+	key, err := hex.DecodeString(string(bytes.TrimSpace(b)))
+	if err != nil || len(key) != KeySize {
+		return nil, fmt.Errorf("key file %s holds neither %d raw, nor hex encoded, bytes", path, KeySize)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,94 @@
+package encryptedrepo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/node/noderepo"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipher(t *testing.T) {
+
+	r := require.New(t)
+
+	_, err := NewCipher([]byte("short"))
+	r.Error(err)
+
+	c, err := NewCipher(bytes.Repeat([]byte{1}, KeySize))
+	r.NoError(err)
+
+	sealed := c.Seal([]byte("value"), []byte("key"))
+	r.NotContains(string(sealed), "value")
+	r.NotEqual(sealed, c.Seal([]byte("value"), []byte("key"))) // by the nonce
+	value, err := c.Open(sealed, []byte("key"))
+	r.NoError(err)
+	r.Equal([]byte("value"), value)
+
+	_, err = c.Open(sealed, []byte("other key"))
+	r.ErrorIs(err, ErrDecrypt)
+	_, err = c.Open(sealed[:10], []byte("key"))
+	r.ErrorIs(err, ErrDecrypt)
+	r.Empty(c.Seal(nil, []byte("key")))
+
+	other, err := NewCipher(bytes.Repeat([]byte{2}, KeySize))
+	r.NoError(err)
+	_, err = other.Open(sealed, []byte("key"))
+	r.ErrorIs(err, ErrDecrypt)
+}
+
+func TestLoadKey(t *testing.T) {
+
+	r := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "key")
+	r.NoError(os.WriteFile(path, bytes.Repeat([]byte{3}, KeySize), 0600))
+	key, err := LoadKeyFile(path)
+	r.NoError(err)
+	r.Equal(bytes.Repeat([]byte{3}, KeySize), key)
+
+	r.NoError(os.WriteFile(path, []byte("0303"), 0600))
+	_, err = LoadKeyFile(path)
+	r.Error(err)
+
+	RegisterKeySource("test", func(ref string) ([]byte, error) {
+		return bytes.Repeat([]byte(ref), KeySize), nil
+	})
+	key, err = LoadKey("test:x")
+	r.NoError(err)
+	r.Equal(bytes.Repeat([]byte("x"), KeySize), key)
+	_, err = LoadKey("unknown:x")
+	r.Error(err)
+	_, err = LoadKey("x")
+	r.Error(err)
+}
+
+func TestIterateChildren(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	defer repo.Close()
+
+	c, err := NewCipher(bytes.Repeat([]byte{1}, KeySize))
+	r.NoError(err)
+	other, err := NewCipher(bytes.Repeat([]byte{2}, KeySize))
+	r.NoError(err)
+
+	r.NoError(Node(repo, c).AppendChanges([]change.Change{{Name: []byte("a"), Height: 1, Value: []byte("value")}}))
+	r.NoError(Node(repo, other).AppendChanges([]change.Change{{Name: []byte("b"), Height: 1, Value: []byte("value")}}))
+
+	// The iteration stops at the changes failing to open, instead of panicking.
+	var names []string
+	Node(repo, c).IterateChildren(nil, func(name []byte, changes []change.Change) bool {
+		names = append(names, string(name))
+		r.Equal([]byte("value"), changes[0].Value)
+		return true
+	})
+	r.Equal([]string{"a"}, names)
+}
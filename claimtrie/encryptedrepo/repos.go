@@ -0,0 +1,170 @@
+package encryptedrepo
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/chain"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/claimvalue"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// sealChanges returns a copy of the changes with their values sealed, bound to their outpoints.
+func sealChanges(c *Cipher, changes []change.Change) []change.Change {
+
+	sealed := make([]change.Change, len(changes))
+	for i, chg := range changes {
+		chg.Value = c.Seal(chg.Value, chg.OutPoint[:])
+		sealed[i] = chg
+	}
+
+	return sealed
+}
+
+// openChanges opens the values of the changes in place.
+func openChanges(c *Cipher, changes []change.Change) error {
+
+	for i := range changes {
+		value, err := c.Open(changes[i].Value, changes[i].OutPoint[:])
+		if err != nil {
+			return fmt.Errorf("change of %q at %d: %w", changes[i].Name, changes[i].Height, err)
+		}
+		changes[i].Value = value
+	}
+
+	return nil
+}
+
+// Node wraps a node.Repo with the values of the changes sealed by c.
+// IterateChildren, which can't fail, logs the changes failing to open, and stops there.
+func Node(repo node.Repo, c *Cipher) node.Repo {
+	return &nodeRepo{Repo: repo, c: c}
+}
+
+type nodeRepo struct {
+	node.Repo
+	c *Cipher
+}
+
+func (r *nodeRepo) AppendChanges(changes []change.Change) error {
+	return r.Repo.AppendChanges(sealChanges(r.c, changes))
+}
+
+func (r *nodeRepo) LoadChanges(name []byte) ([]change.Change, error) {
+
+	changes, err := r.Repo.LoadChanges(name)
+	if err != nil {
+		return nil, err
+	}
+	err = openChanges(r.c, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (r *nodeRepo) IterateChildren(name []byte, f func(name []byte, changes []change.Change) bool) {
+	r.Repo.IterateChildren(name, func(name []byte, changes []change.Change) bool {
+		if err := openChanges(r.c, changes); err != nil {
+			log.Errorf("iterate the children of %q: %s", name, err)
+			return false
+		}
+		return f(name, changes)
+	})
+}
+
+// Snapshots wraps a node.SnapshotRepo with the snapshots sealed by c, bound to their names.
+func Snapshots(repo node.SnapshotRepo, c *Cipher) node.SnapshotRepo {
+	return &snapshotRepo{SnapshotRepo: repo, c: c}
+}
+
+type snapshotRepo struct {
+	node.SnapshotRepo
+	c *Cipher
+}
+
+func (r *snapshotRepo) LoadSnapshot(name []byte) ([]byte, error) {
+
+	sealed, err := r.SnapshotRepo.LoadSnapshot(name)
+	if err != nil || sealed == nil {
+		return sealed, err
+	}
+	snapshot, err := r.c.Open(sealed, name)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot of %q: %w", name, err)
+	}
+
+	return snapshot, nil
+}
+
+func (r *snapshotRepo) SaveSnapshot(name []byte, snapshot []byte) error {
+	return r.SnapshotRepo.SaveSnapshot(name, r.c.Seal(snapshot, name))
+}
+
+// Chain wraps a chain.Repo with the values of the changes sealed by c.
+func Chain(repo chain.Repo, c *Cipher) chain.Repo {
+	return &chainRepo{repo: repo, c: c}
+}
+
+type chainRepo struct {
+	repo chain.Repo
+	c    *Cipher
+}
+
+func (r *chainRepo) Save(height int32, changes []change.Change) error {
+	return r.repo.Save(height, sealChanges(r.c, changes))
+}
+
+func (r *chainRepo) Load(height int32) ([]change.Change, error) {
+
+	changes, err := r.repo.Load(height)
+	if err != nil {
+		return nil, err
+	}
+	err = openChanges(r.c, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (r *chainRepo) Close() error {
+	return r.repo.Close()
+}
+
+// ClaimValue wraps a claimvalue.Repo with the values sealed by c, bound to their outpoints.
+func ClaimValue(repo claimvalue.Repo, c *Cipher) claimvalue.Repo {
+	return &claimValueRepo{repo: repo, c: c}
+}
+
+type claimValueRepo struct {
+	repo claimvalue.Repo
+	c    *Cipher
+}
+
+func (r *claimValueRepo) Set(op wire.OutPoint, value []byte) error {
+	o := change.NewOutPoint(op)
+	return r.repo.Set(op, r.c.Seal(value, o[:]))
+}
+
+func (r *claimValueRepo) Get(op wire.OutPoint) ([]byte, error) {
+
+	sealed, err := r.repo.Get(op)
+	if err != nil || sealed == nil {
+		return sealed, err
+	}
+	o := change.NewOutPoint(op)
+	value, err := r.c.Open(sealed, o[:])
+	if err != nil {
+		return nil, fmt.Errorf("value of %s: %w", op, err)
+	}
+
+	return value, nil
+}
+
+func (r *claimValueRepo) Close() error {
+	return r.repo.Close()
+}
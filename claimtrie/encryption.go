@@ -0,0 +1,30 @@
+package claimtrie
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/encryptedrepo"
+)
+
+// newRepoCipher returns the cipher of the values encrypted at rest, or nil, if they aren't.
+func newRepoCipher(cfg config.Config) (*encryptedrepo.Cipher, error) {
+
+	var key []byte
+	var err error
+	switch {
+	case cfg.EncryptionKeyFile != "" && cfg.EncryptionKMS != "":
+		return nil, fmt.Errorf("the encryption key is either in a file, or from a KMS, not both")
+	case cfg.EncryptionKeyFile != "":
+		key, err = encryptedrepo.LoadKeyFile(cfg.EncryptionKeyFile)
+	case cfg.EncryptionKMS != "":
+		key, err = encryptedrepo.LoadKey(cfg.EncryptionKMS)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptedrepo.NewCipher(key)
+}
@@ -0,0 +1,160 @@
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+type Type int
+
+const (
+	// SupportExpiring is emitted ahead of the expiration of a support.
+	SupportExpiring Type = iota
+
+	// Takeover is emitted when a claim becomes the best claim of a name.
+	Takeover
+
+	// ClaimAdded is emitted when a new claim lands in a block.
+	ClaimAdded
+
+	// BudgetExceeded is emitted when a stage of processing a block takes longer than its budget.
+	BudgetExceeded
+
+	// NameChanged is emitted for each name updated in a block, with its best claim, if any.
+	NameChanged
+
+	// UpstreamDiverged is emitted when the root at a height differs from the upstream checkpoint.
+	UpstreamDiverged
+
+	// OwnershipTransferred is emitted when an update of a claim pays it to another destination than the one spent.
+	OwnershipTransferred
+)
+
+var typeNames = map[Type]string{
+	SupportExpiring:  "SupportExpiring",
+	Takeover:         "Takeover",
+	ClaimAdded:       "ClaimAdded",
+	BudgetExceeded:   "BudgetExceeded",
+	NameChanged:      "NameChanged",
+	UpstreamDiverged: "UpstreamDiverged",
+
+	OwnershipTransferred: "OwnershipTransferred",
+}
+
+func (t Type) String() string {
+	if s, ok := typeNames[t]; ok {
+		return s
+	}
+	return "Unknown"
+}
+
+// ParseType returns the Type of the specified name.
+func ParseType(s string) (Type, bool) {
+	for t, name := range typeNames {
+		if name == s {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// Event is a notification of something happened, or about to happen, in the ClaimTrie.
+type Event struct {
+	Type   Type
+	Height int32 // The height at which the event is emitted.
+
+	Name     []byte
+	ClaimID  string
+	OutPoint string
+	Amount   int64
+	Value    []byte
+	Channel  string // ClaimID of the signing channel, if any.
+
+	EffectiveAmount int64 // Of the best claim, including its activated supports, at a NameChanged.
+
+	ExpireAt int32
+
+	Stage   string // The stage of processing the block, which exceeded the budget.
+	Elapsed time.Duration
+	Budget  time.Duration
+
+	Root         string // The local root, and the upstream one, at an UpstreamDiverged.
+	UpstreamRoot string
+
+	PrevOwner string // The destinations of the claim spent, and of its update, at an OwnershipTransferred.
+	Owner     string
+
+	// The claims for the name at a takeover, the best first, if the takeover diagnostics are enabled.
+	Contenders []Contender
+}
+
+// Contender is a claim for a name at a takeover.
+type Contender struct {
+	ClaimID         string
+	OutPoint        string
+	Amount          int64
+	EffectiveAmount int64 // Including the activated supports; 0 until it's activated.
+	AcceptedAt      int32
+	ActiveAt        int32
+}
+
+// Handler handles an event. It must not block, as events are dispatched synchronously.
+type Handler func(e Event)
+
+// Bus dispatches events to the subscribed handlers in the order they are published.
+type Bus struct {
+	mu          sync.RWMutex
+	next        int
+	subscribers []subscriber
+}
+
+type subscriber struct {
+	id      int
+	handler Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers the handler, and returns a function to unsubscribe it.
+func (b *Bus) Subscribe(h Handler) func() {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+	b.subscribers = append(b.subscribers, subscriber{id: id, handler: h})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subscribers := make([]subscriber, 0, len(b.subscribers))
+		for _, s := range b.subscribers {
+			if s.id != id {
+				subscribers = append(subscribers, s)
+			}
+		}
+		b.subscribers = subscribers
+	}
+}
+
+// Publish dispatches the event to all subscribed handlers.
+func (b *Bus) Publish(e Event) {
+
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, s := range subscribers {
+		s.handler(e)
+	}
+}
+
+// HasSubscribers reports whether any handlers are subscribed, so expensive events can be skipped.
+func (b *Bus) HasSubscribers() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers) > 0
+}
@@ -0,0 +1,203 @@
+package claimtrie
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/event"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/webhook"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Subscribe registers a handler for the events of the ClaimTrie, and returns a function to unsubscribe it.
+func (ct *ClaimTrie) Subscribe(h event.Handler) func() {
+	return ct.events.Subscribe(h)
+}
+
+func newHooks(cfgs []config.WebhookConfig) ([]webhook.Hook, error) {
+
+	hooks := make([]webhook.Hook, 0, len(cfgs))
+	for _, c := range cfgs {
+		h := webhook.Hook{URL: c.URL, Channels: c.Channels}
+		for _, s := range c.Events {
+			t, ok := event.ParseType(s)
+			if !ok {
+				return nil, fmt.Errorf("unknown event type: %s", s)
+			}
+			h.Types = append(h.Types, t)
+		}
+		for _, name := range c.Names {
+			h.Names = append(h.Names, []byte(name))
+		}
+		hooks = append(hooks, h)
+	}
+
+	return hooks, nil
+}
+
+// ownerChange is the destination of a claim spent, and the one of its update.
+type ownerChange struct {
+	prev, next string
+}
+
+// TransferOwnership notes that the update of the claim at the outpoint, in the next block, pays it to
+// owner, instead of prevOwner, the destination of the one spent, for an OwnershipTransferred event.
+func (ct *ClaimTrie) TransferOwnership(op wire.OutPoint, prevOwner, owner string) {
+	if ct.transfers == nil {
+		ct.transfers = map[change.OutPoint]ownerChange{}
+	}
+	ct.transfers[change.NewOutPoint(op)] = ownerChange{prev: prevOwner, next: owner}
+}
+
+// publishBlockEvents emits ClaimAdded events for the claims added in the block,
+// OwnershipTransferred events for the claims updated to other destinations,
+// Takeover events for the updated names, of which the best claim changed, and
+// NameChanged events for all of the updated names.
+func (ct *ClaimTrie) publishBlockEvents(changes []change.Change, transfers map[change.OutPoint]ownerChange,
+	names [][]byte) error {
+
+	for _, chg := range changes {
+		typ := event.ClaimAdded
+		owners, transferred := transfers[chg.OutPoint]
+		if chg.Type == change.UpdateClaim && transferred {
+			typ = event.OwnershipTransferred
+		} else if chg.Type != change.AddClaim {
+			continue
+		}
+		e := event.Event{
+			Type:     typ,
+			Height:   ct.height,
+			Name:     node.NormalizeIfNecessary(chg.Name, ct.height),
+			ClaimID:  chg.ClaimID.String(),
+			OutPoint: chg.OutPoint.String(),
+			Amount:   chg.Amount,
+			Value:    chg.Value,
+		}
+		if id, ok := node.SigningChannel(chg.Value); ok {
+			e.Channel = id.String()
+		}
+		if typ == event.OwnershipTransferred {
+			e.PrevOwner, e.Owner = owners.prev, owners.next
+			if len(e.Name) > 0 && e.Name[0] == '@' {
+				e.Channel = e.ClaimID // a channel itself changed hands
+			}
+		}
+		ct.events.Publish(e)
+	}
+
+	for _, name := range names {
+		n, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return fmt.Errorf("node: %w", err)
+		}
+		var value []byte
+		if n != nil && n.BestClaim != nil {
+			value, err = ct.eventValue(n.BestClaim)
+			if err != nil {
+				return err
+			}
+		}
+		if n == nil || n.BestClaim == nil || n.TakenOverAt != ct.height {
+			ct.events.Publish(nameChanged(name, n, value, ct.height))
+			continue
+		}
+		e := event.Event{
+			Type:     event.Takeover,
+			Height:   ct.height,
+			Name:     name,
+			ClaimID:  n.BestClaim.ClaimID.String(),
+			OutPoint: n.BestClaim.OutPoint.String(),
+			Amount:   n.BestClaim.Amount,
+			Value:    value,
+		}
+		if ct.takeoverDiagnostics {
+			e.Contenders = contenders(n)
+		}
+		ct.events.Publish(e)
+		ct.events.Publish(nameChanged(name, n, value, ct.height))
+	}
+
+	return nil
+}
+
+// nameChanged returns the NameChanged event of the node, which is nil if the name has no claims,
+// with the value of its best claim.
+func nameChanged(name []byte, n *node.Node, value []byte, height int32) event.Event {
+
+	e := event.Event{Type: event.NameChanged, Height: height, Name: name}
+	if n == nil || n.BestClaim == nil {
+		return e
+	}
+	e.ClaimID = n.BestClaim.ClaimID.String()
+	e.OutPoint = n.BestClaim.OutPoint.String()
+	e.Amount = n.BestClaim.Amount
+	e.EffectiveAmount = n.BestClaim.EffectiveAmount(n.Supports)
+	e.Value = value
+	if id, ok := node.SigningChannel(value); ok {
+		e.Channel = id.String()
+	}
+
+	return e
+}
+
+// noticeSupportExpirations schedules the notices for the supports of the changed names,
+// and emits SupportExpiring events for the ones scheduled at the current height.
+func (ct *ClaimTrie) noticeSupportExpirations(changedNames [][]byte) error {
+
+	var names [][]byte
+	var heights []int32
+	for _, name := range changedNames {
+		n, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return fmt.Errorf("node: %w", err)
+		}
+		if n == nil {
+			continue
+		}
+		for _, s := range n.Supports {
+			at := s.ExpireAt() - ct.supportExpiringNotice
+			if s.Status != node.Deactivated && at >= ct.height {
+				names = append(names, name)
+				heights = append(heights, at)
+			}
+		}
+	}
+
+	err := ct.supportExpiringRepo.SetNodesAt(names, heights)
+	if err != nil {
+		return fmt.Errorf("support expiring repo set at: %w", err)
+	}
+
+	names, err = ct.supportExpiringRepo.NodesAt(ct.height)
+	if err != nil {
+		return fmt.Errorf("support expiring repo nodes at: %w", err)
+	}
+
+	for _, name := range names {
+		n, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return fmt.Errorf("node: %w", err)
+		}
+		if n == nil {
+			continue
+		}
+		for _, s := range n.Supports {
+			if s.Status == node.Deactivated || s.ExpireAt()-ct.supportExpiringNotice != ct.height {
+				continue
+			}
+			ct.events.Publish(event.Event{
+				Type:     event.SupportExpiring,
+				Height:   ct.height,
+				Name:     name,
+				ClaimID:  s.ClaimID.String(),
+				OutPoint: s.OutPoint.String(),
+				Amount:   s.Amount,
+				ExpireAt: s.ExpireAt(),
+			})
+		}
+	}
+
+	return nil
+}
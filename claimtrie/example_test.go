@@ -0,0 +1,135 @@
+package claimtrie_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// This example demonstrates adding a claim, which is accepted into the trie when
+// the block is appended.
+func ExampleClaimTrie_AddClaim() {
+	param.SetNetwork(wire.TestNet)
+
+	dir, err := os.MkdirTemp("", "claimtrie")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := config.DefaultConfig
+	cfg.DataDir = dir
+	ct, err := claimtrie.New(cfg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer ct.Close()
+
+	// The claim is made by the first output of a transaction.
+	op := wire.OutPoint{Hash: chainhash.HashH([]byte("tx")), Index: 0}
+	id := node.NewClaimID(op)
+	err = ct.AddClaim([]byte("example"), op, id, 10, nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// The changes of a block take effect when it's appended.
+	err = ct.AppendBlock()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	n, err := ct.Node([]byte("example"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("height:", ct.Height())
+	fmt.Println("best claim:", n.BestClaim.ClaimID == id)
+	fmt.Println("taken over at:", n.TakenOverAt)
+
+	// Output:
+	// height: 1
+	// best claim: true
+	// taken over at: 1
+}
+
+// This example demonstrates the flow of an embedder: feeding the claim changes of
+// each block, checking the resulting root against the one committed by the block,
+// and proving a name against the root.
+func Example_verifiedRoots() {
+	param.SetNetwork(wire.TestNet)
+
+	dir, err := os.MkdirTemp("", "claimtrie")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := config.DefaultConfig
+	cfg.DataDir = dir
+	ct, err := claimtrie.New(cfg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer ct.Close()
+
+	// The claim changes of the transactions of each block, as parsed from their scripts.
+	tx := chainhash.HashH([]byte("tx"))
+	one := wire.OutPoint{Hash: tx, Index: 0}
+	two := wire.OutPoint{Hash: tx, Index: 1}
+	blocks := []func() error{
+		func() error {
+			return ct.AddClaim([]byte("one"), one, node.NewClaimID(one), 10, nil)
+		},
+		func() error {
+			return ct.AddClaim([]byte("two"), two, node.NewClaimID(two), 20, nil)
+		},
+		func() error {
+			return ct.SpendClaim([]byte("one"), one, node.NewClaimID(one))
+		},
+	}
+
+	for _, changes := range blocks {
+		err = changes()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		err = ct.AppendBlock()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		// A full node compares the root with the one in the block header, and
+		// rejects the block, and resets the trie to the previous height, if different.
+		root := ct.MerkleHash()
+		fmt.Printf("block %d: %s\n", ct.Height(), root)
+	}
+
+	proof, err := ct.NameProof([]byte("two"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("verified:", proof.Verify(ct.MerkleHash(), []byte("two")))
+
+	// Output:
+	// block 1: d5cadcb70be56bffdb0af21ee49d688e92caa2776db6d9e74812c32f385f0601
+	// block 2: 14a748d39f2efeb5be7b283d211c32e88817b2e906493ab451c5eb1691f912ee
+	// block 3: 6850e37d0175b666d4241733b72e77f5d6e00b3ebc4d655bdcf37b935c5be8a9
+	// verified: true
+}
@@ -0,0 +1,133 @@
+// Package faultyrepo wraps repos with injected failures, for testing the
+// handling of the errors, and the recovery from the partial writes, of the repos.
+package faultyrepo
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInjected is the default error returned by the failing operations.
+var ErrInjected = errors.New("injected failure")
+
+type fault struct {
+	err   error
+	delay time.Duration
+	keep  int // entries of a batch applied before failing; -1 for the whole operation failing
+
+	corrupt bool // a bit of the value read is flipped, like a rotting disk would.
+}
+
+// Injector counts the operations of the repos wrapped with it, across all of them,
+// and injects the faults registered for the nth ones.
+type Injector struct {
+	mu     sync.Mutex
+	ops    int
+	faults map[int]fault
+}
+
+func NewInjector() *Injector {
+	return &Injector{faults: map[int]fault{}}
+}
+
+// FailAt makes the nth operation (counting from 1) fail with err, or ErrInjected if err is nil.
+func (in *Injector) FailAt(n int, err error) *Injector {
+	return in.set(n, fault{err: orInjected(err), keep: -1})
+}
+
+// DelayAt makes the nth operation take d longer, like a slow or stalled disk would.
+func (in *Injector) DelayAt(n int, d time.Duration) *Injector {
+	return in.set(n, fault{delay: d, keep: -1})
+}
+
+// PartialAt makes the nth operation, if it writes a batch, apply only the first
+// keep entries before failing with err, or ErrInjected if err is nil.
+// Other operations simply fail.
+func (in *Injector) PartialAt(n int, keep int, err error) *Injector {
+	return in.set(n, fault{err: orInjected(err), keep: keep})
+}
+
+// CorruptAt makes the nth operation, if it reads a value, return it with a bit flipped.
+// Other operations succeed.
+func (in *Injector) CorruptAt(n int) *Injector {
+	return in.set(n, fault{corrupt: true, keep: -1})
+}
+
+// Ops returns the number of the operations so far.
+func (in *Injector) Ops() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.ops
+}
+
+func (in *Injector) set(n int, f fault) *Injector {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.faults[n] = f
+	return in
+}
+
+// next counts an operation, and returns its fault, if any, after the delay.
+func (in *Injector) next() (fault, bool) {
+
+	in.mu.Lock()
+	in.ops++
+	f, ok := in.faults[in.ops]
+	in.mu.Unlock()
+
+	if ok && f.delay > 0 {
+		time.Sleep(f.delay)
+	}
+
+	return f, ok && f.err != nil
+}
+
+// fail returns the error of the next operation, which isn't a batch.
+func (in *Injector) fail() error {
+	f, failed := in.next()
+	if !failed {
+		return nil
+	}
+	return f.err
+}
+
+// batch returns the number of the entries of the next operation to apply, and its error.
+func (in *Injector) batch(entries int) (int, error) {
+	f, failed := in.next()
+	if !failed {
+		return entries, nil
+	}
+	if f.keep < 0 {
+		return 0, f.err
+	}
+	if f.keep < entries {
+		entries = f.keep
+	}
+	return entries, f.err
+}
+
+// read returns the error of the next operation, which reads a value, and whether to corrupt it.
+func (in *Injector) read() (bool, error) {
+	f, failed := in.next()
+	if failed {
+		return false, f.err
+	}
+	return f.corrupt, nil
+}
+
+// flip returns a copy of the value with a bit flipped.
+func flip(value []byte) []byte {
+	value = append([]byte(nil), value...)
+	if len(value) > 0 {
+		value[len(value)/2] ^= 1
+	}
+	return value
+}
+
+func orInjected(err error) error {
+	if err == nil {
+		return ErrInjected
+	}
+	return err
+}
@@ -0,0 +1,202 @@
+package faultyrepo
+
+import (
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/block"
+	"github.com/btcsuite/btcd/claimtrie/chain"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/temporal"
+)
+
+// Block wraps a block.Repo with the faults of in.
+func Block(repo block.Repo, in *Injector) block.Repo {
+	return &blockRepo{repo: repo, in: in}
+}
+
+type blockRepo struct {
+	repo block.Repo
+	in   *Injector
+}
+
+func (r *blockRepo) Load() (int32, error) {
+	if err := r.in.fail(); err != nil {
+		return 0, err
+	}
+	return r.repo.Load()
+}
+
+func (r *blockRepo) Set(height int32, hash *chainhash.Hash) error {
+	if err := r.in.fail(); err != nil {
+		return err
+	}
+	return r.repo.Set(height, hash)
+}
+
+func (r *blockRepo) Get(height int32) (*chainhash.Hash, error) {
+	if err := r.in.fail(); err != nil {
+		return nil, err
+	}
+	return r.repo.Get(height)
+}
+
+func (r *blockRepo) Close() error {
+	return r.repo.Close()
+}
+
+// Temporal wraps a temporal.Repo with the faults of in.
+// SetNodesAt is a batch, which may be partially applied.
+func Temporal(repo temporal.Repo, in *Injector) temporal.Repo {
+	return &temporalRepo{repo: repo, in: in}
+}
+
+type temporalRepo struct {
+	repo temporal.Repo
+	in   *Injector
+}
+
+func (r *temporalRepo) SetNodesAt(names [][]byte, heights []int32) error {
+	keep, err := r.in.batch(len(names))
+	if keep > 0 {
+		if err := r.repo.SetNodesAt(names[:keep], heights[:keep]); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (r *temporalRepo) NodesAt(height int32) ([][]byte, error) {
+	if err := r.in.fail(); err != nil {
+		return nil, err
+	}
+	return r.repo.NodesAt(height)
+}
+
+func (r *temporalRepo) DropNodesBefore(height int32) error {
+	if err := r.in.fail(); err != nil {
+		return err
+	}
+	return r.repo.DropNodesBefore(height)
+}
+
+func (r *temporalRepo) Close() error {
+	return r.repo.Close()
+}
+
+// Node wraps a node.Repo with the faults of in.
+// AppendChanges is a batch, which may be partially applied.
+// The iterations aren't subject to the faults, as they can't fail.
+func Node(repo node.Repo, in *Injector) node.Repo {
+	return &nodeRepo{Repo: repo, in: in}
+}
+
+type nodeRepo struct {
+	node.Repo
+	in *Injector
+}
+
+func (r *nodeRepo) AppendChanges(changes []change.Change) error {
+	keep, err := r.in.batch(len(changes))
+	if keep > 0 {
+		if err := r.Repo.AppendChanges(changes[:keep]); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (r *nodeRepo) LoadChanges(name []byte) ([]change.Change, error) {
+	if err := r.in.fail(); err != nil {
+		return nil, err
+	}
+	return r.Repo.LoadChanges(name)
+}
+
+func (r *nodeRepo) DropChanges(name []byte, finalHeight int32) error {
+	if err := r.in.fail(); err != nil {
+		return err
+	}
+	return r.Repo.DropChanges(name, finalHeight)
+}
+
+func (r *nodeRepo) PruneChanges(name []byte, height int32) error {
+	if err := r.in.fail(); err != nil {
+		return err
+	}
+	return r.Repo.PruneChanges(name, height)
+}
+
+func (r *nodeRepo) AddName(name []byte) error {
+	if err := r.in.fail(); err != nil {
+		return err
+	}
+	return r.Repo.AddName(name)
+}
+
+// Chain wraps a chain.Repo with the faults of in.
+// Save is a batch, which may be partially applied.
+func Chain(repo chain.Repo, in *Injector) chain.Repo {
+	return &chainRepo{repo: repo, in: in}
+}
+
+type chainRepo struct {
+	repo chain.Repo
+	in   *Injector
+}
+
+func (r *chainRepo) Save(height int32, changes []change.Change) error {
+	keep, err := r.in.batch(len(changes))
+	if keep > 0 {
+		if err := r.repo.Save(height, changes[:keep]); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (r *chainRepo) Load(height int32) ([]change.Change, error) {
+	if err := r.in.fail(); err != nil {
+		return nil, err
+	}
+	return r.repo.Load(height)
+}
+
+func (r *chainRepo) Close() error {
+	return r.repo.Close()
+}
+
+// MerkleTrie wraps a merkletrie.Repo with the faults of in.
+func MerkleTrie(repo merkletrie.Repo, in *Injector) merkletrie.Repo {
+	return &trieRepo{repo: repo, in: in}
+}
+
+type trieRepo struct {
+	repo merkletrie.Repo
+	in   *Injector
+}
+
+func (r *trieRepo) Get(key []byte) ([]byte, io.Closer, error) {
+	corrupt, err := r.in.read()
+	if err != nil {
+		return nil, nil, err
+	}
+	value, closer, err := r.repo.Get(key)
+	if err == nil && corrupt {
+		value = flip(value)
+	}
+	return value, closer, err
+}
+
+func (r *trieRepo) Set(key, value []byte) error {
+	if err := r.in.fail(); err != nil {
+		return err
+	}
+	return r.repo.Set(key, value)
+}
+
+func (r *trieRepo) Close() error {
+	return r.repo.Close()
+}
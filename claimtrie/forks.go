@@ -0,0 +1,19 @@
+package claimtrie
+
+import (
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// logForks logs the forks active at the height, and the ones scheduled after it.
+func logForks(height int32) {
+	for _, f := range param.Forks() {
+		switch {
+		case f.Height <= height:
+			log.Infof("Fork %s: active since height %d; %s", f.Name, f.Height, f.Description)
+		case f.Scheduled():
+			log.Infof("Fork %s: scheduled at height %d; %s", f.Name, f.Height, f.Description)
+		default:
+			log.Infof("Fork %s: not scheduled", f.Name)
+		}
+	}
+}
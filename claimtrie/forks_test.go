@@ -0,0 +1,584 @@
+package claimtrie
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/claimtrie/proof"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+// refClaim is a claim, or a support, of the reference model.
+type refClaim struct {
+	op         wire.OutPoint
+	id         node.ClaimID
+	amount     int64
+	acceptedAt int32
+	activeAt   int32
+	visibleAt  int32
+	status     node.Status
+}
+
+func (c *refClaim) expireAt() int32 {
+	if c.acceptedAt+param.OriginalClaimExpirationTime > param.ExtendedClaimExpirationForkHeight {
+		return c.acceptedAt + param.ExtendedClaimExpirationTime
+	}
+	return c.acceptedAt + param.OriginalClaimExpirationTime
+}
+
+type refNode struct {
+	claims      []*refClaim
+	supports    []*refClaim
+	best        *refClaim
+	takenOverAt int32
+}
+
+// refModel is a slow reference of the rules of the ClaimTrie: every name is adjusted at every height,
+// and the effective amounts, best claims, and the trie, are recomputed from scratch each time.
+// It shares nothing with the node manager, but the normalization of the names, and the hashes.
+type refModel struct {
+	nodes map[string]*refNode
+}
+
+func newRefModel() *refModel {
+	return &refModel{nodes: map[string]*refNode{}}
+}
+
+func (m *refModel) node(name []byte) *refNode {
+	n, ok := m.nodes[string(name)]
+	if !ok {
+		n = &refNode{}
+		m.nodes[string(name)] = n
+	}
+	return n
+}
+
+// appendBlock applies the changes of the block at the height, in order, and adjusts all the names to it.
+func (m *refModel) appendBlock(height int32, changes []change.Change) {
+
+	for _, chg := range changes {
+		name := node.NormalizeIfNecessary(chg.Name, height)
+		m.apply(name, m.node(name), chg, height)
+	}
+	if height == param.NormalizedNameForkHeight {
+		m.normalize(height)
+	}
+	for name, n := range m.nodes {
+		m.adjust([]byte(name), n, height)
+	}
+}
+
+func (m *refModel) apply(name []byte, n *refNode, chg change.Change, height int32) {
+
+	op := chg.OutPoint.Wire()
+	byOut := func(l []*refClaim) *refClaim {
+		for _, c := range l {
+			if c.op == op {
+				return c
+			}
+		}
+		return nil
+	}
+
+	switch chg.Type {
+	case change.AddClaim:
+		n.claims = append(n.claims, &refClaim{op: op, id: chg.ClaimID, amount: chg.Amount, acceptedAt: height,
+			activeAt: height + delayOf(name, n, chg.ClaimID, height), visibleAt: height})
+	case change.AddSupport:
+		n.supports = append(n.supports, &refClaim{op: op, id: chg.ClaimID, amount: chg.Amount, acceptedAt: height,
+			activeAt: height + delayOf(name, n, chg.ClaimID, height), visibleAt: height})
+	case change.SpendClaim:
+		if c := byOut(n.claims); c != nil {
+			c.status = node.Deactivated
+		}
+	case change.SpendSupport:
+		if c := byOut(n.supports); c != nil {
+			c.status = node.Deactivated
+		}
+	case change.UpdateClaim:
+		// The claim spent by the same transaction takes the new outpoint, amount and heights.
+		for _, c := range n.claims {
+			if c.id != chg.ClaimID {
+				continue
+			}
+			if c.status == node.Deactivated {
+				delay := delayOf(name, n, chg.ClaimID, height)
+				c.op, c.amount, c.status = op, chg.Amount, node.Accepted
+				c.acceptedAt, c.activeAt = height, height+delay
+			}
+			break
+		}
+	}
+}
+
+// delayOf returns the activation delay of a claim, or a support, of the claim ID added at the height.
+func delayOf(name []byte, n *refNode, id node.ClaimID, height int32) int32 {
+
+	if n.best == nil || n.best.id == id {
+		return 0
+	}
+	delay := (height - n.takenOverAt) / param.ActiveDelayFactor
+	if delay > param.MaxActiveDelay {
+		delay = param.MaxActiveDelay
+	}
+	if delay == 0 {
+		return 0
+	}
+
+	contains := func(heights []int32) bool {
+		for _, h := range heights {
+			if h == height {
+				return true
+			}
+		}
+		return false
+	}
+	// The ones past 933294 depend on the children of the name, which the matrix doesn't reach.
+	if height >= param.MaxRemovalWorkaroundHeight {
+		if contains(param.DelayWorkaroundsPart2[string(name)]) {
+			return 0
+		}
+	} else if len(n.claims) > 0 && contains(param.DelayWorkarounds[string(name)]) {
+		return 0
+	}
+
+	return delay
+}
+
+// normalize moves the claims and supports of the names, which aren't normalized, to their normalized names
+// at the fork. They keep their heights, but they're only visible from the fork on.
+func (m *refModel) normalize(height int32) {
+
+	var names []string
+	for name := range m.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		norm := node.Normalize([]byte(name))
+		if bytes.Equal(norm, []byte(name)) {
+			continue
+		}
+		from, to := m.nodes[name], m.node(norm)
+		move := func(l []*refClaim) []*refClaim {
+			var moved []*refClaim
+			for _, c := range l {
+				if c.status == node.Deactivated {
+					continue
+				}
+				cc := *c
+				cc.status, cc.visibleAt = node.Accepted, height
+				moved = append(moved, &cc)
+				c.status = node.Deactivated
+			}
+			return moved
+		}
+		to.claims = append(to.claims, move(from.claims)...)
+		to.supports = append(to.supports, move(from.supports)...)
+	}
+}
+
+// adjust activates, and expires, the claims and supports of the node at the height, and takes it over,
+// if its best claim changed.
+func (m *refModel) adjust(name []byte, n *refNode, height int32) {
+
+	changed := false
+	update := func(l []*refClaim) []*refClaim {
+		var kept []*refClaim
+		for _, c := range l {
+			if c.status == node.Accepted && c.activeAt <= height && c.visibleAt <= height {
+				c.status = node.Activated
+				changed = true
+			}
+			if c.expireAt() <= height || c.status == node.Deactivated {
+				changed = true
+				continue
+			}
+			kept = append(kept, c)
+		}
+		return kept
+	}
+	n.claims = update(n.claims)
+	n.supports = update(n.supports)
+
+	candidate := n.best
+	if changed {
+		candidate = bestOf(n)
+	}
+
+	takeover := candidate == nil || n.best == nil || n.best.status != node.Activated || candidate.id != n.best.id
+	if takeover {
+		// All the claims and supports pending are activated by a takeover.
+		activated := false
+		for _, c := range append(append([]*refClaim(nil), n.claims...), n.supports...) {
+			if c.status == node.Accepted && c.activeAt > height && c.visibleAt <= height {
+				c.activeAt, c.status = height, node.Activated
+				activated = true
+			}
+		}
+		if activated {
+			candidate = bestOf(n)
+		}
+	}
+	if !takeover && height < param.MaxRemovalWorkaroundHeight {
+		takeover = param.IsTakeoverWorkaround(height, name)
+	}
+	if takeover {
+		n.best, n.takenOverAt = candidate, height
+	}
+}
+
+func effectiveAmount(n *refNode, c *refClaim) int64 {
+
+	if c.status != node.Activated {
+		return 0
+	}
+	amount := c.amount
+	for _, s := range n.supports {
+		if s.id == c.id && s.status == node.Activated {
+			amount += s.amount
+		}
+	}
+	return amount
+}
+
+// better reports whether a takes precedence over b: the greater effective amount, then the earlier
+// accepted, then the lesser outpoint.
+func better(n *refNode, a, b *refClaim) bool {
+
+	if ea, eb := effectiveAmount(n, a), effectiveAmount(n, b); ea != eb {
+		return ea > eb
+	}
+	if a.acceptedAt != b.acceptedAt {
+		return a.acceptedAt < b.acceptedAt
+	}
+	if cmp := bytes.Compare(a.op.Hash[:], b.op.Hash[:]); cmp != 0 {
+		return cmp < 0
+	}
+	return a.op.Index < b.op.Index
+}
+
+func bestOf(n *refNode) *refClaim {
+
+	var best *refClaim
+	for _, c := range n.claims {
+		if c.status == node.Activated && (best == nil || better(n, c, best)) {
+			best = c
+		}
+	}
+	return best
+}
+
+// refStore is the ValueStore of the reference model as of a height.
+type refStore struct {
+	m      *refModel
+	height int32
+}
+
+func (s refStore) Hash(name []byte) *chainhash.Hash {
+	n := s.m.nodes[string(name)]
+	if n == nil || len(n.claims) == 0 || n.best == nil || n.best.status != node.Activated {
+		return nil
+	}
+	return proof.ValueHash(n.best.op, n.takenOverAt)
+}
+
+func (s refStore) ClaimHashes(name []byte) []*chainhash.Hash {
+	n := s.m.nodes[string(name)]
+	if n == nil {
+		return nil
+	}
+	var claims []*refClaim
+	for _, c := range n.claims {
+		if c.status == node.Activated {
+			claims = append(claims, c)
+		}
+	}
+	sort.Slice(claims, func(i, j int) bool { return better(n, claims[i], claims[j]) })
+	var hashes []*chainhash.Hash
+	for _, c := range claims {
+		hashes = append(hashes, proof.ValueHash(c.op, n.takenOverAt))
+	}
+	return hashes
+}
+
+// root returns the root of a trie built from scratch of all the names at the height.
+func (m *refModel) root(height int32) *chainhash.Hash {
+
+	trie := merkletrie.NewRamTrie(refStore{m: m, height: height})
+	for name := range m.nodes {
+		trie.Update([]byte(name), true)
+	}
+	if height >= param.AllClaimsInMerkleForkHeight {
+		return trie.MerkleHashAllClaims()
+	}
+	return trie.MerkleHash()
+}
+
+// forkCase is a fork, of which the boundary the change sequences generated for it cross.
+type forkCase struct {
+	fork  string
+	names []string
+	set   func()       // sets the heights of the fork, and the params around it.
+	pivot func() int32 // the height the changes are made around.
+	tail  int32        // the blocks appended past the changes.
+}
+
+const forkCaseSpan = 8 // the changes are made up to this many blocks before, and after, the pivot.
+
+var forkCases = []forkCase{
+	{
+		fork:  "extended_claim_expiration",
+		names: []string{"a", "ab", "b"},
+		set: func() {
+			param.ExtendedClaimExpirationForkHeight = 40
+			param.OriginalClaimExpirationTime = 30
+			param.ExtendedClaimExpirationTime = 50
+		},
+		// The claims accepted after it expire after the fork, with the extended time.
+		pivot: func() int32 { return param.ExtendedClaimExpirationForkHeight - param.OriginalClaimExpirationTime },
+		tail:  55,
+	},
+	{
+		fork:  "normalized_names",
+		names: []string{"Test", "test", "TEST", "Añejo", "añejo", "AÑEJO", "other"},
+		set:   func() { param.NormalizedNameForkHeight = 20 },
+		pivot: func() int32 { return param.NormalizedNameForkHeight },
+		tail:  15,
+	},
+	{
+		fork:  "takeover_workarounds",
+		names: []string{"a", "ab", "b"},
+		set:   func() { param.MaxRemovalWorkaroundHeight = 20 },
+		pivot: func() int32 { return param.MaxRemovalWorkaroundHeight },
+		tail:  15,
+	},
+	{
+		fork:  "all_claims_in_merkle",
+		names: []string{"a", "ab", "b"},
+		set:   func() { param.AllClaimsInMerkleForkHeight = 20 },
+		pivot: func() int32 { return param.AllClaimsInMerkleForkHeight },
+		tail:  15,
+	},
+}
+
+type generatedClaim struct {
+	name string
+	op   wire.OutPoint
+	id   node.ClaimID
+}
+
+// generateForkChanges generates the changes of the blocks up to the pivot, and forkCaseSpan blocks past it,
+// by their heights. The claims and supports are added, updated and spent at random, with small amounts, so
+// that they tie, and take over, often. The claims and supports of the names, which aren't normalized, aren't
+// spent at the normalization fork, which moves them to the normalized names after the changes of the block.
+func generateForkChanges(rnd *rand.Rand, fc forkCase) map[int32][]change.Change {
+
+	from, to := fc.pivot()-forkCaseSpan, fc.pivot()+forkCaseSpan
+	if from < 1 {
+		from = 1
+	}
+
+	var claims, supports []generatedClaim
+	seq := uint32(0)
+	newOutPoint := func() wire.OutPoint {
+		seq++
+		return wire.OutPoint{Hash: chainhash.HashH([]byte(fmt.Sprint(rnd.Int63()))), Index: seq % 3}
+	}
+	pick := func(l []generatedClaim, height int32) (int, bool) {
+		if len(l) == 0 {
+			return 0, false
+		}
+		i := rnd.Intn(len(l))
+		moved := height == param.NormalizedNameForkHeight && !bytes.Equal(node.Normalize([]byte(l[i].name)), []byte(l[i].name))
+		return i, !moved
+	}
+
+	blocks := map[int32][]change.Change{}
+	for height := from; height <= to; height++ {
+		var changes []change.Change
+		for k := rnd.Intn(4); k > 0; k-- {
+			amount := 1 + rnd.Int63n(4)
+			switch op := rnd.Intn(10); {
+			case op < 4:
+				c := generatedClaim{name: fc.names[rnd.Intn(len(fc.names))], op: newOutPoint()}
+				c.id = node.NewClaimID(c.op)
+				claims = append(claims, c)
+				changes = append(changes, change.Change{Type: change.AddClaim, Name: []byte(c.name),
+					OutPoint: change.NewOutPoint(c.op), ClaimID: c.id, Amount: amount})
+			case op < 6:
+				i, ok := pick(claims, height)
+				if !ok {
+					continue
+				}
+				s := generatedClaim{name: claims[i].name, op: newOutPoint(), id: claims[i].id}
+				if rnd.Intn(3) == 0 { // to a sibling name, which it counts for once they're normalized
+					s.name = fc.names[rnd.Intn(len(fc.names))]
+				}
+				supports = append(supports, s)
+				changes = append(changes, change.Change{Type: change.AddSupport, Name: []byte(s.name),
+					OutPoint: change.NewOutPoint(s.op), ClaimID: s.id, Amount: amount})
+			case op < 8:
+				i, ok := pick(claims, height)
+				if !ok {
+					continue
+				}
+				c := claims[i]
+				changes = append(changes, change.Change{Type: change.SpendClaim, Name: []byte(c.name),
+					OutPoint: change.NewOutPoint(c.op), ClaimID: c.id})
+				if op == 7 { // updated by the same transaction
+					claims[i].op = newOutPoint()
+					changes = append(changes, change.Change{Type: change.UpdateClaim, Name: []byte(c.name),
+						OutPoint: change.NewOutPoint(claims[i].op), ClaimID: c.id, Amount: amount})
+				} else {
+					claims = append(claims[:i], claims[i+1:]...)
+				}
+			default:
+				i, ok := pick(supports, height)
+				if !ok {
+					continue
+				}
+				s := supports[i]
+				supports = append(supports[:i], supports[i+1:]...)
+				changes = append(changes, change.Change{Type: change.SpendSupport, Name: []byte(s.name),
+					OutPoint: change.NewOutPoint(s.op), ClaimID: s.id})
+			}
+		}
+		blocks[height] = changes
+	}
+
+	return blocks
+}
+
+// addWorkarounds adds the delay, and takeover, workarounds of the names at some of the heights they're changed
+// at, on both sides of the fork removing the original ones, which is undone once the test is done.
+func addWorkarounds(t *testing.T, rnd *rand.Rand, blocks map[int32][]change.Change) {
+
+	delays := map[string][]int32{}
+	delays2 := map[string][]int32{}
+	for name, heights := range param.DelayWorkarounds {
+		delays[name] = heights
+	}
+	for name, heights := range param.DelayWorkaroundsPart2 {
+		delays2[name] = heights
+	}
+	var takeovers []string
+	t.Cleanup(func() {
+		param.DelayWorkarounds, param.DelayWorkaroundsPart2 = delays, delays2
+		for _, key := range takeovers {
+			delete(param.TakeoverWorkarounds, key)
+		}
+	})
+
+	param.DelayWorkarounds, param.DelayWorkaroundsPart2 = map[string][]int32{}, map[string][]int32{}
+	for height, changes := range blocks {
+		for _, chg := range changes {
+			if rnd.Intn(3) != 0 {
+				continue
+			}
+			name := string(chg.Name)
+			param.DelayWorkarounds[name] = append(param.DelayWorkarounds[name], height)
+			param.DelayWorkaroundsPart2[name] = append(param.DelayWorkaroundsPart2[name], height)
+			key := fmt.Sprintf("%d_%s", height, name)
+			if _, ok := param.TakeoverWorkarounds[key]; !ok {
+				param.TakeoverWorkarounds[key] = int(height)
+				takeovers = append(takeovers, key)
+			}
+		}
+	}
+}
+
+// TestForkMatrix replays the change sequences generated across the boundary of each fork, and checks the best
+// claims, their takeover heights, and the roots of the trie, of every block against the reference model.
+// The takeovers are only forced at the heights the names are changed at, as the nodes are only adjusted to
+// those, and the ones of their activations and expirations.
+func TestForkMatrix(t *testing.T) {
+
+	seeds := 6
+	if testing.Short() {
+		seeds = 2
+	}
+
+	for _, fc := range forkCases {
+		for seed := 1; seed <= seeds; seed++ {
+			fc, seed := fc, seed
+			t.Run(fmt.Sprintf("%s/%d", fc.fork, seed), func(t *testing.T) {
+
+				r := require.New(t)
+
+				setup(t)
+				param.ActiveDelayFactor = 2
+				param.MaxActiveDelay = 6
+				fc.set()
+
+				rnd := rand.New(rand.NewSource(int64(seed)))
+				blocks := generateForkChanges(rnd, fc)
+				if fc.fork == "takeover_workarounds" {
+					addWorkarounds(t, rnd, blocks)
+				}
+
+				ct, err := New(cfg)
+				r.NoError(err)
+				defer ct.Close()
+
+				m := newRefModel()
+				last := fc.pivot() + forkCaseSpan + fc.tail
+				for height := int32(1); height <= last; height++ {
+					for _, chg := range blocks[height] {
+						r.NoError(applyForkChange(ct, chg))
+					}
+					r.NoError(ct.AppendBlock())
+					m.appendBlock(height, blocks[height])
+
+					for name, exp := range m.nodes {
+						n, err := ct.nodeManager.Node([]byte(name))
+						r.NoError(err)
+						var got *node.Claim
+						if n != nil && n.BestClaim != nil && n.BestClaim.Status == node.Activated {
+							got = n.BestClaim
+						}
+						if exp.best == nil {
+							r.Nil(got, "best claim of %q at %d", name, height)
+							continue
+						}
+						r.NotNil(got, "best claim of %q at %d", name, height)
+						r.Equal(exp.best.op, got.OutPoint, "best claim of %q at %d", name, height)
+						r.Equal(exp.takenOverAt, n.TakenOverAt, "takeover of %q at %d", name, height)
+						r.Equal(effectiveAmount(exp, exp.best), got.EffectiveAmount(n.Supports),
+							"effective amount of %q at %d", name, height)
+					}
+					r.Equal(m.root(height).String(), ct.MerkleHash().String(), "root at %d", height)
+				}
+			})
+		}
+	}
+}
+
+func applyForkChange(ct *ClaimTrie, chg change.Change) error {
+
+	op := chg.OutPoint.Wire()
+	switch chg.Type {
+	case change.AddClaim:
+		return ct.AddClaim(chg.Name, op, chg.ClaimID, chg.Amount, nil)
+	case change.UpdateClaim:
+		return ct.UpdateClaim(chg.Name, op, chg.Amount, chg.ClaimID, nil)
+	case change.SpendClaim:
+		return ct.SpendClaim(chg.Name, op, chg.ClaimID)
+	case change.AddSupport:
+		return ct.AddSupport(chg.Name, nil, op, chg.Amount, chg.ClaimID)
+	case change.SpendSupport:
+		return ct.SpendSupport(chg.Name, op, chg.ClaimID)
+	}
+	return fmt.Errorf("invalid change: %v", chg)
+}
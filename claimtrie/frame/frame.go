@@ -0,0 +1,65 @@
+// Package frame reads and writes the frames shared by the wire protocols of the ClaimTrie, such as
+// the ones of the delta and block publishers, the cluster workers and the remote KV stores.
+// The integers are big-endian.
+//
+//	field: len(4B) data
+//	frame: status(1B) field
+package frame
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxSize is the largest field read, which bounds the memory allocated for the length sent by a peer.
+const MaxSize = 64 << 20
+
+// ErrTooLarge is returned when the length of a field read is larger than MaxSize.
+var ErrTooLarge = errors.New("frame too large")
+
+// WriteField writes the field, prefixed by its length.
+func WriteField(w io.Writer, field []byte) error {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(field)))
+	if _, err := w.Write(n[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(field)
+	return err
+}
+
+// ReadField reads a field, prefixed by its length, up to MaxSize bytes.
+func ReadField(r io.Reader) ([]byte, error) {
+	var n [4]byte
+	if _, err := io.ReadFull(r, n[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(n[:])
+	if size > MaxSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, size)
+	}
+	field := make([]byte, size)
+	_, err := io.ReadFull(r, field)
+	return field, err
+}
+
+// Write writes the frame of the status and the payload. It's buffered by w, which the caller flushes.
+func Write(w *bufio.Writer, status byte, payload []byte) error {
+	if err := w.WriteByte(status); err != nil {
+		return err
+	}
+	return WriteField(w, payload)
+}
+
+// Read reads a frame, and returns its status and payload.
+func Read(r *bufio.Reader) (byte, []byte, error) {
+	status, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	payload, err := ReadField(r)
+	return status, payload, err
+}
@@ -0,0 +1,44 @@
+package frame
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrame(t *testing.T) {
+
+	r := require.New(t)
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	r.NoError(Write(w, 1, []byte("payload")))
+	r.NoError(Write(w, 2, nil))
+	r.NoError(w.Flush())
+
+	br := bufio.NewReader(&buf)
+	status, payload, err := Read(br)
+	r.NoError(err)
+	r.Equal(byte(1), status)
+	r.Equal([]byte("payload"), payload)
+	status, payload, err = Read(br)
+	r.NoError(err)
+	r.Equal(byte(2), status)
+	r.Empty(payload)
+}
+
+func TestFrameTooLarge(t *testing.T) {
+
+	r := require.New(t)
+
+	// The length sent by the peer is rejected, before anything is allocated for it.
+	b := []byte{0, 0xff, 0xff, 0xff, 0xff}
+	_, _, err := Read(bufio.NewReader(bytes.NewReader(b)))
+	r.True(errors.Is(err, ErrTooLarge))
+
+	_, err = ReadField(bytes.NewReader([]byte{0x04, 0, 0, 1}))
+	r.True(errors.Is(err, ErrTooLarge))
+}
@@ -0,0 +1,137 @@
+package claimtrie
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// Severity is the severity of a Finding.
+type Severity int
+
+const (
+	SeverityInfo    Severity = iota // nothing is wrong, but it's worth knowing.
+	SeverityWarning                 // likely a consequence of another finding.
+	SeverityError                   // the repos are inconsistent, and the hashes can't be trusted.
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// Finding is a problem found by Fsck in one of the repos, with the suggested repair.
+type Finding struct {
+	Severity Severity
+	Repo     string // block, node, or trie.
+	Key      string // the height, name, or trie key at fault.
+	Problem  string
+	Repair   string
+}
+
+// FsckReport is the outcome of Fsck.
+type FsckReport struct {
+	Height   int32
+	Root     *chainhash.Hash
+	Blocks   int // the heights checked for their roots.
+	Names    int // the names reachable from the root.
+	Findings []Finding
+}
+
+// Worst returns the highest severity of the findings, or SeverityInfo if there are none.
+func (r *FsckReport) Worst() Severity {
+	worst := SeverityInfo
+	for _, f := range r.Findings {
+		if f.Severity > worst {
+			worst = f.Severity
+		}
+	}
+	return worst
+}
+
+func (r *FsckReport) add(severity Severity, repo, key, problem, repair string) {
+	r.Findings = append(r.Findings, Finding{Severity: severity, Repo: repo, Key: key, Problem: problem, Repair: repair})
+}
+
+// The repairs suggested by Fsck.
+const (
+	repairReplay     = "rebuild the ClaimTrie by replaying the chain with `claimtrie chain replay`"
+	repairCheckpoint = "restore a trie checkpoint at, or before, the height, or " + repairReplay
+	repairRestart    = "restart the node; the corrupt trie nodes are rebuilt when the next block is hashed"
+	repairNode       = "inspect the changes of the name with `claimtrie node dump`, then " + repairReplay
+)
+
+// Fsck cross-checks the repos at the current height: every height has a root in the
+// block repo, every trie node reachable from the root resolves, and the value hashes in
+// the trie match the ones of the nodes, which all have to be reachable.
+// The names are held in memory for the check. It mustn't be called concurrently with AppendBlock.
+func (ct *ClaimTrie) Fsck() *FsckReport {
+
+	report := &FsckReport{Height: ct.height}
+
+	for h := int32(1); h <= ct.height; h++ {
+		report.Blocks++
+		hash, err := ct.blockRepo.Get(h)
+		if err != nil {
+			report.add(SeverityError, "block", fmt.Sprint(h), fmt.Sprintf("has no root: %s", err), repairReplay)
+			continue
+		}
+		if h == ct.height {
+			report.Root = hash
+		}
+	}
+	if report.Root == nil {
+		if ct.height > 0 {
+			report.add(SeverityError, "trie", "", "the root of the tip is unknown", repairReplay)
+		} else {
+			report.add(SeverityInfo, "trie", "", "the trie is empty", "")
+		}
+		return report
+	}
+
+	allClaims := ct.height >= param.AllClaimsInMerkleForkHeight
+	reachable := map[string]bool{}
+	res := ct.merkleTrie.At(report.Root).Check(allClaims, func(name []byte) {
+		reachable[string(name)] = true
+	})
+	report.Names = res.Names
+
+	for _, key := range res.Missing {
+		report.add(SeverityError, "trie", fmt.Sprintf("%q", key), "the node is missing", repairCheckpoint)
+	}
+	for _, key := range res.Corrupt {
+		report.add(SeverityError, "trie", fmt.Sprintf("%q", key), "the node fails its checksum", repairRestart)
+	}
+	for _, name := range res.Mismatched {
+		report.add(SeverityError, "trie", fmt.Sprintf("%q", name), "the value hash differs from the one of the node", repairNode)
+	}
+
+	// The names under the missing, or corrupt, nodes are unreachable as a consequence.
+	unreachable := SeverityError
+	if len(res.Missing) > 0 || len(res.Corrupt) > 0 {
+		unreachable = SeverityWarning
+	}
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		if reachable[string(name)] {
+			return true
+		}
+		hasValue := ct.nodeManager.Hash(name) != nil
+		if allClaims {
+			hasValue = len(ct.nodeManager.ClaimHashes(name)) > 0
+		}
+		if hasValue {
+			report.add(unreachable, "node", fmt.Sprintf("%q", name), "has claims, but isn't reachable from the root", repairNode)
+		}
+		return true
+	})
+
+	return report
+}
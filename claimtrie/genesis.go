@@ -0,0 +1,101 @@
+package claimtrie
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/btcsuite/btcd/claimtrie/chain/chainrepo"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// applyGenesisClaims appends the claims and supports read from the file, a dump of the changes of
+// height 0 in the COPY format of the chain repo, before any block, and records the root of the trie
+// with them as the one of height 0. The ClaimTrie must be empty.
+func (ct *ClaimTrie) applyGenesisClaims(path string, repo node.Repo) error {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open genesis claims: %w", err)
+	}
+	defer f.Close()
+
+	var changes []change.Change
+	_, err = chainrepo.ReadCopy(f, func(height int32, block []change.Change) error {
+		if height != 0 {
+			return fmt.Errorf("genesis claims at height %d, instead of 0", height)
+		}
+		for _, chg := range block {
+			if chg.Type != change.AddClaim && chg.Type != change.AddSupport {
+				return change.Wrap(fmt.Errorf("genesis claims can only add claims and supports"), chg)
+			}
+			err := checkValueSize(chg, 0)
+			if err != nil {
+				return err
+			}
+			chg.Name = node.NormalizeIfNecessary(chg.Name, 0)
+			chg.Seq = int32(len(changes))
+			changes = append(changes, chg)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("read genesis claims: %w", err)
+	}
+
+	stored := make([]change.Change, 0, len(changes))
+	for _, chg := range changes {
+		chg, err = ct.claimValues.externalize(chg)
+		if err != nil {
+			return change.Wrap(err, chg)
+		}
+		stored = append(stored, chg)
+	}
+	err = repo.AppendChanges(stored)
+	if err != nil {
+		return fmt.Errorf("save genesis claims to node repo: %w", err)
+	}
+	if ct.chainRepo != nil {
+		err = ct.chainRepo.Save(0, changes)
+		if err != nil {
+			return fmt.Errorf("chain change repo save: %w", err)
+		}
+	}
+
+	names := make([][]byte, 0, len(changes))
+	for _, chg := range changes {
+		names = append(names, chg.Name)
+	}
+	names = removeDuplicates(names)
+
+	updateNames := append([][]byte(nil), names...)
+	updateHeights := make([]int32, len(names), 2*len(names))
+	for _, name := range names {
+		ct.merkleTrie.Update(name, true)
+		if ct.ramTrie != nil {
+			ct.ramTrie.Update(name, true)
+		}
+		newName, nextUpdate := ct.nodeManager.NextUpdateHeightOfNode(name)
+		if nextUpdate > 0 {
+			updateNames = append(updateNames, newName)
+			updateHeights = append(updateHeights, nextUpdate)
+		}
+	}
+	err = ct.temporalRepo.SetNodesAt(updateNames, updateHeights)
+	if err != nil {
+		return fmt.Errorf("temporal repo set at: %w", err)
+	}
+
+	ct.root = ct.MerkleHash()
+	err = ct.merkleTrie.Commit()
+	if err != nil {
+		return fmt.Errorf("merkle trie commit: %w", err)
+	}
+	err = ct.blockRepo.Set(0, ct.root)
+	if err != nil {
+		return fmt.Errorf("block repo set: %w", err)
+	}
+	log.Infof("Applied %d genesis claims and supports of %d names from %s, root: %s", len(changes), len(names), path, ct.root)
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package claimtrie
+
+import (
+	"fmt"
+	"time"
+)
+
+// Health is the status of the ClaimTrie, as reported to the health, and readiness, probes.
+type Health struct {
+	Height int32
+	Root   string
+
+	// When the last block was appended, and how long it took, which are zero before any.
+	LastBlockAt   time.Time
+	LastBlockTook time.Duration
+
+	// The error reading the root of the tip back from the block repo, if any.
+	RepoErr string
+}
+
+// Health returns the status of the ClaimTrie. The repos are checked by reading the root of the tip back
+// from the block repo, and comparing it with the one of the ClaimTrie.
+// It may be called while blocks are being appended.
+func (ct *ClaimTrie) Health() Health {
+
+	ct.statsMu.Lock()
+	h := Health{
+		Height:        ct.stats.Height,
+		Root:          ct.stats.Root,
+		LastBlockAt:   ct.lastBlockAt,
+		LastBlockTook: ct.lastBlockTook,
+	}
+	ct.statsMu.Unlock()
+
+	err := ct.checkRepo(h.Height, h.Root)
+	if err != nil {
+		h.RepoErr = err.Error()
+	}
+
+	return h
+}
+
+func (ct *ClaimTrie) checkRepo(height int32, root string) error {
+
+	if root == "" { // nothing was appended yet
+		return nil
+	}
+
+	hash, err := ct.blockRepo.Get(height)
+	if err != nil {
+		return fmt.Errorf("block repo get %d: %w", height, err)
+	}
+	if hash.String() != root {
+		return fmt.Errorf("root of %d in the block repo: %s, expected: %s", height, hash, root)
+	}
+
+	return nil
+}
+
+// recordBlockTime records when the block, started at start, was appended, and how long it took.
+func (ct *ClaimTrie) recordBlockTime(start time.Time) {
+	ct.statsMu.Lock()
+	ct.lastBlockAt = time.Now()
+	ct.lastBlockTook = ct.lastBlockAt.Sub(start)
+	ct.statsMu.Unlock()
+}
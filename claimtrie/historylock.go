@@ -0,0 +1,100 @@
+package claimtrie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrHistoryBusy is returned by the historical queries, which waited longer than the HistoryReadWait
+// for a reset, or a prune, rewriting the history to finish.
+var ErrHistoryBusy = errors.New("history is being rewritten")
+
+// historyLock is a readers-writer lock of the history, of which the readers give up waiting for the writers after
+// a while, or once their contexts are done. The readers holding it, when a writer comes along, keep reading the
+// state before its rewrite, which waits for them, while the readers coming after wait for the rewrite instead.
+type historyLock struct {
+	mu      sync.Mutex
+	readers int
+	writing chan struct{} // Closed, once the writer holding, or waiting for, the lock is done.
+	drained chan struct{} // Closed by the last reader for the writer waiting.
+}
+
+// RLock read locks the history, waiting for the writer, if any, up to the wait, unless it's 0.
+func (l *historyLock) RLock(ctx context.Context, wait time.Duration) error {
+
+	var timeout <-chan time.Time
+	l.mu.Lock()
+	for l.writing != nil {
+		writing := l.writing
+		l.mu.Unlock()
+		if timeout == nil && wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-writing:
+		case <-timeout:
+			return fmt.Errorf("%w: waited for %s", ErrHistoryBusy, wait)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		l.mu.Lock()
+	}
+	l.readers++
+	l.mu.Unlock()
+
+	return nil
+}
+
+func (l *historyLock) RUnlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.readers--
+	if l.readers == 0 && l.drained != nil {
+		close(l.drained)
+		l.drained = nil
+	}
+}
+
+// Lock locks the history, once the other writers are done, and the readers holding it are.
+func (l *historyLock) Lock() {
+
+	l.mu.Lock()
+	for l.writing != nil {
+		writing := l.writing
+		l.mu.Unlock()
+		<-writing
+		l.mu.Lock()
+	}
+	l.writing = make(chan struct{})
+	var drained chan struct{}
+	if l.readers > 0 {
+		drained = make(chan struct{})
+		l.drained = drained
+	}
+	l.mu.Unlock()
+
+	if drained != nil {
+		<-drained
+	}
+}
+
+func (l *historyLock) Unlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	close(l.writing)
+	l.writing = nil
+}
+
+// readHistory read locks the history for a historical query, waiting for a rewrite up to the HistoryReadWait.
+func (ct *ClaimTrie) readHistory(ctx context.Context) (func(), error) {
+	err := ct.history.RLock(ctx, ct.historyReadWait)
+	if err != nil {
+		return nil, err
+	}
+	return ct.history.RUnlock, nil
+}
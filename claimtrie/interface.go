@@ -0,0 +1,52 @@
+package claimtrie
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Queries are the queries of the names and claims of a ClaimTrie.
+type Queries interface {
+	Height() int32
+	MerkleHash() *chainhash.Hash
+	Node(name []byte) (*node.Node, error)
+	ResolveAt(name []byte, height int32) (*Resolution, error)
+	ResolveAtContext(ctx context.Context, name []byte, height int32) (*Resolution, error)
+	ProveAtContext(ctx context.Context, name []byte, height int32) (*Resolution, *merkletrie.Proof, error)
+	NameProof(name []byte) (*merkletrie.Proof, error)
+	ClaimByID(id node.ClaimID) ([]IndexedClaim, error)
+	ClaimValue(c *node.Claim) ([]byte, error)
+	TopNames(n int) ([]TopName, error)
+	SearchNames(query string, opts SearchOptions) ([][]byte, error)
+	Stats() Stats
+	Health() Health
+}
+
+// Mutations are the changes of the claims and supports of a ClaimTrie, which are applied by AppendBlock.
+type Mutations interface {
+	AddClaim(name []byte, op wire.OutPoint, id node.ClaimID, amt int64, val []byte) error
+	UpdateClaim(name []byte, op wire.OutPoint, amt int64, id node.ClaimID, val []byte) error
+	SpendClaim(name []byte, op wire.OutPoint, id node.ClaimID) error
+	AddSupport(name []byte, value []byte, op wire.OutPoint, amt int64, id node.ClaimID) error
+	SpendSupport(name []byte, op wire.OutPoint, id node.ClaimID) error
+	AppendBlock() error
+	ResetHeight(height int32) error
+	ReportHash(height int32, hash chainhash.Hash) error
+}
+
+// Interface is the ClaimTrie, as the RPC server, and the other users of it, see it, for testing them against
+// a mock, such as the one of claimtriemock, without the repos.
+type Interface interface {
+	Queries
+	Mutations
+
+	SetBatching(enabled bool) error
+	Flush() error
+	Close() error
+}
+
+var _ Interface = (*ClaimTrie)(nil)
@@ -0,0 +1,66 @@
+package claimtrie
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/activity"
+)
+
+// NameLifecycle is a period of a name being claimed, from the height it got a claim, until the one all of its
+// claims were spent, or expired, at, if it's been abandoned since.
+type NameLifecycle struct {
+	ClaimedAt   int32
+	AbandonedAt int32 // 0, while it's still claimed.
+}
+
+// NameLifecycles returns the periods the name has been claimed, in order, such as for showing that it was
+// previously owned. The name is normalized as of the heights, and the lifecycles are recorded as the blocks
+// are appended with the name activity indexed.
+func (ct *ClaimTrie) NameLifecycles(name []byte) ([]NameLifecycle, error) {
+
+	if ct.activityRepo == nil {
+		return nil, fmt.Errorf("name activity isn't indexed")
+	}
+
+	transitions, err := ct.activityRepo.Transitions(name)
+	if err != nil {
+		return nil, fmt.Errorf("name activity repo transitions: %w", err)
+	}
+
+	var lifecycles []NameLifecycle
+	for _, t := range transitions {
+		if t.Claimed {
+			lifecycles = append(lifecycles, NameLifecycle{ClaimedAt: t.Height})
+		} else if len(lifecycles) > 0 {
+			lifecycles[len(lifecycles)-1].AbandonedAt = t.Height
+		}
+	}
+
+	return lifecycles, nil
+}
+
+// NameLifecycleCounts returns the aggregate counts of the names abandoned, and claimed again, for research
+// into the churn of the names.
+func (ct *ClaimTrie) NameLifecycleCounts() (activity.Counts, error) {
+
+	if ct.activityRepo == nil {
+		return activity.Counts{}, fmt.Errorf("name activity isn't indexed")
+	}
+
+	return ct.activityRepo.Counts()
+}
+
+// noticeClaimed records the names updated by the block becoming claimed, or unclaimed.
+func (ct *ClaimTrie) noticeClaimed(names [][]byte) error {
+
+	claimed := make([]bool, len(names))
+	for i, name := range names {
+		n, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return fmt.Errorf("node %q: %w", name, err)
+		}
+		claimed[i] = n != nil && len(n.Claims) > 0
+	}
+
+	return ct.activityRepo.SetClaimedAt(names, claimed, ct.height)
+}
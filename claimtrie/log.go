@@ -1,6 +1,11 @@
 package claimtrie
 
 import (
+	"github.com/btcsuite/btcd/claimtrie/encryptedrepo"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/upstream"
+	"github.com/btcsuite/btcd/claimtrie/webhook"
 	"github.com/btcsuite/btclog"
 )
 
@@ -26,3 +31,23 @@ func DisableLog() {
 func UseLogger(logger btclog.Logger) {
 	log = logger
 }
+
+// Subsystems maps the tags of the subsystems of the ClaimTrie to the functions setting their loggers.
+var Subsystems = map[string]func(btclog.Logger){
+	"CLMT": UseLogger,
+	"NODE": node.UseLogger,
+	"TRIE": merkletrierepo.UseLogger,
+	"UPST": upstream.UseLogger,
+	"HOOK": webhook.UseLogger,
+	"CRPT": encryptedrepo.UseLogger,
+}
+
+// UseBackend sets the loggers of all the subsystems to the ones of the backend, tagged by
+// their subsystems, at the level.
+func UseBackend(backend *btclog.Backend, level btclog.Level) {
+	for subsystem, useLogger := range Subsystems {
+		logger := backend.Logger(subsystem)
+		logger.SetLevel(level)
+		useLogger(logger)
+	}
+}
@@ -0,0 +1,31 @@
+package claimtrie
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// enforceMemoryBudget drops the caches of the trie and the node manager, once
+// their combined estimated size exceeds the budget.
+// Both are safe to drop between blocks: the trie is persisted as it's hashed, and
+// the nodes are rebuilt from the changes in the node repo, as they're needed again.
+func (ct *ClaimTrie) enforceMemoryBudget(root *chainhash.Hash) {
+
+	trie := ct.merkleTrie.CacheSize()
+	nodes := ct.nodeManager.CacheSize()
+	if trie+nodes <= ct.memoryBudget {
+		return
+	}
+
+	// The trie is resolved again only along the paths of the updated names,
+	// so drop it first, unless the nodes take most of the budget.
+	if trie > ct.memoryBudget/2 || nodes <= ct.memoryBudget/2 {
+		ct.merkleTrie.SetRoot(root)
+		log.Debugf("Memory budget: dropped %d bytes of the trie at height %d", trie, ct.height)
+		trie = 0
+	}
+
+	if trie+nodes > ct.memoryBudget {
+		evicted := ct.nodeManager.ShrinkCache(ct.memoryBudget - trie)
+		log.Debugf("Memory budget: dropped %d nodes at height %d", evicted, ct.height)
+	}
+}
@@ -0,0 +1,8 @@
+package merkletrie
+
+import "github.com/btcsuite/btcd/claimtrie/proof"
+
+type (
+	BundledProof = proof.Bundled
+	ProofBundle  = proof.Bundle
+)
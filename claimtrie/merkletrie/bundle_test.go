@@ -0,0 +1,62 @@
+package merkletrie
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProofBundle(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{"a": outPoint(1), "abcdef": outPoint(2), "b": outPoint(3)}
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	trie := New(store, repo)
+	defer trie.Close()
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+	first := *trie.MerkleHash()
+
+	store["b"] = outPoint(4)
+	trie.Update([]byte("b"), false)
+	second := *trie.MerkleHash()
+
+	bundle := &ProofBundle{Name: []byte("abcdef")}
+	for i, root := range []chainhash.Hash{second, first} {
+		p, err := trie.At(&root).Prove([]byte("abcdef"), outPoint(2), 1)
+		r.NoError(err)
+		bundle.Proofs = append(bundle.Proofs, BundledProof{Height: int32(2 - i), Root: root, Proof: p})
+	}
+
+	height, ok := bundle.Verify(&first)
+	r.True(ok)
+	r.Equal(int32(1), height)
+	height, ok = bundle.Verify(&second)
+	r.True(ok)
+	r.Equal(int32(2), height)
+	_, ok = bundle.Verify(store.Hash([]byte("a")))
+	r.False(ok)
+
+	b := bytes.NewBuffer(nil)
+	r.NoError(bundle.Encode(b))
+	size := b.Len()
+	decoded := &ProofBundle{}
+	r.NoError(decoded.Decode(b))
+	r.Equal(bundle, decoded)
+
+	// Only the root node differs between the proofs; the others are shared.
+	separate := 0
+	for _, p := range bundle.Proofs {
+		b.Reset()
+		r.NoError(p.Proof.Encode(b))
+		separate += b.Len() + 4 + 32 // with the height and root
+	}
+	r.Less(size, separate)
+}
@@ -0,0 +1,64 @@
+package merkletrie
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// CheckResult is the outcome of checking a trie against its store.
+type CheckResult struct {
+	Names      int      // The names having values in the trie.
+	Missing    [][]byte // The keys of the vertices linked from their parents, but missing from the repo.
+	Corrupt    [][]byte // The keys of the vertices, of which the stored nodes failed their checksums.
+	Mismatched [][]byte // The names, of which the value hashes differ from the ones of the store.
+}
+
+// Check walks the trie read from the repo at its root, calls f with each name having a value,
+// and compares the value hashes with the ones of the store, computed as MerkleHashAllClaims
+// does, if allClaims is set. The slice passed to f is only valid until it returns.
+// The trie should be a view returned by At.
+func (t *MerkleTrie) Check(allClaims bool, f func(name []byte)) *CheckResult {
+
+	res := &CheckResult{}
+	if t.root.merkleHash == nil || *t.root.merkleHash == *EmptyTrieHash {
+		return res
+	}
+
+	t.check(make([]byte, 0, 256), t.root, allClaims, f, res)
+	res.Corrupt = t.Corrupted()
+
+	return res
+}
+
+func (t *MerkleTrie) check(prefix []byte, v *vertex, allClaims bool, f func(name []byte), res *CheckResult) {
+
+	if !t.resolveChildLinks(v, prefix) {
+		res.Missing = append(res.Missing, append([]byte(nil), prefix...))
+		return
+	}
+	// The walked vertices aren't needed again.
+	defer func() { v.childLinks = map[byte]*vertex{} }()
+
+	if v.hasValue {
+		res.Names++
+		name := prefix[:len(prefix):len(prefix)] // appending to it doesn't write into the keys of the children
+		f(name)
+		if expected := t.valueHash(name, allClaims); expected == nil || *expected != *v.claimsHash {
+			res.Mismatched = append(res.Mismatched, append([]byte(nil), prefix...))
+		}
+	}
+
+	for _, ch := range keysInOrder(v) {
+		t.check(append(prefix, ch), v.childLinks[ch], allClaims, f, res)
+	}
+}
+
+func (t *MerkleTrie) valueHash(name []byte, allClaims bool) *chainhash.Hash {
+	if !allClaims {
+		return t.store.Hash(name)
+	}
+	hashes := t.store.ClaimHashes(name)
+	if len(hashes) == 0 {
+		return nil
+	}
+	return computeMerkleRoot(hashes)
+}
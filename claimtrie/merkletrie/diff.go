@@ -0,0 +1,108 @@
+package merkletrie
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// NameDiff is a name, of which the value hashes differ between two tries; a hash is nil where it has no value.
+type NameDiff struct {
+	Name []byte
+	A, B *chainhash.Hash
+}
+
+// Diff walks the tries at their roots side by side, descending only into the subtries of which the hashes differ,
+// and returns the names, of which the value hashes differ, in order. Both should be views returned by At.
+func Diff(a, b *MerkleTrie) ([]NameDiff, error) {
+
+	var diffs []NameDiff
+	err := diff(a, b, make([]byte, 0, 256), rootVertex(a), rootVertex(b), &diffs)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range []*MerkleTrie{a, b} {
+		if corrupt := t.Corrupted(); len(corrupt) > 0 {
+			return nil, fmt.Errorf("the node at %q fails its checksum", corrupt[0])
+		}
+	}
+
+	return diffs, nil
+}
+
+// rootVertex returns the root of the trie, or nil if it's empty.
+func rootVertex(t *MerkleTrie) *vertex {
+	if t.root.merkleHash == nil || *t.root.merkleHash == *EmptyTrieHash {
+		return nil
+	}
+	return t.root
+}
+
+func diff(a, b *MerkleTrie, prefix []byte, va, vb *vertex, diffs *[]NameDiff) error {
+
+	if va != nil && vb != nil && *va.merkleHash == *vb.merkleHash {
+		return nil
+	}
+	if va != nil && !a.resolveChildLinks(va, prefix) {
+		return fmt.Errorf("%w: %q of %s", ErrNodeNotFound, prefix, a.root.merkleHash)
+	}
+	if vb != nil && !b.resolveChildLinks(vb, prefix) {
+		return fmt.Errorf("%w: %q of %s", ErrNodeNotFound, prefix, b.root.merkleHash)
+	}
+	// The walked vertices aren't needed again.
+	defer func() {
+		for _, v := range []*vertex{va, vb} {
+			if v != nil {
+				v.childLinks = map[byte]*vertex{}
+			}
+		}
+	}()
+
+	ha, hb := valueOf(va), valueOf(vb)
+	if (ha == nil) != (hb == nil) || (ha != nil && *ha != *hb) {
+		*diffs = append(*diffs, NameDiff{Name: append([]byte(nil), prefix...), A: ha, B: hb})
+	}
+
+	var keys []byte
+	if va != nil {
+		keys = keysInOrder(va)
+	}
+	if vb != nil {
+		for _, ch := range keysInOrder(vb) {
+			if va == nil || va.childLinks[ch] == nil {
+				keys = append(keys, ch)
+			}
+		}
+		sortBytes(keys)
+	}
+	for _, ch := range keys {
+		err := diff(a, b, append(prefix, ch), childOf(va, ch), childOf(vb, ch), diffs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func valueOf(v *vertex) *chainhash.Hash {
+	if v == nil || !v.hasValue {
+		return nil
+	}
+	return v.claimsHash
+}
+
+func childOf(v *vertex, ch byte) *vertex {
+	if v == nil {
+		return nil
+	}
+	return v.childLinks[ch]
+}
+
+func sortBytes(keys []byte) {
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j] < keys[j-1]; j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package merkletrie
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+	"github.com/btcsuite/btcd/claimtrie/node"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{"a": outPoint(1), "ab": outPoint(2), "abc": outPoint(3), "b": outPoint(4)}
+	trie := New(store, merkletrierepo.NewMemory())
+	defer trie.Close()
+	for name := range store {
+		trie.Update([]byte(name), true)
+	}
+	before := trie.MerkleHash()
+
+	store["ab"] = outPoint(5)
+	store["c"] = outPoint(6)
+	delete(store, "b")
+	for _, name := range []string{"ab", "b", "c"} {
+		trie.Update([]byte(name), true)
+	}
+	after := trie.MerkleHash()
+
+	diffs, err := Diff(trie.At(before), trie.At(after))
+	r.NoError(err)
+	r.Len(diffs, 3)
+	r.Equal([]byte("ab"), diffs[0].Name)
+	r.Equal(node.CalculateNodeHash(outPoint(2), 1), diffs[0].A)
+	r.Equal(node.CalculateNodeHash(outPoint(5), 1), diffs[0].B)
+	r.Equal([]byte("b"), diffs[1].Name)
+	r.Nil(diffs[1].B)
+	r.Equal([]byte("c"), diffs[2].Name)
+	r.Nil(diffs[2].A)
+
+	diffs, err = Diff(trie.At(after), trie.At(after))
+	r.NoError(err)
+	r.Empty(diffs)
+
+	// Everything differs from the empty trie.
+	diffs, err = Diff(trie.At(EmptyTrieHash), trie.At(after))
+	r.NoError(err)
+	r.Len(diffs, 4)
+}
@@ -0,0 +1,59 @@
+package merkletrie_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// This example demonstrates proving the best claim of a name against the Merkle
+// Hash of the trie, and verifying the proof.
+func ExampleMerkleTrie_Prove() {
+	dir, err := os.MkdirTemp("", "merkletrie")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	repo, err := merkletrierepo.NewPebble(dir)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// The store provides the hashes of the best claims of the names, which are
+	// normally provided by the node manager.
+	tx := chainhash.HashH([]byte("tx"))
+	best := wire.OutPoint{Hash: tx, Index: 1}
+	store := hashStore{
+		"a":  *node.CalculateNodeHash(wire.OutPoint{Hash: tx, Index: 0}, 3),
+		"ab": *node.CalculateNodeHash(best, 5),
+	}
+
+	trie := merkletrie.New(store, repo)
+	defer trie.Close()
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+	root := trie.MerkleHash()
+
+	proof, err := trie.Prove([]byte("ab"), best, 5)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("nodes:", len(proof.Nodes))
+	fmt.Println("verified:", proof.Verify(root, []byte("ab")))
+	fmt.Println("other name:", proof.Verify(root, []byte("ac")))
+
+	// Output:
+	// nodes: 3
+	// verified: true
+	// other name: false
+}
@@ -0,0 +1,100 @@
+package merkletrie_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/faultyrepo"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+
+	"github.com/stretchr/testify/require"
+)
+
+type hashStore map[string]chainhash.Hash
+
+func (s hashStore) ClaimHashes(name []byte) []*chainhash.Hash {
+	if h := s.Hash(name); h != nil {
+		return []*chainhash.Hash{h}
+	}
+	return nil
+}
+
+func (s hashStore) Hash(name []byte) *chainhash.Hash {
+	h, ok := s[string(name)]
+	if !ok {
+		return nil
+	}
+	return &h
+}
+
+func TestFaultyRepo(t *testing.T) {
+
+	r := require.New(t)
+
+	store := hashStore{}
+	for _, name := range []string{"a", "ab", "abc", "b"} {
+		store[name] = chainhash.HashH([]byte(name))
+	}
+
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	defer repo.Close()
+
+	trie := merkletrie.New(store, repo)
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+	root := trie.MerkleHash()
+	r.NoError(trie.Commit())
+
+	// Slow reads while resolving the persisted trie don't change its hash.
+	in := faultyrepo.NewInjector().DelayAt(1, 10*time.Millisecond)
+	trie = merkletrie.New(store, faultyrepo.MerkleTrie(repo, in))
+	trie.SetRoot(root)
+	trie.Update([]byte("ab"), true)
+	r.Equal(root.String(), trie.MerkleHash().String())
+	r.Greater(in.Ops(), 1)
+
+	// A failed read can't be told apart from a corrupt trie.
+	in = faultyrepo.NewInjector().FailAt(1, nil)
+	trie = merkletrie.New(store, faultyrepo.MerkleTrie(repo, in))
+	trie.SetRoot(root)
+	r.Panics(func() { trie.Update([]byte("ab"), true) })
+}
+
+func TestCorruptNode(t *testing.T) {
+
+	r := require.New(t)
+
+	store := hashStore{}
+	for _, name := range []string{"a", "ab", "abc", "b"} {
+		store[name] = chainhash.HashH([]byte(name))
+	}
+
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	defer repo.Close()
+
+	trie := merkletrie.New(store, repo)
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+	root := trie.MerkleHash()
+	r.NoError(trie.Commit())
+
+	// The node at "a" is resolved second, and fails its checksum.
+	in := faultyrepo.NewInjector().CorruptAt(2)
+	trie = merkletrie.New(store, faultyrepo.MerkleTrie(repo, in))
+	trie.SetRoot(root)
+	trie.Update([]byte("abc"), true)
+	r.Equal([][]byte{[]byte("a")}, trie.Corrupted())
+	r.Empty(trie.Corrupted())
+
+	// The subtrie of the corrupt node is rebuilt from the names under it.
+	for _, name := range []string{"a", "ab", "abc"} {
+		trie.Update([]byte(name), false)
+	}
+	r.Equal(root.String(), trie.MerkleHash().String())
+}
@@ -1,15 +1,12 @@
 package merkletrie
 
-import "github.com/btcsuite/btcd/chaincfg/chainhash"
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/proof"
+)
 
 func hashMerkleBranches(left *chainhash.Hash, right *chainhash.Hash) *chainhash.Hash {
-	// Concatenate the left and right nodes.
-	var hash [chainhash.HashSize * 2]byte
-	copy(hash[:chainhash.HashSize], left[:])
-	copy(hash[chainhash.HashSize:], right[:])
-
-	newHash := chainhash.DoubleHashH(hash[:])
-	return &newHash
+	return proof.HashMerkleBranches(left, right)
 }
 
 func computeMerkleRoot(hashes []*chainhash.Hash) *chainhash.Hash {
@@ -27,3 +24,9 @@ func computeMerkleRoot(hashes []*chainhash.Hash) *chainhash.Hash {
 	}
 	return hashes[0]
 }
+
+// ClaimsHash returns the commitment of the trie to the claim hashes of a name, after the all-claims fork,
+// or nil without any.
+func ClaimsHash(hashes []*chainhash.Hash) *chainhash.Hash {
+	return computeMerkleRoot(hashes)
+}
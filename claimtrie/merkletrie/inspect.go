@@ -0,0 +1,148 @@
+package merkletrie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/cockroachdb/pebble"
+)
+
+// ErrNodeNotFound is returned when no stored node has the hash.
+var ErrNodeNotFound = errors.New("trie node not found")
+
+// RawNode is a stored node of the trie, decoded for inspection.
+type RawNode struct {
+	Key      []byte // The path of the vertex from the root.
+	Hash     chainhash.Hash
+	Children []RawChild      // In the order of their bytes.
+	Value    *chainhash.Hash // The claims hash, if the vertex has a value.
+	Checksum bool            // Unset for the nodes written before the checksums were introduced.
+}
+
+// RawChild is a link from a stored node to the one of its child.
+type RawChild struct {
+	Ch   byte
+	Hash chainhash.Hash
+}
+
+// NodeAt returns the stored node of the vertex at the key, which hashes to h.
+// The nodes are stored by both, as the same subtrie may appear at different keys.
+func (t *MerkleTrie) NodeAt(key []byte, h *chainhash.Hash) (*RawNode, error) {
+
+	k := make([]byte, 0, len(key)+chainhash.HashSize)
+	k = append(append(k, key...), h[:]...)
+	b, closer, err := t.repo.Get(k)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s at %q", ErrNodeNotFound, h, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("trie repo get: %w", err)
+	}
+	defer closer.Close()
+
+	nb, ok := nbuf(b).verify()
+	if !ok {
+		return nil, &CorruptNodeError{Key: append([]byte(nil), key...)}
+	}
+
+	n := &RawNode{Key: append([]byte(nil), key...), Hash: *h, Checksum: len(nb) != len(b)}
+	for i := 0; i < nb.entries(); i++ {
+		ch, childHash := nb.entry(i)
+		n.Children = append(n.Children, RawChild{Ch: ch, Hash: *childHash})
+	}
+	if ok, v := nb.hasValue(); ok {
+		n.Value = v
+	}
+
+	return n, nil
+}
+
+// NodeByHash returns the stored node, which hashes to h, searching the trie read from
+// the repo at its root for its key. The trie should be a view returned by At.
+func (t *MerkleTrie) NodeByHash(h *chainhash.Hash) (*RawNode, error) {
+
+	if t.root.merkleHash == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, h)
+	}
+
+	var search func(key []byte, hash *chainhash.Hash) (*RawNode, error)
+	search = func(key []byte, hash *chainhash.Hash) (*RawNode, error) {
+		n, err := t.NodeAt(key, hash)
+		if err != nil {
+			return nil, err
+		}
+		if n.Hash == *h {
+			return n, nil
+		}
+		for _, c := range n.Children {
+			found, err := search(append(key, c.Ch), &c.Hash)
+			if errors.Is(err, ErrNodeNotFound) {
+				continue
+			}
+			if err != nil || found != nil {
+				return found, err
+			}
+		}
+		return nil, nil
+	}
+
+	n, err := search(make([]byte, 0, 256), t.root.merkleHash)
+	if err == nil && n == nil {
+		err = fmt.Errorf("%w: %s under %s", ErrNodeNotFound, h, t.root.merkleHash)
+	}
+
+	return n, err
+}
+
+// Walk calls fn with the stored nodes of the vertices at and under the prefix, down to depth levels
+// below it, in pre-order, as read from the repo at the root. The trie should be a view returned by At.
+func (t *MerkleTrie) Walk(prefix []byte, depth int, fn func(n *RawNode) error) error {
+
+	if t.root.merkleHash == nil {
+		return fmt.Errorf("%w: no root", ErrNodeNotFound)
+	}
+
+	key := make([]byte, 0, len(prefix)+depth)
+	n, err := t.NodeAt(key, t.root.merkleHash)
+	if err != nil {
+		return err
+	}
+	for _, ch := range prefix {
+		var next *chainhash.Hash
+		for i := range n.Children {
+			if n.Children[i].Ch == ch {
+				next = &n.Children[i].Hash
+			}
+		}
+		if next == nil {
+			return fmt.Errorf("%w: no vertex at %q", ErrNodeNotFound, prefix)
+		}
+		key = append(key, ch)
+		n, err = t.NodeAt(key, next)
+		if err != nil {
+			return err
+		}
+	}
+
+	var walk func(n *RawNode, depth int) error
+	walk = func(n *RawNode, depth int) error {
+		err := fn(n)
+		if err != nil || depth == 0 {
+			return err
+		}
+		for _, c := range n.Children {
+			child, err := t.NodeAt(append(n.Key, c.Ch), &c.Hash)
+			if err != nil {
+				return err
+			}
+			err = walk(child, depth-1)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return walk(n, depth)
+}
@@ -0,0 +1,94 @@
+package merkletrie
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+	"github.com/btcsuite/btcd/claimtrie/node"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeByHash(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{"a": outPoint(1), "ab": outPoint(2), "abc": outPoint(3), "b": outPoint(4)}
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	trie := New(store, repo)
+	defer trie.Close()
+	for name := range store {
+		trie.Update([]byte(name), true)
+	}
+	root := trie.MerkleHash()
+	view := trie.At(root)
+
+	n, err := view.NodeByHash(root)
+	r.NoError(err)
+	r.Empty(n.Key)
+	r.Nil(n.Value)
+	r.True(n.Checksum)
+	r.Len(n.Children, 2)
+	r.Equal(byte('a'), n.Children[0].Ch)
+	r.Equal(byte('b'), n.Children[1].Ch)
+
+	// The vertex at "ab" is found under the one at "a".
+	a, err := view.NodeAt([]byte("a"), &n.Children[0].Hash)
+	r.NoError(err)
+	r.Equal(node.CalculateNodeHash(outPoint(1), 1), a.Value)
+	r.Len(a.Children, 1)
+	ab, err := view.NodeByHash(&a.Children[0].Hash)
+	r.NoError(err)
+	r.Equal([]byte("ab"), ab.Key)
+	r.Equal(node.CalculateNodeHash(outPoint(2), 1), ab.Value)
+
+	_, err = view.NodeByHash(&chainhash.Hash{1, 2, 3})
+	r.ErrorIs(err, ErrNodeNotFound)
+	_, err = view.NodeAt([]byte("b"), root)
+	r.ErrorIs(err, ErrNodeNotFound)
+}
+
+func TestWalk(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{"a": outPoint(1), "ab": outPoint(2), "abc": outPoint(3), "abd": outPoint(4), "b": outPoint(5)}
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	trie := New(store, repo)
+	defer trie.Close()
+	for name := range store {
+		trie.Update([]byte(name), true)
+	}
+	view := trie.At(trie.MerkleHash())
+
+	walked := func(prefix string, depth int) []string {
+		var keys []string
+		err := view.Walk([]byte(prefix), depth, func(n *RawNode) error {
+			keys = append(keys, string(n.Key))
+			return nil
+		})
+		r.NoError(err)
+		return keys
+	}
+
+	r.Equal([]string{"", "a", "ab", "abc", "abd", "b"}, walked("", 10))
+	r.Equal([]string{"", "a", "b"}, walked("", 1))
+	r.Equal([]string{"ab", "abc", "abd"}, walked("ab", 1))
+	r.Equal([]string{"abd"}, walked("abd", 0))
+
+	var values int
+	err = view.Walk([]byte("a"), 2, func(n *RawNode) error {
+		if n.Value != nil {
+			values++
+		}
+		return nil
+	})
+	r.NoError(err)
+	r.Equal(4, values)
+
+	err = view.Walk([]byte("ac"), 1, func(n *RawNode) error { return nil })
+	r.ErrorIs(err, ErrNodeNotFound)
+}
@@ -3,17 +3,19 @@ package merkletrie
 import (
 	"bytes"
 	"fmt"
-	"sort"
+	"io"
 	"sync"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/progress"
+	"github.com/btcsuite/btcd/claimtrie/proof"
 	"github.com/cockroachdb/pebble"
 )
 
 var (
 	// EmptyTrieHash represents the Merkle Hash of an empty MerkleTrie.
 	// "0000000000000000000000000000000000000000000000000000000000000001"
-	EmptyTrieHash  = &chainhash.Hash{1}
+	EmptyTrieHash  = proof.EmptyTrieHash
 	NoChildrenHash = &chainhash.Hash{2}
 	NoClaimsHash   = &chainhash.Hash{3}
 )
@@ -29,16 +31,57 @@ type MerkleTrie struct {
 	store ValueStore
 	repo  Repo
 
+	// The nodes written by the hashing, which are buffered in repo until Commit.
+	writes *nodeWrites
+
 	root *vertex
 	bufs *sync.Pool
+	key  keyBuf // Reused by the hashing.
+
+	// Number of the vertices created since the root was set, which bounds the
+	// number of the ones still in memory.
+	vertices int64
+
+	// Keys of the vertices, of which the stored nodes failed the checksums.
+	corrupt [][]byte
+
+	// The progress of hashing the entire trie is reported to onProgress, as counted by meter meanwhile.
+	onProgress progress.Func
+	meter      *progress.Meter
+
+	// The nodes committed by the last block are read ahead of the next one, if it's set.
+	prefetch *prefetcher
+
+	// The stored nodes resolved are cached across SetRoot, if it's set.
+	nodes *nodeCache
+}
+
+// CorruptNodeError is returned for a stored node failing its checksum, which
+// is rebuilt by updating all the names under the key without restoring the children.
+type CorruptNodeError struct {
+	Key []byte
+}
+
+func (e *CorruptNodeError) Error() string {
+	return fmt.Sprintf("corrupt trie node: %q", e.Key)
+}
+
+// Corrupted returns the keys of the vertices, which failed to resolve for the
+// checksums of their stored nodes since the last call, and were left without children.
+func (t *MerkleTrie) Corrupted() [][]byte {
+	keys := t.corrupt
+	t.corrupt = nil
+	return keys
 }
 
 // New returns a MerkleTrie.
 func New(store ValueStore, repo Repo) *MerkleTrie {
 
+	writes := newNodeWrites(repo)
 	tr := &MerkleTrie{
-		store: store,
-		repo:  repo,
+		store:  store,
+		repo:   writes,
+		writes: writes,
 		bufs: &sync.Pool{
 			New: func() interface{} {
 				return new(bytes.Buffer)
@@ -51,8 +94,22 @@ func New(store ValueStore, repo Repo) *MerkleTrie {
 }
 
 // SetRoot drops all resolved nodes in the MerkleTrie, and set the root with specified hash.
+// The stored nodes cached, if SetNodeCache is enabled, are kept.
 func (t *MerkleTrie) SetRoot(h *chainhash.Hash) {
 	t.root = newVertex(h)
+	t.vertices = 0
+}
+
+// Preview returns a view of the trie at the root, which hashes the names updated on it with the values of the
+// store, such as for previewing the root of the next block. The nodes written are buffered with the ones of the
+// trie, until its Commit. The view must not be committed, or closed.
+func (t *MerkleTrie) Preview(root *chainhash.Hash, store ValueStore) *MerkleTrie {
+	return &MerkleTrie{store: store, repo: t.repo, bufs: t.bufs, root: newVertex(root), nodes: t.nodes}
+}
+
+// CacheSize returns the estimated upper bound, in bytes, of the resolved nodes in memory.
+func (t *MerkleTrie) CacheSize() int64 {
+	return t.vertices * vertexSize
 }
 
 // Update updates the nodes along the path to the key.
@@ -66,6 +123,7 @@ func (t *MerkleTrie) Update(name []byte, restoreChildren bool) {
 		}
 		if n.childLinks[ch] == nil {
 			n.childLinks[ch] = newVertex(nil)
+			t.vertices++
 		}
 		n.merkleHash = nil
 		n = n.childLinks[ch]
@@ -79,11 +137,11 @@ func (t *MerkleTrie) Update(name []byte, restoreChildren bool) {
 	n.claimsHash = nil
 }
 
-// resolveChildLinks updates the links on n
-func (t *MerkleTrie) resolveChildLinks(n *vertex, key []byte) {
+// resolveChildLinks updates the links on n, and returns false if its stored node is missing.
+func (t *MerkleTrie) resolveChildLinks(n *vertex, key []byte) bool {
 
 	if n.merkleHash == nil {
-		return
+		return true
 	}
 
 	b := t.bufs.Get().(*bytes.Buffer)
@@ -92,35 +150,90 @@ func (t *MerkleTrie) resolveChildLinks(n *vertex, key []byte) {
 	b.Write(key)
 	b.Write(n.merkleHash[:])
 
-	result, closer, err := t.repo.Get(b.Bytes())
-	if err == pebble.ErrNotFound { // TODO: leaky abstraction
-		return
-	} else if err != nil {
-		panic(err)
+	result, cached := t.nodes.get(b.Bytes())
+	if !cached {
+		var ok bool
+		result, ok = t.prefetch.get(b.Bytes())
+		if !ok {
+			var closer io.Closer
+			var err error
+			result, closer, err = t.repo.Get(b.Bytes())
+			if err == pebble.ErrNotFound { // TODO: leaky abstraction
+				return false
+			} else if err != nil {
+				panic(err)
+			}
+			defer closer.Close()
+		}
 	}
-	defer closer.Close()
 
-	nb := nbuf(result)
+	nb, ok := nbuf(result).verify()
+	if !ok {
+		t.corrupt = append(t.corrupt, append([]byte(nil), key...))
+		return true
+	}
+	if !cached {
+		t.nodes.add(b.Bytes(), result)
+	}
 	n.hasValue, n.claimsHash = nb.hasValue()
 	for i := 0; i < nb.entries(); i++ {
 		p, h := nb.entry(i)
 		n.childLinks[p] = newVertex(h)
 	}
+	t.vertices += int64(nb.entries())
+	return true
 }
 
 // MerkleHash returns the Merkle Hash of the MerkleTrie.
 // All nodes must have been resolved before calling this function.
+// The nodes written are kept in memory, and read through, until Commit.
 func (t *MerkleTrie) MerkleHash() *chainhash.Hash {
-	buf := make([]byte, 0, 256)
-	if h := t.merkle(buf, t.root); h == nil {
+	t.key.reset()
+	if h := t.merkle(&t.key, t.root); h == nil {
 		return EmptyTrieHash
 	}
 	return t.root.merkleHash
 }
 
-// merkle recursively resolves the hashes of the node.
+// keyBuf is the key of the vertex being hashed, which is extended in place for each of its
+// children, and truncated back after, so the keys of the siblings never alias each other,
+// and the hashing only allocates to grow it past the longest key so far.
+type keyBuf struct {
+	b []byte
+}
+
+func (k *keyBuf) reset() {
+	if k.b == nil {
+		k.b = make([]byte, 0, 256)
+	}
+	k.b = k.b[:0]
+}
+
+func (k *keyBuf) push(ch byte) {
+	k.b = append(k.b, ch)
+}
+
+func (k *keyBuf) pop() {
+	k.b = k.b[:len(k.b)-1]
+}
+
+// prefix returns the key of the vertex, which is only valid until the buffer is extended.
+func (k *keyBuf) prefix() []byte {
+	return k.b[:len(k.b):len(k.b)]
+}
+
+// node returns the key of the stored node of the vertex, its prefix followed by the hash,
+// which is only valid until the buffer is extended.
+func (k *keyBuf) node(h *chainhash.Hash) []byte {
+	n := len(k.b)
+	key := append(k.b, h[:]...)
+	k.b = key[:n]
+	return key
+}
+
+// merkle recursively resolves the hashes of the node at the key.
 // All nodes must have been resolved before calling this function.
-func (t *MerkleTrie) merkle(prefix []byte, v *vertex) *chainhash.Hash {
+func (t *MerkleTrie) merkle(key *keyBuf, v *vertex) *chainhash.Hash {
 	if v.merkleHash != nil {
 		return v.merkleHash
 	}
@@ -129,20 +242,22 @@ func (t *MerkleTrie) merkle(prefix []byte, v *vertex) *chainhash.Hash {
 	defer t.bufs.Put(b)
 	b.Reset()
 
-	keys := keysInOrder(v)
+	var buf [256]byte
+	keys := appendKeysInOrder(buf[:0], v)
 
 	for _, ch := range keys {
 		child := v.childLinks[ch]
 		if child == nil {
 			continue
 		}
-		p := append(prefix, ch)
-		h := t.merkle(p, child)
+		key.push(ch)
+		h := t.merkle(key, child)
+		key.pop()
 		if h != nil {
 			b.WriteByte(ch) // nolint : errchk
 			b.Write(h[:])   // nolint : errchk
 		}
-		if h == nil || len(prefix) > 4 { // TODO: determine the right number here
+		if h == nil || len(key.b) > 4 { // TODO: determine the right number here
 			delete(v.childLinks, ch) // keep the RAM down (they get recreated on Update)
 		}
 	}
@@ -150,8 +265,9 @@ func (t *MerkleTrie) merkle(prefix []byte, v *vertex) *chainhash.Hash {
 	if v.hasValue {
 		claimHash := v.claimsHash
 		if claimHash == nil {
-			claimHash = t.store.Hash(prefix)
+			claimHash = t.store.Hash(key.prefix())
 			v.claimsHash = claimHash
+			t.meter.AddNames(1)
 		}
 		if claimHash != nil {
 			b.Write(claimHash[:])
@@ -163,30 +279,46 @@ func (t *MerkleTrie) merkle(prefix []byte, v *vertex) *chainhash.Hash {
 	if b.Len() > 0 {
 		h := chainhash.DoubleHashH(b.Bytes())
 		v.merkleHash = &h
-		t.repo.Set(append(prefix, h[:]...), b.Bytes())
+		t.setNode(key.node(&h), b)
 	}
 
 	return v.merkleHash
 }
 
+// setNode writes the node serialized in b, trailed by its checksum.
+func (t *MerkleTrie) setNode(key []byte, b *bytes.Buffer) {
+	t.repo.Set(key, appendChecksum(b.Bytes()))
+	t.meter.AddNodes(1)
+}
+
 func keysInOrder(v *vertex) []byte {
-	keys := make([]byte, 0, len(v.childLinks))
+	return appendKeysInOrder(make([]byte, 0, len(v.childLinks)), v)
+}
+
+// appendKeysInOrder appends the sorted keys of the children to keys. It doesn't allocate,
+// if keys has the capacity, so the hashing can use a buffer on the stack.
+func appendKeysInOrder(keys []byte, v *vertex) []byte {
+	start := len(keys)
 	for key := range v.childLinks {
 		keys = append(keys, key)
 	}
-	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for i := start + 1; i < len(keys); i++ { // insertion sort; there are few children but near the root.
+		for j := i; j > start && keys[j] < keys[j-1]; j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
 	return keys
 }
 
 func (t *MerkleTrie) MerkleHashAllClaims() *chainhash.Hash {
-	buf := make([]byte, 0, 256)
-	if h := t.merkleAllClaims(buf, t.root); h == nil {
+	t.key.reset()
+	if h := t.merkleAllClaims(&t.key, t.root); h == nil {
 		return EmptyTrieHash
 	}
 	return t.root.merkleHash
 }
 
-func (t *MerkleTrie) merkleAllClaims(prefix []byte, v *vertex) *chainhash.Hash {
+func (t *MerkleTrie) merkleAllClaims(key *keyBuf, v *vertex) *chainhash.Hash {
 	if v.merkleHash != nil {
 		return v.merkleHash
 	}
@@ -194,21 +326,23 @@ func (t *MerkleTrie) merkleAllClaims(prefix []byte, v *vertex) *chainhash.Hash {
 	defer t.bufs.Put(b)
 	b.Reset()
 
-	keys := keysInOrder(v)
+	var buf [256]byte
+	keys := appendKeysInOrder(buf[:0], v)
 	childHashes := make([]*chainhash.Hash, 0, len(keys))
 	for _, ch := range keys {
 		n := v.childLinks[ch]
 		if n == nil {
 			continue
 		}
-		p := append(prefix, ch)
-		h := t.merkleAllClaims(p, n)
+		key.push(ch)
+		h := t.merkleAllClaims(key, n)
+		key.pop()
 		if h != nil {
 			childHashes = append(childHashes, h)
 			b.WriteByte(ch) // nolint : errchk
 			b.Write(h[:])   // nolint : errchk
 		}
-		if h == nil || len(prefix) > 4 { // TODO: determine the right number here
+		if h == nil || len(key.b) > 4 { // TODO: determine the right number here
 			delete(v.childLinks, ch) // keep the RAM down (they get recreated on Update)
 		}
 	}
@@ -217,7 +351,8 @@ func (t *MerkleTrie) merkleAllClaims(prefix []byte, v *vertex) *chainhash.Hash {
 	if v.hasValue {
 		claimsHash = v.claimsHash
 		if claimsHash == nil {
-			claimHashes := t.store.ClaimHashes(prefix)
+			claimHashes := t.store.ClaimHashes(key.prefix())
+			t.meter.AddNames(1)
 			if len(claimHashes) > 0 {
 				claimsHash = computeMerkleRoot(claimHashes)
 				v.claimsHash = claimsHash
@@ -240,17 +375,35 @@ func (t *MerkleTrie) merkleAllClaims(prefix []byte, v *vertex) *chainhash.Hash {
 
 		h := hashMerkleBranches(left, right)
 		v.merkleHash = h
-		t.repo.Set(append(prefix, h[:]...), b.Bytes())
+		t.setNode(key.node(h), b)
 	} else if len(childHashes) == 1 {
 		v.merkleHash = childHashes[0] // pass it up the tree
-		t.repo.Set(append(prefix, v.merkleHash[:]...), b.Bytes())
+		t.setNode(key.node(v.merkleHash), b)
 	}
 
 	return v.merkleHash
 }
 
+// Commit writes the nodes written by the hashing since the last one to the repo. Meanwhile, the ones pending
+// are read from memory, and hashing them again doesn't write them twice.
+// The keys of the nodes are hinted to prefetch at the start of the next block, if it's enabled.
+func (t *MerkleTrie) Commit() error {
+	if t.prefetch != nil {
+		t.prefetch.hint(t.writes.keys())
+	}
+	return t.writes.commit()
+}
+
+// Close commits the nodes pending, and closes the repo.
 func (t *MerkleTrie) Close() error {
-	return t.repo.Close()
+	if t.prefetch != nil {
+		t.prefetch.wait()
+	}
+	err := t.Commit()
+	if cerr := t.repo.Close(); err == nil {
+		err = cerr
+	}
+	return err
 }
 
 func (t *MerkleTrie) Dump(s string, allClaims bool) {
@@ -1,9 +1,17 @@
 package merkletrie
 
 import (
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+
+	"github.com/cockroachdb/pebble"
 
 	"github.com/stretchr/testify/require"
 )
@@ -22,3 +30,373 @@ func TestName(t *testing.T) {
 	root = computeMerkleRoot(data)
 	r.True(target.IsEqual(root))
 }
+
+// benchStore holds the value hashes of the names.
+type benchStore map[string]*chainhash.Hash
+
+func (s benchStore) ClaimHashes(name []byte) []*chainhash.Hash {
+	return []*chainhash.Hash{s[string(name)]}
+}
+
+func (s benchStore) Hash(name []byte) *chainhash.Hash {
+	return s[string(name)]
+}
+
+// nullRepo discards the nodes written, which are never resolved.
+type nullRepo struct{}
+
+func (nullRepo) Get(key []byte) ([]byte, io.Closer, error) { return nil, nil, pebble.ErrNotFound }
+func (nullRepo) Set(key, value []byte) error               { return nil }
+func (nullRepo) Close() error                              { return nil }
+
+// benchTrie returns a hashed trie of 4-byte names, which aren't pruned from memory by merkle.
+func benchTrie(names int) (*MerkleTrie, [][]byte) {
+
+	store := benchStore{}
+	keys := make([][]byte, 0, names)
+	for i := 0; i < names; i++ {
+		key := []byte(fmt.Sprintf("%04d", i))
+		h := chainhash.HashH(key)
+		store[string(key)] = &h
+		keys = append(keys, key)
+	}
+
+	trie := New(store, nullRepo{})
+	for _, key := range keys {
+		trie.Update(key, false)
+	}
+	trie.MerkleHash()
+
+	return trie, keys
+}
+
+func TestHotPathAllocations(t *testing.T) {
+
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	r := require.New(t)
+
+	trie, keys := benchTrie(1000)
+	i := 0
+	r.Zero(testing.AllocsPerRun(100, func() {
+		trie.Update(keys[i%len(keys)], false)
+		i++
+	}))
+
+	// Only the hashes of the vertices along the path are allocated.
+	r.LessOrEqual(testing.AllocsPerRun(100, func() {
+		trie.Update(keys[i%len(keys)], false)
+		trie.MerkleHash()
+		i++
+	}), float64(len(keys[0])+1))
+}
+
+// checkedStore fails the test on the values requested for the keys, which aren't names,
+// or alias the buffer of the trie.
+type checkedStore struct {
+	fakeStore
+	t *testing.T
+}
+
+func (s checkedStore) ClaimHashes(name []byte) []*chainhash.Hash {
+	s.check(name)
+	return s.fakeStore.ClaimHashes(name)
+}
+
+func (s checkedStore) Hash(name []byte) *chainhash.Hash {
+	s.check(name)
+	return s.fakeStore.Hash(name)
+}
+
+func (s checkedStore) check(name []byte) {
+	if _, ok := s.fakeStore[string(name)]; !ok {
+		s.t.Errorf("value of %q requested, which isn't a name", name)
+	}
+	// A store appending to the name must not write into the keys of the siblings.
+	if cap(name) != len(name) {
+		s.t.Errorf("name %q shares the capacity of the key buffer", name)
+	}
+}
+
+func TestMerkleKeys(t *testing.T) {
+
+	r := require.New(t)
+
+	// The siblings below the long prefix grow the key past its initial capacity,
+	// and their keys share the array of their parent's.
+	long := strings.Repeat("x", 300)
+	store := checkedStore{t: t, fakeStore: fakeStore{"a": outPoint(1), "ab": outPoint(2), "ac": outPoint(3)}}
+	for i, suffix := range []string{"", "a", "b", "ba", "bb", "c"} {
+		store.fakeStore[long+suffix] = outPoint(uint32(10 + i))
+	}
+
+	for _, allClaims := range []bool{false, true} {
+		repo, err := merkletrierepo.NewPebble(t.TempDir())
+		r.NoError(err)
+		trie := New(store, repo)
+		ram := NewRamTrie(store.fakeStore)
+		for name := range store.fakeStore {
+			trie.Update([]byte(name), false)
+			ram.Update([]byte(name), false)
+		}
+		var root, expected *chainhash.Hash
+		if allClaims {
+			root, expected = trie.MerkleHashAllClaims(), ram.MerkleHashAllClaims()
+		} else {
+			root, expected = trie.MerkleHash(), ram.MerkleHash()
+		}
+		r.Equal(expected.String(), root.String())
+
+		// The nodes are stored under the keys of their vertices.
+		res := trie.At(root).Check(allClaims, func(name []byte) {})
+		r.Equal(len(store.fakeStore), res.Names)
+		r.Empty(res.Missing)
+		r.Empty(res.Mismatched)
+		r.NoError(trie.Close())
+	}
+}
+
+func BenchmarkUpdate(b *testing.B) {
+
+	trie, keys := benchTrie(1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie.Update(keys[i%len(keys)], false)
+	}
+}
+
+func BenchmarkMerkle(b *testing.B) {
+
+	trie, keys := benchTrie(1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie.Update(keys[i%len(keys)], false)
+		trie.MerkleHash()
+	}
+}
+
+// countingRepo counts the writes of the nodes.
+type countingRepo struct {
+	Repo
+	sets int
+}
+
+func (repo *countingRepo) Set(key, value []byte) error {
+	repo.sets++
+	return repo.Repo.Set(key, value)
+}
+
+func TestCommit(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{}
+	for i := 0; i < 100; i++ {
+		store[fmt.Sprintf("name-%d", i)] = outPoint(uint32(i))
+	}
+
+	pebble, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	repo := &countingRepo{Repo: pebble}
+	trie := New(store, repo)
+	defer trie.Close()
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+
+	// Hashing the same nodes again, such as for a preview of the root, writes nothing until the commit.
+	root := trie.MerkleHash()
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+	r.Equal(root, trie.MerkleHash())
+	r.Zero(repo.sets)
+
+	// The nodes pending are read through.
+	trie.SetRoot(root)
+	trie.Update([]byte("name-1"), true)
+	r.Equal(root, trie.MerkleHash())
+	r.Zero(repo.sets)
+
+	r.NoError(trie.Commit())
+	written := repo.sets
+	r.Greater(written, 0)
+	r.NoError(trie.Commit())
+	r.Equal(written, repo.sets)
+
+	resolved := New(store, pebble)
+	resolved.SetRoot(root)
+	resolved.Update([]byte("name-2"), true)
+	r.Equal(root, resolved.MerkleHash())
+}
+
+func TestPrefetch(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{}
+	for i := 0; i < 100; i++ {
+		store[fmt.Sprintf("name-%d", i)] = outPoint(uint32(i))
+	}
+
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	trie := New(store, repo)
+	defer trie.Close()
+	trie.SetPrefetch(1)
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+	root := trie.MerkleHash()
+	r.NoError(trie.Commit())
+
+	// Only the shallowest nodes, up to the limit, are prefetched once they're dropped.
+	trie.SetRoot(root)
+	trie.Prefetch()
+	<-trie.prefetch.done
+	trie.Update([]byte("name-1"), true)
+	r.Equal(root, trie.MerkleHash())
+	stats := trie.PrefetchStats()
+	r.Equal(int64(1), stats.Prefetched)
+	r.Equal(int64(1), stats.Hits)
+	r.Greater(stats.Misses, int64(0))
+	r.NoError(trie.Commit())
+
+	// Nothing is prefetched, while the vertices are in memory.
+	r.NotEmpty(trie.prefetch.hints)
+	trie.Prefetch()
+	r.Nil(trie.prefetch.done)
+	trie.Update([]byte("name-2"), true)
+	r.Equal(stats, trie.PrefetchStats())
+}
+
+func TestNodeCache(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{}
+	for i := 0; i < 100; i++ {
+		store[fmt.Sprintf("name-%d", i)] = outPoint(uint32(i))
+	}
+
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	trie := New(store, repo)
+	defer trie.Close()
+	trie.SetNodeCache(1 << 20)
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+	root := trie.MerkleHashAllClaims()
+	r.NoError(trie.Commit())
+
+	// The nodes resolved are read from the repo once, and from the cache after SetRoot.
+	resolve := func() NodeCacheStats {
+		trie.SetRoot(root)
+		trie.Update([]byte("name-1"), true)
+		r.Equal(root, trie.MerkleHashAllClaims())
+		return trie.NodeCacheStats()
+	}
+	stats := resolve()
+	r.Greater(stats.Misses, int64(0))
+	r.Equal(int(stats.Misses), stats.Entries)
+	r.Zero(stats.Hits)
+	r.Greater(stats.Bytes, int64(0))
+	r.Equal(NodeCacheStats{Entries: stats.Entries, Bytes: stats.Bytes, Hits: stats.Misses, Misses: stats.Misses}, resolve())
+
+	// The least recently used nodes are evicted over the capacity.
+	trie.SetNodeCache(3 * nodeCacheOverhead)
+	stats = resolve()
+	r.Greater(stats.Evictions, int64(0))
+	r.LessOrEqual(stats.Bytes, int64(3*nodeCacheOverhead))
+}
+
+type batchingRepo struct {
+	*merkletrierepo.Pebble
+	batches int32
+}
+
+func (repo *batchingRepo) SetBatch(keys, values [][]byte) error {
+	atomic.AddInt32(&repo.batches, 1)
+	return repo.Pebble.SetBatch(keys, values)
+}
+
+func TestAsyncWrites(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{}
+	for i := 0; i < 100; i++ {
+		store[fmt.Sprintf("name-%d", i)] = outPoint(uint32(i))
+	}
+
+	pebble, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	repo := &batchingRepo{Pebble: pebble}
+	trie := New(store, repo)
+	defer trie.Close()
+	trie.SetAsyncWrites(1)
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+	root := trie.MerkleHash()
+
+	// The nodes being written in the background are read through.
+	trie.SetRoot(root)
+	trie.Update([]byte("name-1"), true)
+	r.Equal(root, trie.MerkleHash())
+
+	r.NoError(trie.Commit())
+	r.Greater(atomic.LoadInt32(&repo.batches), int32(1))
+
+	resolved := New(store, pebble)
+	resolved.SetRoot(root)
+	resolved.Update([]byte("name-2"), true)
+	r.Equal(root, resolved.MerkleHash())
+}
+
+func TestRepoTimer(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{}
+	for i := 0; i < 10; i++ {
+		store[fmt.Sprintf("name-%d", i)] = outPoint(uint32(i))
+	}
+
+	var reads, writes int32
+	timer := func(write bool, elapsed time.Duration) {
+		if write {
+			atomic.AddInt32(&writes, 1)
+		} else {
+			atomic.AddInt32(&reads, 1)
+		}
+	}
+
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	trie := New(store, repo)
+	defer trie.Close()
+	trie.SetRepoTimer(timer)
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+	root := trie.MerkleHash()
+	r.NoError(trie.Commit())
+	r.Equal(int32(1), writes)
+	r.Zero(reads)
+
+	// No nodes pending, no writes.
+	r.NoError(trie.Commit())
+	r.Equal(int32(1), writes)
+
+	trie.SetRoot(root)
+	trie.Update([]byte("name-1"), true)
+	r.Equal(root, trie.MerkleHash())
+	r.Greater(reads, int32(0))
+}
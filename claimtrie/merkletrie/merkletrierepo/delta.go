@@ -0,0 +1,155 @@
+package merkletrierepo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/progress"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+const deltaFileExt = ".delta"
+
+// DeltaName returns the path of the differential checkpoint at the height against the base one in dir.
+func DeltaName(dir string, base, height int32) string {
+	return filepath.Join(dir, fmt.Sprintf("%010d-%010d%s", base, height, deltaFileExt))
+}
+
+// LastCheckpoint returns the height of the latest complete full checkpoint in dir, or 0 if there's none.
+func LastCheckpoint(dir string) (int32, error) {
+
+	heights, err := listCheckpoints(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil || len(heights) == 0 {
+		return 0, err
+	}
+
+	return heights[len(heights)-1], nil
+}
+
+// DeltaCheckpoint writes a differential checkpoint into dir, which holds only the keys of the repo
+// missing from the full checkpoint at the base height, along with the root hash of the trie at the height.
+// The keys of the trie are addressed by the hashes of their values, so they're never rewritten.
+// Applied to a copy of the base checkpoint with ApplyDelta, it makes up the checkpoint at the height.
+func (repo *Pebble) DeltaCheckpoint(dir string, base, height int32, root *chainhash.Hash) error {
+
+	if repo.shared {
+		return fmt.Errorf("checkpoint of a shared repo is not supported")
+	}
+
+	meter := progress.Start("delta checkpoint", repo.onProgress)
+	defer meter.Stop()
+
+	baseDB, err := pebble.Open(filepath.Join(dir, fmt.Sprintf("%010d", base)), &pebble.Options{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("pebble open base checkpoint: %w", err)
+	}
+	defer baseDB.Close()
+
+	// Unsynced writes would be missing from the iteration.
+	err = repo.db.Flush()
+	if err != nil {
+		return fmt.Errorf("pebble flush: %w", err)
+	}
+
+	name := DeltaName(dir, base, height)
+	err = os.Remove(name + manifestFileExt) // it would list the leftover of an interrupted delta
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove stale manifest: %w", err)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("create delta: %w", err)
+	}
+	w := sstable.NewWriter(f, sstable.WriterOptions{})
+
+	err = writeDelta(w, repo.db, baseDB, meter)
+	if cerr := w.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("close delta: %w", cerr)
+	}
+	if err != nil {
+		os.Remove(name) // nolint : errchk
+		return err
+	}
+
+	// The root file is written last, so mirrors only see complete deltas.
+	err = os.WriteFile(name+rootFileExt, []byte(root.String()), 0644)
+	if err != nil {
+		return fmt.Errorf("write delta root: %w", err)
+	}
+
+	return nil
+}
+
+// writeDelta writes the keys of db missing from base into w, counting them to the meter. Both are iterated in order.
+func writeDelta(w *sstable.Writer, db, base *pebble.DB, meter *progress.Meter) error {
+
+	iter := db.NewIter(nil)
+	defer iter.Close()
+	baseIter := base.NewIter(nil)
+	defer baseIter.Close()
+
+	baseValid := baseIter.First()
+	for valid := iter.First(); valid; valid = iter.Next() {
+		for baseValid && bytes.Compare(baseIter.Key(), iter.Key()) < 0 {
+			baseValid = baseIter.Next()
+		}
+		if baseValid && bytes.Equal(baseIter.Key(), iter.Key()) {
+			continue
+		}
+		err := w.Set(iter.Key(), iter.Value())
+		if err != nil {
+			return fmt.Errorf("write delta: %w", err)
+		}
+		meter.AddNodes(1)
+	}
+
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("pebble iterate: %w", err)
+	}
+	if err := baseIter.Error(); err != nil {
+		return fmt.Errorf("pebble iterate base checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyDelta ingests the differential checkpoint at delta into the copy of its base checkpoint at path.
+func ApplyDelta(path string, delta string) error {
+
+	db, err := pebble.Open(path, &pebble.Options{})
+	if err != nil {
+		return fmt.Errorf("pebble open %s, %w", path, err)
+	}
+
+	// The ingestion consumes the file, so it's given a link, or a copy, of the delta.
+	ingested := filepath.Join(path, filepath.Base(delta))
+	err = vfs.LinkOrCopy(vfs.Default, delta, ingested)
+	if err != nil {
+		db.Close() // nolint : errchk
+		return fmt.Errorf("link delta: %w", err)
+	}
+
+	err = db.Ingest([]string{ingested})
+	os.Remove(ingested) // nolint : errchk (it's left over only if the delta was empty)
+	if err != nil {
+		db.Close() // nolint : errchk
+		return fmt.Errorf("pebble ingest %s: %w", delta, err)
+	}
+
+	err = db.Close()
+	if err != nil {
+		return fmt.Errorf("pebble close: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,349 @@
+package merkletrierepo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const manifestFileExt = ".manifest"
+
+// ErrBadManifest is returned when a checkpoint doesn't match its manifest, or the manifest isn't signed by the key.
+var ErrBadManifest = errors.New("bad checkpoint manifest")
+
+// Manifest lists the files of a full, or a differential, checkpoint, along with the root hash of the
+// trie at its height, and is signed by the operator publishing it, so it can be verified on restore.
+type Manifest struct {
+	Height int32          `json:"height"`
+	Base   int32          `json:"base,omitempty"` // The height of the full checkpoint a delta applies to.
+	Root   string         `json:"root"`
+	Files  []ManifestFile `json:"files"`
+
+	Signature string `json:"signature,omitempty"` // DER encoded, over the Digest of the manifest.
+}
+
+// ManifestFile is a file of a checkpoint, by its path relative to the checkpoint.
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// CheckpointName returns the path of the full checkpoint at the height in dir.
+func CheckpointName(dir string, height int32) string {
+	return filepath.Join(dir, fmt.Sprintf("%010d", height))
+}
+
+// Digest returns the hash signed for the manifest: the double SHA256 of its JSON, without the signature.
+func (m *Manifest) Digest() chainhash.Hash {
+
+	unsigned := *m
+	unsigned.Signature = ""
+	b, _ := json.Marshal(unsigned) // nolint : errchk (it's only strings and ints)
+
+	return chainhash.DoubleHashH(b)
+}
+
+// SignCheckpoint writes the manifest of the checkpoint at path, either the directory of a full one,
+// or the file of a delta, signed by the key, next to it. The checkpoint has to be complete.
+func SignCheckpoint(path string, height, base int32, key *btcec.PrivateKey) (*Manifest, error) {
+
+	b, err := os.ReadFile(path + rootFileExt)
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint root: %w", err)
+	}
+	root, err := chainhash.NewHashFromStr(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("parse checkpoint root: %w", err)
+	}
+
+	files, err := hashCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Height: height, Base: base, Root: root.String(), Files: files}
+	digest := m.Digest()
+	sig, err := key.Sign(digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+	m.Signature = hex.EncodeToString(sig.Serialize())
+
+	b, err = json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode manifest: %w", err)
+	}
+	err = os.WriteFile(path+manifestFileExt, b, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("write manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+// VerifyCheckpoint returns the manifest of the checkpoint at path, once its signature by the key is verified,
+// and the checkpoint holds exactly the files listed, and the root, in it.
+func VerifyCheckpoint(path string, key *btcec.PublicKey) (*Manifest, error) {
+
+	b, err := os.ReadFile(path + manifestFileExt)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	var m Manifest
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode: %s", ErrBadManifest, err)
+	}
+
+	b, err = hex.DecodeString(m.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadManifest, err)
+	}
+	sig, err := btcec.ParseDERSignature(b, btcec.S256())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadManifest, err)
+	}
+	digest := m.Digest()
+	if !sig.Verify(digest[:], key) {
+		return nil, fmt.Errorf("%w: signature", ErrBadManifest)
+	}
+
+	b, err = os.ReadFile(path + rootFileExt)
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint root: %w", err)
+	}
+	if string(b) != m.Root {
+		return nil, fmt.Errorf("%w: root %s, listed %s", ErrBadManifest, b, m.Root)
+	}
+
+	files, err := hashCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+	listed := map[string]ManifestFile{}
+	for _, f := range m.Files {
+		listed[f.Name] = f
+	}
+	for _, f := range files {
+		if listed[f.Name] != f {
+			return nil, fmt.Errorf("%w: file %s", ErrBadManifest, f.Name)
+		}
+		delete(listed, f.Name)
+	}
+	for _, f := range m.Files { // in the order listed, rather than of the map.
+		if _, ok := listed[f.Name]; ok {
+			return nil, fmt.Errorf("%w: missing file %s", ErrBadManifest, f.Name)
+		}
+	}
+
+	return &m, nil
+}
+
+// hashCheckpoint returns the files of the checkpoint at path, in order by name.
+func hashCheckpoint(path string) ([]ManifestFile, error) {
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat checkpoint: %w", err)
+	}
+	if !info.IsDir() {
+		f, err := hashFile(path, filepath.Base(path))
+		if err != nil {
+			return nil, err
+		}
+		return []ManifestFile{f}, nil
+	}
+
+	var files []ManifestFile
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		f, err := hashFile(p, filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk checkpoint: %w", err)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	return files, nil
+}
+
+func hashFile(path, name string) (ManifestFile, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ManifestFile{}, fmt.Errorf("open checkpoint file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return ManifestFile{}, fmt.Errorf("read checkpoint file: %w", err)
+	}
+
+	return ManifestFile{Name: name, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// CopyCheckpoint copies the checkpoint at path, along with its root and manifest, into dir, such as for publishing it.
+func CopyCheckpoint(path, dir string) error {
+
+	dst := filepath.Join(dir, filepath.Base(path))
+	err := copyTree(path, dst)
+	if err != nil {
+		return err
+	}
+	for _, ext := range []string{rootFileExt, manifestFileExt} {
+		err = copyFile(path+ext, dst+ext)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindCheckpoint returns the path of the complete checkpoint at the height in dir, and the height of
+// the full one it applies to, if it's a delta, or 0. The full checkpoints are preferred over the deltas.
+func FindCheckpoint(dir string, height int32) (string, int32, error) {
+
+	path := CheckpointName(dir, height)
+	if _, err := os.Stat(path + rootFileExt); err == nil {
+		return path, 0, nil
+	}
+
+	roots, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("*-%010d%s%s", height, deltaFileExt, rootFileExt)))
+	if err != nil {
+		return "", 0, fmt.Errorf("glob deltas: %w", err)
+	}
+	for _, root := range roots {
+		path = strings.TrimSuffix(root, rootFileExt)
+		base, err := strconv.ParseInt(strings.SplitN(filepath.Base(path), "-", 2)[0], 10, 32)
+		if err == nil && base > 0 {
+			return path, int32(base), nil
+		}
+	}
+
+	return "", 0, fmt.Errorf("no checkpoint at %d in %s: %w", height, dir, os.ErrNotExist)
+}
+
+// RestoreCheckpoint verifies the checkpoint at the height in dir, and the full one it applies to, if it's a delta,
+// against the key, and restores the trie repo at path from them. The path must not exist yet.
+func RestoreCheckpoint(dir string, height int32, path string, key *btcec.PublicKey) (*Manifest, error) {
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("trie repo %s exists already", path)
+	}
+
+	found, baseHeight, err := FindCheckpoint(dir, height)
+	if err != nil {
+		return nil, err
+	}
+
+	var m *Manifest
+	var delta string
+	full, fullHeight := found, height
+	if baseHeight != 0 {
+		delta, full, fullHeight = found, CheckpointName(dir, baseHeight), baseHeight
+		m, err = VerifyCheckpoint(delta, key)
+		if err != nil {
+			return nil, fmt.Errorf("delta at %d: %w", height, err)
+		}
+		if m.Height != height || m.Base != baseHeight {
+			return nil, fmt.Errorf("%w: delta of %d against %d", ErrBadManifest, m.Height, m.Base)
+		}
+	}
+
+	base, err := VerifyCheckpoint(full, key)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint at %d: %w", fullHeight, err)
+	}
+	if base.Height != fullHeight || base.Base != 0 {
+		return nil, fmt.Errorf("%w: checkpoint of %d", ErrBadManifest, base.Height)
+	}
+	if m == nil {
+		m = base
+	}
+
+	err = copyTree(full, path)
+	if err == nil && delta != "" {
+		err = ApplyDelta(path, delta)
+	}
+	if err != nil {
+		os.RemoveAll(path) // nolint : errchk
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func copyTree(src, dst string) error {
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("stat checkpoint: %w", err)
+	}
+	if !info.IsDir() {
+		return copyFile(src, dst)
+	}
+
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		return copyFile(p, filepath.Join(dst, rel))
+	})
+}
+
+func copyFile(src, dst string) error {
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", dst, err)
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("copy %s: %w", src, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package merkletrierepo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignCheckpoint(t *testing.T) {
+
+	r := require.New(t)
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	r.NoError(err)
+	other, err := btcec.NewPrivateKey(btcec.S256())
+	r.NoError(err)
+
+	dir := t.TempDir()
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer repo.Close()
+
+	r.NoError(repo.Set([]byte("a"), []byte("1")))
+	r.NoError(repo.Checkpoint(dir, 1, &chainhash.Hash{1}, 2))
+	r.NoError(repo.Set([]byte("b"), []byte("2")))
+	r.NoError(repo.DeltaCheckpoint(dir, 1, 2, &chainhash.Hash{2}))
+
+	m, err := SignCheckpoint(CheckpointName(dir, 1), 1, 0, key)
+	r.NoError(err)
+	r.Equal(chainhash.Hash{1}.String(), m.Root)
+	r.NotEmpty(m.Files)
+	_, err = SignCheckpoint(DeltaName(dir, 1, 2), 2, 1, key)
+	r.NoError(err)
+
+	_, err = VerifyCheckpoint(CheckpointName(dir, 1), key.PubKey())
+	r.NoError(err)
+	_, err = VerifyCheckpoint(CheckpointName(dir, 1), other.PubKey())
+	r.ErrorIs(err, ErrBadManifest)
+
+	// The published copies restore the repo at the delta, once verified.
+	published := t.TempDir()
+	r.NoError(CopyCheckpoint(CheckpointName(dir, 1), published))
+	r.NoError(CopyCheckpoint(DeltaName(dir, 1, 2), published))
+
+	restored := filepath.Join(t.TempDir(), "trie")
+	_, err = RestoreCheckpoint(published, 2, restored, other.PubKey())
+	r.ErrorIs(err, ErrBadManifest)
+	r.NoDirExists(restored)
+
+	m, err = RestoreCheckpoint(published, 2, restored, key.PubKey())
+	r.NoError(err)
+	r.Equal(int32(2), m.Height)
+	r.Equal(chainhash.Hash{2}.String(), m.Root)
+
+	db, err := NewPebbleReadOnly(restored)
+	r.NoError(err)
+	value, closer, err := db.Get([]byte("b"))
+	r.NoError(err)
+	r.Equal([]byte("2"), value)
+	r.NoError(closer.Close())
+	r.NoError(db.db.Close())
+
+	// A tampered file, or root, fails the verification.
+	r.NoError(os.WriteFile(DeltaName(published, 1, 2)+rootFileExt, []byte(chainhash.Hash{3}.String()), 0644))
+	_, err = RestoreCheckpoint(published, 2, filepath.Join(t.TempDir(), "trie"), key.PubKey())
+	r.ErrorIs(err, ErrBadManifest)
+
+	r.NoError(os.WriteFile(filepath.Join(CheckpointName(published, 1), "extra"), nil, 0644))
+	_, err = RestoreCheckpoint(published, 1, filepath.Join(t.TempDir(), "trie"), key.PubKey())
+	r.ErrorIs(err, ErrBadManifest)
+}
@@ -0,0 +1,137 @@
+package merkletrierepo
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// ErrClosed is returned by the Memory repo once it's closed.
+var ErrClosed = errors.New("repo closed")
+
+// Memory keeps the nodes of the trie in memory, for the tests and regtest runs.
+type Memory struct {
+	mu     sync.RWMutex
+	values map[string][]byte
+	closed bool
+}
+
+func NewMemory() *Memory {
+	return &Memory{values: map[string][]byte{}}
+}
+
+func (repo *Memory) Get(key []byte) ([]byte, io.Closer, error) {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if repo.closed {
+		return nil, nil, ErrClosed
+	}
+	value, ok := repo.values[string(key)]
+	if !ok {
+		return nil, nil, pebble.ErrNotFound
+	}
+
+	return value, nopCloser{}, nil
+}
+
+func (repo *Memory) Set(key, value []byte) error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.closed {
+		return ErrClosed
+	}
+	repo.values[string(key)] = append([]byte(nil), value...)
+
+	return nil
+}
+
+// SetBatch writes the values at once.
+func (repo *Memory) SetBatch(keys, values [][]byte) error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.closed {
+		return ErrClosed
+	}
+	for i, key := range keys {
+		repo.values[string(key)] = append([]byte(nil), values[i]...)
+	}
+
+	return nil
+}
+
+// IterateKeys calls fn with each key of the repo, in order, until it returns false.
+func (repo *Memory) IterateKeys(fn func(key []byte) bool) error {
+
+	repo.mu.RLock()
+	if repo.closed {
+		repo.mu.RUnlock()
+		return ErrClosed
+	}
+	keys := make([]string, 0, len(repo.values))
+	for k := range repo.values {
+		keys = append(keys, k)
+	}
+	repo.mu.RUnlock()
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !fn([]byte(k)) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Delete deletes the keys at once.
+func (repo *Memory) Delete(keys [][]byte) error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.closed {
+		return ErrClosed
+	}
+	for _, key := range keys {
+		delete(repo.values, string(key))
+	}
+
+	return nil
+}
+
+// Clone returns a copy of the repo, which the writes of either don't affect the other.
+func (repo *Memory) Clone() *Memory {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	// The values are never written to in place, so they're shared.
+	values := make(map[string][]byte, len(repo.values))
+	for k, v := range repo.values {
+		values[k] = v
+	}
+
+	return &Memory{values: values, closed: repo.closed}
+}
+
+func (repo *Memory) Close() error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.closed {
+		return ErrClosed
+	}
+	repo.closed = true
+
+	return nil
+}
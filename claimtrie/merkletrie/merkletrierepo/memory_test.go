@@ -0,0 +1,46 @@
+package merkletrierepo
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+
+	r := require.New(t)
+
+	repo := NewMemory()
+	value := []byte("one")
+	r.NoError(repo.Set([]byte("a"), value))
+	r.NoError(repo.SetBatch([][]byte{[]byte("b"), []byte("c")}, [][]byte{[]byte("two"), []byte("three")}))
+	value[0] = 'x' // copied on Set
+
+	got, closer, err := repo.Get([]byte("a"))
+	r.NoError(err)
+	r.Equal([]byte("one"), got)
+	r.NoError(closer.Close())
+	_, _, err = repo.Get([]byte("d"))
+	r.ErrorIs(err, pebble.ErrNotFound)
+
+	// The writes to the clone, and the repo, aren't shared.
+	clone := repo.Clone()
+	r.NoError(clone.Set([]byte("a"), []byte("four")))
+	r.NoError(repo.Set([]byte("d"), []byte("five")))
+	got, _, err = repo.Get([]byte("a"))
+	r.NoError(err)
+	r.Equal([]byte("one"), got)
+	_, _, err = clone.Get([]byte("d"))
+	r.ErrorIs(err, pebble.ErrNotFound)
+
+	r.NoError(repo.Close())
+	_, _, err = repo.Get([]byte("a"))
+	r.ErrorIs(err, ErrClosed)
+	r.ErrorIs(repo.Set([]byte("a"), nil), ErrClosed)
+	r.ErrorIs(repo.Close(), ErrClosed)
+	got, _, err = clone.Get([]byte("a"))
+	r.NoError(err)
+	r.Equal([]byte("four"), got)
+}
@@ -3,19 +3,40 @@ package merkletrierepo
 import (
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
+	"github.com/btcsuite/btcd/claimtrie/progress"
+
 	"github.com/cockroachdb/pebble"
 	humanize "github.com/dustin/go-humanize"
 )
 
 type Pebble struct {
-	db *pebble.DB
+	db     *pebble.DB
+	prefix []byte
+	shared bool
+
+	// The writes batched since BeginBatch, if it's set, which is read through.
+	// mu guards it from the concurrent reads.
+	mu    sync.RWMutex
+	batch *pebble.Batch
+
+	// The progress of writing the checkpoints is reported to onProgress.
+	onProgress progress.Func
 }
 
+// DefaultCacheSize is the size, in bytes, of the block cache of a repo opened with NewPebble.
+const DefaultCacheSize = 512 << 20
+
 func NewPebble(path string) (*Pebble, error) {
+	return NewPebbleWithCache(path, DefaultCacheSize)
+}
+
+// NewPebbleWithCache returns a repo with a block cache of cacheSize bytes.
+func NewPebbleWithCache(path string, cacheSize int64) (*Pebble, error) {
 
-	cache := pebble.NewCache(512 << 20)
+	cache := pebble.NewCache(cacheSize)
 	defer cache.Unref()
 
 	go func() {
@@ -23,7 +44,7 @@ func NewPebble(path string) (*Pebble, error) {
 		for range tick.C {
 
 			m := cache.Metrics()
-			fmt.Printf("cnt: %s, objs: %s, hits: %s, miss: %s, hitrate: %.2f\n",
+			log.Debugf("Trie cache size: %s, objs: %s, hits: %s, miss: %s, hitrate: %.2f",
 				humanize.Bytes(uint64(m.Size)),
 				humanize.Comma(m.Count),
 				humanize.Comma(m.Hits),
@@ -45,20 +66,263 @@ func NewPebble(path string) (*Pebble, error) {
 	return repo, nil
 }
 
+// NewPebbleShared returns a repo storing its keys under the prefix in db, which is shared with other repos.
+// Closing the repo doesn't close db.
+func NewPebbleShared(db *pebble.DB, prefix []byte) *Pebble {
+	return &Pebble{db: db, prefix: prefix, shared: true}
+}
+
+func (repo *Pebble) key(key []byte) []byte {
+	if len(repo.prefix) == 0 {
+		return key
+	}
+	return append(repo.prefix[:len(repo.prefix):len(repo.prefix)], key...)
+}
+
 func (repo *Pebble) Get(key []byte) ([]byte, io.Closer, error) {
-	return repo.db.Get(key)
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if repo.batch == nil {
+		return repo.db.Get(repo.key(key))
+	}
+
+	// The value is copied, as the batch may be written to once the lock is released.
+	value, closer, err := repo.batch.Get(repo.key(key))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closer.Close()
+
+	return append([]byte(nil), value...), nopCloser{}, nil
 }
 
 func (repo *Pebble) Set(key, value []byte) error {
-	return repo.db.Set(key, value, pebble.NoSync)
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.batch != nil {
+		return repo.batch.Set(repo.key(key), value, nil)
+	}
+
+	return repo.db.Set(repo.key(key), value, pebble.NoSync)
+}
+
+// SetBatch writes the values at once, to the writes batched since BeginBatch, if any.
+func (repo *Pebble) SetBatch(keys, values [][]byte) error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	batch := repo.batch
+	if batch == nil {
+		batch = repo.db.NewBatch()
+		defer batch.Close()
+	}
+	for i, key := range keys {
+		err := batch.Set(repo.key(key), values[i], nil)
+		if err != nil {
+			return fmt.Errorf("pebble set: %w", err)
+		}
+	}
+	if batch == repo.batch {
+		return nil
+	}
+
+	err := batch.Commit(pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble commit: %w", err)
+	}
+
+	return nil
+}
+
+// BeginBatch batches the writes until CommitBatch, which writes them at once.
+func (repo *Pebble) BeginBatch() {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.batch == nil {
+		repo.batch = repo.db.NewIndexedBatch()
+	}
+}
+
+// CommitBatch writes, and syncs, the writes batched since BeginBatch, if any.
+func (repo *Pebble) CommitBatch() error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.batch == nil {
+		return nil
+	}
+
+	err := repo.batch.Commit(pebble.Sync)
+	repo.batch.Close()
+	repo.batch = nil
+	if err != nil {
+		return fmt.Errorf("pebble commit: %w", err)
+	}
+
+	return nil
+}
+
+// BatchSize returns the size, in bytes, of the writes batched since BeginBatch.
+func (repo *Pebble) BatchSize() int {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if repo.batch == nil {
+		return 0
+	}
+	return len(repo.batch.Repr())
+}
+
+// BatchRepr returns a copy of the writes batched since BeginBatch, for ApplyBatch, or nil if there are none.
+func (repo *Pebble) BatchRepr() []byte {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if repo.batch == nil || repo.batch.Empty() {
+		return nil
+	}
+	return append([]byte(nil), repo.batch.Repr()...)
+}
+
+// ApplyBatch writes, and syncs, the writes returned by BatchRepr, which were batched before a crash.
+func (repo *Pebble) ApplyBatch(repr []byte) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	err := batch.SetRepr(append([]byte(nil), repr...))
+	if err != nil {
+		return fmt.Errorf("pebble batch repr: %w", err)
+	}
+	err = batch.Commit(pebble.Sync)
+	if err != nil {
+		return fmt.Errorf("pebble commit: %w", err)
+	}
+
+	return nil
+}
+
+// IterateKeys calls fn with each key of the repo, in order, until it returns false. The key is only valid
+// until fn returns. The writes batched since BeginBatch aren't iterated.
+func (repo *Pebble) IterateKeys(fn func(key []byte) bool) error {
+
+	var opts *pebble.IterOptions
+	if len(repo.prefix) > 0 {
+		opts = &pebble.IterOptions{LowerBound: repo.prefix, UpperBound: prefixUpperBound(repo.prefix)}
+	}
+	iter := repo.db.NewIter(opts)
+	for ok := iter.First(); ok; ok = iter.Next() {
+		if !fn(iter.Key()[len(repo.prefix):]) {
+			break
+		}
+	}
+
+	err := iter.Close()
+	if err != nil {
+		return fmt.Errorf("pebble iterate: %w", err)
+	}
+
+	return nil
+}
+
+// Delete deletes the keys at once, from the writes batched since BeginBatch, if any.
+func (repo *Pebble) Delete(keys [][]byte) error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	batch := repo.batch
+	if batch == nil {
+		batch = repo.db.NewBatch()
+		defer batch.Close()
+	}
+	for _, key := range keys {
+		err := batch.Delete(repo.key(key), nil)
+		if err != nil {
+			return fmt.Errorf("pebble delete: %w", err)
+		}
+	}
+	if batch == repo.batch {
+		return nil
+	}
+
+	err := batch.Commit(pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble commit: %w", err)
+	}
+
+	return nil
+}
+
+// Flush writes the memtable of the repo to the disk.
+func (repo *Pebble) Flush() error {
+	return repo.db.Flush()
+}
+
+// Compact compacts the keys of the repo, which speeds up the reads after many of them were written.
+func (repo *Pebble) Compact() error {
+
+	var opts *pebble.IterOptions
+	if len(repo.prefix) > 0 {
+		opts = &pebble.IterOptions{LowerBound: repo.prefix, UpperBound: prefixUpperBound(repo.prefix)}
+	}
+	iter := repo.db.NewIter(opts)
+	defer iter.Close()
+
+	if !iter.First() {
+		return nil
+	}
+	first := append([]byte(nil), iter.Key()...)
+	iter.Last()
+	last := append(append([]byte(nil), iter.Key()...), 0)
+
+	err := repo.db.Compact(first, last)
+	if err != nil {
+		return fmt.Errorf("pebble compact: %w", err)
+	}
+
+	return nil
+}
+
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Pebble) Backup(dir string) error {
+
+	if repo.shared {
+		return fmt.Errorf("backup of a shared repo is not supported")
+	}
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
 }
 
 func (repo *Pebble) Close() error {
 
-	err := repo.db.Flush()
+	err := repo.CommitBatch()
+	if err != nil {
+		return err
+	}
+
+	err = repo.db.Flush()
 	if err != nil {
 		return fmt.Errorf("pebble fludh: %w", err)
 	}
+	if repo.shared {
+		return nil
+	}
 
 	err = repo.db.Close()
 	if err != nil {
@@ -67,3 +331,19 @@ func (repo *Pebble) Close() error {
 
 	return nil
 }
+
+// prefixUpperBound returns the smallest key greater than all keys with the prefix.
+func prefixUpperBound(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil // no upper bound
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
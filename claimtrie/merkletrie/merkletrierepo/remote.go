@@ -0,0 +1,385 @@
+package merkletrierepo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie/frame"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// The remote KV protocol is a stream of requests, each answered by a response in order,
+// which lets the client pipeline them. The integers are big-endian.
+//
+//	request:  'g' keyLen(4B) key
+//	          'b' count(4B) { keyLen(4B) key valueLen(4B) value } * count
+//	response: status(1B) len(4B) payload
+//
+// The payload of a 'g' is the value, or empty if the status is remoteNotFound.
+// The payload of a remoteError is the message. The keys, values and payloads are up to frame.MaxSize bytes.
+const (
+	remoteGet   = 'g'
+	remoteBatch = 'b'
+
+	remoteOK       = 0
+	remoteNotFound = 1
+	remoteError    = 2
+)
+
+// ErrRemote is returned when the remote store fails a request.
+var ErrRemote = errors.New("remote store")
+
+// RemoteOptions tunes the batching and pipelining of a Remote.
+type RemoteOptions struct {
+	// Sets are sent in batches of this many bytes.
+	BatchBytes int
+	// Up to this many requests are sent before waiting for their responses.
+	MaxInFlight int
+	// Timeout of dialing the store, if it's set.
+	DialTimeout time.Duration
+}
+
+var DefaultRemoteOptions = RemoteOptions{
+	BatchBytes:  1 << 20,
+	MaxInFlight: 64,
+	DialTimeout: 10 * time.Second,
+}
+
+type remoteReply struct {
+	value []byte
+	err   error
+}
+
+type remoteCall struct {
+	done chan remoteReply // nil for batches, of which only the errors are kept.
+}
+
+// Remote is a trie repo backed by a KV store speaking the remote KV protocol.
+type Remote struct {
+	conn net.Conn
+	opts RemoteOptions
+
+	mu         sync.Mutex
+	w          *bufio.Writer
+	batch      map[string][]byte
+	batchBytes int
+	calls      chan remoteCall
+
+	errMu sync.Mutex
+	err   error
+
+	reading sync.WaitGroup
+}
+
+func NewRemote(addr string, opts RemoteOptions) (*Remote, error) {
+
+	conn, err := net.DialTimeout("tcp", addr, opts.DialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", addr, err)
+	}
+
+	repo := &Remote{
+		conn:  conn,
+		opts:  opts,
+		w:     bufio.NewWriter(conn),
+		batch: map[string][]byte{},
+		calls: make(chan remoteCall, opts.MaxInFlight),
+	}
+	repo.reading.Add(1)
+	go repo.readReplies()
+
+	return repo, nil
+}
+
+func (repo *Remote) Get(key []byte) ([]byte, io.Closer, error) {
+
+	repo.mu.Lock()
+	if err := repo.failure(); err != nil {
+		repo.mu.Unlock()
+		return nil, nil, err
+	}
+	if value, ok := repo.batch[string(key)]; ok {
+		repo.mu.Unlock()
+		return value, io.NopCloser(nil), nil
+	}
+
+	done := make(chan remoteReply, 1)
+	err := repo.send(remoteCall{done: done}, func() error {
+		return frame.Write(repo.w, remoteGet, key)
+	})
+	repo.mu.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reply := <-done
+	if reply.err != nil {
+		return nil, nil, reply.err
+	}
+
+	return reply.value, io.NopCloser(nil), nil
+}
+
+// Set buffers the value, and sends the buffered ones once they reach the batch size.
+// Errors of the sent batches are returned by the subsequent calls.
+func (repo *Remote) Set(key, value []byte) error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if err := repo.failure(); err != nil {
+		return err
+	}
+
+	k := string(key)
+	if old, ok := repo.batch[k]; ok {
+		repo.batchBytes -= len(k) + len(old)
+	}
+	repo.batch[k] = append([]byte(nil), value...)
+	repo.batchBytes += len(k) + len(value)
+	if repo.batchBytes < repo.opts.BatchBytes {
+		return nil
+	}
+
+	return repo.sendBatch()
+}
+
+// Flush sends the buffered values, and waits for the store to acknowledge all the requests.
+func (repo *Remote) Flush() error {
+
+	repo.mu.Lock()
+	err := repo.sendBatch()
+	if err != nil {
+		repo.mu.Unlock()
+		return err
+	}
+
+	// The responses come in order; the one to this get comes after all the preceding ones.
+	done := make(chan remoteReply, 1)
+	err = repo.send(remoteCall{done: done}, func() error {
+		return frame.Write(repo.w, remoteGet, nil)
+	})
+	repo.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	reply := <-done
+	if reply.err != nil && !errors.Is(reply.err, pebble.ErrNotFound) {
+		return reply.err
+	}
+
+	return repo.failure()
+}
+
+func (repo *Remote) Close() error {
+
+	err := repo.Flush()
+
+	close(repo.calls)
+	cerr := repo.conn.Close()
+	repo.reading.Wait()
+
+	if err != nil {
+		return fmt.Errorf("remote flush: %w", err)
+	}
+	if cerr != nil {
+		return fmt.Errorf("remote close: %w", cerr)
+	}
+
+	return nil
+}
+
+// sendBatch sends the buffered values. The caller holds repo.mu.
+func (repo *Remote) sendBatch() error {
+
+	if len(repo.batch) == 0 {
+		return nil
+	}
+
+	batch := repo.batch
+	repo.batch = map[string][]byte{}
+	repo.batchBytes = 0
+
+	return repo.send(remoteCall{}, func() error {
+		var hdr [5]byte
+		hdr[0] = remoteBatch
+		binary.BigEndian.PutUint32(hdr[1:], uint32(len(batch)))
+		if _, err := repo.w.Write(hdr[:]); err != nil {
+			return err
+		}
+		for k, v := range batch {
+			if err := frame.WriteField(repo.w, []byte(k)); err != nil {
+				return err
+			}
+			if err := frame.WriteField(repo.w, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// send queues the call, and writes its request. The caller holds repo.mu,
+// which keeps the order of the calls the same as the requests.
+func (repo *Remote) send(call remoteCall, write func() error) error {
+
+	repo.calls <- call // blocks while MaxInFlight requests are unanswered
+
+	err := write()
+	if err == nil {
+		err = repo.w.Flush()
+	}
+	if err != nil {
+		err = fmt.Errorf("remote write: %w", err)
+		repo.fail(err)
+		return err
+	}
+
+	return nil
+}
+
+func (repo *Remote) readReplies() {
+
+	defer repo.reading.Done()
+
+	r := bufio.NewReader(repo.conn)
+	for call := range repo.calls {
+		reply := readReply(r)
+		if reply.err != nil && !errors.Is(reply.err, pebble.ErrNotFound) {
+			repo.fail(reply.err)
+		}
+		if call.done != nil {
+			call.done <- reply
+		}
+	}
+}
+
+func (repo *Remote) fail(err error) {
+	repo.errMu.Lock()
+	defer repo.errMu.Unlock()
+	if repo.err == nil {
+		repo.err = err
+	}
+}
+
+func (repo *Remote) failure() error {
+	repo.errMu.Lock()
+	defer repo.errMu.Unlock()
+	return repo.err
+}
+
+func readReply(r *bufio.Reader) remoteReply {
+
+	status, payload, err := frame.Read(r)
+	if err != nil {
+		return remoteReply{err: fmt.Errorf("remote read: %w", err)}
+	}
+
+	switch status {
+	case remoteOK:
+		return remoteReply{value: payload}
+	case remoteNotFound:
+		return remoteReply{err: pebble.ErrNotFound} // what the trie expects
+	default:
+		return remoteReply{err: fmt.Errorf("%w: %s", ErrRemote, payload)}
+	}
+}
+
+// RemoteStore is the KV store served by ServeRemote, such as a Pebble.
+type RemoteStore interface {
+	Get(key []byte) ([]byte, io.Closer, error)
+	Set(key, value []byte) error
+}
+
+// ServeRemote answers the requests of the remote KV protocol on the connections
+// accepted from l with the store, until l is closed.
+// It's an adapter for KV layers, which don't speak the protocol natively.
+func ServeRemote(l net.Listener, store RemoteStore) error {
+
+	var mu sync.Mutex // the requests of each connection are answered in order, and their batches applied whole.
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			w := bufio.NewWriter(conn)
+			for {
+				op, err := r.ReadByte()
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				status, payload := serveRequest(r, op, store)
+				mu.Unlock()
+				if status < 0 {
+					return
+				}
+				if err = frame.Write(w, byte(status), payload); err != nil {
+					return
+				}
+				if r.Buffered() == 0 { // answer pipelined requests together
+					if err = w.Flush(); err != nil {
+						return
+					}
+				}
+			}
+		}()
+	}
+}
+
+func serveRequest(r *bufio.Reader, op byte, store RemoteStore) (int, []byte) {
+
+	switch op {
+	case remoteGet:
+		key, err := frame.ReadField(r)
+		if err != nil {
+			return -1, nil
+		}
+		value, closer, err := store.Get(key)
+		if errors.Is(err, pebble.ErrNotFound) {
+			return remoteNotFound, nil
+		}
+		if err != nil {
+			return remoteError, []byte(err.Error())
+		}
+		value = append([]byte(nil), value...)
+		closer.Close()
+		return remoteOK, value
+
+	case remoteBatch:
+		var hdr [4]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return -1, nil
+		}
+		var failed error
+		for i := binary.BigEndian.Uint32(hdr[:]); i > 0; i-- {
+			key, err := frame.ReadField(r)
+			if err != nil {
+				return -1, nil
+			}
+			value, err := frame.ReadField(r)
+			if err != nil {
+				return -1, nil
+			}
+			if failed == nil {
+				failed = store.Set(key, value)
+			}
+		}
+		if failed != nil {
+			return remoteError, []byte(failed.Error())
+		}
+		return remoteOK, nil
+	}
+
+	return -1, nil
+}
@@ -0,0 +1,83 @@
+package merkletrierepo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct{ RemoteStore }
+
+func (s failingStore) Set(key, value []byte) error {
+	return errors.New("disk full")
+}
+
+func serve(t *testing.T, store RemoteStore) string {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go ServeRemote(l, store) // nolint : errchk
+
+	return l.Addr().String()
+}
+
+func TestRemote(t *testing.T) {
+
+	r := require.New(t)
+
+	store, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer store.Close()
+	addr := serve(t, store)
+
+	opts := DefaultRemoteOptions
+	opts.BatchBytes = 64
+	opts.MaxInFlight = 2
+	repo, err := NewRemote(addr, opts)
+	r.NoError(err)
+
+	for i := 0; i < 100; i++ {
+		r.NoError(repo.Set([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i))))
+	}
+	_, _, err = repo.Get([]byte("missing"))
+	r.Equal(pebble.ErrNotFound, err)
+
+	// Buffered, and sent values alike.
+	for _, i := range []int{0, 50, 99} {
+		value, closer, err := repo.Get([]byte(fmt.Sprintf("key%d", i)))
+		r.NoError(err)
+		r.Equal(fmt.Sprintf("value%d", i), string(value))
+		r.NoError(closer.Close())
+	}
+	r.NoError(repo.Close())
+
+	value, closer, err := store.Get([]byte("key99"))
+	r.NoError(err)
+	r.Equal("value99", string(value))
+	closer.Close()
+}
+
+func TestRemoteFailure(t *testing.T) {
+
+	r := require.New(t)
+
+	store, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer store.Close()
+	addr := serve(t, failingStore{store})
+
+	repo, err := NewRemote(addr, DefaultRemoteOptions)
+	r.NoError(err)
+
+	r.NoError(repo.Set([]byte("a"), []byte("1"))) // buffered
+	err = repo.Flush()
+	r.ErrorIs(err, ErrRemote)
+	_, _, err = repo.Get([]byte("a"))
+	r.ErrorIs(err, ErrRemote)
+	r.Error(repo.Close())
+}
@@ -0,0 +1,264 @@
+package merkletrierepo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/progress"
+
+	"github.com/cockroachdb/pebble"
+)
+
+const rootFileExt = ".root"
+
+// NewPebbleReadOnly opens the repo at path in read-only mode.
+// Pebble holds an exclusive lock on the directory it opens, so the path is
+// usually a checkpoint of the repo a writer is using rather than the repo itself.
+func NewPebbleReadOnly(path string) (*Pebble, error) {
+
+	db, err := pebble.Open(path, &pebble.Options{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("pebble open %s, %w", path, err)
+	}
+
+	return &Pebble{db: db}, nil
+}
+
+// SetProgress sets fn to be reported the progress of writing the checkpoints, and the keys written to the deltas.
+func (repo *Pebble) SetProgress(fn progress.Func) {
+	repo.onProgress = fn
+}
+
+// Checkpoint writes a consistent copy of the repo, along with the root hash of
+// the trie at the height, into dir, where it can be picked up by a Replica.
+// Only the latest keep checkpoints, and the deltas against them, are retained.
+func (repo *Pebble) Checkpoint(dir string, height int32, root *chainhash.Hash, keep int) error {
+
+	if repo.shared {
+		return fmt.Errorf("checkpoint of a shared repo is not supported")
+	}
+
+	meter := progress.Start("checkpoint", repo.onProgress)
+	defer meter.Stop()
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("make checkpoint dir: %w", err)
+	}
+
+	name := CheckpointName(dir, height)
+	err = os.RemoveAll(name) // leftover of an interrupted checkpoint
+	if err != nil {
+		return fmt.Errorf("remove stale checkpoint: %w", err)
+	}
+	err = os.Remove(name + manifestFileExt) // it would list the files of the leftover
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove stale manifest: %w", err)
+	}
+
+	// Unsynced writes would be missing from the copied WAL.
+	err = repo.db.Flush()
+	if err != nil {
+		return fmt.Errorf("pebble flush: %w", err)
+	}
+
+	err = repo.db.Checkpoint(name)
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	// The root file is written last, so replicas only see complete checkpoints.
+	err = os.WriteFile(name+rootFileExt, []byte(root.String()), 0644)
+	if err != nil {
+		return fmt.Errorf("write checkpoint root: %w", err)
+	}
+
+	heights, err := listCheckpoints(dir)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(heights)-keep; i++ {
+		stale := filepath.Join(dir, fmt.Sprintf("%010d", heights[i]))
+		if err = os.Remove(stale + rootFileExt); err != nil {
+			return fmt.Errorf("remove checkpoint root: %w", err)
+		}
+		if err = os.RemoveAll(stale); err != nil {
+			return fmt.Errorf("remove checkpoint: %w", err)
+		}
+		if err = os.Remove(stale + manifestFileExt); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove checkpoint manifest: %w", err)
+		}
+
+		// The deltas against it can't be applied any longer.
+		deltas, err := filepath.Glob(stale + "-*" + deltaFileExt + "*")
+		if err != nil {
+			return fmt.Errorf("glob deltas: %w", err)
+		}
+		for _, delta := range deltas {
+			if err = os.Remove(delta); err != nil {
+				return fmt.Errorf("remove delta: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// listCheckpoints returns the heights of complete checkpoints in dir, in ascending order.
+func listCheckpoints(dir string) ([]int32, error) {
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint dir: %w", err)
+	}
+
+	var heights []int32
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), rootFileExt) {
+			continue
+		}
+		h, err := strconv.ParseInt(strings.TrimSuffix(e.Name(), rootFileExt), 10, 32)
+		if err != nil {
+			continue
+		}
+		heights = append(heights, int32(h))
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	return heights, nil
+}
+
+// Replica is a read-only Repo serving the checkpoints written by a writer into a
+// shared directory. It switches to newer checkpoints when CatchUp is called.
+type Replica struct {
+	dir string
+
+	mu     sync.RWMutex
+	repo   *Pebble
+	height int32
+	root   *chainhash.Hash
+}
+
+// NewReplica returns a Replica opened at the latest checkpoint in dir, if any.
+func NewReplica(dir string) (*Replica, error) {
+
+	r := &Replica{dir: dir}
+	if _, err := r.CatchUp(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// CatchUp switches to the latest checkpoint, and reports whether it has changed.
+func (r *Replica) CatchUp() (bool, error) {
+
+	heights, err := listCheckpoints(r.dir)
+	if err != nil || len(heights) == 0 {
+		return false, err
+	}
+
+	height := heights[len(heights)-1]
+	r.mu.RLock()
+	current := r.repo != nil && r.height == height
+	r.mu.RUnlock()
+	if current {
+		return false, nil
+	}
+
+	name := filepath.Join(r.dir, fmt.Sprintf("%010d", height))
+	b, err := os.ReadFile(name + rootFileExt)
+	if err != nil {
+		return false, fmt.Errorf("read checkpoint root: %w", err)
+	}
+	root, err := chainhash.NewHashFromStr(string(b))
+	if err != nil {
+		return false, fmt.Errorf("parse checkpoint root: %w", err)
+	}
+
+	repo, err := NewPebbleReadOnly(name)
+	if err != nil {
+		return false, err
+	}
+
+	// Wait for the readers of the previous checkpoint to release their values.
+	r.mu.Lock()
+	previous := r.repo
+	r.repo, r.height, r.root = repo, height, root
+	r.mu.Unlock()
+
+	if previous != nil {
+		if err = previous.db.Close(); err != nil {
+			return true, fmt.Errorf("pebble close: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+// Root returns the height and the root hash of the current checkpoint.
+// The root is nil if no checkpoint is available yet.
+func (r *Replica) Root() (int32, *chainhash.Hash) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.height, r.root
+}
+
+// Get returns the value of the key in the current checkpoint.
+// The checkpoint is kept open until the returned closer is closed.
+func (r *Replica) Get(key []byte) ([]byte, io.Closer, error) {
+
+	r.mu.RLock()
+	if r.repo == nil {
+		r.mu.RUnlock()
+		return nil, nil, pebble.ErrNotFound
+	}
+
+	value, closer, err := r.repo.Get(key)
+	if err != nil {
+		r.mu.RUnlock()
+		return nil, nil, err
+	}
+
+	return value, &replicaCloser{closer: closer, mu: &r.mu}, nil
+}
+
+// Set always fails, as replicas are read-only.
+func (r *Replica) Set(key, value []byte) error {
+	return pebble.ErrReadOnly
+}
+
+func (r *Replica) Close() error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.repo == nil {
+		return nil
+	}
+	err := r.repo.db.Close()
+	r.repo = nil
+	if err != nil {
+		return fmt.Errorf("pebble close: %w", err)
+	}
+
+	return nil
+}
+
+type replicaCloser struct {
+	closer io.Closer
+	mu     *sync.RWMutex
+}
+
+func (c *replicaCloser) Close() error {
+	defer c.mu.RUnlock()
+	return c.closer.Close()
+}
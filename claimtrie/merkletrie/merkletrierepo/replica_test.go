@@ -0,0 +1,162 @@
+package merkletrierepo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplica(t *testing.T) {
+
+	r := require.New(t)
+
+	dir := t.TempDir()
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer repo.Close()
+
+	replica, err := NewReplica(dir)
+	r.NoError(err)
+	defer replica.Close()
+
+	_, _, err = replica.Get([]byte("a"))
+	r.Equal(pebble.ErrNotFound, err)
+	_, root := replica.Root()
+	r.Nil(root)
+
+	r.NoError(repo.Set([]byte("a"), []byte("1")))
+	r.NoError(repo.Checkpoint(dir, 1, &chainhash.Hash{1}, 2))
+
+	changed, err := replica.CatchUp()
+	r.NoError(err)
+	r.True(changed)
+	height, root := replica.Root()
+	r.Equal(int32(1), height)
+	r.Equal(chainhash.Hash{1}, *root)
+
+	value, closer, err := replica.Get([]byte("a"))
+	r.NoError(err)
+	r.Equal([]byte("1"), value)
+	r.NoError(closer.Close())
+	r.Equal(pebble.ErrReadOnly, replica.Set([]byte("a"), nil))
+
+	r.NoError(repo.Set([]byte("b"), []byte("2")))
+	r.NoError(repo.Checkpoint(dir, 2, &chainhash.Hash{2}, 2))
+	r.NoError(repo.Checkpoint(dir, 3, &chainhash.Hash{3}, 2))
+
+	heights, err := listCheckpoints(dir)
+	r.NoError(err)
+	r.Equal([]int32{2, 3}, heights)
+
+	changed, err = replica.CatchUp()
+	r.NoError(err)
+	r.True(changed)
+	value, closer, err = replica.Get([]byte("b"))
+	r.NoError(err)
+	r.Equal([]byte("2"), value)
+	r.NoError(closer.Close())
+
+	changed, err = replica.CatchUp()
+	r.NoError(err)
+	r.False(changed)
+}
+
+func TestDeltaCheckpoint(t *testing.T) {
+
+	r := require.New(t)
+
+	dir := t.TempDir()
+	last, err := LastCheckpoint(filepath.Join(dir, "missing"))
+	r.NoError(err)
+	r.Zero(last)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer repo.Close()
+
+	r.NoError(repo.Set([]byte("a"), []byte("1")))
+	r.NoError(repo.Set([]byte("c"), []byte("3")))
+	r.NoError(repo.Checkpoint(dir, 1, &chainhash.Hash{1}, 2))
+	r.NoError(repo.DeltaCheckpoint(dir, 1, 2, &chainhash.Hash{2}))
+	r.NoError(repo.Set([]byte("b"), []byte("2")))
+	r.NoError(repo.Set([]byte("d"), []byte("4")))
+	r.NoError(repo.DeltaCheckpoint(dir, 1, 3, &chainhash.Hash{3}))
+
+	last, err = LastCheckpoint(dir)
+	r.NoError(err)
+	r.Equal(int32(1), last)
+
+	// Only the keys missing from the base are in the delta.
+	f, err := os.Open(DeltaName(dir, 1, 3))
+	r.NoError(err)
+	reader, err := sstable.NewReader(f, sstable.ReaderOptions{})
+	r.NoError(err)
+	iter, err := reader.NewIter(nil, nil)
+	r.NoError(err)
+	var keys []string
+	for k, _ := iter.First(); k != nil; k, _ = iter.Next() {
+		keys = append(keys, string(k.UserKey))
+	}
+	r.NoError(iter.Close())
+	r.NoError(reader.Close())
+	r.Equal([]string{"b", "d"}, keys)
+
+	// A mirror applies the deltas to its copy of the base.
+	for _, height := range []int32{2, 3} {
+		mirror := filepath.Join(t.TempDir(), "mirror")
+		r.NoError(copyDir(filepath.Join(dir, fmt.Sprintf("%010d", 1)), mirror))
+		r.NoError(ApplyDelta(mirror, DeltaName(dir, 1, height)))
+		r.FileExists(DeltaName(dir, 1, height))
+
+		applied, err := NewPebbleReadOnly(mirror)
+		r.NoError(err)
+		for key, value := range map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"} {
+			v, closer, err := applied.Get([]byte(key))
+			if height == 2 && (key == "b" || key == "d") {
+				r.Equal(pebble.ErrNotFound, err)
+				continue
+			}
+			r.NoError(err)
+			r.Equal(value, string(v))
+			r.NoError(closer.Close())
+		}
+		r.NoError(applied.db.Close())
+	}
+
+	// The deltas go along with their base.
+	r.NoError(repo.Checkpoint(dir, 4, &chainhash.Hash{4}, 2))
+	r.NoError(repo.Checkpoint(dir, 5, &chainhash.Hash{5}, 2))
+	r.NoFileExists(DeltaName(dir, 1, 3))
+	r.NoFileExists(DeltaName(dir, 1, 3) + rootFileExt)
+}
+
+func copyDir(src, dst string) error {
+
+	err := os.MkdirAll(dst, 0755)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filepath.Join(dst, e.Name()), b, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package merkletrie
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/proof"
+)
+
+type (
+	ProofValue = proof.Value
+	MultiProof = proof.MultiProof
+)
+
+// ProveMany returns the proof of the values against the Merkle Hash of the trie.
+// As with Prove, the trie must have been hashed with MerkleHash.
+func (t *MerkleTrie) ProveMany(values []ProofValue) (*MultiProof, error) {
+
+	sorted := append([]ProofValue(nil), values...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Name, sorted[j].Name) < 0
+	})
+	for i := 1; i < len(sorted); i++ {
+		if bytes.Equal(sorted[i-1].Name, sorted[i].Name) {
+			return nil, fmt.Errorf("duplicate name %q", sorted[i].Name)
+		}
+	}
+
+	p := &MultiProof{Values: sorted}
+	if len(sorted) == 0 {
+		return p, nil
+	}
+
+	err := t.proveSubtrie(p, t.root, nil, sorted)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// proveSubtrie appends the nodes from v down to the values, which all share its prefix.
+func (t *MerkleTrie) proveSubtrie(p *MultiProof, v *vertex, prefix []byte, values []ProofValue) error {
+
+	if len(v.childLinks) == 0 {
+		t.resolveChildLinks(v, prefix)
+	}
+
+	var self *ProofValue
+	if len(values[0].Name) == len(prefix) {
+		self = &values[0] // sorted first, as it's the shortest
+		values = values[1:]
+	}
+
+	// Group the remaining values by the character following the prefix.
+	groups := map[byte][]ProofValue{}
+	for _, value := range values {
+		ch := value.Name[len(prefix)]
+		groups[ch] = append(groups[ch], value)
+	}
+
+	index := len(p.Nodes)
+	p.Nodes = append(p.Nodes, ProofNode{})
+
+	var pn ProofNode
+	for _, ch := range keysInOrder(v) {
+		child := v.childLinks[ch]
+		if child.merkleHash == nil {
+			return fmt.Errorf("unhashed child at %q", append(prefix[:len(prefix):len(prefix)], ch))
+		}
+		c := ProofChild{Character: ch, Hash: child.merkleHash}
+		if _, ok := groups[ch]; ok {
+			c.Hash = nil
+		}
+		pn.Children = append(pn.Children, c)
+	}
+
+	if self != nil {
+		if !v.hasValue || v.claimsHash == nil {
+			return fmt.Errorf("no value at %q", prefix)
+		}
+		if !v.claimsHash.IsEqual(node.CalculateNodeHash(self.OutPoint, self.TakeoverHeight)) {
+			return fmt.Errorf("value at %q doesn't match the claim", prefix)
+		}
+	} else if v.hasValue {
+		pn.ValueHash = v.claimsHash
+	}
+	p.Nodes[index] = pn
+
+	for _, c := range pn.Children {
+		if c.Hash != nil {
+			continue
+		}
+		child := v.childLinks[c.Character]
+		err := t.proveSubtrie(p, child, append(prefix[:len(prefix):len(prefix)], c.Character), groups[c.Character])
+		if err != nil {
+			return err
+		}
+		delete(groups, c.Character)
+	}
+
+	for ch := range groups {
+		return fmt.Errorf("missing child at %q", append(prefix[:len(prefix):len(prefix)], ch))
+	}
+
+	return nil
+}
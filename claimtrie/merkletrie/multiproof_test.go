@@ -0,0 +1,73 @@
+package merkletrie
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiProof(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{"a": outPoint(1), "ab": outPoint(2), "b": outPoint(3)}
+	for i := 0; i < 50; i++ {
+		store[fmt.Sprintf("abc-%d", i)] = outPoint(uint32(i + 10))
+	}
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	trie := New(store, repo)
+	defer trie.Close()
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+	root := trie.MerkleHash()
+	r.NoError(trie.Commit())
+
+	var values []ProofValue
+	single := 0
+	for _, name := range []string{"abc-7", "a", "abc-3", "ab", "abc-42"} {
+		values = append(values, ProofValue{Name: []byte(name), OutPoint: store[name], TakeoverHeight: 1})
+
+		proof, err := trie.Prove([]byte(name), store[name], 1)
+		r.NoError(err)
+		b := bytes.NewBuffer(nil)
+		r.NoError(proof.Encode(b))
+		single += b.Len()
+	}
+
+	proof, err := trie.ProveMany(values)
+	r.NoError(err)
+	r.True(proof.Verify(root))
+	r.Equal("a", string(proof.Values[0].Name))
+
+	b := bytes.NewBuffer(nil)
+	r.NoError(proof.Encode(b))
+	r.Less(b.Len(), single)
+	decoded := &MultiProof{}
+	r.NoError(decoded.Decode(b))
+	r.Equal(proof, decoded)
+	r.True(decoded.Verify(root))
+
+	// Resolved from the repo.
+	resolved := New(store, repo)
+	resolved.SetRoot(root)
+	proved, err := resolved.ProveMany(values)
+	r.NoError(err)
+	r.Equal(proof, proved)
+
+	decoded.Values[2].OutPoint.Index++
+	r.False(decoded.Verify(root))
+	decoded.Values[2].OutPoint.Index--
+	decoded.Values = decoded.Values[1:]
+	r.False(decoded.Verify(root))
+
+	_, err = trie.ProveMany(append(values, ProofValue{Name: []byte("abd"), OutPoint: outPoint(1)}))
+	r.Error(err)
+	_, err = trie.ProveMany(append(values, values[0]))
+	r.Error(err)
+}
@@ -0,0 +1,56 @@
+package merkletrie
+
+// IterateNames calls f with the names having values in the trie, which sort after the name
+// after, in order, until f returns false. The slice passed to f is only valid until it returns.
+// The trie is read from the repo at its root, so it should be a view returned by At.
+func (t *MerkleTrie) IterateNames(after []byte, f func(name []byte) bool) {
+	t.iterateNames(make([]byte, 0, 256), t.root, after, true, f)
+}
+
+// IterateChild calls f with the names having values under the top-level child ch, in order,
+// until f returns false. As with IterateNames, it should be called on a view returned by At.
+func (t *MerkleTrie) IterateChild(ch byte, f func(name []byte) bool) {
+
+	if t.root.merkleHash == nil {
+		return
+	}
+	if len(t.root.childLinks) == 0 {
+		t.resolveChildLinks(t.root, nil)
+	}
+	if child := t.root.childLinks[ch]; child != nil {
+		t.iterateNames(append(make([]byte, 0, 256), ch), child, nil, false, f)
+	}
+}
+
+// iterateNames walks the vertices below v at prefix in order. If bounded, prefix is
+// a prefix of after, and the names up to after are skipped.
+func (t *MerkleTrie) iterateNames(prefix []byte, v *vertex, after []byte, bounded bool, f func(name []byte) bool) bool {
+
+	if v.merkleHash == nil {
+		return true
+	}
+	if len(v.childLinks) == 0 {
+		t.resolveChildLinks(v, prefix)
+	}
+	// The walked vertices aren't needed again.
+	defer func() { v.childLinks = map[byte]*vertex{} }()
+
+	if v.hasValue && !bounded && !f(prefix) {
+		return false
+	}
+
+	for _, ch := range keysInOrder(v) {
+		childBounded := false
+		if bounded && len(prefix) < len(after) {
+			if ch < after[len(prefix)] {
+				continue
+			}
+			childBounded = ch == after[len(prefix)]
+		}
+		if !t.iterateNames(append(prefix, ch), v.childLinks[ch], after, childBounded, f) {
+			return false
+		}
+	}
+
+	return true
+}
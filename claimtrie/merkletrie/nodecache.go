@@ -0,0 +1,111 @@
+package merkletrie
+
+import (
+	"container/list"
+	"sync"
+)
+
+// nodeCacheOverhead is the estimated size of an entry of the node cache, besides its node.
+const nodeCacheOverhead = 128
+
+// nodeCache keeps the stored nodes resolved, up to a capacity in bytes, evicting the least recently used ones.
+// It's kept by SetRoot, so the paths resolved again after the trie was dropped from memory aren't read from the
+// repo. The nodes are keyed as in the repo, by their paths and hashes, so the ones cached are never stale. The hash
+// alone doesn't do, as the nodes of a single child pass its hash up after the all-claims fork.
+type nodeCache struct {
+	mu       sync.Mutex
+	capacity int64
+	size     int64
+	lru      *list.List // of *nodeCacheEntry, the most recently used first.
+	entries  map[string]*list.Element
+
+	hits, misses, evictions int64
+}
+
+type nodeCacheEntry struct {
+	key  string
+	node []byte
+}
+
+// NodeCacheStats is the size of the node cache, and the nodes resolved, which were, or weren't, in it, and the
+// ones evicted from it.
+type NodeCacheStats struct {
+	Entries   int
+	Bytes     int64
+	Hits      int64
+	Misses    int64
+	Evictions int64
+}
+
+// SetNodeCache enables caching the stored nodes resolved, up to capacity bytes, across the calls of SetRoot.
+func (t *MerkleTrie) SetNodeCache(capacity int64) {
+	t.nodes = &nodeCache{capacity: capacity, lru: list.New(), entries: map[string]*list.Element{}}
+}
+
+// NodeCacheStats returns the effect of the node cache, if it's enabled.
+func (t *MerkleTrie) NodeCacheStats() NodeCacheStats {
+
+	c := t.nodes
+	if c == nil {
+		return NodeCacheStats{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return NodeCacheStats{Entries: len(c.entries), Bytes: c.size, Hits: c.hits, Misses: c.misses, Evictions: c.evictions}
+}
+
+// get returns the node of the key, if it's cached, and counts the hit, or the miss.
+// c may be nil, if the cache is disabled.
+func (c *nodeCache) get(key []byte) ([]byte, bool) {
+
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[string(key)]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.lru.MoveToFront(e)
+
+	return e.Value.(*nodeCacheEntry).node, true
+}
+
+// add caches a copy of the node of the key, which has been verified, evicting the least recently used ones
+// over the capacity.
+func (c *nodeCache) add(key, node []byte) {
+
+	if c == nil {
+		return
+	}
+	size := int64(len(key)+len(node)) + nodeCacheOverhead
+	if size > c.capacity {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[string(key)]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+	entry := &nodeCacheEntry{key: string(key), node: append([]byte(nil), node...)}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.size += size
+
+	for c.size > c.capacity {
+		e := c.lru.Back()
+		evicted := c.lru.Remove(e).(*nodeCacheEntry)
+		delete(c.entries, evicted.key)
+		c.size -= int64(len(evicted.key)+len(evicted.node)) + nodeCacheOverhead
+		c.evictions++
+	}
+}
@@ -0,0 +1,105 @@
+package merkletrie
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/progress"
+)
+
+// ParallelMerkleHash returns the same hash as MerkleHash, but resolves the subtries
+// under the top-level children on the specified number of workers.
+// It's meant for hashing the entire trie, where most of the nodes are dirty.
+func (t *MerkleTrie) ParallelMerkleHash(workers int) *chainhash.Hash {
+	defer t.startMeter()()
+	t.parallel(workers, (*MerkleTrie).merkle)
+	return t.MerkleHash()
+}
+
+// ParallelMerkleHashAllClaims is the parallel version of MerkleHashAllClaims.
+func (t *MerkleTrie) ParallelMerkleHashAllClaims(workers int) *chainhash.Hash {
+	defer t.startMeter()()
+	t.parallel(workers, (*MerkleTrie).merkleAllClaims)
+	return t.MerkleHashAllClaims()
+}
+
+// SetProgress sets fn to be reported the progress of hashing the entire trie with ParallelMerkleHash,
+// and ParallelMerkleHashAllClaims, in the names hashed and the nodes written.
+func (t *MerkleTrie) SetProgress(fn progress.Func) {
+	t.onProgress = fn
+}
+
+// startMeter starts counting the progress of the hashing, if it's reported, and returns the func stopping it.
+func (t *MerkleTrie) startMeter() func() {
+	t.meter = progress.Start("hash", t.onProgress)
+	return func() {
+		t.meter.Stop()
+		t.meter = nil
+	}
+}
+
+// parallel resolves the hashes of the top-level children of the root,
+// which are then merged at the root by the caller.
+func (t *MerkleTrie) parallel(workers int, merkle func(t *MerkleTrie, key *keyBuf, v *vertex) *chainhash.Hash) {
+
+	if t.root.merkleHash != nil || len(t.root.childLinks) == 0 {
+		return
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	// The subtries are disjoint, but the store is shared among the workers.
+	store := &syncStore{store: t.store}
+
+	keys := keysInOrder(t.root)
+	jobs := make(chan byte, len(keys))
+	for _, ch := range keys {
+		jobs <- ch
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			w := &MerkleTrie{
+				store: store,
+				repo:  t.repo,
+				meter: t.meter,
+				bufs: &sync.Pool{
+					New: func() interface{} {
+						return new(bytes.Buffer)
+					},
+				},
+			}
+			for ch := range jobs {
+				w.key.reset()
+				w.key.push(ch)
+				merkle(w, &w.key, t.root.childLinks[ch])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// syncStore serializes the access to a ValueStore, which isn't safe for concurrent use.
+type syncStore struct {
+	sync.Mutex
+	store ValueStore
+}
+
+func (s *syncStore) ClaimHashes(name []byte) []*chainhash.Hash {
+	s.Lock()
+	defer s.Unlock()
+	return s.store.ClaimHashes(name)
+}
+
+func (s *syncStore) Hash(name []byte) *chainhash.Hash {
+	s.Lock()
+	defer s.Unlock()
+	return s.store.Hash(name)
+}
@@ -0,0 +1,82 @@
+package merkletrie
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+	"github.com/btcsuite/btcd/claimtrie/progress"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelMerkleHash(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{"": outPoint(0)}
+	for i := 0; i < 500; i++ {
+		store[fmt.Sprintf("name-%d", i)] = outPoint(uint32(i + 1))
+		store[fmt.Sprintf("%d", i)] = outPoint(uint32(i + 1000))
+	}
+
+	hash := func(parallel bool, allClaims bool) string {
+		repo, err := merkletrierepo.NewPebble(t.TempDir())
+		r.NoError(err)
+		trie := New(store, repo)
+		defer trie.Close()
+		for name := range store {
+			trie.Update([]byte(name), false)
+		}
+		switch {
+		case parallel && allClaims:
+			return trie.ParallelMerkleHashAllClaims(4).String()
+		case parallel:
+			return trie.ParallelMerkleHash(4).String()
+		case allClaims:
+			return trie.MerkleHashAllClaims().String()
+		default:
+			return trie.MerkleHash().String()
+		}
+	}
+
+	r.Equal(hash(false, false), hash(true, false))
+	r.Equal(hash(false, true), hash(true, true))
+}
+
+func TestParallelMerkleHashProgress(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{"": outPoint(0)}
+	for i := 0; i < 500; i++ {
+		store[fmt.Sprintf("name-%d", i)] = outPoint(uint32(i + 1))
+	}
+
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	trie := New(store, repo)
+	defer trie.Close()
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+
+	var reported []progress.Progress
+	trie.SetProgress(func(p progress.Progress) {
+		reported = append(reported, p)
+	})
+	trie.ParallelMerkleHash(4)
+
+	r.NotEmpty(reported)
+	last := reported[len(reported)-1]
+	r.True(last.Done)
+	r.Equal("hash", last.Op)
+	r.Equal(int64(len(store)), last.Names)
+	r.Greater(last.Nodes, int64(len(store)))
+
+	// Nothing is reported once the trie is hashed as usual.
+	reported = nil
+	trie.Update([]byte("name-1"), false)
+	trie.MerkleHash()
+	r.Empty(reported)
+}
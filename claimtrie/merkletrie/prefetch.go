@@ -0,0 +1,136 @@
+package merkletrie
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// prefetcher reads the nodes written by a block ahead of resolving them in the next one, as the names updated
+// by the consecutive blocks are correlated. The nodes are addressed by their hashes, so the ones read are never
+// stale, but they're only needed again, once the vertices resolved are dropped in between.
+type prefetcher struct {
+	limit int
+
+	hints [][]byte // The keys of the nodes committed by the last block.
+
+	mu      sync.RWMutex
+	fetched map[string][]byte
+	stop    chan struct{}
+	done    chan struct{}
+
+	prefetched, hits, misses int64
+}
+
+// PrefetchStats is the number of the nodes prefetched, and of the ones resolved, which were, or weren't, among them.
+type PrefetchStats struct {
+	Prefetched int64
+	Hits       int64
+	Misses     int64
+}
+
+// SetPrefetch enables prefetching up to limit nodes of the ones committed by the last block, at the start of the
+// next one, if the trie was dropped from memory in between. The shallower nodes are preferred over the deeper.
+func (t *MerkleTrie) SetPrefetch(limit int) {
+	t.prefetch = &prefetcher{limit: limit}
+}
+
+// PrefetchStats returns the effect of the prefetching, if it's enabled.
+func (t *MerkleTrie) PrefetchStats() PrefetchStats {
+	p := t.prefetch
+	if p == nil {
+		return PrefetchStats{}
+	}
+	return PrefetchStats{
+		Prefetched: atomic.LoadInt64(&p.prefetched),
+		Hits:       atomic.LoadInt64(&p.hits),
+		Misses:     atomic.LoadInt64(&p.misses),
+	}
+}
+
+// Prefetch starts reading the nodes committed by the last block in the background, if the trie was dropped from
+// memory since, and the prefetching is enabled. The ones read before they're resolved aren't read again.
+func (t *MerkleTrie) Prefetch() {
+
+	p := t.prefetch
+	if p == nil {
+		return
+	}
+	p.wait()
+
+	hints := p.hints
+	p.hints = nil
+	p.mu.Lock()
+	p.fetched = nil
+	p.mu.Unlock()
+	if t.vertices > 0 || len(hints) == 0 {
+		return // the nodes are resolved in memory already
+	}
+
+	p.mu.Lock()
+	p.fetched = make(map[string][]byte, len(hints))
+	p.mu.Unlock()
+	p.stop, p.done = make(chan struct{}), make(chan struct{})
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		for _, key := range hints {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			value, closer, err := t.repo.Get(key)
+			if err != nil {
+				continue // it's resolved as usual
+			}
+			value = append([]byte(nil), value...)
+			closer.Close()
+			p.mu.Lock()
+			p.fetched[string(key)] = value
+			p.mu.Unlock()
+			atomic.AddInt64(&p.prefetched, 1)
+		}
+	}(p.stop, p.done)
+}
+
+// wait stops the prefetching, if it's running.
+func (p *prefetcher) wait() {
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+		p.stop, p.done = nil, nil
+	}
+}
+
+// get returns the node prefetched at the key, if any, and counts the hit, or the miss.
+// p may be nil, if the prefetching is disabled.
+func (p *prefetcher) get(key []byte) ([]byte, bool) {
+
+	if p == nil {
+		return nil, false
+	}
+
+	p.mu.RLock()
+	value, ok := p.fetched[string(key)]
+	active := p.fetched != nil
+	p.mu.RUnlock()
+	if !active {
+		return nil, false // the vertices weren't dropped, so only the ones of the new paths are resolved
+	}
+	if ok {
+		atomic.AddInt64(&p.hits, 1)
+	} else {
+		atomic.AddInt64(&p.misses, 1)
+	}
+
+	return value, ok
+}
+
+// hint sets the keys to prefetch at the start of the next block, the shallowest up to the limit.
+func (p *prefetcher) hint(keys [][]byte) {
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) < len(keys[j]) })
+	if len(keys) > p.limit {
+		keys = keys[:p.limit]
+	}
+	p.hints = keys
+}
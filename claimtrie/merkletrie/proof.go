@@ -0,0 +1,118 @@
+package merkletrie
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/proof"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// The proofs are verified by the proof package, which light clients import without the trie.
+type (
+	ProofChild = proof.Child
+	ProofNode  = proof.Node
+	ProofPair  = proof.Pair
+	Proof      = proof.Proof
+)
+
+// At returns a view of the trie at the root, which shares the repo, for proving names
+// at previous heights. The view must not be updated, or closed.
+func (t *MerkleTrie) At(root *chainhash.Hash) *MerkleTrie {
+	return &MerkleTrie{store: t.store, repo: t.repo, bufs: t.bufs, root: newVertex(root)}
+}
+
+// Prove returns the proof of the name having the best claim at op, which took over
+// at takeoverHeight, against the Merkle Hash of the trie.
+// The trie must have been hashed with MerkleHash; proofs of the hashes computed
+// with MerkleHashAllClaims aren't supported.
+func (t *MerkleTrie) Prove(name []byte, op wire.OutPoint, takeoverHeight int32) (*Proof, error) {
+
+	p := &Proof{HasValue: true, OutPoint: op, TakeoverHeight: takeoverHeight}
+
+	v := t.root
+	for i := 0; ; i++ {
+		if len(v.childLinks) == 0 {
+			t.resolveChildLinks(v, name[:i])
+		}
+
+		var pn ProofNode
+		for _, ch := range keysInOrder(v) {
+			child := v.childLinks[ch]
+			if child.merkleHash == nil {
+				return nil, fmt.Errorf("unhashed child at %q", append(name[:i:i], ch))
+			}
+			c := ProofChild{Character: ch, Hash: child.merkleHash}
+			if i < len(name) && ch == name[i] {
+				c.Hash = nil
+			}
+			pn.Children = append(pn.Children, c)
+		}
+
+		if i == len(name) {
+			if !v.hasValue || v.claimsHash == nil {
+				return nil, fmt.Errorf("no value at %q", name)
+			}
+			if !v.claimsHash.IsEqual(node.CalculateNodeHash(op, takeoverHeight)) {
+				return nil, fmt.Errorf("value at %q doesn't match the claim", name)
+			}
+			p.Nodes = append(p.Nodes, pn)
+			return p, nil
+		}
+
+		if v.hasValue {
+			pn.ValueHash = v.claimsHash
+		}
+		p.Nodes = append(p.Nodes, pn)
+
+		v = v.childLinks[name[i]]
+		if v == nil {
+			return nil, fmt.Errorf("missing child at %q", name[:i+1])
+		}
+	}
+}
+
+// ProveAbsence returns the proof of the name not having a value, against the Merkle Hash of the trie.
+// It leads to the name, or to its longest prefix in the trie. The trie must have been hashed with MerkleHash.
+func (t *MerkleTrie) ProveAbsence(name []byte) (*Proof, error) {
+
+	p := &Proof{}
+
+	v := t.root
+	for i := 0; ; i++ {
+		if len(v.childLinks) == 0 {
+			t.resolveChildLinks(v, name[:i])
+		}
+
+		next := (*vertex)(nil)
+		if i < len(name) {
+			next = v.childLinks[name[i]]
+		}
+
+		var pn ProofNode
+		for _, ch := range keysInOrder(v) {
+			child := v.childLinks[ch]
+			if child.merkleHash == nil {
+				return nil, fmt.Errorf("unhashed child at %q", append(name[:i:i], ch))
+			}
+			c := ProofChild{Character: ch, Hash: child.merkleHash}
+			if next != nil && ch == name[i] {
+				c.Hash = nil
+			}
+			pn.Children = append(pn.Children, c)
+		}
+		if v.hasValue {
+			pn.ValueHash = v.claimsHash
+		}
+		p.Nodes = append(p.Nodes, pn)
+
+		if i == len(name) && pn.ValueHash != nil {
+			return nil, fmt.Errorf("value at %q", name)
+		}
+		if next == nil {
+			return p, nil
+		}
+		v = next
+	}
+}
@@ -0,0 +1,177 @@
+package merkletrie
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore map[string]wire.OutPoint
+
+func (s fakeStore) ClaimHashes(name []byte) []*chainhash.Hash {
+	if h := s.Hash(name); h != nil {
+		return []*chainhash.Hash{h}
+	}
+	return nil
+}
+
+func (s fakeStore) Hash(name []byte) *chainhash.Hash {
+	op, ok := s[string(name)]
+	if !ok {
+		return nil
+	}
+	return node.CalculateNodeHash(op, 1)
+}
+
+func outPoint(i uint32) wire.OutPoint {
+	return wire.OutPoint{Hash: chainhash.HashH([]byte{1, 2, 3}), Index: i}
+}
+
+func TestProofRoundTrip(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{"a": outPoint(1), "ab": outPoint(2), "b": outPoint(3)}
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	trie := New(store, repo)
+	defer trie.Close()
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+	root := trie.MerkleHash()
+	r.NoError(trie.Commit())
+
+	leafB := chainhash.DoubleHashH(store.Hash([]byte("b"))[:])
+	proof := &Proof{
+		Nodes: []ProofNode{
+			{Children: []ProofChild{{Character: 'a'}, {Character: 'b', Hash: &leafB}}},
+			{Children: []ProofChild{{Character: 'b'}}, ValueHash: store.Hash([]byte("a"))},
+			{},
+		},
+		HasValue:       true,
+		OutPoint:       outPoint(2),
+		TakeoverHeight: 1,
+	}
+	r.True(proof.Verify(root, []byte("ab")))
+	r.False(proof.Verify(root, []byte("aa")))
+
+	proved, err := trie.Prove([]byte("ab"), outPoint(2), 1)
+	r.NoError(err)
+	r.Equal(proof, proved)
+
+	resolved := New(store, repo)
+	resolved.SetRoot(root)
+	proved, err = resolved.Prove([]byte("ab"), outPoint(2), 1)
+	r.NoError(err)
+	r.Equal(proof, proved)
+	_, err = resolved.Prove([]byte("ab"), outPoint(3), 1)
+	r.Error(err)
+	_, err = resolved.Prove([]byte("ac"), outPoint(2), 1)
+	r.Error(err)
+	r.False(proof.Verify(&leafB, []byte("ab")))
+
+	b := bytes.NewBuffer(nil)
+	r.NoError(proof.Encode(b))
+	decoded := &Proof{}
+	r.NoError(decoded.Decode(b))
+	r.Equal(proof, decoded)
+	r.True(decoded.Verify(root, []byte("ab")))
+
+	js, err := json.Marshal(proof)
+	r.NoError(err)
+	unmarshalled := &Proof{}
+	r.NoError(json.Unmarshal(js, unmarshalled))
+	r.Equal(proof, unmarshalled)
+
+	decoded.OutPoint.Index = 3
+	r.False(decoded.Verify(root, []byte("ab")))
+}
+
+func TestAbsenceProof(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{"a": outPoint(1), "abc": outPoint(2), "b": outPoint(3)}
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	trie := New(store, repo)
+	defer trie.Close()
+
+	proof, err := trie.ProveAbsence([]byte("a"))
+	r.NoError(err)
+	r.True(proof.VerifyAbsence(EmptyTrieHash, []byte("a")))
+
+	for name := range store {
+		trie.Update([]byte(name), false)
+	}
+	root := trie.MerkleHash()
+
+	// Missing names, ones diverging from the paths, and ones on the paths without values.
+	for _, name := range []string{"c", "ac", "abd", "ab", "abcd", ""} {
+		proof, err := trie.ProveAbsence([]byte(name))
+		r.NoError(err, name)
+		r.True(proof.VerifyAbsence(root, []byte(name)), name)
+	}
+
+	for _, name := range []string{"a", "abc", "b"} {
+		_, err = trie.ProveAbsence([]byte(name))
+		r.Error(err, name)
+	}
+
+	// The proof of a name doesn't prove the absence of the others, nor of the names with the values.
+	proof, err = trie.ProveAbsence([]byte("ac"))
+	r.NoError(err)
+	r.False(proof.VerifyAbsence(root, []byte("ab")))
+	r.False(proof.VerifyAbsence(root, []byte("a")))
+	proof, err = trie.ProveAbsence([]byte("ab"))
+	r.NoError(err)
+	r.False(proof.VerifyAbsence(root, []byte("abc")))
+	valued, err := trie.Prove([]byte("abc"), outPoint(2), 1)
+	r.NoError(err)
+	r.False(valued.VerifyAbsence(root, []byte("abc")))
+}
+
+func TestPairsProofRoundTrip(t *testing.T) {
+
+	r := require.New(t)
+
+	leaf := node.CalculateNodeHash(outPoint(1), 5)
+	sibling := chainhash.HashH([]byte("sibling"))
+	root := hashMerkleBranches(hashMerkleBranches(leaf, &sibling), NoChildrenHash)
+	root = hashMerkleBranches(&sibling, root)
+
+	proof := &Proof{
+		Pairs: []ProofPair{
+			{Odd: false, Hash: sibling},
+			{Odd: false, Hash: *NoChildrenHash},
+			{Odd: true, Hash: sibling},
+		},
+		HasValue:       true,
+		OutPoint:       outPoint(1),
+		TakeoverHeight: 5,
+	}
+	r.True(proof.Verify(root, nil))
+
+	b := bytes.NewBuffer(nil)
+	r.NoError(proof.Encode(b))
+	decoded := &Proof{}
+	r.NoError(decoded.Decode(b))
+	r.Equal(proof, decoded)
+
+	js, err := json.Marshal(proof)
+	r.NoError(err)
+	unmarshalled := &Proof{}
+	r.NoError(json.Unmarshal(js, unmarshalled))
+	r.Equal(proof, unmarshalled)
+
+	proof.Pairs[0].Odd = true
+	r.False(proof.Verify(root, nil))
+}
@@ -0,0 +1,128 @@
+package merkletrie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/cockroachdb/pebble"
+)
+
+// PrunableRepo is implemented by the repos, from which the nodes no longer reachable from the roots retained
+// are deleted by Prune.
+type PrunableRepo interface {
+	// IterateKeys calls fn with each key of the repo, until it returns false. The key is only valid until fn returns.
+	IterateKeys(fn func(key []byte) bool) error
+	// Delete deletes the keys at once.
+	Delete(keys [][]byte) error
+}
+
+// pruneBatchSize is the number of the keys deleted at once by Prune.
+const pruneBatchSize = 10000
+
+// PruneResult is the outcome of pruning the trie repo.
+type PruneResult struct {
+	Roots   int // The roots retained.
+	Kept    int // The nodes reachable from them.
+	Deleted int // The nodes deleted.
+}
+
+// Prune deletes the stored nodes, which aren't reachable from any of the roots, from the repo. As the nodes are
+// addressed by their hashes, the ones of a root superseded are never overwritten, but are shared with the later
+// roots, so they're marked from the roots first, which holds the keys of all the reachable ones in memory.
+// The nodes pending are committed first. It fails, without deleting any, if a node of the roots is missing,
+// or fails its checksum. It mustn't be called concurrently with the hashing, nor with the views reading
+// the roots pruned.
+func (t *MerkleTrie) Prune(roots []*chainhash.Hash) (*PruneResult, error) {
+
+	repo, ok := t.writes.repo.(PrunableRepo)
+	if !ok {
+		return nil, fmt.Errorf("pruning a %T trie repo is not supported", t.writes.repo)
+	}
+
+	err := t.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	res := &PruneResult{}
+	live := map[string]struct{}{}
+	for _, root := range roots {
+		if root == nil || *root == *EmptyTrieHash {
+			continue
+		}
+		res.Roots++
+		err = t.mark(root, live)
+		if err != nil {
+			return nil, fmt.Errorf("mark %s: %w", root, err)
+		}
+	}
+	res.Kept = len(live)
+
+	var stale [][]byte
+	del := func() error {
+		err := repo.Delete(stale)
+		if err != nil {
+			return fmt.Errorf("trie repo delete: %w", err)
+		}
+		res.Deleted += len(stale)
+		stale = stale[:0]
+		return nil
+	}
+	var delErr error
+	err = repo.IterateKeys(func(key []byte) bool {
+		if _, ok := live[string(key)]; ok {
+			return true
+		}
+		stale = append(stale, append([]byte(nil), key...))
+		if len(stale) >= pruneBatchSize {
+			delErr = del()
+		}
+		return delErr == nil
+	})
+	if err == nil {
+		err = delErr
+	}
+	if err == nil && len(stale) > 0 {
+		err = del()
+	}
+
+	return res, err
+}
+
+// mark adds the keys of the stored nodes reachable from the root to live, skipping the subtries marked already.
+func (t *MerkleTrie) mark(root *chainhash.Hash, live map[string]struct{}) error {
+
+	stack := [][]byte{append([]byte(nil), root[:]...)} // the keys of the nodes to mark: their prefixes, and hashes
+	var key []byte
+	for len(stack) > 0 {
+		key, stack = stack[len(stack)-1], stack[:len(stack)-1]
+		if _, ok := live[string(key)]; ok {
+			continue
+		}
+
+		b, closer, err := t.repo.Get(key)
+		if errors.Is(err, pebble.ErrNotFound) {
+			return fmt.Errorf("%w: %q", ErrNodeNotFound, key)
+		}
+		if err != nil {
+			return fmt.Errorf("trie repo get: %w", err)
+		}
+		prefix := key[:len(key)-chainhash.HashSize]
+		nb, ok := nbuf(b).verify()
+		if !ok {
+			closer.Close()
+			return &CorruptNodeError{Key: append([]byte(nil), prefix...)}
+		}
+		for i := 0; i < nb.entries(); i++ {
+			ch, h := nb.entry(i)
+			child := make([]byte, 0, len(prefix)+1+chainhash.HashSize)
+			child = append(append(append(child, prefix...), ch), h[:]...)
+			stack = append(stack, child)
+		}
+		closer.Close()
+		live[string(key)] = struct{}{}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package merkletrie
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+
+	pebbleRepo, err := merkletrierepo.NewPebble(t.TempDir())
+	require.NoError(t, err)
+
+	repos := []struct {
+		name string
+		repo Repo
+	}{{"memory", merkletrierepo.NewMemory()}, {"pebble", pebbleRepo}}
+	for _, tc := range repos {
+		repo := tc.repo
+		t.Run(tc.name, func(t *testing.T) {
+
+			r := require.New(t)
+
+			store := fakeStore{"a": outPoint(1), "ab": outPoint(2), "abc": outPoint(3), "b": outPoint(4)}
+			trie := New(store, repo)
+			defer trie.Close()
+
+			var roots []*chainhash.Hash
+			for i, name := range []string{"", "abc", "b", "ab"} {
+				if name != "" {
+					store[name] = outPoint(uint32(10 + i))
+					trie.Update([]byte(name), true)
+				} else {
+					for name := range store {
+						trie.Update([]byte(name), true)
+					}
+				}
+				roots = append(roots, trie.MerkleHash())
+				r.NoError(trie.Commit())
+			}
+
+			// Keeping all the roots deletes none.
+			res, err := trie.Prune(append(roots, EmptyTrieHash))
+			r.NoError(err)
+			r.Equal(&PruneResult{Roots: 4, Kept: res.Kept}, res)
+
+			// The nodes shared by the last two roots are kept, and the rest of the earlier ones deleted.
+			res, err = trie.Prune(roots[2:])
+			r.NoError(err)
+			r.Equal(2, res.Roots)
+			r.Greater(res.Deleted, 0)
+			for _, root := range roots[2:] {
+				check := trie.At(root).Check(false, func([]byte) {})
+				r.Empty(check.Missing)
+				r.Equal(4, check.Names)
+			}
+			_, err = trie.At(roots[0]).NodeByHash(roots[0])
+			r.ErrorIs(err, ErrNodeNotFound)
+
+			// A root, of which the nodes are missing, fails.
+			_, err = trie.Prune(roots[:1])
+			r.ErrorIs(err, ErrNodeNotFound)
+
+			res, err = trie.Prune(roots[3:])
+			r.NoError(err)
+			r.Greater(res.Deleted, 0)
+			r.Equal(4, trie.At(roots[3]).Check(false, func([]byte) {}).Names)
+		})
+	}
+}
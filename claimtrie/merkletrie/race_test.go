@@ -0,0 +1,7 @@
+//go:build race
+// +build race
+
+package merkletrie
+
+// raceEnabled is set, when the tests are run with the race detector, which allocates on its own.
+const raceEnabled = true
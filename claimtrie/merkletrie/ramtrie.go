@@ -0,0 +1,252 @@
+package merkletrie
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// collapsedVertex is a vertex of a radix tree, whose chain of single-child vertices
+// without values is collapsed into its key.
+type collapsedVertex struct {
+	key        []byte             // The edge from the parent, which is empty only at the root.
+	children   []*collapsedVertex // Sorted by the first byte of their keys.
+	merkleHash *chainhash.Hash    // The hash at the end of the key; nil if it's dirty.
+	claimsHash *chainhash.Hash
+	hasValue   bool
+}
+
+func (v *collapsedVertex) child(ch byte) (int, bool) {
+	i := sort.Search(len(v.children), func(i int) bool { return v.children[i].key[0] >= ch })
+	return i, i < len(v.children) && v.children[i].key[0] == ch
+}
+
+// RamTrie implements the Merkle Hash of the MerkleTrie with a collapsed radix tree,
+// which is kept entirely in memory. It's an independent implementation for verifying
+// the roots of the MerkleTrie, and doesn't support proofs.
+type RamTrie struct {
+	store ValueStore
+	root  *collapsedVertex
+	bufs  *sync.Pool
+
+	// The claims hashes of the vertices are computed for MerkleHashAllClaims.
+	allClaims bool
+}
+
+// NewRamTrie returns an empty RamTrie.
+func NewRamTrie(store ValueStore) *RamTrie {
+	return &RamTrie{
+		store: store,
+		root:  &collapsedVertex{},
+		bufs: &sync.Pool{
+			New: func() interface{} {
+				return new(bytes.Buffer)
+			},
+		},
+	}
+}
+
+// Update marks the name as dirty; its value is read from the store on the next hash.
+// The restoreChildren is only for the compatibility with MerkleTrie.
+func (rt *RamTrie) Update(name []byte, restoreChildren bool) {
+
+	v := rt.root
+	v.merkleHash = nil
+	for len(name) > 0 {
+		i, ok := v.child(name[0])
+		if !ok {
+			c := &collapsedVertex{key: append([]byte(nil), name...)}
+			v.children = append(v.children, nil)
+			copy(v.children[i+1:], v.children[i:])
+			v.children[i] = c
+			v = c
+			break
+		}
+
+		c := v.children[i]
+		l := commonPrefix(c.key, name)
+		if l < len(c.key) { // split the key of the child
+			mid := &collapsedVertex{key: c.key[:l:l], children: []*collapsedVertex{c}}
+			c.key = c.key[l:]
+			v.children[i] = mid
+			c = mid
+		}
+		c.merkleHash = nil
+		name = name[l:]
+		v = c
+	}
+
+	v.hasValue = true
+	v.merkleHash = nil
+	v.claimsHash = nil
+}
+
+func commonPrefix(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// MerkleHash returns the Merkle Hash of the trie, as MerkleTrie.MerkleHash does.
+func (rt *RamTrie) MerkleHash() *chainhash.Hash {
+	rt.switchMode(false)
+	if h := rt.merkle(make([]byte, 0, 256), rt.root); h != nil {
+		return h
+	}
+	return EmptyTrieHash
+}
+
+// MerkleHashAllClaims returns the Merkle Hash of the trie, as MerkleTrie.MerkleHashAllClaims does.
+func (rt *RamTrie) MerkleHashAllClaims() *chainhash.Hash {
+	rt.switchMode(true)
+	if h := rt.merkleAllClaims(make([]byte, 0, 256), rt.root); h != nil {
+		return h
+	}
+	return EmptyTrieHash
+}
+
+// switchMode clears the hashes of all the vertices, if they were computed by the other mode,
+// and marks them as dirty, as Hash and ClaimHashes may disagree on having a value.
+func (rt *RamTrie) switchMode(allClaims bool) {
+
+	if rt.allClaims == allClaims {
+		return
+	}
+	rt.allClaims = allClaims
+
+	var clear func(v *collapsedVertex)
+	clear = func(v *collapsedVertex) {
+		v.merkleHash, v.claimsHash = nil, nil
+		v.hasValue = true
+		for _, c := range v.children {
+			clear(c)
+		}
+	}
+	clear(rt.root)
+}
+
+// compact drops the children without hashes, and merges the child of v into v,
+// if it's the only one, and v has neither a value, nor is the root.
+func (rt *RamTrie) compact(v *collapsedVertex, hashed []*collapsedVertex) {
+
+	v.children = hashed
+	if v == rt.root || v.hasValue || len(hashed) != 1 {
+		return
+	}
+
+	c := hashed[0]
+	v.key = append(v.key[:len(v.key):len(v.key)], c.key...)
+	v.children = c.children
+	v.merkleHash = c.merkleHash
+	v.claimsHash = c.claimsHash
+	v.hasValue = c.hasValue
+}
+
+// merkle returns the hash at the end of the key of v, which is nil without any values below.
+// The chain of the hashes along the key is computed by the parent.
+func (rt *RamTrie) merkle(prefix []byte, v *collapsedVertex) *chainhash.Hash {
+
+	if v.merkleHash != nil {
+		return v.merkleHash
+	}
+
+	b := rt.bufs.Get().(*bytes.Buffer)
+	defer rt.bufs.Put(b)
+	b.Reset()
+
+	hashed := v.children[:0]
+	for _, c := range v.children {
+		h := rt.merkle(append(prefix, c.key...), c)
+		if h == nil {
+			continue
+		}
+		hashed = append(hashed, c)
+
+		h = keyHash(c.key, h)
+		b.WriteByte(c.key[0]) // nolint : errchk
+		b.Write(h[:])         // nolint : errchk
+	}
+
+	if v.hasValue {
+		if v.claimsHash == nil {
+			v.claimsHash = rt.store.Hash(prefix)
+		}
+		if v.claimsHash != nil {
+			b.Write(v.claimsHash[:]) // nolint : errchk
+		} else {
+			v.hasValue = false
+		}
+	}
+
+	if b.Len() > 0 {
+		h := chainhash.DoubleHashH(b.Bytes())
+		v.merkleHash = &h
+	}
+	rt.compact(v, hashed)
+	return v.merkleHash
+}
+
+// keyHash returns the hash at the first byte of the key, of which h is the hash at the end.
+// The implied vertices along the key have a single child each, and no value.
+func keyHash(key []byte, h *chainhash.Hash) *chainhash.Hash {
+	for i := len(key) - 1; i > 0; i-- {
+		var link [1 + chainhash.HashSize]byte
+		link[0] = key[i]
+		copy(link[1:], h[:])
+		hc := chainhash.DoubleHashH(link[:])
+		h = &hc
+	}
+	return h
+}
+
+// merkleAllClaims returns the hash at the end of the key of v, which is nil without
+// any values below. The hashes of the single-child vertices pass up the tree unchanged,
+// so the key doesn't affect it.
+func (rt *RamTrie) merkleAllClaims(prefix []byte, v *collapsedVertex) *chainhash.Hash {
+
+	if v.merkleHash != nil {
+		return v.merkleHash
+	}
+
+	hashed := v.children[:0]
+	childHashes := make([]*chainhash.Hash, 0, len(v.children))
+	for _, c := range v.children {
+		h := rt.merkleAllClaims(append(prefix, c.key...), c)
+		if h == nil {
+			continue
+		}
+		hashed = append(hashed, c)
+		childHashes = append(childHashes, h)
+	}
+
+	if v.hasValue && v.claimsHash == nil {
+		claimHashes := rt.store.ClaimHashes(prefix)
+		if len(claimHashes) > 0 {
+			v.claimsHash = computeMerkleRoot(claimHashes)
+		} else {
+			v.hasValue = false
+		}
+	}
+
+	switch {
+	case len(childHashes) > 1 || v.claimsHash != nil:
+		left := NoChildrenHash
+		if len(childHashes) > 0 {
+			left = computeMerkleRoot(childHashes)
+		}
+		right := NoClaimsHash
+		if v.claimsHash != nil {
+			right = v.claimsHash
+		}
+		v.merkleHash = hashMerkleBranches(left, right)
+	case len(childHashes) == 1:
+		v.merkleHash = childHashes[0]
+	}
+
+	rt.compact(v, hashed)
+	return v.merkleHash
+}
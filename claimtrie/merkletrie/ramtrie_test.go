@@ -0,0 +1,84 @@
+package merkletrie
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRamTrie(t *testing.T) {
+
+	r := require.New(t)
+
+	store := fakeStore{}
+	repo, err := merkletrierepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	trie := New(store, repo)
+	defer trie.Close()
+	rt := NewRamTrie(store)
+
+	r.Equal(EmptyTrieHash, rt.MerkleHash())
+	r.Equal(EmptyTrieHash, rt.MerkleHashAllClaims())
+
+	rnd := rand.New(rand.NewSource(1))
+	alphabet := []byte("abc")
+	var names []string
+	for i := 0; i < 200; i++ {
+		name := make([]byte, 1+rnd.Intn(6))
+		for j := range name {
+			name[j] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		names = append(names, string(name))
+	}
+	all := names
+
+	for round := 0; round < 4; round++ {
+		for i, name := range names {
+			switch {
+			case (i+round)%3 == 0:
+				delete(store, name)
+			default:
+				store[name] = outPoint(uint32(i + round))
+			}
+			trie.Update([]byte(name), true)
+			rt.Update([]byte(name), true)
+		}
+
+		// Alternate the modes. Unlike the RamTrie, the MerkleTrie has to be told to recompute
+		// all the hashes, as the ClaimTrie does at the fork.
+		for _, name := range all {
+			trie.Update([]byte(name), true)
+		}
+		hash := func() {
+			if round%2 == 0 {
+				r.Equal(trie.MerkleHash(), rt.MerkleHash(), "round %d", round)
+			} else {
+				r.Equal(trie.MerkleHashAllClaims(), rt.MerkleHashAllClaims(), "round %d", round)
+			}
+		}
+		hash()
+
+		// And incrementally within the mode.
+		for _, name := range names[:len(names)/8] {
+			name += "a"
+			store[name] = outPoint(uint32(round))
+			trie.Update([]byte(name), true)
+			rt.Update([]byte(name), true)
+			all = append(all, name)
+		}
+		hash()
+		names = names[len(names)/4:]
+	}
+
+	for name := range store {
+		delete(store, name)
+		trie.Update([]byte(name), true)
+		rt.Update([]byte(name), true)
+	}
+	r.Equal(EmptyTrieHash, rt.MerkleHashAllClaims())
+	r.Equal(trie.MerkleHashAllClaims(), rt.MerkleHashAllClaims())
+	r.Empty(rt.root.children)
+}
@@ -10,3 +10,9 @@ type Repo interface {
 	Set(key, value []byte) error
 	Close() error
 }
+
+// BatchRepo is implemented by the repos, which write many values at once, such as in a single batch.
+// The nodes committed by the trie are written with it.
+type BatchRepo interface {
+	SetBatch(keys, values [][]byte) error
+}
@@ -0,0 +1,153 @@
+package merkletrie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// ErrTopOverlap is returned when several tops have the same top-level child, or the empty name.
+var ErrTopOverlap = errors.New("tops overlap")
+
+// Top is the top level of a trie: the hashes of the children of the root, and the claims hash
+// of the empty name, if it has one. The root hash of the tries partitioned by their top-level
+// children is combined from their tops with CombineTops.
+type Top struct {
+	Children map[byte]*chainhash.Hash
+	Value    *chainhash.Hash
+}
+
+// Top hashes the trie, and returns its top level.
+func (t *MerkleTrie) Top(allClaims bool) Top {
+
+	if allClaims {
+		t.MerkleHashAllClaims()
+	} else {
+		t.MerkleHash()
+	}
+	if len(t.root.childLinks) == 0 {
+		t.resolveChildLinks(t.root, nil)
+	}
+
+	top := Top{Children: make(map[byte]*chainhash.Hash, len(t.root.childLinks))}
+	for ch, child := range t.root.childLinks {
+		if child.merkleHash != nil {
+			top.Children[ch] = child.merkleHash
+		}
+	}
+	if t.root.hasValue {
+		top.Value = t.root.claimsHash
+	}
+
+	return top
+}
+
+// Top hashes the RamTrie, and returns its top level, as MerkleTrie.Top does.
+func (rt *RamTrie) Top(allClaims bool) Top {
+
+	if allClaims {
+		rt.MerkleHashAllClaims()
+	} else {
+		rt.MerkleHash()
+	}
+
+	top := Top{Children: make(map[byte]*chainhash.Hash, len(rt.root.children))}
+	for _, c := range rt.root.children {
+		if c.merkleHash == nil {
+			continue
+		}
+		h := c.merkleHash
+		if !allClaims {
+			h = keyHash(c.key, h)
+		}
+		top.Children[c.key[0]] = h
+	}
+	if rt.root.hasValue {
+		top.Value = rt.root.claimsHash
+	}
+
+	return top
+}
+
+// CombineTops returns the root hash of the trie, of which the tops are disjoint parts.
+func CombineTops(tops []Top, allClaims bool) (*chainhash.Hash, error) {
+
+	children := map[byte]*chainhash.Hash{}
+	var value *chainhash.Hash
+	for _, top := range tops {
+		for ch, h := range top.Children {
+			if _, ok := children[ch]; ok {
+				return nil, fmt.Errorf("%w at %q", ErrTopOverlap, ch)
+			}
+			children[ch] = h
+		}
+		if top.Value != nil {
+			if value != nil {
+				return nil, fmt.Errorf("%w at the empty name", ErrTopOverlap)
+			}
+			value = top.Value
+		}
+	}
+
+	var buf [256]byte
+	keys := buf[:0]
+	for ch := 0; ch < 256; ch++ {
+		if children[byte(ch)] != nil {
+			keys = append(keys, byte(ch))
+		}
+	}
+
+	if allClaims {
+		childHashes := make([]*chainhash.Hash, 0, len(keys))
+		for _, ch := range keys {
+			childHashes = append(childHashes, children[ch])
+		}
+		switch {
+		case len(childHashes) > 1 || value != nil:
+			left := NoChildrenHash
+			if len(childHashes) > 0 {
+				left = computeMerkleRoot(childHashes)
+			}
+			right := NoClaimsHash
+			if value != nil {
+				right = value
+			}
+			return hashMerkleBranches(left, right), nil
+		case len(childHashes) == 1:
+			return childHashes[0], nil
+		}
+		return EmptyTrieHash, nil
+	}
+
+	var b bytes.Buffer
+	for _, ch := range keys {
+		b.WriteByte(ch)          // nolint : errchk
+		b.Write(children[ch][:]) // nolint : errchk
+	}
+	if value != nil {
+		b.Write(value[:]) // nolint : errchk
+	}
+	if b.Len() == 0 {
+		return EmptyTrieHash, nil
+	}
+	h := chainhash.DoubleHashH(b.Bytes())
+
+	return &h, nil
+}
+
+// LeafStore is a ValueStore of the claims hashes of the names, of which the first one
+// is the hash of the best claim before the all-claims fork.
+type LeafStore map[string][]*chainhash.Hash
+
+func (s LeafStore) ClaimHashes(name []byte) []*chainhash.Hash {
+	return s[string(name)]
+}
+
+func (s LeafStore) Hash(name []byte) *chainhash.Hash {
+	if hs := s[string(name)]; len(hs) > 0 {
+		return hs[0]
+	}
+	return nil
+}
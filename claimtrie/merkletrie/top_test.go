@@ -0,0 +1,116 @@
+package merkletrie
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/btcsuite/btcd/claimtrie/merkletrie/merkletrierepo"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCombineTops(t *testing.T) {
+
+	r := require.New(t)
+
+	newTrie := func(store fakeStore) *MerkleTrie {
+		repo, err := merkletrierepo.NewPebble(t.TempDir())
+		r.NoError(err)
+		trie := New(store, repo)
+		t.Cleanup(func() { trie.Close() })
+		return trie
+	}
+
+	shardOf := func(name string) int {
+		if name == "" {
+			return 0
+		}
+		return int(name[0] % 2)
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	alphabet := []byte("abcd")
+	store := fakeStore{"": outPoint(0)}
+	shards := []fakeStore{{"": outPoint(0)}, {}}
+	for i := 0; i < 200; i++ {
+		name := make([]byte, 1+rnd.Intn(5))
+		for j := range name {
+			name[j] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		store[string(name)] = outPoint(uint32(i + 1))
+		shards[shardOf(string(name))][string(name)] = outPoint(uint32(i + 1))
+	}
+
+	whole := newTrie(store)
+	rt := NewRamTrie(store)
+	var parts []*MerkleTrie
+	for _, shard := range shards {
+		parts = append(parts, newTrie(shard))
+	}
+	for name := range store {
+		whole.Update([]byte(name), true)
+		rt.Update([]byte(name), true)
+		parts[shardOf(name)].Update([]byte(name), true)
+	}
+
+	for _, allClaims := range []bool{false, true} {
+		if allClaims {
+			for name := range store {
+				whole.Update([]byte(name), true)
+				parts[shardOf(name)].Update([]byte(name), true)
+			}
+		}
+		var tops []Top
+		for _, part := range parts {
+			tops = append(tops, part.Top(allClaims))
+		}
+		root, err := CombineTops(tops, allClaims)
+		r.NoError(err)
+		if allClaims {
+			r.Equal(whole.MerkleHashAllClaims(), root)
+		} else {
+			r.Equal(whole.MerkleHash(), root)
+		}
+		r.Equal(whole.Top(allClaims), rt.Top(allClaims))
+
+		_, err = CombineTops(append(tops, tops[1]), allClaims)
+		r.ErrorIs(err, ErrTopOverlap)
+	}
+
+	// A resolved view has the same top.
+	view := whole.At(whole.MerkleHashAllClaims())
+	r.Equal(whole.Top(true), view.Top(true))
+
+	var names []string
+	view.IterateChild('b', func(name []byte) bool {
+		names = append(names, string(name))
+		return true
+	})
+	r.NotEmpty(names)
+	for i, name := range names {
+		r.Equal(byte('b'), name[0])
+		if i > 0 {
+			r.Less(names[i-1], name)
+		}
+	}
+	r.Len(names, func() int {
+		n := 0
+		for name := range store {
+			if name != "" && name[0] == 'b' {
+				n++
+			}
+		}
+		return n
+	}())
+}
+
+func TestCombineTopsEmpty(t *testing.T) {
+
+	r := require.New(t)
+
+	for _, allClaims := range []bool{false, true} {
+		root, err := CombineTops([]Top{{}, {}}, allClaims)
+		r.NoError(err)
+		r.Equal(EmptyTrieHash, root)
+	}
+}
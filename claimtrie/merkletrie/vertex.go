@@ -1,9 +1,15 @@
 package merkletrie
 
 import (
+	"encoding/binary"
+	"hash/crc32"
+
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 )
 
+// vertexSize is the rough size, in bytes, of a vertex, including its link from the parent.
+const vertexSize = 192
+
 type vertex struct {
 	merkleHash *chainhash.Hash
 	claimsHash *chainhash.Hash
@@ -22,8 +28,36 @@ func newVertex(hash *chainhash.Hash) *vertex {
 //   ...
 //   ch(1B) hash(32B)
 //   vhash(32B)
+//   checksum(4B)
+// The checksum is the CRC-32C of the rest, and is missing from the nodes written before it was introduced.
 type nbuf []byte
 
+// checksumSize is the size of the checksum trailing a node. It's told apart from
+// the value hash by the length, as neither 33k+4, nor 33k+32+4 are 0 or 32 mod 33.
+const checksumSize = 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// appendChecksum appends the checksum of the node to the serialized node.
+func appendChecksum(b []byte) []byte {
+	var sum [checksumSize]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.Checksum(b, castagnoli))
+	return append(b, sum[:]...)
+}
+
+// verify returns the node without the checksum, and false if it doesn't match.
+// The nodes without checksums are returned as they are.
+func (nb nbuf) verify() (nbuf, bool) {
+	switch len(nb) % 33 {
+	case 0, 32:
+		return nb, true
+	case checksumSize, (32 + checksumSize) % 33:
+		body := nb[:len(nb)-checksumSize]
+		return body, crc32.Checksum(body, castagnoli) == binary.BigEndian.Uint32(nb[len(body):])
+	}
+	return nil, false
+}
+
 func (nb nbuf) entries() int {
 	return len(nb) / 33
 }
@@ -0,0 +1,37 @@
+package merkletrie
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeChecksum(t *testing.T) {
+
+	r := require.New(t)
+
+	h := chainhash.HashH([]byte("a"))
+	entry := append([]byte{'a'}, h[:]...)
+	for _, body := range [][]byte{entry, append(entry, h[:]...), h[:]} {
+
+		// The nodes written before the checksums are still read.
+		nb, ok := nbuf(body).verify()
+		r.True(ok)
+		r.Equal(body, []byte(nb))
+
+		stored := appendChecksum(append([]byte(nil), body...))
+		nb, ok = nbuf(stored).verify()
+		r.True(ok)
+		r.Equal(body, []byte(nb))
+
+		flipped := append([]byte(nil), stored...)
+		flipped[0] ^= 1
+		_, ok = nbuf(flipped).verify()
+		r.False(ok)
+
+		_, ok = nbuf(stored[:len(stored)-1]).verify()
+		r.False(ok)
+	}
+}
@@ -0,0 +1,232 @@
+package merkletrie
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxPendingWrites is the size, in bytes, of the nodes buffered, over which they're written early,
+// so that hashing the entire trie, such as at the fork, isn't held in memory.
+const maxPendingWrites = 64 << 20
+
+// nodeWrites buffers the nodes written by the hashing until they're committed, and reads them through.
+// Hashing the same nodes again, such as for a preview of the root before the one committed, writes
+// them once. It's shared by the views of the trie, and the workers hashing it in parallel.
+type nodeWrites struct {
+	repo Repo
+
+	mu      sync.RWMutex
+	pending map[string][]byte
+	size    int
+	err     error // of the first early write, which is returned by the commit
+
+	// The nodes are written early in the background, once over asyncSize bytes, if it's set. The ones being
+	// written are read through, until done reports the write.
+	asyncSize int
+	flushing  map[string][]byte
+	done      chan error
+
+	// The reads of the nodes from the repo, and the writes of them, are timed by timer, if it's set.
+	timer RepoTimer
+}
+
+// RepoTimer is reported the time taken by each read of a node from the repo, or write of the nodes to it.
+type RepoTimer func(write bool, elapsed time.Duration)
+
+func newNodeWrites(repo Repo) *nodeWrites {
+	return &nodeWrites{repo: repo, pending: map[string][]byte{}}
+}
+
+func (w *nodeWrites) Get(key []byte) ([]byte, io.Closer, error) {
+
+	w.mu.RLock()
+	value, ok := w.pending[string(key)]
+	if !ok {
+		value, ok = w.flushing[string(key)]
+	}
+	w.mu.RUnlock()
+	if ok {
+		return value, io.NopCloser(nil), nil
+	}
+	if w.timer == nil {
+		return w.repo.Get(key)
+	}
+
+	start := time.Now()
+	value, closer, err := w.repo.Get(key)
+	w.timer(false, time.Since(start))
+
+	return value, closer, err
+}
+
+// Set buffers the node, unless it's pending already. The nodes are addressed by their hashes,
+// so the ones at the same key are the same.
+func (w *nodeWrites) Set(key, value []byte) error {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.pending[string(key)]; ok {
+		return nil
+	}
+	if _, ok := w.flushing[string(key)]; ok {
+		return nil
+	}
+	w.pending[string(key)] = append([]byte(nil), value...) // the hashing reuses its buffers
+	w.size += len(key) + len(value)
+	if w.err != nil {
+		return nil
+	}
+	if w.asyncSize > 0 && w.size > w.asyncSize {
+		w.flushEarly()
+	} else if w.size > maxPendingWrites {
+		w.err = w.write()
+	}
+
+	return nil
+}
+
+// flushEarly writes the nodes pending in the background, once the last ones are written, unless they're over
+// maxPendingWrites, which waits for them. It must be called with mu held.
+func (w *nodeWrites) flushEarly() {
+
+	if w.done != nil {
+		select {
+		case err := <-w.done:
+			w.flushed(err)
+		default:
+			if w.size <= maxPendingWrites {
+				return
+			}
+			w.flushed(<-w.done)
+		}
+		if w.err != nil {
+			return
+		}
+	}
+
+	w.flushing, w.pending, w.size = w.pending, map[string][]byte{}, 0
+	w.done = make(chan error, 1)
+	go func(nodes map[string][]byte, done chan<- error) {
+		done <- w.writeNodes(nodes) // the nodes aren't written to anymore
+	}(w.flushing, w.done)
+}
+
+// flushed records the result of the write in the background. It must be called with mu held.
+func (w *nodeWrites) flushed(err error) {
+	w.flushing, w.done = nil, nil
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// commit writes the nodes pending to the repo.
+func (w *nodeWrites) commit() error {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done != nil {
+		w.flushed(<-w.done)
+	}
+	err := w.err
+	w.err = nil
+	if err == nil {
+		err = w.write()
+	}
+
+	return err
+}
+
+// write writes the nodes pending to the repo. It must be called with mu held.
+func (w *nodeWrites) write() error {
+
+	err := w.writeNodes(w.pending)
+	if err != nil {
+		return err
+	}
+	w.pending = map[string][]byte{}
+	w.size = 0
+
+	return nil
+}
+
+// writeNodes writes the nodes to the repo, timed, if the timer is set.
+func (w *nodeWrites) writeNodes(nodes map[string][]byte) error {
+
+	if w.timer == nil || len(nodes) == 0 {
+		return writeNodes(w.repo, nodes)
+	}
+
+	start := time.Now()
+	err := writeNodes(w.repo, nodes)
+	w.timer(true, time.Since(start))
+
+	return err
+}
+
+// writeNodes writes the nodes to the repo, at once, if it's a BatchRepo.
+func writeNodes(repo Repo, nodes map[string][]byte) error {
+
+	if br, ok := repo.(BatchRepo); ok {
+		keys := make([][]byte, 0, len(nodes))
+		values := make([][]byte, 0, len(nodes))
+		for key, value := range nodes {
+			keys = append(keys, []byte(key))
+			values = append(values, value)
+		}
+		err := br.SetBatch(keys, values)
+		if err != nil {
+			return fmt.Errorf("trie repo set batch: %w", err)
+		}
+		return nil
+	}
+
+	for key, value := range nodes {
+		err := repo.Set([]byte(key), value)
+		if err != nil {
+			return fmt.Errorf("trie repo set: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// keys returns the keys of the nodes pending.
+func (w *nodeWrites) keys() [][]byte {
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	keys := make([][]byte, 0, len(w.pending)+len(w.flushing))
+	for key := range w.pending {
+		keys = append(keys, []byte(key))
+	}
+	for key := range w.flushing {
+		keys = append(keys, []byte(key))
+	}
+
+	return keys
+}
+
+// SetAsyncWrites writes the nodes hashed to the repo in the background, once they're over size bytes, while the
+// hashing goes on. All of them are written by the Commit still. It's disabled, if size is 0.
+func (t *MerkleTrie) SetAsyncWrites(size int) {
+
+	t.writes.mu.Lock()
+	defer t.writes.mu.Unlock()
+
+	t.writes.asyncSize = size
+}
+
+// SetRepoTimer reports the time taken by the reads of the nodes from the repo, and the writes of them, to timer.
+// It must be called before the trie is used.
+func (t *MerkleTrie) SetRepoTimer(timer RepoTimer) {
+	t.writes.timer = timer
+}
+
+func (w *nodeWrites) Close() error {
+	return w.repo.Close()
+}
@@ -0,0 +1,147 @@
+package claimtrie
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/metrics"
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// ErrMetricsDisabled is returned for the metrics, unless they're recorded, with cfg.Metrics.
+var ErrMetricsDisabled = errors.New("metrics disabled")
+
+// The labels of the changes, by their types.
+var changeTypes = map[change.ChangeType]string{
+	change.AddClaim:     "add_claim",
+	change.SpendClaim:   "spend_claim",
+	change.UpdateClaim:  "update_claim",
+	change.AddSupport:   "add_support",
+	change.SpendSupport: "spend_support",
+}
+
+// blockMetrics are the metrics of the blocks appended, and the repos they're read from, and written to.
+type blockMetrics struct {
+	registry *metrics.Registry
+
+	blocks  *metrics.Counter
+	changes *metrics.CounterVec
+	names   *metrics.Counter
+	stages  *metrics.HistogramVec
+	repos   *metrics.HistogramVec
+}
+
+func newBlockMetrics() *blockMetrics {
+
+	reg := metrics.NewRegistry()
+	m := &blockMetrics{
+		registry: reg,
+		blocks:   reg.Counter("claimtrie_blocks_total", "Blocks appended."),
+		changes:  reg.CounterVec("claimtrie_changes_total", "Changes applied by the blocks, by type.", "type"),
+		names: reg.Counter("claimtrie_nodes_resolved_total",
+			"Nodes of the names resolved by the blocks, and hashed: the ones updated, activated, or expired."),
+		stages: reg.HistogramVec("claimtrie_stage_seconds",
+			"Time taken by the stages of appending the blocks: block, nodes, trie, and hash, the Merkle hash.",
+			metrics.DefaultBuckets, "stage"),
+		repos: reg.HistogramVec("claimtrie_repo_seconds",
+			"Latency of the reads of the node and trie repos, and the writes to them.", metrics.DefaultBuckets, "repo", "op"),
+	}
+
+	return m
+}
+
+// registerStats registers the gauges, and the counters, of the Stats of ct, taken after each block.
+func (m *blockMetrics) registerStats(ct *ClaimTrie) {
+
+	reg := m.registry
+	stat := func(fn func(s Stats) float64) func() float64 {
+		return func() float64 { return fn(ct.Stats()) }
+	}
+	ratio := func(hits, misses int64) float64 {
+		if hits+misses == 0 {
+			return 0
+		}
+		return float64(hits) / float64(hits+misses)
+	}
+
+	reg.GaugeFunc("claimtrie_height", "Height of the last block appended.",
+		stat(func(s Stats) float64 { return float64(s.Height) }))
+	reg.GaugeFunc("claimtrie_node_cache_bytes", "Estimated size of the nodes cached.",
+		stat(func(s Stats) float64 { return float64(s.NodeCacheBytes) }))
+	reg.GaugeFunc("claimtrie_trie_cache_bytes", "Estimated size of the trie nodes resolved in memory.",
+		stat(func(s Stats) float64 { return float64(s.TrieCacheBytes) }))
+	reg.CounterFunc("claimtrie_value_cache_misses_total", "Leaf hashes read through from the nodes by the value cache.",
+		stat(func(s Stats) float64 { return float64(s.ValueCacheMisses) }))
+	reg.CounterFunc("claimtrie_trie_node_cache_hits_total", "Trie nodes resolved from the trie node cache.",
+		stat(func(s Stats) float64 { return float64(s.TrieNodeCacheHits) }))
+	reg.CounterFunc("claimtrie_trie_node_cache_misses_total", "Trie nodes resolved, which weren't in the trie node cache.",
+		stat(func(s Stats) float64 { return float64(s.TrieNodeCacheMisses) }))
+	reg.GaugeFunc("claimtrie_trie_node_cache_hit_ratio", "Ratio of the trie nodes resolved from the trie node cache.",
+		stat(func(s Stats) float64 { return ratio(s.TrieNodeCacheHits, s.TrieNodeCacheMisses) }))
+	reg.CounterFunc("claimtrie_prefetch_hits_total", "Trie nodes resolved, which were prefetched.",
+		stat(func(s Stats) float64 { return float64(s.PrefetchHits) }))
+	reg.CounterFunc("claimtrie_prefetch_misses_total", "Trie nodes resolved, which weren't prefetched.",
+		stat(func(s Stats) float64 { return float64(s.PrefetchMisses) }))
+	reg.GaugeFunc("claimtrie_prefetch_hit_ratio", "Ratio of the trie nodes resolved, which were prefetched.",
+		stat(func(s Stats) float64 { return ratio(s.PrefetchHits, s.PrefetchMisses) }))
+}
+
+// recordBlock counts the block appended, with its changes, and the names of the nodes updated by it.
+func (m *blockMetrics) recordBlock(changes []change.Change, names int) {
+	m.blocks.Inc()
+	for i := range changes {
+		m.changes.With(changeTypes[changes[i].Type]).Inc()
+	}
+	m.names.Add(uint64(names))
+}
+
+// timeTrieRepo is the merkletrie.RepoTimer of the trie repo.
+func (m *blockMetrics) timeTrieRepo(write bool, elapsed time.Duration) {
+	op := "read"
+	if write {
+		op = "write"
+	}
+	m.repos.With("trie", op).Observe(elapsed.Seconds())
+}
+
+// timedNodeRepo times the loads of the changes from the node repo, and the appends to it.
+type timedNodeRepo struct {
+	node.Repo
+	read  *metrics.Histogram
+	write *metrics.Histogram
+}
+
+func (m *blockMetrics) timeNodeRepo(repo node.Repo) node.Repo {
+	return &timedNodeRepo{Repo: repo, read: m.repos.With("node", "read"), write: m.repos.With("node", "write")}
+}
+
+func (r *timedNodeRepo) LoadChanges(name []byte) ([]change.Change, error) {
+	defer r.read.ObserveSince(time.Now())
+	return r.Repo.LoadChanges(name)
+}
+
+func (r *timedNodeRepo) AppendChanges(changes []change.Change) error {
+	defer r.write.ObserveSince(time.Now())
+	return r.Repo.AppendChanges(changes)
+}
+
+// WriteMetrics writes the metrics in the Prometheus text exposition format.
+// It may be called while blocks are being appended.
+func (ct *ClaimTrie) WriteMetrics(w io.Writer) error {
+	if ct.metrics == nil {
+		return ErrMetricsDisabled
+	}
+	return ct.metrics.registry.WriteText(w)
+}
+
+// MetricsHandler serves the metrics in the Prometheus text exposition format, such as at /metrics,
+// or a 404, unless they're recorded.
+func (ct *ClaimTrie) MetricsHandler() http.Handler {
+	if ct.metrics == nil {
+		return http.NotFoundHandler()
+	}
+	return ct.metrics.registry.Handler()
+}
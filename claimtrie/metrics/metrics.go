@@ -0,0 +1,333 @@
+// Package metrics records the counters, and the histograms, of the ClaimTrie, and writes them in the
+// Prometheus text exposition format, so that they can be scraped without a client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the buckets of the durations: from 10µs to 10s.
+var DefaultBuckets = []float64{.00001, .0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5, 10}
+
+// Registry holds the metrics, which are written in the order of their names.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+type metric interface {
+	write(w *bufio.Writer, name string)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{metrics: map[string]metric{}}
+}
+
+func (r *Registry) register(name, help, typ string, m metric) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.metrics[name]; ok {
+		panic(fmt.Sprintf("metric registered twice: %s", name))
+	}
+	r.metrics[name] = &described{help: help, typ: typ, metric: m}
+}
+
+type described struct {
+	help string
+	typ  string
+	metric
+}
+
+func (d *described) write(w *bufio.Writer, name string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.ReplaceAll(d.help, "\n", " "))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, d.typ)
+	d.metric.write(w, name)
+}
+
+// Counter is a value, which only goes up.
+type Counter struct {
+	v uint64
+}
+
+func (c *Counter) Add(n uint64) {
+	atomic.AddUint64(&c.v, n)
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.v)
+}
+
+func (c *Counter) write(w *bufio.Writer, name string) {
+	writeSample(w, name, "", c.Value())
+}
+
+// Counter registers a counter.
+func (r *Registry) Counter(name, help string) *Counter {
+	c := &Counter{}
+	r.register(name, help, "counter", c)
+	return c
+}
+
+type funcMetric func() float64
+
+func (f funcMetric) write(w *bufio.Writer, name string) {
+	writeSample(w, name, "", f())
+}
+
+// CounterFunc registers a counter, of which the value is read from fn on each write, such as the
+// ones counted elsewhere.
+func (r *Registry) CounterFunc(name, help string, fn func() float64) {
+	r.register(name, help, "counter", funcMetric(fn))
+}
+
+// GaugeFunc registers a gauge, of which the value is read from fn on each write.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.register(name, help, "gauge", funcMetric(fn))
+}
+
+// Histogram counts the values observed in buckets of their upper bounds, along with their sum.
+type Histogram struct {
+	bounds []float64
+	counts []uint64 // of the values in each bucket, not cumulative, and above the last one.
+	sum    uint64   // bits of the float64.
+}
+
+func newHistogram(bounds []float64) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *Histogram) Observe(v float64) {
+
+	i := sort.SearchFloat64s(h.bounds, v)
+	atomic.AddUint64(&h.counts[i], 1)
+	for {
+		old := atomic.LoadUint64(&h.sum)
+		sum := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&h.sum, old, sum) {
+			return
+		}
+	}
+}
+
+// ObserveSince observes the seconds elapsed since start.
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// Count returns the number of the values observed.
+func (h *Histogram) Count() uint64 {
+	var n uint64
+	for i := range h.counts {
+		n += atomic.LoadUint64(&h.counts[i])
+	}
+	return n
+}
+
+func (h *Histogram) write(w *bufio.Writer, name string) {
+	h.writeLabeled(w, name, "")
+}
+
+func (h *Histogram) writeLabeled(w *bufio.Writer, name, labels string) {
+
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	var n uint64
+	for i, bound := range h.bounds {
+		n += atomic.LoadUint64(&h.counts[i])
+		writeSample(w, name+"_bucket", labels+sep+`le="`+formatFloat(bound)+`"`, n)
+	}
+	n += atomic.LoadUint64(&h.counts[len(h.bounds)])
+	writeSample(w, name+"_bucket", labels+sep+`le="+Inf"`, n)
+	writeSample(w, name+"_sum", labels, math.Float64frombits(atomic.LoadUint64(&h.sum)))
+	writeSample(w, name+"_count", labels, n)
+}
+
+// Histogram registers a histogram with the buckets of the upper bounds, which must be sorted.
+func (r *Registry) Histogram(name, help string, bounds []float64) *Histogram {
+	h := newHistogram(bounds)
+	r.register(name, help, "histogram", h)
+	return h
+}
+
+// vec holds the metrics of a family by the values of its labels.
+type vec struct {
+	labels []string
+	create func() metric
+
+	mu      sync.RWMutex
+	metrics map[string]metric
+	values  map[string][]string
+}
+
+func (v *vec) with(values []string) metric {
+
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("%d label values for %d labels", len(values), len(v.labels)))
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	m, ok := v.metrics[key]
+	v.mu.RUnlock()
+	if ok {
+		return m
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if m, ok = v.metrics[key]; !ok {
+		m = v.create()
+		v.metrics[key] = m
+		v.values[key] = append([]string(nil), values...)
+	}
+
+	return m
+}
+
+func (v *vec) write(w *bufio.Writer, name string) {
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	keys := make([]string, 0, len(v.metrics))
+	for key := range v.metrics {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		pairs := make([]string, len(v.labels))
+		for i, label := range v.labels {
+			pairs[i] = label + `="` + escapeLabel(v.values[key][i]) + `"`
+		}
+		labels := strings.Join(pairs, ",")
+		switch m := v.metrics[key].(type) {
+		case *Counter:
+			writeSample(w, name, labels, m.Value())
+		case *Histogram:
+			m.writeLabeled(w, name, labels)
+		}
+	}
+}
+
+// CounterVec is a family of counters, one for each of the values of its labels.
+type CounterVec struct {
+	v *vec
+}
+
+// With returns the counter of the values of the labels, in their order.
+func (c *CounterVec) With(values ...string) *Counter {
+	return c.v.with(values).(*Counter)
+}
+
+// CounterVec registers a family of counters with the labels.
+func (r *Registry) CounterVec(name, help string, labels ...string) *CounterVec {
+	v := &vec{labels: labels, metrics: map[string]metric{}, values: map[string][]string{},
+		create: func() metric { return &Counter{} }}
+	r.register(name, help, "counter", v)
+	return &CounterVec{v: v}
+}
+
+// HistogramVec is a family of histograms, one for each of the values of its labels.
+type HistogramVec struct {
+	v *vec
+}
+
+// With returns the histogram of the values of the labels, in their order.
+func (h *HistogramVec) With(values ...string) *Histogram {
+	return h.v.with(values).(*Histogram)
+}
+
+// HistogramVec registers a family of histograms with the buckets of the upper bounds, and the labels.
+func (r *Registry) HistogramVec(name, help string, bounds []float64, labels ...string) *HistogramVec {
+	v := &vec{labels: labels, metrics: map[string]metric{}, values: map[string][]string{},
+		create: func() metric { return newHistogram(bounds) }}
+	r.register(name, help, "histogram", v)
+	return &HistogramVec{v: v}
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metrics := make([]metric, len(names))
+	for i, name := range names {
+		metrics[i] = r.metrics[name]
+	}
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for i, name := range names {
+		metrics[i].write(bw, name)
+	}
+
+	return bw.Flush()
+}
+
+// ContentType is the one of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler serves the metrics, such as at /metrics, to the scrapers.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteText(w) // nolint : errchk
+	})
+}
+
+func writeSample(w *bufio.Writer, name, labels string, v interface{}) {
+
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteByte(' ')
+	switch v := v.(type) {
+	case uint64:
+		w.WriteString(strconv.FormatUint(v, 10))
+	case float64:
+		w.WriteString(formatFloat(v))
+	}
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteText(t *testing.T) {
+
+	r := require.New(t)
+
+	reg := NewRegistry()
+	blocks := reg.Counter("test_blocks_total", "Blocks appended.")
+	changes := reg.CounterVec("test_changes_total", "Changes applied.", "type")
+	latency := reg.HistogramVec("test_repo_seconds", "Repo latency.", []float64{.1, 1}, "repo", "op")
+	duration := reg.Histogram("test_block_seconds", "Block duration.", []float64{.1, 1})
+	reg.GaugeFunc("test_hit_ratio", "Hit ratio.", func() float64 { return .5 })
+
+	blocks.Add(3)
+	changes.With("support").Inc()
+	changes.With("claim").Add(2)
+	changes.With("claim").Inc()
+	latency.With("trie", "read").Observe(.05)
+	latency.With("node", `"x"`).Observe(2)
+	duration.Observe(.05)
+	duration.Observe(.5)
+	duration.Observe(.5)
+	duration.Observe(5)
+	r.Equal(uint64(4), duration.Count())
+
+	var b bytes.Buffer
+	r.NoError(reg.WriteText(&b))
+	r.Equal(`# HELP test_block_seconds Block duration.
+# TYPE test_block_seconds histogram
+test_block_seconds_bucket{le="0.1"} 1
+test_block_seconds_bucket{le="1"} 3
+test_block_seconds_bucket{le="+Inf"} 4
+test_block_seconds_sum 6.05
+test_block_seconds_count 4
+# HELP test_blocks_total Blocks appended.
+# TYPE test_blocks_total counter
+test_blocks_total 3
+# HELP test_changes_total Changes applied.
+# TYPE test_changes_total counter
+test_changes_total{type="claim"} 3
+test_changes_total{type="support"} 1
+# HELP test_hit_ratio Hit ratio.
+# TYPE test_hit_ratio gauge
+test_hit_ratio 0.5
+# HELP test_repo_seconds Repo latency.
+# TYPE test_repo_seconds histogram
+test_repo_seconds_bucket{repo="node",op="\"x\"",le="0.1"} 0
+test_repo_seconds_bucket{repo="node",op="\"x\"",le="1"} 0
+test_repo_seconds_bucket{repo="node",op="\"x\"",le="+Inf"} 1
+test_repo_seconds_sum{repo="node",op="\"x\""} 2
+test_repo_seconds_count{repo="node",op="\"x\""} 1
+test_repo_seconds_bucket{repo="trie",op="read",le="0.1"} 1
+test_repo_seconds_bucket{repo="trie",op="read",le="1"} 1
+test_repo_seconds_bucket{repo="trie",op="read",le="+Inf"} 1
+test_repo_seconds_sum{repo="trie",op="read"} 0.05
+test_repo_seconds_count{repo="trie",op="read"} 1
+`, b.String())
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	r.Equal(ContentType, rec.Header().Get("Content-Type"))
+	r.Equal(b.String(), rec.Body.String())
+
+	r.Panics(func() { reg.Counter("test_blocks_total", "Again.") })
+}
@@ -0,0 +1,105 @@
+package claimtrie
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// nameLocks serializes the external coordinators and the block processing per name.
+type nameLocks struct {
+	mu    sync.Mutex
+	locks map[string]*nameLock
+}
+
+type nameLock struct {
+	mu   sync.Mutex
+	refs int // The holder and the waiters, so it's dropped once there's none.
+}
+
+func newNameLocks() *nameLocks {
+	return &nameLocks{locks: map[string]*nameLock{}}
+}
+
+func (l *nameLocks) lock(key string) {
+
+	l.mu.Lock()
+	nl := l.locks[key]
+	if nl == nil {
+		nl = &nameLock{}
+		l.locks[key] = nl
+	}
+	nl.refs++
+	l.mu.Unlock()
+
+	nl.mu.Lock()
+}
+
+func (l *nameLocks) unlock(key string) {
+
+	l.mu.Lock()
+	nl := l.locks[key]
+	nl.refs--
+	if nl.refs == 0 {
+		delete(l.locks, key)
+	}
+	l.mu.Unlock()
+
+	nl.mu.Unlock()
+}
+
+// lockAll locks the names in order, which rules out deadlocks among the holders of several,
+// and returns the func unlocking them.
+func (l *nameLocks) lockAll(names [][]byte, height int32) func() {
+
+	keys := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := lockKey(name, height)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		l.lock(key)
+	}
+
+	return func() {
+		for _, key := range keys {
+			l.unlock(key)
+		}
+	}
+}
+
+// lockKey returns the key of the name as of the block at the height,
+// so the names folded together by the normalization share a lock.
+func lockKey(name []byte, height int32) string {
+	return string(node.NormalizeIfNecessary(name, height))
+}
+
+// WithNameLock calls fn with the node of the name, or nil if there's none, as of the last block
+// appended, and its height. No block updating the name is appended, or reset, until fn returns,
+// while the other blocks aren't held up. fn must not lock other names, or append or reset blocks,
+// which can deadlock.
+func (ct *ClaimTrie) WithNameLock(name []byte, fn func(n *node.Node, height int32) error) error {
+
+	height := atomic.LoadInt32(&ct.tip)
+	key := lockKey(name, height+1)
+	ct.nameLocks.lock(key)
+	defer ct.nameLocks.unlock(key)
+
+	// The tip may have moved on with the blocks not updating the name while waiting,
+	// but the name is as of the latest.
+	height = atomic.LoadInt32(&ct.tip)
+	n, err := ct.nodeManager.NodeAt(height, node.NormalizeIfNecessary(name, height))
+	if err != nil {
+		return err
+	}
+
+	return fn(n, height)
+}
@@ -0,0 +1,41 @@
+package claimtrie
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// setParams sets the params of cfg.Network, if it's set, overridden by cfg.ParamOverrides.
+func setParams(cfg config.Config) error {
+
+	if cfg.Network == "" {
+		if len(cfg.ParamOverrides) > 0 {
+			return fmt.Errorf("the param overrides require the network they override")
+		}
+		return nil
+	}
+
+	var overrides []param.Override
+	for _, s := range cfg.ParamOverrides {
+		o, err := param.ParseOverride(s)
+		if err != nil {
+			return err
+		}
+		if o.Height > 0 {
+			return fmt.Errorf("param override %q: the overrides from a height on apply to the replays only", s)
+		}
+		overrides = append(overrides, o)
+	}
+
+	err := param.SetNetworkByName(cfg.Network)
+	if err != nil {
+		return err
+	}
+	for _, o := range overrides {
+		o.Apply()
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package node
+
+import (
+	"container/list"
+)
+
+// Rough sizes, in bytes, of the materialized nodes and claims, excluding their values.
+const (
+	nodeOverhead  = 128
+	claimOverhead = 160
+)
+
+// nodeCache is a cache of the materialized nodes, which tracks their estimated sizes,
+// and evicts the least recently used ones on demand.
+type nodeCache struct {
+	entries map[string]*list.Element
+	order   *list.List // most recently used at the front.
+	size    int64
+}
+
+type cacheEntry struct {
+	name string
+	node *Node
+	size int64
+}
+
+func newNodeCache() *nodeCache {
+	return &nodeCache{entries: map[string]*list.Element{}, order: list.New()}
+}
+
+func (c *nodeCache) get(name string) (*Node, bool) {
+	e, ok := c.entries[name]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).node, true
+}
+
+func (c *nodeCache) put(name string, n *Node) {
+	c.delete(name)
+	entry := &cacheEntry{name: name, node: n, size: estimateSize(name, n)}
+	c.entries[name] = c.order.PushFront(entry)
+	c.size += entry.size
+}
+
+func (c *nodeCache) delete(name string) {
+	e, ok := c.entries[name]
+	if !ok {
+		return
+	}
+	c.remove(e)
+}
+
+func (c *nodeCache) remove(e *list.Element) {
+	entry := c.order.Remove(e).(*cacheEntry)
+	delete(c.entries, entry.name)
+	c.size -= entry.size
+}
+
+func (c *nodeCache) len() int {
+	return len(c.entries)
+}
+
+func (c *nodeCache) clear() {
+	c.entries = map[string]*list.Element{}
+	c.order.Init()
+	c.size = 0
+}
+
+// shrink evicts the least recently used nodes until the size is within target,
+// and returns the number of the nodes evicted.
+func (c *nodeCache) shrink(target int64) int {
+	evicted := 0
+	for c.size > target && c.order.Len() > 0 {
+		c.remove(c.order.Back())
+		evicted++
+	}
+	return evicted
+}
+
+func estimateSize(name string, n *Node) int64 {
+	size := int64(nodeOverhead + len(name))
+	for _, claims := range []ClaimList{n.Claims, n.Supports} {
+		for _, c := range claims {
+			size += int64(claimOverhead + len(c.Value))
+		}
+	}
+	return size
+}
@@ -3,10 +3,10 @@ package node
 import (
 	"bytes"
 	"encoding/binary"
-	"encoding/hex"
 	"strconv"
 	"strings"
 
+	"github.com/btcsuite/btcd/claimtrie/change"
 	"github.com/btcsuite/btcd/claimtrie/param"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
@@ -15,7 +15,7 @@ import (
 )
 
 // ClaimID represents a Claim's ClaimID.
-type ClaimID [20]byte
+type ClaimID = change.ClaimID
 
 // NewClaimID returns a Claim ID caclculated from Ripemd160(Sha256(OUTPOINT).
 func NewClaimID(op wire.OutPoint) ClaimID {
@@ -32,23 +32,20 @@ func NewClaimID(op wire.OutPoint) ClaimID {
 
 // NewIDFromString returns a Claim ID from a string.
 func NewIDFromString(s string) (ClaimID, error) {
-
-	var id ClaimID
-	_, err := hex.Decode(id[:], []byte(s))
-	for i, j := 0, len(id)-1; i < j; i, j = i+1, j-1 {
-		id[i], id[j] = id[j], id[i]
-	}
-
-	return id, err
+	return change.NewIDFromString(s)
 }
 
-func (id ClaimID) String() string {
+// SigningChannel returns the ClaimID of the channel, which signed the claim value.
+// The signed format is: version(1B) = 1, channel(20B), signature(64B), payload.
+func SigningChannel(value []byte) (ClaimID, bool) {
 
-	for i, j := 0, len(id)-1; i < j; i, j = i+1, j-1 {
-		id[i], id[j] = id[j], id[i]
+	var id ClaimID
+	if len(value) < 1+len(id)+64 || value[0] != 1 {
+		return id, false
 	}
+	copy(id[:], value[1:])
 
-	return hex.EncodeToString(id[:])
+	return id, true
 }
 
 type Status int
@@ -59,16 +56,30 @@ const (
 	Deactivated
 )
 
+func (s Status) String() string {
+	switch s {
+	case Accepted:
+		return "accepted"
+	case Activated:
+		return "activated"
+	case Deactivated:
+		return "deactivated"
+	}
+	return "status(" + strconv.Itoa(int(s)) + ")"
+}
+
 // Claim defines a structure of stake, which could be a Claim or Support.
 type Claim struct {
 	OutPoint   wire.OutPoint
-	ClaimID    string
+	ClaimID    ClaimID
 	Amount     int64
 	AcceptedAt int32 // when arrived (aka, originally landed in block)
 	ActiveAt   int32 // AcceptedAt + actual delay
 	Status     Status
 	Value      []byte
 	VisibleAt  int32
+
+	sortKey SortKey // Valid while the keys of the node are.
 }
 
 func (c *Claim) setOutPoint(op wire.OutPoint) *Claim {
@@ -101,6 +112,7 @@ func (c *Claim) setStatus(status Status) *Claim {
 	return c
 }
 
+// EffectiveAmount returns the amount of the claim, including the ones of its activated supports, if it's activated.
 func (c *Claim) EffectiveAmount(supports ClaimList) int64 {
 
 	if c.Status != Activated {
@@ -110,7 +122,7 @@ func (c *Claim) EffectiveAmount(supports ClaimList) int64 {
 	amt := c.Amount
 
 	for _, s := range supports {
-		if s.Status == Activated && s.ClaimID == c.ClaimID { // TODO: this comparison is hit a lot; byte comparison instead of hex would be faster
+		if s.Status == Activated && s.ClaimID == c.ClaimID {
 			amt += s.Amount
 		}
 	}
@@ -127,6 +139,28 @@ func (c *Claim) ExpireAt() int32 {
 	return c.AcceptedAt + param.OriginalClaimExpirationTime
 }
 
+// Maturity is the depth of a claim, or a support, as of a height.
+type Maturity struct {
+	Confirmations      int32 // The block it was accepted in, and the ones since.
+	BlocksUntilActive  int32 // 0 once it's activated.
+	ExpireAt           int32 // As of the expiration time of the forks.
+	BlocksUntilExpired int32 // 0 once it's expired.
+}
+
+// MaturityAt returns the maturity of the claim as of the height of the node it was taken from.
+func (c *Claim) MaturityAt(height int32) Maturity {
+
+	m := Maturity{Confirmations: height - c.AcceptedAt + 1, ExpireAt: c.ExpireAt()}
+	if c.Status == Accepted && c.ActiveAt > height {
+		m.BlocksUntilActive = c.ActiveAt - height
+	}
+	if m.ExpireAt > height {
+		m.BlocksUntilExpired = m.ExpireAt - height
+	}
+
+	return m
+}
+
 func OutPointLess(a, b wire.OutPoint) bool {
 
 	switch cmp := bytes.Compare(a.Hash[:], b.Hash[:]); {
@@ -0,0 +1,104 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// Collision is a set of names, which merge into one node at the normalization fork.
+type Collision struct {
+	Name   []byte   // The normalized name.
+	Names  [][]byte // The names merging, which have claims before the fork.
+	Winner *Claim   // The best claim of the merged node at the fork, if any.
+	Losers []Loser
+
+	EffectiveAmount int64 // Of the winner, at the fork.
+}
+
+// Loser is the best claim of a name before the normalization fork, which loses the control of it.
+type Loser struct {
+	Name            []byte
+	Claim           *Claim
+	EffectiveAmount int64 // Right before the fork.
+}
+
+// NormalizationCollisions returns the collisions at the normalization fork, sorted by their names.
+// The changes of the manager must reach past the fork, as the merged nodes are rebuilt from them.
+func NormalizationCollisions(nm Manager) ([]Collision, error) {
+
+	fork := param.NormalizedNameForkHeight
+
+	groups := map[string][][]byte{}
+	nm.IterateNames(func(name []byte) bool {
+		norm := Normalize(name)
+		if !bytes.Equal(name, norm) {
+			groups[string(norm)] = append(groups[string(norm)], append([]byte(nil), name...))
+		}
+		return true
+	})
+
+	norms := make([]string, 0, len(groups))
+	for norm := range groups {
+		norms = append(norms, norm)
+	}
+	sort.Strings(norms)
+
+	var collisions []Collision
+	for _, norm := range norms {
+
+		// The normalized name may have had its own claims before the fork.
+		var names [][]byte
+		var nodes []*Node
+		for _, name := range append(groups[norm], []byte(norm)) {
+			n, err := nm.NodeAt(fork-1, name)
+			if err != nil {
+				return nil, fmt.Errorf("node %q at %d: %w", name, fork-1, err)
+			}
+			if n != nil && hasClaimsBefore(n, fork) {
+				names = append(names, name)
+				nodes = append(nodes, n)
+			}
+		}
+		if len(names) < 2 {
+			continue
+		}
+
+		merged, err := nm.NodeAt(fork, []byte(norm))
+		if err != nil {
+			return nil, fmt.Errorf("node %q at %d: %w", norm, fork, err)
+		}
+
+		c := Collision{Name: []byte(norm), Names: names}
+		if merged != nil && merged.BestClaim != nil {
+			c.Winner = merged.BestClaim
+			c.EffectiveAmount = merged.BestClaim.EffectiveAmount(merged.Supports)
+		}
+		for i, n := range nodes {
+			if n.BestClaim == nil || (c.Winner != nil && c.Winner.ClaimID == n.BestClaim.ClaimID) {
+				continue
+			}
+			c.Losers = append(c.Losers, Loser{
+				Name:            names[i],
+				Claim:           n.BestClaim,
+				EffectiveAmount: n.BestClaim.EffectiveAmount(n.Supports),
+			})
+		}
+		collisions = append(collisions, c)
+	}
+
+	return collisions, nil
+}
+
+// hasClaimsBefore reports whether any of the claims of the node is visible before the height,
+// unlike the ones moved to it at the normalization fork.
+func hasClaimsBefore(n *Node, height int32) bool {
+	for _, c := range n.Claims {
+		if c.VisibleAt < height {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/node/noderepo"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizationCollisions(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	repo, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+
+	base, err := NewBaseManager(repo)
+	r.NoError(err)
+	m := NewNormalizingManager(base)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	claims := map[int32]struct {
+		name   string
+		amount int64
+	}{
+		10: {"Foo", 10},
+		20: {"foo", 5},
+		30: {"FOO", 1},
+		40: {"Bar", 3}, // normalized, but nothing to collide with.
+	}
+	ids := map[string]ClaimID{}
+	for h := int32(1); h <= param.NormalizedNameForkHeight+1; h++ {
+		if c, ok := claims[h]; ok {
+			op := wire.OutPoint{Hash: hash, Index: uint32(h)}
+			ids[c.name] = NewClaimID(op)
+			chg := change.New(change.AddClaim).SetName([]byte(c.name)).SetOutPoint(change.NewOutPoint(op)).
+				SetClaimID(ids[c.name]).SetAmount(c.amount).SetHeight(h)
+			r.NoError(m.AppendChange(chg))
+		}
+		_, err = m.IncrementHeightTo(h)
+		r.NoError(err)
+	}
+
+	collisions, err := NormalizationCollisions(m)
+	r.NoError(err)
+	r.Len(collisions, 1)
+
+	c := collisions[0]
+	r.Equal("foo", string(c.Name))
+	r.Equal([][]byte{[]byte("FOO"), []byte("Foo"), []byte("foo")}, c.Names)
+	r.NotNil(c.Winner)
+	r.Equal(ids["Foo"], c.Winner.ClaimID)
+	r.Equal(int64(10), c.EffectiveAmount)
+
+	r.Len(c.Losers, 2)
+	r.Equal("FOO", string(c.Losers[0].Name))
+	r.Equal(ids["FOO"], c.Losers[0].Claim.ClaimID)
+	r.Equal(int64(1), c.Losers[0].EffectiveAmount)
+	r.Equal("foo", string(c.Losers[1].Name))
+	r.Equal(ids["foo"], c.Losers[1].Claim.ClaimID)
+}
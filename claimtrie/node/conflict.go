@@ -0,0 +1,110 @@
+package node
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ErrDuplicateOutPoint is returned when a claim is added with the TXO of an existing one.
+var ErrDuplicateOutPoint = errors.New("claim with the same TXO already exists")
+
+// Conflict is a claim added with the TXO of an existing claim of the name.
+type Conflict struct {
+	Name     []byte
+	Height   int32
+	OutPoint wire.OutPoint
+	ClaimID  ClaimID
+	Previous ClaimID // of the existing claim
+}
+
+type conflictKey struct {
+	name     string
+	height   int32
+	outPoint wire.OutPoint
+}
+
+type missingKey struct {
+	name string
+	key  change.Key
+}
+
+// ConflictTracker records the conflicts, and decides how the new ones are handled.
+//
+// In strict mode, the claims conflicting with the existing ones are rejected.
+// Otherwise, in replay mode, they're kept along with the existing ones.
+// Changes already recorded in the node repo are always replayed so.
+//
+// Likewise, with strict spends, the spends and updates of the claims and supports missing
+// from the nodes are rejected. Otherwise, they're logged, and ignored, as they're replayed.
+type ConflictTracker struct {
+	strict       bool
+	strictSpends bool
+
+	mu        sync.Mutex
+	seen      map[conflictKey]bool
+	conflicts []Conflict
+	missing   map[missingKey]bool
+}
+
+func NewConflictTracker(strict bool) *ConflictTracker {
+	return &ConflictTracker{strict: strict, seen: map[conflictKey]bool{}, missing: map[missingKey]bool{}}
+}
+
+// SetStrictSpends sets whether the spends, and updates, of the missing claims and supports are rejected.
+// It's meant to be called before the tracker is used.
+func (ct *ConflictTracker) SetStrictSpends(strict bool) {
+	ct.strictSpends = strict
+}
+
+// StrictSpends reports whether the spends, and updates, of the missing claims and supports are rejected.
+func (ct *ConflictTracker) StrictSpends() bool {
+	return ct.strictSpends
+}
+
+// Strict reports whether the conflicting claims are rejected.
+func (ct *ConflictTracker) Strict() bool {
+	return ct.strict
+}
+
+// Conflicts returns the conflicts recorded so far, in the order they were found.
+func (ct *ConflictTracker) Conflicts() []Conflict {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	return append([]Conflict(nil), ct.conflicts...)
+}
+
+// record adds the conflict, unless it's already recorded, as the nodes are rebuilt
+// from their changes repeatedly. It reports whether the conflict is a new one.
+func (ct *ConflictTracker) record(c Conflict) bool {
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	key := conflictKey{name: string(c.Name), height: c.Height, outPoint: c.OutPoint}
+	if ct.seen[key] {
+		return false
+	}
+	ct.seen[key] = true
+	ct.conflicts = append(ct.conflicts, c)
+
+	return true
+}
+
+// recordMissing reports whether the change, spending or updating a missing claim or support, is a new one.
+func (ct *ConflictTracker) recordMissing(chg change.Change) bool {
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	k, _ := chg.Key() // the legacy changes repeated are logged once, too
+	key := missingKey{name: string(chg.Name), key: k}
+	if ct.missing[key] {
+		return false
+	}
+	ct.missing[key] = true
+
+	return true
+}
@@ -0,0 +1,205 @@
+package node
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// snapshotMarker precedes the snapshots encoded in the binary format. It's a reserved code of
+// msgpack, which the snapshots stored by earlier versions, as msgpack maps, never start with.
+const snapshotMarker = 0xc1
+
+// snapshotVersion is the version of the binary format, which follows the marker. The fields are
+// only ever appended to the claims, and to the nodes, which the decoders skip, so it's bumped
+// only by the changes the earlier decoders can't read past.
+//
+// A snapshot is the Height, the TakenOverAt, the index of the BestClaim in the Claims, or -1, followed
+// by the BestClaim, if it isn't in them, the Claims, and the Supports, each preceded by its count.
+// A claim is preceded by its length, and made of the OutPoint, the ClaimID, the Amount, the AcceptedAt,
+// the ActiveAt, the VisibleAt, the Status, and the Value, preceded by its length.
+// The ints are varints, zigzag encoded if they're signed.
+const snapshotVersion = 1
+
+// ErrInvalidSnapshot is returned for the snapshots which aren't in the binary format, or are truncated.
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+func encodeSnapshot(s *snapshot) []byte {
+
+	b := []byte{snapshotMarker, snapshotVersion}
+	b = appendVarint(b, int64(s.Height))
+	b = appendVarint(b, int64(s.TakenOverAt))
+	b = appendVarint(b, int64(s.Best))
+	if s.Best < 0 && s.BestClaim != nil {
+		b = append(b, 1)
+		b = appendClaim(b, s.BestClaim)
+	} else {
+		b = append(b, 0)
+	}
+	b = appendClaims(b, s.Claims)
+	b = appendClaims(b, s.Supports)
+
+	return b
+}
+
+func decodeSnapshot(b []byte) (*snapshot, error) {
+
+	if len(b) < 2 || b[0] != snapshotMarker {
+		return nil, fmt.Errorf("%w: no marker", ErrInvalidSnapshot)
+	}
+	if b[1] > snapshotVersion {
+		return nil, fmt.Errorf("%w: version %d is newer than %d", ErrInvalidSnapshot, b[1], snapshotVersion)
+	}
+	d := snapshotDecoder{b: b[2:]}
+
+	s := &snapshot{}
+	s.Height = int32(d.varint())
+	s.TakenOverAt = int32(d.varint())
+	s.Best = int(d.varint())
+	if d.byte() == 1 {
+		s.BestClaim = d.claim()
+	}
+	s.Claims = d.claims()
+	s.Supports = d.claims()
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return s, nil
+}
+
+func appendClaims(b []byte, claims ClaimList) []byte {
+	b = appendUvarint(b, uint64(len(claims)))
+	for _, c := range claims {
+		b = appendClaim(b, c)
+	}
+	return b
+}
+
+func appendClaim(b []byte, c *Claim) []byte {
+
+	var buf [128]byte // fits the fields, but the value
+	e := append(buf[:0], c.OutPoint.Hash[:]...)
+	e = appendUvarint(e, uint64(c.OutPoint.Index))
+	e = append(e, c.ClaimID[:]...)
+	e = appendVarint(e, c.Amount)
+	e = appendVarint(e, int64(c.AcceptedAt))
+	e = appendVarint(e, int64(c.ActiveAt))
+	e = appendVarint(e, int64(c.VisibleAt))
+	e = appendUvarint(e, uint64(c.Status))
+	e = appendUvarint(e, uint64(len(c.Value)))
+
+	b = appendUvarint(b, uint64(len(e)+len(c.Value)))
+	b = append(b, e...)
+	return append(b, c.Value...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// snapshotDecoder reads the fields of a snapshot, until the first error, after which it reads zeros.
+type snapshotDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *snapshotDecoder) fail(field string) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: %s", ErrInvalidSnapshot, field)
+	}
+	d.b = nil
+}
+
+func (d *snapshotDecoder) varint() int64 {
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.fail("varint")
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *snapshotDecoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.fail("uvarint")
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *snapshotDecoder) byte() byte {
+	if len(d.b) < 1 {
+		d.fail("byte")
+		return 0
+	}
+	v := d.b[0]
+	d.b = d.b[1:]
+	return v
+}
+
+// bytes returns the next size bytes, which it doesn't copy.
+func (d *snapshotDecoder) bytes(size uint64) []byte {
+	if size > uint64(len(d.b)) {
+		d.fail("bytes")
+		return nil
+	}
+	v := d.b[:size]
+	d.b = d.b[size:]
+	return v
+}
+
+func (d *snapshotDecoder) claims() ClaimList {
+
+	count := d.uvarint()
+	if count > uint64(len(d.b)) { // each takes at least a byte
+		d.fail("claim count")
+		return nil
+	}
+	if count == 0 {
+		return nil
+	}
+	claims := make(ClaimList, 0, count)
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		claims = append(claims, d.claim())
+	}
+
+	return claims
+}
+
+// claim reads a claim, skipping any fields appended by the later versions.
+func (d *snapshotDecoder) claim() *Claim {
+
+	e := snapshotDecoder{b: d.bytes(d.uvarint())}
+	if d.err != nil {
+		return nil
+	}
+
+	c := &Claim{}
+	copy(c.OutPoint.Hash[:], e.bytes(uint64(len(c.OutPoint.Hash))))
+	c.OutPoint.Index = uint32(e.uvarint())
+	copy(c.ClaimID[:], e.bytes(uint64(len(c.ClaimID))))
+	c.Amount = e.varint()
+	c.AcceptedAt = int32(e.varint())
+	c.ActiveAt = int32(e.varint())
+	c.VisibleAt = int32(e.varint())
+	c.Status = Status(e.uvarint())
+	if size := e.uvarint(); size > 0 {
+		c.Value = append([]byte(nil), e.bytes(size)...)
+	}
+	if e.err != nil {
+		d.err = fmt.Errorf("claim: %w", e.err)
+		return nil
+	}
+
+	return c
+}
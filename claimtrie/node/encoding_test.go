@@ -0,0 +1,111 @@
+package node
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func testSnapshotNode() *Node {
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	n := New()
+	n.TakenOverAt = 7
+	n.Claims = ClaimList{
+		{OutPoint: wire.OutPoint{Hash: hash, Index: 1}, ClaimID: ClaimID{1}, Amount: 10, AcceptedAt: 5, ActiveAt: 7, VisibleAt: 5, Status: Activated, Value: []byte("one")},
+		{OutPoint: wire.OutPoint{Hash: hash, Index: 300}, ClaimID: ClaimID{2}, Amount: 1 << 40, AcceptedAt: 9, ActiveAt: 4000, VisibleAt: 9, Status: Accepted},
+	}
+	n.Supports = ClaimList{
+		{OutPoint: wire.OutPoint{Hash: hash, Index: 2}, ClaimID: ClaimID{1}, Amount: 3, AcceptedAt: 6, ActiveAt: 6, VisibleAt: 6, Status: Deactivated},
+	}
+	n.BestClaim = n.Claims[0]
+
+	return n
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+
+	r := require.New(t)
+
+	n := testSnapshotNode()
+	data, err := EncodeSnapshot(n, 12)
+	r.NoError(err)
+	r.Equal(byte(snapshotMarker), data[0])
+
+	legacy, err := msgpack.Marshal(snapshot{Height: 12, TakenOverAt: n.TakenOverAt, Best: 0, Claims: n.Claims, Supports: n.Supports})
+	r.NoError(err)
+	r.Less(len(data), len(legacy))
+
+	// Both the binary snapshots, and the msgpack ones of earlier versions, are decoded.
+	for _, d := range [][]byte{data, legacy} {
+		got, height, err := DecodeSnapshot(d)
+		r.NoError(err)
+		r.Equal(int32(12), height)
+		r.Equal(n.TakenOverAt, got.TakenOverAt)
+		r.Equal(n.Claims, got.Claims)
+		r.Equal(n.Supports, got.Supports)
+		r.Same(got.Claims[0], got.BestClaim)
+	}
+
+	// A BestClaim out of the Claims, and a node without any, are kept.
+	n.BestClaim = &Claim{ClaimID: ClaimID{3}, AcceptedAt: -1}
+	data, err = EncodeSnapshot(n, 12)
+	r.NoError(err)
+	got, _, err := DecodeSnapshot(data)
+	r.NoError(err)
+	r.Equal(n.BestClaim, got.BestClaim)
+
+	data, err = EncodeSnapshot(New(), 1)
+	r.NoError(err)
+	got, height, err := DecodeSnapshot(data)
+	r.NoError(err)
+	r.Equal(int32(1), height)
+	r.Nil(got.BestClaim)
+	r.Empty(got.Claims)
+}
+
+func TestSnapshotVersions(t *testing.T) {
+
+	r := require.New(t)
+
+	n := testSnapshotNode()
+	s := &snapshot{Height: 12, TakenOverAt: n.TakenOverAt, Best: 0, Claims: n.Claims}
+
+	// The fields appended to the claims, and to the node, by the later versions are skipped.
+	b := []byte{snapshotMarker, snapshotVersion}
+	b = appendVarint(b, int64(s.Height))
+	b = appendVarint(b, int64(s.TakenOverAt))
+	b = appendVarint(b, int64(s.Best))
+	b = append(b, 0)
+	b = appendUvarint(b, uint64(len(s.Claims)))
+	for _, c := range s.Claims {
+		claim := appendClaim(nil, c)
+		size, read := binary.Uvarint(claim)
+		claim = append(appendUvarint(nil, size+2), claim[read:]...)
+		b = append(b, append(claim, 0xff, 0x01)...)
+	}
+	b = appendUvarint(b, 0)
+	b = append(b, 0xff, 0x01)
+
+	got, err := decodeSnapshot(b)
+	r.NoError(err)
+	r.Equal(s.Claims, got.Claims)
+	r.Empty(got.Supports)
+
+	// The later versions, and the truncated snapshots, aren't decoded.
+	data := encodeSnapshot(s)
+	data[1] = snapshotVersion + 1
+	_, _, err = DecodeSnapshot(data)
+	r.ErrorIs(err, ErrInvalidSnapshot)
+
+	data = encodeSnapshot(s)
+	for _, size := range []int{2, 10, len(data) - 1} {
+		_, _, err = DecodeSnapshot(data[:size])
+		r.ErrorIs(err, ErrInvalidSnapshot, "at %d", size)
+	}
+}
@@ -0,0 +1,98 @@
+package node
+
+import (
+	"container/list"
+	"sync"
+)
+
+// historyCache holds the nodes materialized by NodeAt at the recent (name, height) pairs, which the
+// historical queries, such as the ones of an explorer rendering the history of a name, issue in bursts.
+// A node at a height only changes with the changes of its name at, or below, the height, so the entries of
+// the names changed are dropped from the height of the changes. It's safe for concurrent access.
+type historyCache struct {
+	mu      sync.Mutex
+	limit   int
+	entries map[historyKey]*list.Element
+	order   *list.List // most recently used at the front.
+
+	// Incremented by each drop, so that the nodes materialized meanwhile aren't put.
+	gen uint64
+}
+
+type historyKey struct {
+	name   string
+	height int32
+}
+
+type historyEntry struct {
+	key  historyKey
+	node *Node // nil for the names without changes up to the height.
+}
+
+func newHistoryCache(limit int) *historyCache {
+	return &historyCache{limit: limit, entries: map[historyKey]*list.Element{}, order: list.New()}
+}
+
+// get returns a clone of the node of the name at the height, or the generation to put it with, if it's missing.
+func (c *historyCache) get(name []byte, height int32) (*Node, bool, uint64) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[historyKey{string(name), height}]
+	if !ok {
+		return nil, false, c.gen
+	}
+	c.order.MoveToFront(e)
+	n := e.Value.(*historyEntry).node
+	if n != nil {
+		n = n.Clone()
+	}
+
+	return n, true, c.gen
+}
+
+// put caches a clone of the node, unless any names were dropped since the generation.
+func (c *historyCache) put(name []byte, height int32, n *Node, gen uint64) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := historyKey{string(name), height}
+	if gen != c.gen || c.entries[key] != nil {
+		return
+	}
+	if n != nil {
+		n = n.Clone()
+	}
+	c.entries[key] = c.order.PushFront(&historyEntry{key: key, node: n})
+	for c.order.Len() > c.limit {
+		entry := c.order.Remove(c.order.Back()).(*historyEntry)
+		delete(c.entries, entry.key)
+	}
+}
+
+// drop drops the nodes of the names at, and above, the height.
+func (c *historyCache) drop(names [][]byte, height int32) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	if len(c.entries) == 0 || len(names) == 0 {
+		return
+	}
+	dropped := make(map[string]bool, len(names))
+	for _, name := range names {
+		dropped[string(name)] = true
+	}
+	for e := c.order.Front(); e != nil; {
+		next := e.Next()
+		entry := e.Value.(*historyEntry)
+		if entry.key.height >= height && dropped[entry.key.name] {
+			c.order.Remove(e)
+			delete(c.entries, entry.key)
+		}
+		e = next
+	}
+}
@@ -1,44 +1,92 @@
 package node
 
 import (
-	"crypto/sha256"
-	"encoding/binary"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"strconv"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/claimtrie/change"
 	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/claimtrie/proof"
 	"github.com/btcsuite/btcd/wire"
 )
 
 type Manager interface {
 	AppendChange(chg change.Change) error
+	// PendingChanges returns the number of the changes appended for the next height.
+	PendingChanges() int
+	// DiscardChanges drops the changes appended for the next height after the first n of them, such as the
+	// ones of a transaction failing to commit.
+	DiscardChanges(n int)
 	IncrementHeightTo(height int32) ([][]byte, error)
 	DecrementHeightTo(affectedNames [][]byte, height int32) error
 	Height() int32
 	Close() error
 	Node(name []byte) (*Node, error)
+	NodeAt(height int32, name []byte) (*Node, error)
+	// NodeAtContext is NodeAt, which gives up replaying the changes, once the context is done.
+	NodeAtContext(ctx context.Context, height int32, name []byte) (*Node, error)
 	NextUpdateHeightOfNode(name []byte) ([]byte, int32)
+	// PreviewNode returns a copy of the node at the height, which is the next one, as if the changes of the name
+	// were appended for it, without buffering them.
+	PreviewNode(name []byte, changes []change.Change, height int32) (*Node, error)
 	IterateNames(predicate func(name []byte) bool)
 	ClaimHashes(name []byte) []*chainhash.Hash
 	Hash(name []byte) *chainhash.Hash
+	Invalidate(names [][]byte)
+
+	// CacheSize returns the estimated size, in bytes, of the materialized nodes.
+	CacheSize() int64
+	// ShrinkCache drops the least recently used nodes until the cache is within
+	// target bytes, and returns the number of the nodes dropped. The dropped nodes
+	// are rebuilt from the changes in the repo on their next access.
+	ShrinkCache(target int64) int
 }
 
+// contextCheckInterval is the number of the changes applied between checking, if the context is done.
+const contextCheckInterval = 256
+
 type BaseManager struct {
-	repo Repo
+	repo      Repo
+	conflicts *ConflictTracker
 
 	height  int32
-	cache   map[string]*Node
+	cache   *nodeCache
 	changes []change.Change
+
+	// The claim IDs of the pending changes, by name and dedup key, so that re-feeding them is a no-op.
+	pending map[pendingKey]change.ClaimID
+
+	// load materializes the node of the name at the height from the repo.
+	load func(ctx context.Context, name []byte, height int32) (*Node, error)
+
+	// The changes of the nodes may be pruned, so they have to be materialized with load.
+	pruned bool
+
+	// The nodes materialized by NodeAt, if it's set.
+	history *historyCache
+
+	// The versions of the nodes changed by the recent blocks, if it's set.
+	versions *versionCache
 }
 
 func NewBaseManager(repo Repo) (Manager, error) {
+	return NewBaseManagerWithTracker(repo, NewConflictTracker(false))
+}
+
+// NewBaseManagerWithTracker returns a Manager, which handles the claims conflicting
+// with the existing ones as decided by the tracker, and records them into it.
+func NewBaseManagerWithTracker(repo Repo, tracker *ConflictTracker) (Manager, error) {
 
 	nm := &BaseManager{
-		repo:  repo,
-		cache: map[string]*Node{},
+		repo:      repo,
+		conflicts: tracker,
+		cache:     newNodeCache(),
+		pending:   map[pendingKey]change.ClaimID{},
 	}
+	nm.load = nm.replay
 
 	return nm, nil
 }
@@ -47,87 +95,337 @@ func NewBaseManager(repo Repo) (Manager, error) {
 // The returned node may have pending changes.
 func (nm *BaseManager) Node(name []byte) (*Node, error) {
 
-	nameStr := string(name)
-	n, ok := nm.cache[nameStr]
+	n, ok := nm.cache.get(string(name)) // doesn't allocate for the short names of the cache hits
 	if ok && n != nil {
 		return n.AdjustTo(nm.height, -1, name), nil
 	}
 
-	changes, err := nm.repo.LoadChanges(name)
-	if err != nil {
-		return nil, fmt.Errorf("load changes from node repo: %w", err)
+	if nm.versions != nil {
+		if n, ok := nm.versions.get(name, nm.height); ok {
+			if n != nil {
+				nm.cache.put(string(name), n)
+			}
+			return n, nil
+		}
 	}
 
-	n, err = nm.newNodeFromChanges(changes, nm.height)
+	n, err := nm.load(context.Background(), name, nm.height)
 	if err != nil {
-		return nil, fmt.Errorf("create node from changes: %w", err)
+		return nil, err
+	}
+	if nm.versions != nil {
+		nm.versions.record(name, nm.height, n)
 	}
 
 	if n == nil { // they've requested a nonexistent or expired name
 		return nil, nil
 	}
 
-	nm.cache[nameStr] = n
+	nm.cache.put(string(name), n)
+	return n, nil
+}
+
+// NodeAt returns a node at the height, which is rebuilt from its changes, unless it's in the history cache,
+// or a version of it covers the height.
+// Pending changes aren't included. It's safe for concurrent access, as long as the height is committed.
+func (nm *BaseManager) NodeAt(height int32, name []byte) (*Node, error) {
+	return nm.NodeAtContext(context.Background(), height, name)
+}
+
+// NodeAtContext returns a node at the height as NodeAt does, or the error of the context,
+// if it's done before the node is rebuilt.
+func (nm *BaseManager) NodeAtContext(ctx context.Context, height int32, name []byte) (*Node, error) {
+
+	if nm.versions != nil {
+		if n, ok := nm.versions.get(name, height); ok {
+			return n, nil
+		}
+	}
+	if nm.history == nil {
+		return nm.load(ctx, name, height)
+	}
+
+	n, ok, gen := nm.history.get(name, height)
+	if ok {
+		return n, nil
+	}
+	n, err := nm.load(ctx, name, height)
+	if err != nil {
+		return nil, err
+	}
+	nm.history.put(name, height, n, gen)
+
+	return n, nil
+}
+
+// SetHistoryCacheSize caches the nodes materialized by NodeAt at up to size (name, height) pairs,
+// which are shared by the queries at the same heights. It must be called before any NodeAt.
+func (nm *BaseManager) SetHistoryCacheSize(size int) {
+	nm.history = newHistoryCache(size)
+}
+
+// SetVersionDepth keeps the versions of the nodes changed in the last depth blocks, as materialized at the heights
+// of their changes, which NodeAt, and Node, adjust clones of to the heights up to the next changes, rather than
+// rebuilding the nodes from their changes. It must be called before any NodeAt.
+func (nm *BaseManager) SetVersionDepth(depth int32) {
+	nm.versions = newVersionCache(depth, nm.height)
+}
+
+// replay materializes the node by replaying all of its changes.
+func (nm *BaseManager) replay(ctx context.Context, name []byte, height int32) (*Node, error) {
+
+	changes, err := nm.repo.LoadChanges(name)
+	if err != nil {
+		return nil, fmt.Errorf("load changes from node repo: %w", err)
+	}
+
+	n, err := nm.newNodeFromChanges(ctx, changes, height)
+	if err != nil {
+		return nil, fmt.Errorf("create node from changes: %w", err)
+	}
+
 	return n, nil
 }
 
 // newNodeFromChanges returns a new Node constructed from the changes.
 // The changes must preserve their order received.
-func (nm *BaseManager) newNodeFromChanges(changes []change.Change, height int32) (*Node, error) {
+func (nm *BaseManager) newNodeFromChanges(ctx context.Context, changes []change.Change, height int32) (*Node, error) {
 
 	if len(changes) == 0 {
 		return nil, nil
 	}
 
 	n := New()
-	previous := changes[0].Height
-	count := len(changes)
+	count, err := nm.applyChanges(ctx, n, changes[0].Height, changes, height)
+	if err != nil {
+		return nil, err
+	}
 
+	if count <= 0 {
+		return nil, nil
+	}
+	lastChange := changes[count-1]
+	return n.AdjustTo(lastChange.Height, height, lastChange.Name), nil
+}
+
+// applyChanges applies the changes up to the height to n, which has the changes up to,
+// and at, previous applied, and returns the number of the changes applied.
+// n isn't adjusted past the height of the last change applied.
+// The changes applied again, with the dedup key and the claim ID of one applied at their height, are skipped.
+// The legacy changes, without a dedup key, are all applied.
+// It returns the error of the context, if it's done before all of them are applied.
+func (nm *BaseManager) applyChanges(ctx context.Context, n *Node, previous int32, changes []change.Change, height int32) (int, error) {
+
+	applied := map[change.Key]change.ClaimID{}
 	for i, chg := range changes {
+		if i%contextCheckInterval == 0 && ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
 		if chg.Height < previous {
-			return nil, fmt.Errorf("expected the changes to be in order by height")
+			return 0, fmt.Errorf("expected the changes to be in order by height")
 		}
 		if chg.Height > height {
-			count = i
-			break
+			return i, nil
 		}
 		if previous < chg.Height {
 			n.AdjustTo(previous, chg.Height-1, chg.Name) // update bids and activation
 			previous = chg.Height
+			applied = map[change.Key]change.ClaimID{}
+		}
+		if key, keyed := chg.Key(); keyed {
+			if id, ok := applied[key]; ok && id == chg.ClaimID {
+				continue
+			}
+			applied[key] = chg.ClaimID
 		}
 
+		if chg.Type == change.AddClaim {
+			nm.noticeConflict(n, chg)
+		}
 		delay := nm.getDelayForName(n, chg)
 		err := n.ApplyChange(chg, delay)
+		if errors.Is(err, ErrMissingClaim) || errors.Is(err, ErrMissingSupport) {
+			if nm.conflicts.recordMissing(chg) {
+				log.Warnf("Ignoring the spend, or update: %s", err)
+			}
+			err = nil
+		}
 		if err != nil {
-			return nil, fmt.Errorf("append change: %w", err)
+			return 0, change.Wrap(fmt.Errorf("append change: %w", err), chg)
 		}
 	}
 
-	if count <= 0 {
-		return nil, nil
+	return len(changes), nil
+}
+
+// noticeConflict records the conflict, if the claim added by chg has the TXO of an existing one of the node.
+// Both claims are kept, and the one added competes for the name as any claim does: outbidding the other, it takes
+// the name over, unless it has the same ID. This keeps the roots of the blocks replayed the ones they always had.
+// Only the strict mode rejects the claim added, in checkConflict, before it's applied.
+func (nm *BaseManager) noticeConflict(n *Node, chg change.Change) {
+
+	out := chg.OutPoint.Wire()
+	existing := n.Claims.find(byOut(out))
+	if existing == nil {
+		return
 	}
-	lastChange := changes[count-1]
-	return n.AdjustTo(lastChange.Height, height, lastChange.Name), nil
+	c := Conflict{Name: chg.Name, Height: chg.Height, OutPoint: out, ClaimID: chg.ClaimID, Previous: existing.ClaimID}
+	if nm.conflicts.record(c) {
+		log.Warnf("Conflict of claim %s with %s of the same TXO %s, name: %s, height: %d",
+			c.ClaimID, c.Previous, out, chg.Name, chg.Height)
+	}
+}
+
+// checkConflict returns ErrDuplicateOutPoint if the claim added by chg conflicts with
+// an existing one, or one added earlier in the block.
+func (nm *BaseManager) checkConflict(chg change.Change) error {
+
+	out := chg.OutPoint.Wire()
+	for _, pending := range nm.changes {
+		if pending.Type == change.AddClaim && pending.OutPoint == chg.OutPoint && bytes.Equal(pending.Name, chg.Name) {
+			return change.Wrap(ErrDuplicateOutPoint, chg)
+		}
+	}
+
+	n, err := nm.Node(chg.Name)
+	if err != nil {
+		return change.Wrap(fmt.Errorf("node: %w", err), chg)
+	}
+	if n != nil && n.Claims.find(byOut(out)) != nil {
+		return change.Wrap(ErrDuplicateOutPoint, chg)
+	}
+
+	return nil
+}
+
+// checkMissing returns ErrMissingClaim, or ErrMissingSupport, if the claim, or support, spent or updated
+// by chg is neither in the node, nor added earlier in the block.
+func (nm *BaseManager) checkMissing(chg change.Change) error {
+
+	var found func(n *Node) bool
+	var pending func(p change.Change) bool
+	missing := ErrMissingClaim
+	switch chg.Type {
+	case change.SpendClaim:
+		found = func(n *Node) bool { return n.Claims.find(byOut(chg.OutPoint.Wire())) != nil }
+		pending = func(p change.Change) bool {
+			return (p.Type == change.AddClaim || p.Type == change.UpdateClaim) && p.OutPoint == chg.OutPoint
+		}
+	case change.UpdateClaim:
+		found = func(n *Node) bool { return n.Claims.find(byID(chg.ClaimID)) != nil }
+		pending = func(p change.Change) bool {
+			return (p.Type == change.AddClaim || p.Type == change.UpdateClaim) && p.ClaimID == chg.ClaimID
+		}
+	case change.SpendSupport:
+		missing = ErrMissingSupport
+		found = func(n *Node) bool { return n.Supports.find(byOut(chg.OutPoint.Wire())) != nil }
+		pending = func(p change.Change) bool { return p.Type == change.AddSupport && p.OutPoint == chg.OutPoint }
+	default:
+		return nil
+	}
+
+	for _, p := range nm.changes {
+		if pending(p) && bytes.Equal(p.Name, chg.Name) {
+			return nil
+		}
+	}
+
+	n, err := nm.Node(chg.Name)
+	if err != nil {
+		return change.Wrap(fmt.Errorf("node: %w", err), chg)
+	}
+	if n != nil && found(n) {
+		return nil
+	}
+
+	return change.Wrap(missing, chg)
 }
 
+type pendingKey struct {
+	name string
+	key  change.Key
+}
+
+// AppendChange buffers the change for the next height. A change re-fed with the dedup key,
+// and the claim ID, of a pending one, such as after a failed IncrementHeightTo, is ignored.
 func (nm *BaseManager) AppendChange(chg change.Change) error {
 
+	key, keyed := chg.Key()
+	pk := pendingKey{name: string(chg.Name), key: key}
+	if id, ok := nm.pending[pk]; keyed && ok && id == chg.ClaimID {
+		return nil
+	}
+
+	if chg.Type == change.AddClaim && nm.conflicts.Strict() {
+		if err := nm.checkConflict(chg); err != nil {
+			nm.conflicts.record(Conflict{Name: chg.Name, Height: chg.Height, OutPoint: chg.OutPoint.Wire(), ClaimID: chg.ClaimID})
+			return err
+		}
+	}
+
+	if nm.conflicts.StrictSpends() {
+		if err := nm.checkMissing(chg); err != nil {
+			return err
+		}
+	}
+
 	if len(nm.changes) <= 0 {
 		// this little code block is acting as a "block complete" method
 		// that could be called after the merkle hash is complete
-		if len(nm.cache) > param.MaxNodeManagerCacheSize {
+		if nm.cache.len() > param.MaxNodeManagerCacheSize {
 			// TODO: use a better cache model?
-			fmt.Printf("Clearing manager cache at height %d\n", nm.height)
-			nm.cache = map[string]*Node{}
+			log.Debugf("Clearing manager cache at height %d", nm.height)
+			nm.cache.clear()
 		}
 	}
 
-	delete(nm.cache, string(chg.Name))
+	nm.cache.delete(string(chg.Name))
 	nm.changes = append(nm.changes, chg)
+	if keyed {
+		nm.pending[pk] = chg.ClaimID
+	}
 
 	return nil
 }
 
+func (nm *BaseManager) PendingChanges() int {
+	return len(nm.changes)
+}
+
+func (nm *BaseManager) DiscardChanges(n int) {
+
+	for _, chg := range nm.changes[n:] {
+		key, _ := chg.Key()
+		delete(nm.pending, pendingKey{name: string(chg.Name), key: key})
+		nm.cache.delete(string(chg.Name))
+	}
+	nm.changes = nm.changes[:n]
+}
+
+func (nm *BaseManager) PreviewNode(name []byte, changes []change.Change, height int32) (*Node, error) {
+
+	if height != nm.height+1 {
+		return nil, fmt.Errorf("preview of height %d at %d", height, nm.height)
+	}
+
+	n, err := nm.Node(name)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		n = New()
+	} else {
+		n = n.Clone() // the cached one is adjusted in place
+	}
+
+	_, err = nm.applyChanges(context.Background(), n, nm.height, changes, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.AdjustTo(height, height, name), nil
+}
+
 func (nm *BaseManager) IncrementHeightTo(height int32) ([][]byte, error) {
 
 	if height <= nm.height {
@@ -135,19 +433,43 @@ func (nm *BaseManager) IncrementHeightTo(height int32) ([][]byte, error) {
 	}
 
 	names := make([][]byte, 0, len(nm.changes))
+	from := height
 	for i := range nm.changes {
 		names = append(names, nm.changes[i].Name)
+		if nm.changes[i].Height < from {
+			from = nm.changes[i].Height
+		}
+	}
+	var versioned []versionKey
+	if nm.versions != nil {
+		versioned = make([]versionKey, len(nm.changes))
+		for i := range nm.changes {
+			versioned[i] = versionKey{name: string(nm.changes[i].Name), height: nm.changes[i].Height}
+		}
 	}
 
 	if err := nm.repo.AppendChanges(nm.changes); err != nil {
 		return nil, fmt.Errorf("save changes to node repo: %w", err)
 	}
+	for _, name := range names { // the ones read since their changes, such as by a preview, lack them
+		nm.cache.delete(string(name))
+	}
+	if nm.history != nil {
+		nm.history.drop(names, from)
+	}
+	if nm.versions != nil {
+		nm.versions.incremented(versioned, height)
+	}
 
 	// Truncate the buffer size to zero.
 	if len(nm.changes) > 1000 { // TODO: determine a good number here
 		nm.changes = nil // release the RAM
+		nm.pending = map[pendingKey]change.ClaimID{}
 	} else {
 		nm.changes = nm.changes[:0]
+		for pk := range nm.pending {
+			delete(nm.pending, pk)
+		}
 	}
 	nm.height = height
 
@@ -160,17 +482,45 @@ func (nm *BaseManager) DecrementHeightTo(affectedNames [][]byte, height int32) e
 	}
 
 	for _, name := range affectedNames {
-		delete(nm.cache, string(name))
+		nm.cache.delete(string(name))
 		if err := nm.repo.DropChanges(name, height); err != nil {
 			return err
 		}
 	}
+	if nm.history != nil {
+		nm.history.drop(affectedNames, height+1)
+	}
+	if nm.versions != nil {
+		nm.versions.decremented(height)
+	}
 
 	nm.height = height
 
 	return nil
 }
 
+// Invalidate drops the materialized nodes of the names, so they get rebuilt
+// from the changes in the repo on their next access.
+func (nm *BaseManager) Invalidate(names [][]byte) {
+	for _, name := range names {
+		nm.cache.delete(string(name))
+	}
+	if nm.history != nil {
+		nm.history.drop(names, 0)
+	}
+	if nm.versions != nil {
+		nm.versions.drop(names)
+	}
+}
+
+func (nm *BaseManager) CacheSize() int64 {
+	return nm.cache.size
+}
+
+func (nm *BaseManager) ShrinkCache(target int64) int {
+	return nm.cache.shrink(target)
+}
+
 func (nm *BaseManager) getDelayForName(n *Node, chg change.Change) int32 {
 	hasBest := n.BestClaim != nil // && n.BestClaim.Status == Activated
 	if hasBest && n.BestClaim.ClaimID == chg.ClaimID {
@@ -207,7 +557,7 @@ func (nm *BaseManager) decideIfWorkaroundNeeded(n *Node, chg change.Change) bool
 					if h == chg.Height {
 						//hc := nm.hasChildrenButNoSelf(chg.Name, chg.Height, 2)
 						hc := true
-						fmt.Printf("HC: %s: %t\n", chg.Name, hc)
+						log.Debugf("HC: %s: %t", chg.Name, hc)
 						return true
 					}
 				}
@@ -270,18 +620,20 @@ func (nm *BaseManager) Close() error {
 func (nm *BaseManager) hasChildrenButNoSelf(name []byte, height int32, required int) bool {
 	c := map[byte]bool{}
 
-	nm.repo.IterateChildren(name, func(changes []change.Change) bool {
+	nm.repo.IterateChildren(name, func(child []byte, changes []change.Change) bool {
 		// if the key is unseen, generate a node for it to height
 		// if that node is active then increase the count
-		if len(changes) == 0 {
-			return true
+		var n *Node
+		if nm.pruned {
+			n, _ = nm.load(context.Background(), child, height)
+		} else if len(changes) > 0 {
+			n, _ = nm.newNodeFromChanges(context.Background(), changes, height)
 		}
-		n, _ := nm.newNodeFromChanges(changes, height)
 		if n != nil && n.BestClaim != nil && n.BestClaim.Status == Activated {
-			if len(name) >= len(changes[0].Name) {
+			if len(name) >= len(child) {
 				return false // hit self
 			}
-			c[changes[0].Name[len(name)]] = true
+			c[child[len(name)]] = true
 			if len(c) >= required {
 				return false
 			}
@@ -301,11 +653,10 @@ func (nm *BaseManager) ClaimHashes(name []byte) []*chainhash.Hash {
 	if err != nil || n == nil {
 		return nil
 	}
-	n.SortClaims()
 	claimHashes := make([]*chainhash.Hash, 0, len(n.Claims))
-	for _, c := range n.Claims {
+	for _, c := range n.SortedClaims() {
 		if c.Status == Activated { // TODO: unit test this line
-			claimHashes = append(claimHashes, calculateNodeHash(c.OutPoint, n.TakenOverAt))
+			claimHashes = append(claimHashes, CalculateNodeHash(c.OutPoint, n.TakenOverAt))
 		}
 	}
 	return claimHashes
@@ -319,29 +670,14 @@ func (nm *BaseManager) Hash(name []byte) *chainhash.Hash {
 	}
 	if n != nil && len(n.Claims) > 0 {
 		if n.BestClaim != nil && n.BestClaim.Status == Activated {
-			return calculateNodeHash(n.BestClaim.OutPoint, n.TakenOverAt)
+			return CalculateNodeHash(n.BestClaim.OutPoint, n.TakenOverAt)
 		}
 	}
 	return nil
 }
 
-func calculateNodeHash(op wire.OutPoint, takeover int32) *chainhash.Hash {
-
-	txHash := chainhash.DoubleHashH(op.Hash[:])
-
-	nOut := []byte(strconv.Itoa(int(op.Index)))
-	nOutHash := chainhash.DoubleHashH(nOut)
-
-	buf := make([]byte, 8)
-	binary.BigEndian.PutUint64(buf, uint64(takeover))
-	heightHash := chainhash.DoubleHashH(buf)
-
-	h := make([]byte, 0, sha256.Size*3)
-	h = append(h, txHash[:]...)
-	h = append(h, nOutHash[:]...)
-	h = append(h, heightHash[:]...)
-
-	hh := chainhash.DoubleHashH(h)
-
-	return &hh
+// CalculateNodeHash returns the value hash of a claim, which commits to its outpoint and
+// the takeover height of the node.
+func CalculateNodeHash(op wire.OutPoint, takeover int32) *chainhash.Hash {
+	return proof.ValueHash(op, takeover)
 }
@@ -1,6 +1,7 @@
 package node
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -55,13 +56,13 @@ func TestSimpleAddClaim(t *testing.T) {
 	_, err = m.IncrementHeightTo(10)
 	r.NoError(err)
 
-	chg := change.New(change.AddClaim).SetName(name1).SetOutPoint(out1.String()).SetHeight(11)
+	chg := change.New(change.AddClaim).SetName(name1).SetOutPoint(change.NewOutPoint(*out1)).SetHeight(11)
 	err = m.AppendChange(chg)
 	r.NoError(err)
 	_, err = m.IncrementHeightTo(11)
 	r.NoError(err)
 
-	chg = chg.SetName(name2).SetOutPoint(out2.String()).SetHeight(12)
+	chg = chg.SetName(name2).SetOutPoint(change.NewOutPoint(*out2)).SetHeight(12)
 	err = m.AppendChange(chg)
 	r.NoError(err)
 	_, err = m.IncrementHeightTo(12)
@@ -100,14 +101,14 @@ func TestNodeSort(t *testing.T) {
 	r.True(OutPointLess(*out1, *out3))
 
 	n := New()
-	n.Claims = append(n.Claims, &Claim{OutPoint: *out1, AcceptedAt: 3, Amount: 3, ClaimID: "a"})
-	n.Claims = append(n.Claims, &Claim{OutPoint: *out2, AcceptedAt: 3, Amount: 3, ClaimID: "b"})
+	n.Claims = append(n.Claims, &Claim{OutPoint: *out1, AcceptedAt: 3, Amount: 3, ClaimID: ClaimID{'a'}})
+	n.Claims = append(n.Claims, &Claim{OutPoint: *out2, AcceptedAt: 3, Amount: 3, ClaimID: ClaimID{'b'}})
 	n.handleExpiredAndActivated(3)
 	n.updateTakeoverHeight(3, []byte{}, true)
 
 	r.Equal(n.Claims.find(byOut(*out1)).OutPoint.String(), n.BestClaim.OutPoint.String())
 
-	n.Claims = append(n.Claims, &Claim{OutPoint: *out3, AcceptedAt: 3, Amount: 3, ClaimID: "c"})
+	n.Claims = append(n.Claims, &Claim{OutPoint: *out3, AcceptedAt: 3, Amount: 3, ClaimID: ClaimID{'c'}})
 	n.handleExpiredAndActivated(3)
 	n.updateTakeoverHeight(3, []byte{}, true)
 	r.Equal(n.Claims.find(byOut(*out1)).OutPoint.String(), n.BestClaim.OutPoint.String())
@@ -120,10 +121,10 @@ func TestClaimSort(t *testing.T) {
 	param.ExtendedClaimExpirationTime = 1000
 
 	n := New()
-	n.Claims = append(n.Claims, &Claim{OutPoint: *out2, AcceptedAt: 3, Amount: 3, ClaimID: "b"})
-	n.Claims = append(n.Claims, &Claim{OutPoint: *out3, AcceptedAt: 3, Amount: 2, ClaimID: "c"})
-	n.Claims = append(n.Claims, &Claim{OutPoint: *out3, AcceptedAt: 4, Amount: 2, ClaimID: "d"})
-	n.Claims = append(n.Claims, &Claim{OutPoint: *out1, AcceptedAt: 3, Amount: 4, ClaimID: "a"})
+	n.Claims = append(n.Claims, &Claim{OutPoint: *out2, AcceptedAt: 3, Amount: 3, ClaimID: ClaimID{'b'}})
+	n.Claims = append(n.Claims, &Claim{OutPoint: *out3, AcceptedAt: 3, Amount: 2, ClaimID: ClaimID{'c'}})
+	n.Claims = append(n.Claims, &Claim{OutPoint: *out3, AcceptedAt: 4, Amount: 2, ClaimID: ClaimID{'d'}})
+	n.Claims = append(n.Claims, &Claim{OutPoint: *out1, AcceptedAt: 3, Amount: 4, ClaimID: ClaimID{'a'}})
 	n.SortClaims()
 
 	r.Equal(int64(4), n.Claims[0].Amount)
@@ -131,3 +132,544 @@ func TestClaimSort(t *testing.T) {
 	r.Equal(int64(2), n.Claims[2].Amount)
 	r.Equal(int32(4), n.Claims[3].AcceptedAt)
 }
+
+func TestShrinkCache(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	repo, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+
+	m, err := NewBaseManager(repo)
+	r.NoError(err)
+
+	chg := change.New(change.AddClaim).SetHeight(1)
+	r.NoError(m.AppendChange(chg.SetName(name1).SetOutPoint(change.NewOutPoint(*out1))))
+	r.NoError(m.AppendChange(chg.SetName(name2).SetOutPoint(change.NewOutPoint(*out2))))
+	_, err = m.IncrementHeightTo(1)
+	r.NoError(err)
+
+	_, err = m.Node(name1)
+	r.NoError(err)
+	_, err = m.Node(name2)
+	r.NoError(err)
+	size := m.CacheSize()
+	r.Greater(size, int64(0))
+
+	// name1 is the most recently used.
+	_, err = m.Node(name1)
+	r.NoError(err)
+	r.Equal(1, m.ShrinkCache(size-1))
+	r.Less(m.CacheSize(), size)
+	_, ok := m.(*BaseManager).cache.get(string(name1))
+	r.True(ok)
+	r.Equal(1, m.ShrinkCache(0))
+	r.Equal(int64(0), m.CacheSize())
+
+	// Dropped nodes are rebuilt from the repo.
+	n, err := m.Node(name2)
+	r.NoError(err)
+	r.NotNil(n.Claims.find(byOut(*out2)))
+}
+
+// countingRepo counts the loads of the changes.
+type countingRepo struct {
+	Repo
+	loads int
+}
+
+func (repo *countingRepo) LoadChanges(name []byte) ([]change.Change, error) {
+	repo.loads++
+	return repo.Repo.LoadChanges(name)
+}
+
+func TestNodeAtContext(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	repo, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+
+	m, err := NewBaseManager(repo)
+	r.NoError(err)
+	m.(*BaseManager).SetHistoryCacheSize(2)
+
+	chg := change.New(change.AddClaim).SetName(name1).SetOutPoint(change.NewOutPoint(*out1)).SetHeight(1)
+	r.NoError(m.AppendChange(chg))
+	_, err = m.IncrementHeightTo(2)
+	r.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = m.NodeAtContext(ctx, 2, name1)
+	r.ErrorIs(err, context.Canceled)
+
+	// The node given up on isn't cached.
+	n, err := m.NodeAtContext(context.Background(), 2, name1)
+	r.NoError(err)
+	r.Len(n.Claims, 1)
+}
+
+func TestHistoryCache(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	pebble, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	repo := &countingRepo{Repo: pebble}
+
+	m, err := NewBaseManager(repo)
+	r.NoError(err)
+	m.(*BaseManager).SetHistoryCacheSize(2)
+
+	chg := change.New(change.AddClaim).SetName(name1).SetOutPoint(change.NewOutPoint(*out1)).SetHeight(1)
+	r.NoError(m.AppendChange(chg))
+	_, err = m.IncrementHeightTo(2)
+	r.NoError(err)
+
+	n, err := m.NodeAt(2, name1)
+	r.NoError(err)
+	r.Len(n.Claims, 1)
+	n.Claims = nil // the cached node is a clone
+	n, err = m.NodeAt(2, name1)
+	r.NoError(err)
+	r.Len(n.Claims, 1)
+	r.Equal(1, repo.loads)
+
+	// The nodes at the heights below the changes stay cached.
+	sup := change.New(change.AddSupport).SetName(name1).SetOutPoint(change.NewOutPoint(*out2)).SetHeight(3)
+	r.NoError(m.AppendChange(sup))
+	_, err = m.IncrementHeightTo(3)
+	r.NoError(err)
+	_, err = m.NodeAt(2, name1)
+	r.NoError(err)
+	r.Equal(1, repo.loads)
+	n, err = m.NodeAt(3, name1)
+	r.NoError(err)
+	r.Len(n.Supports, 1)
+	r.Equal(2, repo.loads)
+
+	// The ones above the height reset to are dropped.
+	r.NoError(m.DecrementHeightTo([][]byte{name1}, 2))
+	n, err = m.NodeAt(3, name1)
+	r.NoError(err)
+	r.Empty(n.Supports)
+	r.Equal(3, repo.loads)
+
+	// The missing names are cached too, and the least recently used are evicted.
+	n, err = m.NodeAt(2, name2)
+	r.NoError(err)
+	r.Nil(n)
+	_, err = m.NodeAt(2, name2)
+	r.NoError(err)
+	r.Equal(4, repo.loads)
+	_, err = m.NodeAt(2, name1)
+	r.NoError(err)
+	r.Equal(5, repo.loads)
+
+	// Invalidating the names drops them at all the heights.
+	m.Invalidate([][]byte{name1})
+	_, err = m.NodeAt(2, name1)
+	r.NoError(err)
+	r.Equal(6, repo.loads)
+}
+
+func TestDuplicateOutPoint(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	repo, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+
+	tracker := NewConflictTracker(false)
+	m, err := NewBaseManagerWithTracker(repo, tracker)
+	r.NoError(err)
+
+	// A claim re-added, under another ID, with the TXO of an existing claim.
+	chg := change.New(change.AddClaim).SetName(name1).SetOutPoint(change.NewOutPoint(*out1)).SetAmount(2)
+	r.NoError(m.AppendChange(chg.SetHeight(11).SetClaimID(NewClaimID(*out1))))
+	_, err = m.IncrementHeightTo(11)
+	r.NoError(err)
+	r.NoError(m.AppendChange(chg.SetHeight(12).SetClaimID(NewClaimID(*out3))))
+	_, err = m.IncrementHeightTo(12)
+	r.NoError(err)
+
+	// Both claims are kept, as the replay always did.
+	n, err := m.Node(name1)
+	r.NoError(err)
+	r.Len(n.Claims, 2)
+	r.Equal(NewClaimID(*out1), n.Claims[0].ClaimID)
+	r.Equal(NewClaimID(*out3), n.Claims[1].ClaimID)
+
+	// Rebuilding the node from its changes keeps them again, but records the conflict once.
+	m.ShrinkCache(0)
+	n, err = m.Node(name1)
+	r.NoError(err)
+	r.Len(n.Claims, 2)
+	r.Equal([]Conflict{{Name: name1, Height: 12, OutPoint: *out1, ClaimID: NewClaimID(*out3), Previous: NewClaimID(*out1)}},
+		tracker.Conflicts())
+}
+
+func TestDuplicateOutPointStrict(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	repo, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+
+	tracker := NewConflictTracker(true)
+	m, err := NewBaseManagerWithTracker(repo, tracker)
+	r.NoError(err)
+
+	chg := change.New(change.AddClaim).SetName(name1).SetOutPoint(change.NewOutPoint(*out1)).SetAmount(2)
+	r.NoError(m.AppendChange(chg.SetHeight(11).SetClaimID(NewClaimID(*out1))))
+	err = m.AppendChange(chg.SetHeight(11).SetClaimID(NewClaimID(*out2)))
+	r.ErrorIs(err, ErrDuplicateOutPoint)
+	_, err = m.IncrementHeightTo(11)
+	r.NoError(err)
+
+	err = m.AppendChange(chg.SetHeight(12).SetClaimID(NewClaimID(*out3)))
+	r.ErrorIs(err, ErrDuplicateOutPoint)
+	r.Len(tracker.Conflicts(), 2)
+
+	n, err := m.Node(name1)
+	r.NoError(err)
+	r.Len(n.Claims, 1)
+	r.Equal(NewClaimID(*out1), n.Claims[0].ClaimID)
+}
+
+func TestMissingSpends(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	repo, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+
+	// The spends of the missing claims and supports are ignored, unless the spends are strict.
+	tracker := NewConflictTracker(false)
+	m, err := NewBaseManagerWithTracker(repo, tracker)
+	r.NoError(err)
+
+	add := change.New(change.AddClaim).SetName(name1).SetOutPoint(change.NewOutPoint(*out1)).SetClaimID(NewClaimID(*out1)).SetAmount(2)
+	r.NoError(m.AppendChange(add.SetHeight(11)))
+	spend := change.New(change.SpendSupport).SetName(name1).SetOutPoint(change.NewOutPoint(*out2))
+	r.NoError(m.AppendChange(spend.SetHeight(11)))
+	_, err = m.IncrementHeightTo(11)
+	r.NoError(err)
+
+	n, err := m.Node(name1)
+	r.NoError(err)
+	r.Len(n.Claims, 1)
+
+	tracker.SetStrictSpends(true)
+	err = m.AppendChange(spend.SetHeight(12))
+	r.ErrorIs(err, ErrMissingSupport)
+	spendClaim := change.New(change.SpendClaim).SetName(name1).SetHeight(12)
+	err = m.AppendChange(spendClaim.SetOutPoint(change.NewOutPoint(*out2)))
+	r.ErrorIs(err, ErrMissingClaim)
+	update := change.New(change.UpdateClaim).SetName(name1).SetOutPoint(change.NewOutPoint(*out2)).SetAmount(3).SetHeight(12)
+	err = m.AppendChange(update.SetClaimID(NewClaimID(*out3)))
+	r.ErrorIs(err, ErrMissingClaim)
+
+	// The spends of the existing ones, and of the ones added earlier in the block, are accepted.
+	r.NoError(m.AppendChange(spendClaim.SetOutPoint(change.NewOutPoint(*out1))))
+	r.NoError(m.AppendChange(update.SetClaimID(NewClaimID(*out1))))
+	support := change.New(change.AddSupport).SetName(name1).SetOutPoint(change.NewOutPoint(*out3)).SetAmount(1)
+	r.NoError(m.AppendChange(support.SetHeight(12)))
+	r.NoError(m.AppendChange(spend.SetOutPoint(change.NewOutPoint(*out3)).SetHeight(12)))
+	_, err = m.IncrementHeightTo(12)
+	r.NoError(err)
+}
+
+func TestIdempotentChanges(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	repo, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+
+	m, err := NewBaseManager(repo)
+	r.NoError(err)
+
+	id := NewClaimID(*out1)
+	changes := []change.Change{
+		change.New(change.AddClaim).SetName(name1).SetOutPoint(change.NewOutPoint(*out1)).SetClaimID(id).SetAmount(2).SetSeq(1),
+		change.New(change.AddSupport).SetName(name1).SetOutPoint(change.NewOutPoint(*out2)).SetClaimID(id).SetAmount(3).SetSeq(2),
+	}
+	for i := range changes {
+		changes[i] = changes[i].SetHeight(11)
+	}
+
+	// The first of the changes was written before a partial failure, and the block is re-fed twice.
+	r.NoError(repo.AppendChanges(changes[:1]))
+	for i := 0; i < 2; i++ {
+		for _, chg := range changes {
+			r.NoError(m.AppendChange(chg))
+		}
+	}
+	_, err = m.IncrementHeightTo(11)
+	r.NoError(err)
+
+	m.ShrinkCache(0)
+	n, err := m.Node(name1)
+	r.NoError(err)
+	r.Len(n.Claims, 1)
+	r.Len(n.Supports, 1)
+	r.Equal(int64(5), n.Claims[0].EffectiveAmount(n.Supports))
+}
+
+func TestLegacyChangesNotDeduped(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	repo, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+
+	// The changes of a legacy repo have no Seq. A support added twice with the same TXO at a height
+	// is keyed alike, but both are kept, as the replay always did, rather than taken for a re-feed.
+	id := NewClaimID(*out1)
+	support := change.New(change.AddSupport).SetName(name1).SetOutPoint(change.NewOutPoint(*out2)).SetClaimID(id).SetAmount(3)
+	r.NoError(repo.AppendChanges([]change.Change{
+		change.New(change.AddClaim).SetName(name1).SetOutPoint(change.NewOutPoint(*out1)).SetClaimID(id).SetAmount(2).SetHeight(11),
+		support.SetHeight(11),
+		support.SetHeight(11),
+	}))
+
+	m, err := NewBaseManager(repo)
+	r.NoError(err)
+	_, err = m.IncrementHeightTo(11)
+	r.NoError(err)
+
+	n, err := m.Node(name1)
+	r.NoError(err)
+	r.Len(n.Claims, 1)
+	r.Len(n.Supports, 2)
+	r.Equal(int64(8), n.Claims[0].EffectiveAmount(n.Supports))
+}
+
+func TestSnapshotManager(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	repo, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	base, err := NewBaseManager(repo)
+	r.NoError(err)
+	defer base.Close()
+
+	repo, err = noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	snapshots, err := noderepo.NewSnapshots(t.TempDir())
+	r.NoError(err)
+	m, err := NewSnapshotManager(repo, snapshots, 1, NewConflictTracker(false))
+	r.NoError(err)
+	defer m.Close()
+
+	id1, id2 := NewClaimID(*out1), NewClaimID(*out2)
+	blocks := [][]change.Change{
+		{change.New(change.AddClaim).SetOutPoint(change.NewOutPoint(*out1)).SetClaimID(id1).SetAmount(10)},
+		{change.New(change.AddClaim).SetOutPoint(change.NewOutPoint(*out2)).SetClaimID(id2).SetAmount(5)},
+		{change.New(change.AddSupport).SetOutPoint(change.NewOutPoint(*out3)).SetClaimID(id2).SetAmount(10)},
+		{},
+		{change.New(change.SpendClaim).SetOutPoint(change.NewOutPoint(*out1)).SetClaimID(id1)},
+		{},
+		{change.New(change.SpendSupport).SetOutPoint(change.NewOutPoint(*out3)).SetClaimID(id2)},
+	}
+
+	same := func(height int32) {
+		expected, err := base.NodeAt(height, name1)
+		r.NoError(err)
+		actual, err := m.NodeAt(height, name1)
+		r.NoError(err)
+		r.Equal(base.Hash(name1), m.Hash(name1))
+		if expected == nil {
+			r.Nil(actual)
+			return
+		}
+		r.NotNil(actual)
+		r.Equal(expected.TakenOverAt, actual.TakenOverAt)
+		r.Equal(expected.BestClaim.ClaimID, actual.BestClaim.ClaimID)
+		r.Len(actual.Claims, len(expected.Claims))
+		r.Len(actual.Supports, len(expected.Supports))
+	}
+
+	for i, changes := range blocks {
+		height := int32(i + 1)
+		for _, chg := range changes {
+			chg = chg.SetName(name1).SetHeight(height)
+			r.NoError(base.AppendChange(chg))
+			r.NoError(m.AppendChange(chg))
+		}
+		_, err = base.IncrementHeightTo(height)
+		r.NoError(err)
+		_, err = m.IncrementHeightTo(height)
+		r.NoError(err)
+
+		m.Invalidate([][]byte{name1}) // rebuilt from the snapshot
+		for h := int32(1); h <= height; h++ {
+			same(h)
+		}
+	}
+
+	data, err := snapshots.LoadSnapshot(name1)
+	r.NoError(err)
+	r.NotNil(data)
+
+	r.NoError(base.DecrementHeightTo([][]byte{name1}, 3))
+	r.NoError(m.DecrementHeightTo([][]byte{name1}, 3))
+	data, err = snapshots.LoadSnapshot(name1)
+	r.NoError(err)
+	r.Nil(data)
+	same(3)
+
+	// The snapshot saved materializing it at 3 doesn't cover the earlier heights.
+	sm := m.(*SnapshotManager)
+	cost, err := sm.ReplayCost(name1, 3)
+	r.NoError(err)
+	r.Zero(cost)
+	cost, err = sm.ReplayCost(name1, 2)
+	r.NoError(err)
+	r.Equal(2, cost)
+
+	r.NoError(sm.SaveSnapshot(name1, 2))
+	cost, err = sm.ReplayCost(name1, 2)
+	r.NoError(err)
+	r.Zero(cost)
+	cost, err = sm.ReplayCost(name1, 3)
+	r.NoError(err)
+	r.Equal(1, cost)
+	same(2)
+	same(3)
+}
+
+// failingManager fails to materialize the nodes, while fail is set.
+type failingManager struct {
+	Manager
+	fail bool
+}
+
+func (m *failingManager) Node(name []byte) (*Node, error) {
+	if m.fail {
+		return nil, fmt.Errorf("node of %q unavailable", name)
+	}
+	return m.Manager.Node(name)
+}
+
+func TestNormalizationForkFailure(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	repo, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+
+	base, err := NewBaseManager(repo)
+	r.NoError(err)
+	failing := &failingManager{Manager: base}
+	m := NewNormalizingManager(failing)
+
+	chg := change.New(change.AddClaim).SetName([]byte("Name")).SetOutPoint(change.NewOutPoint(*out1)).SetHeight(1)
+	r.NoError(m.AppendChange(chg))
+	for h := int32(1); h < param.NormalizedNameForkHeight; h++ {
+		_, err = m.IncrementHeightTo(h)
+		r.NoError(err)
+	}
+
+	// The names aren't re-keyed past a failure, which is retried.
+	failing.fail = true
+	_, err = m.IncrementHeightTo(param.NormalizedNameForkHeight)
+	r.Error(err)
+	failing.fail = false
+	_, err = m.IncrementHeightTo(param.NormalizedNameForkHeight)
+	r.NoError(err)
+
+	n, err := m.Node([]byte("name"))
+	r.NoError(err)
+	r.Len(n.Claims, 1)
+	n, err = m.Node([]byte("Name"))
+	r.NoError(err)
+	r.True(n == nil || len(n.Claims) == 0)
+}
+
+func TestNodeVersions(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	pebble, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+	repo := &countingRepo{Repo: pebble}
+
+	m, err := NewBaseManager(repo)
+	r.NoError(err)
+	m.(*BaseManager).SetVersionDepth(4)
+	replayed, err := NewBaseManager(repo)
+	r.NoError(err)
+
+	// The changes at 2, 4 and 5, with the node read at each height appended, as the ClaimTrie does.
+	changes := map[int32]change.Change{
+		2: change.New(change.AddClaim).SetName(name1).SetOutPoint(change.NewOutPoint(*out1)).SetHeight(2),
+		4: change.New(change.AddSupport).SetName(name1).SetOutPoint(change.NewOutPoint(*out2)).SetHeight(4),
+		5: change.New(change.AddClaim).SetName(name1).SetOutPoint(change.NewOutPoint(*out3)).SetHeight(5),
+	}
+	for h := int32(1); h <= 7; h++ {
+		if chg, ok := changes[h]; ok {
+			r.NoError(m.AppendChange(chg))
+		}
+		_, err = m.IncrementHeightTo(h)
+		r.NoError(err)
+		_, err = replayed.IncrementHeightTo(h)
+		r.NoError(err)
+		_, err = m.Node(name1)
+		r.NoError(err)
+	}
+
+	same := func(height int32) {
+		exp, err := replayed.NodeAt(height, name1)
+		r.NoError(err)
+		n, err := m.NodeAt(height, name1)
+		r.NoError(err)
+		r.Equal(exp.Clone(), n.Clone(), "at %d", height) // without the memoized keys
+	}
+
+	// The recent heights adjust the versions, and the ones below the depth are rebuilt.
+	loads := repo.loads
+	for h := int32(4); h <= 7; h++ {
+		same(h)
+	}
+	r.Equal(loads+4, repo.loads) // of the replayed ones only
+	same(2)
+	r.Equal(loads+6, repo.loads)
+
+	// The versions are clones.
+	n, err := m.NodeAt(6, name1)
+	r.NoError(err)
+	n.Claims = nil
+	same(6)
+
+	// The ones above the height reset to are dropped, and the one below is reopened.
+	r.NoError(m.DecrementHeightTo([][]byte{name1}, 4))
+	r.NoError(replayed.DecrementHeightTo([][]byte{name1}, 4))
+	loads = repo.loads
+	same(4)
+	n, err = m.Node(name1)
+	r.NoError(err)
+	r.Len(n.Claims, 1)
+	r.Len(n.Supports, 1)
+	r.Equal(loads+1, repo.loads)
+
+	// The invalidated names are rebuilt.
+	m.Invalidate([][]byte{name1})
+	loads = repo.loads
+	same(4)
+	r.Equal(loads+2, repo.loads)
+}
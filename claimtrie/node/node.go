@@ -1,7 +1,7 @@
 package node
 
 import (
-	"fmt"
+	"errors"
 	"math"
 	"sort"
 
@@ -9,14 +9,22 @@ import (
 	"github.com/btcsuite/btcd/claimtrie/param"
 )
 
-// ErrNotFound is returned when a claim or support is not found.
-var mispents = map[string]bool{}
+var (
+	// ErrMissingClaim is returned when a claim is spent, or updated, but missing from the node.
+	ErrMissingClaim = errors.New("missing claim")
+
+	// ErrMissingSupport is returned when a support is spent, but missing from the node.
+	ErrMissingSupport = errors.New("missing support")
+)
 
 type Node struct {
 	BestClaim   *Claim    // The claim that has most effective amount at the current height.
 	TakenOverAt int32     // The height at when the current BestClaim took over.
-	Claims      ClaimList // List of all Claims.
-	Supports    ClaimList // List of all Supports, including orphaned ones.
+	Claims      ClaimList // List of all Claims, in the order they were added.
+	Supports    ClaimList // List of all Supports, including orphaned ones, in the order they were added.
+
+	keysValid bool   // The sort keys of the claims, and best, are up to date.
+	best      *Claim // The activated claim with the greatest sort key, if any.
 }
 
 // New returns a new node.
@@ -24,9 +32,12 @@ func New() *Node {
 	return &Node{}
 }
 
+// ApplyChange applies the change to the claims, or supports, of the node. The keys of the claims are only
+// invalidated by the changes, which affect the effective amounts they're computed with, so that the supports
+// accepted, but not activated yet, don't recompute them.
 func (n *Node) ApplyChange(chg change.Change, delay int32) error {
 
-	out := NewOutPointFromString(chg.OutPoint)
+	out := chg.OutPoint.Wire()
 
 	visibleAt := chg.VisibleHeight
 	if visibleAt <= 0 {
@@ -36,7 +47,7 @@ func (n *Node) ApplyChange(chg change.Change, delay int32) error {
 	switch chg.Type {
 	case change.AddClaim:
 		c := &Claim{
-			OutPoint:   *out,
+			OutPoint:   out,
 			Amount:     chg.Amount,
 			ClaimID:    chg.ClaimID,
 			AcceptedAt: chg.Height, // not tracking original height in this version (but we could)
@@ -44,32 +55,29 @@ func (n *Node) ApplyChange(chg change.Change, delay int32) error {
 			Value:      chg.Value,
 			VisibleAt:  visibleAt,
 		}
-		old := n.Claims.find(byOut(*out)) // TODO: remove this after proving ResetHeight works
-		if old != nil {
-			fmt.Printf("CONFLICT WITH EXISTING TXO! Name: %s, Height: %d\n", chg.Name, chg.Height)
-		}
+		c.sortKey = NewSortKey(0, c.AcceptedAt, c.OutPoint) // not activated yet
 		n.Claims = append(n.Claims, c)
 
 	case change.SpendClaim:
-		c := n.Claims.find(byOut(*out))
-		if c != nil {
-			c.setStatus(Deactivated)
-		} else if !mispents[fmt.Sprintf("%d_%s", chg.Height, chg.ClaimID)] {
-			mispents[fmt.Sprintf("%d_%s", chg.Height, chg.ClaimID)] = true
-			fmt.Printf("Spending claim but missing existing claim with TXO %s\n   "+
-				"Name: %s, ID: %s\n", chg.OutPoint, chg.Name, chg.ClaimID)
+		c := n.Claims.find(byOut(out))
+		if c == nil {
+			// apparently it's legit to be absent in the map:
+			// 'two' at 481100, 36a719a156a1df178531f3c712b8b37f8e7cc3b36eea532df961229d936272a1:0
+			return change.Wrap(ErrMissingClaim, chg)
 		}
-		// apparently it's legit to be absent in the map:
-		// 'two' at 481100, 36a719a156a1df178531f3c712b8b37f8e7cc3b36eea532df961229d936272a1:0
+		c.setStatus(Deactivated)
+		n.invalidateKeys()
 
 	case change.UpdateClaim:
 		// Find and remove the claim, which has just been spent.
 		c := n.Claims.find(byID(chg.ClaimID))
 		if c != nil && c.Status == Deactivated {
 
+			n.invalidateKeys()
+
 			// Keep its ID, which was generated from the spent claim.
 			// And update the rest of properties.
-			c.setOutPoint(*out).SetAmt(chg.Amount).SetValue(chg.Value)
+			c.setOutPoint(out).SetAmt(chg.Amount).SetValue(chg.Value)
 			c.setStatus(Accepted) // it was Deactivated in the spend
 
 			// It's a bug, but the old code would update these.
@@ -78,11 +86,11 @@ func (n *Node) ApplyChange(chg change.Change, delay int32) error {
 			c.setActiveAt(chg.Height + delay) // TODO: Fork this out
 
 		} else {
-			fmt.Printf("Updating claim but missing existing claim with ID %s", chg.ClaimID)
+			return change.Wrap(ErrMissingClaim, chg)
 		}
 	case change.AddSupport:
 		n.Supports = append(n.Supports, &Claim{
-			OutPoint:   *out,
+			OutPoint:   out,
 			Amount:     chg.Amount,
 			ClaimID:    chg.ClaimID,
 			AcceptedAt: chg.Height,
@@ -92,13 +100,14 @@ func (n *Node) ApplyChange(chg change.Change, delay int32) error {
 		})
 
 	case change.SpendSupport:
-		s := n.Supports.find(byOut(*out))
-		if s != nil {
-			s.setStatus(Deactivated)
-		} else {
-			fmt.Printf("Spending support but missing existing support with TXO %s\n   "+
-				"Name: %s, ID: %s\n", chg.OutPoint, chg.Name, chg.ClaimID)
+		s := n.Supports.find(byOut(out))
+		if s == nil {
+			return change.Wrap(ErrMissingSupport, chg)
+		}
+		if s.Status == Activated {
+			n.invalidateKeys()
 		}
+		s.setStatus(Deactivated)
 	}
 	return nil
 }
@@ -139,7 +148,7 @@ func (n *Node) updateTakeoverHeight(height int32, name []byte, refindBest bool)
 		// This is a super ugly hack to work around bug in old code.
 		// The bug: un/support a name then update it. This will cause its takeover height to be reset to current.
 		// This is because the old code would add to the cache without setting block originals when dealing in supports.
-		_, takeoverHappening = param.TakeoverWorkarounds[fmt.Sprintf("%d_%s", height, name)] // TODO: ditch the fmt call
+		takeoverHappening = param.IsTakeoverWorkaround(height, name)
 	}
 
 	if takeoverHappening {
@@ -152,28 +161,35 @@ func (n *Node) handleExpiredAndActivated(height int32) int {
 
 	changes := 0
 	update := func(items ClaimList) ClaimList {
-		for i := 0; i < len(items); i++ {
-			c := items[i]
+		kept := items[:0] // in order, however the node was loaded, from its changes or a snapshot.
+		for _, c := range items {
 			if c.Status == Accepted && c.ActiveAt <= height && c.VisibleAt <= height {
 				c.setStatus(Activated)
 				changes++
 			}
 			if c.ExpireAt() <= height || c.Status == Deactivated {
-				if i < len(items)-1 {
-					items[i] = items[len(items)-1]
-					i--
-				}
-				items = items[:len(items)-1]
 				changes++
+				continue
 			}
+			kept = append(kept, c)
 		}
-		return items
+		return kept
 	}
 	n.Claims = update(n.Claims)
 	n.Supports = update(n.Supports)
+	if changes > 0 {
+		n.invalidateKeys()
+	}
 	return changes
 }
 
+// EffectiveAmount returns the effective amount of the claim of the node, with the supports of the node.
+// It's read from the sort key of the claim.
+func (n *Node) EffectiveAmount(c *Claim) int64 {
+	n.refreshKeys()
+	return c.sortKey.EffectiveAmount()
+}
+
 // NextUpdate returns the nearest height in the future that the node should
 // be refreshed due to changes of claims or supports.
 func (n Node) NextUpdate() int32 {
@@ -214,49 +230,12 @@ func (n Node) NextUpdate() int32 {
 	return next
 }
 
-func (n Node) findBestClaim() *Claim {
+// findBestClaim returns the activated claim with the greatest sort key, if any.
+func (n *Node) findBestClaim() *Claim {
 
 	// WARNING: this method is called billions of times.
-	// if we just had some easy way to know that our best claim was the first one in the list...
-	// or it may be faster to cache effective amount in the db at some point.
-
-	var best *Claim
-	var bestAmount int64
-	for _, candidate := range n.Claims {
-
-		// not using switch here for performance reasons
-		if candidate.Status != Activated {
-			continue
-		}
-
-		if best == nil {
-			best = candidate
-			continue
-		}
-
-		candidateAmount := candidate.EffectiveAmount(n.Supports)
-		if bestAmount <= 0 { // trying to reduce calls to EffectiveAmount
-			bestAmount = best.EffectiveAmount(n.Supports)
-		}
-
-		switch {
-		case candidateAmount > bestAmount:
-			best = candidate
-			bestAmount = candidateAmount
-		case candidateAmount < bestAmount:
-			continue
-		case candidate.AcceptedAt < best.AcceptedAt:
-			best = candidate
-			bestAmount = candidateAmount
-		case candidate.AcceptedAt > best.AcceptedAt:
-			continue
-		case OutPointLess(candidate.OutPoint, best.OutPoint):
-			best = candidate
-			bestAmount = candidateAmount
-		}
-	}
-
-	return best
+	n.refreshKeys()
+	return n.best
 }
 
 func (n *Node) activateAllClaims(height int32) int {
@@ -276,25 +255,58 @@ func (n *Node) activateAllClaims(height int32) int {
 			count++
 		}
 	}
+	if count > 0 {
+		n.invalidateKeys()
+	}
 	return count
 }
 
+// SortClaims sorts the claims by their sort keys, the greatest first.
 func (n *Node) SortClaims() {
 
-	// purposefully sorting by descent
-	sort.Slice(n.Claims, func(j, i int) bool {
-		iAmount := n.Claims[i].EffectiveAmount(n.Supports)
-		jAmount := n.Claims[j].EffectiveAmount(n.Supports)
-		switch {
-		case iAmount < jAmount:
-			return true
-		case iAmount > jAmount:
-			return false
-		case n.Claims[i].AcceptedAt > n.Claims[j].AcceptedAt:
-			return true
-		case n.Claims[i].AcceptedAt < n.Claims[j].AcceptedAt:
-			return false
-		}
-		return OutPointLess(n.Claims[j].OutPoint, n.Claims[i].OutPoint)
+	n.refreshKeys()
+	sort.Slice(n.Claims, func(i, j int) bool {
+		return n.Claims[j].sortKey.Less(&n.Claims[i].sortKey)
+	})
+}
+
+// SortedClaims returns the claims sorted by their sort keys, the greatest first, leaving the order of
+// n.Claims, which may be shared by the caches, as it is.
+func (n *Node) SortedClaims() ClaimList {
+
+	n.refreshKeys()
+	claims := make(ClaimList, len(n.Claims))
+	copy(claims, n.Claims)
+	sort.Slice(claims, func(i, j int) bool {
+		return claims[j].sortKey.Less(&claims[i].sortKey)
 	})
+
+	return claims
+}
+
+// Clone returns a deep copy of the node, which is unaffected by later changes to n.
+func (n *Node) Clone() *Node {
+
+	clone := &Node{TakenOverAt: n.TakenOverAt}
+	clone.Claims = cloneClaims(n.Claims)
+	clone.Supports = cloneClaims(n.Supports)
+	for i, c := range n.Claims {
+		if c == n.BestClaim {
+			clone.BestClaim = clone.Claims[i]
+		}
+	}
+
+	return clone
+}
+
+func cloneClaims(l ClaimList) ClaimList {
+	if l == nil {
+		return nil
+	}
+	clone := make(ClaimList, len(l))
+	for i, c := range l {
+		cc := *c
+		clone[i] = &cc
+	}
+	return clone
 }
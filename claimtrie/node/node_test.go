@@ -0,0 +1,148 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+// benchNode returns a node with the claims, each supported by the supports, all activated.
+func benchNode(claims, supports int) *Node {
+
+	n := New()
+	hash := chainhash.HashH([]byte("bench"))
+	for i := 0; i < claims; i++ {
+		op := wire.OutPoint{Hash: hash, Index: uint32(i)}
+		id := NewClaimID(op)
+		n.Claims = append(n.Claims, &Claim{OutPoint: op, ClaimID: id, Amount: int64(i + 1),
+			AcceptedAt: 1, ActiveAt: 1, VisibleAt: 1, Status: Activated})
+		for j := 0; j < supports; j++ {
+			op := wire.OutPoint{Hash: hash, Index: uint32(claims + i*supports + j)}
+			n.Supports = append(n.Supports, &Claim{OutPoint: op, ClaimID: id, Amount: int64(j + 1),
+				AcceptedAt: 1, ActiveAt: 1, VisibleAt: 1, Status: Activated})
+		}
+	}
+	n.BestClaim = n.findBestClaim()
+	n.TakenOverAt = 1
+
+	return n
+}
+
+func TestHotPathAllocations(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.MainNet)
+
+	n := benchNode(10, 5)
+	r.Zero(testing.AllocsPerRun(100, func() {
+		n.findBestClaim()
+	}))
+
+	// Below MaxRemovalWorkaroundHeight, the takeover workarounds are looked up too.
+	name := []byte("HunterxHunterAMV")
+	r.Zero(testing.AllocsPerRun(100, func() {
+		n.AdjustTo(100, -1, name)
+	}))
+
+	// Spending, and updating, claims touch the existing ones only.
+	spend := change.New(change.SpendSupport).SetOutPoint(change.NewOutPoint(n.Supports[0].OutPoint))
+	r.Zero(testing.AllocsPerRun(100, func() {
+		r.NoError(n.ApplyChange(spend, 0))
+	}))
+}
+
+func BenchmarkApplyChange(b *testing.B) {
+
+	hash := chainhash.HashH([]byte("bench"))
+	chgs := make([]change.Change, 1000)
+	for i := range chgs {
+		op := wire.OutPoint{Hash: hash, Index: uint32(i)}
+		chgs[i] = change.New(change.AddSupport).SetOutPoint(change.NewOutPoint(op)).SetAmount(1).SetHeight(1)
+	}
+
+	var n *Node
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%len(chgs) == 0 {
+			b.StopTimer()
+			n = benchNode(1, 0)
+			b.StartTimer()
+		}
+		_ = n.ApplyChange(chgs[i%len(chgs)], 0)
+	}
+}
+
+func BenchmarkAdjustTo(b *testing.B) {
+
+	param.SetNetwork(wire.MainNet)
+	n := benchNode(10, 5)
+	name := []byte("bench")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n.AdjustTo(100, -1, name)
+	}
+}
+
+func BenchmarkFindBestClaim(b *testing.B) {
+
+	n := benchNode(10, 5)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n.findBestClaim()
+	}
+}
+
+func TestClaimMaturity(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+
+	// Accepted at 100, it expires before the extended expiration fork at 800.
+	c := &Claim{AcceptedAt: 100, ActiveAt: 110, Status: Accepted}
+	r.Equal(Maturity{Confirmations: 6, BlocksUntilActive: 5, ExpireAt: 600, BlocksUntilExpired: 495}, c.MaturityAt(105))
+
+	c.Status = Activated
+	r.Equal(Maturity{Confirmations: 501, ExpireAt: 600, BlocksUntilExpired: 0}, c.MaturityAt(600))
+
+	// Accepted at 400, it's extended by the fork.
+	c = &Claim{AcceptedAt: 400, ActiveAt: 400, Status: Activated}
+	r.Equal(Maturity{Confirmations: 401, ExpireAt: 1000, BlocksUntilExpired: 200}, c.MaturityAt(800))
+	r.Equal("activated", c.Status.String())
+}
+
+func TestClaimOrder(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+
+	n := benchNode(5, 0)
+	out := func(l ClaimList) []uint32 {
+		var idx []uint32
+		for _, c := range l {
+			idx = append(idx, c.OutPoint.Index)
+		}
+		return idx
+	}
+
+	// The claims are kept in the order they were added, as the spent ones are dropped.
+	r.NoError(n.ApplyChange(change.New(change.SpendClaim).SetOutPoint(change.NewOutPoint(n.Claims[1].OutPoint)), 0))
+	n.AdjustTo(2, -1, []byte("order"))
+	r.Equal([]uint32{0, 2, 3, 4}, out(n.Claims))
+
+	// The sorted claims are a copy.
+	r.Equal([]uint32{4, 3, 2, 0}, out(n.SortedClaims()))
+	r.Equal([]uint32{0, 2, 3, 4}, out(n.Claims))
+}
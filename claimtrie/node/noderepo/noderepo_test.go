@@ -10,7 +10,7 @@ import (
 )
 
 var (
-	opStr1        = "0000000000000000000000000000000000000000000000000000000000000000:1"
+	op1           = change.NewOutPoint(*node.NewOutPointFromString("0000000000000000000000000000000000000000000000000000000000000000:1"))
 	testNodeName1 = []byte("name1")
 )
 
@@ -39,7 +39,7 @@ func testNodeRepo(t *testing.T, repo node.Repo, setup, cleanup func()) {
 
 	r := require.New(t)
 
-	chg := change.New(change.AddClaim).SetName(testNodeName1).SetOutPoint(opStr1)
+	chg := change.New(change.AddClaim).SetName(testNodeName1).SetOutPoint(op1)
 
 	testcases := []struct {
 		name     string
@@ -180,9 +180,33 @@ func TestIterator(t *testing.T) {
 	r.NoError(err)
 
 	var received []change.Change
-	repo.IterateChildren([]byte{}, func(changes []change.Change) bool {
+	repo.IterateChildren([]byte{}, func(name []byte, changes []change.Change) bool {
 		received = append(received, changes...)
 		return true
 	})
 	r.Equal(creation, received)
 }
+
+func TestCompact(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	r.NoError(repo.Compact()) // empty
+
+	chg := change.Change{Name: []byte("a"), Height: 5}
+	for i := 0; i < 10; i++ {
+		r.NoError(repo.AppendChanges([]change.Change{chg}))
+	}
+	r.NoError(repo.Compact())
+
+	changes, err := repo.LoadChanges([]byte("a"))
+	r.NoError(err)
+	r.Len(changes, 10)
+}
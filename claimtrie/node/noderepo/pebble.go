@@ -10,12 +10,25 @@ import (
 )
 
 type Pebble struct {
-	db *pebble.DB
+	db     *pebble.DB
+	prefix []byte
+	shared bool
 }
 
+// DefaultCacheSize is the size, in bytes, of the block cache of a repo opened with NewPebble.
+const DefaultCacheSize = 128 << 20
+
 func NewPebble(path string) (*Pebble, error) {
+	return NewPebbleWithCache(path, DefaultCacheSize)
+}
+
+// NewPebbleWithCache returns a repo with a block cache of cacheSize bytes.
+func NewPebbleWithCache(path string, cacheSize int64) (*Pebble, error) {
+
+	cache := pebble.NewCache(cacheSize)
+	defer cache.Unref()
 
-	db, err := pebble.Open(path, &pebble.Options{Cache: pebble.NewCache(128 << 20), BytesPerSync: 16 << 20})
+	db, err := pebble.Open(path, &pebble.Options{Cache: cache, BytesPerSync: 16 << 20})
 	if err != nil {
 		return nil, fmt.Errorf("pebble open %s, %w", path, err)
 	}
@@ -25,6 +38,19 @@ func NewPebble(path string) (*Pebble, error) {
 	return repo, nil
 }
 
+// NewPebbleShared returns a repo storing its keys under the prefix in db, which is shared with other repos.
+// Closing the repo doesn't close db.
+func NewPebbleShared(db *pebble.DB, prefix []byte) *Pebble {
+	return &Pebble{db: db, prefix: prefix, shared: true}
+}
+
+func (repo *Pebble) key(name []byte) []byte {
+	if len(repo.prefix) == 0 {
+		return name
+	}
+	return append(repo.prefix[:len(repo.prefix):len(repo.prefix)], name...)
+}
+
 // AppendChanges makes an assumption that anything you pass to it is newer than what was saved before.
 func (repo *Pebble) AppendChanges(changes []change.Change) error {
 
@@ -37,7 +63,7 @@ func (repo *Pebble) AppendChanges(changes []change.Change) error {
 			return fmt.Errorf("msgpack marshal value: %w", err)
 		}
 
-		err = batch.Merge(chg.Name, value, pebble.NoSync)
+		err = batch.Merge(repo.key(chg.Name), value, pebble.NoSync)
 		if err != nil {
 			return fmt.Errorf("pebble set: %w", err)
 		}
@@ -52,7 +78,7 @@ func (repo *Pebble) AppendChanges(changes []change.Change) error {
 
 func (repo *Pebble) LoadChanges(name []byte) ([]change.Change, error) {
 
-	data, closer, err := repo.db.Get(name)
+	data, closer, err := repo.db.Get(repo.key(name))
 	if err != nil && err != pebble.ErrNotFound {
 		return nil, fmt.Errorf("pebble get: %w", err)
 	}
@@ -99,20 +125,51 @@ func (repo *Pebble) DropChanges(name []byte, finalHeight int32) error {
 		return fmt.Errorf("pebble drop: %w", err)
 	}
 	// making a performance assumption that DropChanges won't happen often:
-	err = repo.db.Set(name, []byte{}, pebble.NoSync)
+	err = repo.db.Set(repo.key(name), []byte{}, pebble.NoSync)
 	if err != nil {
 		return fmt.Errorf("pebble drop: %w", err)
 	}
 	return repo.AppendChanges(changes[:i])
 }
 
-func (repo *Pebble) IterateChildren(name []byte, f func(changes []change.Change) bool) {
+// PruneChanges drops the changes of the name before, and at, the height.
+// The name is kept, even if none of its changes are left.
+func (repo *Pebble) PruneChanges(name []byte, height int32) error {
+	changes, err := repo.LoadChanges(name)
+	if err != nil {
+		return fmt.Errorf("pebble prune: %w", err)
+	}
+	i := 0
+	for ; i < len(changes); i++ {
+		if changes[i].Height > height {
+			break
+		}
+	}
+	if i == 0 {
+		return nil
+	}
+	err = repo.db.Set(repo.key(name), []byte{}, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble prune: %w", err)
+	}
+	return repo.AppendChanges(changes[i:])
+}
+
+func (repo *Pebble) AddName(name []byte) error {
+	err := repo.db.Merge(repo.key(name), []byte{}, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble add name: %w", err)
+	}
+	return nil
+}
+
+func (repo *Pebble) IterateChildren(name []byte, f func(name []byte, changes []change.Change) bool) {
 	end := bytes.NewBuffer(nil)
-	end.Write(name)
+	end.Write(repo.key(name))
 	end.Write(bytes.Repeat([]byte{255, 255, 255, 255}, 64))
 
 	prefixIterOptions := &pebble.IterOptions{
-		LowerBound: name,
+		LowerBound: repo.key(name),
 		UpperBound: end.Bytes(),
 	}
 
@@ -124,29 +181,81 @@ func (repo *Pebble) IterateChildren(name []byte, f func(changes []change.Change)
 		if err != nil {
 			panic(err)
 		}
-		if !f(changes) {
+		if !f(iter.Key()[len(repo.prefix):], changes) {
 			return
 		}
 	}
 }
 
 func (repo *Pebble) IterateAll(predicate func(name []byte) bool) {
-	iter := repo.db.NewIter(nil)
+	var opts *pebble.IterOptions
+	if len(repo.prefix) > 0 {
+		opts = &pebble.IterOptions{LowerBound: repo.prefix, UpperBound: prefixUpperBound(repo.prefix)}
+	}
+	iter := repo.db.NewIter(opts)
 	defer iter.Close()
 
 	for iter.First(); iter.Valid(); iter.Next() {
-		if !predicate(iter.Key()) {
+		if !predicate(iter.Key()[len(repo.prefix):]) {
 			break
 		}
 	}
 }
 
+// Flush writes the memtable of the repo to the disk.
+func (repo *Pebble) Flush() error {
+	return repo.db.Flush()
+}
+
+// Compact compacts the keys of the repo, which speeds up the reads after many of them were rewritten.
+func (repo *Pebble) Compact() error {
+
+	var opts *pebble.IterOptions
+	if len(repo.prefix) > 0 {
+		opts = &pebble.IterOptions{LowerBound: repo.prefix, UpperBound: prefixUpperBound(repo.prefix)}
+	}
+	iter := repo.db.NewIter(opts)
+	defer iter.Close()
+
+	if !iter.First() {
+		return nil
+	}
+	first := append([]byte(nil), iter.Key()...)
+	iter.Last()
+	last := append(append([]byte(nil), iter.Key()...), 0)
+
+	err := repo.db.Compact(first, last)
+	if err != nil {
+		return fmt.Errorf("pebble compact: %w", err)
+	}
+
+	return nil
+}
+
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Pebble) Backup(dir string) error {
+
+	if repo.shared {
+		return fmt.Errorf("backup of a shared repo is not supported")
+	}
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
+}
+
 func (repo *Pebble) Close() error {
 
 	err := repo.db.Flush()
 	if err != nil {
 		return fmt.Errorf("pebble flush: %w", err)
 	}
+	if repo.shared {
+		return nil
+	}
 
 	err = repo.db.Close()
 	if err != nil {
@@ -155,3 +264,15 @@ func (repo *Pebble) Close() error {
 
 	return nil
 }
+
+// prefixUpperBound returns the smallest key greater than all keys with the prefix.
+func prefixUpperBound(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil // no upper bound
+}
@@ -0,0 +1,191 @@
+package noderepo
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Snapshots is a Pebble-backed repo of the snapshots of the nodes.
+type Snapshots struct {
+	db *pebble.DB
+
+	// The writes batched since BeginBatch, if it's set, which is read through.
+	// mu guards it from the concurrent reads.
+	mu    sync.RWMutex
+	batch *pebble.Batch
+}
+
+func NewSnapshots(path string) (*Snapshots, error) {
+
+	db, err := pebble.Open(path, &pebble.Options{Cache: pebble.NewCache(64 << 20), BytesPerSync: 16 << 20})
+	if err != nil {
+		return nil, fmt.Errorf("pebble open %s, %w", path, err)
+	}
+
+	return &Snapshots{db: db}, nil
+}
+
+// reader returns the batch, if the writes are batched, or the DB. It must be called with mu held.
+func (repo *Snapshots) reader() pebble.Reader {
+	if repo.batch != nil {
+		return repo.batch
+	}
+	return repo.db
+}
+
+// writer returns the batch, if the writes are batched, or the DB. It must be called with mu held.
+func (repo *Snapshots) writer() pebble.Writer {
+	if repo.batch != nil {
+		return repo.batch
+	}
+	return repo.db
+}
+
+func (repo *Snapshots) LoadSnapshot(name []byte) ([]byte, error) {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	data, closer, err := repo.reader().Get(name)
+	if err == pebble.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pebble get: %w", err)
+	}
+	defer closer.Close()
+
+	return append([]byte(nil), data...), nil
+}
+
+func (repo *Snapshots) SaveSnapshot(name []byte, snapshot []byte) error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	err := repo.writer().Set(name, snapshot, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble set: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Snapshots) DropSnapshot(name []byte) error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	err := repo.writer().Delete(name, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble delete: %w", err)
+	}
+
+	return nil
+}
+
+// BeginBatch batches the writes until CommitBatch, which writes them at once.
+func (repo *Snapshots) BeginBatch() {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.batch == nil {
+		repo.batch = repo.db.NewIndexedBatch()
+	}
+}
+
+// CommitBatch writes, and syncs, the writes batched since BeginBatch, if any.
+func (repo *Snapshots) CommitBatch() error {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.batch == nil {
+		return nil
+	}
+
+	err := repo.batch.Commit(pebble.Sync)
+	repo.batch.Close()
+	repo.batch = nil
+	if err != nil {
+		return fmt.Errorf("pebble commit: %w", err)
+	}
+
+	return nil
+}
+
+// BatchSize returns the size, in bytes, of the writes batched since BeginBatch.
+func (repo *Snapshots) BatchSize() int {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if repo.batch == nil {
+		return 0
+	}
+	return len(repo.batch.Repr())
+}
+
+// BatchRepr returns a copy of the writes batched since BeginBatch, for ApplyBatch, or nil if there are none.
+func (repo *Snapshots) BatchRepr() []byte {
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if repo.batch == nil || repo.batch.Empty() {
+		return nil
+	}
+	return append([]byte(nil), repo.batch.Repr()...)
+}
+
+// ApplyBatch writes, and syncs, the writes returned by BatchRepr, which were batched before a crash.
+func (repo *Snapshots) ApplyBatch(repr []byte) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	err := batch.SetRepr(append([]byte(nil), repr...))
+	if err != nil {
+		return fmt.Errorf("pebble batch repr: %w", err)
+	}
+	err = batch.Commit(pebble.Sync)
+	if err != nil {
+		return fmt.Errorf("pebble commit: %w", err)
+	}
+
+	return nil
+}
+
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Snapshots) Backup(dir string) error {
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Snapshots) Close() error {
+
+	err := repo.CommitBatch()
+	if err != nil {
+		return err
+	}
+
+	err = repo.db.Flush()
+	if err != nil {
+		return fmt.Errorf("pebble flush: %w", err)
+	}
+
+	err = repo.db.Close()
+	if err != nil {
+		return fmt.Errorf("pebble close: %w", err)
+	}
+
+	return nil
+}
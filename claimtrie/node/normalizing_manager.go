@@ -25,8 +25,20 @@ func (nm *NormalizingManager) AppendChange(chg change.Change) error {
 	return nm.Manager.AppendChange(chg)
 }
 
+func (nm *NormalizingManager) PreviewNode(name []byte, changes []change.Change, height int32) (*Node, error) {
+	normalized := make([]change.Change, len(changes))
+	for i, chg := range changes {
+		chg.Name = NormalizeIfNecessary(chg.Name, chg.Height)
+		normalized[i] = chg
+	}
+	return nm.Manager.PreviewNode(name, normalized, height)
+}
+
 func (nm *NormalizingManager) IncrementHeightTo(height int32) ([][]byte, error) {
-	nm.addNormalizationForkChangesIfNecessary(height)
+	err := nm.addNormalizationForkChangesIfNecessary(height)
+	if err != nil {
+		return nil, fmt.Errorf("normalization fork: %w", err)
+	}
 	return nm.Manager.IncrementHeightTo(height)
 }
 
@@ -45,7 +57,10 @@ func (nm *NormalizingManager) NextUpdateHeightOfNode(name []byte) ([]byte, int32
 	return name, nextUpdate
 }
 
-func (nm *NormalizingManager) addNormalizationForkChangesIfNecessary(height int32) {
+// addNormalizationForkChangesIfNecessary re-keys the claims and supports of the names, which aren't normalized,
+// under their normalized names at the fork. It's retried by the next call, if it fails, as the node manager
+// dedups the changes appended again.
+func (nm *NormalizingManager) addNormalizationForkChangesIfNecessary(height int32) error {
 
 	if nm.Manager.Height()+1 != height {
 		// initialization phase
@@ -55,13 +70,13 @@ func (nm *NormalizingManager) addNormalizationForkChangesIfNecessary(height int3
 	}
 
 	if nm.normalizedAt >= 0 || height != param.NormalizedNameForkHeight {
-		return
+		return nil
 	}
-	nm.normalizedAt = height
-	fmt.Printf("Generating necessary changes for the normalization fork...\n")
+	log.Info("Generating necessary changes for the normalization fork...")
 
 	// the original code had an unfortunate bug where many unnecessary takeovers
 	// were triggered at the normalization fork
+	var err error
 	predicate := func(name []byte) bool {
 		norm := Normalize(name)
 		eq := bytes.Equal(name, norm)
@@ -74,50 +89,70 @@ func (nm *NormalizingManager) addNormalizationForkChangesIfNecessary(height int3
 
 		// by loading changes for norm here, you can determine if there will be a conflict
 
-		n, err := nm.Manager.Node(clone)
-		if err != nil || n == nil {
+		var n *Node
+		n, err = nm.Manager.Node(clone)
+		if err != nil {
+			err = fmt.Errorf("node %q: %w", clone, err)
+			return false
+		}
+		if n == nil {
 			return true
 		}
+		var changes []change.Change
 		for _, c := range n.Claims {
-			nm.Manager.AppendChange(change.Change{
+			changes = append(changes, change.Change{
 				Type:          change.AddClaim,
 				Name:          norm,
 				Height:        c.AcceptedAt,
-				OutPoint:      c.OutPoint.String(),
+				OutPoint:      change.NewOutPoint(c.OutPoint),
 				ClaimID:       c.ClaimID,
 				Amount:        c.Amount,
 				Value:         c.Value,
 				ActiveHeight:  c.ActiveAt, // necessary to match the old hash
 				VisibleHeight: height,     // necessary to match the old hash; it would have been much better without
 			})
-			nm.Manager.AppendChange(change.Change{
+			changes = append(changes, change.Change{
 				Type:     change.SpendClaim,
 				Name:     clone,
 				Height:   height,
-				OutPoint: c.OutPoint.String(),
+				OutPoint: change.NewOutPoint(c.OutPoint),
 			})
 		}
 		for _, c := range n.Supports {
-			nm.Manager.AppendChange(change.Change{
+			changes = append(changes, change.Change{
 				Type:          change.AddSupport,
 				Name:          norm,
 				Height:        c.AcceptedAt,
-				OutPoint:      c.OutPoint.String(),
+				OutPoint:      change.NewOutPoint(c.OutPoint),
 				ClaimID:       c.ClaimID,
 				Amount:        c.Amount,
 				Value:         c.Value,
 				ActiveHeight:  c.ActiveAt,
 				VisibleHeight: height,
 			})
-			nm.Manager.AppendChange(change.Change{
+			changes = append(changes, change.Change{
 				Type:     change.SpendSupport,
 				Name:     clone,
 				Height:   height,
-				OutPoint: c.OutPoint.String(),
+				OutPoint: change.NewOutPoint(c.OutPoint),
 			})
 		}
 
+		for _, chg := range changes {
+			err = nm.Manager.AppendChange(chg)
+			if err != nil {
+				err = change.Wrap(fmt.Errorf("append change: %w", err), chg)
+				return false
+			}
+		}
+
 		return true
 	}
 	nm.Manager.IterateNames(predicate)
+	if err != nil {
+		return err
+	}
+	nm.normalizedAt = height
+
+	return nil
 }
@@ -17,13 +17,31 @@ type Repo interface {
 
 	DropChanges(name []byte, finalHeight int32) error
 
+	// PruneChanges drops the changes of the name before, and at, the height,
+	// which are covered by the base snapshot of the node.
+	PruneChanges(name []byte, height int32) error
+
+	// AddName keeps the name, even without any changes, as the ones covered by a base snapshot only are.
+	AddName(name []byte) error
+
 	// Close closes the repo.
 	Close() error
 
-	// IterateChildren returns change sets for each of name.+
+	// IterateChildren returns change sets for each of name.+, along with the names.
 	// Return false on f to stop the iteration.
-	IterateChildren(name []byte, f func(changes []change.Change) bool)
+	IterateChildren(name []byte, f func(name []byte, changes []change.Change) bool)
 
 	// IterateAll iterates keys until the predicate function returns false
 	IterateAll(predicate func(name []byte) bool)
 }
+
+// SnapshotRepo defines APIs for the SnapshotManager to persist the latest snapshot of each node.
+type SnapshotRepo interface {
+	// LoadSnapshot returns the snapshot of the name, or nil if there's none.
+	LoadSnapshot(name []byte) ([]byte, error)
+	SaveSnapshot(name []byte, snapshot []byte) error
+	DropSnapshot(name []byte) error
+
+	// Close closes the repo.
+	Close() error
+}
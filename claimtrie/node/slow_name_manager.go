@@ -0,0 +1,58 @@
+package node
+
+import (
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+type SlowNameManager struct { // implements Manager
+	Manager
+	threshold time.Duration
+}
+
+// NewSlowNameManager returns a Manager, which logs the names taking longer
+// than threshold to be resolved, or sorted and hashed.
+func NewSlowNameManager(manager Manager, threshold time.Duration) Manager {
+	return &SlowNameManager{
+		Manager:   manager,
+		threshold: threshold,
+	}
+}
+
+func (sm *SlowNameManager) Node(name []byte) (*Node, error) {
+	start := time.Now()
+	n, err := sm.Manager.Node(name)
+	sm.report("resolving", name, start)
+	return n, err
+}
+
+func (sm *SlowNameManager) ClaimHashes(name []byte) []*chainhash.Hash {
+	start := time.Now()
+	hashes := sm.Manager.ClaimHashes(name)
+	sm.report("sorting claims of", name, start)
+	return hashes
+}
+
+func (sm *SlowNameManager) Hash(name []byte) *chainhash.Hash {
+	start := time.Now()
+	hash := sm.Manager.Hash(name)
+	sm.report("hashing", name, start)
+	return hash
+}
+
+func (sm *SlowNameManager) report(op string, name []byte, start time.Time) {
+
+	elapsed := time.Since(start)
+	if elapsed < sm.threshold {
+		return
+	}
+
+	// The node is cached by now, so this doesn't cost much.
+	claims, supports := 0, 0
+	if n, err := sm.Manager.Node(name); err == nil && n != nil {
+		claims, supports = len(n.Claims), len(n.Supports)
+	}
+	log.Warnf("Slow name: %s %q at height %d took %s, claims: %d, supports: %d",
+		op, name, sm.Manager.Height(), elapsed, claims, supports)
+}
@@ -0,0 +1,430 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ErrPruned is returned for a node at a height, of which the changes were pruned.
+var ErrPruned = errors.New("the changes of the node at the height are pruned")
+
+type SnapshotManager struct { // implements Manager
+	*BaseManager
+	snapshots SnapshotRepo
+	threshold int
+
+	// The base snapshots of the nodes, which cover their pruned changes, if pruning is enabled.
+	bases SnapshotRepo
+}
+
+// NewSnapshotManager returns a Manager, which materializes the nodes from their latest
+// snapshots and the changes since, instead of replaying all their changes.
+// A snapshot is saved once more than threshold changes of a node were replayed.
+func NewSnapshotManager(repo Repo, snapshots SnapshotRepo, threshold int, tracker *ConflictTracker) (Manager, error) {
+	return NewSnapshotManagerWithBases(repo, snapshots, nil, threshold, tracker)
+}
+
+// NewSnapshotManagerWithBases returns a SnapshotManager, which can Prune the changes of the
+// nodes, once they're covered by the base snapshots saved into bases.
+func NewSnapshotManagerWithBases(repo Repo, snapshots, bases SnapshotRepo, threshold int, tracker *ConflictTracker) (Manager, error) {
+
+	base, err := NewBaseManagerWithTracker(repo, tracker)
+	if err != nil {
+		return nil, err
+	}
+
+	sm := &SnapshotManager{
+		BaseManager: base.(*BaseManager),
+		snapshots:   snapshots,
+		threshold:   threshold,
+		bases:       bases,
+	}
+	sm.load = sm.loadFromSnapshot
+	sm.pruned = bases != nil
+
+	return sm, nil
+}
+
+// snapshot is a node with the changes up to, and at, Height applied,
+// which isn't adjusted past the Height.
+type snapshot struct {
+	Height      int32
+	TakenOverAt int32
+	Best        int    // The index of the BestClaim in Claims, or -1.
+	BestClaim   *Claim // Set if the BestClaim isn't in Claims.
+	Claims      ClaimList
+	Supports    ClaimList
+}
+
+func (sm *SnapshotManager) loadFromSnapshot(ctx context.Context, name []byte, height int32) (*Node, error) {
+
+	n, previous, count, err := sm.materialize(ctx, name, height)
+	if err != nil || n == nil {
+		return nil, err
+	}
+
+	if count > sm.threshold {
+		err = sm.saveSnapshot(sm.snapshots, name, n, previous)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return n.AdjustTo(previous, height, name), nil
+}
+
+// materialize returns the node of the name with the changes up to the height applied, the height
+// of the last of them, which it isn't adjusted past, and the number of the changes replayed.
+func (sm *SnapshotManager) materialize(ctx context.Context, name []byte, height int32) (*Node, int32, int, error) {
+
+	changes, err := sm.repo.LoadChanges(name)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("load changes from node repo: %w", err)
+	}
+
+	n, previous, err := sm.loadSnapshot(sm.snapshots, name)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if n != nil && previous > height {
+		n = nil
+	}
+	if sm.bases != nil {
+		base, baseHeight, err := sm.loadSnapshot(sm.bases, name)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if base != nil && baseHeight > height {
+			return nil, 0, 0, fmt.Errorf("%w: %q at %d, pruned up to %d", ErrPruned, name, height, baseHeight)
+		}
+		if base != nil && (n == nil || baseHeight > previous) {
+			n, previous = base, baseHeight
+		}
+	}
+	if n == nil && len(changes) == 0 {
+		return nil, 0, 0, nil
+	}
+
+	if n != nil {
+		i := 0
+		for i < len(changes) && changes[i].Height <= previous {
+			i++
+		}
+		changes = changes[i:]
+	}
+
+	fresh := n == nil
+	if fresh {
+		n, previous = New(), changes[0].Height
+	}
+
+	count, err := sm.applyChanges(ctx, n, previous, changes, height)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("create node from changes: %w", err)
+	}
+	if count <= 0 {
+		if fresh {
+			return nil, 0, 0, nil
+		}
+		return n, previous, 0, nil
+	}
+
+	return n, changes[count-1].Height, count, nil
+}
+
+// ReplayCost returns the number of the changes replayed onto the latest snapshot of the name,
+// or its base, to materialize it at the height, without applying them.
+func (sm *SnapshotManager) ReplayCost(name []byte, height int32) (int, error) {
+
+	changes, err := sm.repo.LoadChanges(name)
+	if err != nil {
+		return 0, fmt.Errorf("load changes from node repo: %w", err)
+	}
+
+	n, previous, err := sm.loadSnapshot(sm.snapshots, name)
+	if err != nil {
+		return 0, err
+	}
+	if n == nil || previous > height {
+		previous = 0
+	}
+	if sm.bases != nil {
+		base, baseHeight, err := sm.loadSnapshot(sm.bases, name)
+		if err != nil {
+			return 0, err
+		}
+		if base != nil && baseHeight > previous && baseHeight <= height {
+			previous = baseHeight
+		}
+	}
+
+	cost := 0
+	for _, chg := range changes {
+		if chg.Height > previous && chg.Height <= height {
+			cost++
+		}
+	}
+
+	return cost, nil
+}
+
+// SaveSnapshot saves the snapshot of the node of the name at the height, so that materializing it
+// replays none of its changes up to then, regardless of the threshold.
+func (sm *SnapshotManager) SaveSnapshot(name []byte, height int32) error {
+
+	n, previous, count, err := sm.materialize(context.Background(), name, height)
+	if err != nil || n == nil || count == 0 {
+		return err
+	}
+
+	return sm.saveSnapshot(sm.snapshots, name, n, previous)
+}
+
+// loadSnapshot returns the snapshot of the name in the repo, and its height, or nil if there's none.
+func (sm *SnapshotManager) loadSnapshot(repo SnapshotRepo, name []byte) (*Node, int32, error) {
+
+	data, err := repo.LoadSnapshot(name)
+	if err != nil {
+		return nil, 0, fmt.Errorf("load snapshot: %w", err)
+	}
+	if data == nil {
+		return nil, 0, nil
+	}
+
+	return DecodeSnapshot(data)
+}
+
+// DecodeSnapshot returns the node encoded by EncodeSnapshot, and its height.
+func DecodeSnapshot(data []byte) (*Node, int32, error) {
+
+	s, err := decodeSnapshotOrMsgpack(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	n := &Node{TakenOverAt: s.TakenOverAt, Claims: s.Claims, Supports: s.Supports, BestClaim: s.BestClaim}
+	if s.Best >= 0 && s.Best < len(s.Claims) {
+		n.BestClaim = s.Claims[s.Best]
+	}
+
+	return n, s.Height, nil
+}
+
+// Prune saves the nodes at the height as their base snapshots, and drops their changes before,
+// and at, the height, which aren't needed to materialize them at, or after, it anymore.
+// It returns the number of the nodes pruned. The nodes can't be rolled back past the height after.
+func (sm *SnapshotManager) Prune(height int32) (int, error) {
+
+	if sm.bases == nil {
+		return 0, fmt.Errorf("pruning requires a base snapshot repo")
+	}
+
+	pruned := 0
+	var err error
+	sm.repo.IterateAll(func(key []byte) bool {
+		name := append([]byte(nil), key...)
+		var changes []change.Change
+		changes, err = sm.repo.LoadChanges(name)
+		if err != nil {
+			err = fmt.Errorf("load changes from node repo: %w", err)
+			return false
+		}
+		if len(changes) == 0 || changes[0].Height > height {
+			return true
+		}
+
+		var n *Node
+		var last int32
+		n, last, _, err = sm.materialize(context.Background(), name, height)
+		if err != nil || n == nil {
+			return err == nil
+		}
+		err = sm.saveSnapshot(sm.bases, name, n, last)
+		if err != nil {
+			return false
+		}
+		err = sm.repo.PruneChanges(name, height)
+		if err != nil {
+			err = fmt.Errorf("prune changes: %w", err)
+			return false
+		}
+		pruned++
+		return true
+	})
+
+	return pruned, err
+}
+
+// CompactExpired replaces the change histories of the names, of which the nodes are left with no claims, or
+// supports, at the height, and which have no changes after it, with tombstones: their empty nodes saved as the
+// base snapshots at the height. It returns the number of the names compacted. Their nodes can't be materialized,
+// or rolled back, before the height after, while the ones of the other names still are.
+func (sm *SnapshotManager) CompactExpired(height int32) (int, error) {
+
+	if sm.bases == nil {
+		return 0, fmt.Errorf("compaction requires a base snapshot repo")
+	}
+
+	compacted := 0
+	var err error
+	sm.repo.IterateAll(func(key []byte) bool {
+		name := append([]byte(nil), key...)
+		var changes []change.Change
+		changes, err = sm.repo.LoadChanges(name)
+		if err != nil {
+			err = fmt.Errorf("load changes from node repo: %w", err)
+			return false
+		}
+		if len(changes) == 0 || changes[len(changes)-1].Height > height {
+			return true
+		}
+
+		var n *Node
+		var last int32
+		n, last, _, err = sm.materialize(context.Background(), name, height)
+		if err != nil || n == nil {
+			return err == nil
+		}
+		n = n.AdjustTo(last, height, name)
+		if len(n.Claims) > 0 || len(n.Supports) > 0 {
+			return true
+		}
+
+		err = sm.saveSnapshot(sm.bases, name, n, height)
+		if err != nil {
+			return false
+		}
+		err = sm.repo.PruneChanges(name, height)
+		if err != nil {
+			err = fmt.Errorf("prune changes: %w", err)
+			return false
+		}
+		err = sm.snapshots.DropSnapshot(name) // it's covered by the tombstone
+		if err != nil {
+			err = fmt.Errorf("drop snapshot: %w", err)
+			return false
+		}
+		compacted++
+		return true
+	})
+
+	return compacted, err
+}
+
+// ImportBase saves the node, with its changes up to, and at, the height applied, as the base snapshot of the
+// name, which has no changes in the repo, as when a ClaimTrie is bootstrapped from an export of its state.
+func (sm *SnapshotManager) ImportBase(name []byte, n *Node, height int32) error {
+
+	if sm.bases == nil {
+		return fmt.Errorf("importing requires a base snapshot repo")
+	}
+
+	err := sm.saveSnapshot(sm.bases, name, n, height)
+	if err != nil {
+		return err
+	}
+	err = sm.repo.AddName(name)
+	if err != nil {
+		return fmt.Errorf("add name to node repo: %w", err)
+	}
+
+	return nil
+}
+
+// EncodeSnapshot returns the node, with its changes up to, and at, the height applied, in the format of the snapshots.
+func EncodeSnapshot(n *Node, height int32) ([]byte, error) {
+
+	s := snapshot{Height: height, TakenOverAt: n.TakenOverAt, Best: -1, Claims: n.Claims, Supports: n.Supports}
+	for i, c := range n.Claims {
+		if c == n.BestClaim {
+			s.Best = i
+		}
+	}
+	if s.Best < 0 {
+		s.BestClaim = n.BestClaim
+	}
+
+	return encodeSnapshot(&s), nil
+}
+
+// decodeSnapshotOrMsgpack returns the snapshot, either in the binary format, or in msgpack, as stored by earlier versions.
+func decodeSnapshotOrMsgpack(data []byte) (*snapshot, error) {
+
+	if len(data) > 0 && data[0] == snapshotMarker {
+		return decodeSnapshot(data)
+	}
+
+	var s snapshot
+	err := msgpack.Unmarshal(data, &s)
+	if err != nil {
+		return nil, fmt.Errorf("msgpack unmarshal snapshot: %w", err)
+	}
+
+	return &s, nil
+}
+
+func (sm *SnapshotManager) saveSnapshot(repo SnapshotRepo, name []byte, n *Node, height int32) error {
+
+	data, err := EncodeSnapshot(n, height)
+	if err != nil {
+		return err
+	}
+	err = repo.SaveSnapshot(name, data)
+	if err != nil {
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// AppendChange drops the snapshot of the name, if the change is dated before the next height,
+// as the ones at the normalization fork are.
+func (sm *SnapshotManager) AppendChange(chg change.Change) error {
+
+	if chg.Height <= sm.height {
+		if err := sm.snapshots.DropSnapshot(chg.Name); err != nil {
+			return fmt.Errorf("drop snapshot: %w", err)
+		}
+	}
+
+	return sm.BaseManager.AppendChange(chg)
+}
+
+// DecrementHeightTo drops the snapshots of the affected names, as well as their changes past the height.
+func (sm *SnapshotManager) DecrementHeightTo(affectedNames [][]byte, height int32) error {
+
+	for _, name := range affectedNames {
+		if err := sm.snapshots.DropSnapshot(name); err != nil {
+			return fmt.Errorf("drop snapshot: %w", err)
+		}
+	}
+
+	return sm.BaseManager.DecrementHeightTo(affectedNames, height)
+}
+
+func (sm *SnapshotManager) Close() error {
+
+	err := sm.BaseManager.Close()
+	if err != nil {
+		return err
+	}
+
+	err = sm.snapshots.Close()
+	if err != nil {
+		return fmt.Errorf("close snapshot repo: %w", err)
+	}
+
+	if sm.bases != nil {
+		err = sm.bases.Close()
+		if err != nil {
+			return fmt.Errorf("close base snapshot repo: %w", err)
+		}
+	}
+
+	return nil
+}
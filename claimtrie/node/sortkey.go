@@ -0,0 +1,107 @@
+package node
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// SortKeySize is the size of a SortKey.
+const SortKeySize = 8 + 4 + chainhash.HashSize + 4
+
+// SortKey orders the claims of a node by their precedence with bytes.Compare: the greater key
+// has the greater effective amount, then the earlier AcceptedAt, then the lesser outpoint.
+// The best claim of a node has the greatest key among its activated claims.
+//
+//	effectiveAmount(8B) ^acceptedAt(4B) ^hash(32B) ^index(4B)
+//
+// The integers are big-endian with their sign bits flipped, and ^ inverts the bits.
+type SortKey [SortKeySize]byte
+
+// NewSortKey returns the key of a claim with the effective amount.
+func NewSortKey(effectiveAmount int64, acceptedAt int32, op wire.OutPoint) SortKey {
+
+	var k SortKey
+	binary.BigEndian.PutUint64(k[:], uint64(effectiveAmount)^1<<63)
+	binary.BigEndian.PutUint32(k[8:], ^(uint32(acceptedAt) ^ 1<<31))
+	for i, b := range op.Hash {
+		k[12+i] = ^b
+	}
+	binary.BigEndian.PutUint32(k[12+chainhash.HashSize:], ^op.Index)
+
+	return k
+}
+
+// Less reports whether k sorts before other, that is, it has the lesser precedence.
+func (k *SortKey) Less(other *SortKey) bool {
+	return bytes.Compare(k[:], other[:]) < 0
+}
+
+// EffectiveAmount returns the effective amount the key was created with.
+func (k *SortKey) EffectiveAmount() int64 {
+	return int64(binary.BigEndian.Uint64(k[:]) ^ 1<<63)
+}
+
+// SortKey returns the key of the claim of the node, of which the effective amount
+// includes the supports of the node.
+func (n *Node) SortKey(c *Claim) SortKey {
+	n.refreshKeys()
+	return c.sortKey
+}
+
+// supportTotals are the scratch maps of the amounts of the supports counted for each claim ID.
+var supportTotals = sync.Pool{New: func() interface{} { return map[ClaimID]int64{} }}
+
+// refreshKeys computes the keys of the claims, and the best claim among them, unless they're still valid.
+// They're invalidated by the changes of the claims or supports. The supports are summed up by their claim
+// IDs in a single pass, so it's linear in the claims and supports, however many claims they're split among.
+func (n *Node) refreshKeys() {
+
+	if n.keysValid {
+		return
+	}
+
+	n.best = nil
+	if len(n.Claims) == 1 {
+		c := n.Claims[0]
+		c.sortKey = NewSortKey(c.EffectiveAmount(n.Supports), c.AcceptedAt, c.OutPoint)
+		if c.Status == Activated {
+			n.best = c
+		}
+		n.keysValid = true
+		return
+	}
+
+	totals := supportTotals.Get().(map[ClaimID]int64)
+	for _, s := range n.Supports {
+		if s.Status == Activated {
+			totals[s.ClaimID] += s.Amount
+		}
+	}
+
+	for _, c := range n.Claims {
+		var amt int64
+		if c.Status == Activated {
+			amt = c.Amount + totals[c.ClaimID]
+		}
+		c.sortKey = NewSortKey(amt, c.AcceptedAt, c.OutPoint)
+		if c.Status == Activated && (n.best == nil || n.best.sortKey.Less(&c.sortKey)) {
+			n.best = c
+		}
+	}
+
+	for id := range totals {
+		delete(totals, id)
+	}
+	supportTotals.Put(totals)
+
+	n.keysValid = true
+}
+
+// invalidateKeys marks the keys of the claims for recomputing.
+func (n *Node) invalidateKeys() {
+	n.keysValid = false
+}
@@ -0,0 +1,157 @@
+package node
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+// precedes is the comparator the sort keys replace.
+func precedes(n *Node, a, b *Claim) bool {
+	aAmount, bAmount := a.EffectiveAmount(n.Supports), b.EffectiveAmount(n.Supports)
+	switch {
+	case aAmount != bAmount:
+		return aAmount > bAmount
+	case a.AcceptedAt != b.AcceptedAt:
+		return a.AcceptedAt < b.AcceptedAt
+	}
+	return OutPointLess(a.OutPoint, b.OutPoint)
+}
+
+func TestSortKey(t *testing.T) {
+
+	r := require.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+	for round := 0; round < 100; round++ {
+		n := New()
+		for i := 0; i < 1+rnd.Intn(8); i++ {
+			op := wire.OutPoint{Hash: chainhash.Hash{byte(rnd.Intn(3))}, Index: uint32(rnd.Intn(3) + 10*i)}
+			status := Activated
+			if rnd.Intn(4) == 0 {
+				status = Accepted
+			}
+			id := NewClaimID(op)
+			n.Claims = append(n.Claims, &Claim{OutPoint: op, ClaimID: id, Amount: int64(rnd.Intn(3)),
+				AcceptedAt: int32(rnd.Intn(3)), Status: status})
+			if rnd.Intn(2) == 0 {
+				n.Supports = append(n.Supports, &Claim{ClaimID: id, Amount: int64(rnd.Intn(3)), Status: Activated})
+			}
+		}
+
+		var expected *Claim
+		for _, c := range n.Claims {
+			if c.Status == Activated && (expected == nil || precedes(n, c, expected)) {
+				expected = c
+			}
+		}
+		r.Equal(expected, n.findBestClaim())
+
+		n.SortClaims()
+		r.True(sort.SliceIsSorted(n.Claims, func(i, j int) bool { return precedes(n, n.Claims[i], n.Claims[j]) }))
+
+		// The keys sort the claims the same way externally.
+		keys := make([]SortKey, len(n.Claims))
+		for i, c := range n.Claims {
+			keys[i] = n.SortKey(c)
+		}
+		r.True(sort.SliceIsSorted(keys, func(i, j int) bool { return bytes.Compare(keys[j][:], keys[i][:]) < 0 }))
+	}
+}
+
+func TestSortKeyInvalidation(t *testing.T) {
+
+	r := require.New(t)
+
+	n := benchNode(2, 0)
+	r.Equal(n.Claims[1], n.findBestClaim())
+
+	// A support activated for the lesser claim takes it over.
+	op := wire.OutPoint{Hash: chainhash.HashH([]byte("support")), Index: 0}
+	chg := change.New(change.AddSupport).SetOutPoint(change.NewOutPoint(op)).SetAmount(10).SetHeight(2)
+	chg.ClaimID = n.Claims[0].ClaimID
+	r.NoError(n.ApplyChange(chg, 0))
+	r.Equal(n.Claims[1], n.findBestClaim()) // not activated yet
+	n.handleExpiredAndActivated(2)
+	r.Equal(n.Claims[0], n.findBestClaim())
+
+	k1, k2 := NewSortKey(1, 0, wire.OutPoint{}), NewSortKey(-1, 0, wire.OutPoint{})
+	r.True(k2.Less(&k1))
+	k1, k2 = NewSortKey(0, 1, wire.OutPoint{}), NewSortKey(0, 2, wire.OutPoint{})
+	r.True(k2.Less(&k1))
+}
+
+func TestSortKeyIncremental(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	defer param.SetNetwork(wire.TestNet)
+
+	// The best claim, and the effective amounts, kept up to date by the changes match the ones recomputed.
+	rnd := rand.New(rand.NewSource(1))
+	hash := chainhash.HashH([]byte("incremental"))
+	for round := 0; round < 20; round++ {
+		n := New()
+		var ids []ClaimID
+		var supports []wire.OutPoint
+		for h := int32(1); h < 40; h++ {
+			for i := 0; i < rnd.Intn(4); i++ {
+				op := wire.OutPoint{Hash: hash, Index: uint32(h*100) + uint32(i)}
+				chg := change.New(change.AddClaim).SetOutPoint(change.NewOutPoint(op)).SetHeight(h)
+				switch {
+				case len(ids) == 0 || rnd.Intn(3) == 0:
+					chg = chg.SetClaimID(NewClaimID(op)).SetAmount(int64(1 + rnd.Intn(5)))
+					ids = append(ids, chg.ClaimID)
+				case rnd.Intn(2) == 0:
+					chg.Type = change.AddSupport
+					chg = chg.SetClaimID(ids[rnd.Intn(len(ids))]).SetAmount(int64(1 + rnd.Intn(5)))
+					supports = append(supports, op)
+				case len(supports) > 0:
+					chg.Type = change.SpendSupport
+					chg = chg.SetOutPoint(change.NewOutPoint(supports[rnd.Intn(len(supports))]))
+				default:
+					continue
+				}
+				_ = n.ApplyChange(chg, int32(rnd.Intn(3)))
+				requireKeysUpToDate(r, n)
+			}
+			n.AdjustTo(h, -1, nil)
+			requireKeysUpToDate(r, n)
+		}
+	}
+}
+
+func BenchmarkRefreshKeys(b *testing.B) {
+
+	// Along the lines of the largest names of the mainnet, of which the claims have thousands of supports.
+	n := benchNode(100, 100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n.invalidateKeys()
+		n.findBestClaim()
+	}
+}
+
+func requireKeysUpToDate(r *require.Assertions, n *Node) {
+
+	best, rebuilt := n.findBestClaim(), n.Clone().findBestClaim()
+	if best == nil {
+		r.Nil(rebuilt)
+	} else {
+		r.Equal(best.ClaimID, rebuilt.ClaimID)
+	}
+	for _, c := range n.Claims {
+		r.Equal(c.EffectiveAmount(n.Supports), n.EffectiveAmount(c))
+	}
+}
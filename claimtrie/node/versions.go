@@ -0,0 +1,172 @@
+package node
+
+import (
+	"math"
+	"sync"
+)
+
+// versionCache holds the versions of the nodes changed in the last depth blocks: a version is the node of a name as
+// materialized at the height of its changes, which stands for the node at the heights from it up to the next changes
+// of the name, with the activations and takeovers due in between applied to a clone of it. The versions are never
+// modified, so they're copy-on-write snapshots shared by the queries at any of the heights. It's safe for concurrent access.
+type versionCache struct {
+	mu     sync.RWMutex
+	depth  int32
+	height int32 // The committed one.
+
+	versions map[string][]*nodeVersion // In order by height.
+	added    []versionKey              // In order by height, for pruning them.
+
+	// The names changed by the last increment, by the height of their last changes, which are recorded,
+	// once they're materialized at it.
+	changed map[string]int32
+}
+
+type nodeVersion struct {
+	height int32 // Of the changes.
+	until  int32 // The height of the next changes, or openVersion.
+	node   *Node // nil for the names without claims nor supports.
+}
+
+type versionKey struct {
+	name   string
+	height int32
+}
+
+const openVersion = math.MaxInt32
+
+func newVersionCache(depth int32, height int32) *versionCache {
+	return &versionCache{depth: depth, height: height, versions: map[string][]*nodeVersion{}, changed: map[string]int32{}}
+}
+
+// get returns a clone of the node of the name at the height, adjusted to it, if a version covers it.
+func (c *versionCache) get(name []byte, height int32) (*Node, bool) {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if height > c.height {
+		return nil, false
+	}
+	vs := c.versions[string(name)]
+	for i := len(vs) - 1; i >= 0; i-- {
+		v := vs[i]
+		if v.height > height {
+			continue
+		}
+		if height >= v.until {
+			return nil, false
+		}
+		if v.node == nil {
+			return nil, true
+		}
+		return v.node.Clone().AdjustTo(v.height, height, name), true
+	}
+
+	return nil, false
+}
+
+// record records a clone of the node of the name, materialized at the height, if the name was changed at it.
+func (c *versionCache) record(name []byte, height int32, n *Node) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if h, ok := c.changed[string(name)]; !ok || h != height || height != c.height {
+		return
+	}
+	delete(c.changed, string(name))
+	if n != nil {
+		n = n.Clone()
+	}
+	c.versions[string(name)] = append(c.versions[string(name)], &nodeVersion{height: height, until: openVersion, node: n})
+	c.added = append(c.added, versionKey{name: string(name), height: height})
+}
+
+// incremented closes the versions of the changes, appended up to the height, and drops the ones
+// recorded at, or before, the depth below it.
+func (c *versionCache) incremented(changes []versionKey, height int32) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name := range c.changed {
+		delete(c.changed, name)
+	}
+	for _, chg := range changes {
+		vs := c.versions[chg.name]
+		for len(vs) > 0 && vs[len(vs)-1].height >= chg.height { // changes appended below the last version
+			vs = vs[:len(vs)-1]
+		}
+		if len(vs) > 0 && vs[len(vs)-1].until > chg.height {
+			vs[len(vs)-1].until = chg.height
+		}
+		c.versions[chg.name] = vs
+		if h, ok := c.changed[chg.name]; !ok || chg.height > h {
+			c.changed[chg.name] = chg.height
+		}
+	}
+	c.height = height
+
+	c.prune(height - c.depth)
+}
+
+// prune drops the versions recorded at, or below, the height.
+func (c *versionCache) prune(height int32) {
+
+	i := 0
+	for ; i < len(c.added) && c.added[i].height <= height; i++ {
+		key := c.added[i]
+		vs := c.versions[key.name]
+		if len(vs) == 0 || vs[0].height != key.height { // dropped since
+			continue
+		}
+		if len(vs) == 1 {
+			delete(c.versions, key.name)
+			continue
+		}
+		vs[0] = nil
+		c.versions[key.name] = vs[1:]
+	}
+	c.added = c.added[i:]
+}
+
+// decremented drops the versions above the height, and reopens the ones closed by their changes.
+func (c *versionCache) decremented(height int32) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name := range c.changed {
+		delete(c.changed, name)
+	}
+	for name, vs := range c.versions {
+		for len(vs) > 0 && vs[len(vs)-1].height > height {
+			vs = vs[:len(vs)-1]
+		}
+		if len(vs) == 0 {
+			delete(c.versions, name)
+			continue
+		}
+		if vs[len(vs)-1].until > height {
+			vs[len(vs)-1].until = openVersion
+		}
+		c.versions[name] = vs
+	}
+	for len(c.added) > 0 && c.added[len(c.added)-1].height > height {
+		c.added = c.added[:len(c.added)-1]
+	}
+	c.height = height
+}
+
+// drop drops all the versions of the names.
+func (c *versionCache) drop(names [][]byte) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, name := range names {
+		delete(c.versions, string(name))
+		delete(c.changed, string(name))
+	}
+}
@@ -0,0 +1,409 @@
+package outpointrepo
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/outpoint"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Key formats:
+//
+//	'o' + outpoint(36B): flags(1B) + claim ID(20B) + [owner length(2B) + owner] + name, the entry of the outpoint.
+//	'a' + owner length(2B) + owner + outpoint(36B): the outpoints paid to the owner.
+//	'c' + height(4B) + outpoint(36B): the outpoints created at the height.
+//	's' + height(4B) + outpoint(36B): the entries of the outpoints spent at the height.
+//	'h': the height(4B) last updated, or rewound, to.
+const (
+	entryPrefix   = 'o'
+	ownerPrefix   = 'a'
+	createdPrefix = 'c'
+	spentPrefix   = 's'
+	heightKey     = 'h'
+)
+
+// The flags of the entries.
+const (
+	supportFlag = 1 << iota
+	ownerFlag
+)
+
+type Pebble struct {
+	db *pebble.DB
+}
+
+func NewPebble(path string) (*Pebble, error) {
+
+	db, err := pebble.Open(path, &pebble.Options{Cache: pebble.NewCache(16 << 20)})
+	if err != nil {
+		return nil, fmt.Errorf("pebble open %s, %w", path, err)
+	}
+
+	repo := &Pebble{db: db}
+
+	return repo, nil
+}
+
+func entryKey(op wire.OutPoint) []byte {
+	o := change.NewOutPoint(op)
+	return append([]byte{entryPrefix}, o[:]...)
+}
+
+func ownerKey(owner []byte, op wire.OutPoint) []byte {
+	key := ownerBounds(owner)
+	o := change.NewOutPoint(op)
+	return append(key, o[:]...)
+}
+
+// ownerBounds returns the prefix of the keys of the outpoints paid to the owner.
+func ownerBounds(owner []byte) []byte {
+	key := make([]byte, 3, 3+len(owner)+len(change.OutPoint{}))
+	key[0] = ownerPrefix
+	binary.BigEndian.PutUint16(key[1:], uint16(len(owner)))
+	return append(key, owner...)
+}
+
+// prefixEnd returns the first key after the ones with the prefix.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+func historyKey(prefix byte, height int32, op wire.OutPoint) []byte {
+	key := make([]byte, 5, 5+len(change.OutPoint{}))
+	key[0] = prefix
+	binary.BigEndian.PutUint32(key[1:], uint32(height))
+	o := change.NewOutPoint(op)
+	return append(key, o[:]...)
+}
+
+// historyBounds returns the bounds of the keys of the history after the height.
+func historyBounds(prefix byte, height int32) ([]byte, []byte) {
+	lower := make([]byte, 5)
+	lower[0] = prefix
+	binary.BigEndian.PutUint32(lower[1:], uint32(height+1))
+	return lower, []byte{prefix + 1}
+}
+
+func encodeEntry(e outpoint.Entry) []byte {
+	value := make([]byte, 1+len(e.ClaimID), 1+len(e.ClaimID)+2+len(e.Owner)+len(e.Name))
+	if e.Support {
+		value[0] |= supportFlag
+	}
+	copy(value[1:], e.ClaimID[:])
+	if len(e.Owner) > 0 {
+		value[0] |= ownerFlag
+		value = append(value, byte(len(e.Owner)>>8), byte(len(e.Owner)))
+		value = append(value, e.Owner...)
+	}
+	return append(value, e.Name...)
+}
+
+func decodeEntry(op wire.OutPoint, value []byte) (*outpoint.Entry, error) {
+	e := &outpoint.Entry{OutPoint: op}
+	if len(value) < 1+len(e.ClaimID) {
+		return nil, fmt.Errorf("invalid entry of %s: %d bytes", op, len(value))
+	}
+	e.Support = value[0]&supportFlag != 0
+	copy(e.ClaimID[:], value[1:])
+	rest := value[1+len(e.ClaimID):]
+	if value[0]&ownerFlag != 0 {
+		if len(rest) < 2 || len(rest) < 2+int(binary.BigEndian.Uint16(rest)) {
+			return nil, fmt.Errorf("invalid owner of %s: %d bytes", op, len(rest))
+		}
+		n := 2 + int(binary.BigEndian.Uint16(rest))
+		e.Owner = append([]byte(nil), rest[2:n]...)
+		rest = rest[n:]
+	}
+	e.Name = append([]byte(nil), rest...)
+	return e, nil
+}
+
+// setEntry indexes the entry, and its owner, if any.
+func setEntry(batch *pebble.Batch, e outpoint.Entry) error {
+	err := batch.Set(entryKey(e.OutPoint), encodeEntry(e), pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble set: %w", err)
+	}
+	if len(e.Owner) > 0 {
+		err = batch.Set(ownerKey(e.Owner, e.OutPoint), nil, pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble set: %w", err)
+		}
+	}
+	return nil
+}
+
+// deleteEntry drops the entry of the value, and its owner, if any.
+func deleteEntry(batch *pebble.Batch, op wire.OutPoint, value []byte) error {
+	e, err := decodeEntry(op, value)
+	if err != nil {
+		return err
+	}
+	err = batch.Delete(entryKey(op), pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble delete: %w", err)
+	}
+	if len(e.Owner) > 0 {
+		err = batch.Delete(ownerKey(e.Owner, op), pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble delete: %w", err)
+		}
+	}
+	return nil
+}
+
+func opFromKey(key []byte) wire.OutPoint {
+	var o change.OutPoint
+	copy(o[:], key[len(key)-len(o):])
+	return o.Wire()
+}
+
+func (repo *Pebble) Get(op wire.OutPoint) (*outpoint.Entry, error) {
+
+	value, closer, err := repo.db.Get(entryKey(op))
+	if err == pebble.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pebble get: %w", err)
+	}
+	defer closer.Close()
+
+	return decodeEntry(op, value)
+}
+
+func (repo *Pebble) Owned(owner []byte) ([]outpoint.Entry, error) {
+
+	lower := ownerBounds(owner)
+	iter := repo.db.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: prefixEnd(lower)})
+	var ops []wire.OutPoint
+	for iter.First(); iter.Valid(); iter.Next() {
+		ops = append(ops, opFromKey(iter.Key()))
+	}
+	err := iter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("pebble iter: %w", err)
+	}
+
+	entries := make([]outpoint.Entry, 0, len(ops))
+	for _, op := range ops {
+		e, err := repo.Get(op)
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			entries = append(entries, *e)
+		}
+	}
+
+	return entries, nil
+}
+
+func (repo *Pebble) Update(height int32, created []outpoint.Entry, spent []wire.OutPoint) error {
+
+	batch := repo.db.NewIndexedBatch()
+	defer batch.Close()
+
+	for _, e := range created {
+		err := setEntry(batch, e)
+		if err != nil {
+			return err
+		}
+		err = batch.Set(historyKey(createdPrefix, height, e.OutPoint), nil, pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble set: %w", err)
+		}
+	}
+
+	for _, op := range spent {
+		value, closer, err := batch.Get(entryKey(op))
+		if err == pebble.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("pebble get: %w", err)
+		}
+		value = append([]byte(nil), value...)
+		closer.Close()
+		err = batch.Set(historyKey(spentPrefix, height, op), value, pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble set: %w", err)
+		}
+		err = deleteEntry(batch, op, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := setHeight(batch, height)
+	if err != nil {
+		return err
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+func setHeight(batch *pebble.Batch, height int32) error {
+	value := make([]byte, 4)
+	binary.BigEndian.PutUint32(value, uint32(height))
+	err := batch.Set([]byte{heightKey}, value, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble set: %w", err)
+	}
+	return nil
+}
+
+func (repo *Pebble) Rewind(height int32) error {
+
+	batch := repo.db.NewIndexedBatch()
+	defer batch.Close()
+
+	// The entries spent are restored before the ones created are dropped,
+	// so that the ones both created and spent after the height are gone.
+	lower, upper := historyBounds(spentPrefix, height)
+	iter := repo.db.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+	for iter.First(); iter.Valid(); iter.Next() {
+		op := opFromKey(iter.Key())
+		e, err := decodeEntry(op, iter.Value())
+		if err == nil {
+			err = setEntry(batch, *e)
+		}
+		if err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	err := iter.Close()
+	if err != nil {
+		return fmt.Errorf("pebble iter: %w", err)
+	}
+	err = batch.DeleteRange(lower, upper, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble delete range: %w", err)
+	}
+
+	lower, upper = historyBounds(createdPrefix, height)
+	iter = repo.db.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+	for iter.First(); iter.Valid(); iter.Next() {
+		// The entries are read through the batch, as the ones created and spent after the height are restored by it.
+		op := opFromKey(iter.Key())
+		value, closer, err := batch.Get(entryKey(op))
+		if err == pebble.ErrNotFound {
+			continue
+		}
+		if err == nil {
+			value = append([]byte(nil), value...)
+			closer.Close()
+			err = deleteEntry(batch, op, value)
+		}
+		if err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	err = iter.Close()
+	if err != nil {
+		return fmt.Errorf("pebble iter: %w", err)
+	}
+	err = batch.DeleteRange(lower, upper, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble delete range: %w", err)
+	}
+
+	err = setHeight(batch, height)
+	if err != nil {
+		return err
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+func (repo *Pebble) DropBefore(height int32) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	for _, prefix := range []byte{createdPrefix, spentPrefix} {
+		upper, _ := historyBounds(prefix, height-1)
+		err := batch.DeleteRange([]byte{prefix}, upper, pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble delete range: %w", err)
+		}
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+func (repo *Pebble) Height() (int32, error) {
+
+	value, closer, err := repo.db.Get([]byte{heightKey})
+	if err == pebble.ErrNotFound {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("pebble get: %w", err)
+	}
+	defer closer.Close()
+
+	return int32(binary.BigEndian.Uint32(value)), nil
+}
+
+func (repo *Pebble) Reset(height int32, entries []outpoint.Entry) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	err := batch.DeleteRange([]byte{0}, []byte{0xff}, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble delete range: %w", err)
+	}
+	for _, e := range entries {
+		err = setEntry(batch, e)
+		if err != nil {
+			return err
+		}
+	}
+	err = setHeight(batch, height)
+	if err != nil {
+		return err
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Pebble) Backup(dir string) error {
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Pebble) Close() error {
+
+	err := repo.db.Flush()
+	if err != nil {
+		return fmt.Errorf("pebble flush: %w", err)
+	}
+
+	err = repo.db.Close()
+	if err != nil {
+		return fmt.Errorf("pebble close: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package outpointrepo
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/outpoint"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutPoints(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	height, err := repo.Height()
+	r.NoError(err)
+	r.Equal(int32(-1), height)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	op := func(i uint32) wire.OutPoint { return wire.OutPoint{Hash: hash, Index: i} }
+	claim := outpoint.Entry{OutPoint: op(1), Name: []byte("a"), ClaimID: change.ClaimID{1}}
+	support := outpoint.Entry{OutPoint: op(2), Name: []byte("a"), ClaimID: change.ClaimID{1}, Support: true}
+	other := outpoint.Entry{OutPoint: op(3), Name: []byte("b"), ClaimID: change.ClaimID{3}}
+
+	r.NoError(repo.Update(1, []outpoint.Entry{claim, support}, nil))
+	r.NoError(repo.Update(2, []outpoint.Entry{other}, []wire.OutPoint{op(2), op(9)}))
+	r.NoError(repo.Update(3, nil, []wire.OutPoint{op(1), op(3)}))
+
+	e, err := repo.Get(op(3))
+	r.NoError(err)
+	r.Nil(e)
+	height, err = repo.Height()
+	r.NoError(err)
+	r.Equal(int32(3), height)
+
+	// The entries spent after the height are restored, and the ones created after it are dropped.
+	r.NoError(repo.Rewind(2))
+	e, err = repo.Get(op(1))
+	r.NoError(err)
+	r.Equal(&claim, e)
+	e, err = repo.Get(op(3))
+	r.NoError(err)
+	r.Equal(&other, e)
+	e, err = repo.Get(op(2))
+	r.NoError(err)
+	r.Nil(e)
+
+	r.NoError(repo.Rewind(1))
+	e, err = repo.Get(op(2))
+	r.NoError(err)
+	r.Equal(&support, e)
+	e, err = repo.Get(op(3))
+	r.NoError(err)
+	r.Nil(e)
+
+	// Without the history, the rewinds keep the entries.
+	r.NoError(repo.Update(2, nil, []wire.OutPoint{op(2)}))
+	r.NoError(repo.DropBefore(3))
+	r.NoError(repo.Rewind(1))
+	e, err = repo.Get(op(2))
+	r.NoError(err)
+	r.Nil(e)
+
+	// The entries are indexed by their owners, along with the rewinds.
+	owner := []byte{0x76, 0xa9, 1}
+	owned := outpoint.Entry{OutPoint: op(4), Name: []byte("c"), ClaimID: change.ClaimID{4}, Owner: owner}
+	longer := outpoint.Entry{OutPoint: op(5), Name: []byte("d"), ClaimID: change.ClaimID{5}, Owner: append(owner, 2)}
+	r.NoError(repo.Update(2, []outpoint.Entry{owned, longer}, nil))
+	e, err = repo.Get(op(4))
+	r.NoError(err)
+	r.Equal(&owned, e)
+	entries, err := repo.Owned(owner)
+	r.NoError(err)
+	r.Equal([]outpoint.Entry{owned}, entries)
+	r.NoError(repo.Update(3, nil, []wire.OutPoint{op(4)}))
+	entries, err = repo.Owned(owner)
+	r.NoError(err)
+	r.Empty(entries)
+	r.NoError(repo.Rewind(2))
+	entries, err = repo.Owned(owner)
+	r.NoError(err)
+	r.Equal([]outpoint.Entry{owned}, entries)
+	r.NoError(repo.Rewind(1))
+	entries, err = repo.Owned(owner)
+	r.NoError(err)
+	r.Empty(entries)
+
+	r.NoError(repo.Reset(5, []outpoint.Entry{other}))
+	e, err = repo.Get(op(1))
+	r.NoError(err)
+	r.Nil(e)
+	e, err = repo.Get(op(3))
+	r.NoError(err)
+	r.Equal(&other, e)
+	height, err = repo.Height()
+	r.NoError(err)
+	r.Equal(int32(5), height)
+}
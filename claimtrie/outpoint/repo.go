@@ -0,0 +1,39 @@
+package outpoint
+
+import (
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Entry is a claim, or a support, by the outpoint it's created at.
+type Entry struct {
+	OutPoint wire.OutPoint
+	Name     []byte
+	ClaimID  change.ClaimID
+	Support  bool
+	Owner    []byte // The destination script, without the claim prefix, if it was noted.
+}
+
+// Repo defines APIs for the index of the claims and supports by their outpoints
+// to access persistence layer. The entries spent are kept by the height, for the rewinds.
+type Repo interface {
+	// Get returns the entry of the outpoint, or nil if there's none.
+	Get(op wire.OutPoint) (*Entry, error)
+	// Owned returns the entries paid to the destination script.
+	Owned(owner []byte) ([]Entry, error)
+	// Update indexes the entries created, and drops the ones of the outpoints spent, at the height.
+	// The outpoints spent, which aren't indexed, are ignored.
+	Update(height int32, created []Entry, spent []wire.OutPoint) error
+	// Rewind restores the index as of the height.
+	Rewind(height int32) error
+	// DropBefore drops the entries created and spent at the heights before the height,
+	// which the index can't be rewound past after.
+	DropBefore(height int32) error
+
+	// Height returns the height last updated, or rewound, to, or -1 if there's none.
+	Height() (int32, error)
+	// Reset replaces all the entries, and their history, with the ones at the height.
+	Reset(height int32, entries []Entry) error
+
+	Close() error
+}
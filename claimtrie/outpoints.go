@@ -0,0 +1,167 @@
+package claimtrie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/outpoint"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ErrOutPointNotIndexed is returned by SpendOutPoint, if the claims and supports aren't indexed by their outpoints.
+var ErrOutPointNotIndexed = errors.New("outpoints aren't indexed")
+
+// SpendOutPoint spends the claim, or the support, created at the outpoint, under its name.
+// It reports whether one was, as the outpoints of the transactions may not be claims or supports at all.
+func (ct *ClaimTrie) SpendOutPoint(op wire.OutPoint) (bool, error) {
+
+	e, err := ct.outPoint(op, nil)
+	if err != nil || e == nil {
+		return false, err
+	}
+
+	return true, ct.forwardNodeChange(spendChange(e))
+}
+
+// SpendOutPoint spends the claim, or the support, created at the outpoint, in the transaction,
+// as ClaimTrie.SpendOutPoint does.
+func (tx *Transaction) SpendOutPoint(op wire.OutPoint) (bool, error) {
+
+	if tx.done {
+		return false, ErrTransactionDone
+	}
+
+	e, err := tx.ct.outPoint(op, tx.created)
+	if err != nil || e == nil {
+		return false, err
+	}
+
+	return true, tx.add(spendChange(e))
+}
+
+// SetOwner notes the destination script of the claim, or the support, created at the outpoint in the next block,
+// without the claim prefix, so that it's indexed along with the outpoint, such as for RenewalCandidates.
+// It's ignored, if the outpoints aren't indexed.
+func (ct *ClaimTrie) SetOwner(op wire.OutPoint, owner []byte) {
+	if ct.outPoints == nil {
+		return
+	}
+	if ct.pendingOwners == nil {
+		ct.pendingOwners = map[change.OutPoint][]byte{}
+	}
+	ct.pendingOwners[change.NewOutPoint(op)] = append([]byte(nil), owner...)
+}
+
+// SetOwner notes the destination script of the claim, or the support, created in the transaction,
+// as ClaimTrie.SetOwner does.
+func (tx *Transaction) SetOwner(op wire.OutPoint, owner []byte) error {
+	if tx.done {
+		return ErrTransactionDone
+	}
+	if tx.ct.outPoints == nil {
+		return nil
+	}
+	if tx.dests == nil {
+		tx.dests = map[wire.OutPoint][]byte{}
+	}
+	tx.dests[op] = owner
+	return nil
+}
+
+// outPoint returns the entry of the outpoint created by the changes of the transaction, if any, the ones
+// not appended yet, or the blocks.
+func (ct *ClaimTrie) outPoint(op wire.OutPoint, created map[change.OutPoint]outpoint.Entry) (*outpoint.Entry, error) {
+
+	if ct.outPoints == nil {
+		return nil, ErrOutPointNotIndexed
+	}
+
+	key := change.NewOutPoint(op)
+	if e, ok := created[key]; ok {
+		return &e, nil
+	}
+	if e, ok := ct.pendingOutPoints[key]; ok {
+		return &e, nil
+	}
+
+	e, err := ct.outPoints.Get(op)
+	if err != nil {
+		return nil, fmt.Errorf("outpoint repo get: %w", err)
+	}
+
+	return e, nil
+}
+
+func spendChange(e *outpoint.Entry) change.Change {
+	if e.Support {
+		return spendSupportChange(e.Name, e.OutPoint, e.ClaimID)
+	}
+	return spendClaimChange(e.Name, e.OutPoint, e.ClaimID)
+}
+
+// createdEntry returns the entry of the claim, or the support, created by the change, if any.
+func createdEntry(chg change.Change) (outpoint.Entry, bool) {
+	e := outpoint.Entry{OutPoint: chg.OutPoint.Wire(), Name: chg.Name, ClaimID: chg.ClaimID}
+	switch chg.Type {
+	case change.AddClaim, change.UpdateClaim:
+		return e, true
+	case change.AddSupport:
+		e.Support = true
+		return e, true
+	}
+	return e, false
+}
+
+// updateOutPoints indexes the outpoints created, and drops the ones spent, by the changes of the block.
+func (ct *ClaimTrie) updateOutPoints(changes []change.Change) error {
+
+	var created []outpoint.Entry
+	var spent []wire.OutPoint
+	for _, chg := range changes {
+		if e, ok := createdEntry(chg); ok {
+			e.Owner = ct.pendingOwners[chg.OutPoint]
+			created = append(created, e)
+		} else if chg.Type == change.SpendClaim || chg.Type == change.SpendSupport {
+			spent = append(spent, chg.OutPoint.Wire())
+		}
+	}
+	for key := range ct.pendingOutPoints {
+		delete(ct.pendingOutPoints, key)
+	}
+	ct.pendingOwners = nil
+
+	return ct.outPoints.Update(ct.height, created, spent)
+}
+
+// rebuildOutPoints indexes the claims and supports of all the nodes from scratch.
+// The expired ones, which aren't in the nodes anymore, aren't indexed, and the owners, which the nodes
+// don't keep, aren't either.
+func (ct *ClaimTrie) rebuildOutPoints() error {
+
+	var entries []outpoint.Entry
+	var err error
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		n, e := ct.nodeManager.Node(name)
+		if e != nil {
+			err = fmt.Errorf("node: %w", e)
+			return false
+		}
+		if n == nil {
+			return true
+		}
+		name = append([]byte(nil), name...)
+		for _, c := range n.Claims {
+			entries = append(entries, outpoint.Entry{OutPoint: c.OutPoint, Name: name, ClaimID: c.ClaimID})
+		}
+		for _, s := range n.Supports {
+			entries = append(entries, outpoint.Entry{OutPoint: s.OutPoint, Name: name, ClaimID: s.ClaimID, Support: true})
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	return ct.outPoints.Reset(ct.height, entries)
+}
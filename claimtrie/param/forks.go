@@ -0,0 +1,52 @@
+package param
+
+import (
+	"math"
+	"sort"
+)
+
+// Fork is a change of the ClaimTrie rules, which applies from a height on.
+type Fork struct {
+	Name        string
+	Height      int32 // math.MaxInt32 if it's not scheduled.
+	Description string
+}
+
+// Scheduled reports whether the fork is scheduled at a height.
+func (f Fork) Scheduled() bool {
+	return f.Height != math.MaxInt32
+}
+
+// Forks returns the forks of the network set by SetNetwork, in order by height.
+func Forks() []Fork {
+
+	forks := []Fork{
+		{"extended_claim_expiration", ExtendedClaimExpirationForkHeight,
+			"the claims and supports, which haven't expired by then, expire after ExtendedClaimExpirationTime blocks"},
+		{"normalized_names", NormalizedNameForkHeight,
+			"the names are normalized, and the claims under the names normalized alike are merged"},
+		{"all_claims_in_merkle", AllClaimsInMerkleForkHeight,
+			"the leaves of the trie hash all the claims of a name, instead of only the best one"},
+		{"invalid_update", InvalidUpdateForkHeight,
+			"an update of a claim not spent under its name invalidates the transaction, instead of being ignored"},
+		{"max_claim_value_size", MaxClaimValueSizeForkHeight,
+			"the claims and supports with values larger than MaxClaimValueSize are rejected"},
+	}
+	sort.SliceStable(forks, func(i, j int) bool { return forks[i].Height < forks[j].Height })
+
+	return forks
+}
+
+// ActiveForks returns the forks, of which the rules apply at the height, in order by height.
+func ActiveForks(height int32) []Fork {
+
+	var active []Fork
+	for _, f := range Forks() {
+		if f.Height > height {
+			break
+		}
+		active = append(active, f)
+	}
+
+	return active
+}
@@ -0,0 +1,34 @@
+package param
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestActiveForks(t *testing.T) {
+
+	r := require.New(t)
+
+	SetNetwork(wire.TestNet)
+
+	forks := Forks()
+	for i := 1; i < len(forks); i++ {
+		r.LessOrEqual(forks[i-1].Height, forks[i].Height)
+	}
+
+	r.Empty(ActiveForks(NormalizedNameForkHeight - 1))
+
+	active := ActiveForks(NormalizedNameForkHeight)
+	r.Len(active, 1)
+	r.Equal("normalized_names", active[0].Name)
+
+	active = ActiveForks(ExtendedClaimExpirationForkHeight)
+	r.Len(active, 3)
+	for _, f := range active {
+		r.True(f.Scheduled())
+	}
+	r.False(forks[len(forks)-1].Scheduled())
+}
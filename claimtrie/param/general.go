@@ -1,6 +1,8 @@
 package param
 
 import (
+	"math"
+
 	"github.com/btcsuite/btcd/wire"
 )
 
@@ -18,12 +20,24 @@ var (
 
 	NormalizedNameForkHeight    int32
 	AllClaimsInMerkleForkHeight int32
+
+	// Height from which an update of a claim under a different name, or of a claim
+	// not spent in the same transaction, invalidates the transaction.
+	// Prior to it, such updates were accepted, but ignored.
+	InvalidUpdateForkHeight int32
+
+	// Height from which the claims and supports with values larger than MaxClaimValueSize
+	// are rejected, when applied to the ClaimTrie. Prior to it, only the sizes of the claim
+	// scripts were bound.
+	MaxClaimValueSizeForkHeight int32
+	MaxClaimValueSize           int
 )
 
 func SetNetwork(net wire.BitcoinNet) {
 	MaxActiveDelay = 4032
 	ActiveDelayFactor = 32
 	MaxNodeManagerCacheSize = 16000
+	MaxClaimValueSize = 8192
 
 	switch net {
 	case wire.MainNet:
@@ -31,8 +45,10 @@ func SetNetwork(net wire.BitcoinNet) {
 		ExtendedClaimExpirationTime = 2102400
 		ExtendedClaimExpirationForkHeight = 400155 // https://lbry.io/news/hf1807
 		MaxRemovalWorkaroundHeight = 658300
-		NormalizedNameForkHeight = 539940    // targeting 21 March 2019}, https://lbry.com/news/hf1903
-		AllClaimsInMerkleForkHeight = 658309 // targeting 30 Oct 2019}, https://lbry.com/news/hf1910
+		NormalizedNameForkHeight = 539940       // targeting 21 March 2019}, https://lbry.com/news/hf1903
+		AllClaimsInMerkleForkHeight = 658309    // targeting 30 Oct 2019}, https://lbry.com/news/hf1910
+		InvalidUpdateForkHeight = math.MaxInt32 // not scheduled yet
+		MaxClaimValueSizeForkHeight = math.MaxInt32
 	case wire.TestNet3:
 		OriginalClaimExpirationTime = 262974
 		ExtendedClaimExpirationTime = 2102400
@@ -40,6 +56,8 @@ func SetNetwork(net wire.BitcoinNet) {
 		MaxRemovalWorkaroundHeight = 100
 		NormalizedNameForkHeight = 1
 		AllClaimsInMerkleForkHeight = 109
+		InvalidUpdateForkHeight = math.MaxInt32
+		MaxClaimValueSizeForkHeight = math.MaxInt32
 	case wire.TestNet, wire.SimNet: // "regtest"
 		OriginalClaimExpirationTime = 500
 		ExtendedClaimExpirationTime = 600
@@ -47,5 +65,7 @@ func SetNetwork(net wire.BitcoinNet) {
 		MaxRemovalWorkaroundHeight = -1
 		NormalizedNameForkHeight = 250
 		AllClaimsInMerkleForkHeight = 349
+		InvalidUpdateForkHeight = math.MaxInt32
+		MaxClaimValueSizeForkHeight = math.MaxInt32
 	}
 }
@@ -0,0 +1,40 @@
+package param
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Networks are the networks, of which SetNetwork sets the params, by name.
+var Networks = map[string]wire.BitcoinNet{
+	"mainnet": wire.MainNet,
+	"testnet": wire.TestNet3,
+	"regtest": wire.TestNet,
+}
+
+// NetworkNames returns the names of the Networks, in order.
+func NetworkNames() []string {
+
+	var names []string
+	for name := range Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// SetNetworkByName sets the params of the network of the name, one of NetworkNames.
+func SetNetworkByName(name string) error {
+
+	net, ok := Networks[name]
+	if !ok {
+		return fmt.Errorf("invalid network: %q, expected one of %s", name, strings.Join(NetworkNames(), ", "))
+	}
+	SetNetwork(net)
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package param
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// overridable returns the params, which can be overridden, by name. The heights of the forks are named after them.
+func overridable() map[string]*int32 {
+	return map[string]*int32{
+		"max_active_delay":              &MaxActiveDelay,
+		"active_delay_factor":           &ActiveDelayFactor,
+		"expiration":                    &OriginalClaimExpirationTime,
+		"extended_expiration":           &ExtendedClaimExpirationTime,
+		"max_removal_workaround_height": &MaxRemovalWorkaroundHeight,
+
+		"extended_claim_expiration_height": &ExtendedClaimExpirationForkHeight,
+		"normalized_names_height":          &NormalizedNameForkHeight,
+		"all_claims_in_merkle_height":      &AllClaimsInMerkleForkHeight,
+		"invalid_update_height":            &InvalidUpdateForkHeight,
+		"max_claim_value_size_height":      &MaxClaimValueSizeForkHeight,
+	}
+}
+
+// OverridableParams returns the names of the params, which can be overridden, in order.
+func OverridableParams() []string {
+
+	var names []string
+	for name := range overridable() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Override is a param set to another value than the one of the network, from a height on,
+// for simulating alternative rules over the changes of the network.
+type Override struct {
+	Name   string
+	Value  int32
+	Height int32 // The height of the first block the value applies to, or 0.
+}
+
+// ParseOverride parses an override of the form <name>=<value>[@<height>].
+// The value of a height param is either a height, or "never".
+func ParseOverride(s string) (Override, error) {
+
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 {
+		return Override{}, fmt.Errorf("param override %q: expected <name>=<value>[@<height>]", s)
+	}
+
+	o := Override{Name: kv[0]}
+	value := kv[1]
+	if i := strings.LastIndexByte(value, '@'); i >= 0 {
+		h, err := strconv.ParseInt(value[i+1:], 10, 32)
+		if err != nil || h < 0 {
+			return Override{}, fmt.Errorf("param override %q: invalid height %q", s, value[i+1:])
+		}
+		o.Height, value = int32(h), value[:i]
+	}
+
+	if value == "never" && strings.HasSuffix(o.Name, "_height") {
+		o.Value = math.MaxInt32
+	} else {
+		v, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return Override{}, fmt.Errorf("param override %q: invalid value %q", s, value)
+		}
+		o.Value = int32(v)
+	}
+
+	if _, ok := overridable()[o.Name]; !ok {
+		return Override{}, fmt.Errorf("param override %q: unknown param %q, expected one of %s",
+			s, o.Name, strings.Join(OverridableParams(), ", "))
+	}
+
+	return o, nil
+}
+
+// Apply sets the param to the value. The values are read as of their use, so an override applied past
+// the start of a replay doesn't revisit the claims, of which the activation, or expiration, is decided already.
+func (o Override) Apply() {
+	*overridable()[o.Name] = o.Value
+}
+
+func (o Override) String() string {
+
+	s := fmt.Sprintf("%s=%d", o.Name, o.Value)
+	if o.Height > 0 {
+		s += fmt.Sprintf("@%d", o.Height)
+	}
+
+	return s
+}
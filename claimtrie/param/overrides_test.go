@@ -0,0 +1,55 @@
+package param
+
+import (
+	"math"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverride(t *testing.T) {
+
+	r := require.New(t)
+
+	SetNetwork(wire.TestNet)
+	defer SetNetwork(wire.TestNet)
+
+	o, err := ParseOverride("expiration=1000")
+	r.NoError(err)
+	r.Equal(Override{Name: "expiration", Value: 1000}, o)
+	o.Apply()
+	r.Equal(int32(1000), OriginalClaimExpirationTime)
+
+	o, err = ParseOverride("normalized_names_height=never@100")
+	r.NoError(err)
+	r.Equal(Override{Name: "normalized_names_height", Value: math.MaxInt32, Height: 100}, o)
+	r.Equal("normalized_names_height=2147483647@100", o.String())
+	o.Apply()
+	r.Equal(int32(math.MaxInt32), NormalizedNameForkHeight)
+
+	for _, s := range []string{"expiration", "expiration=never", "expiration=1@-1", "unknown=1"} {
+		_, err = ParseOverride(s)
+		r.Error(err, s)
+	}
+}
+
+func TestSetNetworkByName(t *testing.T) {
+
+	r := require.New(t)
+
+	defer SetNetwork(wire.TestNet)
+
+	r.Equal([]string{"mainnet", "regtest", "testnet"}, NetworkNames())
+
+	r.NoError(SetNetworkByName("mainnet"))
+	r.Equal(int32(262974), OriginalClaimExpirationTime)
+	r.Equal(int32(539940), NormalizedNameForkHeight)
+
+	r.NoError(SetNetworkByName("regtest"))
+	r.Equal(int32(500), OriginalClaimExpirationTime)
+	r.Equal(int32(250), NormalizedNameForkHeight)
+
+	r.Error(SetNetworkByName("simnet"))
+}
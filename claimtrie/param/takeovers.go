@@ -1,7 +1,20 @@
 package param
 
+import "strconv"
+
 var TakeoverWorkarounds = generateTakeoverWorkarounds()
 
+// IsTakeoverWorkaround reports whether a takeover of the name is forced at the height.
+// It doesn't allocate for names up to 64 bytes, which is longer than any of the workarounds.
+func IsTakeoverWorkaround(height int32, name []byte) bool {
+	var buf [80]byte
+	key := strconv.AppendInt(buf[:0], int64(height), 10)
+	key = append(key, '_')
+	key = append(key, name...)
+	_, ok := TakeoverWorkarounds[string(key)]
+	return ok
+}
+
 func generateTakeoverWorkarounds() map[string]int { // TODO: the values here are unused; bools would probably be better
 	return map[string]int{
 		"496856_HunterxHunterAMV":                            496835,
@@ -0,0 +1,82 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// DefaultPolicy is the relay policy applied unless configured otherwise.
+var DefaultPolicy = Policy{
+	MaxNameSize:      txscript.MaxClaimNameSize,
+	MaxValueSize:     txscript.MaxClaimScriptSize,
+	MinSupportAmount: 1000,
+}
+
+var (
+	// ErrNameTooLong is returned when the name of a claim script exceeds the policy limit.
+	ErrNameTooLong = fmt.Errorf("claim name too long")
+
+	// ErrValueTooLarge is returned when the value of a claim script exceeds the policy limit.
+	ErrValueTooLarge = fmt.Errorf("claim value too large")
+
+	// ErrDustSupport is returned when the amount of a support is below the dust threshold.
+	ErrDustSupport = fmt.Errorf("support amount is dust")
+)
+
+// Policy defines the standardness rules of claim scripts for relaying and mining.
+// Unlike the consensus rules, which live in the param package, these can be
+// adjusted by each node without affecting the validity of blocks.
+type Policy struct {
+	MaxNameSize      int   // Maximum size of a name in bytes.
+	MaxValueSize     int   // Maximum size of a claim value in bytes.
+	MinSupportAmount int64 // Supports with a lower amount are considered dust.
+}
+
+// CheckScript returns an error if the claim script is not standard.
+// Scripts that are not claim scripts are always considered standard here, and so are the claim
+// scripts of the future versions, which are NOPs until a soft-fork defines them.
+func (p Policy) CheckScript(script []byte, amount int64) error {
+
+	if len(script) == 0 {
+		return nil
+	}
+
+	cs, err := txscript.RecognizeClaimScript(script, txscript.ClaimScriptVersionCurrent)
+	if err == txscript.ErrNotClaimScript || err == txscript.ErrFutureClaimScript {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(cs.Name()) > p.MaxNameSize {
+		return fmt.Errorf("%w: %d > %d", ErrNameTooLong, len(cs.Name()), p.MaxNameSize)
+	}
+
+	switch cs.Opcode() {
+	case txscript.OP_CLAIMNAME, txscript.OP_UPDATECLAIM:
+		if len(cs.Value()) > p.MaxValueSize {
+			return fmt.Errorf("%w: %d > %d", ErrValueTooLarge, len(cs.Value()), p.MaxValueSize)
+		}
+	case txscript.OP_SUPPORTCLAIM:
+		if amount < p.MinSupportAmount {
+			return fmt.Errorf("%w: %d < %d", ErrDustSupport, amount, p.MinSupportAmount)
+		}
+	}
+
+	return nil
+}
+
+// CheckTx returns an error if any of the claim scripts in the transaction outputs is not standard.
+func (p Policy) CheckTx(tx *wire.MsgTx) error {
+
+	for i, txOut := range tx.TxOut {
+		if err := p.CheckScript(txOut.PkScript, txOut.Value); err != nil {
+			return fmt.Errorf("output %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
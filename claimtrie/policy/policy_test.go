@@ -0,0 +1,48 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckScript(t *testing.T) {
+
+	r := require.New(t)
+
+	p := Policy{MaxNameSize: 4, MaxValueSize: 8, MinSupportAmount: 10}
+	claimID := []byte("12345123451234512345")
+
+	claim, err := txscript.ClaimNameScript("test", "value")
+	r.NoError(err)
+	r.NoError(p.CheckScript(claim, 1))
+
+	claim, err = txscript.ClaimNameScript("tester", "value")
+	r.NoError(err)
+	r.True(errors.Is(p.CheckScript(claim, 1), ErrNameTooLong))
+
+	update, err := txscript.UpdateClaimScript("test", claimID, "a larger value")
+	r.NoError(err)
+	r.True(errors.Is(p.CheckScript(update, 1), ErrValueTooLarge))
+
+	support, err := txscript.SupportClaimScript("test", claimID, nil)
+	r.NoError(err)
+	r.NoError(p.CheckScript(support, 10))
+	r.True(errors.Is(p.CheckScript(support, 9), ErrDustSupport))
+
+	nop, err := txscript.NewScriptBuilder().AddOp(txscript.OP_NOP9).AddData([]byte("tester")).
+		AddOp(txscript.OP_DROP).AddOp(txscript.OP_TRUE).Script()
+	r.NoError(err)
+	r.NoError(p.CheckScript(nop, 1))
+
+	r.NoError(p.CheckScript([]byte{txscript.OP_TRUE}, 0))
+	r.NoError(p.CheckScript(nil, 0))
+
+	tx := wire.NewMsgTx(1)
+	tx.AddTxOut(wire.NewTxOut(100, claim))
+	r.True(errors.Is(p.CheckTx(tx), ErrNameTooLong))
+}
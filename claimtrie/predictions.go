@@ -0,0 +1,107 @@
+package claimtrie
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// TakeoverPrediction is a change of the best claim of a name, which happens at
+// Height unless new transactions affect the name by then.
+type TakeoverPrediction struct {
+	Name   []byte
+	Height int32
+
+	// Copies of the best claims before and after the takeover; nil if there's none.
+	Previous *node.Claim
+	Winner   *node.Claim
+}
+
+// PredictTakeovers returns the takeovers scheduled within the next blocks, due to
+// the pending activations of claims and supports, and their expirations,
+// in order by height.
+func (ct *ClaimTrie) PredictTakeovers(withinBlocks int32) ([]TakeoverPrediction, error) {
+
+	until := ct.height + withinBlocks
+	if withinBlocks > math.MaxInt32-ct.height {
+		until = math.MaxInt32
+	}
+
+	var predictions []TakeoverPrediction
+	seen := map[string]bool{}
+	for h := ct.height + 1; h <= until && h > 0; h++ {
+		names, err := ct.temporalRepo.NodesAt(h)
+		if err != nil {
+			return nil, fmt.Errorf("temporal repo nodes at: %w", err)
+		}
+		for _, name := range names {
+			if seen[string(name)] {
+				continue
+			}
+			seen[string(name)] = true
+
+			n, err := ct.nodeManager.Node(name)
+			if err != nil {
+				return nil, fmt.Errorf("node: %w", err)
+			}
+			if n == nil {
+				continue
+			}
+			predictions = append(predictions, predictTakeovers(name, n.Clone(), ct.height, until)...)
+		}
+	}
+
+	sortPredictions(predictions)
+
+	return predictions, nil
+}
+
+// predictTakeovers advances n through its scheduled updates until the height.
+func predictTakeovers(name []byte, n *node.Node, height, until int32) []TakeoverPrediction {
+
+	var predictions []TakeoverPrediction
+	for h := n.NextUpdate(); h > height && h <= until; h = n.NextUpdate() {
+		previous := n.BestClaim
+		n.AdjustTo(h, h, name)
+		if sameClaim(previous, n.BestClaim) {
+			height = h
+			continue
+		}
+		predictions = append(predictions, TakeoverPrediction{
+			Name:     name,
+			Height:   h,
+			Previous: copyClaim(previous),
+			Winner:   copyClaim(n.BestClaim),
+		})
+		height = h
+	}
+
+	return predictions
+}
+
+func sameClaim(a, b *node.Claim) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ClaimID == b.ClaimID
+}
+
+func copyClaim(c *node.Claim) *node.Claim {
+	if c == nil {
+		return nil
+	}
+	cc := *c
+	return &cc
+}
+
+func sortPredictions(predictions []TakeoverPrediction) {
+	sort.SliceStable(predictions, func(i, j int) bool {
+		if predictions[i].Height != predictions[j].Height {
+			return predictions[i].Height < predictions[j].Height
+		}
+		return bytes.Compare(predictions[i].Name, predictions[j].Name) < 0
+	})
+}
@@ -0,0 +1,134 @@
+package claimtrie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// ErrPreviewAtFork is returned for previewing the root of the block at the normalization fork, which re-keys
+// the names that aren't normalized, if there're any.
+var ErrPreviewAtFork = errors.New("root can't be previewed at the normalization fork")
+
+// PreviewRoot returns the root the ClaimTrie would have, once the changes of the transaction, along with the ones
+// pending, are committed, and the block is appended. Nothing is applied, so the transaction can still be committed,
+// or aborted. It's meant for the block templates, of which the blocks are mined after.
+func (tx *Transaction) PreviewRoot() (*chainhash.Hash, error) {
+
+	if tx.done {
+		return nil, ErrTransactionDone
+	}
+	if tx.height != tx.ct.height+1 {
+		return nil, fmt.Errorf("transaction of block %d previewed at height %d", tx.height, tx.ct.height)
+	}
+
+	return tx.ct.previewRoot(tx.changes)
+}
+
+func (ct *ClaimTrie) previewRoot(changes []change.Change) (*chainhash.Hash, error) {
+
+	height := ct.height + 1
+	if height == param.NormalizedNameForkHeight && ct.hasUnnormalizedNames() {
+		return nil, fmt.Errorf("%w: %d", ErrPreviewAtFork, height)
+	}
+
+	// The changes are grouped by their names, as the node manager keys them.
+	byName := map[string][]change.Change{}
+	var names [][]byte
+	add := func(chg change.Change) {
+		name := node.NormalizeIfNecessary(chg.Name, height)
+		if _, ok := byName[string(name)]; !ok {
+			names = append(names, name)
+		}
+		byName[string(name)] = append(byName[string(name)], chg)
+	}
+	for _, chg := range ct.changes {
+		add(chg)
+	}
+	for i, chg := range changes {
+		chg.Height = height
+		chg.Seq = int32(len(ct.changes)+i) + 1
+		add(chg)
+	}
+	expirations, err := ct.temporalRepo.NodesAt(height)
+	if err != nil {
+		return nil, fmt.Errorf("temporal repo nodes at: %w", err)
+	}
+	for _, name := range expirations {
+		if _, ok := byName[string(name)]; !ok {
+			names = append(names, name)
+			byName[string(name)] = nil
+		}
+	}
+
+	root := ct.root
+	if root == nil {
+		root = merkletrie.EmptyTrieHash
+	}
+	hitFork := height == param.AllClaimsInMerkleForkHeight
+	if len(names) == 0 && !hitFork {
+		return root, nil
+	}
+
+	store := previewStore{nodes: map[string]*node.Node{}, ValueStore: ct.nodeManager}
+	for _, name := range names {
+		n, err := ct.nodeManager.PreviewNode(name, byName[string(name)], height)
+		if err != nil {
+			return nil, fmt.Errorf("preview node %q: %w", name, err)
+		}
+		store.nodes[string(name)] = n
+	}
+
+	trie := ct.merkleTrie.Preview(root, store)
+	for _, name := range names {
+		trie.Update(name, true)
+	}
+	if hitFork { // all the names are rehashed, as AppendBlock does
+		ct.nodeManager.IterateNames(func(name []byte) bool {
+			trie.Update(name, false)
+			return true
+		})
+	}
+	if height >= param.AllClaimsInMerkleForkHeight {
+		return trie.MerkleHashAllClaims(), nil
+	}
+	return trie.MerkleHash(), nil
+}
+
+// hasUnnormalizedNames reports whether any of the names would be re-keyed at the normalization fork.
+func (ct *ClaimTrie) hasUnnormalizedNames() bool {
+	found := false
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		found = !bytes.Equal(name, node.Normalize(name))
+		return !found
+	})
+	return found
+}
+
+// previewStore is a ValueStore of the nodes previewed, and the current ones of the names without any update.
+type previewStore struct {
+	merkletrie.ValueStore
+	nodes map[string]*node.Node
+}
+
+func (s previewStore) Hash(name []byte) *chainhash.Hash {
+	n, ok := s.nodes[string(name)]
+	if !ok {
+		return s.ValueStore.Hash(name)
+	}
+	return bestClaimHash(n)
+}
+
+func (s previewStore) ClaimHashes(name []byte) []*chainhash.Hash {
+	n, ok := s.nodes[string(name)]
+	if !ok {
+		return s.ValueStore.ClaimHashes(name)
+	}
+	return claimHashes(n)
+}
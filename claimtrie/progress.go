@@ -0,0 +1,47 @@
+package claimtrie
+
+import (
+	"sort"
+
+	"github.com/btcsuite/btcd/claimtrie/progress"
+)
+
+// SetProgress sets fn to be reported the progress of the long-running operations: hashing the entire trie
+// at the fork, writing the checkpoints of the trie repo, and compacting the repos. Meanwhile, the
+// progress is logged every progress.Interval, and the latest of each operation is kept for Progress.
+func (ct *ClaimTrie) SetProgress(fn progress.Func) {
+	ct.progressMu.Lock()
+	defer ct.progressMu.Unlock()
+	ct.onProgress = fn
+}
+
+// Progress returns the progress of the running, or the last, run of each long-running operation, by the operations.
+func (ct *ClaimTrie) Progress() []progress.Progress {
+
+	ct.progressMu.Lock()
+	defer ct.progressMu.Unlock()
+
+	ps := make([]progress.Progress, 0, len(ct.progress))
+	for _, p := range ct.progress {
+		ps = append(ps, p)
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i].Op < ps[j].Op })
+
+	return ps
+}
+
+// reportProgress is the progress.Func of the trie, the trie repo, and the compaction.
+func (ct *ClaimTrie) reportProgress(p progress.Progress) {
+
+	ct.progressMu.Lock()
+	ct.progress[p.Op] = p
+	fn := ct.onProgress
+	ct.progressMu.Unlock()
+
+	if !p.Done {
+		log.Infof("Progress of %s: %d names hashed, %d nodes written, in %s", p.Op, p.Names, p.Nodes, p.Elapsed)
+	}
+	if fn != nil {
+		fn(p)
+	}
+}
@@ -0,0 +1,103 @@
+package progress
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Interval is how often a Meter reports the progress while the operation runs.
+var Interval = time.Second
+
+// Progress is the progress of a long-running operation, such as hashing the entire trie,
+// writing a checkpoint of it, or compacting a repo.
+type Progress struct {
+	Op      string        `json:"op"`
+	Names   int64         `json:"names"` // The names hashed so far.
+	Nodes   int64         `json:"nodes"` // The nodes written so far.
+	Elapsed time.Duration `json:"elapsed"`
+	Done    bool          `json:"done"`
+}
+
+// Func is called with the progress of an operation every Interval, and once it's done.
+// It's called from a goroutine of its own, but never concurrently for the same Meter.
+type Func func(Progress)
+
+// Meter counts the work of an operation, and reports it to a Func until Stop is called.
+// The methods of a nil Meter are no-ops, so the operations count unconditionally.
+type Meter struct {
+	op    string
+	fn    Func
+	start time.Time
+
+	names int64 // atomic
+	nodes int64 // atomic
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Start returns a Meter reporting the progress of op to fn, or nil if fn is nil.
+func Start(op string, fn Func) *Meter {
+
+	if fn == nil {
+		return nil
+	}
+
+	m := &Meter{op: op, fn: fn, start: time.Now(), stop: make(chan struct{})}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.fn(m.Progress())
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+
+	return m
+}
+
+// AddNames counts n names hashed.
+func (m *Meter) AddNames(n int64) {
+	if m != nil {
+		atomic.AddInt64(&m.names, n)
+	}
+}
+
+// AddNodes counts n nodes written.
+func (m *Meter) AddNodes(n int64) {
+	if m != nil {
+		atomic.AddInt64(&m.nodes, n)
+	}
+}
+
+// Progress returns the progress so far.
+func (m *Meter) Progress() Progress {
+	return Progress{
+		Op:      m.op,
+		Names:   atomic.LoadInt64(&m.names),
+		Nodes:   atomic.LoadInt64(&m.nodes),
+		Elapsed: time.Since(m.start),
+	}
+}
+
+// Stop stops the periodic reports, and reports the final progress as done.
+func (m *Meter) Stop() {
+
+	if m == nil {
+		return
+	}
+
+	close(m.stop)
+	m.wg.Wait()
+
+	p := m.Progress()
+	p.Done = true
+	m.fn(p)
+}
@@ -0,0 +1,48 @@
+package progress
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter(t *testing.T) {
+
+	r := require.New(t)
+
+	defer func(interval time.Duration) { Interval = interval }(Interval)
+	Interval = time.Millisecond
+
+	var mu sync.Mutex
+	var reported []Progress
+	m := Start("hash", func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, p)
+	})
+	m.AddNames(2)
+	m.AddNodes(3)
+	r.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(reported) > 0
+	}, time.Second, time.Millisecond)
+	m.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, p := range reported[:len(reported)-1] {
+		r.False(p.Done)
+	}
+	last := reported[len(reported)-1]
+	r.Equal(Progress{Op: "hash", Names: 2, Nodes: 3, Elapsed: last.Elapsed, Done: true}, last)
+
+	// The nil Meter of an unreported operation counts nothing.
+	m = Start("hash", nil)
+	r.Nil(m)
+	m.AddNames(1)
+	m.AddNodes(1)
+	m.Stop()
+}
@@ -0,0 +1,146 @@
+package proof
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// NamedProof is a proof of the value of the name.
+type NamedProof struct {
+	Name  []byte
+	Proof *Proof
+}
+
+// VerifyProofBatch reports whether each of the proofs commits its name to the root hash,
+// as Verify does, such as for a page of search results verified against one block header.
+// The nodes shared by the paths of the names are only hashed up to the root once, and the
+// buffers are reused across the proofs.
+func VerifyProofBatch(root *chainhash.Hash, proofs []NamedProof) []bool {
+
+	v := batchVerifier{root: root, nodes: map[string]chainhash.Hash{}}
+	results := make([]bool, len(proofs))
+	for i, np := range proofs {
+		if np.Proof == nil {
+			continue
+		}
+		if len(np.Proof.Pairs) > 0 {
+			results[i] = v.verifyPairs(np.Proof)
+			continue
+		}
+		results[i] = v.verifyNodes(np.Proof, np.Name)
+	}
+
+	return results
+}
+
+type batchVerifier struct {
+	root *chainhash.Hash
+
+	// The hashes of the nodes, keyed by their prefixes, on the paths verified up to the root.
+	nodes map[string]chainhash.Hash
+
+	buf  []byte
+	path []chainhash.Hash // The hashes computed for the nodes of the proof, from the last one up.
+}
+
+func (v *batchVerifier) verifyPairs(p *Proof) bool {
+
+	if !p.HasValue {
+		return false
+	}
+
+	var h chainhash.Hash
+	ValueHashInto(&h, p.OutPoint, p.TakeoverHeight)
+	for i := range p.Pairs {
+		pair := &p.Pairs[i]
+		if pair.Odd {
+			HashMerkleBranchesInto(&h, &pair.Hash, &h)
+		} else {
+			HashMerkleBranchesInto(&h, &h, &pair.Hash)
+		}
+	}
+
+	return h == *v.root
+}
+
+// verifyNodes verifies the proof from the name up, until the node at a prefix,
+// of which the hash was verified already, or the root.
+func (v *batchVerifier) verifyNodes(p *Proof, name []byte) bool {
+
+	// Node i of a well formed proof is at the prefix of the name of length i.
+	if len(p.Nodes) != len(name)+1 {
+		return p.Verify(v.root, name)
+	}
+
+	var h chainhash.Hash
+	if p.HasValue {
+		ValueHashInto(&h, p.OutPoint, p.TakeoverHeight)
+	}
+
+	v.path = v.path[:0]
+	for i := len(p.Nodes) - 1; i >= 0; i-- {
+		last := i == len(p.Nodes)-1
+		v.buf = v.buf[:0]
+		onPath := 0
+		for _, c := range p.Nodes[i].Children {
+			v.buf = append(v.buf, c.Character)
+			if c.Hash != nil {
+				v.buf = append(v.buf, c.Hash[:]...)
+				continue
+			}
+			// The child on the path, whose hash is computed from the next node.
+			if last || onPath > 0 || name[i] != c.Character {
+				return false
+			}
+			v.buf = append(v.buf, h[:]...)
+			onPath++
+		}
+		if !last && onPath == 0 {
+			return false
+		}
+
+		value := p.Nodes[i].ValueHash
+		if last {
+			value = nil
+			if p.HasValue {
+				value = &h
+			}
+		}
+		if value != nil {
+			v.buf = append(v.buf, value[:]...)
+		}
+
+		if len(v.buf) == 0 {
+			return len(name) == 0 && v.root.IsEqual(EmptyTrieHash)
+		}
+		h = chainhash.DoubleHashH(v.buf)
+		v.path = append(v.path, h)
+
+		if known, ok := v.nodes[string(name[:i])]; ok {
+			if known != h {
+				return false
+			}
+			v.remember(name, i)
+			return true
+		}
+	}
+
+	if h != *v.root {
+		return false
+	}
+	v.remember(name, 0)
+
+	return true
+}
+
+// remember records the hashes of the path below, and at, the prefix of the name of length depth.
+func (v *batchVerifier) remember(name []byte, depth int) {
+	for j, h := range v.path {
+		prefix := name[:len(name)-j]
+		if len(prefix) < depth {
+			break
+		}
+		if _, ok := v.nodes[string(prefix)]; !ok {
+			v.nodes[string(prefix)] = h
+		}
+	}
+}
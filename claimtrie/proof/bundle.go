@@ -0,0 +1,181 @@
+package proof
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const proofBundleVersion = 1
+
+// Bundled is the proof of a name against the root of the trie at the height.
+type Bundled struct {
+	Height int32
+	Root   chainhash.Hash
+	Proof  *Proof
+}
+
+// Bundle holds the proofs of a name at several heights, newest first, so that
+// clients on a slightly different tip can still verify it.
+type Bundle struct {
+	Name   []byte
+	Proofs []Bundled
+}
+
+// Verify returns the height of the proof against the root, and reports whether it's valid.
+func (b *Bundle) Verify(root *chainhash.Hash) (int32, bool) {
+	for _, p := range b.Proofs {
+		if p.Root.IsEqual(root) {
+			return p.Height, p.Proof.Verify(root, b.Name)
+		}
+	}
+	return 0, false
+}
+
+// Encode writes the bundle in the canonical binary format:
+//
+//	version(1B) len(varint) name
+//	count(varint) { height(4B) root(32B) txhash(32B) nout(4B) takeover(4B) count(varint) nodes }
+//
+// where each node is preceded by a shared(1B) flag. A shared node is the same as the
+// one at the same depth of the previous proof, and omitted; the others are encoded as
+// those of a Proof. Only the proofs with Nodes are supported.
+func (b *Bundle) Encode(w io.Writer) error {
+
+	if _, err := w.Write([]byte{proofBundleVersion}); err != nil {
+		return err
+	}
+	if err := wire.WriteVarBytes(w, 0, b.Name); err != nil {
+		return err
+	}
+	if err := wire.WriteVarInt(w, 0, uint64(len(b.Proofs))); err != nil {
+		return err
+	}
+
+	var previous []Node
+	for _, p := range b.Proofs {
+		if !p.Proof.HasValue || len(p.Proof.Pairs) > 0 {
+			return fmt.Errorf("unsupported proof at height %d", p.Height)
+		}
+
+		var buf [4 + 32 + 32 + 4 + 4]byte
+		binary.BigEndian.PutUint32(buf[:4], uint32(p.Height))
+		copy(buf[4:36], p.Root[:])
+		copy(buf[36:68], p.Proof.OutPoint.Hash[:])
+		binary.BigEndian.PutUint32(buf[68:], p.Proof.OutPoint.Index)
+		binary.BigEndian.PutUint32(buf[72:], uint32(p.Proof.TakeoverHeight))
+		if _, err := w.Write(buf[:]); err != nil {
+			return err
+		}
+
+		if err := wire.WriteVarInt(w, 0, uint64(len(p.Proof.Nodes))); err != nil {
+			return err
+		}
+		for i, n := range p.Proof.Nodes {
+			if i < len(previous) && equalProofNodes(n, previous[i]) {
+				if _, err := w.Write([]byte{1}); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := w.Write([]byte{0}); err != nil {
+				return err
+			}
+			if err := writeNode(w, n); err != nil {
+				return err
+			}
+		}
+		previous = p.Proof.Nodes
+	}
+
+	return nil
+}
+
+// Decode reads a bundle written by Encode.
+func (b *Bundle) Decode(r io.Reader) error {
+
+	var version [1]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return err
+	}
+	if version[0] != proofBundleVersion {
+		return fmt.Errorf("unsupported proof bundle version: %d", version[0])
+	}
+
+	name, err := wire.ReadVarBytes(r, 0, 1<<16, "name")
+	if err != nil {
+		return err
+	}
+	count, err := readCount(r)
+	if err != nil {
+		return err
+	}
+
+	*b = Bundle{Name: name, Proofs: make([]Bundled, count)}
+
+	var previous []Node
+	for i := range b.Proofs {
+		var buf [4 + 32 + 32 + 4 + 4]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return err
+		}
+		p := &Proof{HasValue: true}
+		bp := &b.Proofs[i]
+		bp.Height = int32(binary.BigEndian.Uint32(buf[:4]))
+		copy(bp.Root[:], buf[4:36])
+		copy(p.OutPoint.Hash[:], buf[36:68])
+		p.OutPoint.Index = binary.BigEndian.Uint32(buf[68:])
+		p.TakeoverHeight = int32(binary.BigEndian.Uint32(buf[72:]))
+		bp.Proof = p
+
+		nodes, err := readCount(r)
+		if err != nil {
+			return err
+		}
+		p.Nodes = make([]Node, nodes)
+		for j := range p.Nodes {
+			var shared [1]byte
+			if _, err := io.ReadFull(r, shared[:]); err != nil {
+				return err
+			}
+			switch {
+			case shared[0] == 1 && j < len(previous):
+				p.Nodes[j] = previous[j]
+			case shared[0] == 0:
+				p.Nodes[j], err = readNode(r)
+				if err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("invalid shared node flag: %d", shared[0])
+			}
+		}
+		previous = p.Nodes
+	}
+
+	return nil
+}
+
+func equalProofNodes(a, b Node) bool {
+
+	if len(a.Children) != len(b.Children) || !equalHashes(a.ValueHash, b.ValueHash) {
+		return false
+	}
+	for i := range a.Children {
+		if a.Children[i].Character != b.Children[i].Character || !equalHashes(a.Children[i].Hash, b.Children[i].Hash) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalHashes(a, b *chainhash.Hash) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
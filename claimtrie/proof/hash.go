@@ -0,0 +1,59 @@
+package proof
+
+import (
+	"encoding/binary"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// EmptyTrieHash represents the Merkle Hash of an empty trie.
+// "0000000000000000000000000000000000000000000000000000000000000001"
+var EmptyTrieHash = &chainhash.Hash{1}
+
+// ValueHash returns the value hash of a claim, which commits to its outpoint and
+// the takeover height of the node. It's the leaf of the proofs of the claim.
+func ValueHash(op wire.OutPoint, takeover int32) *chainhash.Hash {
+	var hh chainhash.Hash
+	ValueHashInto(&hh, op, takeover)
+	return &hh
+}
+
+// ValueHashInto computes the ValueHash into dst, without allocating.
+func ValueHashInto(dst *chainhash.Hash, op wire.OutPoint, takeover int32) {
+
+	var h [chainhash.HashSize * 3]byte
+
+	txHash := chainhash.DoubleHashH(op.Hash[:])
+	copy(h[:], txHash[:])
+
+	var nOut [20]byte
+	nOutHash := chainhash.DoubleHashH(strconv.AppendInt(nOut[:0], int64(int(op.Index)), 10))
+	copy(h[chainhash.HashSize:], nOutHash[:])
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(takeover))
+	heightHash := chainhash.DoubleHashH(buf[:])
+	copy(h[chainhash.HashSize*2:], heightHash[:])
+
+	*dst = chainhash.DoubleHashH(h[:])
+}
+
+// HashMerkleBranches returns the hash of the binary merkle node of the branches.
+func HashMerkleBranches(left *chainhash.Hash, right *chainhash.Hash) *chainhash.Hash {
+	var newHash chainhash.Hash
+	HashMerkleBranchesInto(&newHash, left, right)
+	return &newHash
+}
+
+// HashMerkleBranchesInto computes the HashMerkleBranches into dst, which may be one of
+// the branches, without allocating.
+func HashMerkleBranchesInto(dst, left, right *chainhash.Hash) {
+	// Concatenate the left and right nodes.
+	var hash [chainhash.HashSize * 2]byte
+	copy(hash[:chainhash.HashSize], left[:])
+	copy(hash[chainhash.HashSize:], right[:])
+
+	*dst = chainhash.DoubleHashH(hash[:])
+}
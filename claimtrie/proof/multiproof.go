@@ -0,0 +1,180 @@
+package proof
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const multiProofVersion = 1
+
+// Value is the best claim of a name proven by a MultiProof.
+type Value struct {
+	Name           []byte
+	OutPoint       wire.OutPoint
+	TakeoverHeight int32
+}
+
+// MultiProof proves the values of many names against the Merkle Hash of the trie.
+// The nodes shared by the paths of the names, and the hashes of their siblings,
+// are only included once.
+type MultiProof struct {
+	// The nodes spanned by the paths of the names, in depth-first order.
+	// A child with a nil Hash is on a path, and is computed from the nodes following it.
+	// The ValueHash of the node of a proven name is unset, as it's computed from its Value.
+	Nodes []Node
+
+	// Sorted by name.
+	Values []Value
+}
+
+// Verify reports whether the proof commits all of its Values to the root hash.
+func (p *MultiProof) Verify(root *chainhash.Hash) bool {
+
+	if len(p.Values) == 0 || len(p.Nodes) == 0 {
+		return false
+	}
+	for i := 1; i < len(p.Values); i++ {
+		if bytes.Compare(p.Values[i-1].Name, p.Values[i].Name) >= 0 {
+			return false
+		}
+	}
+
+	v := &multiProofVerifier{proof: p}
+	h, ok := v.verify(nil)
+	if !ok || v.node != len(p.Nodes) || v.value != len(p.Values) {
+		return false
+	}
+
+	return h.IsEqual(root)
+}
+
+type multiProofVerifier struct {
+	proof *MultiProof
+	node  int // the next node to consume
+	value int // the next value to consume
+}
+
+// verify computes the hash of the node at the prefix, and of the ones below it.
+func (v *multiProofVerifier) verify(prefix []byte) (*chainhash.Hash, bool) {
+
+	if v.node >= len(v.proof.Nodes) {
+		return nil, false
+	}
+	pn := v.proof.Nodes[v.node]
+	v.node++
+
+	// Values are consumed in order, which is that of the depth-first traversal.
+	var value *chainhash.Hash
+	if v.value < len(v.proof.Values) && bytes.Equal(v.proof.Values[v.value].Name, prefix) {
+		if pn.ValueHash != nil {
+			return nil, false
+		}
+		pv := v.proof.Values[v.value]
+		value = ValueHash(pv.OutPoint, pv.TakeoverHeight)
+		v.value++
+	} else {
+		value = pn.ValueHash
+	}
+
+	b := bytes.NewBuffer(nil)
+	for i, c := range pn.Children {
+		if i > 0 && c.Character <= pn.Children[i-1].Character {
+			return nil, false
+		}
+		b.WriteByte(c.Character) // nolint : errchk
+		h := c.Hash
+		if h == nil {
+			var ok bool
+			h, ok = v.verify(append(prefix[:len(prefix):len(prefix)], c.Character))
+			if !ok {
+				return nil, false
+			}
+		}
+		b.Write(h[:]) // nolint : errchk
+	}
+	if value != nil {
+		b.Write(value[:]) // nolint : errchk
+	}
+
+	if b.Len() == 0 {
+		return nil, false
+	}
+	h := chainhash.DoubleHashH(b.Bytes())
+
+	return &h, true
+}
+
+// Encode writes the proof in the canonical binary format:
+//
+//	version(1B)
+//	count(varint) { len(varint) name txhash(32B) nout(4B) takeover(4B) }
+//	count(varint) nodes
+//
+// where the nodes are encoded as those of a Proof.
+func (p *MultiProof) Encode(w io.Writer) error {
+
+	if _, err := w.Write([]byte{multiProofVersion}); err != nil {
+		return err
+	}
+
+	if err := wire.WriteVarInt(w, 0, uint64(len(p.Values))); err != nil {
+		return err
+	}
+	for _, value := range p.Values {
+		if err := wire.WriteVarBytes(w, 0, value.Name); err != nil {
+			return err
+		}
+		var buf [32 + 4 + 4]byte
+		copy(buf[:32], value.OutPoint.Hash[:])
+		binary.BigEndian.PutUint32(buf[32:], value.OutPoint.Index)
+		binary.BigEndian.PutUint32(buf[36:], uint32(value.TakeoverHeight))
+		if _, err := w.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+
+	return writeNodes(w, p.Nodes)
+}
+
+// Decode reads a proof written by Encode.
+func (p *MultiProof) Decode(r io.Reader) error {
+
+	var version [1]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return err
+	}
+	if version[0] != multiProofVersion {
+		return fmt.Errorf("unsupported multiproof version: %d", version[0])
+	}
+
+	count, err := readCount(r)
+	if err != nil {
+		return err
+	}
+
+	*p = MultiProof{Values: make([]Value, count)}
+	for i := range p.Values {
+		name, err := wire.ReadVarBytes(r, 0, 1<<16, "name")
+		if err != nil {
+			return err
+		}
+		var buf [32 + 4 + 4]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return err
+		}
+		value := &p.Values[i]
+		value.Name = name
+		copy(value.OutPoint.Hash[:], buf[:32])
+		value.OutPoint.Index = binary.BigEndian.Uint32(buf[32:])
+		value.TakeoverHeight = int32(binary.BigEndian.Uint32(buf[36:]))
+	}
+
+	p.Nodes, err = readNodes(r)
+
+	return err
+}
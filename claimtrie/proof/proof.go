@@ -0,0 +1,494 @@
+// Package proof verifies the values of names, and the absence of the unclaimed ones,
+// against the Merkle Hashes of the ClaimTrie.
+//
+// It only depends on chainhash and wire, and none of the storage of the ClaimTrie,
+// so that light clients can verify the resolutions received from untrusted hubs,
+// given the roots from the block headers they trust.
+package proof
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const proofVersion = 1
+
+const (
+	proofFlagHasValue = 1 << iota
+	proofFlagPairs
+)
+
+// maxProofEntries bounds the number of entries decoded from untrusted input.
+const maxProofEntries = 1 << 16
+
+// Child is a child link of a node along the path of a Proof.
+// The child on the path has a nil Hash, as it is computed from the next node.
+type Child struct {
+	Character byte
+	Hash      *chainhash.Hash
+}
+
+// Node is a node along the path of a Proof, ordered from the root to the name.
+type Node struct {
+	Children  []Child         // Sorted by character.
+	ValueHash *chainhash.Hash // Unset on the last node, whose value is computed from the OutPoint.
+}
+
+// Pair is a sibling hash on the binary merkle path used after the all-claims fork.
+// Odd reports whether the computed hash is the right branch, and Hash the left one, or vice versa.
+type Pair struct {
+	Odd  bool
+	Hash chainhash.Hash
+}
+
+// Proof proves the value of a name against the Merkle Hash of the trie.
+// It follows the layout of lbrycrd's getnameproof, where Nodes are used before
+// the all-claims fork, and Pairs after the fork.
+type Proof struct {
+	Nodes []Node
+	Pairs []Pair
+
+	HasValue       bool
+	OutPoint       wire.OutPoint
+	TakeoverHeight int32
+}
+
+// Verify reports whether the proof commits the name to the root hash.
+// The name is only verified by proofs with Nodes, as Pairs don't commit to it.
+func (p *Proof) Verify(root *chainhash.Hash, name []byte) bool {
+
+	var h *chainhash.Hash
+	if p.HasValue {
+		h = ValueHash(p.OutPoint, p.TakeoverHeight)
+	}
+
+	if len(p.Pairs) > 0 {
+		if h == nil {
+			return false
+		}
+		for _, pair := range p.Pairs {
+			if pair.Odd {
+				h = HashMerkleBranches(&pair.Hash, h)
+			} else {
+				h = HashMerkleBranches(h, &pair.Hash)
+			}
+		}
+		return h.IsEqual(root)
+	}
+
+	h, ok := p.nodesRoot(name, h)
+	return ok && h.IsEqual(root)
+}
+
+// VerifyAbsence reports whether the proof commits the root hash to the name not having a value.
+// Its Nodes lead from the root to the name, or to its longest prefix in the trie, of which
+// the children are all hashed, and the value, if any, is the one of the prefix.
+// The absence can't be proven after the all-claims fork, as the hashes don't commit to the names.
+func (p *Proof) VerifyAbsence(root *chainhash.Hash, name []byte) bool {
+
+	if p.HasValue || len(p.Pairs) > 0 || len(p.Nodes) == 0 || len(p.Nodes) > len(name)+1 {
+		return false
+	}
+
+	depth := len(p.Nodes) - 1
+	last := p.Nodes[depth]
+	if depth == len(name) && last.ValueHash != nil {
+		return false
+	}
+	for _, c := range last.Children {
+		if depth < len(name) && c.Character == name[depth] {
+			return false
+		}
+	}
+
+	h, ok := p.nodesRoot(name[:depth], last.ValueHash)
+	return ok && h.IsEqual(root)
+}
+
+// nodesRoot returns the root hash computed from the Nodes along the path to the name, of which the
+// last one has the value h, if it's set, and reports whether they follow the path.
+func (p *Proof) nodesRoot(name []byte, h *chainhash.Hash) (*chainhash.Hash, bool) {
+
+	if len(p.Nodes) == 0 {
+		return nil, false
+	}
+
+	matched := len(name)
+	b := bytes.NewBuffer(nil)
+	for i := len(p.Nodes) - 1; i >= 0; i-- {
+		last := i == len(p.Nodes)-1
+		b.Reset()
+		onPath := 0
+		for _, c := range p.Nodes[i].Children {
+			b.WriteByte(c.Character) // nolint : errchk
+			if c.Hash != nil {
+				b.Write(c.Hash[:]) // nolint : errchk
+				continue
+			}
+			// The child on the path, whose hash is computed from the next node.
+			if last || onPath > 0 || matched == 0 || name[matched-1] != c.Character {
+				return nil, false
+			}
+			b.Write(h[:]) // nolint : errchk
+			onPath++
+			matched--
+		}
+		if !last && onPath == 0 {
+			return nil, false
+		}
+
+		value := p.Nodes[i].ValueHash
+		if last {
+			value = h
+		}
+		if value != nil {
+			b.Write(value[:]) // nolint : errchk
+		}
+
+		if b.Len() == 0 {
+			return EmptyTrieHash, matched == 0
+		}
+		nh := chainhash.DoubleHashH(b.Bytes())
+		h = &nh
+	}
+
+	return h, matched == 0
+}
+
+// Encode writes the proof in the canonical binary format:
+//
+//	version(1B) flags(1B) [txhash(32B) nout(4B) takeover(4B)]
+//	count(varint) nodes | pairs
+//
+// where each node is encoded as:
+//
+//	count(varint) { ch(1B) hashed(1B) [hash(32B)] } hashed(1B) [vhash(32B)]
+//
+// and each pair as:
+//
+//	odd(1B) hash(32B)
+func (p *Proof) Encode(w io.Writer) error {
+
+	flags := byte(0)
+	if p.HasValue {
+		flags |= proofFlagHasValue
+	}
+	if len(p.Pairs) > 0 {
+		flags |= proofFlagPairs
+	}
+	if _, err := w.Write([]byte{proofVersion, flags}); err != nil {
+		return err
+	}
+
+	if p.HasValue {
+		var buf [32 + 4 + 4]byte
+		copy(buf[:32], p.OutPoint.Hash[:])
+		binary.BigEndian.PutUint32(buf[32:], p.OutPoint.Index)
+		binary.BigEndian.PutUint32(buf[36:], uint32(p.TakeoverHeight))
+		if _, err := w.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+
+	if flags&proofFlagPairs != 0 {
+		if err := wire.WriteVarInt(w, 0, uint64(len(p.Pairs))); err != nil {
+			return err
+		}
+		for _, pair := range p.Pairs {
+			odd := byte(0)
+			if pair.Odd {
+				odd = 1
+			}
+			if _, err := w.Write([]byte{odd}); err != nil {
+				return err
+			}
+			if _, err := w.Write(pair.Hash[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return writeNodes(w, p.Nodes)
+}
+
+func writeNodes(w io.Writer, nodes []Node) error {
+
+	if err := wire.WriteVarInt(w, 0, uint64(len(nodes))); err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if err := writeNode(w, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeNode(w io.Writer, n Node) error {
+
+	if err := wire.WriteVarInt(w, 0, uint64(len(n.Children))); err != nil {
+		return err
+	}
+	for _, c := range n.Children {
+		if _, err := w.Write([]byte{c.Character}); err != nil {
+			return err
+		}
+		if err := writeOptionalHash(w, c.Hash != nil, c.Hash); err != nil {
+			return err
+		}
+	}
+
+	return writeOptionalHash(w, n.ValueHash != nil, n.ValueHash)
+}
+
+// Decode reads a proof written by Encode.
+func (p *Proof) Decode(r io.Reader) error {
+
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return err
+	}
+	if hdr[0] != proofVersion {
+		return fmt.Errorf("unsupported proof version: %d", hdr[0])
+	}
+	flags := hdr[1]
+
+	*p = Proof{HasValue: flags&proofFlagHasValue != 0}
+	if p.HasValue {
+		var buf [32 + 4 + 4]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return err
+		}
+		copy(p.OutPoint.Hash[:], buf[:32])
+		p.OutPoint.Index = binary.BigEndian.Uint32(buf[32:])
+		p.TakeoverHeight = int32(binary.BigEndian.Uint32(buf[36:]))
+	}
+
+	if flags&proofFlagPairs == 0 {
+		var err error
+		p.Nodes, err = readNodes(r)
+		return err
+	}
+
+	count, err := readCount(r)
+	if err != nil {
+		return err
+	}
+
+	p.Pairs = make([]Pair, count)
+	for i := range p.Pairs {
+		odd, h, err := readOptionalHash(r, true)
+		if err != nil {
+			return err
+		}
+		p.Pairs[i] = Pair{Odd: odd, Hash: *h}
+	}
+
+	return nil
+}
+
+func readNodes(r io.Reader) ([]Node, error) {
+
+	count, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]Node, count)
+	for i := range nodes {
+		nodes[i], err = readNode(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nodes, nil
+}
+
+func readNode(r io.Reader) (Node, error) {
+
+	var n Node
+
+	children, err := readCount(r)
+	if err != nil {
+		return n, err
+	}
+	if children > 0 {
+		n.Children = make([]Child, children)
+	}
+	for j := range n.Children {
+		var ch [1]byte
+		if _, err := io.ReadFull(r, ch[:]); err != nil {
+			return n, err
+		}
+		_, h, err := readOptionalHash(r, false)
+		if err != nil {
+			return n, err
+		}
+		n.Children[j] = Child{Character: ch[0], Hash: h}
+	}
+	_, n.ValueHash, err = readOptionalHash(r, false)
+
+	return n, err
+}
+
+func readCount(r io.Reader) (int, error) {
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return 0, err
+	}
+	if count > maxProofEntries {
+		return 0, fmt.Errorf("too many proof entries: %d", count)
+	}
+	return int(count), nil
+}
+
+func writeOptionalHash(w io.Writer, flag bool, h *chainhash.Hash) error {
+	if !flag {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	if _, err := w.Write([]byte{1}); err != nil {
+		return err
+	}
+	_, err := w.Write(h[:])
+	return err
+}
+
+// readOptionalHash reads a flag and the hash following it.
+// If always is set, the hash is read regardless of the flag.
+func readOptionalHash(r io.Reader, always bool) (bool, *chainhash.Hash, error) {
+	var flag [1]byte
+	if _, err := io.ReadFull(r, flag[:]); err != nil {
+		return false, nil, err
+	}
+	if flag[0] > 1 {
+		return false, nil, fmt.Errorf("invalid proof flag: %d", flag[0])
+	}
+	if flag[0] == 0 && !always {
+		return false, nil, nil
+	}
+	h := &chainhash.Hash{}
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return false, nil, err
+	}
+	return flag[0] == 1, h, nil
+}
+
+type jsonProofChild struct {
+	Character byte   `json:"character"`
+	NodeHash  string `json:"nodeHash,omitempty"`
+}
+
+type jsonProofNode struct {
+	Children  []jsonProofChild `json:"children,omitempty"`
+	ValueHash string           `json:"valueHash,omitempty"`
+}
+
+type jsonProofPair struct {
+	Odd  bool   `json:"odd"`
+	Hash string `json:"hash"`
+}
+
+type jsonProof struct {
+	Nodes          []jsonProofNode `json:"nodes,omitempty"`
+	Pairs          []jsonProofPair `json:"pairs,omitempty"`
+	TxHash         string          `json:"txhash,omitempty"`
+	NOut           *uint32         `json:"nOut,omitempty"`
+	TakeoverHeight *int32          `json:"last takeover height,omitempty"`
+}
+
+// MarshalJSON encodes the proof with the field names used by lbrycrd's getnameproof.
+func (p *Proof) MarshalJSON() ([]byte, error) {
+
+	jp := jsonProof{}
+	for _, n := range p.Nodes {
+		jn := jsonProofNode{}
+		for _, c := range n.Children {
+			jc := jsonProofChild{Character: c.Character}
+			if c.Hash != nil {
+				jc.NodeHash = c.Hash.String()
+			}
+			jn.Children = append(jn.Children, jc)
+		}
+		if n.ValueHash != nil {
+			jn.ValueHash = n.ValueHash.String()
+		}
+		jp.Nodes = append(jp.Nodes, jn)
+	}
+	for _, pair := range p.Pairs {
+		jp.Pairs = append(jp.Pairs, jsonProofPair{Odd: pair.Odd, Hash: pair.Hash.String()})
+	}
+	if p.HasValue {
+		nOut, takeover := p.OutPoint.Index, p.TakeoverHeight
+		jp.TxHash = p.OutPoint.Hash.String()
+		jp.NOut = &nOut
+		jp.TakeoverHeight = &takeover
+	}
+
+	return json.Marshal(jp)
+}
+
+// UnmarshalJSON decodes a proof encoded by MarshalJSON.
+func (p *Proof) UnmarshalJSON(b []byte) error {
+
+	var jp jsonProof
+	if err := json.Unmarshal(b, &jp); err != nil {
+		return err
+	}
+
+	*p = Proof{}
+	for _, jn := range jp.Nodes {
+		n := Node{}
+		for _, jc := range jn.Children {
+			c := Child{Character: jc.Character}
+			if jc.NodeHash != "" {
+				h, err := chainhash.NewHashFromStr(jc.NodeHash)
+				if err != nil {
+					return fmt.Errorf("node hash: %w", err)
+				}
+				c.Hash = h
+			}
+			n.Children = append(n.Children, c)
+		}
+		if jn.ValueHash != "" {
+			h, err := chainhash.NewHashFromStr(jn.ValueHash)
+			if err != nil {
+				return fmt.Errorf("value hash: %w", err)
+			}
+			n.ValueHash = h
+		}
+		p.Nodes = append(p.Nodes, n)
+	}
+	for _, jpair := range jp.Pairs {
+		h, err := chainhash.NewHashFromStr(jpair.Hash)
+		if err != nil {
+			return fmt.Errorf("pair hash: %w", err)
+		}
+		p.Pairs = append(p.Pairs, Pair{Odd: jpair.Odd, Hash: *h})
+	}
+	if jp.TxHash != "" {
+		h, err := chainhash.NewHashFromStr(jp.TxHash)
+		if err != nil {
+			return fmt.Errorf("tx hash: %w", err)
+		}
+		p.HasValue = true
+		p.OutPoint.Hash = *h
+		if jp.NOut != nil {
+			p.OutPoint.Index = *jp.NOut
+		}
+		if jp.TakeoverHeight != nil {
+			p.TakeoverHeight = *jp.TakeoverHeight
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package proof_test
+
+import (
+	"bytes"
+	"go/build"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/proof"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+
+	r := require.New(t)
+
+	// The trie holds only the name "a", whose root is computed by hand.
+	op := wire.OutPoint{Hash: chainhash.HashH([]byte("tx")), Index: 1}
+	value := proof.ValueHash(op, 10)
+	leaf := chainhash.DoubleHashH(value[:])
+	root := chainhash.DoubleHashH(append([]byte{'a'}, leaf[:]...))
+
+	p := &proof.Proof{
+		Nodes:          []proof.Node{{Children: []proof.Child{{Character: 'a'}}}, {}},
+		HasValue:       true,
+		OutPoint:       op,
+		TakeoverHeight: 10,
+	}
+	r.True(p.Verify(&root, []byte("a")))
+	r.False(p.Verify(&root, []byte("b")))
+	r.False(p.Verify(&leaf, []byte("a")))
+
+	b := bytes.NewBuffer(nil)
+	r.NoError(p.Encode(b))
+	var decoded proof.Proof
+	r.NoError(decoded.Decode(b))
+	r.True(decoded.Verify(&root, []byte("a")))
+
+	decoded.TakeoverHeight++
+	r.False(decoded.Verify(&root, []byte("a")))
+}
+
+func TestVerifyAbsence(t *testing.T) {
+
+	r := require.New(t)
+
+	// The trie holds only the name "a", as in TestVerify.
+	op := wire.OutPoint{Hash: chainhash.HashH([]byte("tx")), Index: 1}
+	value := proof.ValueHash(op, 10)
+	leaf := chainhash.DoubleHashH(value[:])
+	root := chainhash.DoubleHashH(append([]byte{'a'}, leaf[:]...))
+
+	// The path of "b" ends at the root, and the one of "ab" at "a", which has no child 'b'.
+	b := &proof.Proof{Nodes: []proof.Node{{Children: []proof.Child{{Character: 'a', Hash: &leaf}}}}}
+	r.True(b.VerifyAbsence(&root, []byte("b")))
+	r.True(b.VerifyAbsence(&root, []byte("bc")))
+	r.False(b.VerifyAbsence(&root, []byte("a")))
+	r.False(b.VerifyAbsence(&leaf, []byte("b")))
+
+	ab := &proof.Proof{Nodes: []proof.Node{{Children: []proof.Child{{Character: 'a'}}}, {ValueHash: value}}}
+	r.True(ab.VerifyAbsence(&root, []byte("ab")))
+	r.False(ab.VerifyAbsence(&root, []byte("a")))
+	r.False(ab.VerifyAbsence(&root, []byte("bb")))
+
+	// The proofs of the values don't prove the absence.
+	a := &proof.Proof{Nodes: []proof.Node{{Children: []proof.Child{{Character: 'a'}}}, {}}, HasValue: true, OutPoint: op, TakeoverHeight: 10}
+	r.False(a.VerifyAbsence(&root, []byte("a")))
+
+	r.True((&proof.Proof{Nodes: []proof.Node{{}}}).VerifyAbsence(proof.EmptyTrieHash, []byte("a")))
+
+	buf := bytes.NewBuffer(nil)
+	r.NoError(ab.Encode(buf))
+	var decoded proof.Proof
+	r.NoError(decoded.Decode(buf))
+	r.True(decoded.VerifyAbsence(&root, []byte("ab")))
+
+	decoded.Nodes[1].ValueHash = &leaf
+	r.False(decoded.VerifyAbsence(&root, []byte("ab")))
+}
+
+// testTrie builds the proofs of the names, of which the values are committed by a trie
+// computed by hand, one character per node.
+func testTrie(values map[string]*chainhash.Hash) (chainhash.Hash, map[string]*proof.Proof) {
+
+	children := func(prefix string) []byte {
+		seen := map[byte]bool{}
+		var chs []byte
+		for name := range values {
+			if len(name) > len(prefix) && strings.HasPrefix(name, prefix) && !seen[name[len(prefix)]] {
+				seen[name[len(prefix)]] = true
+				chs = append(chs, name[len(prefix)])
+			}
+		}
+		sort.Slice(chs, func(i, j int) bool { return chs[i] < chs[j] })
+		return chs
+	}
+	var hash func(prefix string) chainhash.Hash
+	hash = func(prefix string) chainhash.Hash {
+		var b []byte
+		for _, ch := range children(prefix) {
+			h := hash(prefix + string(ch))
+			b = append(append(b, ch), h[:]...)
+		}
+		if value, ok := values[prefix]; ok {
+			b = append(b, value[:]...)
+		}
+		return chainhash.DoubleHashH(b)
+	}
+
+	proofs := map[string]*proof.Proof{}
+	for name := range values {
+		p := &proof.Proof{}
+		for i := 0; i <= len(name); i++ {
+			prefix := name[:i]
+			var n proof.Node
+			for _, ch := range children(prefix) {
+				c := proof.Child{Character: ch}
+				if i == len(name) || ch != name[i] {
+					h := hash(prefix + string(ch))
+					c.Hash = &h
+				}
+				n.Children = append(n.Children, c)
+			}
+			if i < len(name) {
+				n.ValueHash = values[prefix]
+			}
+			p.Nodes = append(p.Nodes, n)
+		}
+		proofs[name] = p
+	}
+
+	return hash(""), proofs
+}
+
+func TestVerifyProofBatch(t *testing.T) {
+
+	r := require.New(t)
+
+	names := []string{"a", "ab", "abc", "abd", "b", "ba", "bcd"}
+	values := map[string]*chainhash.Hash{}
+	ops := map[string]wire.OutPoint{}
+	for i, name := range names {
+		ops[name] = wire.OutPoint{Hash: chainhash.HashH([]byte(name)), Index: uint32(i)}
+		values[name] = proof.ValueHash(ops[name], int32(i))
+	}
+	root, proofs := testTrie(values)
+
+	var batch []proof.NamedProof
+	for i, name := range names {
+		p := proofs[name]
+		p.HasValue, p.OutPoint, p.TakeoverHeight = true, ops[name], int32(i)
+		batch = append(batch, proof.NamedProof{Name: []byte(name), Proof: p})
+	}
+
+	// The proofs sharing the verified prefixes still fail on their own faults.
+	tampered := *proofs["abd"]
+	tampered.TakeoverHeight++
+	batch = append(batch,
+		proof.NamedProof{Name: []byte("abd"), Proof: &tampered},
+		proof.NamedProof{Name: []byte("abe"), Proof: proofs["abd"]},
+		proof.NamedProof{Name: []byte("a"), Proof: proofs["ab"]},
+		proof.NamedProof{Name: []byte("c"), Proof: nil},
+	)
+
+	results := proof.VerifyProofBatch(&root, batch)
+	r.Len(results, len(batch))
+	for i, np := range batch {
+		r.Equal(np.Proof != nil && np.Proof.Verify(&root, np.Name), results[i], "%s", np.Name)
+	}
+	r.Equal([]bool{true, true, true, true, true, true, true, false, false, false, false}, results)
+
+	// Against another root, none verifies.
+	other := chainhash.HashH([]byte("other"))
+	for _, ok := range proof.VerifyProofBatch(&other, batch) {
+		r.False(ok)
+	}
+}
+
+func TestHashAllocations(t *testing.T) {
+
+	r := require.New(t)
+
+	op := wire.OutPoint{Hash: chainhash.HashH([]byte("tx")), Index: 1}
+	var h chainhash.Hash
+	r.Zero(testing.AllocsPerRun(100, func() { proof.ValueHashInto(&h, op, 10) }))
+	r.Equal(*proof.ValueHash(op, 10), h)
+
+	left, right := chainhash.HashH([]byte("left")), chainhash.HashH([]byte("right"))
+	r.Zero(testing.AllocsPerRun(100, func() { proof.HashMerkleBranchesInto(&h, &left, &right) }))
+	r.Equal(*proof.HashMerkleBranches(&left, &right), h)
+}
+
+// TestDependencies keeps the package importable by light clients, without the storage of the ClaimTrie.
+func TestDependencies(t *testing.T) {
+
+	r := require.New(t)
+
+	allowed := []string{"/chaincfg/chainhash", "/wire", "golang.org/x/crypto/"}
+	seen := map[string]bool{}
+	var walk func(path string)
+	walk = func(path string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+
+		pkg, err := build.Import(path, ".", 0)
+		r.NoError(err)
+		if pkg.Goroot {
+			return
+		}
+		if path != "github.com/btcsuite/btcd/claimtrie/proof" {
+			ok := false
+			for _, a := range allowed {
+				ok = ok || strings.Contains(path, a)
+			}
+			r.True(ok, "proof depends on %s", path)
+		}
+		for _, imp := range pkg.Imports {
+			walk(imp)
+		}
+	}
+	walk("github.com/btcsuite/btcd/claimtrie/proof")
+}
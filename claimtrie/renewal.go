@@ -0,0 +1,89 @@
+package claimtrie
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// RenewalCandidate is a claim of an owner, which expires within the window of RenewalCandidates,
+// unless it's updated before, such as by a renewal service.
+type RenewalCandidate struct {
+	Name               []byte // Normalized as of the height.
+	ClaimID            change.ClaimID
+	OutPoint           wire.OutPoint
+	Amount             int64
+	ExpireAt           int32
+	BlocksUntilExpired int32
+}
+
+// RenewalCandidates returns the claims paid to the owner, a destination script noted by SetOwner,
+// which haven't expired as of the last block committed, but expire within the window of blocks after it,
+// the soonest first. The supports of the owner aren't renewed along with the claims, so they aren't listed.
+// It fails with ErrOutPointNotIndexed, if the outpoints aren't indexed.
+func (ct *ClaimTrie) RenewalCandidates(owner []byte, window int32) ([]RenewalCandidate, error) {
+
+	if ct.outPoints == nil {
+		return nil, ErrOutPointNotIndexed
+	}
+
+	entries, err := ct.outPoints.Owned(owner)
+	if err != nil {
+		return nil, fmt.Errorf("outpoint repo owned: %w", err)
+	}
+
+	unlock, err := ct.readHistory(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	height := ct.Snapshot().height
+	var candidates []RenewalCandidate
+	for _, e := range entries {
+		if e.Support {
+			continue
+		}
+		n, err := ct.nodeManager.NodeAt(height, e.Name)
+		if err != nil {
+			return nil, fmt.Errorf("node %q at %d: %w", e.Name, height, err)
+		}
+		if n == nil {
+			continue // the claim is indexed by a block after the one committed
+		}
+		for _, c := range n.Claims {
+			if c.OutPoint != e.OutPoint {
+				continue
+			}
+			m := c.MaturityAt(height)
+			if m.BlocksUntilExpired > 0 && m.BlocksUntilExpired <= window {
+				candidates = append(candidates, RenewalCandidate{
+					Name:               e.Name,
+					ClaimID:            c.ClaimID,
+					OutPoint:           c.OutPoint,
+					Amount:             c.Amount,
+					ExpireAt:           m.ExpireAt,
+					BlocksUntilExpired: m.BlocksUntilExpired,
+				})
+			}
+			break
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		switch {
+		case a.ExpireAt != b.ExpireAt:
+			return a.ExpireAt < b.ExpireAt
+		case !bytes.Equal(a.Name, b.Name):
+			return bytes.Compare(a.Name, b.Name) < 0
+		}
+		return node.OutPointLess(a.OutPoint, b.OutPoint)
+	})
+
+	return candidates, nil
+}
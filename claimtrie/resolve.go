@@ -0,0 +1,148 @@
+package claimtrie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// ErrNotRetained is returned when the state of a name is resolved at a height,
+// of which the changes are pruned, or which is above the last one committed.
+var ErrNotRetained = errors.New("height is not retained")
+
+// ErrNameNotFound is returned for the names without any claims, or supports.
+// Their absence is proven by AbsenceProof.
+var ErrNameNotFound = errors.New("name not found")
+
+// ErrNoBestClaim is returned for proving the names, which have claims, or supports, but no active claim.
+var ErrNoBestClaim = errors.New("name has no best claim")
+
+// checkBestClaim returns ErrNameNotFound, or ErrNoBestClaim, if the node doesn't have a best claim.
+func checkBestClaim(name []byte, n *node.Node) error {
+	if n == nil || len(n.Claims) == 0 && len(n.Supports) == 0 {
+		return fmt.Errorf("%w: %q", ErrNameNotFound, name)
+	}
+	if n.BestClaim == nil {
+		return fmt.Errorf("%w: %q", ErrNoBestClaim, name)
+	}
+	return nil
+}
+
+// historyCacher is a node manager caching the nodes materialized at the heights.
+type historyCacher interface {
+	SetHistoryCacheSize(size int)
+}
+
+// versioner is a node manager keeping the versions of the nodes changed by the recent blocks.
+type versioner interface {
+	SetVersionDepth(depth int32)
+}
+
+// Resolution is the state of a name as of a block.
+type Resolution struct {
+	Name   []byte // Normalized as of the height.
+	Height int32
+	Root   chainhash.Hash // The root hash of the trie at the height.
+	Node   *node.Node     // Without a BestClaim, if none of the claims is active.
+}
+
+// ResolveAt resolves the name as of the height, by replaying its changes up to it.
+// It fails with ErrNameNotFound, if the name had no claims or supports at the height.
+// It's safe for concurrent access, including while a block is appended.
+func (ct *ClaimTrie) ResolveAt(name []byte, height int32) (*Resolution, error) {
+	return ct.ResolveAtContext(context.Background(), name, height)
+}
+
+// ResolveAtContext resolves the name as ResolveAt does, but gives up, returning the error of the context,
+// once it's done, so that a name with a long history doesn't hold up the resets of the height for long.
+func (ct *ClaimTrie) ResolveAtContext(ctx context.Context, name []byte, height int32) (*Resolution, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	unlock, err := ct.readHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	committed := ct.Snapshot().height
+	if height > committed || height < ct.prunedAt || height < 0 {
+		return nil, fmt.Errorf("%w: %d, retained from %d to %d", ErrNotRetained, height, ct.prunedAt, committed)
+	}
+
+	root, err := ct.blockRepo.Get(height)
+	if err != nil {
+		return nil, fmt.Errorf("root at %d: %w", height, err)
+	}
+
+	normName := node.NormalizeIfNecessary(name, height)
+	n, err := ct.nodeManager.NodeAtContext(ctx, height, normName)
+	if err != nil {
+		return nil, fmt.Errorf("node at %d: %w", height, err)
+	}
+	if n == nil || len(n.Claims) == 0 && len(n.Supports) == 0 {
+		return nil, fmt.Errorf("%w: %q at %d", ErrNameNotFound, normName, height)
+	}
+
+	return &Resolution{Name: normName, Height: height, Root: *root, Node: n}, nil
+}
+
+// RootAt returns the root of the trie at the height, which must be committed, and retained.
+// It's safe for concurrent access, including while a block is appended.
+func (ct *ClaimTrie) RootAt(height int32) (*chainhash.Hash, error) {
+
+	unlock, err := ct.readHistory(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	committed := ct.Snapshot().height
+	if height > committed || height < ct.prunedAt || height < 0 {
+		return nil, fmt.Errorf("%w: %d, retained from %d to %d", ErrNotRetained, height, ct.prunedAt, committed)
+	}
+
+	root, err := ct.blockRepo.Get(height)
+	if err != nil {
+		return nil, fmt.Errorf("root at %d: %w", height, err)
+	}
+
+	return root, nil
+}
+
+// ProveAtContext resolves the name as of the height, as ResolveAtContext does, and returns the proof of its best
+// claim against the root at the height, which proof.Proof.Verify verifies. It fails with ErrNoBestClaim without one.
+// Proofs are only supported before the all-claims fork.
+func (ct *ClaimTrie) ProveAtContext(ctx context.Context, name []byte, height int32) (*Resolution, *merkletrie.Proof, error) {
+
+	if height >= param.AllClaimsInMerkleForkHeight {
+		return nil, nil, fmt.Errorf("name proofs are unsupported after the all-claims fork")
+	}
+
+	if from := atomic.LoadInt32(&ct.trieRetainedFrom); height < from {
+		return nil, nil, fmt.Errorf("%w: %d, the trie nodes are retained from %d", ErrNotRetained, height, from)
+	}
+	res, err := ct.ResolveAtContext(ctx, name, height)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = checkBestClaim(res.Name, res.Node); err != nil {
+		return nil, nil, err
+	}
+
+	// The view shares the repo, in which the nodes of the roots retained are stored.
+	p, err := ct.merkleTrie.At(&res.Root).Prove(res.Name, res.Node.BestClaim.OutPoint, res.Node.TakenOverAt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res, p, nil
+}
@@ -0,0 +1,298 @@
+// Package resolver answers the queries of the wallets, and hubs, about the names and claims of a ClaimTrie
+// over the JSON-RPC of btcd, from the state as of the last block committed, while the ClaimTrie syncs.
+package resolver
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// maxRequestSize is the limit of the body of a request, which holds only a name, or a Claim ID.
+const maxRequestSize = 1 << 16
+
+// Server is an http.Handler of the JSON-RPC requests of the resolvename, getclaimsforname, getvalueforname,
+// and getclaimbyid commands. The last one requires the ClaimTrie to index the Claim IDs.
+type Server struct {
+	ct *claimtrie.ClaimTrie
+}
+
+// NewServer returns a Server of the queries of the ClaimTrie.
+func NewServer(ct *claimtrie.ClaimTrie) *Server {
+	return &Server{ct: ct}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, "read request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req btcjson.Request
+	var result interface{}
+	var rpcErr *btcjson.RPCError
+	if err = json.Unmarshal(body, &req); err != nil {
+		rpcErr = btcjson.NewRPCError(btcjson.ErrRPCParse.Code, "Failed to parse request: "+err.Error())
+	} else {
+		result, rpcErr = s.handle(&req)
+	}
+
+	version := req.Jsonrpc
+	if !version.IsValid() {
+		version = btcjson.RpcVersion1
+	}
+	b, err := btcjson.MarshalResponse(version, req.ID, result, rpcErr)
+	if err != nil {
+		http.Error(w, "marshal response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b) // nolint : errchk
+}
+
+// handle parses the request into its command, and answers it from the last block committed.
+func (s *Server) handle(req *btcjson.Request) (interface{}, *btcjson.RPCError) {
+
+	cmd, err := btcjson.UnmarshalCmd(req)
+	if err != nil {
+		if jerr, ok := err.(btcjson.Error); ok && jerr.ErrorCode == btcjson.ErrUnregisteredMethod {
+			return nil, btcjson.ErrRPCMethodNotFound
+		}
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParams.Code, err.Error())
+	}
+
+	snap := s.ct.Snapshot()
+	switch c := cmd.(type) {
+	case *btcjson.ResolveNameCmd:
+		return s.resolveName(snap, []byte(c.Name))
+	case *btcjson.GetClaimsForNameCmd:
+		return s.getClaimsForName(snap, []byte(c.Name))
+	case *btcjson.GetValueForNameCmd:
+		return s.getValueForName(snap, []byte(c.Name))
+	case *btcjson.GetClaimByIDCmd:
+		return s.getClaimByID(snap, c.ClaimID)
+	}
+
+	return nil, btcjson.ErrRPCMethodNotFound
+}
+
+func (s *Server) resolveName(snap *claimtrie.Snapshot, name []byte) (interface{}, *btcjson.RPCError) {
+
+	res, err := snap.Resolve(name)
+	if err != nil {
+		return nil, queryError(err, "Failed to resolve the name")
+	}
+
+	result := btcjson.ResolveResult{
+		Name:        string(res.Name),
+		Height:      res.Height,
+		ClaimTrie:   res.Root.String(),
+		Claims:      []btcjson.ResolvedClaim{},
+		TotalClaims: len(res.Node.Claims),
+	}
+	for _, c := range res.Node.Claims {
+		rc, rpcErr := s.resolvedClaim(c, res)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		result.Claims = append(result.Claims, rc)
+	}
+	if res.Node.BestClaim != nil {
+		best, rpcErr := s.resolvedClaim(res.Node.BestClaim, res)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		result.BestClaim = &best
+		result.TakenOverAt = res.Node.TakenOverAt
+	}
+
+	return result, nil
+}
+
+func (s *Server) getClaimsForName(snap *claimtrie.Snapshot, name []byte) (interface{}, *btcjson.RPCError) {
+
+	root := snap.Root()
+	result := btcjson.GetClaimsForNameResult{
+		Name:      string(node.NormalizeIfNecessary(name, snap.Height())),
+		Height:    snap.Height(),
+		ClaimTrie: root.String(),
+		Claims:    []btcjson.ResolvedClaim{},
+		Supports:  []btcjson.NameSupport{},
+	}
+
+	res, err := snap.Resolve(name)
+	if errors.Is(err, claimtrie.ErrNameNotFound) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, queryError(err, "Failed to list the claims of the name")
+	}
+
+	for _, c := range res.Node.Claims {
+		rc, rpcErr := s.resolvedClaim(c, res)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		result.Claims = append(result.Claims, rc)
+	}
+	for _, c := range res.Node.Supports {
+		result.Supports = append(result.Supports, btcjson.NameSupport{
+			ClaimID:    c.ClaimID.String(),
+			OutPoint:   c.OutPoint.String(),
+			Amount:     c.Amount,
+			AcceptedAt: c.AcceptedAt,
+			ActiveAt:   c.ActiveAt,
+			Status:     c.Status.String(),
+		})
+	}
+	if res.Node.BestClaim != nil {
+		result.TakenOverAt = res.Node.TakenOverAt
+	}
+
+	return result, nil
+}
+
+func (s *Server) getValueForName(snap *claimtrie.Snapshot, name []byte) (interface{}, *btcjson.RPCError) {
+
+	res, err := snap.Resolve(name)
+	if err == nil && res.Node.BestClaim == nil {
+		err = claimtrie.ErrNoBestClaim
+	}
+	if err != nil {
+		return nil, queryError(err, "Failed to get the value of the name")
+	}
+
+	best := res.Node.BestClaim
+	value, rpcErr := s.value(best)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return btcjson.GetValueForNameResult{
+		Name:            string(res.Name),
+		Height:          res.Height,
+		ClaimTrie:       res.Root.String(),
+		ClaimID:         best.ClaimID.String(),
+		OutPoint:        best.OutPoint.String(),
+		Amount:          best.Amount,
+		EffectiveAmount: best.EffectiveAmount(res.Node.Supports),
+		TakenOverAt:     res.Node.TakenOverAt,
+		Value:           hex.EncodeToString(value),
+	}, nil
+}
+
+// getClaimByID returns the claims with the Claim ID, as the collision policy of the index resolves them,
+// which are still in their names as of the block. The index may be ahead of it, while a block is appended.
+func (s *Server) getClaimByID(snap *claimtrie.Snapshot, claimID string) (interface{}, *btcjson.RPCError) {
+
+	id, err := node.NewIDFromString(claimID)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Invalid claim ID: "+err.Error())
+	}
+
+	indexed, err := s.ct.ClaimByID(id)
+	if err != nil {
+		return nil, queryError(err, "Failed to look up the claim ID")
+	}
+
+	results := []btcjson.GetClaimByIDResult{}
+	for _, ic := range indexed {
+		res, err := snap.Resolve(ic.Name)
+		if errors.Is(err, claimtrie.ErrNameNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, queryError(err, "Failed to resolve the name of the claim")
+		}
+		for _, c := range res.Node.Claims {
+			if c.OutPoint != ic.OutPoint || c.Status == node.Deactivated {
+				continue
+			}
+			rc, rpcErr := s.resolvedClaim(c, res)
+			if rpcErr != nil {
+				return nil, rpcErr
+			}
+			results = append(results, btcjson.GetClaimByIDResult{
+				Name:      string(res.Name),
+				Height:    res.Height,
+				ClaimTrie: res.Root.String(),
+				Claim:     rc,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+func (s *Server) resolvedClaim(c *node.Claim, res *claimtrie.Resolution) (btcjson.ResolvedClaim, *btcjson.RPCError) {
+
+	value, rpcErr := s.value(c)
+	if rpcErr != nil {
+		return btcjson.ResolvedClaim{}, rpcErr
+	}
+
+	m := c.MaturityAt(res.Height)
+	rc := btcjson.ResolvedClaim{
+		ClaimID:            c.ClaimID.String(),
+		OutPoint:           c.OutPoint.String(),
+		Amount:             c.Amount,
+		EffectiveAmount:    c.EffectiveAmount(res.Node.Supports),
+		AcceptedAt:         c.AcceptedAt,
+		ActiveAt:           c.ActiveAt,
+		Value:              hex.EncodeToString(value),
+		Confirmations:      m.Confirmations,
+		Status:             c.Status.String(),
+		BlocksUntilActive:  m.BlocksUntilActive,
+		ExpiresAt:          m.ExpireAt,
+		BlocksUntilExpired: m.BlocksUntilExpired,
+	}
+	if id, ok := node.SigningChannel(value); ok {
+		rc.Channel = id.String()
+	}
+
+	return rc, nil
+}
+
+// value returns the value of the claim, which is empty, if the ClaimTrie doesn't store the values.
+func (s *Server) value(c *node.Claim) ([]byte, *btcjson.RPCError) {
+
+	value, err := s.ct.ClaimValue(c)
+	if err != nil && !errors.Is(err, claimtrie.ErrValueNotStored) {
+		return nil, queryError(err, "Failed to fetch the value of the claim")
+	}
+
+	return value, nil
+}
+
+// queryError maps the errors of the queries to the RPC errors.
+func queryError(err error, context string) *btcjson.RPCError {
+
+	switch {
+	case errors.Is(err, claimtrie.ErrNameNotFound):
+		return btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "The name has no claims or supports")
+	case errors.Is(err, claimtrie.ErrNoBestClaim):
+		return btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "The name has no best claim")
+	case errors.Is(err, claimtrie.ErrClaimIDNotIndexed):
+		return btcjson.NewRPCError(btcjson.ErrRPCMisc, "The claim IDs are not indexed")
+	case errors.Is(err, claimtrie.ErrClaimIDCollision):
+		return btcjson.NewRPCError(btcjson.ErrRPCMisc, "The claim ID is shared by several claims: "+err.Error())
+	case errors.Is(err, claimtrie.ErrStaleSnapshot), errors.Is(err, claimtrie.ErrHistoryBusy):
+		return btcjson.NewRPCError(btcjson.ErrRPCMisc, "The claim trie is being reorganized, try again: "+err.Error())
+	}
+
+	return btcjson.NewRPCError(btcjson.ErrRPCInternal.Code, context+": "+err.Error())
+}
@@ -0,0 +1,108 @@
+package resolver_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/claimtrie/resolver"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	cfg := config.DefaultConfig
+	cfg.DataDir = t.TempDir()
+	cfg.ClaimIDIndex = true
+	ct, err := claimtrie.New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	srv := httptest.NewServer(resolver.NewServer(ct))
+	defer srv.Close()
+	c, err := rpcclient.New(&rpcclient.ConnConfig{
+		Host:         strings.TrimPrefix(srv.URL, "http://"),
+		User:         "user",
+		Pass:         "pass",
+		HTTPPostMode: true,
+		DisableTLS:   true,
+	}, nil)
+	r.NoError(err)
+	defer c.Shutdown()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	op1, op2 := wire.OutPoint{Hash: hash, Index: 1}, wire.OutPoint{Hash: hash, Index: 2}
+	id1 := node.NewClaimID(op1)
+	r.NoError(ct.AddClaim([]byte("test"), op1, id1, 10, []byte("value")))
+	r.NoError(ct.AddSupport([]byte("test"), nil, op2, 5, id1))
+	r.NoError(ct.AppendBlock())
+
+	_, err = c.ResolveName("missing")
+	r.Error(err)
+	r.Equal(btcjson.ErrRPCInvalidParameter, err.(*btcjson.RPCError).Code)
+
+	res, err := c.ResolveName("test")
+	r.NoError(err)
+	r.Equal(int32(1), res.Height)
+	r.Equal(ct.MerkleHash().String(), res.ClaimTrie)
+	r.NotNil(res.BestClaim)
+	r.Equal(id1.String(), res.BestClaim.ClaimID)
+	r.Equal(int64(15), res.BestClaim.EffectiveAmount)
+
+	claims, err := c.GetClaimsForName("test")
+	r.NoError(err)
+	r.Len(claims.Claims, 1)
+	r.Len(claims.Supports, 1)
+	r.Equal(op2.String(), claims.Supports[0].OutPoint)
+
+	claims, err = c.GetClaimsForName("missing")
+	r.NoError(err)
+	r.Empty(claims.Claims)
+
+	value, err := c.GetValueForName("test")
+	r.NoError(err)
+	r.Equal(id1.String(), value.ClaimID)
+	r.Equal("76616c7565", value.Value)
+
+	byID, err := c.GetClaimByID(id1.String())
+	r.NoError(err)
+	r.Len(byID, 1)
+	r.Equal("test", byID[0].Name)
+	r.Equal(op1.String(), byID[0].Claim.OutPoint)
+
+	// The IDs of another length, such as the ones which would overflow it, are rejected.
+	for _, invalid := range []string{id1.String() + id1.String(), id1.String()[:8], strings.Repeat("z", 40)} {
+		_, err = c.GetClaimByID(invalid)
+		r.Error(err)
+		r.Equal(btcjson.ErrRPCInvalidParameter, err.(*btcjson.RPCError).Code)
+	}
+
+	// The queries are answered as of the last block, before the spend is committed.
+	r.NoError(ct.SpendClaim([]byte("test"), op1, id1))
+	byID, err = c.GetClaimByID(id1.String())
+	r.NoError(err)
+	r.Len(byID, 1)
+
+	r.NoError(ct.AppendBlock())
+	byID, err = c.GetClaimByID(id1.String())
+	r.NoError(err)
+	r.Empty(byID)
+	_, err = c.GetValueForName("test")
+	r.Error(err)
+
+	_, err = c.GetBlockCount()
+	r.Error(err)
+	r.Equal(btcjson.ErrRPCMethodNotFound.Code, err.(*btcjson.RPCError).Code)
+}
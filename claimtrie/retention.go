@@ -0,0 +1,188 @@
+package claimtrie
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// pruner saves the nodes as their base snapshots, and drops the changes they cover.
+type pruner interface {
+	Prune(height int32) (int, error)
+}
+
+// expiredCompactor replaces the change histories of the names left empty with tombstones.
+type expiredCompactor interface {
+	CompactExpired(height int32) (int, error)
+}
+
+func checkRetention(cfg config.Config) error {
+
+	if cfg.ExpiredCompactionBlocks < 0 {
+		return fmt.Errorf("invalid blocks to compact the expired names after: %d", cfg.ExpiredCompactionBlocks)
+	}
+
+	switch cfg.ChangeRetention {
+	case config.RetainAll, "":
+		if cfg.ExpiredCompactionBlocks == 0 {
+			return nil
+		}
+	case config.RetainBlocks:
+		if cfg.ChangeRetentionBlocks <= 0 {
+			return fmt.Errorf("invalid blocks to retain the changes of: %d", cfg.ChangeRetentionBlocks)
+		}
+	case config.RetainNone:
+	default:
+		return fmt.Errorf("unknown retention: %q", cfg.ChangeRetention)
+	}
+
+	if cfg.NodeManager != config.NodeManagerSnapshot {
+		return fmt.Errorf("retention %q, or the compaction of the expired names, requires the %q node manager",
+			cfg.ChangeRetention, config.NodeManagerSnapshot)
+	}
+	if cfg.PruneInterval <= 0 {
+		return fmt.Errorf("invalid prune interval: %d", cfg.PruneInterval)
+	}
+
+	return nil
+}
+
+// retainedFrom returns the height, before which the changes are pruned at the height.
+func (ct *ClaimTrie) retainedFrom(height int32) int32 {
+	if ct.retention == config.RetainNone {
+		return height
+	}
+	if height > ct.retentionBlocks {
+		return height - ct.retentionBlocks
+	}
+	return 0
+}
+
+// pruneIfDue prunes the changes past the retention every pruneInterval blocks.
+// The changes before the normalization fork are kept until it's passed, as it
+// re-adds the claims at the heights they were accepted at.
+func (ct *ClaimTrie) pruneIfDue() error {
+
+	if ct.pruner == nil || ct.height%ct.pruneInterval != 0 || ct.height <= param.NormalizedNameForkHeight {
+		return nil
+	}
+	height := ct.retainedFrom(ct.height)
+	if height <= ct.prunedAt {
+		return nil
+	}
+
+	start := time.Now()
+	ct.history.Lock()
+	pruned, err := ct.pruner.Prune(height)
+	if err == nil {
+		ct.prunedAt = height
+	}
+	ct.history.Unlock()
+	if err != nil {
+		return fmt.Errorf("prune up to %d: %w", height, err)
+	}
+	log.Infof("Pruned the changes of %d names up to %d in %s", pruned, height, time.Since(start))
+
+	if ct.compaction != nil {
+		ct.compaction.add(pruned)
+	}
+
+	return nil
+}
+
+// compactExpiredIfDue replaces the change histories of the names, which have been empty for expiredBlocks
+// blocks, with tombstones every pruneInterval blocks, once the normalization fork is passed, as Prune does.
+func (ct *ClaimTrie) compactExpiredIfDue() error {
+
+	if ct.expiredCompactor == nil || ct.height%ct.pruneInterval != 0 || ct.height <= param.NormalizedNameForkHeight {
+		return nil
+	}
+	height := ct.height - ct.expiredBlocks
+	if height <= ct.compactedAt {
+		return nil
+	}
+
+	start := time.Now()
+	ct.history.Lock()
+	compacted, err := ct.expiredCompactor.CompactExpired(height)
+	if err == nil {
+		ct.compactedAt = height
+	}
+	ct.history.Unlock()
+	if err != nil {
+		return fmt.Errorf("compact expired names up to %d: %w", height, err)
+	}
+	log.Infof("Compacted the changes of %d expired names up to %d in %s", compacted, height, time.Since(start))
+
+	if ct.compaction != nil {
+		ct.compaction.add(compacted)
+	}
+
+	return nil
+}
+
+// PruneTrie deletes the nodes of the trie repo, which aren't reachable from the roots of the last TrieRetentionBlocks
+// blocks, as the block repo references them, nor from the current one, and compacts the repo. The nodes of the roots
+// superseded, such as by the resets, are deleted too. The ClaimTrie can't be reset, nor proven at, before the roots
+// retained after. The changes to the ClaimTrie, and the readers of its history, wait for it meanwhile.
+func (ct *ClaimTrie) PruneTrie() (*merkletrie.PruneResult, error) {
+
+	if ct.trieRetentionBlocks <= 0 {
+		return nil, fmt.Errorf("the trie retention isn't set")
+	}
+
+	defer ct.holdChanges()()
+
+	if ct.batching && ct.txn != nil {
+		err := ct.commitBatch()
+		if err != nil {
+			return nil, err
+		}
+		ct.txn = ct.txns.Begin()
+	}
+
+	from := ct.height - ct.trieRetentionBlocks + 1
+	if from < 1 {
+		from = 1
+	}
+	roots := make([]*chainhash.Hash, 0, ct.height-from+2)
+	for h := from; h <= ct.height; h++ {
+		root, err := ct.blockRepo.Get(h)
+		if err != nil {
+			return nil, fmt.Errorf("root at %d: %w", h, err)
+		}
+		roots = append(roots, root)
+	}
+	if ct.root != nil {
+		roots = append(roots, ct.root)
+	}
+
+	start := time.Now()
+	ct.history.Lock()
+	res, err := ct.merkleTrie.Prune(roots)
+	if res != nil && from > ct.trieRetainedFrom { // some may be deleted, even if it failed
+		atomic.StoreInt32(&ct.trieRetainedFrom, from)
+	}
+	ct.history.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("prune trie before %d: %w", from, err)
+	}
+	log.Infof("Pruned %d trie nodes, keeping the %d nodes of %d roots from %d, in %s",
+		res.Deleted, res.Kept, res.Roots, from, time.Since(start))
+
+	if ct.trieCompacter != nil && res.Deleted > 0 {
+		start = time.Now()
+		err = ct.trieCompacter.Compact()
+		if err != nil {
+			return res, fmt.Errorf("compact trie repo: %w", err)
+		}
+		log.Infof("Compacted the trie repo in %s", time.Since(start))
+	}
+
+	return res, nil
+}
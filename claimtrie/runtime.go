@@ -0,0 +1,165 @@
+package claimtrie
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// Stats is a snapshot of the ClaimTrie, taken after each block.
+type Stats struct {
+	Height         int32
+	Root           string
+	NodeCacheBytes int64
+	TrieCacheBytes int64
+	Conflicts      int
+
+	// The leaf hashes read through from the nodes, while the value cache is enabled.
+	ValueCacheMisses int64
+
+	// The trie nodes prefetched, and the ones resolved, which were, or weren't, among them, while it's enabled.
+	PrefetchedNodes int64
+	PrefetchHits    int64
+	PrefetchMisses  int64
+
+	// The size of the trie node cache, and the nodes resolved, which were, or weren't, in it, and the ones
+	// evicted from it, while it's enabled.
+	TrieNodeCacheBytes     int64
+	TrieNodeCacheHits      int64
+	TrieNodeCacheMisses    int64
+	TrieNodeCacheEvictions int64
+
+	// The nodes, which didn't match their rebuilt ones, while the consistency check was enabled.
+	Inconsistencies  int64
+	ConsistencyCheck bool
+
+	// The roots, which diverged from the upstream checkpoints, and the height of the last one verified.
+	UpstreamDivergences int64
+	UpstreamVerified    int32
+
+	// The blocks of changes, and the resets, dropped by the change sinks, of which the queues were full.
+	SinkDrops int64
+
+	// The stages of processing the blocks, which exceeded their budgets, and how many times.
+	Alerts map[string]int64
+}
+
+type flusher interface {
+	Flush() error
+}
+
+// SetConsistencyCheck enables, or disables, rebuilding the nodes updated by each block from
+// the node repo, and comparing them with the cached ones. Mismatches are logged.
+// It may be called while blocks are being appended.
+func (ct *ClaimTrie) SetConsistencyCheck(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&ct.consistencyCheck, v)
+}
+
+// Stats returns the snapshot taken after the last block.
+// It may be called while blocks are being appended.
+func (ct *ClaimTrie) Stats() Stats {
+	ct.statsMu.Lock()
+	defer ct.statsMu.Unlock()
+	stats := ct.stats
+	stats.Alerts = make(map[string]int64, len(ct.alerts))
+	for stage, n := range ct.alerts {
+		stats.Alerts[stage] = n
+	}
+	stats.Inconsistencies = atomic.LoadInt64(&ct.inconsistencies)
+	stats.ConsistencyCheck = atomic.LoadInt32(&ct.consistencyCheck) == 1
+	stats.UpstreamDivergences = atomic.LoadInt64(&ct.upstreamDivergences)
+	stats.SinkDrops = atomic.LoadInt64(&ct.sinks.dropped)
+	if ct.upstream != nil {
+		stats.UpstreamVerified = ct.upstream.Latest()
+	}
+	return stats
+}
+
+// Flush writes the buffered writes of the trie and node repos to their disks.
+// It may be called while blocks are being appended.
+func (ct *ClaimTrie) Flush() error {
+	for name, repo := range ct.flushers {
+		err := repo.Flush()
+		if err != nil {
+			return fmt.Errorf("flush %s repo: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (ct *ClaimTrie) updateStats() {
+
+	stats := Stats{
+		Height:         ct.height,
+		NodeCacheBytes: ct.nodeManager.CacheSize(),
+		TrieCacheBytes: ct.merkleTrie.CacheSize(),
+		Conflicts:      len(ct.conflicts.Conflicts()),
+	}
+	if ct.root != nil {
+		stats.Root = ct.root.String()
+	}
+	if ct.values != nil {
+		stats.ValueCacheMisses = ct.values.Misses()
+	}
+	prefetch := ct.merkleTrie.PrefetchStats()
+	stats.PrefetchedNodes, stats.PrefetchHits, stats.PrefetchMisses = prefetch.Prefetched, prefetch.Hits, prefetch.Misses
+	nodes := ct.merkleTrie.NodeCacheStats()
+	stats.TrieNodeCacheBytes, stats.TrieNodeCacheHits = nodes.Bytes, nodes.Hits
+	stats.TrieNodeCacheMisses, stats.TrieNodeCacheEvictions = nodes.Misses, nodes.Evictions
+
+	ct.statsMu.Lock()
+	ct.stats = stats
+	ct.statsMu.Unlock()
+}
+
+// checkConsistency compares the cached nodes of the names with the ones rebuilt from the node repo.
+func (ct *ClaimTrie) checkConsistency(names [][]byte) error {
+
+	for _, name := range names {
+		cached, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return fmt.Errorf("node: %w", err)
+		}
+		rebuilt, err := ct.nodeManager.NodeAt(ct.height, name)
+		if err != nil {
+			return fmt.Errorf("node at: %w", err)
+		}
+		if sameNode(cached, rebuilt) {
+			continue
+		}
+		atomic.AddInt64(&ct.inconsistencies, 1)
+		log.Errorf("Inconsistent node %s at %d: cached hash %s, rebuilt hash %s",
+			name, ct.height, nodeHash(cached), nodeHash(rebuilt))
+	}
+
+	return nil
+}
+
+func sameNode(a, b *node.Node) bool {
+	ha, hb := nodeHash(a), nodeHash(b)
+	if ha == nil || hb == nil {
+		return ha == hb && claims(a) == claims(b)
+	}
+	return *ha == *hb && claims(a) == claims(b)
+}
+
+func claims(n *node.Node) int {
+	if n == nil {
+		return 0
+	}
+	return len(n.Claims)
+}
+
+// nodeHash returns the value hash of the node, as the node manager calculates it.
+func nodeHash(n *node.Node) *chainhash.Hash {
+	if n == nil || n.BestClaim == nil || n.BestClaim.Status != node.Activated {
+		return nil
+	}
+	return node.CalculateNodeHash(n.BestClaim.OutPoint, n.TakenOverAt)
+}
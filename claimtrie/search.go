@@ -0,0 +1,231 @@
+package claimtrie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// ErrNamesNotSearchable is returned by SearchNames, if the names aren't indexed by their tokens.
+var ErrNamesNotSearchable = errors.New("names aren't indexed for search")
+
+// SearchOptions are the options of SearchNames.
+type SearchOptions struct {
+	// Match the tokens containing the query tokens anywhere, instead of only the ones starting with them.
+	Substring bool
+
+	// Return up to Limit names, in order, after the name After, if it's set.
+	After []byte
+	Limit int
+}
+
+// nameSearch indexes the names with best claims by their tokens: the runs of the letters and digits of
+// their normalized forms, which are split on the punctuation, and the rest.
+type nameSearch struct {
+	mu     sync.RWMutex
+	tokens map[string]map[string]bool // The names having each token.
+	names  map[string][]string        // The tokens of each name.
+
+	// The tokens in order, for the prefix searches, which are sorted again once dirty.
+	sorted []string
+	dirty  bool
+}
+
+func newNameSearch() *nameSearch {
+	return &nameSearch{
+		tokens: map[string]map[string]bool{},
+		names:  map[string][]string{},
+	}
+}
+
+// tokenize returns the distinct tokens of the name, or of the query.
+func tokenize(name []byte) []string {
+
+	fields := bytes.FieldsFunc(node.Normalize(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+	})
+
+	tokens := make([]string, 0, len(fields))
+	seen := map[string]bool{}
+	for _, f := range fields {
+		if !seen[string(f)] {
+			seen[string(f)] = true
+			tokens = append(tokens, string(f))
+		}
+	}
+
+	return tokens
+}
+
+// SearchNames returns up to opts.Limit names with best claims, in order, which have tokens starting with,
+// or containing, each of the tokens of the query, as of the last block appended.
+// The names, and the query, are normalized first, so the search doesn't tell the cases apart.
+func (ct *ClaimTrie) SearchNames(query string, opts SearchOptions) ([][]byte, error) {
+
+	if ct.search == nil {
+		return nil, ErrNamesNotSearchable
+	}
+	if opts.Limit <= 0 {
+		return nil, fmt.Errorf("invalid limit: %d", opts.Limit)
+	}
+
+	terms := tokenize([]byte(query))
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	s := ct.search
+	s.mu.Lock() // for sorting the tokens, if they're dirty
+	defer s.mu.Unlock()
+	if s.dirty {
+		s.sorted = s.sorted[:0]
+		for token := range s.tokens {
+			s.sorted = append(s.sorted, token)
+		}
+		sort.Strings(s.sorted)
+		s.dirty = false
+	}
+
+	var matched map[string]bool
+	for _, term := range terms {
+		found := map[string]bool{}
+		for _, token := range s.matching(term, opts.Substring) {
+			for name := range s.tokens[token] {
+				if matched == nil || matched[name] {
+					found[name] = true
+				}
+			}
+		}
+		matched = found
+		if len(matched) == 0 {
+			return nil, nil
+		}
+	}
+
+	names := make([]string, 0, len(matched))
+	for name := range matched {
+		if opts.After == nil || name > string(opts.After) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > opts.Limit {
+		names = names[:opts.Limit]
+	}
+
+	result := make([][]byte, 0, len(names))
+	for _, name := range names {
+		result = append(result, []byte(name))
+	}
+
+	return result, nil
+}
+
+// matching returns the tokens starting with, or containing, the term. It must be called with mu held, and sorted.
+func (s *nameSearch) matching(term string, substring bool) []string {
+
+	if substring {
+		var tokens []string
+		for _, token := range s.sorted {
+			if strings.Contains(token, term) {
+				tokens = append(tokens, token)
+			}
+		}
+		return tokens
+	}
+
+	i := sort.SearchStrings(s.sorted, term)
+	j := i
+	for j < len(s.sorted) && strings.HasPrefix(s.sorted[j], term) {
+		j++
+	}
+	return s.sorted[i:j]
+}
+
+// rebuildNameSearch indexes all the names from scratch.
+func (ct *ClaimTrie) rebuildNameSearch() error {
+
+	ct.search.mu.Lock()
+	ct.search.tokens = map[string]map[string]bool{}
+	ct.search.names = map[string][]string{}
+	ct.search.dirty = true
+	ct.search.mu.Unlock()
+
+	var names [][]byte
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		names = append(names, append([]byte(nil), name...))
+		return true
+	})
+
+	return ct.updateNameSearch(names)
+}
+
+// updateNameSearch indexes the names with best claims, and drops the others.
+func (ct *ClaimTrie) updateNameSearch(names [][]byte) error {
+
+	s := ct.search
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range names {
+		name = node.NormalizeIfNecessary(name, ct.height)
+
+		n, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return fmt.Errorf("node: %w", err)
+		}
+
+		indexed := n != nil && n.BestClaim != nil && n.BestClaim.Status == node.Activated
+		if _, ok := s.names[string(name)]; ok == indexed {
+			continue // the tokens of a name never change
+		}
+		if indexed {
+			s.add(string(name))
+		} else {
+			s.remove(string(name))
+		}
+	}
+
+	return nil
+}
+
+// rewindNameSearch re-indexes the names after resetting from a later height.
+func (ct *ClaimTrie) rewindNameSearch(names [][]byte, from int32) error {
+
+	if ct.height < param.NormalizedNameForkHeight && from >= param.NormalizedNameForkHeight {
+		return ct.rebuildNameSearch() // the names were normalized in between
+	}
+	return ct.updateNameSearch(names)
+}
+
+func (s *nameSearch) add(name string) {
+
+	tokens := tokenize([]byte(name))
+	for _, token := range tokens {
+		if s.tokens[token] == nil {
+			s.tokens[token] = map[string]bool{}
+			s.dirty = true
+		}
+		s.tokens[token][name] = true
+	}
+	s.names[name] = tokens
+}
+
+func (s *nameSearch) remove(name string) {
+
+	for _, token := range s.names[name] {
+		delete(s.tokens[token], name)
+		if len(s.tokens[token]) == 0 {
+			delete(s.tokens, token)
+			s.dirty = true
+		}
+	}
+	delete(s.names, name)
+}
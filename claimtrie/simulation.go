@@ -0,0 +1,128 @@
+package claimtrie
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// simulatedOutPoint is the outpoint of the claims, and supports, simulated by SimulateBid.
+var simulatedOutPoint = wire.OutPoint{Hash: chainhash.HashH([]byte("claimtrie simulated bid"))}
+
+// BidSimulation is the outcome of a claim, or a support, of a name, which would be accepted by the next block,
+// unless other transactions affect the name by then.
+type BidSimulation struct {
+	Name       []byte
+	Amount     int64
+	ClaimID    node.ClaimID // of the claim simulated, or the one supported.
+	Support    bool
+	AcceptAt   int32
+	ActivateAt int32 // The height the bid activates at, earlier than its delay, if a takeover activates it.
+
+	// Whether the claim of the bid is the best one, once the bid is activated, and its effective amount then.
+	Wins            bool
+	EffectiveAmount int64
+
+	// Copies of the best claim of the name, as of the last block, and of the one, once the bid is activated,
+	// and the effective amount of it, which a claim must exceed, once activated, to take over the name.
+	Previous   *node.Claim
+	Best       *node.Claim
+	BestAmount int64
+	CounterBid int64 // the effective amount a claim must reach, to take the name from the Best: BestAmount + 1.
+}
+
+// SimulateBid returns the outcome of a claim of the amount, or a support of it for the claim of the ID, of the name,
+// which would be accepted by the next block, along with the changes pending for it. Nothing is applied.
+func (ct *ClaimTrie) SimulateBid(name []byte, amount int64, supported *node.ClaimID) (*BidSimulation, error) {
+
+	defer ct.holdChanges()()
+
+	height := ct.height + 1
+	normalized := node.NormalizeIfNecessary(name, height)
+
+	previous, err := ct.nodeManager.Node(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("node: %w", err)
+	}
+
+	var changes []change.Change
+	for _, chg := range ct.changes {
+		if string(node.NormalizeIfNecessary(chg.Name, height)) == string(normalized) {
+			changes = append(changes, chg)
+		}
+	}
+	sim := &BidSimulation{Name: name, Amount: amount, AcceptAt: height}
+	if previous != nil {
+		sim.Previous = copyClaim(previous.BestClaim)
+	}
+	var bid change.Change
+	if supported != nil {
+		sim.ClaimID, sim.Support = *supported, true
+		bid = addSupportChange(name, nil, simulatedOutPoint, amount, *supported)
+	} else {
+		sim.ClaimID = node.NewClaimID(simulatedOutPoint)
+		bid = addClaimChange(name, simulatedOutPoint, sim.ClaimID, amount, nil)
+	}
+	bid.Height, bid.Seq = height, int32(len(ct.changes))+1
+	changes = append(changes, bid)
+
+	n, err := ct.nodeManager.PreviewNode(normalized, changes, height)
+	if err != nil {
+		return nil, fmt.Errorf("preview node %q: %w", normalized, err)
+	}
+	claim := findClaim(n.Claims, sim.ClaimID)
+	if claim == nil {
+		return nil, fmt.Errorf("claim %s of %q: %w", sim.ClaimID, name, ErrNameNotFound)
+	}
+	bids := n.Claims
+	if sim.Support {
+		bids = n.Supports
+	}
+	b := findOutPoint(bids, simulatedOutPoint)
+
+	// The node is advanced through its updates, until the bid is activated, at its delay at the latest.
+	at := height
+	for b.Status != node.Activated {
+		next := n.NextUpdate()
+		if next <= at {
+			return nil, fmt.Errorf("the bid isn't activated after %d", at)
+		}
+		n.AdjustTo(next, next, normalized)
+		at = next
+	}
+	sim.ActivateAt = at
+
+	if claim = findClaim(n.Claims, sim.ClaimID); claim != nil {
+		sim.EffectiveAmount = n.EffectiveAmount(claim)
+	}
+	sim.Best = copyClaim(n.BestClaim)
+	if n.BestClaim != nil {
+		sim.Wins = n.BestClaim.ClaimID == sim.ClaimID
+		sim.BestAmount = n.EffectiveAmount(n.BestClaim)
+	}
+	sim.CounterBid = sim.BestAmount + 1
+
+	return sim, nil
+}
+
+// findClaim returns the claim of the ID, which isn't spent, if any.
+func findClaim(claims node.ClaimList, id node.ClaimID) *node.Claim {
+	for _, c := range claims {
+		if c.ClaimID == id && c.Status != node.Deactivated {
+			return c
+		}
+	}
+	return nil
+}
+
+func findOutPoint(claims node.ClaimList, op wire.OutPoint) *node.Claim {
+	for _, c := range claims {
+		if c.OutPoint == op {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package claimtrie
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// AppliedChange is a change of a block appended, along with the status of its claim, or support, as of the block.
+type AppliedChange struct {
+	change.Change
+	Status node.Status // Deactivated for the spends, and the claims, or supports, which aren't in the node after the block.
+}
+
+// ChangeSink receives the changes applied by the blocks, such as for bridging them to an external stream processor.
+// Its calls are made in order, from a goroutine of the sink, so they may block, such as until the changes are
+// acknowledged, which holds up the blocks, or drops them, once its queue is full, as per its SinkOptions.
+type ChangeSink interface {
+	// HandleChanges receives the changes of the block at the height, in the order they were applied.
+	HandleChanges(height int32, changes []AppliedChange)
+	// HandleReset receives the height the ClaimTrie is reset to, which undoes the changes of the blocks after it.
+	HandleReset(height int32)
+}
+
+type SinkOptions struct {
+	QueueSize int  // The blocks, and resets, queued for the sink.
+	Block     bool // AppendBlock waits for the sink, once its queue is full, instead of dropping the block.
+}
+
+var DefaultSinkOptions = SinkOptions{
+	QueueSize: 64,
+	Block:     true,
+}
+
+type sinkItem struct {
+	height  int32
+	changes []AppliedChange
+	reset   bool
+}
+
+type changeSink struct {
+	id    int
+	sink  ChangeSink
+	opts  SinkOptions
+	queue chan sinkItem
+	done  chan struct{}
+}
+
+func (s *changeSink) run() {
+	defer close(s.done)
+	for item := range s.queue {
+		if item.reset {
+			s.sink.HandleReset(item.height)
+		} else {
+			s.sink.HandleChanges(item.height, item.changes)
+		}
+	}
+}
+
+// changeSinks are the sinks registered, to which the blocks are queued.
+type changeSinks struct {
+	mu      sync.RWMutex
+	next    int
+	sinks   []*changeSink
+	dropped int64 // The blocks, and resets, dropped by the sinks with full queues; accessed atomically.
+}
+
+// AddChangeSink registers the sink for the changes of the blocks appended from now on, and returns a function to
+// remove it, which waits for the sink to handle the ones queued. The sinks are removed by Close as well.
+func (ct *ClaimTrie) AddChangeSink(sink ChangeSink, opts SinkOptions) func() {
+
+	s := &changeSink{sink: sink, opts: opts, queue: make(chan sinkItem, opts.QueueSize), done: make(chan struct{})}
+	go s.run()
+
+	ct.sinks.mu.Lock()
+	s.id = ct.sinks.next
+	ct.sinks.next++
+	ct.sinks.sinks = append(ct.sinks.sinks, s)
+	ct.sinks.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { ct.sinks.remove(s.id) })
+	}
+}
+
+func (cs *changeSinks) remove(id int) {
+
+	cs.mu.Lock()
+	sinks := make([]*changeSink, 0, len(cs.sinks))
+	var removed *changeSink
+	for _, s := range cs.sinks {
+		if s.id == id {
+			removed = s
+		} else {
+			sinks = append(sinks, s)
+		}
+	}
+	cs.sinks = sinks
+	cs.mu.Unlock()
+
+	if removed != nil {
+		close(removed.queue)
+		<-removed.done
+	}
+}
+
+// removeAll removes the sinks, once they've handled the blocks queued.
+func (cs *changeSinks) removeAll() {
+
+	cs.mu.RLock()
+	var ids []int
+	for _, s := range cs.sinks {
+		ids = append(ids, s.id)
+	}
+	cs.mu.RUnlock()
+
+	for _, id := range ids {
+		cs.remove(id)
+	}
+}
+
+func (cs *changeSinks) active() bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return len(cs.sinks) > 0
+}
+
+// publish queues the item for the sinks, dropping it for the ones, which are full, and don't block.
+func (cs *changeSinks) publish(item sinkItem) {
+
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	for _, s := range cs.sinks {
+		if s.opts.Block {
+			s.queue <- item
+			continue
+		}
+		select {
+		case s.queue <- item:
+		default:
+			atomic.AddInt64(&cs.dropped, 1)
+			log.Warnf("change sink queue is full, dropping the changes of block %d", item.height)
+		}
+	}
+}
+
+// publishChanges queues the changes of the block for the sinks, with the statuses of their claims, and supports.
+func (ct *ClaimTrie) publishChanges(changes []change.Change) error {
+
+	nodes := map[string]*node.Node{}
+	applied := make([]AppliedChange, 0, len(changes))
+	for _, chg := range changes {
+		name := node.NormalizeIfNecessary(chg.Name, ct.height)
+		n, ok := nodes[string(name)]
+		if !ok {
+			var err error
+			n, err = ct.nodeManager.Node(name)
+			if err != nil {
+				return fmt.Errorf("node: %w", err)
+			}
+			nodes[string(name)] = n
+		}
+		applied = append(applied, AppliedChange{Change: chg, Status: appliedStatus(n, chg)})
+	}
+	ct.sinks.publish(sinkItem{height: ct.height, changes: applied})
+
+	return nil
+}
+
+// appliedStatus returns the status of the claim, or the support, created by the change in the node.
+func appliedStatus(n *node.Node, chg change.Change) node.Status {
+
+	if n == nil {
+		return node.Deactivated
+	}
+	list := n.Claims
+	switch chg.Type {
+	case change.AddSupport:
+		list = n.Supports
+	case change.SpendClaim, change.SpendSupport:
+		return node.Deactivated
+	}
+	op := chg.OutPoint.Wire()
+	for _, c := range list {
+		if c.OutPoint == op {
+			return c.Status
+		}
+	}
+
+	return node.Deactivated
+}
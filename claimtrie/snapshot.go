@@ -0,0 +1,149 @@
+package claimtrie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// ErrStaleSnapshot is returned by the queries of a Snapshot, of which the block was reset since.
+var ErrStaleSnapshot = errors.New("snapshot is stale")
+
+// Snapshot is the state of the ClaimTrie as of the last block committed, when it was taken.
+// Its queries are safe for concurrent access, including while a block is appended,
+// of which they never see the changes.
+type Snapshot struct {
+	ct     *ClaimTrie
+	height int32
+	root   chainhash.Hash
+}
+
+// Snapshot returns the state as of the last block committed.
+func (ct *ClaimTrie) Snapshot() *Snapshot {
+	return ct.committed.Load().(*Snapshot)
+}
+
+// commit publishes the current height and root to the queries, once the block is applied.
+func (ct *ClaimTrie) commit() {
+	root := merkletrie.EmptyTrieHash // no block is appended yet
+	if ct.root != nil {
+		root = ct.root
+	}
+	ct.committed.Store(&Snapshot{ct: ct, height: ct.height, root: *root})
+}
+
+// Height returns the height of the block.
+func (s *Snapshot) Height() int32 {
+	return s.height
+}
+
+// Root returns the root hash of the trie at the block.
+func (s *Snapshot) Root() chainhash.Hash {
+	return s.root
+}
+
+// Resolve resolves the name as of the block, as ClaimTrie.ResolveAt does.
+func (s *Snapshot) Resolve(name []byte) (*Resolution, error) {
+
+	unlock, err := s.ct.readHistory(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	err = s.check()
+	if err != nil {
+		return nil, err
+	}
+
+	normName := node.NormalizeIfNecessary(name, s.height)
+	n, err := s.ct.nodeManager.NodeAt(s.height, normName)
+	if err != nil {
+		return nil, fmt.Errorf("node at %d: %w", s.height, err)
+	}
+	if n == nil || len(n.Claims) == 0 && len(n.Supports) == 0 {
+		return nil, fmt.Errorf("%w: %q at %d", ErrNameNotFound, normName, s.height)
+	}
+
+	return &Resolution{Name: normName, Height: s.height, Root: s.root, Node: n}, nil
+}
+
+// ListClaims returns the claims of the name as of the block.
+func (s *Snapshot) ListClaims(name []byte) (node.ClaimList, error) {
+
+	res, err := s.Resolve(name)
+	if errors.Is(err, ErrNameNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Node.Claims, nil
+}
+
+// Prove returns the proof of the best claim of the name against the root.
+// It fails with ErrNameNotFound, or ErrNoBestClaim, without one.
+// Proofs are only supported before the all-claims fork.
+func (s *Snapshot) Prove(name []byte) (*merkletrie.Proof, error) {
+
+	if s.height >= param.AllClaimsInMerkleForkHeight {
+		return nil, fmt.Errorf("name proofs are unsupported after the all-claims fork")
+	}
+
+	res, err := s.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkBestClaim(res.Name, res.Node); err != nil {
+		return nil, err
+	}
+
+	// The view shares the repo, in which the nodes of the root were stored before its commit.
+	return s.ct.merkleTrie.At(&s.root).Prove(res.Name, res.Node.BestClaim.OutPoint, res.Node.TakenOverAt)
+}
+
+// ProveAbsence returns the proof of the name not having a best claim against the root,
+// as ClaimTrie.AbsenceProof does.
+func (s *Snapshot) ProveAbsence(name []byte) (*merkletrie.Proof, error) {
+
+	if s.height >= param.AllClaimsInMerkleForkHeight {
+		return nil, fmt.Errorf("name proofs are unsupported after the all-claims fork")
+	}
+
+	res, err := s.Resolve(name)
+	if err != nil && !errors.Is(err, ErrNameNotFound) {
+		return nil, err
+	}
+	normName := node.NormalizeIfNecessary(name, s.height)
+	if err == nil && res.Node.BestClaim != nil {
+		return nil, fmt.Errorf("best claim of %q: %s", normName, res.Node.BestClaim.ClaimID)
+	}
+
+	return s.ct.merkleTrie.At(&s.root).ProveAbsence(normName)
+}
+
+// check returns ErrStaleSnapshot, if the block was reset since. The history must be read locked.
+func (s *Snapshot) check() error {
+
+	if s.height > s.ct.Snapshot().height {
+		return fmt.Errorf("%w: at %d", ErrStaleSnapshot, s.height)
+	}
+	if s.height == 0 {
+		return nil
+	}
+	root, err := s.ct.blockRepo.Get(s.height)
+	if err != nil {
+		return fmt.Errorf("root at %d: %w", s.height, err)
+	}
+	if *root != s.root {
+		return fmt.Errorf("%w: at %d", ErrStaleSnapshot, s.height)
+	}
+
+	return nil
+}
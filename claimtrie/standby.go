@@ -0,0 +1,151 @@
+package claimtrie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/standby"
+)
+
+var (
+	// ErrStandbyDiverged is returned for a block of the primary, of which the parent isn't the root of the standby,
+	// such as after a reorganization deeper than the lag of the standby, which is restored from a backup then.
+	ErrStandbyDiverged = errors.New("standby diverges from the primary")
+
+	// ErrStandbyRootMismatch is returned for a block of the primary, which the standby doesn't hash to the root of.
+	ErrStandbyRootMismatch = errors.New("standby root doesn't match the primary's")
+
+	// ErrTipMismatch is returned by Promote, when the standby doesn't reach the tip of the primary, and its root.
+	ErrTipMismatch = errors.New("standby doesn't match the tip of the primary")
+)
+
+// StandbyPublisher returns the publisher of the blocks to the standbys, if enabled.
+func (ct *ClaimTrie) StandbyPublisher() *standby.Publisher {
+	return ct.standbys
+}
+
+// publishStandbyBlock publishes the changes of the block, which are reused by the next one, and its root.
+func (ct *ClaimTrie) publishStandbyBlock(parent *chainhash.Hash, changes []change.Change) {
+
+	if parent == nil {
+		parent = merkletrie.EmptyTrieHash
+	}
+	b := &standby.Block{Height: ct.height, Parent: *parent, Root: *ct.root,
+		Changes: append([]change.Change(nil), changes...)}
+	ct.standbys.Publish(b)
+}
+
+// StandbySource is the primary of a Standby, such as a standby.Client of its publisher.
+type StandbySource interface {
+	Block(height int32) (*standby.Block, error)
+	Tip() (int32, chainhash.Hash, error)
+}
+
+// Standby keeps a ClaimTrie the lag blocks behind the tip of its primary, by applying the changes of the blocks
+// of it, verified against their roots, so the blocks the primary reorganizes away within the lag aren't applied.
+// It only moves forward, and is promoted to serve the queries, once the primary fails, by Promote.
+type Standby struct {
+	ct     *ClaimTrie
+	source StandbySource
+	lag    int32
+}
+
+func NewStandby(ct *ClaimTrie, source StandbySource, lag int32) *Standby {
+	if lag < 0 {
+		lag = 0
+	}
+	return &Standby{ct: ct, source: source, lag: lag}
+}
+
+// Sync applies the blocks of the primary, up to the lag blocks behind its tip, and returns how many were applied.
+func (s *Standby) Sync() (int, error) {
+
+	tip, _, err := s.source.Tip()
+	if errors.Is(err, standby.ErrNotPublished) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return s.applyTo(tip - s.lag)
+}
+
+// Promote applies the blocks of the primary up to its tip, and verifies the standby is at the height, and the root,
+// of it, before it takes over the queries. The primary must still answer for its tip, which isn't known otherwise.
+func (s *Standby) Promote() (int32, chainhash.Hash, error) {
+
+	tip, root, err := s.source.Tip()
+	if err != nil {
+		return 0, root, fmt.Errorf("tip of the primary: %w", err)
+	}
+	_, err = s.applyTo(tip)
+	if err != nil {
+		return 0, root, err
+	}
+
+	height, h := s.ct.Height(), s.ct.MerkleHash()
+	if height != tip || *h != root {
+		return 0, root, fmt.Errorf("%w: at %d, root %s, instead of %d, root %s", ErrTipMismatch, height, h, tip, root)
+	}
+
+	return tip, root, nil
+}
+
+func (s *Standby) applyTo(height int32) (int, error) {
+
+	applied := 0
+	for s.ct.Height() < height {
+		b, err := s.source.Block(s.ct.Height() + 1)
+		if err != nil {
+			return applied, err
+		}
+		err = s.apply(b)
+		if err != nil {
+			return applied, err
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+// apply appends the block, which is reset, if it doesn't hash to its root.
+func (s *Standby) apply(b *standby.Block) error {
+
+	height := s.ct.Height()
+	if b.Height != height+1 {
+		return fmt.Errorf("block %d out of order, after %d", b.Height, height)
+	}
+	parent := merkletrie.EmptyTrieHash
+	if height > 0 {
+		parent = s.ct.MerkleHash()
+	}
+	if b.Parent != *parent {
+		return fmt.Errorf("%w at %d: parent %s, root %s", ErrStandbyDiverged, b.Height, b.Parent, parent)
+	}
+
+	for _, chg := range b.Changes {
+		err := s.ct.forwardNodeChange(chg)
+		if err != nil {
+			return fmt.Errorf("apply block %d: %w", b.Height, err)
+		}
+	}
+	err := s.ct.AppendBlock()
+	if err != nil {
+		return fmt.Errorf("append block %d: %w", b.Height, err)
+	}
+
+	if root := s.ct.MerkleHash(); *root != b.Root {
+		err = s.ct.ResetHeight(height)
+		if err != nil {
+			return fmt.Errorf("reset the mismatched block %d: %w", b.Height, err)
+		}
+		return fmt.Errorf("%w at %d: standby %s, primary %s", ErrStandbyRootMismatch, b.Height, root, b.Root)
+	}
+
+	return nil
+}
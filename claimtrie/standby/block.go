@@ -0,0 +1,46 @@
+// Package standby streams the changes of the blocks of a ClaimTrie, along with their roots, to the standby
+// instances, which apply them a number of blocks behind, so that one of them can take over the queries,
+// once the primary fails.
+package standby
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/change"
+)
+
+// Block is the changes of the block at the height, in the order they were applied, and its root.
+type Block struct {
+	Height  int32
+	Parent  chainhash.Hash // The root of the previous block.
+	Root    chainhash.Hash
+	Changes []change.Change
+}
+
+// The encoding of a Block: parent(32B) root(32B), and the Block message of change.proto, with the height.
+func (b *Block) encode() []byte {
+
+	buf := make([]byte, 0, 2*chainhash.HashSize)
+	buf = append(buf, b.Parent[:]...)
+	buf = append(buf, b.Root[:]...)
+
+	return append(buf, change.MarshalBlock(b.Height, b.Changes)...)
+}
+
+func decodeBlock(buf []byte) (*Block, error) {
+
+	if len(buf) < 2*chainhash.HashSize {
+		return nil, fmt.Errorf("truncated block")
+	}
+	b := &Block{}
+	copy(b.Parent[:], buf)
+	copy(b.Root[:], buf[chainhash.HashSize:])
+	height, changes, err := change.UnmarshalBlock(buf[2*chainhash.HashSize:])
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal block: %w", err)
+	}
+	b.Height, b.Changes = height, changes
+
+	return b, nil
+}
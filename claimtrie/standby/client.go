@@ -0,0 +1,105 @@
+package standby
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/frame"
+)
+
+// Client fetches the blocks from a publisher over one connection.
+type Client struct {
+	addr    string
+	timeout time.Duration
+	conn    net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+}
+
+// NewClient returns a Client of the publisher at the address, which is dialed, and answers
+// each request, within the timeout, if it's set.
+func NewClient(addr string, timeout time.Duration) *Client {
+	return &Client{addr: addr, timeout: timeout}
+}
+
+// Block fetches the block at the height.
+func (c *Client) Block(height int32) (*Block, error) {
+
+	payload, err := c.request(appendUint32([]byte{requestBlock}, uint32(height)))
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %w", height, err)
+	}
+
+	return decodeBlock(payload)
+}
+
+// Tip fetches the height, and the root, of the last block of the publisher.
+func (c *Client) Tip() (int32, chainhash.Hash, error) {
+
+	var root chainhash.Hash
+	payload, err := c.request([]byte{requestTip})
+	if err != nil {
+		return 0, root, fmt.Errorf("tip: %w", err)
+	}
+	if len(payload) != 4+chainhash.HashSize {
+		return 0, root, fmt.Errorf("tip: invalid payload of %d bytes", len(payload))
+	}
+	copy(root[:], payload[4:])
+
+	return int32(binary.BigEndian.Uint32(payload)), root, nil
+}
+
+// request sends the request, and returns the payload of its response. The connection is dropped
+// on failures, and dialed again by the next request.
+func (c *Client) request(req []byte) ([]byte, error) {
+
+	if c.conn == nil {
+		conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+		if err != nil {
+			return nil, fmt.Errorf("dial publisher %s: %w", c.addr, err)
+		}
+		c.conn, c.r, c.w = conn, bufio.NewReader(conn), bufio.NewWriter(conn)
+	}
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout)) // nolint : errchk
+	}
+
+	_, err := c.w.Write(req)
+	if err == nil {
+		err = c.w.Flush()
+	}
+	var status byte
+	var payload []byte
+	if err == nil {
+		status, payload, err = frame.Read(c.r)
+	}
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return nil, fmt.Errorf("publisher %s: %w", c.addr, err)
+	}
+
+	switch status {
+	case statusOK:
+		return payload, nil
+	case statusNotPublished:
+		return nil, fmt.Errorf("%w at %s", ErrNotPublished, c.addr)
+	case statusPruned:
+		return nil, fmt.Errorf("%w at %s", ErrPruned, c.addr)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPublisher, payload)
+}
+
+// Close closes the connection to the publisher.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,193 @@
+package standby
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/frame"
+)
+
+// The standby protocol is a stream of requests to a publisher, each answered by a response
+// in order. The integers are big-endian.
+//
+//	request:  'b' height(4B) | 't'
+//	response: status(1B) len(4B) payload, of up to frame.MaxSize bytes
+//
+// The payload of a statusOK is the encoded Block, or the height(4B) and root(32B) of the tip,
+// and the message of the others.
+const (
+	requestBlock = 'b'
+	requestTip   = 't'
+
+	statusOK           = 0
+	statusNotPublished = 1
+	statusPruned       = 2
+	statusError        = 3
+)
+
+var (
+	// ErrNotPublished is returned for the blocks past the tip of the publisher, and for its tip, before any block.
+	ErrNotPublished = errors.New("block not published yet")
+
+	// ErrPruned is returned for the blocks older than the ones kept by the publisher.
+	// The standby has to be restored from a backup of the primary instead.
+	ErrPruned = errors.New("block pruned")
+
+	// ErrPublisher is returned when the publisher fails a request.
+	ErrPublisher = errors.New("block publisher")
+)
+
+// Publisher keeps the last blocks of the primary, and serves them to the standbys.
+type Publisher struct {
+	mu     sync.Mutex
+	keep   int
+	blocks []*Block // of consecutive heights.
+}
+
+// NewPublisher returns a Publisher, which keeps the last keep blocks.
+func NewPublisher(keep int) *Publisher {
+	if keep < 1 {
+		keep = 1
+	}
+	return &Publisher{keep: keep}
+}
+
+// Publish adds the block, replacing the ones of its height, and after, if any.
+func (p *Publisher) Publish(b *Block) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rewind(b.Height - 1)
+	if n := len(p.blocks); n > 0 && p.blocks[n-1].Height != b.Height-1 {
+		p.blocks = nil // a gap, such as after a reset past the kept ones
+	}
+	p.blocks = append(p.blocks, b)
+	if n := len(p.blocks); n > p.keep {
+		p.blocks = append(p.blocks[:0:0], p.blocks[n-p.keep:]...)
+	}
+}
+
+// Rewind drops the blocks after the height, which were reorganized away.
+func (p *Publisher) Rewind(height int32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rewind(height)
+}
+
+func (p *Publisher) rewind(height int32) {
+	for len(p.blocks) > 0 && p.blocks[len(p.blocks)-1].Height > height {
+		p.blocks = p.blocks[:len(p.blocks)-1]
+	}
+}
+
+// Block returns the block at the height.
+func (p *Publisher) Block(height int32) (*Block, error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.blocks) == 0 || height > p.blocks[len(p.blocks)-1].Height {
+		return nil, fmt.Errorf("%w: %d", ErrNotPublished, height)
+	}
+	first := p.blocks[0].Height
+	if height < first {
+		return nil, fmt.Errorf("%w: %d, before %d", ErrPruned, height, first)
+	}
+
+	return p.blocks[height-first], nil
+}
+
+// Tip returns the height, and the root, of the last block published.
+func (p *Publisher) Tip() (int32, chainhash.Hash, error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.blocks) == 0 {
+		return 0, chainhash.Hash{}, fmt.Errorf("%w: no tip", ErrNotPublished)
+	}
+	b := p.blocks[len(p.blocks)-1]
+
+	return b.Height, b.Root, nil
+}
+
+// Serve answers the requests of the standby protocol on the connections accepted from l,
+// until l is closed.
+func (p *Publisher) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go p.serveConn(conn)
+	}
+}
+
+func (p *Publisher) serveConn(conn net.Conn) {
+
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+
+		var payload []byte
+		switch op {
+		case requestBlock:
+			var n [4]byte
+			if _, err = io.ReadFull(r, n[:]); err != nil {
+				return
+			}
+			var b *Block
+			b, err = p.Block(int32(binary.BigEndian.Uint32(n[:])))
+			if err == nil {
+				payload = b.encode()
+			}
+		case requestTip:
+			var height int32
+			var root chainhash.Hash
+			height, root, err = p.Tip()
+			if err == nil {
+				payload = appendUint32(nil, uint32(height))
+				payload = append(payload, root[:]...)
+			}
+		default:
+			writeResponse(w, statusError, []byte("unknown request")) // nolint : errchk
+			return
+		}
+
+		status := byte(statusOK)
+		switch {
+		case errors.Is(err, ErrNotPublished):
+			status, payload = statusNotPublished, []byte(err.Error())
+		case errors.Is(err, ErrPruned):
+			status, payload = statusPruned, []byte(err.Error())
+		}
+		if err = writeResponse(w, status, payload); err != nil {
+			return
+		}
+	}
+}
+
+func writeResponse(w *bufio.Writer, status byte, payload []byte) error {
+	if err := frame.Write(w, status, payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], v)
+	return append(b, n[:]...)
+}
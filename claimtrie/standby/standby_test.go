@@ -0,0 +1,111 @@
+package standby_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/claimtrie/standby"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStandby(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	cfg := config.DefaultConfig
+	cfg.DataDir = t.TempDir()
+	cfg.StandbyBlocks = 20
+	primary, err := claimtrie.New(cfg)
+	r.NoError(err)
+	defer primary.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	defer l.Close()
+	go primary.StandbyPublisher().Serve(l) // nolint : errchk
+	c := standby.NewClient(l.Addr().String(), 10*time.Second)
+	defer c.Close()
+
+	_, _, err = c.Tip()
+	r.ErrorIs(err, standby.ErrNotPublished)
+
+	standbyCfg := config.DefaultConfig
+	standbyCfg.DataDir = t.TempDir()
+	ct, err := claimtrie.New(standbyCfg)
+	r.NoError(err)
+	defer ct.Close()
+	s := claimtrie.NewStandby(ct, c, 3)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	names := []string{"test", "Test", "other", "a", "ab"}
+	appendBlock := func(i uint32) {
+		op := wire.OutPoint{Hash: hash, Index: i}
+		name := []byte(names[int(i)%len(names)])
+		switch i % 3 {
+		case 0:
+			r.NoError(primary.AddClaim(name, op, node.NewClaimID(op), int64(i), []byte("value")))
+		case 1:
+			if i > 16 { // the claims are spent a few blocks after
+				prev := wire.OutPoint{Hash: hash, Index: i - 16}
+				r.NoError(primary.SpendClaim([]byte(names[int(i-16)%len(names)]), prev, node.NewClaimID(prev)))
+			}
+		case 2:
+			r.NoError(primary.AddSupport(name, nil, op, int64(i), node.NewClaimID(wire.OutPoint{Hash: hash, Index: i - 2})))
+		}
+		r.NoError(primary.AppendBlock())
+	}
+
+	// The standby stays the lag behind the primary.
+	for i := uint32(1); i <= 270; i++ {
+		appendBlock(i)
+		if i%7 == 0 {
+			_, err = s.Sync()
+			r.NoError(err)
+			r.Equal(primary.Height()-3, ct.Height())
+		}
+	}
+
+	// The blocks reorganized away within the lag aren't applied.
+	_, err = s.Sync()
+	r.NoError(err)
+	r.Equal(int32(267), ct.Height())
+	r.NoError(primary.ResetHeight(268))
+	for i := uint32(1269); i <= 1272; i++ {
+		appendBlock(i)
+	}
+	applied, err := s.Sync()
+	r.NoError(err)
+	r.Equal(2, applied)
+
+	// Promoting it catches it up to the tip, and verifies the root of it.
+	height, root, err := s.Promote()
+	r.NoError(err)
+	r.Equal(primary.Height(), height)
+	r.Equal(*primary.MerkleHash(), root)
+	r.Equal(root, *ct.MerkleHash())
+	n, err := ct.Node([]byte("other"))
+	r.NoError(err)
+	r.NotEmpty(n.Claims)
+
+	// A reorganization past the standby diverges from it.
+	r.NoError(primary.ResetHeight(ct.Height() - 1))
+	appendBlock(2000)
+	appendBlock(2001)
+	_, err = s.Sync()
+	r.NoError(err)
+	_, _, err = s.Promote()
+	r.ErrorIs(err, claimtrie.ErrStandbyDiverged)
+
+	// The blocks before the kept ones are pruned.
+	_, err = c.Block(200)
+	r.ErrorIs(err, standby.ErrPruned)
+}
@@ -0,0 +1,322 @@
+package claimtrie
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+const (
+	stateMagic   = "claimtrie-state"
+	stateVersion = 1
+
+	stateNode = 'n' // uvarint(len(name)) + name + uvarint(len(snapshot)) + snapshot
+	stateEnd  = 'e' // uvarint(nodes)
+
+	maxStateRecord = 1 << 28
+)
+
+// stateImporter saves the nodes imported as their base snapshots.
+type stateImporter interface {
+	ImportBase(name []byte, n *node.Node, height int32) error
+}
+
+// StateHeader is the summary of an export of the state of the ClaimTrie.
+type StateHeader struct {
+	Height int32
+	Root   chainhash.Hash
+	Nodes  int
+}
+
+// ExportState writes the nodes of all the names as of the height, with their claims, supports, and takeover
+// heights, to w, which ImportState bootstraps a ClaimTrie from, without replaying their changes.
+//
+// The export is gzipped, and, after its summary line:
+//
+//	claimtrie-state v1 height=<height> root=<root>
+//
+// holds a record of each node, as the node snapshots encode it, and the number of them last. The trie isn't
+// exported, as it's rebuilt from the nodes, and verified against the root, on import.
+func (ct *ClaimTrie) ExportState(ctx context.Context, w io.Writer, height int32) (*StateHeader, error) {
+
+	if height <= 0 || height > ct.height {
+		return nil, fmt.Errorf("height %d out of range: 1 to %d", height, ct.height)
+	}
+	if height < ct.prunedAt {
+		return nil, fmt.Errorf("the changes before %d are pruned", ct.prunedAt)
+	}
+	root, err := ct.blockRepo.Get(height)
+	if err != nil {
+		return nil, fmt.Errorf("block repo get: %w", err)
+	}
+
+	var names [][]byte
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		names = append(names, append([]byte(nil), name...))
+		return true
+	})
+
+	h := &StateHeader{Height: height, Root: *root}
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriterSize(zw, 1<<20)
+	_, err = fmt.Fprintf(bw, "%s v%d height=%d root=%s\n", stateMagic, stateVersion, h.Height, h.Root)
+	if err != nil {
+		return nil, fmt.Errorf("write summary: %w", err)
+	}
+
+	for _, name := range names {
+		n, err := ct.exportedNode(ctx, name, height)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil || (len(n.Claims) == 0 && len(n.Supports) == 0) {
+			continue
+		}
+		data, err := node.EncodeSnapshot(n, height)
+		if err != nil {
+			return nil, err
+		}
+		err = writeStateRecord(bw, stateNode, name, data)
+		if err != nil {
+			return nil, fmt.Errorf("write node: %w", err)
+		}
+		h.Nodes++
+	}
+
+	err = writeStateRecord(bw, stateEnd, nil, nil)
+	if err == nil {
+		_, err = bw.Write(appendUvarint(nil, uint64(h.Nodes)))
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("write end: %w", err)
+	}
+
+	return h, nil
+}
+
+// exportedNode returns the node of the name as of the height, read locking the history only for it.
+func (ct *ClaimTrie) exportedNode(ctx context.Context, name []byte, height int32) (*node.Node, error) {
+
+	unlock, err := ct.readHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if height > ct.Height() {
+		return nil, fmt.Errorf("%w: reset to %d, before the export at %d", ErrStaleSnapshot, ct.Height(), height)
+	}
+
+	n, err := ct.nodeManager.NodeAt(height, name)
+	if err != nil {
+		return nil, fmt.Errorf("node %q at %d: %w", name, height, err)
+	}
+
+	return n, nil
+}
+
+func writeStateRecord(w *bufio.Writer, kind byte, name, data []byte) error {
+
+	b := []byte{kind}
+	if kind == stateNode {
+		b = appendUvarint(b, uint64(len(name)))
+		b = append(b, name...)
+		b = appendUvarint(b, uint64(len(data)))
+	}
+	_, err := w.Write(b)
+	if err == nil {
+		_, err = w.Write(data)
+	}
+
+	return err
+}
+
+// ImportState bootstraps a ClaimTrie, in the empty cfg.DataDir, from an export written by ExportState. The nodes
+// are saved as their base snapshots at the height of the export, and the trie, which is rebuilt from them, must
+// hash to the root of the export. The ClaimTrie is configured with config.NodeManagerSnapshot, and a
+// ChangeRetention other than config.RetainAll, which keep the base snapshots. It can't be reset before the height.
+func ImportState(cfg config.Config, r io.Reader) (*StateHeader, error) {
+
+	if cfg.NodeManager != config.NodeManagerSnapshot || cfg.ChangeRetention == config.RetainAll ||
+		cfg.ChangeRetention == "" {
+		return nil, fmt.Errorf("importing the state requires the snapshot node manager, and a change retention")
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open export: %w", err)
+	}
+	br := bufio.NewReaderSize(zr, 1<<20)
+	h, err := readStateSummary(br)
+	if err != nil {
+		return nil, err
+	}
+
+	ct, err := New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("create claimtrie: %w", err)
+	}
+	err = ct.importState(br, h)
+	cerr := ct.Close()
+	if err != nil {
+		return nil, err
+	}
+	if cerr != nil {
+		return nil, fmt.Errorf("close claimtrie: %w", cerr)
+	}
+
+	return h, nil
+}
+
+func readStateSummary(br *bufio.Reader) (*StateHeader, error) {
+
+	summary, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read summary: %w", err)
+	}
+
+	h := &StateHeader{}
+	var version int
+	var root string
+	_, err = fmt.Sscanf(summary, stateMagic+" v%d height=%d root=%s\n", &version, &h.Height, &root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid summary: %q: %w", strings.TrimSpace(summary), err)
+	}
+	if version != stateVersion {
+		return nil, fmt.Errorf("unsupported export version: %d", version)
+	}
+	if h.Height <= 0 {
+		return nil, fmt.Errorf("invalid height: %d", h.Height)
+	}
+	hash, err := chainhash.NewHashFromStr(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root: %w", err)
+	}
+	h.Root = *hash
+
+	return h, nil
+}
+
+// importState saves the nodes read from br as their base snapshots, and appends the block of the export.
+func (ct *ClaimTrie) importState(br *bufio.Reader, h *StateHeader) error {
+
+	if ct.height != 0 || ct.root != nil {
+		return fmt.Errorf("the ClaimTrie isn't empty, at height %d", ct.height)
+	}
+	if ct.importer == nil {
+		return fmt.Errorf("the node manager can't import the base snapshots")
+	}
+
+	var names [][]byte
+	for {
+		kind, err := br.ReadByte()
+		if err != nil {
+			return fmt.Errorf("read record: %w", err)
+		}
+		if kind == stateEnd {
+			break
+		}
+		if kind != stateNode {
+			return fmt.Errorf("invalid record: %q", kind)
+		}
+		name, err := readStateBytes(br)
+		if err != nil {
+			return fmt.Errorf("read name: %w", err)
+		}
+		data, err := readStateBytes(br)
+		if err != nil {
+			return fmt.Errorf("read node %q: %w", name, err)
+		}
+		n, height, err := node.DecodeSnapshot(data)
+		if err != nil {
+			return fmt.Errorf("decode node %q: %w", name, err)
+		}
+		if height != h.Height {
+			return fmt.Errorf("node %q at %d, instead of %d", name, height, h.Height)
+		}
+		err = ct.importer.ImportBase(name, n, h.Height)
+		if err != nil {
+			return fmt.Errorf("import node %q: %w", name, err)
+		}
+		names = append(names, name)
+	}
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return fmt.Errorf("read end: %w", err)
+	}
+	if int(count) != len(names) {
+		return fmt.Errorf("read %d nodes, instead of %d", len(names), count)
+	}
+	h.Nodes = len(names)
+
+	_, err = ct.nodeManager.IncrementHeightTo(h.Height)
+	if err != nil {
+		return fmt.Errorf("node manager increment: %w", err)
+	}
+	ct.height = h.Height
+
+	updateNames := make([][]byte, 0, len(names))
+	updateHeights := make([]int32, 0, len(names))
+	for _, name := range names {
+		ct.merkleTrie.Update(name, true)
+		newName, nextUpdate := ct.nodeManager.NextUpdateHeightOfNode(name)
+		if nextUpdate > 0 {
+			updateNames = append(updateNames, newName)
+			updateHeights = append(updateHeights, nextUpdate)
+		}
+	}
+	err = ct.temporalRepo.SetNodesAt(updateNames, updateHeights)
+	if err != nil {
+		return fmt.Errorf("temporal repo set at: %w", err)
+	}
+
+	root := ct.MerkleHash()
+	if *root != h.Root {
+		return fmt.Errorf("the nodes hash to %s, instead of the root %s of the export", root, h.Root)
+	}
+	err = ct.merkleTrie.Commit()
+	if err != nil {
+		return fmt.Errorf("merkle trie commit: %w", err)
+	}
+	err = ct.blockRepo.Set(ct.height, root)
+	if err != nil {
+		return fmt.Errorf("block repo set: %w", err)
+	}
+	ct.root = root
+
+	return nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func readStateBytes(br *bufio.Reader) ([]byte, error) {
+
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxStateRecord {
+		return nil, fmt.Errorf("record of %d bytes is too large", size)
+	}
+	b := make([]byte, size)
+	_, err = io.ReadFull(br, b)
+
+	return b, err
+}
@@ -0,0 +1,25 @@
+package takeover
+
+import "github.com/btcsuite/btcd/claimtrie/event"
+
+// Record is the diagnostic of a takeover of the name at the height.
+type Record struct {
+	Name   []byte
+	Height int32
+
+	// The claims for the name, the new best first, then the runners-up.
+	Contenders []event.Contender
+}
+
+// Repo defines APIs for the diagnostics of the takeovers to access persistence layer.
+type Repo interface {
+	// Save records the takeovers.
+	Save(records []Record) error
+	// Rewind drops the takeovers of the names after height.
+	Rewind(names [][]byte, height int32) error
+
+	// Takeovers returns the takeovers of the name, oldest first.
+	Takeovers(name []byte) ([]Record, error)
+
+	Close() error
+}
@@ -0,0 +1,198 @@
+package takeoverrepo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/claimtrie/event"
+	"github.com/btcsuite/btcd/claimtrie/takeover"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Key format:
+//
+//	len(2B) + name + height(4B): the contenders of the takeover of the name at the height.
+//
+// Value format:
+//
+//	count(varint) { claimID(varstr) outPoint(varstr) amount(8B) effective(8B) accepted(4B) active(4B) }
+type Pebble struct {
+	db *pebble.DB
+}
+
+func NewPebble(path string) (*Pebble, error) {
+
+	db, err := pebble.Open(path, &pebble.Options{Cache: pebble.NewCache(16 << 20)})
+	if err != nil {
+		return nil, fmt.Errorf("pebble open %s, %w", path, err)
+	}
+
+	repo := &Pebble{db: db}
+
+	return repo, nil
+}
+
+func key(name []byte, height int32) []byte {
+	k := make([]byte, 2+len(name)+4)
+	binary.BigEndian.PutUint16(k, uint16(len(name)))
+	copy(k[2:], name)
+	binary.BigEndian.PutUint32(k[2+len(name):], uint32(height))
+	return k
+}
+
+func marshal(contenders []event.Contender) ([]byte, error) {
+
+	b := bytes.NewBuffer(nil)
+	err := wire.WriteVarInt(b, 0, uint64(len(contenders)))
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range contenders {
+		err = wire.WriteVarString(b, 0, c.ClaimID)
+		if err != nil {
+			return nil, err
+		}
+		err = wire.WriteVarString(b, 0, c.OutPoint)
+		if err != nil {
+			return nil, err
+		}
+		var buf [8 + 8 + 4 + 4]byte
+		binary.BigEndian.PutUint64(buf[0:], uint64(c.Amount))
+		binary.BigEndian.PutUint64(buf[8:], uint64(c.EffectiveAmount))
+		binary.BigEndian.PutUint32(buf[16:], uint32(c.AcceptedAt))
+		binary.BigEndian.PutUint32(buf[20:], uint32(c.ActiveAt))
+		b.Write(buf[:]) // nolint : errchk
+	}
+
+	return b.Bytes(), nil
+}
+
+func unmarshal(value []byte) ([]event.Contender, error) {
+
+	r := bytes.NewReader(value)
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(len(value)) {
+		return nil, fmt.Errorf("too many contenders: %d", count)
+	}
+
+	contenders := make([]event.Contender, count)
+	for i := range contenders {
+		c := &contenders[i]
+		c.ClaimID, err = wire.ReadVarString(r, 0)
+		if err != nil {
+			return nil, err
+		}
+		c.OutPoint, err = wire.ReadVarString(r, 0)
+		if err != nil {
+			return nil, err
+		}
+		var buf [8 + 8 + 4 + 4]byte
+		_, err = io.ReadFull(r, buf[:])
+		if err != nil {
+			return nil, err
+		}
+		c.Amount = int64(binary.BigEndian.Uint64(buf[0:]))
+		c.EffectiveAmount = int64(binary.BigEndian.Uint64(buf[8:]))
+		c.AcceptedAt = int32(binary.BigEndian.Uint32(buf[16:]))
+		c.ActiveAt = int32(binary.BigEndian.Uint32(buf[20:]))
+	}
+
+	return contenders, nil
+}
+
+func (repo *Pebble) Save(records []takeover.Record) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	for _, rec := range records {
+		value, err := marshal(rec.Contenders)
+		if err != nil {
+			return fmt.Errorf("marshal contenders: %w", err)
+		}
+		err = batch.Set(key(rec.Name, rec.Height), value, pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble set: %w", err)
+		}
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+func (repo *Pebble) Rewind(names [][]byte, height int32) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	for _, name := range names {
+		err := batch.DeleteRange(key(name, height+1), key(name, -1), pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble delete range: %w", err)
+		}
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+func (repo *Pebble) Takeovers(name []byte) ([]takeover.Record, error) {
+
+	iter := repo.db.NewIter(&pebble.IterOptions{
+		LowerBound: key(name, 0),
+		UpperBound: key(name, -1), // sorts after all the heights
+	})
+
+	var records []takeover.Record
+	for iter.First(); iter.Valid(); iter.Next() {
+		k := iter.Key()
+		contenders, err := unmarshal(iter.Value())
+		if err != nil {
+			iter.Close()
+			return nil, fmt.Errorf("unmarshal contenders of %q: %w", name, err)
+		}
+		records = append(records, takeover.Record{
+			Name:       append([]byte(nil), name...),
+			Height:     int32(binary.BigEndian.Uint32(k[len(k)-4:])),
+			Contenders: contenders,
+		})
+	}
+
+	err := iter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("pebble iter: %w", err)
+	}
+
+	return records, nil
+}
+
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Pebble) Backup(dir string) error {
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Pebble) Close() error {
+
+	err := repo.db.Flush()
+	if err != nil {
+		return fmt.Errorf("pebble flush: %w", err)
+	}
+
+	err = repo.db.Close()
+	if err != nil {
+		return fmt.Errorf("pebble close: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package takeoverrepo
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/claimtrie/event"
+	"github.com/btcsuite/btcd/claimtrie/takeover"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeovers(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	winner := event.Contender{ClaimID: "aa", OutPoint: "tx:0", Amount: 5, EffectiveAmount: 8, AcceptedAt: 1, ActiveAt: 1}
+	runnerUp := event.Contender{ClaimID: "bb", OutPoint: "tx:1", Amount: 7, EffectiveAmount: 7, AcceptedAt: 2, ActiveAt: 3}
+	pending := event.Contender{ClaimID: "cc", OutPoint: "tx:2", Amount: 9, AcceptedAt: 4, ActiveAt: 9}
+
+	a, ab := []byte("a"), []byte("ab")
+	r.NoError(repo.Save([]takeover.Record{
+		{Name: a, Height: 3, Contenders: []event.Contender{winner}},
+		{Name: ab, Height: 3, Contenders: []event.Contender{winner, runnerUp}},
+	}))
+	r.NoError(repo.Save([]takeover.Record{{Name: a, Height: 5, Contenders: []event.Contender{runnerUp, winner, pending}}}))
+
+	records, err := repo.Takeovers(a)
+	r.NoError(err)
+	r.Equal([]takeover.Record{
+		{Name: a, Height: 3, Contenders: []event.Contender{winner}},
+		{Name: a, Height: 5, Contenders: []event.Contender{runnerUp, winner, pending}},
+	}, records)
+
+	// The names sharing the prefix are kept apart.
+	records, err = repo.Takeovers(ab)
+	r.NoError(err)
+	r.Len(records, 1)
+	r.Equal([]event.Contender{winner, runnerUp}, records[0].Contenders)
+
+	r.NoError(repo.Rewind([][]byte{a}, 4))
+	records, err = repo.Takeovers(a)
+	r.NoError(err)
+	r.Len(records, 1)
+	r.Equal(int32(3), records[0].Height)
+}
@@ -0,0 +1,100 @@
+package claimtrie
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/claimtrie/event"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/takeover"
+)
+
+// contenders returns the claims for the name, which aren't deactivated, the best first,
+// then the runners-up by their effective amounts, and the pending ones by their amounts.
+func contenders(n *node.Node) []event.Contender {
+
+	claims := make(node.ClaimList, 0, len(n.Claims))
+	for _, c := range n.Claims {
+		if c.Status != node.Deactivated && c != n.BestClaim {
+			claims = append(claims, c)
+		}
+	}
+	sort.SliceStable(claims, func(i, j int) bool {
+		ei, ej := n.EffectiveAmount(claims[i]), n.EffectiveAmount(claims[j])
+		switch {
+		case ei != ej:
+			return ei > ej
+		case claims[i].Amount != claims[j].Amount:
+			return claims[i].Amount > claims[j].Amount
+		case claims[i].AcceptedAt != claims[j].AcceptedAt:
+			return claims[i].AcceptedAt < claims[j].AcceptedAt
+		}
+		return node.OutPointLess(claims[i].OutPoint, claims[j].OutPoint)
+	})
+	if n.BestClaim != nil {
+		claims = append(node.ClaimList{n.BestClaim}, claims...)
+	}
+
+	cs := make([]event.Contender, 0, len(claims))
+	for _, c := range claims {
+		cs = append(cs, event.Contender{
+			ClaimID:         c.ClaimID.String(),
+			OutPoint:        c.OutPoint.String(),
+			Amount:          c.Amount,
+			EffectiveAmount: n.EffectiveAmount(c),
+			AcceptedAt:      c.AcceptedAt,
+			ActiveAt:        c.ActiveAt,
+		})
+	}
+
+	return cs
+}
+
+// diagnoseTakeovers logs the contenders of the names taken over at the current height,
+// and saves them to the takeover repo, if any.
+func (ct *ClaimTrie) diagnoseTakeovers(names [][]byte) error {
+
+	var records []takeover.Record
+	for _, name := range names {
+		n, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return fmt.Errorf("node: %w", err)
+		}
+		if n == nil || n.BestClaim == nil || n.TakenOverAt != ct.height {
+			continue
+		}
+
+		cs := contenders(n)
+		runnersUp := make([]string, 0, len(cs)-1)
+		for _, c := range cs[1:] {
+			runnersUp = append(runnersUp, fmt.Sprintf("%s (%d, active at %d)", c.ClaimID, c.EffectiveAmount, c.ActiveAt))
+		}
+		log.Infof("Takeover of %q at %d by %s (%d, accepted at %d), runners-up: [%s]",
+			name, ct.height, cs[0].ClaimID, cs[0].EffectiveAmount, cs[0].AcceptedAt, strings.Join(runnersUp, ", "))
+
+		records = append(records, takeover.Record{Name: name, Height: ct.height, Contenders: cs})
+	}
+
+	if ct.takeoverRepo == nil || len(records) == 0 {
+		return nil
+	}
+
+	err := ct.takeoverRepo.Save(records)
+	if err != nil {
+		return fmt.Errorf("takeover repo save: %w", err)
+	}
+
+	return nil
+}
+
+// Takeovers returns the recorded diagnostics of the takeovers of the name, oldest first.
+// The takeovers are only recorded with the TakeoverRecord of the config.
+func (ct *ClaimTrie) Takeovers(name []byte) ([]takeover.Record, error) {
+
+	if ct.takeoverRepo == nil {
+		return nil, fmt.Errorf("takeover records are disabled")
+	}
+
+	return ct.takeoverRepo.Takeovers(node.NormalizeIfNecessary(name, ct.height))
+}
@@ -1,8 +1,14 @@
 package temporal
 
 // Repo defines APIs for Temporal to access persistence layer.
+// It persists, at each height, the names updated at it, for the rollbacks, and the names
+// scheduled to be updated at it, by the activations and expirations of their claims and
+// supports, so a restart resumes the schedule without materializing any nodes.
 type Repo interface {
 	SetNodesAt(names [][]byte, heights []int32) error
-	NodesAt(height int32) ([][]byte, error)
+	NodesAt(height int32) ([][]byte, error) // in order by name.
+
+	// DropNodesBefore drops the nodes at the heights before the height.
+	DropNodesBefore(height int32) error
 	Close() error
 }
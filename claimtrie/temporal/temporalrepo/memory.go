@@ -1,5 +1,10 @@
 package temporalrepo
 
+import (
+	"bytes"
+	"sort"
+)
+
 type Memory struct {
 	cache map[int32]map[string]bool
 }
@@ -32,10 +37,22 @@ func (repo *Memory) NodesAt(height int32) ([][]byte, error) {
 	for name := range repo.cache[height] {
 		names = append(names, []byte(name))
 	}
+	sort.Slice(names, func(i, j int) bool { return bytes.Compare(names[i], names[j]) < 0 }) // as the pebble repo does.
 
 	return names, nil
 }
 
+func (repo *Memory) DropNodesBefore(height int32) error {
+
+	for h := range repo.cache {
+		if h < height {
+			delete(repo.cache, h)
+		}
+	}
+
+	return nil
+}
+
 func (repo *Memory) Close() error {
 	return nil
 }
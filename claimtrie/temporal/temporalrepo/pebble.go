@@ -12,9 +12,20 @@ type Pebble struct {
 	db *pebble.DB
 }
 
+// DefaultCacheSize is the size, in bytes, of the block cache of a repo opened with NewPebble.
+const DefaultCacheSize = 128 << 20
+
 func NewPebble(path string) (*Pebble, error) {
+	return NewPebbleWithCache(path, DefaultCacheSize)
+}
+
+// NewPebbleWithCache returns a repo with a block cache of cacheSize bytes.
+func NewPebbleWithCache(path string, cacheSize int64) (*Pebble, error) {
 
-	db, err := pebble.Open(path, &pebble.Options{Cache: pebble.NewCache(128 << 20)})
+	cache := pebble.NewCache(cacheSize)
+	defer cache.Unref()
+
+	db, err := pebble.Open(path, &pebble.Options{Cache: cache})
 	if err != nil {
 		return nil, fmt.Errorf("pebble open %s, %w", path, err)
 	}
@@ -77,6 +88,30 @@ func (repo *Pebble) NodesAt(height int32) ([][]byte, error) {
 	return names, nil
 }
 
+func (repo *Pebble) DropNodesBefore(height int32) error {
+
+	end := make([]byte, 4)
+	binary.BigEndian.PutUint32(end, uint32(height))
+
+	err := repo.db.DeleteRange([]byte{}, end, pebble.NoSync)
+	if err != nil {
+		return fmt.Errorf("pebble delete range: %w", err)
+	}
+
+	return nil
+}
+
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Pebble) Backup(dir string) error {
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
+}
+
 func (repo *Pebble) Close() error {
 
 	err := repo.db.Flush()
@@ -68,13 +68,38 @@ func testTemporalRepo(t *testing.T, repo temporal.Repo) {
 
 	names, err = repo.NodesAt(1)
 	r.NoError(err)
-	r.ElementsMatch([][]byte{nameA, nameB}, names)
+	r.Equal([][]byte{nameA, nameB}, names) // in order by name, of both repos.
 
 	names, err = repo.NodesAt(4)
 	r.NoError(err)
-	r.ElementsMatch([][]byte{nameB, nameC}, names)
+	r.Equal([][]byte{nameB, nameC}, names)
 
 	names, err = repo.NodesAt(3)
 	r.NoError(err)
-	r.ElementsMatch([][]byte{nameA, nameC}, names)
+	r.Equal([][]byte{nameA, nameC}, names)
+}
+
+func TestDropNodesBefore(t *testing.T) {
+
+	r := require.New(t)
+
+	pebbleRepo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer pebbleRepo.Close()
+
+	for _, repo := range []temporal.Repo{NewMemory(), pebbleRepo} {
+		err := repo.SetNodesAt([][]byte{[]byte("a"), []byte("b"), []byte("c")}, []int32{1, 2, 3})
+		r.NoError(err)
+
+		r.NoError(repo.DropNodesBefore(3))
+
+		for _, h := range []int32{1, 2} {
+			names, err := repo.NodesAt(h)
+			r.NoError(err)
+			r.Empty(names)
+		}
+		names, err := repo.NodesAt(3)
+		r.NoError(err)
+		r.ElementsMatch([][]byte{[]byte("c")}, names)
+	}
 }
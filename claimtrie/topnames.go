@@ -0,0 +1,196 @@
+package claimtrie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// ErrTopNamesNotIndexed is returned by TopNames, if the names aren't indexed by their amounts.
+var ErrTopNamesNotIndexed = errors.New("top names aren't indexed")
+
+// TopName is a name ranked by the effective amount of its best claim.
+type TopName struct {
+	Name        []byte
+	ClaimID     node.ClaimID
+	Amount      int64 // The effective amount of the best claim, including its supports.
+	TakenOverAt int32
+}
+
+// before reports whether a ranks before b: by amount, highest first, then by name.
+func (a *TopName) before(b *TopName) bool {
+	if a.Amount != b.Amount {
+		return a.Amount > b.Amount
+	}
+	return bytes.Compare(a.Name, b.Name) < 0
+}
+
+const (
+	topNamesMaxLevel = 24 // Enough for 4^24 names, as each level holds a quarter of the one below.
+	topNamesP        = 4
+)
+
+type topNameEntry struct {
+	TopName
+	next []*topNameEntry
+}
+
+// topNames is a skip list of the names with best claims, in order by rank,
+// which takes O(log n) to update a name, instead of scanning all of them.
+type topNames struct {
+	mu      sync.RWMutex
+	head    topNameEntry
+	entries map[string]*topNameEntry
+	seed    uint64
+}
+
+func newTopNames() *topNames {
+	return &topNames{
+		head:    topNameEntry{next: make([]*topNameEntry, topNamesMaxLevel)},
+		entries: map[string]*topNameEntry{},
+		seed:    0x9e3779b97f4a7c15,
+	}
+}
+
+// TopNames returns up to n names with the highest effective amounts of their best claims,
+// as of the last block appended, in order.
+func (ct *ClaimTrie) TopNames(n int) ([]TopName, error) {
+
+	if ct.topNames == nil {
+		return nil, ErrTopNamesNotIndexed
+	}
+
+	ct.topNames.mu.RLock()
+	defer ct.topNames.mu.RUnlock()
+
+	var names []TopName
+	for e := ct.topNames.head.next[0]; e != nil && len(names) < n; e = e.next[0] {
+		tn := e.TopName
+		tn.Name = append([]byte(nil), e.Name...)
+		names = append(names, tn)
+	}
+
+	return names, nil
+}
+
+// rebuildTopNames ranks all the names from scratch.
+func (ct *ClaimTrie) rebuildTopNames() error {
+
+	ct.topNames.mu.Lock()
+	for _, e := range ct.topNames.entries {
+		ct.topNames.remove(e)
+	}
+	ct.topNames.mu.Unlock()
+
+	var names [][]byte
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		names = append(names, append([]byte(nil), name...))
+		return true
+	})
+
+	return ct.updateTopNames(names)
+}
+
+// updateTopNames re-ranks the names by the amounts of their best claims.
+func (ct *ClaimTrie) updateTopNames(names [][]byte) error {
+
+	ct.topNames.mu.Lock()
+	defer ct.topNames.mu.Unlock()
+
+	for _, name := range names {
+		name = node.NormalizeIfNecessary(name, ct.height)
+
+		n, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return fmt.Errorf("node: %w", err)
+		}
+
+		var tn *TopName
+		if n != nil && n.BestClaim != nil && n.BestClaim.Status == node.Activated {
+			tn = &TopName{
+				Name:        name,
+				ClaimID:     n.BestClaim.ClaimID,
+				Amount:      n.BestClaim.EffectiveAmount(n.Supports),
+				TakenOverAt: n.TakenOverAt,
+			}
+		}
+
+		e := ct.topNames.entries[string(name)]
+		if e != nil && tn != nil && e.ClaimID == tn.ClaimID && e.Amount == tn.Amount && e.TakenOverAt == tn.TakenOverAt {
+			continue
+		}
+		if e != nil {
+			ct.topNames.remove(e)
+		}
+		if tn != nil {
+			tn.Name = append([]byte(nil), name...)
+			ct.topNames.insert(*tn)
+		}
+	}
+
+	return nil
+}
+
+// rewindTopNames re-ranks the names after resetting from a later height.
+func (ct *ClaimTrie) rewindTopNames(names [][]byte, from int32) error {
+
+	if ct.height < param.NormalizedNameForkHeight && from >= param.NormalizedNameForkHeight {
+		return ct.rebuildTopNames() // the names were normalized in between
+	}
+	return ct.updateTopNames(names)
+}
+
+func (tl *topNames) level() int {
+	// xorshift64*, as the levels only need to be spread, not unpredictable.
+	tl.seed ^= tl.seed >> 12
+	tl.seed ^= tl.seed << 25
+	tl.seed ^= tl.seed >> 27
+	r := tl.seed * 2685821657736338717
+
+	level := 1
+	for level < topNamesMaxLevel && r%topNamesP == 0 {
+		level++
+		r /= topNamesP
+	}
+	return level
+}
+
+// predecessors returns the last entries ranked before tn at each level.
+func (tl *topNames) predecessors(tn *TopName) [topNamesMaxLevel]*topNameEntry {
+
+	var prev [topNamesMaxLevel]*topNameEntry
+	e := &tl.head
+	for i := topNamesMaxLevel - 1; i >= 0; i-- {
+		for e.next[i] != nil && e.next[i].before(tn) {
+			e = e.next[i]
+		}
+		prev[i] = e
+	}
+	return prev
+}
+
+func (tl *topNames) insert(tn TopName) {
+
+	prev := tl.predecessors(&tn)
+	e := &topNameEntry{TopName: tn, next: make([]*topNameEntry, tl.level())}
+	for i := range e.next {
+		e.next[i] = prev[i].next[i]
+		prev[i].next[i] = e
+	}
+	tl.entries[string(tn.Name)] = e
+}
+
+func (tl *topNames) remove(e *topNameEntry) {
+
+	prev := tl.predecessors(&e.TopName)
+	for i := range e.next {
+		if prev[i].next[i] == e {
+			prev[i].next[i] = e.next[i]
+		}
+	}
+	delete(tl.entries, string(e.Name))
+}
@@ -0,0 +1,135 @@
+package claimtrie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/claimtrie/change"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/outpoint"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ErrTransactionDone is returned by the calls to a Transaction after it's committed or aborted.
+var ErrTransactionDone = errors.New("transaction is already committed or aborted")
+
+// Transaction buffers the changes of a block, which are only applied to the
+// ClaimTrie on Commit, so that they can be discarded as a whole on Abort.
+type Transaction struct {
+	ct      *ClaimTrie
+	height  int32
+	changes []change.Change
+	created map[change.OutPoint]outpoint.Entry // The claims and supports added, if the outpoints are indexed.
+	owners  map[wire.OutPoint]ownerChange
+	dests   map[wire.OutPoint][]byte // The destination scripts of the outpoints created, if they're indexed.
+	done    bool
+}
+
+// Begin returns a Transaction for the changes of the block at height, which
+// must be the next one to be appended.
+func (ct *ClaimTrie) Begin(height int32) *Transaction {
+	return &Transaction{ct: ct, height: height}
+}
+
+// Height returns the height of the block of the transaction.
+func (tx *Transaction) Height() int32 {
+	return tx.height
+}
+
+// AddClaim adds a Claim to the transaction.
+func (tx *Transaction) AddClaim(name []byte, op wire.OutPoint, id node.ClaimID, amt int64, val []byte) error {
+	return tx.add(addClaimChange(name, op, id, amt, val))
+}
+
+// UpdateClaim updates a Claim in the transaction.
+func (tx *Transaction) UpdateClaim(name []byte, op wire.OutPoint, amt int64, id node.ClaimID, val []byte) error {
+	return tx.add(updateClaimChange(name, op, amt, id, val))
+}
+
+// SpendClaim spends a Claim in the transaction.
+func (tx *Transaction) SpendClaim(name []byte, op wire.OutPoint, id node.ClaimID) error {
+	return tx.add(spendClaimChange(name, op, id))
+}
+
+// AddSupport adds a Support to the transaction.
+func (tx *Transaction) AddSupport(name []byte, value []byte, op wire.OutPoint, amt int64, id node.ClaimID) error {
+	return tx.add(addSupportChange(name, value, op, amt, id))
+}
+
+// SpendSupport spends a Support in the transaction.
+func (tx *Transaction) SpendSupport(name []byte, op wire.OutPoint, id node.ClaimID) error {
+	return tx.add(spendSupportChange(name, op, id))
+}
+
+// TransferOwnership notes the destinations of a claim updated in the transaction, as ClaimTrie.TransferOwnership does.
+func (tx *Transaction) TransferOwnership(op wire.OutPoint, prevOwner, owner string) error {
+	if tx.done {
+		return ErrTransactionDone
+	}
+	if tx.owners == nil {
+		tx.owners = map[wire.OutPoint]ownerChange{}
+	}
+	tx.owners[op] = ownerChange{prev: prevOwner, next: owner}
+	return nil
+}
+
+func (tx *Transaction) add(chg change.Change) error {
+	if tx.done {
+		return ErrTransactionDone
+	}
+	err := checkValueSize(chg, tx.height)
+	if err != nil {
+		return err
+	}
+	tx.changes = append(tx.changes, chg)
+	if tx.ct.outPoints != nil {
+		if e, ok := createdEntry(chg); ok {
+			if tx.created == nil {
+				tx.created = map[change.OutPoint]outpoint.Entry{}
+			}
+			tx.created[chg.OutPoint] = e
+		}
+	}
+	return nil
+}
+
+// Commit applies the changes of the transaction to the ClaimTrie, all of them, or none, if one is rejected.
+// The block still has to be appended with AppendBlock.
+func (tx *Transaction) Commit() error {
+
+	if tx.done {
+		return ErrTransactionDone
+	}
+	tx.done = true
+
+	if tx.height != tx.ct.height+1 {
+		return fmt.Errorf("transaction of block %d committed at height %d", tx.height, tx.ct.height)
+	}
+
+	err := tx.ct.forwardNodeChanges(tx.changes)
+	if err != nil {
+		return err
+	}
+	for op, owners := range tx.owners {
+		tx.ct.TransferOwnership(op, owners.prev, owners.next)
+	}
+	for op, owner := range tx.dests {
+		tx.ct.SetOwner(op, owner)
+	}
+	tx.changes = nil
+	tx.created = nil
+	tx.owners = nil
+	tx.dests = nil
+
+	return nil
+}
+
+// Abort discards the changes of the transaction.
+func (tx *Transaction) Abort() {
+	tx.done = true
+	tx.changes = nil
+	tx.created = nil
+	tx.owners = nil
+	tx.dests = nil
+}
@@ -0,0 +1,206 @@
+package claimtrie
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/config"
+	"github.com/btcsuite/btcd/claimtrie/node/noderepo"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Benchmark is the measurements of the host of a ClaimTrie, taken by RunBenchmark.
+type Benchmark struct {
+	CPUs         int
+	HashesPerSec float64 // of the trie nodes, on one CPU.
+
+	// The mean latencies of a synced write, and of a read, of a repo in the data dir.
+	RepoWrite time.Duration
+	RepoRead  time.Duration
+
+	DataDirBytes int64
+	Took         time.Duration
+}
+
+// Recommendation is a change of a setting of config.Config, suggested by Advise.
+type Recommendation struct {
+	Setting   string
+	Current   string
+	Suggested string
+	Reason    string
+}
+
+// TuningReport is the benchmark of the host, and the recommendations of it, as reported at startup.
+type TuningReport struct {
+	Benchmark       *Benchmark
+	Recommendations []Recommendation
+}
+
+// Tune runs the benchmark, and advises on the settings of cfg, from its measurements.
+func Tune(cfg config.Config) (*TuningReport, error) {
+
+	b, err := RunBenchmark(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TuningReport{Benchmark: b, Recommendations: Advise(cfg, b)}, nil
+}
+
+const (
+	benchmarkHashTime   = 100 * time.Millisecond
+	benchmarkRepoWrites = 200
+)
+
+// RunBenchmark measures the hashing throughput of the host, and the latencies of a scratch repo in cfg.DataDir,
+// which is removed after, along with the size of the data dir. It takes a fraction of a second, on a local disk.
+func RunBenchmark(cfg config.Config) (*Benchmark, error) {
+
+	start := time.Now()
+	b := &Benchmark{CPUs: runtime.NumCPU()}
+
+	// A trie node hashes the hashes of its children, a few of them, on average.
+	buf := make([]byte, 4*chainhash.HashSize)
+	hashes := 0
+	for time.Since(start) < benchmarkHashTime {
+		for i := 0; i < 1000; i++ {
+			h := chainhash.DoubleHashH(buf)
+			copy(buf, h[:])
+		}
+		hashes += 1000
+	}
+	b.HashesPerSec = float64(hashes) / time.Since(start).Seconds()
+
+	err := os.MkdirAll(cfg.DataDir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("make data dir: %w", err)
+	}
+	err = filepath.Walk(cfg.DataDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			b.DataDirBytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("size data dir: %w", err)
+	}
+
+	b.RepoWrite, b.RepoRead, err = benchmarkRepo(cfg.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	b.Took = time.Since(start)
+
+	return b, nil
+}
+
+func benchmarkRepo(dataDir string) (write, read time.Duration, err error) {
+
+	dir, err := os.MkdirTemp(dataDir, "benchmark")
+	if err != nil {
+		return 0, 0, fmt.Errorf("make benchmark dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := pebble.NewCache(1 << 20) // so the reads aren't all cached
+	defer cache.Unref()
+	db, err := pebble.Open(dir, &pebble.Options{Cache: cache})
+	if err != nil {
+		return 0, 0, fmt.Errorf("pebble open %s: %w", dir, err)
+	}
+	defer db.Close()
+
+	value := make([]byte, 256)
+	start := time.Now()
+	for i := 0; i < benchmarkRepoWrites; i++ {
+		err = db.Set([]byte(strconv.Itoa(i)), value, pebble.Sync)
+		if err != nil {
+			return 0, 0, fmt.Errorf("pebble set: %w", err)
+		}
+	}
+	write = time.Since(start) / benchmarkRepoWrites
+
+	err = db.Flush()
+	if err != nil {
+		return 0, 0, fmt.Errorf("pebble flush: %w", err)
+	}
+	start = time.Now()
+	for i := 0; i < benchmarkRepoWrites; i++ {
+		_, closer, err := db.Get([]byte(strconv.Itoa(i)))
+		if err != nil {
+			return 0, 0, fmt.Errorf("pebble get: %w", err)
+		}
+		closer.Close()
+	}
+	read = time.Since(start) / benchmarkRepoWrites
+
+	return write, read, nil
+}
+
+// The thresholds of the recommendations: the latencies of a disk slower than a local SSD, and the hashing
+// throughput of a slow CPU.
+const (
+	slowRepoRead   = 200 * time.Microsecond
+	slowRepoWrite  = 2 * time.Millisecond
+	slowHashPerSec = 500000
+)
+
+// Advise returns the changes of the settings of cfg, which the benchmark suggests, such as the cache sizes for
+// a data dir much larger than them, on a slow disk. They're advisory; none of them changes the roots.
+func Advise(cfg config.Config, b *Benchmark) []Recommendation {
+
+	var recs []Recommendation
+	add := func(setting string, current, suggested interface{}, reason string) {
+		recs = append(recs, Recommendation{Setting: setting, Current: fmt.Sprint(current),
+			Suggested: fmt.Sprint(suggested), Reason: reason})
+	}
+
+	switch {
+	case cfg.HashWorkers > b.CPUs:
+		add("HashWorkers", cfg.HashWorkers, b.CPUs, "more hash workers than CPUs")
+	case cfg.HashWorkers == 1 && b.CPUs > 1 && b.HashesPerSec < slowHashPerSec:
+		add("HashWorkers", cfg.HashWorkers, 0, fmt.Sprintf("the hashing is slow, at %.0f/s, on one of %d CPUs",
+			b.HashesPerSec, b.CPUs))
+	}
+
+	if b.RepoRead > slowRepoRead {
+		nodeCache := cacheSize(cfg.NodeRepoPebble.CacheSize, noderepo.DefaultCacheSize)
+		if b.DataDirBytes > 8*nodeCache && nodeCache < 2<<30 {
+			suggested := b.DataDirBytes / 8
+			if suggested > 2<<30 {
+				suggested = 2 << 30
+			}
+			add("NodeRepoPebble.CacheSize", nodeCache, suggested, fmt.Sprintf(
+				"the reads take %s, and the data dir, of %d MiB, is over 8 times the cache", b.RepoRead, b.DataDirBytes>>20))
+		}
+		if cfg.MemoryBudget > 0 && cfg.TrieNodeCache == 0 {
+			add("TrieNodeCache", 0, cfg.MemoryBudget/8, fmt.Sprintf(
+				"the reads take %s, and the trie is dropped from memory to keep within the MemoryBudget", b.RepoRead))
+		}
+		if cfg.MemoryBudget > 0 && cfg.TriePrefetch == 0 {
+			add("TriePrefetch", 0, 4096, fmt.Sprintf(
+				"the reads take %s, and the trie is dropped from memory to keep within the MemoryBudget", b.RepoRead))
+		}
+	}
+
+	if b.RepoWrite > slowRepoWrite {
+		if cfg.TrieAsyncWrites == 0 {
+			add("TrieAsyncWrites", 0, 1<<20, fmt.Sprintf("the synced writes take %s", b.RepoWrite))
+		}
+		if cfg.BatchBlocks == 0 {
+			add("BatchBlocks", 0, 100, fmt.Sprintf(
+				"the synced writes take %s, which the initial block download batches across", b.RepoWrite))
+		}
+	}
+
+	return recs
+}
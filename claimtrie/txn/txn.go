@@ -0,0 +1,268 @@
+// Package txn commits the writes batched by several repos as one transaction, which survives a crash
+// in between their commits: the batches are written to a journal first, which is the commit marker,
+// and rewritten from it on recovery, if they weren't all committed.
+package txn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrCorruptJournal is returned by Recover, if the journal isn't the one written by a Commit.
+var ErrCorruptJournal = errors.New("corrupt transaction journal")
+
+// Repo is a repo batching its writes, which contributes its batch to the transactions.
+type Repo interface {
+	// BeginBatch batches the writes until CommitBatch, which writes, and syncs, them at once.
+	BeginBatch()
+	CommitBatch() error
+	BatchSize() int
+
+	// BatchRepr returns the writes batched since BeginBatch, encoded for ApplyBatch, or nil if there are none.
+	BatchRepr() []byte
+
+	// ApplyBatch writes, and syncs, the writes encoded by BatchRepr. Writing them again is a no-op.
+	ApplyBatch(repr []byte) error
+}
+
+// Flusher is a repo, of which the writes aren't batched, but made durable before the batches are committed.
+// Its writes past the last transaction committed have to be tolerated on recovery.
+type Flusher interface {
+	Flush() error
+}
+
+type participant struct {
+	name    string
+	repo    Repo
+	flusher Flusher
+}
+
+// Coordinator commits the batches of the repos registered with it.
+type Coordinator struct {
+	journal  string
+	repos    []participant
+	flushers []participant
+}
+
+// New returns a Coordinator writing its journal to the file at path.
+func New(path string) *Coordinator {
+	return &Coordinator{journal: path}
+}
+
+// Register adds the repo to the transactions. The batches are committed in the order the repos are registered,
+// so the one holding the last height committed, which the rest are recovered to, is registered last.
+func (c *Coordinator) Register(name string, repo Repo) {
+	c.repos = append(c.repos, participant{name: name, repo: repo})
+}
+
+// RegisterFlusher adds the repo to be flushed before the batches are committed.
+func (c *Coordinator) RegisterFlusher(name string, f Flusher) {
+	c.flushers = append(c.flushers, participant{name: name, flusher: f})
+}
+
+// Txn is the writes batched by the repos since it began. Only one is open at a time.
+type Txn struct {
+	c *Coordinator
+}
+
+// Begin batches the writes of the repos until the Txn is committed.
+func (c *Coordinator) Begin() *Txn {
+	for _, p := range c.repos {
+		p.repo.BeginBatch()
+	}
+	return &Txn{c: c}
+}
+
+// Size returns the bytes batched by the repos.
+func (txn *Txn) Size() int64 {
+	var size int64
+	for _, p := range txn.c.repos {
+		size += int64(p.repo.BatchSize())
+	}
+	return size
+}
+
+// Commit flushes the flushers, and commits the batches of the repos in two phases. The batches are
+// written to the journal, which marks the Txn committed once it's synced, and then to the repos.
+// The journal is removed after. If any of them fails in between, Recover writes them all again.
+func (txn *Txn) Commit() error {
+
+	c := txn.c
+	for _, p := range c.flushers {
+		err := p.flusher.Flush()
+		if err != nil {
+			return fmt.Errorf("flush %s repo: %w", p.name, err)
+		}
+	}
+
+	reprs := make([][]byte, len(c.repos))
+	for i, p := range c.repos {
+		reprs[i] = p.repo.BatchRepr()
+	}
+	err := c.writeJournal(reprs)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range c.repos {
+		err = p.repo.CommitBatch()
+		if err != nil {
+			return fmt.Errorf("commit %s batch: %w", p.name, err) // recovered from the journal
+		}
+	}
+
+	err = os.Remove(c.journal)
+	if err != nil {
+		return fmt.Errorf("remove journal: %w", err)
+	}
+
+	return nil
+}
+
+// Recover writes the batches of the last Txn again, if it was marked committed, but its journal wasn't
+// removed, as the repos may have committed some of them only. It reports whether there was one.
+// It has to be called before the repos are read.
+func (c *Coordinator) Recover() (bool, error) {
+
+	os.Remove(c.journal + ".tmp") // nolint : errchk (the Txn wasn't marked committed)
+
+	b, err := os.ReadFile(c.journal)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read journal: %w", err)
+	}
+
+	reprs, err := c.parseJournal(b)
+	if err != nil {
+		return false, err
+	}
+	for i, p := range c.repos {
+		if len(reprs[i]) == 0 {
+			continue
+		}
+		err = p.repo.ApplyBatch(reprs[i])
+		if err != nil {
+			return false, fmt.Errorf("apply %s batch: %w", p.name, err)
+		}
+	}
+
+	err = os.Remove(c.journal)
+	if err != nil {
+		return false, fmt.Errorf("remove journal: %w", err)
+	}
+
+	return true, nil
+}
+
+// writeJournal writes the names of the repos and their batches, followed by their SHA256,
+// to a temporary file, which is synced, and renamed to the journal atomically.
+func (c *Coordinator) writeJournal(reprs [][]byte) error {
+
+	var buf bytes.Buffer
+	var n [binary.MaxVarintLen64]byte
+	for i, p := range c.repos {
+		buf.Write(n[:binary.PutUvarint(n[:], uint64(len(p.name)))])
+		buf.WriteString(p.name)
+		buf.Write(n[:binary.PutUvarint(n[:], uint64(len(reprs[i])))])
+		buf.Write(reprs[i])
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	buf.Write(sum[:])
+
+	tmp := c.journal + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("create journal: %w", err)
+	}
+	_, err = f.Write(buf.Bytes())
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write journal: %w", err)
+	}
+
+	err = os.Rename(tmp, c.journal)
+	if err != nil {
+		return fmt.Errorf("rename journal: %w", err)
+	}
+
+	return syncDir(filepath.Dir(c.journal))
+}
+
+// parseJournal returns the batches in the journal, in the order of the repos registered.
+func (c *Coordinator) parseJournal(b []byte) ([][]byte, error) {
+
+	if len(b) < sha256.Size {
+		return nil, fmt.Errorf("%w: %d bytes", ErrCorruptJournal, len(b))
+	}
+	body, sum := b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+	if s := sha256.Sum256(body); !bytes.Equal(s[:], sum) {
+		return nil, fmt.Errorf("%w: checksum", ErrCorruptJournal)
+	}
+
+	byName := map[string][]byte{}
+	r := bytes.NewReader(body)
+	readBytes := func() ([]byte, error) {
+		size, err := binary.ReadUvarint(r)
+		if err != nil || size > uint64(r.Len()) {
+			return nil, fmt.Errorf("%w: length", ErrCorruptJournal)
+		}
+		v := make([]byte, size)
+		_, err = io.ReadFull(r, v)
+		return v, err
+	}
+	for r.Len() > 0 {
+		name, err := readBytes()
+		if err != nil {
+			return nil, err
+		}
+		repr, err := readBytes()
+		if err != nil {
+			return nil, err
+		}
+		byName[string(name)] = repr
+	}
+
+	reprs := make([][]byte, len(c.repos))
+	for i, p := range c.repos {
+		repr, ok := byName[p.name]
+		if !ok {
+			return nil, fmt.Errorf("%w: no batch of the %s repo", ErrCorruptJournal, p.name)
+		}
+		reprs[i] = repr
+		delete(byName, p.name)
+	}
+	for name := range byName {
+		return nil, fmt.Errorf("%w: batch of an unknown %s repo", ErrCorruptJournal, name)
+	}
+
+	return reprs, nil
+}
+
+func syncDir(dir string) error {
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open journal dir: %w", err)
+	}
+	defer d.Close()
+
+	err = d.Sync()
+	if err != nil {
+		return fmt.Errorf("sync journal dir: %w", err)
+	}
+
+	return nil
+}
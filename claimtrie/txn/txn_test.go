@@ -0,0 +1,120 @@
+package txn
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo keeps its writes in memory, which are lost along with the batch on a crash.
+type memRepo struct {
+	committed map[string]string
+	batch     map[string]string
+}
+
+func (m *memRepo) Set(key, value string) {
+	if m.batch != nil {
+		m.batch[key] = value
+		return
+	}
+	m.committed[key] = value
+}
+
+func (m *memRepo) BeginBatch() {
+	m.batch = map[string]string{}
+}
+
+func (m *memRepo) CommitBatch() error {
+	for k, v := range m.batch {
+		m.committed[k] = v
+	}
+	m.batch = nil
+	return nil
+}
+
+func (m *memRepo) BatchSize() int {
+	return len(m.BatchRepr())
+}
+
+func (m *memRepo) BatchRepr() []byte {
+	if len(m.batch) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(m.batch)
+	return b
+}
+
+func (m *memRepo) ApplyBatch(repr []byte) error {
+	var batch map[string]string
+	err := json.Unmarshal(repr, &batch)
+	for k, v := range batch {
+		m.committed[k] = v
+	}
+	return err
+}
+
+func TestRecover(t *testing.T) {
+
+	r := require.New(t)
+
+	journal := filepath.Join(t.TempDir(), "journal")
+	a, b := &memRepo{committed: map[string]string{}}, &memRepo{committed: map[string]string{}}
+	crash := func() *Coordinator {
+		a, b = &memRepo{committed: a.committed}, &memRepo{committed: b.committed}
+		c := New(journal)
+		c.Register("a", a)
+		c.Register("b", b)
+		return c
+	}
+
+	c := crash()
+	recovered, err := c.Recover()
+	r.NoError(err)
+	r.False(recovered)
+
+	txn := c.Begin()
+	a.Set("k", "1")
+	b.Set("k", "1")
+	r.NotZero(txn.Size())
+	r.NoError(txn.Commit())
+	r.NoFileExists(journal)
+	r.Equal("1", b.committed["k"])
+
+	// The commit is interrupted after the first repo, once it's marked in the journal.
+	c.Begin()
+	a.Set("k", "2")
+	b.Set("k", "2")
+	r.NoError(c.writeJournal([][]byte{a.BatchRepr(), b.BatchRepr()}))
+	r.NoError(a.CommitBatch())
+
+	c = crash()
+	recovered, err = c.Recover()
+	r.NoError(err)
+	r.True(recovered)
+	r.NoFileExists(journal)
+	r.Equal("2", a.committed["k"])
+	r.Equal("2", b.committed["k"])
+
+	// It's discarded, if it isn't marked yet.
+	c.Begin()
+	a.Set("k", "3")
+	b.Set("k", "3")
+	r.NoError(c.writeJournal([][]byte{a.BatchRepr(), b.BatchRepr()}))
+	r.NoError(os.Rename(journal, journal+".tmp"))
+
+	c = crash()
+	recovered, err = c.Recover()
+	r.NoError(err)
+	r.False(recovered)
+	r.NoFileExists(journal + ".tmp")
+	r.Equal("2", a.committed["k"])
+	r.Equal("2", b.committed["k"])
+
+	// A corrupt journal isn't applied.
+	r.NoError(os.WriteFile(journal, []byte("corrupt journal of more than the length of a checksum"), 0644))
+	_, err = c.Recover()
+	r.ErrorIs(err, ErrCorruptJournal)
+}
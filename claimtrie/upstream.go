@@ -0,0 +1,44 @@
+package claimtrie
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/event"
+	"github.com/btcsuite/btcd/claimtrie/upstream"
+)
+
+// verifyUpstream verifies the root of the current height against the upstream checkpoint,
+// and the stored roots against the checkpoints published for the heights passed already.
+func (ct *ClaimTrie) verifyUpstream(root *chainhash.Hash) {
+
+	for _, cp := range ct.upstream.Unverified(ct.height) {
+		h, err := ct.blockRepo.Get(cp.Height)
+		if err != nil {
+			log.Warnf("Verify upstream checkpoint at %d: %s", cp.Height, err)
+			continue
+		}
+		ct.verifyUpstreamAt(cp.Height, h)
+	}
+	ct.verifyUpstreamAt(ct.height, root)
+}
+
+// verifyUpstreamAt counts, logs and emits a divergence of the root from the checkpoint at the height.
+func (ct *ClaimTrie) verifyUpstreamAt(height int32, root *chainhash.Hash) {
+
+	_, err := ct.upstream.Verify(height, root)
+	var divergence *upstream.DivergenceError
+	if !errors.As(err, &divergence) {
+		return
+	}
+
+	atomic.AddInt64(&ct.upstreamDivergences, 1)
+	log.Errorf("The ClaimTrie diverges from the network: %s", err)
+	ct.events.Publish(event.Event{
+		Type:         event.UpstreamDiverged,
+		Height:       height,
+		Root:         divergence.Root.String(),
+		UpstreamRoot: divergence.Upstream.String(),
+	})
+}
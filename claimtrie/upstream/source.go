@@ -0,0 +1,154 @@
+// Package upstream fetches the checkpoints of the roots of the ClaimTrie, which are signed
+// and published over HTTPS, and verifies the local roots against them.
+package upstream
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// ErrBadSignature is returned when the published checkpoints aren't signed by the key of the source.
+var ErrBadSignature = errors.New("bad signature of the checkpoints")
+
+// maxDocumentSize bounds the published document read.
+const maxDocumentSize = 16 << 20
+
+// Checkpoint is the root of the trie at a height, as published upstream.
+type Checkpoint struct {
+	Height int32
+	Root   chainhash.Hash
+}
+
+// document is the published JSON of the checkpoints, such as
+// {"checkpoints":[{"height":1000,"root":"<hex>"}],"signature":"<hex>"}.
+type document struct {
+	Checkpoints []checkpointJSON `json:"checkpoints"`
+	Signature   string           `json:"signature"` // DER encoded, over the Digest of the checkpoints.
+}
+
+type checkpointJSON struct {
+	Height int32  `json:"height"`
+	Root   string `json:"root"`
+}
+
+// Digest returns the hash signed for the checkpoints: the double SHA256 of their heights,
+// in big-endian, and roots, in the order they're listed.
+func Digest(cps []Checkpoint) chainhash.Hash {
+
+	b := make([]byte, len(cps)*(4+chainhash.HashSize))
+	for i, cp := range cps {
+		entry := b[i*(4+chainhash.HashSize):]
+		binary.BigEndian.PutUint32(entry, uint32(cp.Height))
+		copy(entry[4:], cp.Root[:])
+	}
+
+	return chainhash.DoubleHashH(b)
+}
+
+// Sign returns the published document of the checkpoints, signed by the key.
+func Sign(cps []Checkpoint, key *btcec.PrivateKey) ([]byte, error) {
+
+	digest := Digest(cps)
+	sig, err := key.Sign(digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+
+	doc := document{Signature: hex.EncodeToString(sig.Serialize())}
+	for _, cp := range cps {
+		doc.Checkpoints = append(doc.Checkpoints, checkpointJSON{Height: cp.Height, Root: cp.Root.String()})
+	}
+
+	return json.Marshal(doc)
+}
+
+// Source is the URL, at which the checkpoints signed by the key are published.
+type Source struct {
+	URL    string
+	PubKey *btcec.PublicKey
+	Client *http.Client
+}
+
+// NewSource returns the Source of the HTTPS URL, and the hex encoded public key.
+func NewSource(rawURL, pubKey string) (*Source, error) {
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("not an https url: %s", rawURL)
+	}
+
+	b, err := hex.DecodeString(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %w", err)
+	}
+	key, err := btcec.ParsePubKey(b, btcec.S256())
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+
+	return &Source{URL: rawURL, PubKey: key, Client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// Fetch returns the published checkpoints, in order by height, once their signature is verified.
+func (s *Source) Fetch() ([]Checkpoint, error) {
+
+	resp, err := s.Client.Get(s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get: status %d", resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize))
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	var doc document
+	err = json.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	cps := make([]Checkpoint, 0, len(doc.Checkpoints))
+	for _, c := range doc.Checkpoints {
+		root, err := chainhash.NewHashFromStr(c.Root)
+		if err != nil {
+			return nil, fmt.Errorf("decode root at %d: %w", c.Height, err)
+		}
+		cps = append(cps, Checkpoint{Height: c.Height, Root: *root})
+	}
+
+	b, err = hex.DecodeString(doc.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadSignature, err)
+	}
+	sig, err := btcec.ParseDERSignature(b, btcec.S256())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadSignature, err)
+	}
+	digest := Digest(cps)
+	if !sig.Verify(digest[:], s.PubKey) {
+		return nil, ErrBadSignature
+	}
+
+	sort.Slice(cps, func(i, j int) bool { return cps[i].Height < cps[j].Height })
+
+	return cps, nil
+}
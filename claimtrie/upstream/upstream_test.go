@@ -0,0 +1,77 @@
+package upstream
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier(t *testing.T) {
+
+	r := require.New(t)
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	r.NoError(err)
+	other, err := btcec.NewPrivateKey(btcec.S256())
+	r.NoError(err)
+
+	cps := []Checkpoint{{Height: 20, Root: chainhash.Hash{2}}, {Height: 10, Root: chainhash.Hash{1}}}
+	doc, err := Sign(cps, key)
+	r.NoError(err)
+	forged, err := Sign(cps, other)
+	r.NoError(err)
+
+	body := doc
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write(body) // nolint : errchk
+	}))
+	defer srv.Close()
+
+	_, err = NewSource("http://example.com", "02")
+	r.Error(err)
+	source, err := NewSource(srv.URL, "00")
+	r.Error(err)
+	source = &Source{URL: srv.URL, PubKey: key.PubKey(), Client: srv.Client()}
+
+	fetched, err := source.Fetch()
+	r.NoError(err)
+	r.Equal([]Checkpoint{cps[1], cps[0]}, fetched)
+
+	body = forged
+	_, err = source.Fetch()
+	r.ErrorIs(err, ErrBadSignature)
+	body = doc
+
+	v := NewVerifier(source, time.Hour)
+	defer v.Close()
+	r.Eventually(func() bool { return len(v.Unverified(30)) == 2 }, 10*time.Second, 10*time.Millisecond)
+	r.Equal([]Checkpoint{cps[1]}, v.Unverified(20))
+
+	ok, err := v.Verify(15, &chainhash.Hash{9})
+	r.NoError(err)
+	r.False(ok)
+
+	ok, err = v.Verify(10, &chainhash.Hash{1})
+	r.NoError(err)
+	r.True(ok)
+
+	ok, err = v.Verify(20, &chainhash.Hash{9})
+	r.True(ok)
+	var divergence *DivergenceError
+	r.ErrorAs(err, &divergence)
+	r.Equal(chainhash.Hash{2}, divergence.Upstream)
+	r.Empty(v.Unverified(30))
+	r.Equal(int32(20), v.Latest())
+
+	// The known checkpoints keep their roots.
+	v.Add([]Checkpoint{{Height: 20, Root: chainhash.Hash{9}}, {Height: 30, Root: chainhash.Hash{3}}})
+	r.Equal([]Checkpoint{{Height: 30, Root: chainhash.Hash{3}}}, v.Unverified(40))
+	_, err = v.Verify(20, &chainhash.Hash{2})
+	r.NoError(err)
+}
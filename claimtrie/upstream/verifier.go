@@ -0,0 +1,146 @@
+package upstream
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// DivergenceError is returned when a local root differs from the upstream checkpoint.
+type DivergenceError struct {
+	Height   int32
+	Root     chainhash.Hash
+	Upstream chainhash.Hash
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("root at height %d diverges from upstream: %s, upstream: %s", e.Height, e.Root, e.Upstream)
+}
+
+// Verifier verifies the roots against the checkpoints, which are refreshed from the source
+// every interval in the background.
+type Verifier struct {
+	source   *Source
+	interval time.Duration
+
+	mu      sync.Mutex
+	roots   map[int32]chainhash.Hash
+	pending []int32 // Heights of the checkpoints not verified yet, in order.
+	latest  int32   // Height of the last checkpoint verified, whether the root matched or not.
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// NewVerifier returns a Verifier, which starts fetching the checkpoints from the source.
+func NewVerifier(source *Source, interval time.Duration) *Verifier {
+
+	v := &Verifier{
+		source:   source,
+		interval: interval,
+		roots:    map[int32]chainhash.Hash{},
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go v.run()
+
+	return v
+}
+
+func (v *Verifier) run() {
+	defer close(v.done)
+	for {
+		cps, err := v.source.Fetch()
+		if err != nil {
+			log.Warnf("Fetch upstream checkpoints from %s: %s", v.source.URL, err)
+		} else {
+			v.Add(cps)
+		}
+		select {
+		case <-v.quit:
+			return
+		case <-time.After(v.interval):
+		}
+	}
+}
+
+// Add merges the checkpoints into the known ones. A height already known keeps its root.
+func (v *Verifier) Add(cps []Checkpoint) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	added := false
+	for _, cp := range cps {
+		if root, ok := v.roots[cp.Height]; ok {
+			if root != cp.Root {
+				log.Warnf("Upstream checkpoint at %d changed from %s to %s; keeping the former", cp.Height, root, cp.Root)
+			}
+			continue
+		}
+		v.roots[cp.Height] = cp.Root
+		v.pending = append(v.pending, cp.Height)
+		added = true
+	}
+	if added {
+		sort.Slice(v.pending, func(i, j int) bool { return v.pending[i] < v.pending[j] })
+	}
+}
+
+// Unverified returns the checkpoints below the height, which weren't verified yet.
+func (v *Verifier) Unverified(below int32) []Checkpoint {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var cps []Checkpoint
+	for _, height := range v.pending {
+		if height >= below {
+			break
+		}
+		cps = append(cps, Checkpoint{Height: height, Root: v.roots[height]})
+	}
+
+	return cps
+}
+
+// Verify returns a DivergenceError, if the root differs from the checkpoint at the height.
+// It returns false if there's no checkpoint at the height.
+func (v *Verifier) Verify(height int32, root *chainhash.Hash) (bool, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	upstream, ok := v.roots[height]
+	if !ok {
+		return false, nil
+	}
+
+	i := sort.Search(len(v.pending), func(i int) bool { return v.pending[i] >= height })
+	if i < len(v.pending) && v.pending[i] == height {
+		v.pending = append(v.pending[:i], v.pending[i+1:]...)
+	}
+	if height > v.latest {
+		v.latest = height
+	}
+
+	if upstream != *root {
+		return true, &DivergenceError{Height: height, Root: *root, Upstream: upstream}
+	}
+
+	return true, nil
+}
+
+// Latest returns the height of the last checkpoint verified.
+func (v *Verifier) Latest() int32 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.latest
+}
+
+// Close stops fetching the checkpoints.
+func (v *Verifier) Close() error {
+	close(v.quit)
+	<-v.done
+	return nil
+}
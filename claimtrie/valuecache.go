@@ -0,0 +1,119 @@
+package claimtrie
+
+import (
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/param"
+)
+
+// valueCache is a read-through ValueStore of the leaf hashes of the MerkleTrie.
+// The names are refreshed, or invalidated, as the ClaimTrie marks them dirty, so the trie
+// is hashed without loading the nodes again.
+type valueCache struct {
+	mu     sync.Mutex
+	store  merkletrie.ValueStore
+	size   int
+	hashes map[string]*chainhash.Hash
+	claims map[string][]*chainhash.Hash
+	misses int64
+}
+
+func newValueCache(store merkletrie.ValueStore, size int) *valueCache {
+	return &valueCache{
+		store:  store,
+		size:   size,
+		hashes: map[string]*chainhash.Hash{},
+		claims: map[string][]*chainhash.Hash{},
+	}
+}
+
+func (vc *valueCache) Hash(name []byte) *chainhash.Hash {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if h, ok := vc.hashes[string(name)]; ok {
+		return h
+	}
+	vc.misses++
+	h := vc.store.Hash(name)
+	vc.evict()
+	vc.hashes[string(name)] = h
+
+	return h
+}
+
+func (vc *valueCache) ClaimHashes(name []byte) []*chainhash.Hash {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if hs, ok := vc.claims[string(name)]; ok {
+		return hs
+	}
+	vc.misses++
+	hs := vc.store.ClaimHashes(name)
+	vc.evict()
+	vc.claims[string(name)] = hs
+
+	return hs
+}
+
+// refresh replaces the cached value of the dirty name with the one hashed at the height,
+// while its node is still cached by the node manager.
+func (vc *valueCache) refresh(name []byte, height int32) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	delete(vc.hashes, string(name))
+	delete(vc.claims, string(name))
+	if height >= param.AllClaimsInMerkleForkHeight {
+		vc.evict()
+		vc.claims[string(name)] = vc.store.ClaimHashes(name)
+	} else {
+		vc.evict()
+		vc.hashes[string(name)] = vc.store.Hash(name)
+	}
+}
+
+// invalidate drops the cached values of the names, which are read through on their next hash.
+func (vc *valueCache) invalidate(names [][]byte) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	for _, name := range names {
+		delete(vc.hashes, string(name))
+		delete(vc.claims, string(name))
+	}
+}
+
+// reset drops all the cached values.
+func (vc *valueCache) reset() {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	vc.hashes = map[string]*chainhash.Hash{}
+	vc.claims = map[string][]*chainhash.Hash{}
+}
+
+// Misses returns how many values were read through from the store.
+func (vc *valueCache) Misses() int64 {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	return vc.misses
+}
+
+// evict drops an arbitrary entry, once the cache holds its size.
+func (vc *valueCache) evict() {
+	if len(vc.hashes)+len(vc.claims) < vc.size {
+		return
+	}
+	for k := range vc.hashes {
+		delete(vc.hashes, k)
+		return
+	}
+	for k := range vc.claims {
+		delete(vc.claims, k)
+		return
+	}
+}
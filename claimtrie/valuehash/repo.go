@@ -0,0 +1,25 @@
+package valuehash
+
+import "github.com/btcsuite/btcd/chaincfg/chainhash"
+
+// Entry is the leaf value hash of a name in the trie as of the height; Hash is nil, once the name has no value.
+type Entry struct {
+	Height int32
+	Hash   *chainhash.Hash
+}
+
+// Repo defines APIs for the audit index of the leaf value hashes of the names by the heights, to access
+// persistence layer.
+type Repo interface {
+	// Set records the hashes of the names at the height, unless they're the same as the ones recorded before it.
+	Set(names [][]byte, hashes []*chainhash.Hash, height int32) error
+	// Rewind drops the hashes of the names after height.
+	Rewind(names [][]byte, height int32) error
+
+	// HashAt returns the hash of the name as of the height, and whether any was recorded at, or before, it.
+	HashAt(name []byte, height int32) (*chainhash.Hash, bool, error)
+	// History returns the hashes recorded for the name, oldest first.
+	History(name []byte) ([]Entry, error)
+
+	Close() error
+}
@@ -0,0 +1,163 @@
+package valuehashrepo
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/valuehash"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Key format:
+//
+//	len(2B) + name + height(4B): the leaf value hash(32B) of the name as of the height, or none, once it has no value.
+type Pebble struct {
+	db *pebble.DB
+}
+
+func NewPebble(path string) (*Pebble, error) {
+
+	db, err := pebble.Open(path, &pebble.Options{Cache: pebble.NewCache(16 << 20)})
+	if err != nil {
+		return nil, fmt.Errorf("pebble open %s, %w", path, err)
+	}
+
+	repo := &Pebble{db: db}
+
+	return repo, nil
+}
+
+func key(name []byte, height int32) []byte {
+	k := make([]byte, 2+len(name)+4)
+	binary.BigEndian.PutUint16(k, uint16(len(name)))
+	copy(k[2:], name)
+	binary.BigEndian.PutUint32(k[2+len(name):], uint32(height))
+	return k
+}
+
+func decodeHash(value []byte) *chainhash.Hash {
+	if len(value) == 0 {
+		return nil
+	}
+	var h chainhash.Hash
+	copy(h[:], value)
+	return &h
+}
+
+// hashAt returns the hash of the name as of the height, and whether any is recorded.
+func hashAt(r pebble.Reader, name []byte, height int32) (*chainhash.Hash, bool, error) {
+
+	iter := r.NewIter(&pebble.IterOptions{LowerBound: key(name, 0), UpperBound: key(name, height+1)})
+	found := iter.Last()
+	var h *chainhash.Hash
+	if found {
+		h = decodeHash(iter.Value())
+	}
+	err := iter.Close()
+	if err != nil {
+		return nil, false, fmt.Errorf("pebble iter: %w", err)
+	}
+
+	return h, found, nil
+}
+
+func sameHash(a, b *chainhash.Hash) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (repo *Pebble) Set(names [][]byte, hashes []*chainhash.Hash, height int32) error {
+
+	batch := repo.db.NewIndexedBatch()
+	defer batch.Close()
+
+	for i, name := range names {
+		prev, ok, err := hashAt(batch, name, height)
+		if err != nil {
+			return err
+		}
+		h := hashes[i]
+		if (ok && sameHash(prev, h)) || (!ok && h == nil) {
+			continue
+		}
+		var value []byte
+		if h != nil {
+			value = h[:]
+		}
+		err = batch.Set(key(name, height), value, pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble set: %w", err)
+		}
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+func (repo *Pebble) Rewind(names [][]byte, height int32) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	for _, name := range names {
+		err := batch.DeleteRange(key(name, height+1), key(name, -1), pebble.NoSync)
+		if err != nil {
+			return fmt.Errorf("pebble delete range: %w", err)
+		}
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
+
+func (repo *Pebble) HashAt(name []byte, height int32) (*chainhash.Hash, bool, error) {
+	return hashAt(repo.db, name, height)
+}
+
+func (repo *Pebble) History(name []byte) ([]valuehash.Entry, error) {
+
+	iter := repo.db.NewIter(&pebble.IterOptions{LowerBound: key(name, 0), UpperBound: key(name, -1)})
+
+	var entries []valuehash.Entry
+	for iter.First(); iter.Valid(); iter.Next() {
+		entries = append(entries, valuehash.Entry{
+			Height: int32(binary.BigEndian.Uint32(iter.Key()[2+len(name):])),
+			Hash:   decodeHash(iter.Value()),
+		})
+	}
+
+	err := iter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("pebble iter: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Backup writes a consistent copy of the repo, as of its last write, to the dir, which must not exist.
+func (repo *Pebble) Backup(dir string) error {
+
+	err := repo.db.Checkpoint(dir, pebble.WithFlushedWAL())
+	if err != nil {
+		return fmt.Errorf("pebble checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Pebble) Close() error {
+
+	err := repo.db.Flush()
+	if err != nil {
+		return fmt.Errorf("pebble flush: %w", err)
+	}
+
+	err = repo.db.Close()
+	if err != nil {
+		return fmt.Errorf("pebble close: %w", err)
+	}
+
+	return nil
+}
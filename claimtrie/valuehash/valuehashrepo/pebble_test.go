@@ -0,0 +1,49 @@
+package valuehashrepo
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/valuehash"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueHashes(t *testing.T) {
+
+	r := require.New(t)
+
+	repo, err := NewPebble(t.TempDir())
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	// The hashes unchanged, and the names without any value before, aren't recorded.
+	a, b := []byte("a"), []byte("b")
+	h1, h2 := chainhash.HashH([]byte{1}), chainhash.HashH([]byte{2})
+	r.NoError(repo.Set([][]byte{a, b}, []*chainhash.Hash{&h1, nil}, 1))
+	r.NoError(repo.Set([][]byte{a, b}, []*chainhash.Hash{&h1, &h2}, 2))
+	r.NoError(repo.Set([][]byte{a}, []*chainhash.Hash{&h2}, 4))
+	r.NoError(repo.Set([][]byte{a}, []*chainhash.Hash{nil}, 5))
+
+	entries, err := repo.History(a)
+	r.NoError(err)
+	r.Equal([]valuehash.Entry{{Height: 1, Hash: &h1}, {Height: 4, Hash: &h2}, {Height: 5}}, entries)
+
+	h, ok, err := repo.HashAt(a, 3)
+	r.NoError(err)
+	r.True(ok)
+	r.Equal(h1, *h)
+	_, ok, err = repo.HashAt(b, 1)
+	r.NoError(err)
+	r.False(ok)
+
+	// The hashes after the height are dropped on rewind.
+	r.NoError(repo.Rewind([][]byte{a}, 4))
+	h, ok, err = repo.HashAt(a, 10)
+	r.NoError(err)
+	r.True(ok)
+	r.Equal(h2, *h)
+}
@@ -0,0 +1,138 @@
+package claimtrie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/claimtrie/valuehash"
+)
+
+// ErrValueHashesNotIndexed is returned by the queries of the value hashes, unless they're indexed.
+var ErrValueHashesNotIndexed = errors.New("value hashes aren't indexed")
+
+// ValueHashAt returns the leaf value hash of the name in the trie as of the height, which is nil without a value,
+// so that the hashes of the name can be compared across the heights, and the nodes, without recomputing them.
+// It's recorded as the blocks are appended, or backfilled by BackfillValueHashes.
+func (ct *ClaimTrie) ValueHashAt(name []byte, height int32) (*chainhash.Hash, error) {
+
+	if ct.valueHashes == nil {
+		return nil, ErrValueHashesNotIndexed
+	}
+
+	h, _, err := ct.valueHashes.HashAt(node.NormalizeIfNecessary(name, height), height)
+	if err != nil {
+		return nil, fmt.Errorf("value hash repo hash at: %w", err)
+	}
+
+	return h, nil
+}
+
+// ValueHashHistory returns the leaf value hashes of the name, at the heights they changed at, oldest first.
+// The name is looked up as is, so the history of the one normalized starts at the normalization fork.
+func (ct *ClaimTrie) ValueHashHistory(name []byte) ([]valuehash.Entry, error) {
+
+	if ct.valueHashes == nil {
+		return nil, ErrValueHashesNotIndexed
+	}
+
+	return ct.valueHashes.History(name)
+}
+
+// BackfillValueHashes records the value hashes of the names updated by the blocks within the heights, inclusive,
+// which were appended before they were indexed, and returns the number of the names recorded. The names updated
+// by the blocks have to be still in the temporal repo, which drops them past the MaxReorgDepth, if it's set.
+func (ct *ClaimTrie) BackfillValueHashes(from, to int32) (int, error) {
+
+	if ct.valueHashes == nil {
+		return 0, ErrValueHashesNotIndexed
+	}
+	if to > ct.height {
+		to = ct.height
+	}
+	if ct.maxReorgDepth > 0 && from <= ct.height-ct.maxReorgDepth {
+		return 0, fmt.Errorf("%w: the names updated before %d are dropped", ErrNotRetained, ct.height-ct.maxReorgDepth+1)
+	}
+
+	recorded := 0
+	for height := from; height <= to; height++ {
+		names, err := ct.temporalRepo.NodesAt(height)
+		if err != nil {
+			return recorded, fmt.Errorf("temporal repo nodes at: %w", err)
+		}
+		names = removeDuplicates(names)
+		hashes := make([]*chainhash.Hash, len(names))
+		for i, name := range names {
+			n, err := ct.nodeManager.NodeAt(height, name)
+			if err != nil {
+				return recorded, fmt.Errorf("node %q at %d: %w", name, height, err)
+			}
+			hashes[i] = leafHash(n, height)
+		}
+		err = ct.valueHashes.Set(names, hashes, height)
+		if err != nil {
+			return recorded, fmt.Errorf("value hash repo set: %w", err)
+		}
+		recorded += len(names)
+	}
+
+	return recorded, nil
+}
+
+// recordValueHashes records the value hashes of the names updated by the block, or all of them at the
+// all-claims fork, of which the hashes all change.
+func (ct *ClaimTrie) recordValueHashes(names [][]byte) error {
+
+	if ct.height == param.AllClaimsInMerkleForkHeight {
+		names = nil
+		ct.nodeManager.IterateNames(func(name []byte) bool {
+			names = append(names, append([]byte(nil), name...))
+			return true
+		})
+	}
+
+	hashes := make([]*chainhash.Hash, len(names))
+	for i, name := range names {
+		n, err := ct.nodeManager.Node(name)
+		if err != nil {
+			return fmt.Errorf("node %q: %w", name, err)
+		}
+		hashes[i] = leafHash(n, ct.height)
+	}
+
+	return ct.valueHashes.Set(names, hashes, ct.height)
+}
+
+// leafHash returns the value hash of the node in the trie at the height, which commits to its best claim,
+// or all of its claims after the all-claims fork.
+func leafHash(n *node.Node, height int32) *chainhash.Hash {
+	if n == nil {
+		return nil
+	}
+	if height >= param.AllClaimsInMerkleForkHeight {
+		return merkletrie.ClaimsHash(claimHashes(n))
+	}
+	return bestClaimHash(n)
+}
+
+// bestClaimHash returns the value hash of the best claim of the node, if it's activated.
+func bestClaimHash(n *node.Node) *chainhash.Hash {
+	if n.BestClaim != nil && n.BestClaim.Status == node.Activated {
+		return node.CalculateNodeHash(n.BestClaim.OutPoint, n.TakenOverAt)
+	}
+	return nil
+}
+
+// claimHashes returns the value hashes of the activated claims of the node, in order.
+func claimHashes(n *node.Node) []*chainhash.Hash {
+	var hashes []*chainhash.Hash
+	for _, c := range n.SortedClaims() {
+		if c.Status == node.Activated {
+			hashes = append(hashes, node.CalculateNodeHash(c.OutPoint, n.TakenOverAt))
+		}
+	}
+	return hashes
+}
@@ -0,0 +1,197 @@
+package claimtrie
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie/merkletrie"
+)
+
+// The names scheduled to be updated within this many blocks are warmed up.
+const warmupBlocks = 10
+
+// snapshotSaver saves the snapshots of the nodes on demand.
+type snapshotSaver interface {
+	ReplayCost(name []byte, height int32) (int, error)
+	SaveSnapshot(name []byte, height int32) error
+}
+
+// idleWarmer warms up the names scheduled to be updated by the next blocks, once no block
+// was appended for a while, as the node is likely caught up to the tip by then: it snapshots
+// their nodes, which replay the most changes first, and reads the stored trie nodes along
+// their paths, so that the next block, however busy, materializes and hashes them cheaply.
+type idleWarmer struct {
+	ct        *ClaimTrie
+	idle      time.Duration
+	snapshots snapshotSaver // nil, unless the nodes are snapshotted.
+	now       func() time.Time
+
+	// Held while a name is warmed up, and for the changes to the ClaimTrie, which stamp lastActive.
+	mu         sync.Mutex
+	lastActive time.Time
+	warmedAt   int32 // The height last warmed up at, so each is only once.
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newIdleWarmer(ct *ClaimTrie, idle time.Duration, snapshots snapshotSaver) *idleWarmer {
+
+	w := &idleWarmer{
+		ct:        ct,
+		idle:      idle,
+		snapshots: snapshots,
+		now:       time.Now,
+		quit:      make(chan struct{}),
+	}
+	w.lastActive = w.now()
+
+	w.wg.Add(1)
+	go w.run()
+
+	return w
+}
+
+// holdChanges holds the backups, and pauses the warm up, until the returned func is called, for changing
+// the ClaimTrie.
+func (ct *ClaimTrie) holdChanges() func() {
+
+	ct.changing.Lock()
+	if ct.warmer == nil {
+		return ct.changing.Unlock
+	}
+
+	w := ct.warmer
+	w.mu.Lock()
+	w.lastActive = w.now()
+	return func() {
+		w.lastActive = w.now()
+		w.mu.Unlock()
+		ct.changing.Unlock()
+	}
+}
+
+func (w *idleWarmer) run() {
+
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.quit:
+			return
+		case <-time.After(w.idle):
+		}
+		w.warm()
+	}
+}
+
+// idleSince reports whether no change was made since the time, and for long enough. It must be called with mu held.
+func (w *idleWarmer) idleSince(t time.Time) bool {
+	return !w.lastActive.After(t) && w.now().Sub(w.lastActive) >= w.idle
+}
+
+// warm warms up the names scheduled after the last block committed, until a change is made.
+func (w *idleWarmer) warm() {
+
+	w.mu.Lock()
+	start := w.now()
+	s := w.ct.Snapshot()
+	if !w.idleSince(start) || s.height == w.warmedAt || s.height == 0 {
+		w.mu.Unlock()
+		return
+	}
+	names, err := w.scheduled(s.height)
+	w.mu.Unlock()
+	if err != nil {
+		log.Warnf("Warming up at %d: %s", s.height, err)
+		return
+	}
+
+	// step runs fn with mu held, unless a change was made since the start.
+	step := func(fn func() error) bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if !w.idleSince(start) {
+			return false
+		}
+		err := fn()
+		if err != nil {
+			log.Warnf("Warming up at %d: %s", s.height, err)
+			return false
+		}
+		return true
+	}
+
+	snapshotted := 0
+	if w.snapshots != nil {
+		costs := make([]int, len(names))
+		for i := range names {
+			if !step(func() (err error) { costs[i], err = w.snapshots.ReplayCost(names[i], s.height); return err }) {
+				return
+			}
+		}
+		order := make([]int, len(names))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool { return costs[order[i]] > costs[order[j]] })
+		for _, i := range order {
+			if costs[i] == 0 {
+				break
+			}
+			if !step(func() error { return w.snapshots.SaveSnapshot(names[i], s.height) }) {
+				return
+			}
+			snapshotted++
+		}
+	}
+
+	view := w.ct.merkleTrie.At(&s.root)
+	for _, name := range names {
+		ok := step(func() error {
+			err := view.Walk(name, 0, func(*merkletrie.RawNode) error { return nil })
+			if errors.Is(err, merkletrie.ErrNodeNotFound) {
+				return nil // a new name, of which the path isn't stored in full yet
+			}
+			return err
+		})
+		if !ok {
+			return
+		}
+	}
+
+	w.mu.Lock()
+	w.warmedAt = s.height
+	w.mu.Unlock()
+	log.Debugf("Warmed up %d names due within %d blocks of %d, snapshotted %d, in %s",
+		len(names), warmupBlocks, s.height, snapshotted, time.Since(start))
+}
+
+// scheduled returns the names scheduled to be updated by the blocks after the height.
+func (w *idleWarmer) scheduled(height int32) ([][]byte, error) {
+
+	var names [][]byte
+	seen := map[string]bool{}
+	for h := height + 1; h <= height+warmupBlocks; h++ {
+		due, err := w.ct.temporalRepo.NodesAt(h)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range due {
+			if !seen[string(name)] {
+				seen[string(name)] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// stop stops the warm up, and waits for it to return.
+func (w *idleWarmer) stop() error {
+	close(w.quit)
+	w.wg.Wait()
+	return nil
+}
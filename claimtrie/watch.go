@@ -0,0 +1,172 @@
+package claimtrie
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/btcsuite/btcd/claimtrie/event"
+	"github.com/btcsuite/btcd/claimtrie/node"
+)
+
+// maxWatchedEvents is the number of the most recent events retained for each watched name.
+const maxWatchedEvents = 256
+
+// WatchedName is the retained state of a watched name.
+type WatchedName struct {
+	Name []byte
+
+	// Snapshot of the node as of UpdatedAt, the last height it was updated at.
+	// It's nil if the name has no claims or supports.
+	Node      *node.Node
+	UpdatedAt int32
+
+	// The most recent events about the name, in the order they were emitted.
+	Events []event.Event
+}
+
+type watcher struct {
+	mu          sync.Mutex
+	names       map[string]*WatchedName
+	unsubscribe func()
+}
+
+// Watch registers the names, of which the events and the node states are retained
+// regardless of the node cache, until they are unwatched.
+func (ct *ClaimTrie) Watch(names [][]byte) error {
+
+	ct.watcher.mu.Lock()
+	defer ct.watcher.mu.Unlock()
+
+	for _, name := range names {
+		if _, ok := ct.watcher.names[string(name)]; ok {
+			continue
+		}
+		w := &WatchedName{Name: name}
+		err := ct.snapshot(w)
+		if err != nil {
+			return fmt.Errorf("snapshot %s: %w", name, err)
+		}
+		ct.watcher.names[string(name)] = w
+	}
+
+	if ct.watcher.unsubscribe == nil && len(ct.watcher.names) > 0 {
+		ct.watcher.unsubscribe = ct.events.Subscribe(ct.retainWatchedEvent)
+	}
+
+	return nil
+}
+
+// Unwatch drops the names, and their retained states.
+func (ct *ClaimTrie) Unwatch(names [][]byte) {
+
+	ct.watcher.mu.Lock()
+	defer ct.watcher.mu.Unlock()
+
+	for _, name := range names {
+		delete(ct.watcher.names, string(name))
+	}
+
+	if ct.watcher.unsubscribe != nil && len(ct.watcher.names) == 0 {
+		ct.watcher.unsubscribe()
+		ct.watcher.unsubscribe = nil
+	}
+}
+
+// WatchedState returns copies of the retained states of the watched names, in order by name.
+func (ct *ClaimTrie) WatchedState() []WatchedName {
+
+	ct.watcher.mu.Lock()
+	defer ct.watcher.mu.Unlock()
+
+	states := make([]WatchedName, 0, len(ct.watcher.names))
+	for _, w := range ct.watcher.names {
+		state := *w
+		if w.Node != nil {
+			state.Node = w.Node.Clone()
+		}
+		state.Events = append([]event.Event(nil), w.Events...)
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return bytes.Compare(states[i].Name, states[j].Name) < 0
+	})
+
+	return states
+}
+
+// snapshotWatched refreshes the snapshots of the watched names among the updated ones.
+func (ct *ClaimTrie) snapshotWatched(updated [][]byte) error {
+
+	ct.watcher.mu.Lock()
+	defer ct.watcher.mu.Unlock()
+
+	if len(ct.watcher.names) == 0 {
+		return nil
+	}
+
+	for _, w := range ct.watcher.names {
+		key := node.NormalizeIfNecessary(w.Name, ct.height)
+		for _, name := range updated {
+			if !bytes.Equal(key, name) {
+				continue
+			}
+			err := ct.snapshot(w)
+			if err != nil {
+				return fmt.Errorf("snapshot %s: %w", w.Name, err)
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// refreshWatched refreshes the snapshots of all the watched names, e.g. after a reorg.
+func (ct *ClaimTrie) refreshWatched() error {
+
+	ct.watcher.mu.Lock()
+	defer ct.watcher.mu.Unlock()
+
+	for _, w := range ct.watcher.names {
+		err := ct.snapshot(w)
+		if err != nil {
+			return fmt.Errorf("snapshot %s: %w", w.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (ct *ClaimTrie) snapshot(w *WatchedName) error {
+
+	n, err := ct.nodeManager.Node(node.NormalizeIfNecessary(w.Name, ct.height))
+	if err != nil {
+		return err
+	}
+
+	w.Node = nil
+	if n != nil {
+		w.Node = n.Clone()
+	}
+	w.UpdatedAt = ct.height
+
+	return nil
+}
+
+func (ct *ClaimTrie) retainWatchedEvent(e event.Event) {
+
+	ct.watcher.mu.Lock()
+	defer ct.watcher.mu.Unlock()
+
+	for _, w := range ct.watcher.names {
+		if !bytes.Equal(node.NormalizeIfNecessary(w.Name, e.Height), e.Name) {
+			continue
+		}
+		w.Events = append(w.Events, e)
+		if len(w.Events) > maxWatchedEvents {
+			w.Events = append(w.Events[:0], w.Events[len(w.Events)-maxWatchedEvents:]...)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie/event"
+)
+
+// Hook is an endpoint, to which the matching events are POSTed.
+type Hook struct {
+	URL string
+
+	Types    []event.Type // Event types to deliver; all of them if empty.
+	Names    [][]byte     // Watched names.
+	Channels []string     // ClaimIDs of watched channels.
+}
+
+// matches reports whether the event is of the hook's types, and is about one of
+// its watched names or channels. Hooks without any watches match any event.
+func (h *Hook) matches(e event.Event) bool {
+
+	if len(h.Types) > 0 {
+		found := false
+		for _, t := range h.Types {
+			found = found || t == e.Type
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(h.Names) == 0 && len(h.Channels) == 0 {
+		return true
+	}
+	for _, name := range h.Names {
+		if bytes.Equal(name, e.Name) {
+			return true
+		}
+	}
+	for _, ch := range h.Channels {
+		if e.Channel != "" && ch == e.Channel {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Options struct {
+	QueueSize  int           // Deliveries beyond it are dropped.
+	MaxRetries int           // Retries of a failed delivery.
+	Backoff    time.Duration // Delay before the first retry, doubled on each of the following ones.
+	Timeout    time.Duration // Timeout of each request.
+}
+
+var DefaultOptions = Options{
+	QueueSize:  1024,
+	MaxRetries: 5,
+	Backoff:    time.Second,
+	Timeout:    10 * time.Second,
+}
+
+// Payload is the JSON body POSTed for an event.
+type Payload struct {
+	Type     string `json:"type"`
+	Height   int32  `json:"height"`
+	Name     string `json:"name"`
+	ClaimID  string `json:"claimId,omitempty"`
+	OutPoint string `json:"outPoint,omitempty"`
+	Amount   int64  `json:"amount,omitempty"`
+	Channel  string `json:"channel,omitempty"`
+	ExpireAt int32  `json:"expireAt,omitempty"`
+
+	Stage     string `json:"stage,omitempty"`
+	ElapsedMs int64  `json:"elapsedMs,omitempty"`
+	BudgetMs  int64  `json:"budgetMs,omitempty"`
+
+	Root         string `json:"root,omitempty"`
+	UpstreamRoot string `json:"upstreamRoot,omitempty"`
+
+	PrevOwner string `json:"prevOwner,omitempty"`
+	Owner     string `json:"owner,omitempty"`
+
+	Contenders []Contender `json:"contenders,omitempty"`
+}
+
+// Contender is a claim for the name at a takeover, in a Payload.
+type Contender struct {
+	ClaimID         string `json:"claimId"`
+	OutPoint        string `json:"outPoint"`
+	Amount          int64  `json:"amount"`
+	EffectiveAmount int64  `json:"effectiveAmount"`
+	AcceptedAt      int32  `json:"acceptedAt"`
+	ActiveAt        int32  `json:"activeAt"`
+}
+
+func newPayload(e event.Event) Payload {
+	p := Payload{
+		Type:     e.Type.String(),
+		Height:   e.Height,
+		Name:     string(e.Name),
+		ClaimID:  e.ClaimID,
+		OutPoint: e.OutPoint,
+		Amount:   e.Amount,
+		Channel:  e.Channel,
+		ExpireAt: e.ExpireAt,
+
+		Stage:     e.Stage,
+		ElapsedMs: e.Elapsed.Milliseconds(),
+		BudgetMs:  e.Budget.Milliseconds(),
+
+		Root:         e.Root,
+		UpstreamRoot: e.UpstreamRoot,
+
+		PrevOwner: e.PrevOwner,
+		Owner:     e.Owner,
+	}
+	for _, c := range e.Contenders {
+		p.Contenders = append(p.Contenders, Contender(c))
+	}
+	return p
+}
+
+type delivery struct {
+	url  string
+	body []byte
+}
+
+// Dispatcher delivers the events to the hooks in the background, in the order they're handled.
+type Dispatcher struct {
+	hooks  []Hook
+	opts   Options
+	client *http.Client
+
+	queue chan delivery
+	quit  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// New returns a Dispatcher, of which Handle is meant to be subscribed to the ClaimTrie.
+func New(hooks []Hook, opts Options) *Dispatcher {
+
+	d := &Dispatcher{
+		hooks:  hooks,
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		queue:  make(chan delivery, opts.QueueSize),
+		quit:   make(chan struct{}),
+	}
+
+	d.wg.Add(1)
+	go d.run()
+
+	return d
+}
+
+// Handle queues the event for the matching hooks. It doesn't block.
+func (d *Dispatcher) Handle(e event.Event) {
+
+	var body []byte
+	for i := range d.hooks {
+		h := &d.hooks[i]
+		if !h.matches(e) {
+			continue
+		}
+		if body == nil {
+			var err error
+			body, err = json.Marshal(newPayload(e))
+			if err != nil {
+				log.Errorf("webhook marshal %s event: %s", e.Type, err)
+				return
+			}
+		}
+		select {
+		case <-d.quit:
+			return
+		case d.queue <- delivery{url: h.URL, body: body}:
+		default:
+			log.Warnf("webhook queue is full, dropping %s event of %q for %s", e.Type, e.Name, h.URL)
+		}
+	}
+}
+
+// Close stops the delivery. The pending deliveries are abandoned.
+func (d *Dispatcher) Close() error {
+	close(d.quit)
+	d.wg.Wait()
+	return nil
+}
+
+func (d *Dispatcher) run() {
+
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-d.quit:
+			return
+		case del := <-d.queue:
+			d.deliver(del)
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(del delivery) {
+
+	backoff := d.opts.Backoff
+	for attempt := 0; ; attempt++ {
+
+		err := d.post(del)
+		if err == nil {
+			return
+		}
+		if attempt >= d.opts.MaxRetries {
+			log.Warnf("webhook delivery to %s failed after %d attempts: %s", del.url, attempt+1, err)
+			return
+		}
+
+		select {
+		case <-d.quit:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (d *Dispatcher) post(del delivery) error {
+
+	resp, err := d.client.Post(del.url, "application/json", bytes.NewReader(del.body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return nil
+}
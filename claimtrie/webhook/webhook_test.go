@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie/event"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcher(t *testing.T) {
+
+	r := require.New(t)
+
+	var mu sync.Mutex
+	var received []Payload
+	failures := 1
+	done := make(chan struct{}, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var p Payload
+		r.NoError(json.NewDecoder(req.Body).Decode(&p))
+		received = append(received, p)
+		done <- struct{}{}
+	}))
+	defer srv.Close()
+
+	hooks := []Hook{
+		{URL: srv.URL, Types: []event.Type{event.Takeover}, Names: [][]byte{[]byte("watched")}},
+		{URL: srv.URL, Types: []event.Type{event.ClaimAdded}, Channels: []string{"abcd"}},
+	}
+	opts := DefaultOptions
+	opts.Backoff = time.Millisecond
+	d := New(hooks, opts)
+	defer d.Close()
+
+	d.Handle(event.Event{Type: event.Takeover, Name: []byte("other"), Height: 1})
+	d.Handle(event.Event{Type: event.ClaimAdded, Name: []byte("watched"), Height: 2})
+	d.Handle(event.Event{Type: event.Takeover, Name: []byte("watched"), Height: 3, ClaimID: "1234"})
+	d.Handle(event.Event{Type: event.ClaimAdded, Name: []byte("any"), Height: 4, Channel: "abcd"})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			r.FailNow("timed out waiting for deliveries")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	r.Equal([]Payload{
+		{Type: "Takeover", Height: 3, Name: "watched", ClaimID: "1234"},
+		{Type: "ClaimAdded", Height: 4, Name: "any", Channel: "abcd"},
+	}, received)
+}
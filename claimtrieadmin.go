@@ -0,0 +1,255 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/claimtrie"
+)
+
+// claimTrieAdmin serves the runtime controls of the ClaimTrie over HTTP, on a TCP
+// address, which requires the basic auth of the RPC credentials, or a unix socket,
+// only the owner can connect to, if the address is a path:
+//
+//	POST /loglevel?level=debug[&subsystem=CLMT]
+//	POST /consistency?enabled=true
+//	POST /flush
+//	POST /rehash?name=<name>[&name=<name>...]
+//	GET  /stats
+//	GET  /progress
+//	GET  /tuning, with clmtbenchmark
+type claimTrieAdmin struct {
+	ct       *claimtrie.ClaimTrie
+	chain    *blockchain.BlockChain  // Set once it's created, after the ClaimTrie, before Start.
+	tuning   *claimtrie.TuningReport // The benchmark run at startup, if any.
+	authsha  *[sha256.Size]byte      // The hash of the Authorization header required, unless it's on a unix socket.
+	listener net.Listener
+	server   *http.Server
+}
+
+// The timeouts of the admin requests. The writes wait for the flushes and the rehashes, which are long.
+const (
+	claimTrieAdminReadTimeout  = 10 * time.Second
+	claimTrieAdminWriteTimeout = 10 * time.Minute
+)
+
+func newClaimTrieAdmin(addr, user, pass string, ct *claimtrie.ClaimTrie) (*claimTrieAdmin, error) {
+
+	a := &claimTrieAdmin{ct: ct}
+
+	network := "tcp"
+	if strings.ContainsRune(addr, os.PathSeparator) {
+		network = "unix"
+		os.Remove(addr) // left by an unclean shutdown
+	} else {
+		if user == "" || pass == "" {
+			return nil, errors.New("the ClaimTrie admin on a TCP address requires rpcuser and rpcpass, " +
+				"or it may listen on a unix socket path instead")
+		}
+		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+		authsha := sha256.Sum256([]byte(auth))
+		a.authsha = &authsha
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	if network == "unix" {
+		if err = os.Chmod(addr, 0600); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("restrict %s: %w", addr, err)
+		}
+	}
+	a.listener = listener
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/loglevel", a.post(a.handleLogLevel))
+	mux.HandleFunc("/consistency", a.post(a.handleConsistency))
+	mux.HandleFunc("/flush", a.post(a.handleFlush))
+	mux.HandleFunc("/rehash", a.post(a.handleRehash))
+	mux.HandleFunc("/stats", a.handleStats)
+	mux.HandleFunc("/progress", a.handleProgress)
+	mux.HandleFunc("/tuning", a.handleTuning)
+	a.server = &http.Server{
+		Handler:           a.authorized(mux),
+		ReadHeaderTimeout: claimTrieAdminReadTimeout,
+		ReadTimeout:       claimTrieAdminReadTimeout,
+		WriteTimeout:      claimTrieAdminWriteTimeout,
+	}
+
+	return a, nil
+}
+
+func (a *claimTrieAdmin) Start() {
+	clmtLog.Infof("ClaimTrie admin listening on %s", a.listener.Addr())
+	go func() {
+		err := a.server.Serve(a.listener)
+		if err != nil && err != http.ErrServerClosed {
+			clmtLog.Errorf("ClaimTrie admin: %v", err)
+		}
+	}()
+}
+
+func (a *claimTrieAdmin) Stop() {
+	a.server.Close()
+}
+
+// authorized rejects the requests without the Authorization header of the RPC credentials, if it's required.
+func (a *claimTrieAdmin) authorized(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.authsha != nil {
+			authsha := sha256.Sum256([]byte(r.Header.Get("Authorization")))
+			if subtle.ConstantTimeCompare(authsha[:], a.authsha[:]) != 1 {
+				clmtLog.Warnf("ClaimTrie admin: authentication failure from %s", r.RemoteAddr)
+				w.Header().Set("WWW-Authenticate", `Basic realm="ClaimTrie admin"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (a *claimTrieAdmin) post(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (a *claimTrieAdmin) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+
+	level := r.URL.Query().Get("level")
+	if !validLogLevel(level) {
+		http.Error(w, fmt.Sprintf("invalid log level: %q", level), http.StatusBadRequest)
+		return
+	}
+
+	subsystem := r.URL.Query().Get("subsystem")
+	if subsystem == "" {
+		setLogLevels(level)
+	} else if _, ok := subsystemLoggers[subsystem]; ok {
+		setLogLevel(subsystem, level)
+	} else {
+		http.Error(w, fmt.Sprintf("invalid subsystem: %q", subsystem), http.StatusBadRequest)
+		return
+	}
+
+	clmtLog.Infof("Log level of %q set to %s", subsystem, level)
+}
+
+func (a *claimTrieAdmin) handleConsistency(w http.ResponseWriter, r *http.Request) {
+
+	enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+	if err != nil {
+		http.Error(w, "invalid enabled", http.StatusBadRequest)
+		return
+	}
+
+	a.ct.SetConsistencyCheck(enabled)
+	clmtLog.Infof("Consistency check enabled: %v", enabled)
+}
+
+func (a *claimTrieAdmin) handleFlush(w http.ResponseWriter, r *http.Request) {
+
+	err := a.ct.Flush()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	clmtLog.Infof("Flushed the ClaimTrie repos")
+}
+
+func (a *claimTrieAdmin) handleRehash(w http.ResponseWriter, r *http.Request) {
+
+	var names [][]byte
+	for _, name := range r.URL.Query()["name"] {
+		names = append(names, []byte(name))
+	}
+	if len(names) == 0 {
+		http.Error(w, "no name", http.StatusBadRequest)
+		return
+	}
+
+	root, matched, err := a.chain.RehashClaimNames(names)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	clmtLog.Infof("Rehashed %q, root: %s, matches the best block: %v", names, root, matched)
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(struct {
+		Root    string `json:"root"`
+		Matched bool   `json:"matched"`
+	}{root.String(), matched})
+	if err != nil {
+		clmtLog.Errorf("ClaimTrie admin: encode rehash: %v", err)
+	}
+}
+
+func (a *claimTrieAdmin) handleStats(w http.ResponseWriter, r *http.Request) {
+
+	stats := a.ct.Stats()
+	clmtLog.Infof("ClaimTrie stats: %+v", stats)
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(stats)
+	if err != nil {
+		clmtLog.Errorf("ClaimTrie admin: encode stats: %v", err)
+	}
+}
+
+// handleProgress returns the progress of the running, or the last, run of each long-running operation.
+func (a *claimTrieAdmin) handleProgress(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(a.ct.Progress())
+	if err != nil {
+		clmtLog.Errorf("ClaimTrie admin: encode progress: %v", err)
+	}
+}
+
+// handleTuning returns the benchmark run at startup, and the settings suggested by it.
+func (a *claimTrieAdmin) handleTuning(w http.ResponseWriter, r *http.Request) {
+
+	if a.tuning == nil {
+		http.Error(w, "no benchmark was run; restart with --clmtbenchmark", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(a.tuning)
+	if err != nil {
+		clmtLog.Errorf("ClaimTrie admin: encode tuning: %v", err)
+	}
+}
+
+// logTuningReport logs the benchmark, and the settings suggested by it, once, at startup.
+func logTuningReport(t *claimtrie.TuningReport) {
+
+	b := t.Benchmark
+	clmtLog.Infof("ClaimTrie benchmark: %d CPUs, %.0f hashes/s, repo writes: %s, reads: %s, data dir: %d MiB, took %s",
+		b.CPUs, b.HashesPerSec, b.RepoWrite, b.RepoRead, b.DataDirBytes>>20, b.Took.Round(time.Millisecond))
+	if len(t.Recommendations) == 0 {
+		clmtLog.Infof("ClaimTrie benchmark: no change of the settings suggested")
+	}
+	for _, rec := range t.Recommendations {
+		clmtLog.Infof("ClaimTrie benchmark suggests %s: %s, instead of %s, as %s", rec.Setting, rec.Suggested, rec.Current, rec.Reason)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/btcsuite/btcd/claimtrie"
+)
+
+// claimTrieHealth serves the read-only probes of the ClaimTrie over HTTP, for the orchestration systems:
+//
+//	GET /healthz, which fails while the repos can't be read back.
+//	GET /readyz, which also fails while the ClaimTrie is more than maxLag blocks behind the best connected
+//	peer, or there's none.
+//
+// Both of them reply with a healthStatus.
+type claimTrieHealth struct {
+	ct       claimtrie.Queries
+	source   func() int32 // The best height of the connected peers, or 0 without any.
+	maxLag   int32
+	listener net.Listener
+	server   *http.Server
+}
+
+// healthStatus is the reply of the probes.
+type healthStatus struct {
+	Height        int32  `json:"height"`
+	Root          string `json:"root"`
+	SourceHeight  int32  `json:"sourceheight"`
+	Lag           int32  `json:"lag"`
+	LastBlockAt   int64  `json:"lastblockat,omitempty"` // Unix time.
+	LastBlockTook string `json:"lastblocktook,omitempty"`
+	RepoErr       string `json:"repoerr,omitempty"`
+	Healthy       bool   `json:"healthy"`
+	Ready         bool   `json:"ready"`
+}
+
+func newClaimTrieHealth(addr string, ct claimtrie.Queries, source func() int32, maxLag int32) (*claimTrieHealth, error) {
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+
+	h := &claimTrieHealth{ct: ct, source: source, maxLag: maxLag, listener: listener}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", h.get(func(s healthStatus) bool { return s.Healthy }))
+	mux.HandleFunc("/readyz", h.get(func(s healthStatus) bool { return s.Ready }))
+	h.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	return h, nil
+}
+
+func (h *claimTrieHealth) Start() {
+	clmtLog.Infof("ClaimTrie health probes listening on %s", h.listener.Addr())
+	go func() {
+		err := h.server.Serve(h.listener)
+		if err != nil && err != http.ErrServerClosed {
+			clmtLog.Errorf("ClaimTrie health: %v", err)
+		}
+	}()
+}
+
+func (h *claimTrieHealth) Stop() {
+	h.server.Close()
+}
+
+// get replies to the GET requests with the status, and 503 Service Unavailable, unless it passes.
+func (h *claimTrieHealth) get(passes func(healthStatus) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s := h.status()
+		w.Header().Set("Content-Type", "application/json")
+		if !passes(s) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		err := json.NewEncoder(w).Encode(s)
+		if err != nil {
+			clmtLog.Errorf("ClaimTrie health: encode status: %v", err)
+		}
+	}
+}
+
+func (h *claimTrieHealth) status() healthStatus {
+
+	health := h.ct.Health()
+	s := healthStatus{
+		Height:       health.Height,
+		Root:         health.Root,
+		SourceHeight: h.source(),
+		RepoErr:      health.RepoErr,
+		Healthy:      health.RepoErr == "",
+	}
+	if !health.LastBlockAt.IsZero() {
+		s.LastBlockAt = health.LastBlockAt.Unix()
+		s.LastBlockTook = health.LastBlockTook.String()
+	}
+	if s.SourceHeight > s.Height {
+		s.Lag = s.SourceHeight - s.Height
+	}
+	s.Ready = s.Healthy && s.SourceHeight > 0 && s.Lag <= h.maxLag
+
+	return s
+}
@@ -47,6 +47,10 @@ const (
 	defaultMaxRPCClients         = 10
 	defaultMaxRPCWebsockets      = 25
 	defaultMaxRPCConcurrentReqs  = 20
+	defaultMaxRPCClaims          = 1000
+	defaultRPCQueryTimeout       = time.Second * 10
+	defaultClaimTrieHistWait     = time.Second * 10
+	defaultClaimTrieReadyLag     = 6
 	defaultDbType                = "ffldb"
 	defaultFreeTxRelayLimit      = 15.0
 	defaultTrickleInterval       = peer.DefaultTrickleInterval
@@ -111,6 +115,60 @@ type config struct {
 	ClaimTrieImpl        string        `long:"clmtimpl" description:"Implementation of ClaimTrie"`
 	ClaimTrieRecord      bool          `long:"clmtrecord" description:"Record claim operations made to ClaimTrie"`
 	ClaimTrieHeight      uint32        `long:"clmtheight" description:"Reset height of ClaimTrie"`
+	ClaimTrieSlowBlock   time.Duration `long:"clmtslowblock" description:"Log blocks taking the ClaimTrie longer than this to process (0 to disable)"`
+	ClaimTrieSlowName    time.Duration `long:"clmtslowname" description:"Log names taking the ClaimTrie longer than this to resolve (0 to disable)"`
+	ClaimTrieChanStats   bool          `long:"clmtchannelstats" description:"Maintain the claim count and amount staked of each channel"`
+	ClaimTrieClaimIDs    bool          `long:"clmtclaimidindex" description:"Index the claims by their claim IDs"`
+	ClaimTrieCollisions  string        `long:"clmtidcollisions" description:"Policy of the claims sharing a claim ID, or a prefix of one, in the claim ID index: strict, which fails the lookup, but never the block, first, which returns the claim accepted first, or both, which returns all of them by outpoint (default strict)"`
+	ClaimTrieSearch      bool          `long:"clmtnamesearch" description:"Index the names by their tokens, for searchnames"`
+	ClaimTrieTopNames    bool          `long:"clmttopnames" description:"Rank the names by the effective amounts of their best claims, for gettopnames"`
+	ClaimTrieIdleWarmup  time.Duration `long:"clmtidlewarmup" description:"Once no block was processed for this long, warm up the names due in the next blocks in the background (0 to disable)"`
+	ClaimTrieGenesis     string        `long:"clmtgenesisclaims" description:"Add the claims and supports dumped to this file, in the COPY format of the chain repo, at height 0 of an empty ClaimTrie"`
+	ClaimTrieOutPoints   bool          `long:"clmtoutpointindex" description:"Index the claims and supports by their outpoints, for spending them by those alone"`
+	ClaimTrieValues      string        `long:"clmtvaluestorage" description:"Storage of the values of the claims: inline, in the node repo, blobs, in a repo of their own, fetched on demand, or hashes-only, which drops them, for resolving and proving the names only (default inline)"`
+	ClaimTrieValueBlob   int           `long:"clmtvalueblobsize" description:"Keep the values of at least this many bytes in the blobs, with clmtvaluestorage=blobs, and the smaller ones inline (0 for all)"`
+	ClaimTrieHistCache   int           `long:"clmthistorycache" description:"Cache the nodes of up to this many names at the heights resolved by the historical queries (0 to disable)"`
+	ClaimTrieVersions    int32         `long:"clmtnodeversions" description:"Keep the nodes changed in up to this many recent blocks, as of the heights of their changes, for the queries at the recent heights (0 to disable)"`
+	ClaimTrieHistWait    time.Duration `long:"clmthistorywait" description:"Fail the historical queries waiting longer than this for a reorg, or a prune, of the ClaimTrie to finish (0 to wait for it)"`
+	ClaimTrieBatchBlk    int32         `long:"clmtbatchblocks" description:"Batch the ClaimTrie writes across this many blocks while syncing, committing them at once (0 to disable)"`
+	ClaimTrieBatchSize   int64         `long:"clmtbatchsize" description:"Commit the ClaimTrie writes batched while syncing once they're over this many MiB, with clmtbatchblocks (0 for unbounded)"`
+	ClaimTriePrefetch    int           `long:"clmtprefetch" description:"Read up to this many trie nodes of the last block ahead of the next one, once the trie is dropped from memory in between (0 to disable)"`
+	ClaimTrieNodeCache   int64         `long:"clmtnodecache" description:"Cache the trie nodes read within this many MiB, across the blocks, once the trie is dropped from memory in between (0 to disable)"`
+	ClaimTrieKeyFile     string        `long:"clmtkeyfile" description:"Encrypt the values of the claims and supports at rest in the ClaimTrie repos with the AES-256 key in this file, raw or hex encoded"`
+	ClaimTrieAsyncWrite  int           `long:"clmtasyncwrites" description:"Write the trie nodes hashed by a block in the background, once over this many bytes, while the hashing goes on (0 to disable)"`
+	ClaimTrieMemory      int64         `long:"clmtmemory" description:"Keep the caches of ClaimTrie within this many MiB (0 for unbounded)"`
+	ClaimTrieActivity    bool          `long:"clmtnameactivity" description:"Index the names by the heights they were first seen, last active, and claimed, or abandoned, at"`
+	ClaimTrieValueHashes bool          `long:"clmtvaluehashes" description:"Index the value hashes of the names by the heights they changed at, for auditing the ClaimTrie"`
+	ClaimTrieHistory     bool          `long:"clmtclaimhistory" description:"Record the takeovers of the ClaimTrie names, for the queries of their best claims as of the heights"`
+	ClaimTrieStrict      bool          `long:"clmtstrictconflicts" description:"Reject the claims added with the TXO of existing ones, instead of keeping both"`
+	ClaimTrieStrictSpend bool          `long:"clmtstrictspends" description:"Reject the spends, and updates, of the claims and supports missing from the names, instead of logging them"`
+	ClaimTrieStrictOps   bool          `long:"clmtstrictscripts" description:"Reject the blocks with claim scripts of a version after the one known, and with invalid claim updates prior to their fork, instead of ignoring them"`
+	ClaimTrieVerifyRoots bool          `long:"clmtverifyroots" description:"Reject the blocks, of which the claim trie roots in their headers don't match the ones of the ClaimTrie, instead of logging the first mismatch"`
+	ClaimTrieCompact     int           `long:"clmtcompactafter" description:"Compact the ClaimTrie repos in the background after this many changes (0 to disable)"`
+	ClaimTrieRemote      string        `long:"clmttrieremote" description:"Address of a KV store speaking the remote KV protocol to back the trie with, instead of Pebble"`
+	ClaimTrieAdmin       string        `long:"clmtadmin" description:"Serve the ClaimTrie runtime controls on this address, with the basic auth of rpcuser and rpcpass, or unix socket path"`
+	ClaimTrieBenchmark   bool          `long:"clmtbenchmark" description:"Benchmark the hashing, and the repos in the data dir, at startup, and log the ClaimTrie settings suggested by it, which clmtadmin serves too"`
+	ClaimTrieHealth      string        `long:"clmthealth" description:"Serve the read-only health, and readiness, probes of the ClaimTrie, /healthz and /readyz, on this address"`
+	ClaimTrieReadyLag    int32         `long:"clmtreadylag" description:"Report the ClaimTrie as ready, with clmthealth, while it's at most this many blocks behind the best connected peer"`
+	ClaimTrieCompactWin  string        `long:"clmtcompactwindows" description:"Comma separated windows of the local time to compact in, such as 02:00-05:00 (any time if empty)"`
+	ClaimTrieNodeMgr     string        `long:"clmtnodemanager" description:"Strategy of materializing the ClaimTrie nodes: replay, or snapshot (default replay)"`
+	ClaimTrieReorg       int32         `long:"clmtmaxreorgdepth" description:"Refuse to reset the ClaimTrie further back than this many blocks, and prune the data kept for it (0 to disable)"`
+	ClaimTrieCrossVal    bool          `long:"clmtcrossvalidate" description:"Compare the root of the ClaimTrie with the one of an in-memory collapsed trie after each block, and halt on a divergence"`
+	ClaimTrieProfile     string        `long:"clmtprofile" description:"Preset the ClaimTrie caches, workers and background work for the hardware: desktop, server, or raspberry-pi (overridden by the other clmt options)"`
+	ClaimTrieBudgets     string        `long:"clmtbudgets" description:"Comma separated budgets of the stages of processing a block (block, nodes, trie, hash), such as block=500ms, exceeding which is logged, counted and emitted as an event"`
+	ClaimTrieTakeovers   bool          `long:"clmttakeoverdiag" description:"Log the runners-up of each claim takeover, with their amounts and heights, and attach them to the takeover events"`
+	ClaimTrieTakeoverRec bool          `long:"clmttakeoverrecord" description:"Also save the takeover diagnostics to a repo, queryable by name (implies clmttakeoverdiag)"`
+	ClaimTrieRetention   string        `long:"clmtretention" description:"Retention policy of the ClaimTrie change history: all, blocks (the last clmtretainblocks), or none, which fails the reorgs past the last prune (requires clmtnodemanager=snapshot)"`
+	ClaimTrieRetainBlk   int32         `long:"clmtretainblocks" description:"Number of blocks to retain the ClaimTrie changes of, with clmtretention=blocks"`
+	ClaimTrieCompactExp  int32         `long:"clmtcompactexpired" description:"Number of blocks after which the change histories of the ClaimTrie names left with no claims are replaced with tombstones, failing the reorgs past them (requires clmtnodemanager=snapshot)"`
+	ClaimTrieTrieRetain  int32         `long:"clmttrieretain" description:"Number of the last blocks, of which the ClaimTrie trie roots are kept by claimtrie prune, failing the reorgs past them (0 keeps all)"`
+	ClaimTrieDeltaSync   string        `long:"clmtdeltasync" description:"Serve the names dirtied by each block, and their leaf hashes, to the ClaimTrie followers on this address"`
+	ClaimTrieDeltaBlk    int           `long:"clmtdeltablocks" description:"Number of the last blocks to keep the deltas of, with clmtdeltasync (default 100)"`
+	ClaimTrieStandby     string        `long:"clmtstandby" description:"Serve the changes of each block, and its root, to the ClaimTrie standbys on this address"`
+	ClaimTrieStandbyBlk  int           `long:"clmtstandbyblocks" description:"Number of the last blocks to keep the changes of, with clmtstandby (default 100)"`
+	ClaimTrieUpstream    string        `long:"clmtupstream" description:"HTTPS URL of the signed checkpoints of the ClaimTrie roots, to verify the local ones against while syncing"`
+	ClaimTrieUpstreamKey string        `long:"clmtupstreamkey" description:"Hex encoded public key, which signs the checkpoints of clmtupstream"`
+	ClaimTrieMetrics     string        `long:"clmtmetrics" description:"Serve the ClaimTrie metrics in the Prometheus text format at /metrics on this address"`
 	ConnectPeers         []string      `long:"connect" description:"Connect only to the specified peers at startup"`
 	CPUProfile           string        `long:"cpuprofile" description:"Write CPU profile to the specified file"`
 	DataDir              string        `short:"b" long:"datadir" description:"Directory to store data"`
@@ -155,9 +213,11 @@ type config struct {
 	RPCLimitPass         string        `long:"rpclimitpass" default-mask:"-" description:"Password for limited RPC connections"`
 	RPCLimitUser         string        `long:"rpclimituser" description:"Username for limited RPC connections"`
 	RPCListeners         []string      `long:"rpclisten" description:"Add an interface/port to listen for RPC connections (default port: 8334, testnet: 18334)"`
+	RPCMaxClaims         int           `long:"rpcmaxclaims" description:"Max number of claims, or names, returned by a claim query RPC; the rest are truncated (0 for no limit)"`
 	RPCMaxClients        int           `long:"rpcmaxclients" description:"Max number of RPC clients for standard connections"`
 	RPCMaxConcurrentReqs int           `long:"rpcmaxconcurrentreqs" description:"Max number of concurrent RPC requests that may be processed concurrently"`
 	RPCMaxWebsockets     int           `long:"rpcmaxwebsockets" description:"Max number of RPC websocket connections"`
+	RPCQueryTimeout      time.Duration `long:"rpcquerytimeout" description:"Max time a claim query RPC may take before it's aborted (0 for no limit)"`
 	RPCQuirks            bool          `long:"rpcquirks" description:"Mirror some JSON-RPC quirks of Bitcoin Core -- NOTE: Discouraged unless interoperability issues need to be worked around"`
 	RPCPass              string        `short:"P" long:"rpcpass" default-mask:"-" description:"Password for RPC connections"`
 	RPCUser              string        `short:"u" long:"rpcuser" description:"Username for RPC connections"`
@@ -422,6 +482,8 @@ func loadConfig() (*config, []string, error) {
 		RPCMaxClients:        defaultMaxRPCClients,
 		RPCMaxWebsockets:     defaultMaxRPCWebsockets,
 		RPCMaxConcurrentReqs: defaultMaxRPCConcurrentReqs,
+		RPCMaxClaims:         defaultMaxRPCClaims,
+		RPCQueryTimeout:      defaultRPCQueryTimeout,
 		DataDir:              defaultDataDir,
 		LogDir:               defaultLogDir,
 		DbType:               defaultDbType,
@@ -440,6 +502,8 @@ func loadConfig() (*config, []string, error) {
 		Generate:             defaultGenerate,
 		TxIndex:              defaultTxIndex,
 		AddrIndex:            defaultAddrIndex,
+		ClaimTrieHistWait:    defaultClaimTrieHistWait,
+		ClaimTrieReadyLag:    defaultClaimTrieReadyLag,
 	}
 
 	// Service options which are only added on Windows.
@@ -803,6 +867,24 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	if cfg.RPCMaxClaims < 0 {
+		str := "%s: The rpcmaxclaims option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.RPCMaxClaims)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.RPCQueryTimeout < 0 {
+		str := "%s: The rpcquerytimeout option may not be less than 0 " +
+			"-- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.RPCQueryTimeout)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Validate the the minrelaytxfee.
 	cfg.minRelayTxFee, err = btcutil.NewAmount(cfg.MinRelayTxFee)
 	if err != nil {
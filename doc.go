@@ -127,12 +127,17 @@ Application Options:
       --rpclimituser=         Username for limited RPC connections
       --rpclisten=            Add an interface/port to listen for RPC
                               connections (default port: 8334, testnet: 18334)
+      --rpcmaxclaims=         Max number of claims, or names, returned by a claim
+                              query RPC; the rest are truncated (0 for no
+                              limit) (default: 1000)
       --rpcmaxclients=        Max number of RPC clients for standard
                               connections (default: 10)
       --rpcmaxconcurrentreqs= Max number of concurrent RPC requests that may be
                               processed concurrently (default: 20)
       --rpcmaxwebsockets=     Max number of RPC websocket connections (default:
                               25)
+      --rpcquerytimeout=      Max time a claim query RPC may take before it's
+                              aborted (0 for no limit) (default: 10s)
       --rpcquirks             Mirror some JSON-RPC quirks of Bitcoin Core --
                               NOTE: Discouraged unless interoperability issues
                               need to be worked around
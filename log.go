@@ -79,7 +79,6 @@ func init() {
 	connmgr.UseLogger(cmgrLog)
 	database.UseLogger(bcdbLog)
 	blockchain.UseLogger(chanLog)
-	claimtrie.UseLogger(clmtLog)
 	indexers.UseLogger(indxLog)
 	mining.UseLogger(minrLog)
 	cpuminer.UseLogger(minrLog)
@@ -87,6 +86,16 @@ func init() {
 	txscript.UseLogger(scrpLog)
 	netsync.UseLogger(syncLog)
 	mempool.UseLogger(txmpLog)
+
+	// The subsystems of the ClaimTrie, other than CLMT, get loggers of their own.
+	for subsystem, useLogger := range claimtrie.Subsystems {
+		logger, ok := subsystemLoggers[subsystem]
+		if !ok {
+			logger = backendLog.Logger(subsystem)
+			subsystemLoggers[subsystem] = logger
+		}
+		useLogger(logger)
+	}
 }
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
@@ -838,10 +838,19 @@ mempoolLoop:
 		}
 	}
 
+	// The claim trie root commits to the claims of the selected transactions.
+	block := btcutil.NewBlock(&msgBlock)
+	block.SetHeight(nextBlockHeight)
+	claimTrie, err := g.chain.PreviewClaimTrieRoot(block)
+	if err != nil {
+		return nil, err
+	}
+	msgBlock.Header.ClaimTrie = *claimTrie
+
 	// Finally, perform a full check on the created block against the chain
 	// consensus rules to ensure it properly connects to the current best
 	// chain with no issues.
-	block := btcutil.NewBlock(&msgBlock)
+	block = btcutil.NewBlock(&msgBlock)
 	block.SetHeight(nextBlockHeight)
 	if err := g.chain.CheckConnectBlockTemplate(block); err != nil {
 		return nil, err
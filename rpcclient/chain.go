@@ -685,6 +685,465 @@ func (c *Client) GetBlockHeaderVerbose(blockHash *chainhash.Hash) (*btcjson.GetB
 	return c.GetBlockHeaderVerboseAsync(blockHash).Receive()
 }
 
+// FutureGetBlockClaimRootResult is a future promise to deliver the result of a
+// GetBlockClaimRootAsync RPC invocation (or an applicable error).
+type FutureGetBlockClaimRootResult chan *response
+
+// Receive waits for the response promised by the future and returns the
+// claim trie root committed to by the block requested from the server.
+func (r FutureGetBlockClaimRootResult) Receive() (*btcjson.GetBlockClaimRootResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetBlockClaimRootResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetBlockClaimRootAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See GetBlockClaimRoot for the blocking version and more details.
+func (c *Client) GetBlockClaimRootAsync(blockHash *chainhash.Hash) FutureGetBlockClaimRootResult {
+	hash := ""
+	if blockHash != nil {
+		hash = blockHash.String()
+	}
+
+	cmd := btcjson.NewGetBlockClaimRootCmd(hash)
+	return c.sendCmd(cmd)
+}
+
+// GetBlockClaimRoot returns the root hash of the claim trie committed to by
+// the block with the given hash.
+func (c *Client) GetBlockClaimRoot(blockHash *chainhash.Hash) (*btcjson.GetBlockClaimRootResult, error) {
+	return c.GetBlockClaimRootAsync(blockHash).Receive()
+}
+
+// FutureGetNameProofResult is a future promise to deliver the result of a
+// GetNameProofAsync RPC invocation (or an applicable error).
+type FutureGetNameProofResult chan *response
+
+// Receive waits for the response promised by the future and returns the
+// proof of the best claim of the name as of the block requested from the server.
+func (r FutureGetNameProofResult) Receive() (*btcjson.GetNameProofResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetNameProofResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetNameProofAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See GetNameProof for the blocking version and more details.
+func (c *Client) GetNameProofAsync(name string, blockHash *chainhash.Hash) FutureGetNameProofResult {
+	var hash *string
+	if blockHash != nil {
+		hash = btcjson.String(blockHash.String())
+	}
+
+	cmd := btcjson.NewGetNameProofCmd(name, hash)
+	return c.sendCmd(cmd)
+}
+
+// GetNameProof returns the proof of the best claim of the name against the
+// root of the claim trie at the block with the given hash, or at the best
+// block if the hash is nil.
+func (c *Client) GetNameProof(name string, blockHash *chainhash.Hash) (*btcjson.GetNameProofResult, error) {
+	return c.GetNameProofAsync(name, blockHash).Receive()
+}
+
+// FutureResolveResult is a future promise to deliver the result of a
+// ResolveAsync RPC invocation (or an applicable error).
+type FutureResolveResult chan *response
+
+// Receive waits for the response promised by the future and returns the
+// claims of the name as of the block requested from the server.
+func (r FutureResolveResult) Receive() (*btcjson.ResolveResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.ResolveResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ResolveAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See Resolve for the blocking version and more details.
+func (c *Client) ResolveAsync(name string, blockHash *chainhash.Hash) FutureResolveResult {
+	var hash *string
+	if blockHash != nil {
+		hash = btcjson.String(blockHash.String())
+	}
+
+	cmd := btcjson.NewResolveCmd(name, hash)
+	return c.sendCmd(cmd)
+}
+
+// Resolve returns the claims of the name as of the block with the given hash,
+// or as of the best block if the hash is nil.
+func (c *Client) Resolve(name string, blockHash *chainhash.Hash) (*btcjson.ResolveResult, error) {
+	return c.ResolveAsync(name, blockHash).Receive()
+}
+
+// FutureGetActiveForksResult is a future promise to deliver the result of a
+// GetActiveForksAsync RPC invocation (or an applicable error).
+type FutureGetActiveForksResult chan *response
+
+// Receive waits for the response promised by the future and returns the forks
+// of the claim trie rules active at the height requested from the server.
+func (r FutureGetActiveForksResult) Receive() ([]btcjson.GetActiveForksResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []btcjson.GetActiveForksResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetActiveForksAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See GetActiveForks for the blocking version and more details.
+func (c *Client) GetActiveForksAsync(height *int32) FutureGetActiveForksResult {
+	cmd := btcjson.NewGetActiveForksCmd(height)
+	return c.sendCmd(cmd)
+}
+
+// GetActiveForks returns the forks of the claim trie rules active at the
+// height, or at the best block if the height is nil.
+func (c *Client) GetActiveForks(height *int32) ([]btcjson.GetActiveForksResult, error) {
+	return c.GetActiveForksAsync(height).Receive()
+}
+
+// FutureBackupClaimTrieResult is a future promise to deliver the result of a
+// BackupClaimTrieAsync RPC invocation (or an applicable error).
+type FutureBackupClaimTrieResult chan *response
+
+// Receive waits for the response promised by the future and returns the path
+// of the backup written, and the block it is as of.
+func (r FutureBackupClaimTrieResult) Receive() (*btcjson.BackupClaimTrieResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.BackupClaimTrieResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// BackupClaimTrieAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See BackupClaimTrie for the blocking version and more details.
+func (c *Client) BackupClaimTrieAsync() FutureBackupClaimTrieResult {
+	cmd := btcjson.NewBackupClaimTrieCmd()
+	return c.sendCmd(cmd)
+}
+
+// BackupClaimTrie writes a consistent copy of the repos of the claim trie to
+// a directory on the server, and returns its path.
+func (c *Client) BackupClaimTrie() (*btcjson.BackupClaimTrieResult, error) {
+	return c.BackupClaimTrieAsync().Receive()
+}
+
+// FutureExportClaimsResult is a future promise to deliver the result of an
+// ExportClaimsAsync RPC invocation (or an applicable error).
+type FutureExportClaimsResult chan *response
+
+// Receive waits for the response promised by the future and returns the path
+// of the export written, and the block it is as of.
+func (r FutureExportClaimsResult) Receive() (*btcjson.ExportClaimsResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.ExportClaimsResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ExportClaimsAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See ExportClaims for the blocking version and more details.
+func (c *Client) ExportClaimsAsync(order *string) FutureExportClaimsResult {
+	cmd := btcjson.NewExportClaimsCmd(order)
+	return c.sendCmd(cmd)
+}
+
+// ExportClaims writes the claims of all the names to a file on the server,
+// sorted by claim ID, or by name, and returns its path.
+func (c *Client) ExportClaims(order *string) (*btcjson.ExportClaimsResult, error) {
+	return c.ExportClaimsAsync(order).Receive()
+}
+
+// FutureResolveNameResult is a future promise to deliver the result of a
+// ResolveNameAsync RPC invocation (or an applicable error).
+type FutureResolveNameResult chan *response
+
+// Receive waits for the response promised by the future and returns the state of the name
+// as of the last block of the claimtrie server.
+func (r FutureResolveNameResult) Receive() (*btcjson.ResolveResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.ResolveResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ResolveNameAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See ResolveName for the blocking version and more details.
+func (c *Client) ResolveNameAsync(name string) FutureResolveNameResult {
+	cmd := btcjson.NewResolveNameCmd(name)
+	return c.sendCmd(cmd)
+}
+
+// ResolveName resolves the name on a claimtrie server, as of its last block.
+func (c *Client) ResolveName(name string) (*btcjson.ResolveResult, error) {
+	return c.ResolveNameAsync(name).Receive()
+}
+
+// FutureGetClaimsForNameResult is a future promise to deliver the result of a
+// GetClaimsForNameAsync RPC invocation (or an applicable error).
+type FutureGetClaimsForNameResult chan *response
+
+// Receive waits for the response promised by the future and returns the claims, and
+// supports, of the name as of the last block of the claimtrie server.
+func (r FutureGetClaimsForNameResult) Receive() (*btcjson.GetClaimsForNameResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetClaimsForNameResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetClaimsForNameAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See GetClaimsForName for the blocking version and more details.
+func (c *Client) GetClaimsForNameAsync(name string) FutureGetClaimsForNameResult {
+	cmd := btcjson.NewGetClaimsForNameCmd(name)
+	return c.sendCmd(cmd)
+}
+
+// GetClaimsForName returns the claims, and supports, of the name from a claimtrie
+// server.
+func (c *Client) GetClaimsForName(name string) (*btcjson.GetClaimsForNameResult, error) {
+	return c.GetClaimsForNameAsync(name).Receive()
+}
+
+// FutureGetValueForNameResult is a future promise to deliver the result of a
+// GetValueForNameAsync RPC invocation (or an applicable error).
+type FutureGetValueForNameResult chan *response
+
+// Receive waits for the response promised by the future and returns the best claim of the
+// name as of the last block of the claimtrie server.
+func (r FutureGetValueForNameResult) Receive() (*btcjson.GetValueForNameResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetValueForNameResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetValueForNameAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See GetValueForName for the blocking version and more details.
+func (c *Client) GetValueForNameAsync(name string) FutureGetValueForNameResult {
+	cmd := btcjson.NewGetValueForNameCmd(name)
+	return c.sendCmd(cmd)
+}
+
+// GetValueForName returns the best claim of the name from a claimtrie server.
+func (c *Client) GetValueForName(name string) (*btcjson.GetValueForNameResult, error) {
+	return c.GetValueForNameAsync(name).Receive()
+}
+
+// FutureGetClaimByIDResult is a future promise to deliver the result of a
+// GetClaimByIDAsync RPC invocation (or an applicable error).
+type FutureGetClaimByIDResult chan *response
+
+// Receive waits for the response promised by the future and returns the claims with the
+// claim ID, along with their names.
+func (r FutureGetClaimByIDResult) Receive() ([]btcjson.GetClaimByIDResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []btcjson.GetClaimByIDResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetClaimByIDAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See GetClaimByID for the blocking version and more details.
+func (c *Client) GetClaimByIDAsync(claimID string) FutureGetClaimByIDResult {
+	cmd := btcjson.NewGetClaimByIDCmd(claimID)
+	return c.sendCmd(cmd)
+}
+
+// GetClaimByID returns the claims with the claim ID from a claimtrie server,
+// which indexes them.
+func (c *Client) GetClaimByID(claimID string) ([]btcjson.GetClaimByIDResult, error) {
+	return c.GetClaimByIDAsync(claimID).Receive()
+}
+
+// FutureGetTopNamesResult is a future promise to deliver the result of a
+// GetTopNamesAsync RPC invocation (or an applicable error).
+type FutureGetTopNamesResult chan *response
+
+// Receive waits for the response promised by the future and returns the names
+// with the highest effective amounts of their best claims, in order.
+func (r FutureGetTopNamesResult) Receive() ([]btcjson.GetTopNamesResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []btcjson.GetTopNamesResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetTopNamesAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See GetTopNames for the blocking version and more details.
+func (c *Client) GetTopNamesAsync(count *int) FutureGetTopNamesResult {
+	cmd := btcjson.NewGetTopNamesCmd(count)
+	return c.sendCmd(cmd)
+}
+
+// GetTopNames returns up to count names with the highest effective amounts
+// of their best claims, or 10 if the count is nil.
+func (c *Client) GetTopNames(count *int) ([]btcjson.GetTopNamesResult, error) {
+	return c.GetTopNamesAsync(count).Receive()
+}
+
+// FutureSearchNamesResult is a future promise to deliver the result of a
+// SearchNamesAsync RPC invocation (or an applicable error).
+type FutureSearchNamesResult chan *response
+
+// Receive waits for the response promised by the future and returns the names
+// found, in order.
+func (r FutureSearchNamesResult) Receive() ([]string, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SearchNamesAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See SearchNames for the blocking version and more details.
+func (c *Client) SearchNamesAsync(query string, substring *bool, after *string, count *int) FutureSearchNamesResult {
+	cmd := btcjson.NewSearchNamesCmd(query, substring, after, count)
+	return c.sendCmd(cmd)
+}
+
+// SearchNames returns up to count names, or 10 if the count is nil, of which
+// the tokens start with, or contain, if substring is set, the ones of the query,
+// after the name after, if it's not nil.
+func (c *Client) SearchNames(query string, substring *bool, after *string, count *int) ([]string, error) {
+	return c.SearchNamesAsync(query, substring, after, count).Receive()
+}
+
 // FutureGetMempoolEntryResult is a future promise to deliver the result of a
 // GetMempoolEntryAsync RPC invocation (or an applicable error).
 type FutureGetMempoolEntryResult chan *response
@@ -882,3 +882,50 @@ func (c *Client) DecodeScriptAsync(serializedScript []byte) FutureDecodeScriptRe
 func (c *Client) DecodeScript(serializedScript []byte) (*btcjson.DecodeScriptResult, error) {
 	return c.DecodeScriptAsync(serializedScript).Receive()
 }
+
+// FutureValidateClaimTxResult is a future promise to deliver the result of a
+// ValidateClaimTxAsync RPC invocation (or an applicable error).
+type FutureValidateClaimTxResult chan *response
+
+// Receive waits for the response promised by the future and returns whether
+// the claim operations of the transaction would be accepted.
+func (r FutureValidateClaimTxResult) Receive() (*btcjson.ValidateClaimTxResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.ValidateClaimTxResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ValidateClaimTxAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See ValidateClaimTx for the blocking version and more details.
+func (c *Client) ValidateClaimTxAsync(tx *wire.MsgTx) FutureValidateClaimTxResult {
+	txHex := ""
+	if tx != nil {
+		// Serialize the transaction and convert to hex string.
+		buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+		if err := tx.Serialize(buf); err != nil {
+			return newFutureError(err)
+		}
+		txHex = hex.EncodeToString(buf.Bytes())
+	}
+
+	cmd := btcjson.NewValidateClaimTxCmd(txHex)
+	return c.sendCmd(cmd)
+}
+
+// ValidateClaimTx reports whether the claim operations of the transaction
+// would be accepted in the next block, without broadcasting it.
+func (c *Client) ValidateClaimTx(tx *wire.MsgTx) (*btcjson.ValidateClaimTxResult, error) {
+	return c.ValidateClaimTxAsync(tx).Receive()
+}
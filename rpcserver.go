@@ -7,6 +7,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
@@ -21,6 +22,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -33,6 +35,10 @@ import (
 	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie"
+	"github.com/btcsuite/btcd/claimtrie/node"
+	"github.com/btcsuite/btcd/claimtrie/param"
+	"github.com/btcsuite/btcd/claimtrie/policy"
 	"github.com/btcsuite/btcd/database"
 	"github.com/btcsuite/btcd/mempool"
 	"github.com/btcsuite/btcd/mining"
@@ -131,12 +137,15 @@ type commandHandler func(*rpcServer, interface{}, <-chan struct{}) (interface{},
 var rpcHandlers map[string]commandHandler
 var rpcHandlersBeforeInit = map[string]commandHandler{
 	"addnode":                handleAddNode,
+	"backupclaimtrie":        handleBackupClaimTrie,
 	"createrawtransaction":   handleCreateRawTransaction,
 	"debuglevel":             handleDebugLevel,
 	"decoderawtransaction":   handleDecodeRawTransaction,
 	"decodescript":           handleDecodeScript,
 	"estimatefee":            handleEstimateFee,
+	"exportclaims":           handleExportClaims,
 	"generate":               handleGenerate,
+	"getactiveforks":         handleGetActiveForks,
 	"getaddednodeinfo":       handleGetAddedNodeInfo,
 	"getbestblock":           handleGetBestBlock,
 	"getbestblockhash":       handleGetBestBlockHash,
@@ -144,6 +153,7 @@ var rpcHandlersBeforeInit = map[string]commandHandler{
 	"getblockchaininfo":      handleGetBlockChainInfo,
 	"getblockcount":          handleGetBlockCount,
 	"getblockhash":           handleGetBlockHash,
+	"getblockclaimroot":      handleGetBlockClaimRoot,
 	"getblockheader":         handleGetBlockHeader,
 	"getblocktemplate":       handleGetBlockTemplate,
 	"getcfilter":             handleGetCFilter,
@@ -158,15 +168,19 @@ var rpcHandlersBeforeInit = map[string]commandHandler{
 	"getmempoolinfo":         handleGetMempoolInfo,
 	"getmininginfo":          handleGetMiningInfo,
 	"getnettotals":           handleGetNetTotals,
+	"getnameproof":           handleGetNameProof,
 	"getnetworkhashps":       handleGetNetworkHashPS,
 	"getnodeaddresses":       handleGetNodeAddresses,
 	"getpeerinfo":            handleGetPeerInfo,
 	"getrawmempool":          handleGetRawMempool,
 	"getrawtransaction":      handleGetRawTransaction,
+	"gettopnames":            handleGetTopNames,
 	"gettxout":               handleGetTxOut,
 	"help":                   handleHelp,
 	"node":                   handleNode,
 	"ping":                   handlePing,
+	"resolve":                handleResolve,
+	"searchnames":            handleSearchNames,
 	"searchrawtransactions":  handleSearchRawTransactions,
 	"sendrawtransaction":     handleSendRawTransaction,
 	"setgenerate":            handleSetGenerate,
@@ -175,6 +189,7 @@ var rpcHandlersBeforeInit = map[string]commandHandler{
 	"submitblock":            handleSubmitBlock,
 	"uptime":                 handleUptime,
 	"validateaddress":        handleValidateAddress,
+	"validateclaimtx":        handleValidateClaimTx,
 	"verifychain":            handleVerifyChain,
 	"verifymessage":          handleVerifyMessage,
 	"version":                handleVersion,
@@ -260,11 +275,13 @@ var rpcLimited = map[string]struct{}{
 	"decoderawtransaction":  {},
 	"decodescript":          {},
 	"estimatefee":           {},
+	"getactiveforks":        {},
 	"getbestblock":          {},
 	"getbestblockhash":      {},
 	"getblock":              {},
 	"getblockcount":         {},
 	"getblockhash":          {},
+	"getblockclaimroot":     {},
 	"getblockheader":        {},
 	"getcfilter":            {},
 	"getcfilterheader":      {},
@@ -272,16 +289,21 @@ var rpcLimited = map[string]struct{}{
 	"getdifficulty":         {},
 	"getheaders":            {},
 	"getinfo":               {},
+	"getnameproof":          {},
 	"getnettotals":          {},
 	"getnetworkhashps":      {},
 	"getrawmempool":         {},
 	"getrawtransaction":     {},
+	"gettopnames":           {},
 	"gettxout":              {},
+	"resolve":               {},
+	"searchnames":           {},
 	"searchrawtransactions": {},
 	"sendrawtransaction":    {},
 	"submitblock":           {},
 	"uptime":                {},
 	"validateaddress":       {},
+	"validateclaimtx":       {},
 	"verifymessage":         {},
 	"version":               {},
 }
@@ -704,7 +726,7 @@ func createVoutList(mtx *wire.MsgTx, chainParams *chaincfg.Params, filterAddrMap
 		// Ignore the error here since an error means the script
 		// couldn't parse and there is no additional information about
 		// it anyways.
-		scriptClass, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(
+		scriptClass, addrs, reqSigs, _ := txscript.ExtractClaimPkScriptAddrs(
 			v.PkScript, chainParams)
 
 		// Encode the addresses while checking if the address passes the
@@ -833,7 +855,7 @@ func handleDecodeScript(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 	// Get information about the script.
 	// Ignore the error here since an error means the script couldn't parse
 	// and there is no additinal information about it anyways.
-	scriptClass, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(script,
+	scriptClass, addrs, reqSigs, _ := txscript.ExtractClaimPkScriptAddrs(script,
 		s.cfg.ChainParams)
 	addresses := make([]string, len(addrs))
 	for i, addr := range addrs {
@@ -936,6 +958,27 @@ func handleGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 	return reply, nil
 }
 
+// handleGetActiveForks implements the getactiveforks command.
+func handleGetActiveForks(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetActiveForksCmd)
+
+	height := s.cfg.Chain.BestSnapshot().Height
+	if c.Height != nil {
+		height = *c.Height
+	}
+
+	forks := []btcjson.GetActiveForksResult{}
+	for _, f := range param.ActiveForks(height) {
+		forks = append(forks, btcjson.GetActiveForksResult{
+			Name:        f.Name,
+			Height:      f.Height,
+			Description: f.Description,
+		})
+	}
+
+	return forks, nil
+}
+
 // handleGetAddedNodeInfo handles getaddednodeinfo commands.
 func handleGetAddedNodeInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetAddedNodeInfoCmd)
@@ -1127,6 +1170,7 @@ func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 		Version:       blockHeader.Version,
 		VersionHex:    fmt.Sprintf("%08x", blockHeader.Version),
 		MerkleRoot:    blockHeader.MerkleRoot.String(),
+		ClaimTrie:     blockHeader.ClaimTrie.String(),
 		PreviousHash:  blockHeader.PrevBlock.String(),
 		Nonce:         blockHeader.Nonce,
 		Time:          blockHeader.Timestamp.Unix(),
@@ -1320,6 +1364,34 @@ func handleGetBlockHash(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 	return hash.String(), nil
 }
 
+// handleGetBlockClaimRoot implements the getblockclaimroot command.
+func handleGetBlockClaimRoot(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetBlockClaimRootCmd)
+
+	hash, err := chainhash.NewHashFromStr(c.Hash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.Hash)
+	}
+	blockHeader, err := s.cfg.Chain.HeaderByHash(hash)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+	blockHeight, err := s.cfg.Chain.BlockHeightByHash(hash)
+	if err != nil {
+		context := "Failed to obtain block height"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	return btcjson.GetBlockClaimRootResult{
+		Hash:      c.Hash,
+		Height:    blockHeight,
+		ClaimTrie: blockHeader.ClaimTrie.String(),
+	}, nil
+}
+
 // handleGetBlockHeader implements the getblockheader command.
 func handleGetBlockHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetBlockHeaderCmd)
@@ -1378,6 +1450,7 @@ func handleGetBlockHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct
 		Version:       blockHeader.Version,
 		VersionHex:    fmt.Sprintf("%08x", blockHeader.Version),
 		MerkleRoot:    blockHeader.MerkleRoot.String(),
+		ClaimTrie:     blockHeader.ClaimTrie.String(),
 		NextHash:      nextHashString,
 		PreviousHash:  blockHeader.PrevBlock.String(),
 		Nonce:         uint64(blockHeader.Nonce),
@@ -1751,6 +1824,7 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 		CurTime:      header.Timestamp.Unix(),
 		Height:       int64(template.Height),
 		PreviousHash: header.PrevBlock.String(),
+		ClaimTrie:    header.ClaimTrie.String(),
 		WeightLimit:  blockchain.MaxBlockWeight,
 		SigOpLimit:   blockchain.MaxBlockSigOpsCost,
 		SizeLimit:    wire.MaxBlockPayload,
@@ -2025,6 +2099,8 @@ func chainErrToGBTErrString(err error) string {
 		return "high-hash"
 	case blockchain.ErrBadMerkleRoot:
 		return "bad-txnmrklroot"
+	case blockchain.ErrBadClaimTrie:
+		return "bad-claimtrie"
 	case blockchain.ErrBadCheckpoint:
 		return "bad-checkpoint"
 	case blockchain.ErrForkTooOld:
@@ -2697,6 +2773,257 @@ func handleGetRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan str
 	return *rawTxn, nil
 }
 
+// checkMaxClaims returns an error, if the count of the claims, or names, requested from a claim query
+// is above --rpcmaxclaims.
+func checkMaxClaims(count int) error {
+	if cfg.RPCMaxClaims > 0 && count > cfg.RPCMaxClaims {
+		return &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Count must not be more than --rpcmaxclaims=%d", cfg.RPCMaxClaims),
+		}
+	}
+	return nil
+}
+
+// handleExportClaims implements the exportclaims command.
+func handleExportClaims(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ExportClaimsCmd)
+
+	ct := s.cfg.Chain.ClaimTrie()
+	if ct == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The claim trie is not available",
+		}
+	}
+
+	order := claimtrie.ExportByClaimID
+	if c.Order != nil {
+		order = *c.Order
+	}
+	if order != claimtrie.ExportByClaimID && order != claimtrie.ExportByName {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Order must be %s or %s", claimtrie.ExportByClaimID, claimtrie.ExportByName),
+		}
+	}
+
+	// The export runs for as long as it takes, unless the client disconnects.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-closeChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	snapshot := ct.Snapshot()
+	dir := filepath.Join(cfg.DataDir, "exports")
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, internalRPCError(err.Error(), "Could not create the exports directory")
+	}
+	path := filepath.Join(dir, fmt.Sprintf("claims-by-%s-%d.tsv", order, snapshot.Height()))
+
+	// The export is written aside, so that a failed one never replaces the last one.
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, internalRPCError(err.Error(), "Could not create the export")
+	}
+	rows, err := snapshot.ExportClaims(ctx, f, order)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return nil, internalRPCError(err.Error(), "Could not export the claims")
+	}
+
+	root := snapshot.Root()
+	return &btcjson.ExportClaimsResult{
+		Path:   path,
+		Order:  order,
+		Height: snapshot.Height(),
+		Root:   root.String(),
+		Rows:   rows,
+	}, nil
+}
+
+// handleBackupClaimTrie implements the backupclaimtrie command.
+func handleBackupClaimTrie(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+
+	ct := s.cfg.Chain.ClaimTrie()
+	if ct == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The claim trie is not available",
+		}
+	}
+
+	dir := filepath.Join(cfg.DataDir, "backups")
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, internalRPCError(err.Error(), "Could not create the backups directory")
+	}
+
+	// The backup is written aside, so that a failed one is never mistaken for a complete one.
+	tmp := filepath.Join(dir, "claimtrie.tmp")
+	os.RemoveAll(tmp)
+	h, err := ct.Backup(tmp)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, internalRPCError(err.Error(), "Could not back up the claim trie")
+	}
+	path := filepath.Join(dir, fmt.Sprintf("claimtrie-%d", h.Height))
+	if _, err = os.Stat(path); err == nil {
+		os.RemoveAll(tmp)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("The backup %s exists already", path),
+		}
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, internalRPCError(err.Error(), "Could not move the backup")
+	}
+
+	return &btcjson.BackupClaimTrieResult{
+		Path:   path,
+		Height: h.Height,
+		Root:   h.Root.String(),
+		Repos:  h.Repos,
+	}, nil
+}
+
+// claimQueryContext returns the context of a claim query, which is done once it runs past
+// --rpcquerytimeout, or the client disconnects, so that it gives up holding the claim trie.
+func claimQueryContext(closeChan <-chan struct{}) (context.Context, context.CancelFunc) {
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if cfg.RPCQueryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), cfg.RPCQueryTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	go func() {
+		select {
+		case <-closeChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// handleGetTopNames implements the gettopnames command.
+func handleGetTopNames(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetTopNamesCmd)
+
+	ct := s.cfg.Chain.ClaimTrie()
+	if ct == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The claim trie is not available",
+		}
+	}
+
+	count := 10
+	if c.Count != nil {
+		count = *c.Count
+	}
+	if count < 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Count must not be negative",
+		}
+	}
+	if err := checkMaxClaims(count); err != nil {
+		return nil, err
+	}
+
+	names, err := ct.TopNames(count)
+	if errors.Is(err, claimtrie.ErrTopNamesNotIndexed) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The names are not ranked; restart with --clmttopnames",
+		}
+	}
+	if err != nil {
+		return nil, internalRPCError(err.Error(), "Could not rank the names")
+	}
+
+	result := []btcjson.GetTopNamesResult{}
+	for _, n := range names {
+		result = append(result, btcjson.GetTopNamesResult{
+			Name:            string(n.Name),
+			ClaimID:         n.ClaimID.String(),
+			EffectiveAmount: n.Amount,
+			TakenOverAt:     n.TakenOverAt,
+		})
+	}
+
+	return result, nil
+}
+
+// handleSearchNames implements the searchnames command.
+func handleSearchNames(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SearchNamesCmd)
+
+	ct := s.cfg.Chain.ClaimTrie()
+	if ct == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The claim trie is not available",
+		}
+	}
+
+	opts := claimtrie.SearchOptions{Limit: 10}
+	if c.Substring != nil {
+		opts.Substring = *c.Substring
+	}
+	if c.After != nil {
+		opts.After = []byte(*c.After)
+	}
+	if c.Count != nil {
+		opts.Limit = *c.Count
+	}
+	if opts.Limit <= 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Count must be positive",
+		}
+	}
+	if err := checkMaxClaims(opts.Limit); err != nil {
+		return nil, err
+	}
+
+	names, err := ct.SearchNames(c.Query, opts)
+	if errors.Is(err, claimtrie.ErrNamesNotSearchable) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The names are not indexed for search; restart with --clmtnamesearch",
+		}
+	}
+	if err != nil {
+		return nil, internalRPCError(err.Error(), "Could not search the names")
+	}
+
+	result := []string{}
+	for _, name := range names {
+		result = append(result, string(name))
+	}
+
+	return result, nil
+}
+
 // handleGetTxOut handles gettxout commands.
 func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetTxOutCmd)
@@ -2780,7 +3107,7 @@ func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 	// Get further info about the script.
 	// Ignore the error here since an error means the script couldn't parse
 	// and there is no additional information about it anyways.
-	scriptClass, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(pkScript,
+	scriptClass, addrs, reqSigs, _ := txscript.ExtractClaimPkScriptAddrs(pkScript,
 		s.cfg.ChainParams)
 	addresses := make([]string, len(addrs))
 	for i, addr := range addrs {
@@ -3011,7 +3338,7 @@ func createVinListPrevOut(s *rpcServer, mtx *wire.MsgTx, chainParams *chaincfg.P
 		// Ignore the error here since an error means the script
 		// couldn't parse and there is no additional information about
 		// it anyways.
-		_, addrs, _, _ := txscript.ExtractPkScriptAddrs(
+		_, addrs, _, _ := txscript.ExtractClaimPkScriptAddrs(
 			originTxOut.PkScript, chainParams)
 
 		// Encode the addresses while checking if the address passes the
@@ -3076,6 +3403,205 @@ func fetchMempoolTxnsForAddress(s *rpcServer, addr btcutil.Address, numToSkip, n
 	return mpTxns[numToSkip:rangeEnd], numToSkip
 }
 
+// handleGetNameProof implements the getnameproof command.
+func handleGetNameProof(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetNameProofCmd)
+
+	if s.cfg.Chain.ClaimTrie() == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The claim trie is not available",
+		}
+	}
+
+	hash := &s.cfg.Chain.BestSnapshot().Hash
+	if c.BlockHash != nil {
+		var err error
+		hash, err = chainhash.NewHashFromStr(*c.BlockHash)
+		if err != nil {
+			return nil, rpcDecodeHexError(*c.BlockHash)
+		}
+		if _, err = s.cfg.Chain.BlockHeightByHash(hash); err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCBlockNotFound,
+				Message: "Block not found",
+			}
+		}
+	}
+
+	ctx, cancel := claimQueryContext(closeChan)
+	defer cancel()
+	_, p, err := s.cfg.Chain.ProveClaimName(ctx, []byte(c.Name), hash)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Proving the name took longer than --rpcquerytimeout=%v", cfg.RPCQueryTimeout),
+		}
+	}
+	if errors.Is(err, claimtrie.ErrHistoryBusy) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The claim trie is being reorganized, try again: " + err.Error(),
+		}
+	}
+	if errors.Is(err, claimtrie.ErrNotRetained) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "The claim trie state is not retained at the block: " + err.Error(),
+		}
+	}
+	if errors.Is(err, claimtrie.ErrNameNotFound) || errors.Is(err, claimtrie.ErrNoBestClaim) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "The name has no best claim at the block",
+		}
+	}
+	if err != nil {
+		context := "Failed to prove the name"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	result := btcjson.GetNameProofResult{
+		Nodes:              make([]btcjson.NameProofNode, 0, len(p.Nodes)),
+		TxHash:             p.OutPoint.Hash.String(),
+		NOut:               p.OutPoint.Index,
+		LastTakeoverHeight: p.TakeoverHeight,
+	}
+	for _, n := range p.Nodes {
+		pn := btcjson.NameProofNode{}
+		for _, child := range n.Children {
+			pc := btcjson.NameProofChild{Character: child.Character}
+			if child.Hash != nil {
+				pc.NodeHash = child.Hash.String()
+			}
+			pn.Children = append(pn.Children, pc)
+		}
+		if n.ValueHash != nil {
+			pn.ValueHash = n.ValueHash.String()
+		}
+		result.Nodes = append(result.Nodes, pn)
+	}
+
+	return result, nil
+}
+
+// handleResolve implements the resolve command.
+func handleResolve(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ResolveCmd)
+
+	if s.cfg.Chain.ClaimTrie() == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The claim trie is not available",
+		}
+	}
+
+	hash := &s.cfg.Chain.BestSnapshot().Hash
+	if c.BlockHash != nil {
+		var err error
+		hash, err = chainhash.NewHashFromStr(*c.BlockHash)
+		if err != nil {
+			return nil, rpcDecodeHexError(*c.BlockHash)
+		}
+		if _, err = s.cfg.Chain.BlockHeightByHash(hash); err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCBlockNotFound,
+				Message: "Block not found",
+			}
+		}
+	}
+
+	ctx, cancel := claimQueryContext(closeChan)
+	defer cancel()
+	res, err := s.cfg.Chain.ResolveClaimName(ctx, []byte(c.Name), hash)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Resolving the name took longer than --rpcquerytimeout=%v", cfg.RPCQueryTimeout),
+		}
+	}
+	if errors.Is(err, claimtrie.ErrHistoryBusy) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The claim trie is being reorganized, try again: " + err.Error(),
+		}
+	}
+	if errors.Is(err, claimtrie.ErrNotRetained) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "The claim trie state is not retained at the block: " + err.Error(),
+		}
+	}
+	if errors.Is(err, claimtrie.ErrNameNotFound) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "The name has no claims or supports at the block",
+		}
+	}
+	if err != nil {
+		context := "Failed to resolve the name"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	result := btcjson.ResolveResult{
+		Name:      string(res.Name),
+		Hash:      hash.String(),
+		Height:    res.Height,
+		ClaimTrie: res.Root.String(),
+		Claims:    []btcjson.ResolvedClaim{},
+	}
+	if res.Node == nil {
+		return result, nil
+	}
+	resolved := func(c *node.Claim) (btcjson.ResolvedClaim, error) {
+		value, err := s.cfg.Chain.ClaimTrie().ClaimValue(c)
+		if err != nil && !errors.Is(err, claimtrie.ErrValueNotStored) {
+			return btcjson.ResolvedClaim{}, internalRPCError(err.Error(), "Failed to fetch the value of the claim")
+		}
+		m := c.MaturityAt(res.Height)
+		rc := btcjson.ResolvedClaim{
+			ClaimID:            c.ClaimID.String(),
+			OutPoint:           c.OutPoint.String(),
+			Amount:             c.Amount,
+			EffectiveAmount:    c.EffectiveAmount(res.Node.Supports),
+			AcceptedAt:         c.AcceptedAt,
+			ActiveAt:           c.ActiveAt,
+			Value:              hex.EncodeToString(value),
+			Confirmations:      m.Confirmations,
+			Status:             c.Status.String(),
+			BlocksUntilActive:  m.BlocksUntilActive,
+			ExpiresAt:          m.ExpireAt,
+			BlocksUntilExpired: m.BlocksUntilExpired,
+		}
+		if id, ok := node.SigningChannel(value); ok {
+			rc.Channel = id.String()
+		}
+		return rc, nil
+	}
+	result.TotalClaims = len(res.Node.Claims)
+	for _, c := range res.Node.Claims {
+		if cfg.RPCMaxClaims > 0 && len(result.Claims) >= cfg.RPCMaxClaims {
+			result.Truncated = true
+			break
+		}
+		rc, err := resolved(c)
+		if err != nil {
+			return nil, err
+		}
+		result.Claims = append(result.Claims, rc)
+	}
+	if res.Node.BestClaim != nil {
+		best, err := resolved(res.Node.BestClaim)
+		if err != nil {
+			return nil, err
+		}
+		result.BestClaim = &best
+		result.TakenOverAt = res.Node.TakenOverAt
+	}
+
+	return result, nil
+}
+
 // handleSearchRawTransactions implements the searchrawtransactions command.
 func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Respond with an error if the address index is not enabled.
@@ -3555,6 +4081,13 @@ func handleSubmitBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 		}
 	}
 
+	// The claim trie root isn't enforced as the blocks are connected, so it's
+	// checked against the expected one for the blocks extending the tip.
+	err = s.cfg.Chain.CheckClaimTrieRoot(block)
+	if err != nil {
+		return fmt.Sprintf("rejected: %s", err.Error()), nil
+	}
+
 	// Process this block using the same rules as blocks coming from other
 	// nodes.  This will in turn relay it to the network like normal.
 	_, err = s.cfg.SyncMgr.SubmitBlock(block, blockchain.BFNone)
@@ -3571,6 +4104,90 @@ func handleUptime(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (int
 	return time.Now().Unix() - s.cfg.StartupTime, nil
 }
 
+// handleValidateClaimTx implements the validateclaimtx command.
+func handleValidateClaimTx(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ValidateClaimTxCmd)
+
+	// Deserialize the transaction.
+	hexStr := c.HexTx
+	if len(hexStr)%2 != 0 {
+		hexStr = "0" + hexStr
+	}
+	serializedTx, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, rpcDecodeHexError(hexStr)
+	}
+	var mtx wire.MsgTx
+	err = mtx.Deserialize(bytes.NewReader(serializedTx))
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "TX decode failed: " + err.Error(),
+		}
+	}
+	tx := btcutil.NewTx(&mtx)
+
+	// The inputs are looked up in the main chain, and then in the mempool.
+	view, err := s.cfg.Chain.FetchUtxoView(tx)
+	if err != nil {
+		context := "Failed to fetch the inputs"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	for _, txIn := range mtx.TxIn {
+		prevOut := txIn.PreviousOutPoint
+		entry := view.LookupEntry(prevOut)
+		if entry != nil && !entry.IsSpent() {
+			continue
+		}
+		if prevTx, err := s.cfg.TxMemPool.FetchTransaction(&prevOut.Hash); err == nil {
+			view.AddTxOut(prevTx, prevOut.Index, mining.UnminedHeight)
+		}
+	}
+
+	// The operations are checked as of the next block.
+	height := s.cfg.Chain.BestSnapshot().Height + 1
+	ops, err := blockchain.ValidateClaimOps(tx, view, height, policy.DefaultPolicy)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNoTxInfo,
+			Message: "Failed to validate the claim operations: " + err.Error(),
+		}
+	}
+
+	result := btcjson.ValidateClaimTxResult{
+		Txid:       mtx.TxHash().String(),
+		Accepted:   true,
+		Operations: []btcjson.ClaimOpResult{},
+	}
+	for _, op := range ops {
+		opResult := btcjson.ClaimOpResult{
+			Spend:    op.Spend,
+			Index:    op.Index,
+			Name:     string(op.Name),
+			ClaimID:  op.ClaimID.String(),
+			Amount:   op.Amount,
+			Accepted: op.Err == nil,
+		}
+		switch op.Opcode {
+		case txscript.OP_CLAIMNAME:
+			opResult.Op = "claimname"
+		case txscript.OP_UPDATECLAIM:
+			opResult.Op = "updateclaim"
+		case txscript.OP_SUPPORTCLAIM:
+			opResult.Op = "supportclaim"
+		default:
+			opResult.Op = "unknown"
+		}
+		if op.Err != nil {
+			opResult.Reason = op.Err.Error()
+			result.Accepted = false
+		}
+		result.Operations = append(result.Operations, opResult)
+	}
+
+	return result, nil
+}
+
 // handleValidateAddress implements the validateaddress command.
 func handleValidateAddress(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.ValidateAddressCmd)
@@ -34,6 +34,15 @@ var helpDescsEnUS = map[string]string{
 	"addnode-addr":      "IP address and port of the peer to operate on",
 	"addnode-subcmd":    "'add' to add a persistent peer, 'remove' to remove a persistent peer, or 'onetry' to try a single connection to a peer",
 
+	// BackupClaimTrieCmd help.
+	"backupclaimtrie--synopsis": "Writes a consistent copy of the repos of the claim trie, as of the best block, to a directory in the backups directory of the data directory, while the node keeps running. The blocks wait for it to be written.",
+
+	// BackupClaimTrieResult help.
+	"backupclaimtrieresult-path":   "The path of the directory written",
+	"backupclaimtrieresult-height": "The height of the block the backup is as of",
+	"backupclaimtrieresult-root":   "The claim trie root of the block",
+	"backupclaimtrieresult-repos":  "The paths of the repos backed up, relative to the data directory of the claim trie",
+
 	// NodeCmd help.
 	"node--synopsis":     "Attempts to add or remove a peer.",
 	"node-subcmd":        "'disconnect' to remove all matching non-persistent peers, 'remove' to remove a persistent peer, or 'connect' to connect to a peer",
@@ -124,12 +133,32 @@ var helpDescsEnUS = map[string]string{
 	"estimatefee--result0": "Estimated fee per kilobyte in satoshis for a block to " +
 		"be mined in the next NumBlocks blocks.",
 
+	// ExportClaimsCmd help.
+	"exportclaims--synopsis": "Writes the unspent claims of all the names, as of the best block, to a file in the exports directory of the data directory, sorted by claim ID, or name, after an index of the offsets of the rows by the first byte of their keys. The rows past the header are in the text format of Postgres COPY: claim ID, name, outpoint, amount and status.",
+	"exportclaims-order":     "The order of the claims: claimid, or name",
+
+	// ExportClaimsResult help.
+	"exportclaimsresult-path":   "The path of the file written",
+	"exportclaimsresult-order":  "The order of the claims",
+	"exportclaimsresult-height": "The height of the block the claims are as of",
+	"exportclaimsresult-root":   "The claim trie root of the block",
+	"exportclaimsresult-rows":   "The number of claims written",
+
 	// GenerateCmd help
 	"generate--synopsis": "Generates a set number of blocks (simnet or regtest only) and returns a JSON\n" +
 		" array of their hashes.",
 	"generate-numblocks": "Number of blocks to generate",
 	"generate--result0":  "The hashes, in order, of blocks generated by the call",
 
+	// GetActiveForksCmd help.
+	"getactiveforks--synopsis": "Returns the forks of the claim trie rules, which are active at a height, in order by height.",
+	"getactiveforks-height":    "The height to return the active forks at (default: the best block)",
+
+	// GetActiveForksResult help.
+	"getactiveforksresult-name":        "The name of the fork",
+	"getactiveforksresult-height":      "The height the fork is active from",
+	"getactiveforksresult-description": "What the fork changes",
+
 	// GetAddedNodeInfoResultAddr help.
 	"getaddednodeinforesultaddr-address":   "The ip address for this DNS entry",
 	"getaddednodeinforesultaddr-connected": "The connection 'direction' (inbound/outbound/false)",
@@ -245,6 +274,7 @@ var helpDescsEnUS = map[string]string{
 	"getblockverboseresult-version":           "The block version",
 	"getblockverboseresult-versionHex":        "The block version in hexadecimal",
 	"getblockverboseresult-merkleroot":        "Root hash of the merkle tree",
+	"getblockverboseresult-claimTrie":         "Root hash of the claim trie",
 	"getblockverboseresult-tx":                "The transaction hashes (only when verbosity=1)",
 	"getblockverboseresult-rawtx":             "The transactions as JSON objects (only when verbosity=2)",
 	"getblockverboseresult-time":              "The block time in seconds since 1 Jan 1970 GMT",
@@ -265,6 +295,15 @@ var helpDescsEnUS = map[string]string{
 	"getblockhash-index":     "The block height",
 	"getblockhash--result0":  "The block hash",
 
+	// GetBlockClaimRootCmd help.
+	"getblockclaimroot--synopsis": "Returns the root hash of the claim trie committed to by a block given its hash.",
+	"getblockclaimroot-hash":      "The hash of the block",
+
+	// GetBlockClaimRootResult help.
+	"getblockclaimrootresult-hash":      "The hash of the block (same as provided)",
+	"getblockclaimrootresult-height":    "The height of the block in the block chain",
+	"getblockclaimrootresult-claimtrie": "Root hash of the claim trie",
+
 	// GetBlockHeaderCmd help.
 	"getblockheader--synopsis":   "Returns information about a block header given its hash.",
 	"getblockheader-hash":        "The hash of the block",
@@ -280,6 +319,7 @@ var helpDescsEnUS = map[string]string{
 	"getblockheaderverboseresult-version":           "The block version",
 	"getblockheaderverboseresult-versionHex":        "The block version in hexadecimal",
 	"getblockheaderverboseresult-merkleroot":        "Root hash of the merkle tree",
+	"getblockheaderverboseresult-claimtrie":         "Root hash of the claim trie",
 	"getblockheaderverboseresult-time":              "The block time in seconds since 1 Jan 1970 GMT",
 	"getblockheaderverboseresult-nonce":             "The block nonce",
 	"getblockheaderverboseresult-bits":              "The bits which represent the block difficulty",
@@ -316,6 +356,7 @@ var helpDescsEnUS = map[string]string{
 	"getblocktemplateresult-curtime":                    "Current time as seen by the server (recommended for block time); must fall within mintime/maxtime rules",
 	"getblocktemplateresult-height":                     "Height of the block to be solved",
 	"getblocktemplateresult-previousblockhash":          "Hex-encoded big-endian hash of the previous block",
+	"getblocktemplateresult-claimtrie":                  "Hex-encoded big-endian root of the claim trie after the block, computed over its transactions",
 	"getblocktemplateresult-sigoplimit":                 "Number of sigops allowed in blocks ",
 	"getblocktemplateresult-sizelimit":                  "Number of bytes allowed in blocks",
 	"getblocktemplateresult-transactions":               "Array of transactions as JSON objects",
@@ -519,6 +560,43 @@ var helpDescsEnUS = map[string]string{
 	"getrawtransaction--condition1": "verbose=true",
 	"getrawtransaction--result0":    "Hex-encoded bytes of the serialized transaction",
 
+	// GetNameProofCmd help.
+	"getnameproof--synopsis": "Returns the proof of the best claim of a name against the root of the claim trie at a block in the main chain, in the layout of lbrycrd's. Proofs are unsupported from the all-claims fork on.",
+	"getnameproof-name":      "The name to prove",
+	"getnameproof-blockhash": "The hash of the block to prove the name at (default: the best block)",
+
+	// GetNameProofResult help.
+	"getnameproofresult-nodes":                "The nodes on the path from the root to the name",
+	"getnameproofresult-txhash":               "The hash of the transaction of the best claim",
+	"getnameproofresult-nOut":                 "The output index of the best claim",
+	"getnameproofresult-last takeover height": "The height the best claim took over the name at",
+
+	// NameProofNode help.
+	"nameproofnode-children":  "The children of the node, in order of their characters",
+	"nameproofnode-valueHash": "The hash of the value of the node, if it has one, except for the last node, of which it is computed from the best claim",
+
+	// NameProofChild help.
+	"nameproofchild-character": "The character of the child",
+	"nameproofchild-nodeHash":  "The hash of the child, unless it is the next node on the path",
+
+	// GetTopNamesCmd help.
+	"gettopnames--synopsis": "Returns the names with the highest effective amounts of their best claims, including their supports, in order. Requires --clmttopnames.",
+	"gettopnames-count":     "The number of names to return, up to --rpcmaxclaims",
+
+	// GetTopNamesResult help.
+	"gettopnamesresult-name":            "The name",
+	"gettopnamesresult-claimid":         "The claim ID of the best claim",
+	"gettopnamesresult-effectiveamount": "The amount of the best claim and its active supports",
+	"gettopnamesresult-takenoverat":     "The height the best claim took over the name at",
+
+	// SearchNamesCmd help.
+	"searchnames--synopsis": "Returns the names with best claims, in order, of which the tokens, split on the punctuation, start with each of the tokens of the query, regardless of case. Requires --clmtnamesearch.",
+	"searchnames-query":     "The tokens to search for",
+	"searchnames-substring": "Match the tokens containing the ones of the query anywhere, instead of starting with them",
+	"searchnames-after":     "Return the names after this one, such as the last of the previous page",
+	"searchnames-count":     "The number of names to return, up to --rpcmaxclaims",
+	"searchnames--result0":  "The names found",
+
 	// GetTxOutResult help.
 	"gettxoutresult-bestblock":     "The block hash that contains the transaction output",
 	"gettxoutresult-confirmations": "The number of confirmations",
@@ -545,6 +623,37 @@ var helpDescsEnUS = map[string]string{
 	"ping--synopsis": "Queues a ping to be sent to each connected peer.\n" +
 		"Ping times are provided by getpeerinfo via the pingtime and pingwait fields.",
 
+	// ResolveCmd help.
+	"resolve--synopsis": "Returns the claims of a name as of a block in the main chain, replayed from its changes retained by the claim trie. Fails if the name has no claims or supports at the block, while a name with only inactive claims has no best claim.",
+	"resolve-name":      "The name to resolve",
+	"resolve-blockhash": "The hash of the block to resolve the name at (default: the best block)",
+
+	// ResolveResult help.
+	"resolveresult-name":        "The name, normalized as of the block",
+	"resolveresult-hash":        "The hash of the block",
+	"resolveresult-height":      "The height of the block in the block chain",
+	"resolveresult-claimtrie":   "Root hash of the claim trie at the block",
+	"resolveresult-bestclaim":   "The best claim of the name, if any",
+	"resolveresult-takenoverat": "The height the best claim took over the name at",
+	"resolveresult-claims":      "The claims of the name, up to --rpcmaxclaims",
+	"resolveresult-totalclaims": "The number of the claims of the name",
+	"resolveresult-truncated":   "Whether the claims of the name past --rpcmaxclaims are left out",
+
+	// ResolvedClaim help.
+	"resolvedclaim-claimid":            "The ID of the claim",
+	"resolvedclaim-outpoint":           "The outpoint of the claim",
+	"resolvedclaim-amount":             "The amount of the claim",
+	"resolvedclaim-effectiveamount":    "The amount of the claim and its activated supports",
+	"resolvedclaim-acceptedat":         "The height the claim was accepted at",
+	"resolvedclaim-activeat":           "The height the claim is activated at",
+	"resolvedclaim-value":              "The hex-encoded value of the claim",
+	"resolvedclaim-channel":            "The ID of the channel, which signed the claim, if any",
+	"resolvedclaim-confirmations":      "The number of blocks the claim was accepted in, and since, as of the block",
+	"resolvedclaim-status":             "The status of the claim as of the block: accepted, activated, or deactivated",
+	"resolvedclaim-blocksuntilactive":  "The number of blocks until the claim is activated, or 0 if it is",
+	"resolvedclaim-expiresat":          "The height the claim expires at, as of the expiration time of the forks",
+	"resolvedclaim-blocksuntilexpired": "The number of blocks until the claim expires, or 0 if it did",
+
 	// SearchRawTransactionsCmd help.
 	"searchrawtransactions--synopsis": "Returns raw data for transactions involving the passed address.\n" +
 		"Returned transactions are pulled from both the database, and transactions currently in the mempool.\n" +
@@ -607,6 +716,26 @@ var helpDescsEnUS = map[string]string{
 	"validateaddress--synopsis": "Verify an address is valid.",
 	"validateaddress-address":   "Bitcoin address to validate",
 
+	// ValidateClaimTxCmd help.
+	"validateclaimtx--synopsis": "Reports whether the claim operations of a raw transaction would be accepted by the consensus rules of the next block, and the relay policy, without broadcasting it.\n" +
+		"The inputs are looked up in the main chain, and then in the memory pool.",
+	"validateclaimtx-hextx": "Serialized, hex-encoded transaction",
+
+	// ValidateClaimTxResult help.
+	"validateclaimtxresult-txid":       "The hash of the transaction",
+	"validateclaimtxresult-accepted":   "Whether all the claim operations would be accepted",
+	"validateclaimtxresult-operations": "The claim operations of the inputs, and then of the outputs",
+
+	// ClaimOpResult help.
+	"claimopresult-op":       "The claim opcode: claimname, updateclaim, or supportclaim (unknown if the script is malformed)",
+	"claimopresult-spend":    "Whether the operation is spent by an input, instead of added by an output",
+	"claimopresult-index":    "The index of the input, or the output",
+	"claimopresult-name":     "The name of the claim script",
+	"claimopresult-claimid":  "The ID of the claim, or of the supported one",
+	"claimopresult-amount":   "The amount of the claim, or the support",
+	"claimopresult-accepted": "Whether the operation would be accepted",
+	"claimopresult-reason":   "Why the operation wouldn't be accepted, if so",
+
 	// VerifyChainCmd help.
 	"verifychain--synopsis": "Verifies the block chain database.\n" +
 		"The actual checks performed by the checklevel parameter are implementation specific.\n" +
@@ -636,6 +765,17 @@ var helpDescsEnUS = map[string]string{
 	// StopNotifyBlocksCmd help.
 	"stopnotifyblocks--synopsis": "Cancel registered notifications for whenever a block is connected or disconnected from the main (best) chain.",
 
+	// NotifyClaimNamesCmd help.
+	"notifyclaimnames--synopsis": "Send a claimnamechanged notification when the best claim of any of the passed names, its effective amount, or its value changes in a newly-attached block.\n" +
+		"Also sent for the names of which the best claim is, or was, signed by any of the passed channels.",
+	"notifyclaimnames-names":    "List of the names to receive notifications about",
+	"notifyclaimnames-channels": "List of the claim IDs of the channels to receive notifications about",
+
+	// StopNotifyClaimNamesCmd help.
+	"stopnotifyclaimnames--synopsis": "Cancel registered claim name notifications for each passed name and channel.",
+	"stopnotifyclaimnames-names":     "List of the names to cancel notifications for",
+	"stopnotifyclaimnames-channels":  "List of the claim IDs of the channels to cancel notifications for",
+
 	// NotifyNewTransactionsCmd help.
 	"notifynewtransactions--synopsis": "Send either a txaccepted or a txacceptedverbose notification when a new transaction is accepted into the mempool.",
 	"notifynewtransactions-verbose":   "Specifies which type of notification to receive. If verbose is true, then the caller receives txacceptedverbose, otherwise the caller receives txaccepted",
@@ -713,18 +853,22 @@ var helpDescsEnUS = map[string]string{
 // pointer to the type (or nil to indicate no return value).
 var rpcResultTypes = map[string][]interface{}{
 	"addnode":                nil,
+	"backupclaimtrie":        {(*btcjson.BackupClaimTrieResult)(nil)},
 	"createrawtransaction":   {(*string)(nil)},
 	"debuglevel":             {(*string)(nil), (*string)(nil)},
 	"decoderawtransaction":   {(*btcjson.TxRawDecodeResult)(nil)},
 	"decodescript":           {(*btcjson.DecodeScriptResult)(nil)},
 	"estimatefee":            {(*float64)(nil)},
+	"exportclaims":           {(*btcjson.ExportClaimsResult)(nil)},
 	"generate":               {(*[]string)(nil)},
+	"getactiveforks":         {(*[]btcjson.GetActiveForksResult)(nil)},
 	"getaddednodeinfo":       {(*[]string)(nil), (*[]btcjson.GetAddedNodeInfoResult)(nil)},
 	"getbestblock":           {(*btcjson.GetBestBlockResult)(nil)},
 	"getbestblockhash":       {(*string)(nil)},
 	"getblock":               {(*string)(nil), (*btcjson.GetBlockVerboseResult)(nil)},
 	"getblockcount":          {(*int64)(nil)},
 	"getblockhash":           {(*string)(nil)},
+	"getblockclaimroot":      {(*btcjson.GetBlockClaimRootResult)(nil)},
 	"getblockheader":         {(*string)(nil), (*btcjson.GetBlockHeaderVerboseResult)(nil)},
 	"getblocktemplate":       {(*btcjson.GetBlockTemplateResult)(nil), (*string)(nil), nil},
 	"getblockchaininfo":      {(*btcjson.GetBlockChainInfoResult)(nil)},
@@ -740,15 +884,19 @@ var rpcResultTypes = map[string][]interface{}{
 	"getmempoolinfo":         {(*btcjson.GetMempoolInfoResult)(nil)},
 	"getmininginfo":          {(*btcjson.GetMiningInfoResult)(nil)},
 	"getnettotals":           {(*btcjson.GetNetTotalsResult)(nil)},
+	"getnameproof":           {(*btcjson.GetNameProofResult)(nil)},
 	"getnetworkhashps":       {(*int64)(nil)},
 	"getnodeaddresses":       {(*[]btcjson.GetNodeAddressesResult)(nil)},
 	"getpeerinfo":            {(*[]btcjson.GetPeerInfoResult)(nil)},
 	"getrawmempool":          {(*[]string)(nil), (*btcjson.GetRawMempoolVerboseResult)(nil)},
 	"getrawtransaction":      {(*string)(nil), (*btcjson.TxRawResult)(nil)},
+	"gettopnames":            {(*[]btcjson.GetTopNamesResult)(nil)},
 	"gettxout":               {(*btcjson.GetTxOutResult)(nil)},
+	"searchnames":            {(*[]string)(nil)},
 	"node":                   nil,
 	"help":                   {(*string)(nil), (*string)(nil)},
 	"ping":                   nil,
+	"resolve":                {(*btcjson.ResolveResult)(nil)},
 	"searchrawtransactions":  {(*string)(nil), (*[]btcjson.SearchRawTransactionsResult)(nil)},
 	"sendrawtransaction":     {(*string)(nil)},
 	"setgenerate":            nil,
@@ -757,6 +905,7 @@ var rpcResultTypes = map[string][]interface{}{
 	"submitblock":            {nil, (*string)(nil)},
 	"uptime":                 {(*int64)(nil)},
 	"validateaddress":        {(*btcjson.ValidateAddressChainResult)(nil)},
+	"validateclaimtx":        {(*btcjson.ValidateClaimTxResult)(nil)},
 	"verifychain":            {(*bool)(nil)},
 	"verifymessage":          {(*bool)(nil)},
 	"version":                {(*map[string]btcjson.VersionResult)(nil)},
@@ -766,6 +915,8 @@ var rpcResultTypes = map[string][]interface{}{
 	"session":                   {(*btcjson.SessionResult)(nil)},
 	"notifyblocks":              nil,
 	"stopnotifyblocks":          nil,
+	"notifyclaimnames":          nil,
+	"stopnotifyclaimnames":      nil,
 	"notifynewtransactions":     nil,
 	"stopnotifynewtransactions": nil,
 	"notifyreceived":            nil,
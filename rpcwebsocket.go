@@ -24,6 +24,8 @@ import (
 	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/claimtrie/event"
+	"github.com/btcsuite/btcd/claimtrie/node"
 	"github.com/btcsuite/btcd/database"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
@@ -66,11 +68,13 @@ var wsHandlersBeforeInit = map[string]wsCommandHandler{
 	"loadtxfilter":              handleLoadTxFilter,
 	"help":                      handleWebsocketHelp,
 	"notifyblocks":              handleNotifyBlocks,
+	"notifyclaimnames":          handleNotifyClaimNames,
 	"notifynewtransactions":     handleNotifyNewTransactions,
 	"notifyreceived":            handleNotifyReceived,
 	"notifyspent":               handleNotifySpent,
 	"session":                   handleSession,
 	"stopnotifyblocks":          handleStopNotifyBlocks,
+	"stopnotifyclaimnames":      handleStopNotifyClaimNames,
 	"stopnotifynewtransactions": handleStopNotifyNewTransactions,
 	"stopnotifyspent":           handleStopNotifySpent,
 	"stopnotifyreceived":        handleStopNotifyReceived,
@@ -246,6 +250,23 @@ func (m *wsNotificationManager) NotifyMempoolTx(tx *btcutil.Tx, isNew bool) {
 	}
 }
 
+// NotifyClaimTrieEvent passes a NameChanged event of the ClaimTrie to the
+// notification manager for claim name notification processing.  It's
+// subscribed to the ClaimTrie while any client has registered for claim names.
+func (m *wsNotificationManager) NotifyClaimTrieEvent(e event.Event) {
+	if e.Type != event.NameChanged {
+		return
+	}
+
+	// As the events are published while the ClaimTrie is processing a
+	// block, use a select statement to unblock enqueuing the notification
+	// once the RPC server has begun shutting down.
+	select {
+	case m.queueNotification <- (*notificationClaimNameChanged)(&e):
+	case <-m.quit:
+	}
+}
+
 // wsClientFilter tracks relevant addresses for each websocket client for
 // the `rescanblocks` extension. It is modified by the `loadtxfilter` command.
 //
@@ -451,6 +472,7 @@ type notificationTxAcceptedByMempool struct {
 	isNew bool
 	tx    *btcutil.Tx
 }
+type notificationClaimNameChanged event.Event
 
 // Notification control requests
 type notificationRegisterClient wsClient
@@ -475,6 +497,16 @@ type notificationUnregisterAddr struct {
 	wsc  *wsClient
 	addr string
 }
+type notificationRegisterClaimNames struct {
+	wsc      *wsClient
+	names    []string
+	channels []string
+}
+type notificationUnregisterClaimNames struct {
+	wsc      *wsClient
+	names    []string
+	channels []string
+}
 
 // notificationHandler reads notifications and control messages from the queue
 // handler and processes one at a time.
@@ -493,6 +525,8 @@ func (m *wsNotificationManager) notificationHandler() {
 	txNotifications := make(map[chan struct{}]*wsClient)
 	watchedOutPoints := make(map[wire.OutPoint]map[chan struct{}]*wsClient)
 	watchedAddrs := make(map[string]map[chan struct{}]*wsClient)
+	claimNames := newClaimNameWatches()
+	defer claimNames.unsubscribe()
 
 out:
 	for {
@@ -539,6 +573,9 @@ out:
 				m.notifyForTx(watchedOutPoints, watchedAddrs, n.tx, nil)
 				m.notifyRelevantTxAccepted(n.tx, clients)
 
+			case *notificationClaimNameChanged:
+				m.notifyClaimNameChanged(claimNames, (*event.Event)(n))
+
 			case *notificationRegisterBlocks:
 				wsc := (*wsClient)(n)
 				blockNotifications[wsc.quit] = wsc
@@ -564,6 +601,9 @@ out:
 				for addr := range wsc.addrRequests {
 					m.removeAddrRequest(watchedAddrs, wsc, addr)
 				}
+				m.removeClaimNameRequests(claimNames, wsc,
+					stringKeys(wsc.claimNameRequests),
+					stringKeys(wsc.channelRequests))
 				delete(clients, wsc.quit)
 
 			case *notificationRegisterSpent:
@@ -578,6 +618,12 @@ out:
 			case *notificationUnregisterAddr:
 				m.removeAddrRequest(watchedAddrs, n.wsc, n.addr)
 
+			case *notificationRegisterClaimNames:
+				m.addClaimNameRequests(claimNames, n.wsc, n.names, n.channels)
+
+			case *notificationUnregisterClaimNames:
+				m.removeClaimNameRequests(claimNames, n.wsc, n.names, n.channels)
+
 			case *notificationRegisterNewMempoolTxs:
 				wsc := (*wsClient)(n)
 				txNotifications[wsc.quit] = wsc
@@ -655,7 +701,7 @@ func (m *wsNotificationManager) subscribedClients(tx *btcutil.Tx,
 	}
 
 	for i, output := range msgTx.TxOut {
-		_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+		_, addrs, _, err := txscript.ExtractClaimPkScriptAddrs(
 			output.PkScript, m.server.cfg.ChainParams)
 		if err != nil {
 			// Clients are not able to subscribe to
@@ -998,7 +1044,7 @@ func (m *wsNotificationManager) notifyForTxOuts(ops map[wire.OutPoint]map[chan s
 	txHex := ""
 	wscNotified := make(map[chan struct{}]struct{})
 	for i, txOut := range tx.MsgTx().TxOut {
-		_, txAddrs, _, err := txscript.ExtractPkScriptAddrs(
+		_, txAddrs, _, err := txscript.ExtractClaimPkScriptAddrs(
 			txOut.PkScript, m.server.cfg.ChainParams)
 		if err != nil {
 			continue
@@ -1176,6 +1222,177 @@ func (*wsNotificationManager) removeAddrRequest(addrs map[string]map[chan struct
 	}
 }
 
+// claimNameWatches holds the websocket clients registered for claim names, or
+// for the names of which the best claim is signed by a channel, and the last
+// best claims notified, so only the changes of them are notified.  It's owned
+// by the notification handler.
+type claimNameWatches struct {
+	names    map[string]map[chan struct{}]*wsClient
+	channels map[string]map[chan struct{}]*wsClient
+	winners  map[string]btcjson.ClaimNameWinner
+
+	// unsubscribe from the ClaimTrie events, if subscribed.
+	unsubscribe func()
+}
+
+func newClaimNameWatches() *claimNameWatches {
+	return &claimNameWatches{
+		names:       make(map[string]map[chan struct{}]*wsClient),
+		channels:    make(map[string]map[chan struct{}]*wsClient),
+		winners:     make(map[string]btcjson.ClaimNameWinner),
+		unsubscribe: func() {},
+	}
+}
+
+// RegisterClaimNames requests notifications to the passed websocket client
+// when the best claim of any of the names, or of any name of which the best
+// claim is signed by any of the channels, changes.
+func (m *wsNotificationManager) RegisterClaimNames(wsc *wsClient, names, channels []string) {
+	m.queueNotification <- &notificationRegisterClaimNames{
+		wsc:      wsc,
+		names:    names,
+		channels: channels,
+	}
+}
+
+// UnregisterClaimNames removes the requests from the passed websocket client
+// to be notified about the names and channels.
+func (m *wsNotificationManager) UnregisterClaimNames(wsc *wsClient, names, channels []string) {
+	m.queueNotification <- &notificationUnregisterClaimNames{
+		wsc:      wsc,
+		names:    names,
+		channels: channels,
+	}
+}
+
+// addClaimNameRequests adds the websocket client wsc to the claim name and
+// channel sets of w, and subscribes to the events of the ClaimTrie with the
+// first request.
+func (m *wsNotificationManager) addClaimNameRequests(w *claimNameWatches,
+	wsc *wsClient, names, channels []string) {
+
+	subscribed := len(w.names)+len(w.channels) > 0
+	addRequests := func(requests map[string]map[chan struct{}]*wsClient,
+		tracked map[string]struct{}, keys []string) {
+
+		for _, key := range keys {
+			tracked[key] = struct{}{}
+			cmap, ok := requests[key]
+			if !ok {
+				cmap = make(map[chan struct{}]*wsClient)
+				requests[key] = cmap
+			}
+			cmap[wsc.quit] = wsc
+		}
+	}
+	addRequests(w.names, wsc.claimNameRequests, names)
+	addRequests(w.channels, wsc.channelRequests, channels)
+
+	ct := m.server.cfg.Chain.ClaimTrie()
+	if ct != nil && !subscribed && len(w.names)+len(w.channels) > 0 {
+		w.unsubscribe = ct.Subscribe(m.NotifyClaimTrieEvent)
+	}
+}
+
+// removeClaimNameRequests removes the websocket client wsc from the claim
+// name and channel sets of w, and unsubscribes from the events of the
+// ClaimTrie once no requests are left.
+func (m *wsNotificationManager) removeClaimNameRequests(w *claimNameWatches,
+	wsc *wsClient, names, channels []string) {
+
+	removeRequests := func(requests map[string]map[chan struct{}]*wsClient,
+		tracked map[string]struct{}, keys []string) {
+
+		for _, key := range keys {
+			delete(tracked, key)
+			cmap, ok := requests[key]
+			if !ok {
+				continue
+			}
+			delete(cmap, wsc.quit)
+			if len(cmap) == 0 {
+				delete(requests, key)
+			}
+		}
+	}
+	removeRequests(w.names, wsc.claimNameRequests, names)
+	removeRequests(w.channels, wsc.channelRequests, channels)
+
+	if len(w.names)+len(w.channels) == 0 {
+		w.unsubscribe()
+		w.unsubscribe = func() {}
+		w.winners = make(map[string]btcjson.ClaimNameWinner)
+	}
+}
+
+// notifyClaimNameChanged notifies the websocket clients registered for the
+// name of the event, or for the channel of its best claim, or of the one
+// notified before, if the best claim, its effective amount, or its value has
+// changed since the last notification.
+func (m *wsNotificationManager) notifyClaimNameChanged(w *claimNameWatches, e *event.Event) {
+
+	name := string(e.Name)
+	previous, notified := w.winners[name]
+
+	clients := make(map[chan struct{}]*wsClient)
+	for quit, wsc := range w.names[name] {
+		clients[quit] = wsc
+	}
+	for _, channel := range []string{e.Channel, previous.Channel} {
+		if channel == "" {
+			continue
+		}
+		for quit, wsc := range w.channels[channel] {
+			clients[quit] = wsc
+		}
+	}
+	if len(clients) == 0 {
+		return
+	}
+
+	var winner *btcjson.ClaimNameWinner
+	if e.ClaimID != "" {
+		winner = &btcjson.ClaimNameWinner{
+			ClaimID:         e.ClaimID,
+			OutPoint:        e.OutPoint,
+			Amount:          e.Amount,
+			EffectiveAmount: e.EffectiveAmount,
+			Value:           hex.EncodeToString(e.Value),
+			Channel:         e.Channel,
+		}
+	}
+	switch {
+	case winner == nil && !notified:
+		return
+	case winner == nil:
+		delete(w.winners, name)
+	case notified && *winner == previous:
+		return
+	default:
+		w.winners[name] = *winner
+	}
+
+	ntfn := btcjson.NewClaimNameChangedNtfn(e.Height, name, winner)
+	marshalledJSON, err := btcjson.MarshalCmd(btcjson.RpcVersion1, nil, ntfn)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal claim name changed notification: "+
+			"%v", err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// stringKeys returns the keys of the set.
+func stringKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
 // AddClient adds the passed websocket client to the notification manager.
 func (m *wsNotificationManager) AddClient(wsc *wsClient) {
 	m.queueNotification <- (*notificationRegisterClient)(wsc)
@@ -1287,6 +1504,12 @@ type wsClient struct {
 	// Owned by the notification manager.
 	spentRequests map[wire.OutPoint]struct{}
 
+	// claimNameRequests and channelRequests are the sets of the claim names,
+	// and channel claim IDs, the client has requested to be notified about.
+	// Owned by the notification manager.
+	claimNameRequests map[string]struct{}
+	channelRequests   map[string]struct{}
+
 	// filterData is the new generation transaction filter backported from
 	// github.com/decred/dcrd for the new backported `loadtxfilter` and
 	// `rescanblocks` methods.
@@ -2007,6 +2230,8 @@ func newWebsocketClient(server *rpcServer, conn *websocket.Conn,
 		server:            server,
 		addrRequests:      make(map[string]struct{}),
 		spentRequests:     make(map[wire.OutPoint]struct{}),
+		claimNameRequests: make(map[string]struct{}),
+		channelRequests:   make(map[string]struct{}),
 		serviceRequestSem: makeSemaphore(cfg.RPCMaxConcurrentReqs),
 		ntfnChan:          make(chan []byte, 1), // nonblocking sync
 		sendChan:          make(chan wsResponse, websocketSendBufferSize),
@@ -2114,6 +2339,73 @@ func handleNotifyBlocks(wsc *wsClient, icmd interface{}) (interface{}, error) {
 	return nil, nil
 }
 
+// claimNameRequests returns the normalized names, and the validated channel
+// claim IDs, of a notifyclaimnames, or stopnotifyclaimnames, command.
+func claimNameRequests(wsc *wsClient, names []string, channels *[]string) ([]string, []string, error) {
+	if wsc.server.cfg.Chain.ClaimTrie() == nil {
+		return nil, nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "the ClaimTrie is disabled",
+		}
+	}
+
+	height := wsc.server.cfg.Chain.BestSnapshot().Height
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		normalized = append(normalized, string(node.NormalizeIfNecessary([]byte(name), height)))
+	}
+
+	var ids []string
+	if channels != nil {
+		for _, channel := range *channels {
+			id, err := node.NewIDFromString(channel)
+			if err != nil {
+				return nil, nil, &btcjson.RPCError{
+					Code:    btcjson.ErrRPCInvalidParameter,
+					Message: fmt.Sprintf("invalid channel claim ID %q: %v", channel, err),
+				}
+			}
+			ids = append(ids, id.String())
+		}
+	}
+
+	return normalized, ids, nil
+}
+
+// handleNotifyClaimNames implements the notifyclaimnames command extension for
+// websocket connections.
+func handleNotifyClaimNames(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.NotifyClaimNamesCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	names, channels, err := claimNameRequests(wsc, cmd.Names, cmd.Channels)
+	if err != nil {
+		return nil, err
+	}
+
+	wsc.server.ntfnMgr.RegisterClaimNames(wsc, names, channels)
+	return nil, nil
+}
+
+// handleStopNotifyClaimNames implements the stopnotifyclaimnames command
+// extension for websocket connections.
+func handleStopNotifyClaimNames(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.StopNotifyClaimNamesCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	names, channels, err := claimNameRequests(wsc, cmd.Names, cmd.Channels)
+	if err != nil {
+		return nil, err
+	}
+
+	wsc.server.ntfnMgr.UnregisterClaimNames(wsc, names, channels)
+	return nil, nil
+}
+
 // handleSession implements the session command extension for websocket
 // connections.
 func handleSession(wsc *wsClient, icmd interface{}) (interface{}, error) {
@@ -2383,7 +2675,7 @@ func rescanBlock(wsc *wsClient, lookups *rescanKeys, blk *btcutil.Block) {
 		}
 
 		for txOutIdx, txout := range tx.MsgTx().TxOut {
-			_, addrs, _, _ := txscript.ExtractPkScriptAddrs(
+			_, addrs, _, _ := txscript.ExtractClaimPkScriptAddrs(
 				txout.PkScript, wsc.server.cfg.ChainParams)
 
 			for _, addr := range addrs {
@@ -2458,7 +2750,7 @@ func rescanBlockFilter(filter *wsClientFilter, block *btcutil.Block, params *cha
 
 		// Scan outputs.
 		for i, output := range msgTx.TxOut {
-			_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+			_, addrs, _, err := txscript.ExtractClaimPkScriptAddrs(
 				output.PkScript, params)
 			if err != nil {
 				continue
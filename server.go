@@ -209,6 +209,8 @@ type server struct {
 	startupTime   int64
 
 	chainParams          *chaincfg.Params
+	claimTrieAdmin       *claimTrieAdmin
+	claimTrieHealth      *claimTrieHealth
 	addrManager          *addrmgr.AddrManager
 	connManager          *connmgr.ConnManager
 	sigCache             *txscript.SigCache
@@ -1890,6 +1892,25 @@ type removeNodeMsg struct {
 
 // handleQuery is the central handler for all queries and commands from other
 // goroutines related to peer state.
+// bestPeerHeight returns the best height of the connected peers, or 0 without any, or once the server is shut down.
+func (s *server) bestPeerHeight() int32 {
+
+	replyChan := make(chan []*serverPeer)
+	select {
+	case s.query <- getPeersMsg{reply: replyChan}:
+	case <-s.quit:
+		return 0
+	}
+
+	var best int32
+	for _, sp := range <-replyChan {
+		if h := sp.LastBlock(); h > best {
+			best = h
+		}
+	}
+	return best
+}
+
 func (s *server) handleQuery(state *peerState, querymsg interface{}) {
 	switch msg := querymsg.(type) {
 	case getConnCountMsg:
@@ -2390,6 +2411,13 @@ func (s *server) Start() {
 		s.rpcServer.Start()
 	}
 
+	if s.claimTrieAdmin != nil {
+		s.claimTrieAdmin.Start()
+	}
+	if s.claimTrieHealth != nil {
+		s.claimTrieHealth.Start()
+	}
+
 	// Start the CPU miner if generation is enabled.
 	if cfg.Generate {
 		s.cpuMiner.Start()
@@ -2415,6 +2443,13 @@ func (s *server) Stop() error {
 		s.rpcServer.Stop()
 	}
 
+	if s.claimTrieAdmin != nil {
+		s.claimTrieAdmin.Stop()
+	}
+	if s.claimTrieHealth != nil {
+		s.claimTrieHealth.Stop()
+	}
+
 	// Save fee estimator state in the database.
 	s.db.Update(func(tx database.Tx) error {
 		metadata := tx.Metadata()
@@ -2728,7 +2763,87 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 
 	claimTrieCfg := claimtrieconfig.DefaultConfig
 	claimTrieCfg.DataDir = filepath.Join(cfg.DataDir, "claim_dbs")
+	if cfg.ClaimTrieProfile != "" {
+		err = claimTrieCfg.ApplyProfile(cfg.ClaimTrieProfile)
+		if err != nil {
+			return nil, fmt.Errorf("claimtrie profile: %w", err)
+		}
+	}
 	claimTrieCfg.Record = cfg.ClaimTrieRecord
+	claimTrieCfg.SlowBlockThreshold = cfg.ClaimTrieSlowBlock
+	claimTrieCfg.SlowNameThreshold = cfg.ClaimTrieSlowName
+	claimTrieCfg.ChannelStats = cfg.ClaimTrieChanStats
+	claimTrieCfg.ClaimIDIndex = cfg.ClaimTrieClaimIDs
+	claimTrieCfg.ClaimIDCollisions = cfg.ClaimTrieCollisions
+	claimTrieCfg.TopNames = cfg.ClaimTrieTopNames
+	claimTrieCfg.NameSearch = cfg.ClaimTrieSearch
+	claimTrieCfg.IdleWarmup = cfg.ClaimTrieIdleWarmup
+	claimTrieCfg.GenesisClaims = cfg.ClaimTrieGenesis
+	claimTrieCfg.OutPointIndex = cfg.ClaimTrieOutPoints
+	claimTrieCfg.ValueStorage = cfg.ClaimTrieValues
+	claimTrieCfg.ValueBlobSize = cfg.ClaimTrieValueBlob
+	claimTrieCfg.HistoryCacheSize = cfg.ClaimTrieHistCache
+	claimTrieCfg.NodeVersionDepth = cfg.ClaimTrieVersions
+	claimTrieCfg.HistoryReadWait = cfg.ClaimTrieHistWait
+	claimTrieCfg.BatchBlocks = cfg.ClaimTrieBatchBlk
+	claimTrieCfg.BatchBytes = cfg.ClaimTrieBatchSize << 20
+	if cfg.ClaimTrieMemory != 0 {
+		claimTrieCfg.MemoryBudget = cfg.ClaimTrieMemory << 20
+	}
+	claimTrieCfg.TriePrefetch = cfg.ClaimTriePrefetch
+	claimTrieCfg.TrieNodeCache = cfg.ClaimTrieNodeCache << 20
+	claimTrieCfg.EncryptionKeyFile = cfg.ClaimTrieKeyFile
+	claimTrieCfg.TrieAsyncWrites = cfg.ClaimTrieAsyncWrite
+	claimTrieCfg.NameActivity = cfg.ClaimTrieActivity
+	claimTrieCfg.ValueHashIndex = cfg.ClaimTrieValueHashes
+	claimTrieCfg.ClaimHistory = cfg.ClaimTrieHistory
+	claimTrieCfg.StrictConflicts = cfg.ClaimTrieStrict
+	claimTrieCfg.StrictSpends = cfg.ClaimTrieStrictSpend
+	claimTrieCfg.MerkleTrieRemote = cfg.ClaimTrieRemote
+	if cfg.ClaimTrieCompact != 0 {
+		claimTrieCfg.CompactionThreshold = cfg.ClaimTrieCompact
+	}
+	claimTrieCfg.MaxReorgDepth = cfg.ClaimTrieReorg
+	claimTrieCfg.CrossValidateTrie = cfg.ClaimTrieCrossVal
+	claimTrieCfg.TakeoverDiagnostics = cfg.ClaimTrieTakeovers || cfg.ClaimTrieTakeoverRec
+	claimTrieCfg.TakeoverRecord = cfg.ClaimTrieTakeoverRec
+	claimTrieCfg.CompactionWindows, err = claimtrieconfig.ParseTimeWindows(cfg.ClaimTrieCompactWin)
+	if err != nil {
+		return nil, fmt.Errorf("claimtrie compaction windows: %w", err)
+	}
+	claimTrieCfg.Budgets, err = claimtrieconfig.ParseBudgets(cfg.ClaimTrieBudgets)
+	if err != nil {
+		return nil, fmt.Errorf("claimtrie budgets: %w", err)
+	}
+	if cfg.ClaimTrieNodeMgr != "" {
+		claimTrieCfg.NodeManager = cfg.ClaimTrieNodeMgr
+	}
+	claimTrieCfg.ExpiredCompactionBlocks = cfg.ClaimTrieCompactExp
+	claimTrieCfg.TrieRetentionBlocks = cfg.ClaimTrieTrieRetain
+	if cfg.ClaimTrieRetention != "" {
+		claimTrieCfg.ChangeRetention = cfg.ClaimTrieRetention
+		claimTrieCfg.ChangeRetentionBlocks = cfg.ClaimTrieRetainBlk
+	}
+	if cfg.ClaimTrieDeltaSync != "" {
+		claimTrieCfg.DeltaSyncListen = cfg.ClaimTrieDeltaSync
+		claimTrieCfg.DeltaSyncBlocks = 100
+		if cfg.ClaimTrieDeltaBlk > 0 {
+			claimTrieCfg.DeltaSyncBlocks = cfg.ClaimTrieDeltaBlk
+		}
+	}
+	if cfg.ClaimTrieStandby != "" {
+		claimTrieCfg.StandbyListen = cfg.ClaimTrieStandby
+		claimTrieCfg.StandbyBlocks = 100
+		if cfg.ClaimTrieStandbyBlk > 0 {
+			claimTrieCfg.StandbyBlocks = cfg.ClaimTrieStandbyBlk
+		}
+	}
+	claimTrieCfg.UpstreamURL = cfg.ClaimTrieUpstream
+	claimTrieCfg.UpstreamKey = cfg.ClaimTrieUpstreamKey
+	if cfg.ClaimTrieMetrics != "" {
+		claimTrieCfg.Metrics = true
+		claimTrieCfg.MetricsListen = cfg.ClaimTrieMetrics
+	}
 
 	var ct *claimtrie.ClaimTrie
 
@@ -2749,6 +2864,28 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 			}
 			clmtLog.Infof("Height is reset to %d", h)
 		}
+		var tuning *claimtrie.TuningReport
+		if cfg.ClaimTrieBenchmark {
+			tuning, err = claimtrie.Tune(claimTrieCfg)
+			if err != nil {
+				clmtLog.Warnf("ClaimTrie benchmark: %v", err)
+			} else {
+				logTuningReport(tuning)
+			}
+		}
+		if cfg.ClaimTrieAdmin != "" {
+			s.claimTrieAdmin, err = newClaimTrieAdmin(cfg.ClaimTrieAdmin, cfg.RPCUser, cfg.RPCPass, ct)
+			if err != nil {
+				return nil, err
+			}
+			s.claimTrieAdmin.tuning = tuning
+		}
+		if cfg.ClaimTrieHealth != "" {
+			s.claimTrieHealth, err = newClaimTrieHealth(cfg.ClaimTrieHealth, ct, s.bestPeerHeight, cfg.ClaimTrieReadyLag)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Create a new block chain instance with the appropriate configuration.
@@ -2762,10 +2899,16 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		IndexManager: indexManager,
 		HashCache:    s.hashCache,
 		ClaimTrie:    ct,
+
+		StrictClaimScripts:   cfg.ClaimTrieStrictOps,
+		VerifyClaimTrieRoots: cfg.ClaimTrieVerifyRoots,
 	})
 	if err != nil {
 		return nil, err
 	}
+	if s.claimTrieAdmin != nil {
+		s.claimTrieAdmin.chain = s.chain
+	}
 
 	// Search for a FeeEstimator state in the database. If none can be found
 	// or if it cannot be loaded, create a new one.
@@ -3,7 +3,9 @@ package txscript
 import (
 	"fmt"
 
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
 )
 
 const (
@@ -29,8 +31,49 @@ var (
 	// ErrInvalidClaimScript is returned when a script has a ClaimScript Opcode,
 	// but does not conform to the format.
 	ErrInvalidClaimScript = fmt.Errorf("invalid claim script")
+
+	// ErrFutureClaimScript is returned by RecognizeClaimScript when the script has a claim Opcode
+	// introduced by a version after the one recognized.
+	ErrFutureClaimScript = fmt.Errorf("future claim script")
+)
+
+// ClaimScriptVersion is a version of the claim scripts, as extended by the soft-forks, which define
+// the claim Opcodes of the future claim scripts.
+type ClaimScriptVersion int
+
+const (
+	// ClaimScriptV1 is OP_CLAIMNAME, OP_SUPPORTCLAIM, with or without a value, and OP_UPDATECLAIM.
+	ClaimScriptV1 ClaimScriptVersion = 1
+
+	// ClaimScriptVersionCurrent is the latest version of the claim scripts known.
+	ClaimScriptVersionCurrent = ClaimScriptV1
 )
 
+// claimOpcodeVersions are the versions the claim Opcodes were introduced by. The NOPs left are not
+// claim Opcodes, until a soft-fork defines them, and the version introducing them is added here.
+var claimOpcodeVersions = map[byte]ClaimScriptVersion{
+	OP_CLAIMNAME:    ClaimScriptV1,
+	OP_SUPPORTCLAIM: ClaimScriptV1,
+	OP_UPDATECLAIM:  ClaimScriptV1,
+}
+
+// RecognizeClaimScript decodes the claim script, as DecodeClaimScript does, if its Opcode is known as of
+// the version. It returns ErrFutureClaimScript for the Opcodes of later versions, which the nodes not
+// knowing them are to treat as NOPs, as they did with the claim Opcodes before those.
+func RecognizeClaimScript(script []byte, version ClaimScriptVersion) (*ClaimScript, error) {
+	if len(script) == 0 {
+		return nil, ErrNotClaimScript
+	}
+	v, ok := claimOpcodeVersions[script[0]]
+	if !ok {
+		return nil, ErrNotClaimScript
+	}
+	if v > version {
+		return nil, ErrFutureClaimScript
+	}
+	return DecodeClaimScript(script)
+}
+
 // ClaimNameScript ...
 func ClaimNameScript(name string, value string) ([]byte, error) {
 	return NewScriptBuilder().AddOp(OP_CLAIMNAME).AddData([]byte(name)).AddData([]byte(value)).
@@ -54,6 +97,9 @@ func UpdateClaimScript(name string, claimID []byte, value string) ([]byte, error
 
 // DecodeClaimScript ...
 func DecodeClaimScript(script []byte) (*ClaimScript, error) {
+	if len(script) == 0 {
+		return nil, ErrNotClaimScript
+	}
 	op := script[0]
 	if op != OP_CLAIMNAME && op != OP_SUPPORTCLAIM && op != OP_UPDATECLAIM {
 		return nil, ErrNotClaimScript
@@ -74,9 +120,10 @@ func DecodeClaimScript(script []byte) (*ClaimScript, error) {
 }
 
 // ClaimScript ...
-// OP_CLAIMNAME    <Name> <Value>           OP_2DROP OP_DROP <P2PKH>
-// OP_SUPPORTCLAIM <Name> <ClaimID>         OP_2DROP OP_DROP <P2PKH>
-// OP_UPDATECLAIM  <Name> <ClaimID> <Value> OP_2DROP OP_2DROP <P2PKH>
+// OP_CLAIMNAME    <Name> <Value>           OP_2DROP OP_DROP <PkScript>
+// OP_SUPPORTCLAIM <Name> <ClaimID>         OP_2DROP OP_DROP <PkScript>
+// OP_UPDATECLAIM  <Name> <ClaimID> <Value> OP_2DROP OP_2DROP <PkScript>
+// The PkScript controlling the claim is usually P2PKH, but may be of any class, such as P2SH or P2WPKH.
 type ClaimScript struct {
 	op   byte
 	pops []parsedOpcode
@@ -87,6 +134,11 @@ func (cs *ClaimScript) Opcode() byte {
 	return cs.op
 }
 
+// Version returns the version of the claim scripts, which introduced the Opcode.
+func (cs *ClaimScript) Version() ClaimScriptVersion {
+	return claimOpcodeVersions[cs.op]
+}
+
 // Name ...
 func (cs *ClaimScript) Name() []byte {
 	return cs.pops[1].data
@@ -111,8 +163,8 @@ func (cs *ClaimScript) Value() []byte {
 // Size ...
 func (cs *ClaimScript) Size() int {
 	ops := 5
-	if cs.pops[0].opcode.value == OP_UPDATECLAIM {
-		ops++
+	if cs.op == OP_UPDATECLAIM || (cs.op == OP_SUPPORTCLAIM && cs.pops[3].opcode.value != OP_2DROP) {
+		ops++ // of the value, dropped along with the claim ID
 	}
 	size := 0
 	for _, op := range cs.pops[:ops] {
@@ -134,6 +186,12 @@ func StripClaimScriptPrefix(script []byte) []byte {
 	return script[cs.Size():]
 }
 
+// ExtractClaimPkScriptAddrs is ExtractPkScriptAddrs, but of the PkScript following the claim script,
+// if it's one, so the claims are attributed to the addresses controlling them.
+func ExtractClaimPkScriptAddrs(pkScript []byte, chainParams *chaincfg.Params) (ScriptClass, []btcutil.Address, int, error) {
+	return ExtractPkScriptAddrs(StripClaimScriptPrefix(pkScript), chainParams)
+}
+
 // ClaimScriptSize returns size of the claim script minus the script pubkey part.
 func ClaimScriptSize(script []byte) int {
 	cs, err := DecodeClaimScript(script)
@@ -3,6 +3,9 @@ package txscript
 import (
 	"testing"
 
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -71,3 +74,101 @@ func TestCreationParseLoopSupport(t *testing.T) {
 	r.Equal(claimID, script.ClaimID())
 	r.Nil(script.Value())
 }
+
+func TestExtractClaimPkScriptAddrs(t *testing.T) {
+
+	r := require.New(t)
+
+	params := &chaincfg.MainNetParams
+	hash := make([]byte, 20)
+	p2pkh, err := btcutil.NewAddressPubKeyHash(hash, params)
+	r.NoError(err)
+	p2sh, err := btcutil.NewAddressScriptHashFromHash(hash, params)
+	r.NoError(err)
+	p2wpkh, err := btcutil.NewAddressWitnessPubKeyHash(hash, params)
+	r.NoError(err)
+
+	tests := []struct {
+		addr  btcutil.Address
+		class ScriptClass
+	}{
+		{p2pkh, PubKeyHashTy},
+		{p2sh, ScriptHashTy},
+		{p2wpkh, WitnessV0PubKeyHashTy},
+	}
+	for _, test := range tests {
+		pkScript, err := PayToAddrScript(test.addr)
+		r.NoError(err)
+		prefix, err := UpdateClaimScript("tester", []byte("12345123451234512345"), "value")
+		r.NoError(err)
+		script := append(prefix[:len(prefix)-1:len(prefix)-1], pkScript...) // replace the OP_TRUE
+
+		cs, err := DecodeClaimScript(script)
+		r.NoError(err)
+		r.Equal([]byte("tester"), cs.Name())
+
+		class, addrs, reqSigs, err := ExtractClaimPkScriptAddrs(script, params)
+		r.NoError(err)
+		r.Equal(test.class, class)
+		r.Equal(1, reqSigs)
+		r.Len(addrs, 1)
+		r.Equal(test.addr.EncodeAddress(), addrs[0].EncodeAddress())
+
+		// Other scripts are extracted as they are.
+		class, addrs, _, err = ExtractClaimPkScriptAddrs(pkScript, params)
+		r.NoError(err)
+		r.Equal(test.class, class)
+		r.Equal(test.addr.EncodeAddress(), addrs[0].EncodeAddress())
+	}
+
+	_, err = DecodeClaimScript(nil)
+	r.Equal(ErrNotClaimScript, err)
+}
+
+// TestStripClaimScriptPrefix ensures the payment script following each form of the claim scripts is recovered.
+func TestStripClaimScriptPrefix(t *testing.T) {
+
+	r := require.New(t)
+
+	claimID := []byte("12345123451234512345")
+	pkScript := []byte{OP_DUP, OP_HASH160}
+	build := func(script []byte, err error) []byte {
+		r.NoError(err)
+		return append(script[:len(script)-1:len(script)-1], pkScript...) // replace the OP_TRUE
+	}
+
+	for _, script := range [][]byte{
+		build(ClaimNameScript("tester", "value")),
+		build(UpdateClaimScript("tester", claimID, "value")),
+		build(SupportClaimScript("tester", claimID, nil)),
+		build(SupportClaimScript("tester", claimID, []byte("value"))),
+	} {
+		r.Equal(pkScript, StripClaimScriptPrefix(script))
+		r.Equal(len(script)-len(pkScript), ClaimScriptSize(script))
+	}
+
+	r.Equal(pkScript, StripClaimScriptPrefix(pkScript))
+}
+
+func TestRecognizeClaimScript(t *testing.T) {
+
+	r := require.New(t)
+
+	claim, err := ClaimNameScript("tester", "value")
+	r.NoError(err)
+	cs, err := RecognizeClaimScript(claim, ClaimScriptVersionCurrent)
+	r.NoError(err)
+	r.Equal(ClaimScriptV1, cs.Version())
+
+	// The NOPs left after the claim Opcodes aren't claim Opcodes, of any version, until a spec defines them.
+	nop, err := NewScriptBuilder().AddOp(OP_NOP9).AddData([]byte("tester")).AddData([]byte("value")).
+		AddOp(OP_2DROP).AddOp(OP_DROP).AddOp(OP_TRUE).Script()
+	r.NoError(err)
+	_, err = RecognizeClaimScript(nop, ClaimScriptVersionCurrent)
+	r.Equal(ErrNotClaimScript, err)
+
+	_, err = RecognizeClaimScript(claim, 0)
+	r.Equal(ErrFutureClaimScript, err)
+	_, err = RecognizeClaimScript([]byte{OP_TRUE}, ClaimScriptVersionCurrent)
+	r.Equal(ErrNotClaimScript, err)
+}